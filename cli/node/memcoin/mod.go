@@ -20,10 +20,64 @@
 //    --member $(memcoin --config /tmp/node1 ordering export)\
 //    --member $(memcoin --config /tmp/node2 ordering export)
 //
+//  # The leader can also change for every block with a policy selected when
+//  # the chain is set up, like --leader-policy round-robin.
+//
+//  # A chain can also be described by a JSON file with the members, the leader
+//  # policy, the initial state and the limits of the blocks. Each member can
+//  # print the digest of the genesis block before the chain is created, and
+//  # the setup checks it.
+//  memcoin --config /tmp/node2 ordering setup --genesis genesis.json --dry-run
+//  memcoin --config /tmp/node1 ordering setup --genesis genesis.json\
+//    --digest <hex digest>
+//
 //  # Add the third after the chain is set up.
 //  memcoin --config /tmp/node1 ordering roster add\
 //    --member $(memcoin --config /tmp/node3 ordering export)
 //
+//  # Replace the signing key of a member. The node keeps using the current key
+//  # until the roster change is committed.
+//  memcoin --config /tmp/node2 ordering roster rotate --wait 20s
+//
+//  # Bound the blocks to 100 transactions and 1MB of transactions.
+//  memcoin --config /tmp/node1 ordering limits --max-transactions 100\
+//    --max-size 1048576 --wait 20s
+//
+//  # Get a proof for a key and verify it offline against the genesis block.
+//  memcoin --config /tmp/node1 ordering genesis > /tmp/genesis.json
+//  memcoin --config /tmp/node1 ordering proof --key mykey > /tmp/proof.json
+//  memcoin ordering verify --genesis /tmp/genesis.json --proof /tmp/proof.json
+//
+//  # Nodes started with --history can prove the value at a previous block.
+//  memcoin --config /tmp/node1 ordering proof --key mykey --index 3
+//
+//  # Every member must be started with --ordered-index for the value contract
+//  # to list its keys.
+//  memcoin --config /tmp/node1 start --port 2001 --ordered-index &
+//
+//  # Find the block that includes a transaction and whether it is accepted.
+//  memcoin --config /tmp/node1 ordering receipt --id <hex transaction id>
+//
+//  # Archive the chain and bootstrap a new node from it.
+//  memcoin --config /tmp/node1 ordering chain export --out /tmp/chain.bin
+//  memcoin --config /tmp/node4 ordering chain import --in /tmp/chain.bin
+//
+//  # A new node started with --snapshot-sync restores the state of the leader
+//  # instead of replaying the blocks when it misses at least 100 blocks.
+//  memcoin --config /tmp/node5 start --port 2005 --snapshot-sync 100 &
+//
+//  # Download the missing blocks from several members, 50 blocks at a time.
+//  memcoin --config /tmp/node6 start --port 2006 --sync-range 50 &
+//
+//  # Follow the chain as an observer that serves the proofs without voting. The
+//  # observer only accepts the genesis block with the given digest.
+//  memcoin --config /tmp/node7 start --port 2007\
+//    --observe $(memcoin --config /tmp/node1 ordering export)\
+//    --observe-digest <hex digest> &
+//
+//  # List the misbehaviors of the roster members detected by a node.
+//  memcoin --config /tmp/node1 ordering evidence list
+//
 package main
 
 import (
@@ -108,8 +108,29 @@ func TestMemcoin_Scenario_SetupAndTransactions(t *testing.T) {
 	require.EqualError(t, err, "command error: transaction not found after timeout")
 
 	// Test a bad command.
-	err = runWithCfg([]string{os.Args[0], "ordering", "setup"}, cfg)
+	err = runWithCfg([]string{os.Args[0], "ordering", "roster", "add"}, cfg)
 	require.EqualError(t, err, `Required flag "member" not set`)
+
+	// Get a proof of the roster and verify it offline with the genesis block.
+	genesisPath := filepath.Join(dir, "genesis.json")
+	proofPath := filepath.Join(dir, "proof.json")
+
+	args = []string{os.Args[0], "--config", node2, "ordering", "genesis"}
+	runToFile(t, args, genesisPath)
+
+	args = []string{
+		os.Args[0], "--config", node2, "ordering", "proof",
+		"--key", strings.Repeat("00", 32), "--hex",
+	}
+	runToFile(t, args, proofPath)
+
+	args = []string{
+		os.Args[0], "ordering", "verify",
+		"--genesis", genesisPath, "--proof", proofPath,
+	}
+
+	err = runWithCfg(args, cfg)
+	require.NoError(t, err)
 }
 
 // This test creates a chain with two nodes, then gracefully close them. It
@@ -261,6 +282,16 @@ func getToken(t *testing.T, path string) []string {
 	return strings.Split(buffer.String(), " ")
 }
 
+func runToFile(t *testing.T, args []string, path string) {
+	buffer := new(bytes.Buffer)
+
+	err := runWithCfg(args, config{Writer: buffer})
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path, buffer.Bytes(), os.ModePerm)
+	require.NoError(t, err)
+}
+
 func getExport(t *testing.T, path string) []string {
 	buffer := bytes.NewBufferString("--member ")
 	cfg := config{
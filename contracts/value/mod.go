@@ -5,7 +5,6 @@ package value
 import (
 	"fmt"
 	"io"
-	"sort"
 	"strings"
 
 	"go.dedis.ch/dela"
@@ -44,6 +43,15 @@ const (
 	// credentialAllCommand defines the credential command that is allowed to
 	// perform all commands.
 	credentialAllCommand = "all"
+
+	// keyPrefix is prepended to the keys of the contract in the store so that
+	// they are listed apart from the keys of the other contracts. The values
+	// written before the keys were prefixed are stored under the raw key: they
+	// are still read and deleted, but they are listed only once written again.
+	keyPrefix = "value:"
+
+	// keyEnd is the first key after the ones of the contract in the store.
+	keyEnd = "value;"
 )
 
 // Command defines a type of command for the value contract
@@ -80,9 +88,6 @@ func RegisterContract(exec *native.Service, c Contract) {
 //
 // - implements native.Contract
 type Contract struct {
-	// index contains all the keys set (and not delete) by this contract so far
-	index map[string]struct{}
-
 	// access is the access control service managing this smart contract
 	access access.Service
 
@@ -99,7 +104,6 @@ type Contract struct {
 // NewContract creates a new Value contract
 func NewContract(aKey []byte, srvc access.Service) Contract {
 	contract := Contract{
-		index:     map[string]struct{}{},
 		access:    srvc,
 		accessKey: aKey,
 		printer:   infoLog{},
@@ -172,13 +176,11 @@ func (c valueCommand) write(snap store.Snapshot, step execution.Step) error {
 		return xerrors.Errorf("'%s' not found in tx arg", ValueArg)
 	}
 
-	err := snap.Set(key, value)
+	err := snap.Set(storeKey(key), value)
 	if err != nil {
 		return xerrors.Errorf("failed to set value: %v", err)
 	}
 
-	c.index[string(key)] = struct{}{}
-
 	dela.Logger.Info().Str("contract", ContractName).Msgf("setting %s=%s", key, value)
 
 	return nil
@@ -191,11 +193,19 @@ func (c valueCommand) read(snap store.Snapshot, step execution.Step) error {
 		return xerrors.Errorf("'%s' not found in tx arg", KeyArg)
 	}
 
-	val, err := snap.Get(key)
+	val, err := snap.Get(storeKey(key))
 	if err != nil {
 		return xerrors.Errorf("failed to get key '%s': %v", key, err)
 	}
 
+	if val == nil {
+		// The value might have been written before the keys were prefixed.
+		val, err = snap.Get(key)
+		if err != nil {
+			return xerrors.Errorf("failed to get raw key '%s': %v", key, err)
+		}
+	}
+
 	fmt.Fprintf(c.printer, "%s=%s", key, val)
 
 	return nil
@@ -208,35 +218,51 @@ func (c valueCommand) delete(snap store.Snapshot, step execution.Step) error {
 		return xerrors.Errorf("'%s' not found in tx arg", KeyArg)
 	}
 
-	err := snap.Delete(key)
+	err := snap.Delete(storeKey(key))
 	if err != nil {
 		return xerrors.Errorf("failed to delete key '%s': %v", key, err)
 	}
 
-	delete(c.index, string(key))
+	// The value might have been written before the keys were prefixed.
+	err = snap.Delete(key)
+	if err != nil {
+		return xerrors.Errorf("failed to delete raw key '%s': %v", key, err)
+	}
 
 	return nil
 }
 
-// list implements commands. It performs the LIST command
+// list implements commands. It performs the LIST command by iterating over
+// the keys of the contract in the store, in order. The tree of the store must
+// keep the ordered index of its keys.
 func (c valueCommand) list(snap store.Snapshot) error {
+	rangeable, ok := snap.(store.RangeReadable)
+	if !ok {
+		return xerrors.Errorf("snapshot '%T' cannot iterate over its keys", snap)
+	}
+
 	res := []string{}
 
-	for k := range c.index {
-		v, err := snap.Get([]byte(k))
-		if err != nil {
-			return xerrors.Errorf("failed to get key '%s': %v", k, err)
-		}
+	err := rangeable.ForRange([]byte(keyPrefix), []byte(keyEnd),
+		func(key, value []byte) error {
+			res = append(res, fmt.Sprintf("%s=%s", key[len(keyPrefix):], value))
 
-		res = append(res, fmt.Sprintf("%s=%s", k, v))
+			return nil
+		})
+	if err != nil {
+		return xerrors.Errorf("failed to iterate: %v", err)
 	}
 
-	sort.Strings(res)
 	fmt.Fprint(c.printer, strings.Join(res, ","))
 
 	return nil
 }
 
+// storeKey returns the key in the store of the key of the contract.
+func storeKey(key []byte) []byte {
+	return append([]byte(keyPrefix), key...)
+}
+
 // infoLog defines an output using zerolog
 //
 // - implements io.writer
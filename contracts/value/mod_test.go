@@ -64,16 +64,10 @@ func TestCommand_Write(t *testing.T) {
 
 	snap := fake.NewSnapshot()
 
-	_, found := contract.index["dummy"]
-	require.False(t, found)
-
 	err = cmd.write(snap, makeStep(t, KeyArg, "dummy", ValueArg, "value"))
 	require.NoError(t, err)
 
-	_, found = contract.index["dummy"]
-	require.True(t, found)
-
-	res, err := snap.Get([]byte("dummy"))
+	res, err := snap.Get([]byte("value:dummy"))
 	require.NoError(t, err)
 	require.Equal(t, "value", string(res))
 }
@@ -92,7 +86,7 @@ func TestCommand_Read(t *testing.T) {
 	require.EqualError(t, err, fake.Err("failed to get key 'dummy'"))
 
 	snap := fake.NewSnapshot()
-	snap.Set([]byte("dummy"), []byte("value"))
+	snap.Set([]byte("value:dummy"), []byte("value"))
 
 	buf := &bytes.Buffer{}
 	cmd.Contract.printer = buf
@@ -101,6 +95,25 @@ func TestCommand_Read(t *testing.T) {
 	require.NoError(t, err)
 
 	require.Equal(t, "dummy=value", buf.String())
+
+	// A value written before the keys were prefixed is still readable.
+	snap.Set([]byte("legacy"), []byte("old"))
+
+	buf.Reset()
+	err = cmd.read(snap, makeStep(t, KeyArg, "legacy"))
+	require.NoError(t, err)
+	require.Equal(t, "legacy=old", buf.String())
+
+	snap.Set([]byte("value:legacy"), []byte("new"))
+
+	buf.Reset()
+	err = cmd.read(snap, makeStep(t, KeyArg, "legacy"))
+	require.NoError(t, err)
+	require.Equal(t, "legacy=new", buf.String())
+
+	err = cmd.read(rawSnapshot{InMemorySnapshot: fake.NewSnapshot()},
+		makeStep(t, KeyArg, "dummy"))
+	require.EqualError(t, err, fake.Err("failed to get raw key 'dummy'"))
 }
 
 func TestCommand_Delete(t *testing.T) {
@@ -117,24 +130,27 @@ func TestCommand_Delete(t *testing.T) {
 	require.EqualError(t, err, fake.Err("failed to delete key 'dummy'"))
 
 	snap := fake.NewSnapshot()
-	snap.Set([]byte("dummy"), []byte("value"))
-	contract.index["dummy"] = struct{}{}
+	snap.Set([]byte("value:dummy"), []byte("value"))
+	snap.Set([]byte("dummy"), []byte("old"))
 
 	err = cmd.delete(snap, makeStep(t, KeyArg, "dummy"))
 	require.NoError(t, err)
 
-	res, err := snap.Get([]byte("dummy"))
+	res, err := snap.Get([]byte("value:dummy"))
+	require.Nil(t, err)
+	require.Nil(t, res)
+
+	res, err = snap.Get([]byte("dummy"))
 	require.Nil(t, err)
 	require.Nil(t, res)
 
-	_, found := contract.index["dummy"]
-	require.False(t, found)
+	err = cmd.delete(rawSnapshot{InMemorySnapshot: fake.NewSnapshot()},
+		makeStep(t, KeyArg, "dummy"))
+	require.EqualError(t, err, fake.Err("failed to delete raw key 'dummy'"))
 }
 
 func TestCommand_List(t *testing.T) {
 	contract := NewContract([]byte{}, fakeAccess{})
-	contract.index["key1"] = struct{}{}
-	contract.index["key2"] = struct{}{}
 
 	buf := &bytes.Buffer{}
 	contract.printer = buf
@@ -144,8 +160,10 @@ func TestCommand_List(t *testing.T) {
 	}
 
 	snap := fake.NewSnapshot()
-	snap.Set([]byte("key1"), []byte("value1"))
-	snap.Set([]byte("key2"), []byte("value2"))
+	snap.Set([]byte("value:key2"), []byte("value2"))
+	snap.Set([]byte("value:key1"), []byte("value1"))
+	snap.Set([]byte("access:key3"), []byte("value3"))
+	snap.Set([]byte("value;key4"), []byte("value4"))
 
 	err := cmd.list(snap)
 	require.NoError(t, err)
@@ -153,8 +171,11 @@ func TestCommand_List(t *testing.T) {
 	require.Equal(t, "key1=value1,key2=value2", buf.String())
 
 	err = cmd.list(fake.NewBadSnapshot())
-	// we can't assume an order from the map
-	require.Regexp(t, "^failed to get key", err.Error())
+	require.EqualError(t, err, fake.Err("failed to iterate"))
+
+	err = cmd.list(fakeStore{})
+	require.EqualError(t, err,
+		"snapshot 'value.fakeStore' cannot iterate over its keys")
 }
 
 func TestInfoLog(t *testing.T) {
@@ -233,3 +254,25 @@ func (c fakeCmd) delete(snap store.Snapshot, step execution.Step) error {
 func (c fakeCmd) list(snap store.Snapshot) error {
 	return c.err
 }
+
+// rawSnapshot is a snapshot that fails to read and delete the keys that are
+// not prefixed.
+type rawSnapshot struct {
+	*fake.InMemorySnapshot
+}
+
+func (snap rawSnapshot) Get(key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(key, []byte(keyPrefix)) {
+		return nil, fake.GetError()
+	}
+
+	return snap.InMemorySnapshot.Get(key)
+}
+
+func (snap rawSnapshot) Delete(key []byte) error {
+	if !bytes.HasPrefix(key, []byte(keyPrefix)) {
+		return fake.GetError()
+	}
+
+	return snap.InMemorySnapshot.Delete(key)
+}
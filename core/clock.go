@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time of a component. It allows a simulation to run
+// the components with a virtual time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel that receives the current time once the duration
+	// has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// SystemClock is the clock of the operating system.
+//
+// - implements core.Clock
+type SystemClock struct{}
+
+// Now implements core.Clock. It returns the local time.
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements core.Clock. It returns a channel populated after the
+// duration.
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WithTimeout returns a copy of the parent context that is canceled when the
+// duration has elapsed according to the clock, or when the cancel function is
+// called.
+func WithTimeout(parent context.Context, clock Clock,
+	d time.Duration) (context.Context, context.CancelFunc) {
+
+	_, system := clock.(SystemClock)
+	if system {
+		return context.WithTimeout(parent, d)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+
+	go func() {
+		select {
+		case <-clock.After(d):
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
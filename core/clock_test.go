@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemClock_Now(t *testing.T) {
+	clock := SystemClock{}
+
+	require.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+}
+
+func TestSystemClock_After(t *testing.T) {
+	clock := SystemClock{}
+
+	select {
+	case <-clock.After(time.Millisecond):
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), SystemClock{}, time.Millisecond)
+	defer cancel()
+
+	<-ctx.Done()
+	require.Equal(t, context.DeadlineExceeded, ctx.Err())
+
+	clock := fakeClock{ch: make(chan time.Time, 1)}
+
+	ctx, cancel = WithTimeout(context.Background(), clock, time.Hour)
+	defer cancel()
+
+	require.NoError(t, ctx.Err())
+
+	clock.ch <- time.Now()
+	<-ctx.Done()
+	require.Equal(t, context.Canceled, ctx.Err())
+
+	ctx, cancel = WithTimeout(context.Background(), clock, time.Hour)
+	cancel()
+	require.Equal(t, context.Canceled, ctx.Err())
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type fakeClock struct {
+	SystemClock
+	ch chan time.Time
+}
+
+func (c fakeClock) After(time.Duration) <-chan time.Time {
+	return c.ch
+}
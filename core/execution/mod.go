@@ -6,6 +6,8 @@
 package execution
 
 import (
+	"time"
+
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/txn"
 )
@@ -16,6 +18,10 @@ import (
 type Step struct {
 	Previous []txn.Transaction
 	Current  txn.Transaction
+
+	// Timestamp is the time of the block that contains the transaction. It is
+	// the zero time when the ordering service does not provide one.
+	Timestamp time.Time
 }
 
 // Result is the result of a transaction execution.
@@ -0,0 +1,290 @@
+// This file contains the implementation of the chain archives, which allow a
+// node to export its chain and another node to import it without the help of
+// a live leader.
+
+package cosipbft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// ArchiveVersion is the version of the format of the chain archives
+	// written by the service.
+	ArchiveVersion uint16 = 1
+
+	// archiveMaxFrame is the maximum size of a frame so that a corrupted
+	// archive cannot exhaust the memory.
+	archiveMaxFrame = 1 << 28
+)
+
+var archiveMagic = []byte("DELACHAIN")
+
+// ExportChain writes the chain to the writer. The archive starts with a
+// versioned header, followed by the genesis block and every block link in
+// order, each of them serialized and prefixed with its size. It returns an
+// error when the node was restored from a snapshot and misses the first blocks.
+func (s *Service) ExportChain(w io.Writer) error {
+	genesis, err := s.genesis.Get()
+	if err != nil {
+		return xerrors.Errorf("reading genesis: %v", err)
+	}
+
+	// The length is read once so that the blocks appended in the meantime are
+	// not part of the archive.
+	length := s.blocks.Len()
+
+	if length > 0 {
+		// A store restored from a snapshot does not have the blocks before
+		// its base, which is detected before anything is written.
+		_, err = s.blocks.GetByIndex(0)
+		if xerrors.Is(err, blockstore.ErrNoHistory) {
+			return xerrors.Errorf("chain is incomplete: %v", err)
+		}
+		if err != nil {
+			return xerrors.Errorf("reading block 0: %v", err)
+		}
+	}
+
+	aw := archiveWriter{w: w, context: s.context}
+
+	err = aw.writeHeader()
+	if err != nil {
+		return xerrors.Errorf("writing header: %v", err)
+	}
+
+	err = aw.write(genesis)
+	if err != nil {
+		return xerrors.Errorf("writing genesis: %v", err)
+	}
+
+	for index := uint64(0); index < length; index++ {
+		link, err := s.blocks.GetByIndex(index)
+		if err != nil {
+			return xerrors.Errorf("reading block %d: %v", index, err)
+		}
+
+		err = aw.write(link)
+		if err != nil {
+			return xerrors.Errorf("writing block %d: %v", index, err)
+		}
+	}
+
+	return nil
+}
+
+// ImportChain reads an archive written by ExportChain and applies it to the
+// node. The genesis block creates the chain if the node does not have one yet,
+// otherwise it must match the current one. Every block is then verified as if
+// it was received from a leader: the forward link, the collective signatures
+// and the tree root after applying the transactions. Blocks already stored are
+// skipped which allows to resume an import.
+func (s *Service) ImportChain(r io.Reader) error {
+	ar := archiveReader{r: r}
+
+	err := ar.readHeader()
+	if err != nil {
+		return xerrors.Errorf("invalid archive: %v", err)
+	}
+
+	data, err := ar.next()
+	if err != nil {
+		return xerrors.Errorf("reading genesis: %v", err)
+	}
+
+	msg, err := s.genesisFac.Deserialize(s.context, data)
+	if err != nil {
+		return xerrors.Errorf("malformed genesis: %v", err)
+	}
+
+	genesis, ok := msg.(types.Genesis)
+	if !ok {
+		return xerrors.Errorf("invalid genesis '%T'", msg)
+	}
+
+	err = s.importGenesis(genesis)
+	if err != nil {
+		return xerrors.Errorf("importing genesis: %v", err)
+	}
+
+	for {
+		data, err := ar.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return xerrors.Errorf("reading block: %v", err)
+		}
+
+		link, err := s.linkFac.BlockLinkOf(s.context, data)
+		if err != nil {
+			return xerrors.Errorf("malformed block: %v", err)
+		}
+
+		err = s.importLink(link)
+		if err != nil {
+			return xerrors.Errorf("importing block %d: %v",
+				link.GetBlock().GetIndex(), err)
+		}
+	}
+}
+
+func (s *Service) importGenesis(genesis types.Genesis) error {
+	if s.genesis.Exists() {
+		return s.matchGenesis(genesis)
+	}
+
+	// The genesis is rebuilt from the roster, and the tree root must match
+	// the one of the archive.
+	root := genesis.GetRoot()
+
+	err := s.storeGenesis(newGenesisParam(genesis), &root)
+	if xerrors.Is(err, errGenesisExists) {
+		return s.matchGenesis(genesis)
+	}
+
+	if err != nil {
+		return xerrors.Errorf("storing genesis: %v", err)
+	}
+
+	return nil
+}
+
+func (s *Service) importLink(link types.BlockLink) error {
+	index := link.GetBlock().GetIndex()
+
+	if index < s.blocks.Len() {
+		stored, err := s.blocks.GetByIndex(index)
+		if err != nil {
+			return xerrors.Errorf("reading block: %v", err)
+		}
+
+		if stored.GetTo() != link.GetTo() {
+			return xerrors.Errorf("mismatch block '%v' != '%v'",
+				link.GetTo(), stored.GetTo())
+		}
+
+		return nil
+	}
+
+	var latest types.Digest
+
+	last, err := s.blocks.Last()
+	if err == nil {
+		latest = last.GetTo()
+	} else {
+		genesis, err := s.genesis.Get()
+		if err != nil {
+			return xerrors.Errorf("reading genesis: %v", err)
+		}
+
+		latest = genesis.GetHash()
+	}
+
+	if link.GetFrom() != latest {
+		return xerrors.Errorf("mismatch link '%v' != '%v'", link.GetFrom(), latest)
+	}
+
+	err = s.pbftsm.CatchUp(link)
+	if err != nil {
+		return xerrors.Errorf("catch up failed: %v", err)
+	}
+
+	return nil
+}
+
+// archiveWriter writes the header and the frames of an archive.
+type archiveWriter struct {
+	w       io.Writer
+	context serde.Context
+}
+
+func (aw archiveWriter) writeHeader() error {
+	buffer := make([]byte, len(archiveMagic)+2)
+	copy(buffer, archiveMagic)
+	binary.BigEndian.PutUint16(buffer[len(archiveMagic):], ArchiveVersion)
+
+	_, err := aw.w.Write(buffer)
+	if err != nil {
+		return xerrors.Errorf("write failed: %v", err)
+	}
+
+	return nil
+}
+
+func (aw archiveWriter) write(msg serde.Message) error {
+	data, err := msg.Serialize(aw.context)
+	if err != nil {
+		return xerrors.Errorf("failed to serialize: %v", err)
+	}
+
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(data)))
+
+	_, err = aw.w.Write(append(size, data...))
+	if err != nil {
+		return xerrors.Errorf("write failed: %v", err)
+	}
+
+	return nil
+}
+
+// archiveReader reads the header and the frames of an archive.
+type archiveReader struct {
+	r io.Reader
+}
+
+func (ar archiveReader) readHeader() error {
+	buffer := make([]byte, len(archiveMagic)+2)
+
+	_, err := io.ReadFull(ar.r, buffer)
+	if err != nil {
+		return xerrors.Errorf("read failed: %v", err)
+	}
+
+	if !bytes.Equal(buffer[:len(archiveMagic)], archiveMagic) {
+		return xerrors.New("unknown format")
+	}
+
+	version := binary.BigEndian.Uint16(buffer[len(archiveMagic):])
+	if version != ArchiveVersion {
+		return xerrors.Errorf("unsupported version %d", version)
+	}
+
+	return nil
+}
+
+// next returns the content of the next frame, or io.EOF if the archive has no
+// more frames.
+func (ar archiveReader) next() ([]byte, error) {
+	size := make([]byte, 4)
+
+	_, err := io.ReadFull(ar.r, size)
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("read size failed: %v", err)
+	}
+
+	length := binary.BigEndian.Uint32(size)
+	if length > archiveMaxFrame {
+		return nil, xerrors.Errorf("frame too big: %d", length)
+	}
+
+	data := make([]byte, length)
+
+	_, err = io.ReadFull(ar.r, data)
+	if err != nil {
+		return nil, xerrors.Errorf("read frame failed: %v", err)
+	}
+
+	return data, nil
+}
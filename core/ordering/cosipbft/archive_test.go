@@ -0,0 +1,267 @@
+package cosipbft
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+)
+
+func TestService_Scenario_ChainArchive(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 4)
+	defer clean()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial := ro.Take(mino.RangeFilter(0, 3)).(crypto.CollectiveAuthority)
+
+	err := nodes[0].service.Setup(ctx, initial)
+	require.NoError(t, err)
+
+	events := nodes[0].service.Watch(ctx)
+
+	for i := 0; i < 3; i++ {
+		err = nodes[0].pool.Add(makeTx(t, uint64(i), nodes[0].signer))
+		require.NoError(t, err)
+
+		waitEvent(t, events)
+	}
+
+	buffer := new(bytes.Buffer)
+
+	err = nodes[0].service.ExportChain(buffer)
+	require.NoError(t, err)
+
+	archive := buffer.Bytes()
+
+	// The last node is not part of the roster so it can only get the chain
+	// from the archive.
+	fresh := nodes[3].service
+
+	err = fresh.ImportChain(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), fresh.blocks.Len())
+	require.Equal(t, nodes[0].service.tree.Get().GetRoot(), fresh.tree.Get().GetRoot())
+
+	// Importing again is a no-op as the blocks are already stored.
+	err = fresh.ImportChain(bytes.NewReader(archive))
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), fresh.blocks.Len())
+
+	buffer.Reset()
+	err = fresh.ExportChain(buffer)
+	require.NoError(t, err)
+	require.Equal(t, archive, buffer.Bytes())
+}
+
+func TestService_ExportChain(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.blocks = blockstore.NewInMemory()
+
+	err := srvc.ExportChain(new(bytes.Buffer))
+	require.EqualError(t, err, "reading genesis: missing genesis block")
+
+	genesis, err := types.NewGenesis(authority.New(nil, nil))
+	require.NoError(t, err)
+
+	srvc.genesis.Set(genesis)
+
+	sigs := types.WithSignatures(fake.Signature{}, fake.Signature{})
+	srvc.blocks.Store(makeBlock(t, genesis.GetHash(), sigs))
+
+	buffer := new(bytes.Buffer)
+
+	err = srvc.ExportChain(buffer)
+	require.NoError(t, err)
+
+	ar := archiveReader{r: buffer}
+	require.NoError(t, ar.readHeader())
+
+	for i := 0; i < 2; i++ {
+		_, err = ar.next()
+		require.NoError(t, err)
+	}
+
+	_, err = ar.next()
+	require.Equal(t, io.EOF, err)
+
+	err = srvc.ExportChain(badWriter{})
+	require.EqualError(t, err, fake.Err("writing header: write failed"))
+
+	err = srvc.ExportChain(badWriter{counter: fake.NewCounter(1)})
+	require.EqualError(t, err, fake.Err("writing genesis: write failed"))
+
+	err = srvc.ExportChain(badWriter{counter: fake.NewCounter(2)})
+	require.EqualError(t, err, fake.Err("writing block 0: write failed"))
+
+	srvc.blocks = badBlockStore{length: 1}
+	err = srvc.ExportChain(new(bytes.Buffer))
+	require.EqualError(t, err, fake.Err("reading block 0"))
+
+	// A store restored from a snapshot misses the blocks before its base.
+	blocks := blockstore.NewInMemory()
+	link := makeBlock(t, genesis.GetHash(), sigs)
+	require.NoError(t, blocks.Store(link))
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithIndex(1))
+	require.NoError(t, err)
+
+	next, err := types.NewBlockLink(link.GetTo(), block, sigs)
+	require.NoError(t, err)
+	require.NoError(t, blocks.Store(next))
+
+	chain, err := blocks.GetChain()
+	require.NoError(t, err)
+
+	srvc.blocks = blockstore.NewInMemory()
+	require.NoError(t, srvc.blocks.StoreChain(chain))
+
+	buffer.Reset()
+	err = srvc.ExportChain(buffer)
+	require.EqualError(t, err,
+		"chain is incomplete: block 0: block before the base of the store")
+	require.Zero(t, buffer.Len())
+}
+
+func TestService_ImportChain(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.genesisFac = types.NewGenesisFactory(fakeRosterFac{})
+	srvc.linkFac = types.NewLinkFactory(
+		types.NewBlockFactory(simple.NewResultFactory(signed.NewTransactionFactory())),
+		fake.SignatureFactory{},
+		authority.NewChangeSetFactory(fake.AddressFactory{}, fake.PublicKeyFactory{}),
+	)
+	srvc.pbftsm = fakeSM{}
+
+	genesis, err := types.NewGenesis(authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner)))
+	require.NoError(t, err)
+
+	err = srvc.ImportChain(new(bytes.Buffer))
+	require.EqualError(t, err, "invalid archive: read failed: EOF")
+
+	err = srvc.ImportChain(bytes.NewBufferString("NOTACHAIN.."))
+	require.EqualError(t, err, "invalid archive: unknown format")
+
+	err = srvc.ImportChain(bytes.NewBuffer(append(archiveMagic, 0, 2)))
+	require.EqualError(t, err, "invalid archive: unsupported version 2")
+
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t)))
+	require.EqualError(t, err, "reading genesis: EOF")
+
+	err = srvc.ImportChain(bytes.NewBuffer(append(makeArchive(t), 0xff, 0xff, 0xff, 0xff)))
+	require.EqualError(t, err, "reading genesis: frame too big: 4294967295")
+
+	err = srvc.ImportChain(bytes.NewBuffer(append(makeArchive(t), 0, 0, 0, 1)))
+	require.EqualError(t, err, "reading genesis: read frame failed: EOF")
+
+	err = srvc.ImportChain(bytes.NewBuffer(append(makeArchive(t), 0, 0, 0, 1, 'a')))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed genesis: ")
+
+	srvc.genesisFac = fake.MessageFactory{}
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t, fake.Message{})))
+	require.EqualError(t, err, "invalid genesis 'fake.Message'")
+
+	srvc.genesisFac = types.NewGenesisFactory(fakeRosterFac{})
+
+	other, err := types.NewGenesis(authority.New(nil, nil))
+	require.NoError(t, err)
+
+	srvc.genesis.Set(other)
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t, genesis)))
+	require.EqualError(t, err, fmt.Sprintf(
+		"importing genesis: mismatch genesis '%v' != '%v'", genesis.GetHash(), other.GetHash()))
+
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.genesis.Set(genesis)
+
+	sigs := types.WithSignatures(fake.Signature{}, fake.Signature{})
+
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t, genesis)))
+	require.NoError(t, err)
+
+	link := makeBlock(t, types.Digest{}, sigs)
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t, genesis, link)))
+	require.EqualError(t, err, fmt.Sprintf(
+		"importing block 0: mismatch link '%v' != '%v'", types.Digest{}, genesis.GetHash()))
+
+	link = makeBlock(t, genesis.GetHash(), sigs)
+	srvc.pbftsm = fakeSM{err: fake.GetError()}
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t, genesis, link)))
+	require.EqualError(t, err, fake.Err("importing block 0: catch up failed"))
+
+	srvc.pbftsm = fakeSM{}
+	srvc.blocks.Store(makeBlock(t, genesis.GetHash(), sigs))
+	err = srvc.ImportChain(bytes.NewBuffer(makeArchive(t, genesis, link)))
+	require.NoError(t, err)
+
+	err = srvc.ImportChain(bytes.NewBuffer(append(makeArchive(t, genesis), 0, 0, 0, 1, 'a')))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed block: ")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+// makeArchive returns the header of an archive followed by the frames of the
+// messages.
+func makeArchive(t *testing.T, msgs ...serde.Message) []byte {
+	buffer := new(bytes.Buffer)
+	aw := archiveWriter{w: buffer, context: json.NewContext()}
+
+	require.NoError(t, aw.writeHeader())
+
+	for _, msg := range msgs {
+		require.NoError(t, aw.write(msg))
+	}
+
+	return buffer.Bytes()
+}
+
+type badWriter struct {
+	counter *fake.Counter
+}
+
+func (w badWriter) Write(data []byte) (int, error) {
+	if w.counter.Done() {
+		return 0, fake.GetError()
+	}
+
+	w.counter.Decrease()
+
+	return len(data), nil
+}
+
+type badBlockStore struct {
+	blockstore.BlockStore
+	length uint64
+}
+
+func (s badBlockStore) Len() uint64 {
+	return s.length
+}
+
+func (s badBlockStore) GetByIndex(uint64) (types.BlockLink, error) {
+	return nil, fake.GetError()
+}
+
+func (s badBlockStore) Last() (types.BlockLink, error) {
+	return nil, fake.GetError()
+}
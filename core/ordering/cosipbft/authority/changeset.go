@@ -27,6 +27,12 @@ type RosterChangeSet struct {
 	remove  []uint
 	addrs   []mino.Address
 	pubkeys []crypto.PublicKey
+	// weights is nil when the new participants have the default weight.
+	weights []uint32
+	rotate  []uint
+	rotkeys []crypto.PublicKey
+	reweigh []uint
+	rewvals []uint32
 }
 
 // NewChangeSet creates a new empty change set.
@@ -45,26 +51,94 @@ func (set *RosterChangeSet) GetNewAddresses() []mino.Address {
 	return append([]mino.Address{}, set.addrs...)
 }
 
+// GetWeights returns the list of weights of the new participants, in the same
+// order as the addresses.
+func (set *RosterChangeSet) GetWeights() []uint32 {
+	weights := make([]uint32, len(set.addrs))
+	for i := range weights {
+		weights[i] = DefaultWeight
+
+		if i < len(set.weights) {
+			weights[i] = set.weights[i]
+		}
+	}
+
+	return weights
+}
+
 // GetRemoveIndices returns the list of indices to remove from the authority.
 func (set *RosterChangeSet) GetRemoveIndices() []uint {
 	return append([]uint{}, set.remove...)
 }
 
+// GetRotateIndices implements authority.ChangeSet. It returns the list of
+// indices of the members that have their public key replaced.
+func (set *RosterChangeSet) GetRotateIndices() []uint {
+	return append([]uint{}, set.rotate...)
+}
+
+// GetRotatePublicKeys returns the list of replacing public keys, in the same
+// order as the indices.
+func (set *RosterChangeSet) GetRotatePublicKeys() []crypto.PublicKey {
+	return append([]crypto.PublicKey{}, set.rotkeys...)
+}
+
+// GetWeighIndices returns the list of indices of the members that have their
+// weight replaced.
+func (set *RosterChangeSet) GetWeighIndices() []uint {
+	return append([]uint{}, set.reweigh...)
+}
+
+// GetWeighValues returns the list of replacing weights, in the same order as
+// the indices.
+func (set *RosterChangeSet) GetWeighValues() []uint32 {
+	return append([]uint32{}, set.rewvals...)
+}
+
 // Remove appends the index to the list of removals.
 func (set *RosterChangeSet) Remove(index uint) {
 	set.remove = append(set.remove, index)
 }
 
-// Add appends the address and the public key to the list of new participants.
+// Add appends the address and the public key to the list of new participants
+// with the default weight.
 func (set *RosterChangeSet) Add(addr mino.Address, pubkey crypto.PublicKey) {
+	set.AddWeighted(addr, pubkey, DefaultWeight)
+}
+
+// AddWeighted appends the address, the public key and the weight to the list
+// of new participants.
+func (set *RosterChangeSet) AddWeighted(addr mino.Address, pubkey crypto.PublicKey, weight uint32) {
+	if weight != DefaultWeight && set.weights == nil {
+		set.weights = set.GetWeights()
+	}
+
 	set.addrs = append(set.addrs, addr)
 	set.pubkeys = append(set.pubkeys, pubkey)
+
+	if set.weights != nil {
+		set.weights = append(set.weights, weight)
+	}
+}
+
+// Rotate appends the index and the public key to the list of members that
+// have their public key replaced. The address of the member is kept.
+func (set *RosterChangeSet) Rotate(index uint, pubkey crypto.PublicKey) {
+	set.rotate = append(set.rotate, index)
+	set.rotkeys = append(set.rotkeys, pubkey)
+}
+
+// Weigh appends the index and the weight to the list of members that have their
+// weight replaced.
+func (set *RosterChangeSet) Weigh(index uint, weight uint32) {
+	set.reweigh = append(set.reweigh, index)
+	set.rewvals = append(set.rewvals, weight)
 }
 
 // NumChanges implements authority.ChangeSet. It returns the number of changes
 // that is applied with the change set.
 func (set *RosterChangeSet) NumChanges() int {
-	return len(set.remove) + len(set.addrs)
+	return len(set.remove) + len(set.addrs) + len(set.rotate) + len(set.reweigh)
 }
 
 // Serialize implements serde.Message. It returns the serialized data for this
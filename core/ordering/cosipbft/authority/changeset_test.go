@@ -38,6 +38,35 @@ func TestChangeSet_GetRemoveIndices(t *testing.T) {
 	require.Len(t, cset.GetRemoveIndices(), 1)
 }
 
+func TestChangeSet_GetRotations(t *testing.T) {
+	cset := NewChangeSet()
+	require.Len(t, cset.GetRotateIndices(), 0)
+	require.Len(t, cset.GetRotatePublicKeys(), 0)
+
+	cset.Rotate(2, fake.PublicKey{})
+	require.Equal(t, []uint{2}, cset.GetRotateIndices())
+	require.Len(t, cset.GetRotatePublicKeys(), 1)
+}
+
+func TestChangeSet_GetWeights(t *testing.T) {
+	cset := NewChangeSet()
+	require.Len(t, cset.GetWeights(), 0)
+	require.Len(t, cset.GetWeighIndices(), 0)
+	require.Len(t, cset.GetWeighValues(), 0)
+
+	cset.Add(fake.NewAddress(0), fake.PublicKey{})
+	require.Equal(t, []uint32{1}, cset.GetWeights())
+	require.Nil(t, cset.weights)
+
+	cset.AddWeighted(fake.NewAddress(1), fake.PublicKey{}, 3)
+	cset.Add(fake.NewAddress(2), fake.PublicKey{})
+	require.Equal(t, []uint32{1, 3, 1}, cset.GetWeights())
+
+	cset.Weigh(2, 0)
+	require.Equal(t, []uint{2}, cset.GetWeighIndices())
+	require.Equal(t, []uint32{0}, cset.GetWeighValues())
+}
+
 func TestChangeSet_NumChanges(t *testing.T) {
 	cset := NewChangeSet()
 	require.Equal(t, 0, cset.NumChanges())
@@ -47,6 +76,12 @@ func TestChangeSet_NumChanges(t *testing.T) {
 
 	cset.Add(fake.NewAddress(0), fake.PublicKey{})
 	require.Equal(t, 2, cset.NumChanges())
+
+	cset.Rotate(1, fake.PublicKey{})
+	require.Equal(t, 3, cset.NumChanges())
+
+	cset.Weigh(1, 2)
+	require.Equal(t, 4, cset.NumChanges())
 }
 
 func TestChangeSet_Serialize(t *testing.T) {
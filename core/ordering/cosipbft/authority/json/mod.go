@@ -20,6 +20,8 @@ func init() {
 type Player struct {
 	Address   []byte
 	PublicKey json.RawMessage
+	// Weight is omitted for the default weight.
+	Weight *uint32 `json:",omitempty"`
 }
 
 // ChangeSet is a JSON message of the change set of an authority.
@@ -27,6 +29,11 @@ type ChangeSet struct {
 	Remove     []uint
 	Addresses  [][]byte
 	PublicKeys []json.RawMessage
+	Weights    []uint32          `json:",omitempty"`
+	Rotate     []uint            `json:",omitempty"`
+	RotateKeys []json.RawMessage `json:",omitempty"`
+	Weigh      []uint            `json:",omitempty"`
+	WeighVals  []uint32          `json:",omitempty"`
 }
 
 // Address is a JSON message for an address.
@@ -69,10 +76,35 @@ func (f changeSetFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, e
 		pubkeys = append(pubkeys, raw)
 	}
 
+	var rotkeys []json.RawMessage
+	for _, pubkey := range cset.GetRotatePublicKeys() {
+		raw, err := pubkey.Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't serialize rotate key: %v", err)
+		}
+
+		rotkeys = append(rotkeys, raw)
+	}
+
+	// The weights are only written when one of them is not the default, so
+	// that a change set without weights keeps the same encoding.
+	var weights []uint32
+	for _, weight := range cset.GetWeights() {
+		if weight != authority.DefaultWeight {
+			weights = cset.GetWeights()
+			break
+		}
+	}
+
 	m := ChangeSet{
 		Remove:     cset.GetRemoveIndices(),
 		Addresses:  addrs,
 		PublicKeys: pubkeys,
+		Weights:    weights,
+		Rotate:     cset.GetRotateIndices(),
+		RotateKeys: rotkeys,
+		Weigh:      cset.GetWeighIndices(),
+		WeighVals:  cset.GetWeighValues(),
 	}
 
 	data, err := ctx.Marshal(m)
@@ -112,6 +144,11 @@ func (f changeSetFormat) Decode(ctx serde.Context, data []byte) (serde.Message,
 		cset.Remove(index)
 	}
 
+	if len(m.Weights) > 0 && len(m.Weights) != len(m.Addresses) {
+		return nil, xerrors.Errorf("mismatch weights length %d != %d",
+			len(m.Weights), len(m.Addresses))
+	}
+
 	for i, rawAddr := range m.Addresses {
 		addr := addrFac.FromText(rawAddr)
 
@@ -120,7 +157,35 @@ func (f changeSetFormat) Decode(ctx serde.Context, data []byte) (serde.Message,
 			return nil, xerrors.Errorf("couldn't deserialize public key: %v", err)
 		}
 
-		cset.Add(addr, pubkey)
+		weight := uint32(authority.DefaultWeight)
+		if len(m.Weights) > 0 {
+			weight = m.Weights[i]
+		}
+
+		cset.AddWeighted(addr, pubkey, weight)
+	}
+
+	if len(m.Rotate) != len(m.RotateKeys) {
+		return nil, xerrors.Errorf("mismatch rotate length %d != %d",
+			len(m.Rotate), len(m.RotateKeys))
+	}
+
+	for i, index := range m.Rotate {
+		pubkey, err := pkFac.PublicKeyOf(ctx, m.RotateKeys[i])
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't deserialize rotate key: %v", err)
+		}
+
+		cset.Rotate(index, pubkey)
+	}
+
+	if len(m.Weigh) != len(m.WeighVals) {
+		return nil, xerrors.Errorf("mismatch weigh length %d != %d",
+			len(m.Weigh), len(m.WeighVals))
+	}
+
+	for i, index := range m.Weigh {
+		cset.Weigh(index, m.WeighVals[i])
 	}
 
 	return cset, nil
@@ -159,6 +224,11 @@ func (f rosterFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, erro
 			Address:   addr,
 			PublicKey: pubkey,
 		}
+
+		weight := roster.GetWeight(i)
+		if weight != authority.DefaultWeight {
+			players[i].Weight = &weight
+		}
 	}
 
 	m := Roster(players)
@@ -196,10 +266,16 @@ func (f rosterFormat) Decode(ctx serde.Context, data []byte) (serde.Message, err
 
 	addrs := make([]mino.Address, len(m))
 	pubkeys := make([]crypto.PublicKey, len(m))
+	weights := make([]uint32, len(m))
 
 	for i, player := range m {
 		addrs[i] = addrFac.FromText(player.Address)
 
+		weights[i] = authority.DefaultWeight
+		if player.Weight != nil {
+			weights[i] = *player.Weight
+		}
+
 		pubkey, err := pkFac.PublicKeyOf(ctx, player.PublicKey)
 		if err != nil {
 			return nil, xerrors.Errorf("couldn't deserialize public key: %v", err)
@@ -208,5 +284,5 @@ func (f rosterFormat) Decode(ctx serde.Context, data []byte) (serde.Message, err
 		pubkeys[i] = pubkey
 	}
 
-	return authority.New(addrs, pubkeys), nil
+	return authority.New(addrs, pubkeys).WithWeights(weights), nil
 }
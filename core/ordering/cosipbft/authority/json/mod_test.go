@@ -39,6 +39,27 @@ func TestChangeSetFormat_Encode(t *testing.T) {
 	cset.Add(fake.NewBadAddress(), fake.PublicKey{})
 	_, err = format.Encode(ctx, cset)
 	require.EqualError(t, err, fake.Err("couldn't serialize address"))
+
+	cset = authority.NewChangeSet()
+	cset.Rotate(1, fake.PublicKey{})
+	data, err = format.Encode(ctx, cset)
+	require.NoError(t, err)
+	expected = `{"Remove":[],"Addresses":[],"PublicKeys":[],"Rotate":[1],"RotateKeys":[{}]}`
+	require.Equal(t, expected, string(data))
+
+	cset = authority.NewChangeSet()
+	cset.AddWeighted(fake.NewAddress(2), fake.PublicKey{}, 3)
+	cset.Weigh(1, 0)
+	data, err = format.Encode(ctx, cset)
+	require.NoError(t, err)
+	expected = `{"Remove":[],"Addresses":["AgAAAA=="],"PublicKeys":[{}],` +
+		`"Weights":[3],"Weigh":[1],"WeighVals":[0]}`
+	require.Equal(t, expected, string(data))
+
+	cset = authority.NewChangeSet()
+	cset.Rotate(0, fake.NewBadPublicKey())
+	_, err = format.Encode(ctx, cset)
+	require.EqualError(t, err, fake.Err("couldn't serialize rotate key"))
 }
 
 func TestChangeSetFormat_Decode(t *testing.T) {
@@ -63,10 +84,39 @@ func TestChangeSetFormat_Decode(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, cset, msg)
 
+	cset = authority.NewChangeSet()
+	cset.Rotate(2, fake.PublicKey{})
+
+	msg, err = format.Decode(ctx, []byte(`{"Rotate":[2],"RotateKeys":[{}]}`))
+	require.NoError(t, err)
+	require.Equal(t, cset, msg)
+
+	_, err = format.Decode(ctx, []byte(`{"Rotate":[2]}`))
+	require.EqualError(t, err, "mismatch rotate length 1 != 0")
+
+	cset = authority.NewChangeSet()
+	cset.AddWeighted(fake.NewAddress(0), fake.PublicKey{}, 3)
+	cset.Weigh(1, 0)
+
+	msg, err = format.Decode(ctx, []byte(
+		`{"Addresses":[[]],"PublicKeys":[{}],"Weights":[3],"Weigh":[1],"WeighVals":[0]}`))
+	require.NoError(t, err)
+	require.Equal(t, cset, msg)
+
+	_, err = format.Decode(ctx, []byte(`{"Addresses":[[]],"PublicKeys":[{}],"Weights":[1,2]}`))
+	require.EqualError(t, err, "mismatch weights length 2 != 1")
+
+	_, err = format.Decode(ctx, []byte(`{"Weigh":[2]}`))
+	require.EqualError(t, err, "mismatch weigh length 1 != 0")
+
 	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
 	require.EqualError(t, err, fake.Err("couldn't deserialize change set"))
 
 	badCtx := serde.WithFactory(ctx, authority.PubKeyFac{}, fake.NewBadPublicKeyFactory())
+	_, err = format.Decode(badCtx, []byte(`{"Rotate":[2],"RotateKeys":[{}]}`))
+	require.EqualError(t, err, fake.Err("couldn't deserialize rotate key"))
+
+	badCtx = serde.WithFactory(ctx, authority.PubKeyFac{}, fake.NewBadPublicKeyFactory())
 	_, err = format.Decode(badCtx, []byte(`{"Addresses":[[]],"PublicKeys":[{}]}`))
 	require.EqualError(t, err, fake.Err("couldn't deserialize public key"))
 
@@ -89,6 +139,10 @@ func TestRosterFormat_Encode(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, `[{"Address":"AAAAAA==","PublicKey":{}}]`, string(data))
 
+	data, err = format.Encode(ctx, ro.WithWeights([]uint32{0}))
+	require.NoError(t, err)
+	require.Equal(t, `[{"Address":"AAAAAA==","PublicKey":{},"Weight":0}]`, string(data))
+
 	_, err = format.Encode(fake.NewContext(), fake.Message{})
 	require.EqualError(t, err, "unsupported message of type 'fake.Message'")
 
@@ -114,6 +168,11 @@ func TestRosterFormat_Decode(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, authority.FromAuthority(fake.NewAuthority(1, fake.NewSigner)), ro)
 
+	ro, err = format.Decode(ctx, []byte(`[{},{"Weight":4}]`))
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), ro.(authority.Roster).GetWeight(0))
+	require.Equal(t, uint32(4), ro.(authority.Roster).GetWeight(1))
+
 	_, err = format.Decode(fake.NewBadContext(), []byte(`[]`))
 	require.EqualError(t, err, fake.Err("couldn't deserialize roster"))
 
@@ -25,6 +25,10 @@ type ChangeSet interface {
 
 	// GetNewAddresses returns the list of addresses for the new members.
 	GetNewAddresses() []mino.Address
+
+	// GetRotateIndices returns the list of indices of the members that will
+	// have their public key replaced.
+	GetRotateIndices() []uint
 }
 
 // ChangeSetFactory is the factory to deserialize change sets.
@@ -42,7 +46,8 @@ type Authority interface {
 	crypto.CollectiveAuthority
 
 	// Apply must apply the change set to the collective authority. It should
-	// first remove, then add the new players.
+	// first replace the public keys, then remove, and finally add the new
+	// players.
 	Apply(ChangeSet) Authority
 
 	// Diff should return the change set to apply to get the given authority.
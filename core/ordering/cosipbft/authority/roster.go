@@ -6,6 +6,7 @@
 package authority
 
 import (
+	"encoding/binary"
 	"io"
 
 	"go.dedis.ch/dela"
@@ -73,12 +74,19 @@ func (i *publicKeyIterator) GetNext() crypto.PublicKey {
 	return nil
 }
 
-// Roster contains a list of participants with their addresses and public keys.
+// DefaultWeight is the weight of a member that has not been given one.
+const DefaultWeight = 1
+
+// Roster contains a list of participants with their addresses, public keys and
+// weights. The weight of a member is its share of the rounds it leads when the
+// chain uses a weighted leader policy.
 //
 // - implements authority.Authority
 type Roster struct {
 	addrs   []mino.Address
 	pubkeys []crypto.PublicKey
+	// weights is nil when every member has the default weight.
+	weights []uint32
 }
 
 // New creates a new roster from the list of addresses and public keys.
@@ -104,8 +112,37 @@ func FromAuthority(authority crypto.CollectiveAuthority) Roster {
 	return New(addrs, pubkeys)
 }
 
+// WithWeights returns a copy of the roster where the members have the given
+// weights, in the same order. The members without a weight have the default
+// one.
+func (r Roster) WithWeights(weights []uint32) Roster {
+	all := make([]uint32, r.Len())
+	for i := range all {
+		all[i] = DefaultWeight
+
+		if i < len(weights) {
+			all[i] = weights[i]
+		}
+	}
+
+	r.weights = compactWeights(all)
+
+	return r
+}
+
+// GetWeight returns the weight of the member at the given index.
+func (r Roster) GetWeight(index int) uint32 {
+	if index < 0 || index >= len(r.weights) {
+		return DefaultWeight
+	}
+
+	return r.weights[index]
+}
+
 // Fingerprint implements serde.Fingerprinter. It marshals the roster and writes
-// the result in the given writer.
+// the result in the given writer. The weights are written after the members
+// only if one of them is not the default, so that the fingerprint of a roster
+// without weights stays the same.
 func (r Roster) Fingerprint(w io.Writer) error {
 	for i, addr := range r.addrs {
 		data, err := addr.MarshalText()
@@ -129,6 +166,20 @@ func (r Roster) Fingerprint(w io.Writer) error {
 		}
 	}
 
+	if r.weights == nil {
+		return nil
+	}
+
+	buffer := make([]byte, 4*len(r.weights))
+	for i, weight := range r.weights {
+		binary.LittleEndian.PutUint32(buffer[i*4:], weight)
+	}
+
+	_, err := w.Write(buffer)
+	if err != nil {
+		return xerrors.Errorf("couldn't write weights: %v", err)
+	}
+
 	return nil
 }
 
@@ -141,17 +192,23 @@ func (r Roster) Take(updaters ...mino.FilterUpdater) mino.Players {
 		pubkeys: make([]crypto.PublicKey, len(filter.Indices)),
 	}
 
+	weights := make([]uint32, len(filter.Indices))
+
 	for i, k := range filter.Indices {
 		newRoster.addrs[i] = r.addrs[k]
 		newRoster.pubkeys[i] = r.pubkeys[k]
+		weights[i] = r.GetWeight(k)
 	}
 
+	newRoster.weights = compactWeights(weights)
+
 	return newRoster
 }
 
 // Apply implements authority.Authority. It returns a new authority after
 // applying the change set. The removals must be sorted by descending order and
-// unique or the behaviour will be undefined.
+// unique or the behaviour will be undefined. Public keys are replaced before
+// the removals are applied, and so are the weights.
 func (r Roster) Apply(in ChangeSet) Authority {
 	changeset, ok := in.(*RosterChangeSet)
 	if !ok {
@@ -161,29 +218,49 @@ func (r Roster) Apply(in ChangeSet) Authority {
 
 	addrs := make([]mino.Address, r.Len())
 	pubkeys := make([]crypto.PublicKey, r.Len())
+	weights := make([]uint32, r.Len())
 
 	for i, addr := range r.addrs {
 		addrs[i] = addr
 		pubkeys[i] = r.pubkeys[i]
+		weights[i] = r.GetWeight(i)
+	}
+
+	// Rotations refer to the indices of the current roster, so they are
+	// applied before the removals shift the members.
+	for i, index := range changeset.rotate {
+		if int(index) < len(pubkeys) {
+			pubkeys[index] = changeset.rotkeys[i]
+		}
+	}
+
+	for i, index := range changeset.reweigh {
+		if int(index) < len(weights) {
+			weights[index] = changeset.rewvals[i]
+		}
 	}
 
 	for _, i := range changeset.remove {
 		if int(i) < len(addrs) {
 			addrs = append(addrs[:i], addrs[i+1:]...)
 			pubkeys = append(pubkeys[:i], pubkeys[i+1:]...)
+			weights = append(weights[:i], weights[i+1:]...)
 		}
 	}
 
 	roster := Roster{
 		addrs:   append(addrs, changeset.addrs...),
 		pubkeys: append(pubkeys, changeset.pubkeys...),
+		weights: compactWeights(append(weights, changeset.GetWeights()...)),
 	}
 
 	return roster
 }
 
 // Diff implements authority.Authority. It returns the change set that must be
-// applied to the current authority to get the given one.
+// applied to the current authority to get the given one. A member that keeps
+// its address but has a different public key is reported as a rotation, and
+// one with a different weight as a new weight.
 func (r Roster) Diff(o Authority) ChangeSet {
 	changeset := NewChangeSet()
 
@@ -197,6 +274,15 @@ func (r Roster) Diff(o Authority) ChangeSet {
 	for i < len(r.addrs) || k < len(other.addrs) {
 		if i < len(r.addrs) && k < len(other.addrs) {
 			if r.addrs[i].Equal(other.addrs[k]) {
+				if !r.pubkeys[i].Equal(other.pubkeys[k]) {
+					changeset.rotate = append(changeset.rotate, uint(i))
+					changeset.rotkeys = append(changeset.rotkeys, other.pubkeys[k])
+				}
+
+				if r.GetWeight(i) != other.GetWeight(k) {
+					changeset.Weigh(uint(i), other.GetWeight(k))
+				}
+
 				i++
 				k++
 			} else {
@@ -207,8 +293,7 @@ func (r Roster) Diff(o Authority) ChangeSet {
 			changeset.remove = append(changeset.remove, uint(i))
 			i++
 		} else {
-			changeset.addrs = append(changeset.addrs, other.addrs[k])
-			changeset.pubkeys = append(changeset.pubkeys, other.pubkeys[k])
+			changeset.AddWeighted(other.addrs[k], other.pubkeys[k], other.GetWeight(k))
 			k++
 		}
 	}
@@ -259,6 +344,18 @@ func (r Roster) Serialize(ctx serde.Context) ([]byte, error) {
 	return data, nil
 }
 
+// compactWeights returns the weights, or nil if they are all the default one
+// so that the rosters without weights have a single representation.
+func compactWeights(weights []uint32) []uint32 {
+	for _, weight := range weights {
+		if weight != DefaultWeight {
+			return weights
+		}
+	}
+
+	return nil
+}
+
 // rosterFac is a factory to deserialize authority.
 //
 // - implements authority.Factory
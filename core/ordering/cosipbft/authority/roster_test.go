@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/crypto/bls"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/mino"
@@ -117,6 +118,37 @@ func TestRoster_Fingerprint(t *testing.T) {
 
 	err = roster.Fingerprint(fake.NewBadHashWithDelay(1))
 	require.EqualError(t, err, fake.Err("couldn't write public key"))
+
+	// The weights are only part of the fingerprint when one of them is not
+	// the default.
+	out.Reset()
+	err = roster.WithWeights([]uint32{1, 1}).Fingerprint(out)
+	require.NoError(t, err)
+	require.Equal(t, "\x00\x00\x00\x00PK\x01\x00\x00\x00PK", out.String())
+
+	out.Reset()
+	err = roster.WithWeights([]uint32{2}).Fingerprint(out)
+	require.NoError(t, err)
+	require.Equal(t, "\x00\x00\x00\x00PK\x01\x00\x00\x00PK"+
+		"\x02\x00\x00\x00\x01\x00\x00\x00", out.String())
+
+	err = roster.WithWeights([]uint32{2}).Fingerprint(fake.NewBadHashWithDelay(4))
+	require.EqualError(t, err, fake.Err("couldn't write weights"))
+}
+
+func TestRoster_GetWeight(t *testing.T) {
+	roster := FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+	require.Equal(t, uint32(1), roster.GetWeight(0))
+	require.Nil(t, roster.weights)
+
+	roster = roster.WithWeights([]uint32{0, 5})
+	require.Equal(t, uint32(0), roster.GetWeight(0))
+	require.Equal(t, uint32(5), roster.GetWeight(1))
+	require.Equal(t, uint32(1), roster.GetWeight(2))
+	require.Equal(t, uint32(1), roster.GetWeight(-1))
+	require.Equal(t, uint32(1), roster.GetWeight(3))
+
+	require.Nil(t, roster.WithWeights(nil).weights)
 }
 
 func TestRoster_Take(t *testing.T) {
@@ -127,6 +159,12 @@ func TestRoster_Take(t *testing.T) {
 
 	roster2 = roster.Take(mino.RangeFilter(1, 3))
 	require.Equal(t, 2, roster2.Len())
+
+	roster2 = roster.WithWeights([]uint32{1, 1, 4}).Take(mino.RangeFilter(1, 3))
+	require.Equal(t, []uint32{1, 4}, roster2.(Roster).weights)
+
+	roster2 = roster.WithWeights([]uint32{1, 1, 4}).Take(mino.RangeFilter(0, 2))
+	require.Nil(t, roster2.(Roster).weights)
 }
 
 func TestRoster_Apply(t *testing.T) {
@@ -146,6 +184,36 @@ func TestRoster_Apply(t *testing.T) {
 
 	roster3 := roster2.Apply(cset)
 	require.Equal(t, roster.Len()-1, roster3.Len())
+
+	pubkey := bls.NewSigner().GetPublicKey()
+
+	cset = NewChangeSet()
+	cset.Rotate(2, pubkey)
+	cset.Rotate(5, pubkey)
+	cset.Remove(0)
+
+	roster4 := roster.Apply(cset)
+	require.Equal(t, roster.Len()-1, roster4.Len())
+	require.Equal(t, pubkey, roster4.(Roster).pubkeys[1])
+	require.Equal(t, roster.addrs[2], roster4.(Roster).addrs[1])
+	require.NotEqual(t, pubkey, roster.pubkeys[2])
+
+	// The weights follow the members when some are removed, and the new
+	// members have the weights of the change set.
+	cset = NewChangeSet()
+	cset.Weigh(2, 3)
+	cset.Weigh(5, 3)
+	cset.Remove(0)
+	cset.AddWeighted(fake.NewAddress(5), fake.PublicKey{}, 0)
+	cset.Add(fake.NewAddress(6), fake.PublicKey{})
+
+	roster5 := roster.WithWeights([]uint32{2}).Apply(cset)
+	require.Equal(t, []uint32{1, 3, 0, 1}, roster5.(Roster).weights)
+
+	cset = NewChangeSet()
+	cset.Weigh(0, 1)
+
+	require.Nil(t, roster.WithWeights([]uint32{2}).Apply(cset).(Roster).weights)
 }
 
 func TestRoster_Diff(t *testing.T) {
@@ -167,6 +235,28 @@ func TestRoster_Diff(t *testing.T) {
 	require.Len(t, diff.addrs, 2)
 	require.Len(t, diff.pubkeys, 2)
 
+	roster5 := FromAuthority(fake.NewAuthority(3, bls.Generate))
+	roster6 := roster5.Apply(NewChangeSet()).(Roster)
+	roster6.pubkeys[1] = bls.NewSigner().GetPublicKey()
+	diff = roster5.Diff(roster6).(*RosterChangeSet)
+	require.Equal(t, []uint{1}, diff.rotate)
+	require.Equal(t, []crypto.PublicKey{roster6.pubkeys[1]}, diff.rotkeys)
+	require.Equal(t, 1, diff.NumChanges())
+	require.Equal(t, roster6, roster5.Apply(diff))
+
+	roster7 := roster5.WithWeights([]uint32{1, 4})
+	diff = roster5.Diff(roster7).(*RosterChangeSet)
+	require.Equal(t, []uint{1}, diff.reweigh)
+	require.Equal(t, []uint32{4}, diff.rewvals)
+	require.Equal(t, 1, diff.NumChanges())
+	require.Equal(t, roster7, roster5.Apply(diff))
+
+	roster8 := FromAuthority(fake.NewAuthority(4, fake.NewSigner)).
+		WithWeights([]uint32{1, 1, 1, 2})
+	diff = roster1.Diff(roster8).(*RosterChangeSet)
+	require.Equal(t, []uint32{2}, diff.GetWeights())
+	require.Equal(t, roster8, roster1.Apply(diff))
+
 	diff = roster1.Diff((Authority)(nil)).(*RosterChangeSet)
 	require.Equal(t, NewChangeSet(), diff)
 }
@@ -0,0 +1,30 @@
+// This file contains the helpers to use a chain as the base of a store.
+
+package blockstore
+
+import (
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"golang.org/x/xerrors"
+)
+
+// splitChain returns the forward links before the latest block of the chain,
+// and the link to the latest block. It makes sure that the chain has a link
+// for each block from the genesis.
+func splitChain(chain types.Chain) ([]types.Link, types.BlockLink, error) {
+	links := chain.GetLinks()
+	if len(links) == 0 {
+		return nil, nil, xerrors.New("chain is empty")
+	}
+
+	last, ok := links[len(links)-1].(types.BlockLink)
+	if !ok {
+		return nil, nil, xerrors.Errorf("invalid last link '%T'", links[len(links)-1])
+	}
+
+	if last.GetBlock().GetIndex() != uint64(len(links)-1) {
+		return nil, nil, xerrors.Errorf("mismatch index %d != %d",
+			last.GetBlock().GetIndex(), len(links)-1)
+	}
+
+	return links[:len(links)-1], last, nil
+}
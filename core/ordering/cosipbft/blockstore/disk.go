@@ -24,6 +24,7 @@ type cachedData struct {
 	sync.Mutex
 
 	length  uint64
+	base    uint64
 	last    types.BlockLink
 	indices map[types.Digest]uint64
 }
@@ -34,11 +35,13 @@ type cachedData struct {
 type InDisk struct {
 	*cachedData
 
-	db      kv.DB
-	bucket  []byte
-	context serde.Context
-	fac     types.LinkFactory
-	watcher core.Observable
+	db         kv.DB
+	bucket     []byte
+	txBucket   []byte
+	linkBucket []byte
+	context    serde.Context
+	fac        types.LinkFactory
+	watcher    core.Observable
 
 	txn store.Transaction
 }
@@ -46,11 +49,13 @@ type InDisk struct {
 // NewDiskStore creates a new persistent storage.
 func NewDiskStore(db kv.DB, fac types.LinkFactory) *InDisk {
 	return &InDisk{
-		db:      db,
-		bucket:  []byte("blocks"),
-		context: json.NewContext(),
-		fac:     fac,
-		watcher: core.NewWatcher(),
+		db:         db,
+		bucket:     []byte("blocks"),
+		txBucket:   []byte("transactions"),
+		linkBucket: []byte("links"),
+		context:    json.NewContext(),
+		fac:        fac,
+		watcher:    core.NewWatcher(),
 		cachedData: &cachedData{
 			indices: make(map[types.Digest]uint64),
 		},
@@ -66,23 +71,57 @@ func (s *InDisk) Len() uint64 {
 	return s.length
 }
 
-// Load reads the database to rebuild the cache.
+// Load reads the database to rebuild the cache. The index of the transactions
+// is also rebuilt if it is missing, for instance for a database created by a
+// previous version.
 func (s *InDisk) Load() error {
 	s.Lock()
 	defer s.Unlock()
 
-	return s.doView(func(tx kv.ReadableTx) error {
+	return s.doUpdate(func(tx kv.WritableTx) error {
 		bucket := tx.GetBucket(s.bucket)
 		if bucket == nil {
 			return nil
 		}
 
+		// The forward links are only present when the store has been created
+		// from a chain, and they are counted as blocks.
+		linkBucket := tx.GetBucket(s.linkBucket)
+		if linkBucket != nil {
+			err := linkBucket.Scan([]byte{}, func(key, value []byte) error {
+				s.base++
+				s.length++
+
+				return nil
+			})
+
+			if err != nil {
+				return xerrors.Errorf("while scanning links: %v", err)
+			}
+		}
+
+		var txBucket kv.Bucket
+		if tx.GetBucket(s.txBucket) == nil {
+			var err error
+			txBucket, err = tx.GetBucketOrCreate(s.txBucket)
+			if err != nil {
+				return xerrors.Errorf("bucket failed: %v", err)
+			}
+		}
+
 		err := bucket.Scan([]byte{}, func(key, value []byte) error {
 			link, err := s.fac.BlockLinkOf(s.context, value)
 			if err != nil {
 				return xerrors.Errorf("malformed block: %v", err)
 			}
 
+			if txBucket != nil {
+				err = s.indexTransactions(link, txBucket)
+				if err != nil {
+					return xerrors.Errorf("indexing transactions: %v", err)
+				}
+			}
+
 			s.length++
 			s.last = link
 			s.indices[link.GetBlock().GetHash()] = link.GetBlock().GetIndex()
@@ -130,6 +169,16 @@ func (s *InDisk) Store(link types.BlockLink) error {
 			return xerrors.Errorf("while writing: %v", err)
 		}
 
+		txBucket, err := tx.GetBucketOrCreate(s.txBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket failed: %v", err)
+		}
+
+		err = s.indexTransactions(link, txBucket)
+		if err != nil {
+			return xerrors.Errorf("indexing transactions: %v", err)
+		}
+
 		tx.OnCommit(func() {
 			s.Lock()
 
@@ -146,6 +195,84 @@ func (s *InDisk) Store(link types.BlockLink) error {
 	})
 }
 
+// StoreChain implements blockstore.BlockStore. It stores the latest block of
+// the chain and the forward links of the previous ones, if the store is empty.
+func (s *InDisk) StoreChain(chain types.Chain) error {
+	s.Lock()
+	length := s.length
+	s.Unlock()
+
+	if length > 0 {
+		return xerrors.New("store is not empty")
+	}
+
+	prevs, last, err := splitChain(chain)
+	if err != nil {
+		return xerrors.Errorf("invalid chain: %v", err)
+	}
+
+	return s.doUpdate(func(tx kv.WritableTx) error {
+		linkBucket, err := tx.GetBucketOrCreate(s.linkBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket failed: %v", err)
+		}
+
+		for i, link := range prevs {
+			data, err := link.Serialize(s.context)
+			if err != nil {
+				return xerrors.Errorf("failed to serialize link: %v", err)
+			}
+
+			err = linkBucket.Set(s.makeKey(uint64(i)), data)
+			if err != nil {
+				return xerrors.Errorf("while writing link: %v", err)
+			}
+		}
+
+		data, err := last.Serialize(s.context)
+		if err != nil {
+			return xerrors.Errorf("failed to serialize: %v", err)
+		}
+
+		bucket, err := tx.GetBucketOrCreate(s.bucket)
+		if err != nil {
+			return xerrors.Errorf("bucket failed: %v", err)
+		}
+
+		index := last.GetBlock().GetIndex()
+
+		err = bucket.Set(s.makeKey(index), data)
+		if err != nil {
+			return xerrors.Errorf("while writing: %v", err)
+		}
+
+		txBucket, err := tx.GetBucketOrCreate(s.txBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket failed: %v", err)
+		}
+
+		err = s.indexTransactions(last, txBucket)
+		if err != nil {
+			return xerrors.Errorf("indexing transactions: %v", err)
+		}
+
+		tx.OnCommit(func() {
+			s.Lock()
+
+			s.base = index
+			s.length = index + 1
+			s.last = last
+			s.indices[last.GetBlock().GetHash()] = index
+
+			s.Unlock()
+
+			s.watcher.Notify(last)
+		})
+
+		return nil
+	})
+}
+
 // Get implements blockstore.BlockStore. It loads the block with the given
 // identifier if it exists, otherwise it returns an error.
 func (s *InDisk) Get(id types.Digest) (types.BlockLink, error) {
@@ -163,6 +290,14 @@ func (s *InDisk) Get(id types.Digest) (types.BlockLink, error) {
 // GetByIndex implements blockstore.BlockStore. It returns the block associated
 // to the index if it exists, otherwise it returns an error.
 func (s *InDisk) GetByIndex(index uint64) (link types.BlockLink, err error) {
+	s.Lock()
+	base := s.base
+	s.Unlock()
+
+	if index < base {
+		return nil, xerrors.Errorf("block %d: %w", index, ErrNoHistory)
+	}
+
 	key := s.makeKey(index)
 
 	err = s.doView(func(tx kv.ReadableTx) error {
@@ -191,6 +326,7 @@ func (s *InDisk) GetByIndex(index uint64) (link types.BlockLink, err error) {
 func (s *InDisk) GetChain() (types.Chain, error) {
 	s.Lock()
 	length := s.length
+	base := s.base
 	s.Unlock()
 
 	if length == 0 {
@@ -202,10 +338,15 @@ func (s *InDisk) GetChain() (types.Chain, error) {
 	var chain types.Chain
 
 	err := s.doView(func(tx kv.ReadableTx) error {
+		err := s.readLinks(tx, prevs[:base])
+		if err != nil {
+			return err
+		}
+
 		bucket := tx.GetBucket(s.bucket)
 
-		i := uint64(0)
-		err := bucket.Scan([]byte{}, func(key, value []byte) error {
+		i := base
+		err = bucket.Scan([]byte{}, func(key, value []byte) error {
 			link, err := s.fac.BlockLinkOf(s.context, value)
 			if err != nil {
 				return xerrors.Errorf("block malformed: %v", err)
@@ -236,6 +377,88 @@ func (s *InDisk) GetChain() (types.Chain, error) {
 	return chain, nil
 }
 
+// GetChainAt implements blockstore.BlockStore. It returns a chain to the block
+// at the given index.
+func (s *InDisk) GetChainAt(index uint64) (types.Chain, error) {
+	s.Lock()
+	length := s.length
+	base := s.base
+	s.Unlock()
+
+	if index < base {
+		return nil, xerrors.Errorf("index %d: %w", index, ErrNoHistory)
+	}
+
+	if index >= length {
+		return nil, xerrors.Errorf("index %d not found: %w", index, ErrNoBlock)
+	}
+
+	prevs := make([]types.Link, index)
+
+	var chain types.Chain
+
+	err := s.doView(func(tx kv.ReadableTx) error {
+		err := s.readLinks(tx, prevs[:base])
+		if err != nil {
+			return err
+		}
+
+		bucket := tx.GetBucket(s.bucket)
+
+		for i := base; i <= index; i++ {
+			link, err := s.fac.BlockLinkOf(s.context, bucket.Get(s.makeKey(i)))
+			if err != nil {
+				return xerrors.Errorf("block %d malformed: %v", i, err)
+			}
+
+			if i == index {
+				chain = types.NewChain(link, prevs)
+			} else {
+				prevs[i] = link.Reduce()
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("while reading database: %v", err)
+	}
+
+	return chain, nil
+}
+
+// GetReceipt implements blockstore.BlockStore. It returns the receipt of the
+// transaction from the index if it exists, otherwise it returns an error.
+func (s *InDisk) GetReceipt(id []byte) (Receipt, error) {
+	var receipt Receipt
+
+	err := s.doView(func(tx kv.ReadableTx) error {
+		var data []byte
+
+		bucket := tx.GetBucket(s.txBucket)
+		if bucket != nil {
+			data = bucket.Get(id)
+		}
+
+		if len(data) == 0 {
+			return xerrors.Errorf("transaction %#x not found: %w", id, ErrNoTransaction)
+		}
+
+		var m receiptJSON
+		err := s.context.Unmarshal(data, &m)
+		if err != nil {
+			return xerrors.Errorf("malformed receipt: %v", err)
+		}
+
+		receipt = Receipt(m)
+
+		return nil
+	})
+
+	return receipt, err
+}
+
 // Last implements blockstore.BlockStore. It returns the last block stored in
 // the database.
 func (s *InDisk) Last() (types.BlockLink, error) {
@@ -263,6 +486,8 @@ func (s *InDisk) WithTx(txn store.Transaction) BlockStore {
 	store := &InDisk{
 		db:         s.db,
 		bucket:     s.bucket,
+		txBucket:   s.txBucket,
+		linkBucket: s.linkBucket,
 		context:    s.context,
 		fac:        s.fac,
 		watcher:    s.watcher,
@@ -299,6 +524,46 @@ func (s *InDisk) doView(fn func(tx kv.ReadableTx) error) error {
 	return s.db.View(fn)
 }
 
+// readLinks reads the forward links at the beginning of the store, which are
+// only present when the store has been created from a chain.
+func (s *InDisk) readLinks(tx kv.ReadableTx, links []types.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	bucket := tx.GetBucket(s.linkBucket)
+	if bucket == nil {
+		return xerrors.New("links are missing")
+	}
+
+	for i := range links {
+		link, err := s.fac.LinkOf(s.context, bucket.Get(s.makeKey(uint64(i))))
+		if err != nil {
+			return xerrors.Errorf("link %d malformed: %v", i, err)
+		}
+
+		links[i] = link
+	}
+
+	return nil
+}
+
+func (s *InDisk) indexTransactions(link types.BlockLink, bucket kv.Bucket) error {
+	return forEachReceipt(link, func(id []byte, receipt Receipt) error {
+		data, err := s.context.Marshal(receiptJSON(receipt))
+		if err != nil {
+			return xerrors.Errorf("failed to marshal: %v", err)
+		}
+
+		err = bucket.Set(id, data)
+		if err != nil {
+			return xerrors.Errorf("while writing: %v", err)
+		}
+
+		return nil
+	})
+}
+
 func (s *InDisk) makeKey(index uint64) []byte {
 	key := make([]byte, 8)
 	binary.LittleEndian.PutUint64(key, index)
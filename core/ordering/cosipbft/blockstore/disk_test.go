@@ -2,8 +2,7 @@ package blockstore
 
 import (
 	"context"
-	"io/ioutil"
-	"os"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -11,10 +10,12 @@ import (
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/core/txn/signed"
 	"go.dedis.ch/dela/core/validation"
 	"go.dedis.ch/dela/core/validation/simple"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
 )
 
 func TestInDisk_Len(t *testing.T) {
@@ -81,6 +82,83 @@ func TestInDisk_Store(t *testing.T) {
 	store.db = badDB{bucket: badBucket{}}
 	err = store.Store(makeLink(t, types.Digest{}))
 	require.EqualError(t, err, fake.Err("while writing"))
+
+	store.db = badDB{bucket: badIndexBucket{}}
+	err = store.Store(makeLinkWithTxs(t, types.Digest{}, 0, makeTxs(t, 1)))
+	require.EqualError(t, err, fake.Err("indexing transactions: while writing"))
+}
+
+func TestInDisk_StoreChain(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	store := NewDiskStore(db, makeBlockFac())
+
+	chain := makeChain(t, 3)
+
+	err := store.StoreChain(chain)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), store.Len())
+
+	err = store.StoreChain(chain)
+	require.EqualError(t, err, "store is not empty")
+
+	last, err := store.Last()
+	require.NoError(t, err)
+
+	err = store.Store(makeLink(t, last.GetTo(), types.WithIndex(3)))
+	require.NoError(t, err)
+
+	// The store is reloaded to make sure the base is persisted.
+	store = NewDiskStore(db, makeBlockFac())
+
+	err = store.Load()
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), store.Len())
+
+	_, err = store.GetByIndex(1)
+	require.EqualError(t, err, "block 1: block before the base of the store")
+	require.True(t, xerrors.Is(err, ErrNoHistory))
+
+	next, err := store.GetChain()
+	require.NoError(t, err)
+	require.Len(t, next.GetLinks(), 4)
+	require.Equal(t, chain.GetLinks()[1].GetTo(), next.GetLinks()[1].GetTo())
+
+	next, err = store.GetChainAt(2)
+	require.NoError(t, err)
+	require.Len(t, next.GetLinks(), 3)
+	require.Equal(t, chain.GetBlock().GetHash(), next.GetBlock().GetHash())
+
+	_, err = store.GetChainAt(1)
+	require.EqualError(t, err, "index 1: block before the base of the store")
+
+	_, err = store.GetChainAt(4)
+	require.EqualError(t, err, "index 4 not found: no block")
+
+	store.linkBucket = []byte("missing")
+	_, err = store.GetChain()
+	require.EqualError(t, err, "while reading database: links are missing")
+
+	store.fac = badLinkFac{}
+	store.linkBucket = []byte("links")
+	_, err = store.GetChainAt(2)
+	require.EqualError(t, err, fake.Err("while reading database: link 0 malformed"))
+
+	store = NewDiskStore(db, makeBlockFac())
+	err = store.StoreChain(types.NewChain(nil, nil))
+	require.EqualError(t, err, "invalid chain: invalid last link '<nil>'")
+
+	err = store.StoreChain(makeChain(t, 1))
+	require.NoError(t, err)
+
+	store = NewDiskStore(badDB{}, makeBlockFac())
+	err = store.StoreChain(chain)
+	require.EqualError(t, err, fake.Err("bucket failed"))
+
+	store.db = badDB{bucket: badBucket{}}
+	err = store.StoreChain(chain)
+	require.EqualError(t, err, fake.Err("while writing link"))
 }
 
 func TestInDisk_Get(t *testing.T) {
@@ -149,6 +227,87 @@ func TestInDisk_GetChain(t *testing.T) {
 	require.EqualError(t, err, fake.Err("while reading database: while scanning: block malformed"))
 }
 
+func TestInDisk_GetChainAt(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	store := NewDiskStore(db, makeBlockFac())
+
+	_, err := store.GetChainAt(0)
+	require.EqualError(t, err, "index 0 not found: no block")
+
+	for i := uint64(0); i < 3; i++ {
+		var from types.Digest
+		if store.last != nil {
+			from = store.last.GetTo()
+		}
+
+		err = store.Store(makeLink(t, from, types.WithIndex(i)))
+		require.NoError(t, err)
+	}
+
+	chain, err := store.GetChainAt(1)
+	require.NoError(t, err)
+	require.Len(t, chain.GetLinks(), 2)
+	require.Equal(t, uint64(1), chain.GetBlock().GetIndex())
+
+	store.fac = badLinkFac{}
+	_, err = store.GetChainAt(1)
+	require.EqualError(t, err, fake.Err("while reading database: block 0 malformed"))
+}
+
+func TestInDisk_GetReceipt(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	fac := types.NewLinkFactory(
+		types.NewBlockFactory(simple.NewResultFactory(signed.NewTransactionFactory())),
+		fake.SignatureFactory{},
+		fakeCsFac{},
+	)
+
+	store := NewDiskStore(db, fac)
+
+	txs := makeTxs(t, 3)
+
+	_, err := store.GetReceipt(txs[0].GetID())
+	require.EqualError(t, err,
+		fmt.Sprintf("transaction %#x not found: no transaction", txs[0].GetID()))
+
+	err = store.Store(makeLinkWithTxs(t, types.Digest{}, 0, txs[:1]))
+	require.NoError(t, err)
+
+	err = store.Store(makeLinkWithTxs(t, store.last.GetTo(), 1, txs[1:]))
+	require.NoError(t, err)
+
+	receipt, err := store.GetReceipt(txs[2].GetID())
+	require.NoError(t, err)
+	require.Equal(t, Receipt{Index: 1, Position: 1, Message: "refused"}, receipt)
+
+	// The index is rebuilt when the bucket is missing.
+	store = NewDiskStore(db, fac)
+	store.txBucket = []byte("missing")
+
+	err = store.Load()
+	require.NoError(t, err)
+
+	receipt, err = store.GetReceipt(txs[0].GetID())
+	require.NoError(t, err)
+	require.Equal(t, Receipt{Index: 0, Position: 0, Accepted: true}, receipt)
+
+	err = db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(store.txBucket)
+		require.NoError(t, err)
+
+		return bucket.Set(txs[0].GetID(), []byte("{"))
+	})
+	require.NoError(t, err)
+
+	_, err = store.GetReceipt(txs[0].GetID())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed receipt: ")
+}
+
 func TestInDisk_Last(t *testing.T) {
 	db, clean := makeDB(t)
 	defer clean()
@@ -221,18 +380,9 @@ func TestInDisk_WithTx(t *testing.T) {
 // Utility functions
 
 func makeDB(t *testing.T) (kv.DB, func()) {
-	file, err := ioutil.TempFile(os.TempDir(), "dela-blockstore")
-	require.NoError(t, err)
-
-	db, err := kv.New(file.Name())
-	require.NoError(t, err)
+	db := kv.NewInMemory()
 
-	clean := func() {
-		db.Close()
-		file.Close()
-	}
-
-	return db, clean
+	return db, func() { db.Close() }
 }
 
 func makeBlockFac() types.LinkFactory {
@@ -257,6 +407,19 @@ func (fakeResultFac) ResultOf(serde.Context, []byte) (validation.Result, error)
 	return simple.NewResult(nil), nil
 }
 
+// badIndexBucket is a bucket that only accepts the keys of the blocks.
+type badIndexBucket struct {
+	kv.Bucket
+}
+
+func (badIndexBucket) Set(key, value []byte) error {
+	if len(key) == 8 {
+		return nil
+	}
+
+	return fake.GetError()
+}
+
 type badLinkFac struct {
 	types.LinkFactory
 }
@@ -265,6 +428,10 @@ func (badLinkFac) BlockLinkOf(serde.Context, []byte) (types.BlockLink, error) {
 	return nil, fake.GetError()
 }
 
+func (badLinkFac) LinkOf(serde.Context, []byte) (types.Link, error) {
+	return nil, fake.GetError()
+}
+
 type badLink struct {
 	types.BlockLink
 }
@@ -1,9 +1,6 @@
 package blockstore
 
 import (
-	"io/ioutil"
-	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -58,17 +55,11 @@ func TestCachedGenesis_Exists(t *testing.T) {
 }
 
 func TestGenesisDiskStore_Load(t *testing.T) {
-	dir, err := ioutil.TempDir(os.TempDir(), "dela-blockstore-")
-	require.NoError(t, err)
-
-	defer os.RemoveAll(dir)
-
-	db, err := kv.New(filepath.Join(dir, "test.db"))
-	require.NoError(t, err)
+	db := kv.NewInMemory()
 
 	store := NewGenesisDiskStore(db, makeFac())
 
-	err = store.Load()
+	err := store.Load()
 	require.NoError(t, err)
 	require.False(t, store.set)
 
@@ -92,17 +83,11 @@ func TestGenesisDiskStore_Load(t *testing.T) {
 }
 
 func TestGenesisDiskStore_Set(t *testing.T) {
-	dir, err := ioutil.TempDir(os.TempDir(), "dela-blockstore-")
-	require.NoError(t, err)
-
-	defer os.RemoveAll(dir)
-
-	db, err := kv.New(filepath.Join(dir, "test.db"))
-	require.NoError(t, err)
+	db := kv.NewInMemory()
 
 	store := NewGenesisDiskStore(db, makeFac())
 
-	err = store.Set(makeGenesis(t))
+	err := store.Set(makeGenesis(t))
 	require.NoError(t, err)
 
 	var data []byte
@@ -27,6 +27,7 @@ const sizeWarnLimit = 100
 // - implements blockstore.BlockStore
 type InMemory struct {
 	sync.Mutex
+	base    []types.Link
 	blocks  []types.BlockLink
 	watcher core.Observable
 	withTx  bool
@@ -45,7 +46,7 @@ func (s *InMemory) Len() uint64 {
 	s.Lock()
 	defer s.Unlock()
 
-	return uint64(len(s.blocks))
+	return uint64(len(s.base) + len(s.blocks))
 }
 
 // Store implements blockstore.BlockStore. It stores the block only if the link
@@ -73,6 +74,32 @@ func (s *InMemory) Store(link types.BlockLink) error {
 	return nil
 }
 
+// StoreChain implements blockstore.BlockStore. It stores the latest block of
+// the chain and keeps the forward links of the previous ones, if the store is
+// empty.
+func (s *InMemory) StoreChain(chain types.Chain) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.base)+len(s.blocks) > 0 {
+		return xerrors.New("store is not empty")
+	}
+
+	prevs, last, err := splitChain(chain)
+	if err != nil {
+		return xerrors.Errorf("invalid chain: %v", err)
+	}
+
+	s.base = prevs
+	s.blocks = []types.BlockLink{last}
+
+	if !s.withTx {
+		s.watcher.Notify(last)
+	}
+
+	return nil
+}
+
 // Get implements blockstore.BlockStore. It returns the block link associated to
 // the digest if it exists, otherwise it returns an error.
 func (s *InMemory) Get(id types.Digest) (types.BlockLink, error) {
@@ -94,11 +121,17 @@ func (s *InMemory) GetByIndex(index uint64) (types.BlockLink, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	if int(index) >= len(s.blocks) {
+	base := uint64(len(s.base))
+
+	if index < base {
+		return nil, xerrors.Errorf("block %d: %w", index, ErrNoHistory)
+	}
+
+	if index-base >= uint64(len(s.blocks)) {
 		return nil, xerrors.Errorf("block not found: %w", ErrNoBlock)
 	}
 
-	return s.blocks[index], nil
+	return s.blocks[index-base], nil
 }
 
 // GetChain implements blockstore.BlockStore. It returns the chain to the latest
@@ -113,14 +146,54 @@ func (s *InMemory) GetChain() (types.Chain, error) {
 		return nil, xerrors.New("store is empty")
 	}
 
-	prevs := make([]types.Link, num)
-	for i, block := range s.blocks[:num] {
-		prevs[i] = block.Reduce()
+	prevs := append([]types.Link{}, s.base...)
+	for _, block := range s.blocks[:num] {
+		prevs = append(prevs, block.Reduce())
 	}
 
 	return types.NewChain(s.blocks[num], prevs), nil
 }
 
+// GetChainAt implements blockstore.BlockStore. It returns the chain to the
+// block at the given index.
+func (s *InMemory) GetChainAt(index uint64) (types.Chain, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	base := uint64(len(s.base))
+
+	if index < base {
+		return nil, xerrors.Errorf("index %d: %w", index, ErrNoHistory)
+	}
+
+	if index-base >= uint64(len(s.blocks)) {
+		return nil, xerrors.Errorf("index %d not found: %w", index, ErrNoBlock)
+	}
+
+	prevs := append([]types.Link{}, s.base...)
+	for _, block := range s.blocks[:index-base] {
+		prevs = append(prevs, block.Reduce())
+	}
+
+	return types.NewChain(s.blocks[index-base], prevs), nil
+}
+
+// GetReceipt implements blockstore.BlockStore. It looks for the transaction in
+// the blocks and returns its receipt if it is found.
+func (s *InMemory) GetReceipt(id []byte) (Receipt, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, link := range s.blocks {
+		receipt, found := lookupReceipt(link, id)
+		if found {
+			return receipt, nil
+		}
+	}
+
+	return Receipt{}, xerrors.Errorf("transaction %#x not found: %w", id, ErrNoTransaction)
+}
+
 // Last implements blockstore.BlockStore. It returns the latest block of the
 // store.
 func (s *InMemory) Last() (types.BlockLink, error) {
@@ -146,6 +219,7 @@ func (s *InMemory) Watch(ctx context.Context) <-chan types.BlockLink {
 // apply the list of blocks at the end of the transaction.
 func (s *InMemory) WithTx(txn store.Transaction) BlockStore {
 	store := &InMemory{
+		base:    s.base,
 		blocks:  append([]types.BlockLink{}, s.blocks...),
 		watcher: s.watcher,
 		withTx:  true,
@@ -155,6 +229,7 @@ func (s *InMemory) WithTx(txn store.Transaction) BlockStore {
 
 	txn.OnCommit(func() {
 		s.Lock()
+		s.base = store.base
 		s.blocks = store.blocks
 		s.withTx = false
 
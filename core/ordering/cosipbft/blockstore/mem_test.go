@@ -7,8 +7,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/core/txn/signed"
 	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/crypto/bls"
 	"go.dedis.ch/dela/internal/testing/fake"
+	"golang.org/x/xerrors"
 )
 
 func TestInMemory_Len(t *testing.T) {
@@ -32,6 +36,52 @@ func TestInMemory_Store(t *testing.T) {
 	require.EqualError(t, err, "mismatch link '00000000' != '2c34ce1d'")
 }
 
+func TestInMemory_StoreChain(t *testing.T) {
+	store := NewInMemory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := store.Watch(ctx)
+
+	chain := makeChain(t, 3)
+
+	err := store.StoreChain(chain)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), store.Len())
+	require.Equal(t, uint64(2), (<-ch).GetBlock().GetIndex())
+
+	_, err = store.GetByIndex(1)
+	require.EqualError(t, err, "block 1: block before the base of the store")
+	require.True(t, xerrors.Is(err, ErrNoHistory))
+
+	link, err := store.GetByIndex(2)
+	require.NoError(t, err)
+	require.Equal(t, chain.GetBlock(), link.GetBlock())
+
+	err = store.Store(makeLink(t, link.GetTo(), types.WithIndex(3)))
+	require.NoError(t, err)
+
+	next, err := store.GetChain()
+	require.NoError(t, err)
+	require.Len(t, next.GetLinks(), 4)
+	require.Equal(t, chain.GetLinks()[0], next.GetLinks()[0])
+
+	next, err = store.GetChainAt(2)
+	require.NoError(t, err)
+	require.Equal(t, chain.GetLinks(), next.GetLinks())
+
+	_, err = store.GetChainAt(1)
+	require.EqualError(t, err, "index 1: block before the base of the store")
+
+	err = store.StoreChain(chain)
+	require.EqualError(t, err, "store is not empty")
+
+	store = NewInMemory()
+	err = store.StoreChain(types.NewChain(makeLink(t, types.Digest{}), chain.GetLinks()[:1]))
+	require.EqualError(t, err, "invalid chain: mismatch index 0 != 1")
+}
+
 func TestInMemory_Get(t *testing.T) {
 	store := NewInMemory()
 
@@ -89,6 +139,50 @@ func TestInMemory_GetChain(t *testing.T) {
 	require.EqualError(t, err, "store is empty")
 }
 
+func TestInMemory_GetChainAt(t *testing.T) {
+	store := NewInMemory()
+
+	store.blocks = []types.BlockLink{
+		makeLink(t, types.Digest{}, types.WithIndex(0)),
+		makeLink(t, types.Digest{}, types.WithIndex(1)),
+		makeLink(t, types.Digest{}, types.WithIndex(2)),
+	}
+
+	chain, err := store.GetChainAt(1)
+	require.NoError(t, err)
+	require.Len(t, chain.GetLinks(), 2)
+	require.Equal(t, uint64(1), chain.GetBlock().GetIndex())
+
+	chain, err = store.GetChainAt(0)
+	require.NoError(t, err)
+	require.Len(t, chain.GetLinks(), 1)
+
+	_, err = store.GetChainAt(3)
+	require.EqualError(t, err, "index 3 not found: no block")
+}
+
+func TestInMemory_GetReceipt(t *testing.T) {
+	store := NewInMemory()
+
+	txs := makeTxs(t, 3)
+
+	store.blocks = []types.BlockLink{
+		makeLinkWithTxs(t, types.Digest{}, 0, txs[:1]),
+		makeLinkWithTxs(t, types.Digest{}, 1, txs[1:]),
+	}
+
+	receipt, err := store.GetReceipt(txs[2].GetID())
+	require.NoError(t, err)
+	require.Equal(t, Receipt{Index: 1, Position: 1, Message: "refused"}, receipt)
+
+	receipt, err = store.GetReceipt(txs[0].GetID())
+	require.NoError(t, err)
+	require.Equal(t, Receipt{Index: 0, Position: 0, Accepted: true}, receipt)
+
+	_, err = store.GetReceipt([]byte{0xaa})
+	require.EqualError(t, err, "transaction 0xaa not found: no transaction")
+}
+
 func TestInMemory_Last(t *testing.T) {
 	store := NewInMemory()
 
@@ -208,6 +302,62 @@ func makeLink(t *testing.T, from types.Digest, opts ...types.BlockOption) types.
 	return link
 }
 
+// makeTxs creates signed transactions that can be serialized and deserialized
+// with the default factory.
+func makeTxs(t *testing.T, n int) []txn.Transaction {
+	signer := bls.NewSigner()
+
+	txs := make([]txn.Transaction, n)
+	for i := range txs {
+		tx, err := signed.NewTransaction(uint64(i), signer.GetPublicKey())
+		require.NoError(t, err)
+
+		require.NoError(t, tx.Sign(signer))
+
+		txs[i] = tx
+	}
+
+	return txs
+}
+
+// makeLinkWithTxs creates a link to a block with the transactions, where only
+// the first one is accepted.
+func makeLinkWithTxs(t *testing.T, from types.Digest, index uint64, txs []txn.Transaction) types.BlockLink {
+	results := make([]simple.TransactionResult, len(txs))
+	for i, tx := range txs {
+		if i == 0 {
+			results[i] = simple.NewTransactionResult(tx, true, "")
+		} else {
+			results[i] = simple.NewTransactionResult(tx, false, "refused")
+		}
+	}
+
+	to, err := types.NewBlock(simple.NewResult(results), types.WithIndex(index))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(from, to, types.WithSignatures(fake.Signature{}, fake.Signature{}))
+	require.NoError(t, err)
+
+	return link
+}
+
+// makeChain creates a chain of n blocks where the previous blocks are reduced
+// to their forward links.
+func makeChain(t *testing.T, n int) types.Chain {
+	prevs := make([]types.Link, n-1)
+
+	var from types.Digest
+	for i := range prevs {
+		link := makeLink(t, from, types.WithIndex(uint64(i)))
+		prevs[i] = link.Reduce()
+		from = link.GetTo()
+	}
+
+	last := makeLink(t, from, types.WithIndex(uint64(n-1)))
+
+	return types.NewChain(last, prevs)
+}
+
 type fakeTx struct {
 	store.Transaction
 
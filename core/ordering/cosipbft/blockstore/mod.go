@@ -4,7 +4,13 @@
 // The block store defines the primitives to store a block and read one from the
 // disk. It also provide an API to read a chain from the genesis block to the
 // latest block. It is important to notice that a block is stored alongside the
-// link that has been created during the consensus.
+// link that has been created during the consensus. The receipts of the
+// transactions are also available so that one can learn in which block, and
+// with which outcome, a transaction has been included.
+//
+// A store can also start from a chain instead of the genesis block, for
+// instance after a synchronization from a snapshot of the state, in which case
+// the blocks before the chain are not available.
 //
 // The tree cache stores the latest state of the tree, which is modified after
 // each new block.
@@ -25,8 +31,35 @@ import (
 	"go.dedis.ch/dela/core/store/hashtree"
 )
 
-// ErrNoBlock is the error message returned when the block is unknown.
-var ErrNoBlock = errors.New("no block")
+var (
+	// ErrNoBlock is the error message returned when the block is unknown.
+	ErrNoBlock = errors.New("no block")
+
+	// ErrNoTransaction is the error message returned when the transaction is
+	// not included in any block.
+	ErrNoTransaction = errors.New("no transaction")
+
+	// ErrNoHistory is the error message returned when the block exists but is
+	// before the base of a store created from a chain, so that only its
+	// forward link is known.
+	ErrNoHistory = errors.New("block before the base of the store")
+)
+
+// Receipt is the record of the inclusion of a transaction in a block.
+type Receipt struct {
+	// Index is the index of the block that includes the transaction.
+	Index uint64
+
+	// Position is the position of the transaction in the block.
+	Position int
+
+	// Accepted is true if the transaction has been accepted, in which case the
+	// changes have been applied to the store.
+	Accepted bool
+
+	// Message is the reason when the transaction has been refused.
+	Message string
+}
 
 // TreeCache is a cache to store a tree that needs to be accessed in different
 // places.
@@ -72,16 +105,32 @@ type BlockStore interface {
 	// otherwise it must return an error.
 	Store(types.BlockLink) error
 
+	// StoreChain must store the chain as the base of an empty store, otherwise
+	// it must return an error. Only the forward links of the blocks before the
+	// latest one are known, which means they cannot be read afterwards but the
+	// store can still provide a chain to any later block.
+	StoreChain(types.Chain) error
+
 	// Get must return the block link associated to the digest, or an error.
 	Get(id types.Digest) (types.BlockLink, error)
 
 	// GetByIndex return the block link associated to the index, or an error.
+	// The error must wrap ErrNoHistory if the block is before the base of the
+	// store, and ErrNoBlock if it does not exist yet.
 	GetByIndex(index uint64) (types.BlockLink, error)
 
 	// GetChain returns a chain of the blocks. It can be used to prove the
 	// integrity of the last block from the genesis.
 	GetChain() (types.Chain, error)
 
+	// GetChainAt returns a chain of the blocks up to the given index. It can be
+	// used to prove the integrity of a previous block from the genesis.
+	GetChainAt(index uint64) (types.Chain, error)
+
+	// GetReceipt must return the receipt of the transaction if it is included
+	// in a block, otherwise an error.
+	GetReceipt(id []byte) (Receipt, error)
+
 	// Last must return the latest block link in the store.
 	Last() (types.BlockLink, error)
 
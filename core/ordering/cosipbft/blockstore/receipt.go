@@ -0,0 +1,59 @@
+// This file contains the helpers to build the receipts of the transactions
+// included in a block.
+
+package blockstore
+
+import (
+	"bytes"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/validation"
+)
+
+// receiptJSON is the JSON representation of a receipt stored in the database.
+type receiptJSON struct {
+	Index    uint64
+	Position int
+	Accepted bool
+	Message  string `json:",omitempty"`
+}
+
+func makeReceipt(index uint64, pos int, res validation.TransactionResult) Receipt {
+	accepted, msg := res.GetStatus()
+
+	return Receipt{
+		Index:    index,
+		Position: pos,
+		Accepted: accepted,
+		Message:  msg,
+	}
+}
+
+// forEachReceipt calls the function with the identifier and the receipt of
+// every transaction of the block, in order.
+func forEachReceipt(link types.BlockLink, fn func(id []byte, r Receipt) error) error {
+	block := link.GetBlock()
+
+	for i, res := range block.GetData().GetTransactionResults() {
+		err := fn(res.GetTransaction().GetID(), makeReceipt(block.GetIndex(), i, res))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookupReceipt returns the receipt of the transaction if it is included in
+// the block.
+func lookupReceipt(link types.BlockLink, id []byte) (Receipt, bool) {
+	block := link.GetBlock()
+
+	for i, res := range block.GetData().GetTransactionResults() {
+		if bytes.Equal(res.GetTransaction().GetID(), id) {
+			return makeReceipt(block.GetIndex(), i, res), true
+		}
+	}
+
+	return Receipt{}, false
+}
@@ -6,9 +6,12 @@
 package blocksync
 
 import (
+	"bytes"
 	"context"
 	"io"
+	"math/big"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"go.dedis.ch/dela"
@@ -16,6 +19,8 @@ import (
 	"go.dedis.ch/dela/core/ordering/cosipbft/blocksync/types"
 	"go.dedis.ch/dela/core/ordering/cosipbft/pbft"
 	otypes "go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/mino"
 	"golang.org/x/xerrors"
@@ -32,6 +37,10 @@ type defaultSync struct {
 	rpc    mino.RPC
 	pbftsm pbft.StateMachine
 	blocks blockstore.BlockStore
+	tree   blockstore.TreeCache
+
+	// chunkSize is the maximum number of pairs in a chunk of a snapshot.
+	chunkSize int
 
 	latest      *uint64
 	catchUpLock *sync.Mutex
@@ -46,6 +55,30 @@ type SyncParam struct {
 	LinkFactory     otypes.LinkFactory
 	ChainFactory    otypes.ChainFactory
 	VerifierFactory crypto.VerifierFactory
+
+	// Tree is the cache of the tree that is used to share and restore
+	// snapshots of the state. It is optional when snapshots are disabled.
+	Tree blockstore.TreeCache
+
+	// ProofFactory is the factory of the proofs that the chunks of a snapshot
+	// are complete. It is optional when snapshots are disabled.
+	ProofFactory hashtree.SuffixProofFactory
+
+	// SnapshotThreshold is the number of missing blocks from which a node
+	// without any block requests a snapshot of the state instead of the
+	// blocks. Zero disables the snapshots.
+	SnapshotThreshold uint64
+
+	// RangeSize is the number of blocks requested at once to a member of the
+	// roster when the node has fallen behind. The ranges are downloaded in
+	// parallel from different members. It also bounds the ranges served to
+	// the other members. Zero disables the parallel download and the blocks
+	// are only sent by the leader.
+	RangeSize uint64
+
+	// Roster returns the members that can be asked for blocks. The leader is
+	// always asked when it is not provided.
+	Roster func() (mino.Players, error)
 }
 
 // NewSynchronizer creates a new block synchronizer.
@@ -60,17 +93,28 @@ func NewSynchronizer(param SyncParam) Synchronizer {
 		logger:      logger,
 		genesis:     param.Genesis,
 		blocks:      param.Blocks,
+		tree:        param.Tree,
 		pbftsm:      param.PBFT,
 		verifierFac: param.VerifierFactory,
+		threshold:   param.SnapshotThreshold,
+		me:          param.Mino.GetAddress(),
+		rangeSize:   param.RangeSize,
+		roster:      param.Roster,
+		timeout:     RangeTimeout,
 	}
 
-	fac := types.NewMessageFactory(param.LinkFactory, param.ChainFactory)
+	fac := types.NewMessageFactory(param.LinkFactory, param.ChainFactory,
+		param.ProofFactory)
+
+	h.rpc = mino.MustCreateRPC(param.Mino, "blocksync", h, fac)
 
 	s := defaultSync{
 		logger:      logger,
-		rpc:         mino.MustCreateRPC(param.Mino, "blocksync", h, fac),
+		rpc:         h.rpc,
 		pbftsm:      param.PBFT,
 		blocks:      param.Blocks,
+		tree:        param.Tree,
+		chunkSize:   SnapshotChunkSize,
 		latest:      &latest,
 		catchUpLock: h.catchUpLock,
 	}
@@ -153,6 +197,11 @@ func (s defaultSync) Sync(ctx context.Context, players mino.Players, cfg Config)
 
 				go s.syncNode(in.GetFrom(), sender, from)
 
+			case types.SnapshotRequest:
+				// The participant is not marked as soft-synchronized as it
+				// will send a request for the remaining blocks afterwards.
+				go s.sendSnapshot(sender, from)
+
 			case types.SyncAck:
 				soft[from] = struct{}{}
 				hard[from] = struct{}{}
@@ -172,6 +221,18 @@ func (s defaultSync) Sync(ctx context.Context, players mino.Players, cfg Config)
 func (s defaultSync) syncNode(from uint64, sender mino.Sender, to mino.Address) {
 	for i := from; i < s.blocks.Len(); i++ {
 		link, err := s.blocks.GetByIndex(i)
+		if xerrors.Is(err, blockstore.ErrNoHistory) {
+			s.logger.Warn().Err(err).Msgf("cannot replay the blocks to %v", to)
+
+			// The participant is notified with an empty snapshot so that it
+			// stops waiting for blocks this node does not have.
+			err = <-sender.Send(types.NewSnapshotReply(nil), to)
+			if err != nil {
+				s.logger.Err(err).Msgf("while synchronizing %v", to)
+			}
+
+			return
+		}
 		if err != nil {
 			s.logger.Err(err).Msgf("while synchronizing %v", to)
 			return
@@ -190,6 +251,66 @@ func (s defaultSync) syncNode(from uint64, sender mino.Sender, to mino.Address)
 	}
 }
 
+// sendSnapshot sends the chain of the latest block followed by the chunks of
+// the tree, so that a single message never holds the whole state.
+func (s defaultSync) sendSnapshot(sender mino.Sender, to mino.Address) {
+	chain, chunks, err := s.makeSnapshot()
+	if err != nil {
+		s.logger.Err(err).Msgf("while making snapshot for %v", to)
+
+		// The participant is notified so that it can fall back to the blocks.
+		chain = nil
+		chunks = nil
+	}
+
+	s.logger.Debug().
+		Int("chunks", len(chunks)).
+		Stringer("to", to).
+		Msg("send snapshot")
+
+	err = <-sender.Send(types.NewSnapshotReply(chain), to)
+	if err != nil {
+		s.logger.Err(err).Msgf("while sending snapshot to %v", to)
+		return
+	}
+
+	for _, chunk := range chunks {
+		err = <-sender.Send(types.NewSnapshotChunk(chunk), to)
+		if err != nil {
+			s.logger.Err(err).Msgf("while sending snapshot to %v", to)
+			return
+		}
+	}
+}
+
+func (s defaultSync) makeSnapshot() (otypes.Chain, []hashtree.SuffixProof, error) {
+	if s.tree == nil {
+		return nil, nil, xerrors.New("tree is missing")
+	}
+
+	// The cache is locked so that the tree and the chain are read for the same
+	// block, and that every chunk is proven against the same root.
+	tree, unlock := s.tree.GetWithLock()
+	defer unlock()
+
+	rangeable, ok := tree.(hashtree.RangeTree)
+	if !ok {
+		return nil, nil, xerrors.Errorf("tree '%T' cannot prove suffixes", tree)
+	}
+
+	chain, err := s.blocks.GetChain()
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to read chain: %v", err)
+	}
+
+	chunks, err := makeChunks(rangeable, new(big.Int), 0, s.chunkSize)
+	if err != nil {
+		return nil, nil, xerrors.Errorf("failed to read tree: %v", err)
+	}
+
+	return chain, chunks, nil
+}
+
 // handler is a Mino handler for the synchronization messages.
 //
 // - implements mino.Handler
@@ -202,8 +323,16 @@ type handler struct {
 	logger      zerolog.Logger
 	blocks      blockstore.BlockStore
 	genesis     blockstore.GenesisStore
+	tree        blockstore.TreeCache
 	pbftsm      pbft.StateMachine
 	verifierFac crypto.VerifierFactory
+	threshold   uint64
+
+	rpc       mino.RPC
+	me        mino.Address
+	rangeSize uint64
+	roster    func() (mino.Players, error)
+	timeout   time.Duration
 }
 
 // Stream implements mino.Handler. It waits for an announcement message and then
@@ -249,9 +378,27 @@ func (h *handler) Stream(out mino.Sender, in mino.Receiver) error {
 		*h.latest = m.GetLatestIndex()
 	}
 
-	err = <-out.Send(types.NewSyncRequest(h.blocks.Len()), orch)
-	if err != nil {
-		return xerrors.Errorf("sending request failed: %v", err)
+	if h.useSnapshot(m.GetLatestIndex()) {
+		err = h.restoreSnapshot(ctx, genesis, out, in, orch)
+		if err != nil {
+			// The blocks are replayed instead.
+			h.logger.Warn().Err(err).Msg("snapshot failed")
+		}
+	}
+
+	if h.rangeSize > 0 && h.blocks.Len() <= m.GetLatestIndex() {
+		err = h.download(m.GetChain(), orch)
+		if err != nil {
+			// The leader sends the remaining blocks instead.
+			h.logger.Warn().Err(err).Msg("parallel download failed")
+		}
+	}
+
+	if h.blocks.Len() <= m.GetLatestIndex() {
+		err = <-out.Send(types.NewSyncRequest(h.blocks.Len()), orch)
+		if err != nil {
+			return xerrors.Errorf("sending request failed: %v", err)
+		}
 	}
 
 	for h.blocks.Len() <= m.GetLatestIndex() {
@@ -260,6 +407,14 @@ func (h *handler) Stream(out mino.Sender, in mino.Receiver) error {
 			return xerrors.Errorf("receiver failed: %v", err)
 		}
 
+		_, ok := msg.(types.SnapshotReply)
+		if ok {
+			// The orchestrator was restored from a snapshot and does not have
+			// the blocks this node is missing.
+			return xerrors.Errorf("orchestrator cannot replay the blocks from %d",
+				h.blocks.Len())
+		}
+
 		reply, ok := msg.(types.SyncReply)
 		if ok {
 			h.logger.Debug().
@@ -278,6 +433,152 @@ func (h *handler) Stream(out mino.Sender, in mino.Receiver) error {
 	return h.ack(out, orch)
 }
 
+// useSnapshot returns true when the snapshots are enabled and the node has no
+// block while it misses at least the threshold.
+func (h *handler) useSnapshot(latest uint64) bool {
+	return h.threshold > 0 && h.blocks.Len() == 0 && latest+1 >= h.threshold
+}
+
+// restoreSnapshot requests a snapshot to the orchestrator and applies it when
+// the tree root matches the one of the latest block of the chain, which is
+// verified with the collective signatures. Each chunk is verified against the
+// root as it is received, and the chunks must cover the whole tree in order.
+func (h *handler) restoreSnapshot(ctx context.Context, genesis otypes.Genesis,
+	out mino.Sender, in mino.Receiver, orch mino.Address) error {
+
+	err := <-out.Send(types.NewSnapshotRequest(), orch)
+	if err != nil {
+		return xerrors.Errorf("sending request failed: %v", err)
+	}
+
+	var reply types.SnapshotReply
+
+	for {
+		_, msg, err := in.Recv(ctx)
+		if err != nil {
+			return xerrors.Errorf("receiver failed: %v", err)
+		}
+
+		m, ok := msg.(types.SnapshotReply)
+		if ok {
+			reply = m
+			break
+		}
+	}
+
+	if reply.GetChain() == nil {
+		return xerrors.New("snapshot is not available")
+	}
+
+	err = reply.GetChain().Verify(genesis, h.verifierFac)
+	if err != nil {
+		return xerrors.Errorf("failed to verify chain: %v", err)
+	}
+
+	chunks, err := h.receiveChunks(ctx, in, reply.GetChain().GetBlock().GetTreeRoot())
+	if err != nil {
+		return xerrors.Errorf("failed to receive chunks: %v", err)
+	}
+
+	current, ok := h.tree.Get().(hashtree.IterableTree)
+	if !ok {
+		return xerrors.Errorf("tree '%T' is not iterable", h.tree.Get())
+	}
+
+	// The keys of the current state are removed so that the staged tree is
+	// exactly the snapshot.
+	keys := [][]byte{}
+
+	err = current.ForEach(func(key, value []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+
+	if err != nil {
+		return xerrors.Errorf("failed to read tree: %v", err)
+	}
+
+	stage, err := current.Stage(func(snap store.Snapshot) error {
+		for _, key := range keys {
+			err := snap.Delete(key)
+			if err != nil {
+				return xerrors.Errorf("failed to delete key: %v", err)
+			}
+		}
+
+		for _, chunk := range chunks {
+			err := chunk.ForEach(func(key, value []byte) error {
+				err := snap.Set(key, value)
+				if err != nil {
+					return xerrors.Errorf("failed to set key: %v", err)
+				}
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return xerrors.Errorf("failed to stage tree: %v", err)
+	}
+
+	err = h.pbftsm.Restore(reply.GetChain(), stage)
+	if err != nil {
+		return xerrors.Errorf("pbft restore failed: %v", err)
+	}
+
+	h.logger.Info().
+		Uint64("index", reply.GetChain().GetBlock().GetIndex()).
+		Msg("state restored from snapshot")
+
+	return nil
+}
+
+// receiveChunks returns the chunks of the snapshot after verifying that each
+// of them is proven against the root, and that they cover the whole tree. The
+// chunks are staged only once complete so that the database is not locked while
+// waiting for the orchestrator.
+func (h *handler) receiveChunks(ctx context.Context, in mino.Receiver,
+	root otypes.Digest) ([]hashtree.SuffixProof, error) {
+
+	chunks := []hashtree.SuffixProof{}
+	cursor := newChunkCursor()
+
+	for !cursor.Done() {
+		_, msg, err := in.Recv(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("receiver failed: %v", err)
+		}
+
+		m, ok := msg.(types.SnapshotChunk)
+		if !ok {
+			continue
+		}
+
+		proof := m.GetProof()
+
+		if !bytes.Equal(proof.GetRoot(), root[:]) {
+			return nil, xerrors.Errorf("mismatch chunk root %#x != %#x",
+				proof.GetRoot(), root[:])
+		}
+
+		err = cursor.Next(proof.GetSuffix())
+		if err != nil {
+			return nil, xerrors.Errorf("invalid chunk: %v", err)
+		}
+
+		chunks = append(chunks, proof)
+	}
+
+	return chunks, nil
+}
+
 func (h *handler) waitAnnounce(ctx context.Context,
 	in mino.Receiver) (*types.SyncMessage, mino.Address, error) {
 
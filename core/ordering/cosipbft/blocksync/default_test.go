@@ -3,6 +3,10 @@ package blocksync
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
 	"strconv"
 	"sync"
 	"testing"
@@ -14,6 +18,10 @@ import (
 	"go.dedis.ch/dela/core/ordering/cosipbft/blocksync/types"
 	"go.dedis.ch/dela/core/ordering/cosipbft/pbft"
 	otypes "go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/core/store/hashtree/binprefix"
+	"go.dedis.ch/dela/core/store/kv"
 	"go.dedis.ch/dela/core/txn/signed"
 	"go.dedis.ch/dela/core/validation/simple"
 	"go.dedis.ch/dela/crypto"
@@ -77,6 +85,93 @@ func TestDefaultSync_Basic(t *testing.T) {
 	}
 }
 
+func TestDefaultSync_Snapshot(t *testing.T) {
+	n := 3
+	num := 5
+
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-blocksync")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	trees := make([]hashtree.Tree, n)
+	for i := range trees {
+		trees[i] = makeTree(t, filepath.Join(dir, strconv.Itoa(i)), fmt.Sprintf("node%d", i))
+	}
+
+	trees[0] = makeTree(t, filepath.Join(dir, "src"), "node0", "A", "B", "C", "D")
+
+	syncs, genesis, roster := makeNodes(t, n, func(i int, param *SyncParam) {
+		param.Tree = blockstore.NewTreeCache(trees[i])
+		param.PBFT = testSM{blocks: param.Blocks, tree: param.Tree}
+		param.ProofFactory = binprefix.NewSuffixProofFactory()
+		param.SnapshotThreshold = 1
+	})
+
+	// The snapshot is sent in several chunks.
+	syncs[0].chunkSize = 2
+
+	root := otypes.Digest{}
+	copy(root[:], trees[0].GetRoot())
+
+	storeTreeBlocks(t, syncs[0].blocks, num, root, genesis.GetHash().Bytes()...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = syncs[0].Sync(ctx, roster, Config{MinSoft: n, MinHard: n})
+	require.NoError(t, err)
+
+	for i := 1; i < n; i++ {
+		require.Equal(t, uint64(num), syncs[i].blocks.Len(), strconv.Itoa(i))
+		require.Equal(t, trees[0].GetRoot(), syncs[i].tree.Get().GetRoot())
+
+		for _, key := range []string{"node0", "A", "B", "C", "D"} {
+			value, err := syncs[i].tree.Get().Get([]byte(key))
+			require.NoError(t, err)
+			require.Equal(t, []byte(key), value)
+		}
+
+		// The key only known by the node is removed by the snapshot.
+		value, err := syncs[i].tree.Get().Get([]byte(fmt.Sprintf("node%d", i)))
+		require.NoError(t, err)
+		require.Nil(t, value)
+	}
+}
+
+func TestDefaultSync_ParallelDownload(t *testing.T) {
+	n := 8
+	k := 4
+	num := 23
+
+	var players mino.Players
+
+	syncs, genesis, roster := makeNodes(t, n, func(i int, param *SyncParam) {
+		param.RangeSize = 5
+		param.Roster = func() (mino.Players, error) {
+			return players, nil
+		}
+	})
+
+	players = roster
+
+	// The first nodes have all the blocks, and the others none, so that some
+	// ranges are first requested to members that cannot provide them.
+	for i := 0; i < k; i++ {
+		storeBlocks(t, syncs[i].blocks, num, genesis.GetHash().Bytes()...)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := syncs[0].Sync(ctx, roster, Config{MinSoft: n, MinHard: n})
+	require.NoError(t, err)
+
+	for i := k; i < n; i++ {
+		require.Equal(t, uint64(num), syncs[i].blocks.Len(), strconv.Itoa(i))
+	}
+}
+
 func TestDefaultSync_GetLatest(t *testing.T) {
 	latest := uint64(5)
 
@@ -148,6 +243,132 @@ func TestDefaultSync_Sync(t *testing.T) {
 	wait(t)
 }
 
+func TestDefaultSync_SendSnapshot(t *testing.T) {
+	sync := defaultSync{
+		blocks: blockstore.NewInMemory(),
+	}
+
+	logger, check := fake.CheckLog("while making snapshot for fake.Address[0]")
+
+	sync.logger = logger
+	sync.sendSnapshot(fake.Sender{}, fake.NewAddress(0))
+	check(t)
+
+	logger, check = fake.CheckLog("while sending snapshot to fake.Address[0]")
+
+	sync.logger = logger
+	sync.sendSnapshot(fake.NewBadSender(), fake.NewAddress(0))
+	check(t)
+}
+
+func TestDefaultSync_MakeSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-blocksync")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	sync := defaultSync{
+		blocks:    blockstore.NewInMemory(),
+		tree:      blockstore.NewTreeCache(makeTree(t, filepath.Join(dir, "db"), "A", "B")),
+		chunkSize: 2,
+	}
+
+	storeBlocks(t, sync.blocks, 2)
+
+	chain, chunks, err := sync.makeSnapshot()
+	require.NoError(t, err)
+	require.Len(t, chain.GetLinks(), 2)
+	require.Len(t, chunks, 1)
+
+	// The keys end with different bits so they are split in two chunks.
+	sync.chunkSize = 1
+	_, chunks, err = sync.makeSnapshot()
+	require.NoError(t, err)
+	require.Len(t, chunks, 2)
+
+	sync.blocks = badBlockStore{errChain: fake.GetError()}
+	_, _, err = sync.makeSnapshot()
+	require.EqualError(t, err, fake.Err("failed to read chain"))
+
+	sync.tree = blockstore.NewTreeCache(fakeTree{})
+	_, _, err = sync.makeSnapshot()
+	require.EqualError(t, err,
+		"tree 'blocksync.fakeTree' cannot prove suffixes")
+
+	sync.tree = blockstore.NewTreeCache(fakeRangeTree{err: fake.GetError()})
+	sync.blocks = blockstore.NewInMemory()
+	storeBlocks(t, sync.blocks, 1)
+	_, _, err = sync.makeSnapshot()
+	require.EqualError(t, err, fake.Err("failed to read tree: failed to count suffix"))
+
+	sync.tree = nil
+	_, _, err = sync.makeSnapshot()
+	require.EqualError(t, err, "tree is missing")
+}
+
+func TestMakeChunks(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-blocksync")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	tree := makeTree(t, filepath.Join(dir, "db"), "A", "B", "C").(hashtree.RangeTree)
+
+	chunks, err := makeChunks(tree, new(big.Int), 0, 3)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	chunks, err = makeChunks(tree, new(big.Int), 0, 1)
+	require.NoError(t, err)
+
+	keys := []string{}
+	cursor := newChunkCursor()
+
+	for _, chunk := range chunks {
+		require.Equal(t, tree.GetRoot(), chunk.GetRoot())
+		require.NoError(t, cursor.Next(chunk.GetSuffix()))
+
+		err = chunk.ForEach(func(key, value []byte) error {
+			keys = append(keys, string(key))
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	require.True(t, cursor.Done())
+	require.ElementsMatch(t, []string{"A", "B", "C"}, keys)
+
+	// A chunk larger than the size is sent when the keys cannot be split.
+	chunks, err = makeChunks(tree, new(big.Int).SetBytes([]byte("A")), maxSuffixBits, 0)
+	require.NoError(t, err)
+	require.Len(t, chunks, 1)
+
+	_, err = makeChunks(fakeRangeTree{errProof: fake.GetError()}, new(big.Int), 0, 1)
+	require.EqualError(t, err, fake.Err("failed to prove suffix"))
+}
+
+func TestChunkCursor_Next(t *testing.T) {
+	cursor := newChunkCursor()
+	require.False(t, cursor.Done())
+
+	err := cursor.Next([]byte{1}, 1)
+	require.EqualError(t, err,
+		fmt.Sprintf("chunk at %#x does not follow 0x0", new(big.Int).Lsh(big.NewInt(1), 255)))
+
+	err = cursor.Next(nil, maxSuffixBits+1)
+	require.EqualError(t, err, "suffix is too long: 257 > 256")
+
+	require.NoError(t, cursor.Next(nil, 1))
+	require.False(t, cursor.Done())
+
+	err = cursor.Next(nil, 1)
+	require.EqualError(t, err,
+		fmt.Sprintf("chunk at 0x0 does not follow %#x", new(big.Int).Lsh(big.NewInt(1), 255)))
+
+	require.NoError(t, cursor.Next([]byte{1}, 1))
+	require.True(t, cursor.Done())
+}
+
 func TestDefaultSync_SyncNode(t *testing.T) {
 	sync := defaultSync{
 		blocks: blockstore.NewInMemory(),
@@ -161,6 +382,19 @@ func TestDefaultSync_SyncNode(t *testing.T) {
 	sync.syncNode(0, fake.NewBadSender(), fake.NewAddress(0))
 
 	check(t)
+
+	chain, err := sync.blocks.GetChain()
+	require.NoError(t, err)
+
+	sync.blocks = blockstore.NewInMemory()
+	require.NoError(t, sync.blocks.StoreChain(chain))
+
+	logger, check = fake.CheckLog("cannot replay the blocks to fake.Address[0]")
+
+	sync.logger = logger
+	sync.syncNode(0, fake.Sender{}, fake.NewAddress(0))
+
+	check(t)
 }
 
 func TestHandler_Stream(t *testing.T) {
@@ -240,6 +474,14 @@ func TestHandler_Stream(t *testing.T) {
 	require.Error(t, err)
 	require.Regexp(t, "pbft catch up failed: mismatch link '[0]{8}' != '[0-9a-f]{8}'", err.Error())
 
+	recv = fake.NewReceiver(
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncMessage(makeChain(t, 6))),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSnapshotReply(nil)),
+	)
+
+	err = handler.Stream(fake.Sender{}, recv)
+	require.EqualError(t, err, "orchestrator cannot replay the blocks from 3")
+
 	recv = fake.NewReceiver(
 		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncMessage(makeChain(t, 0))),
 	)
@@ -248,6 +490,383 @@ func TestHandler_Stream(t *testing.T) {
 	require.EqualError(t, err, fake.Err("sending ack failed"))
 }
 
+func TestHandler_Stream_Snapshot(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-blocksync")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	src := makeTree(t, filepath.Join(dir, "src"), "A").(hashtree.RangeTree)
+
+	proof, err := src.GetSuffix(nil, 0)
+	require.NoError(t, err)
+
+	root := otypes.Digest{}
+	copy(root[:], src.GetRoot())
+
+	latest := uint64(0)
+	blocks := blockstore.NewInMemory()
+	storeTreeBlocks(t, blocks, 3, root)
+
+	handler := &handler{
+		latest:      &latest,
+		catchUpLock: new(sync.Mutex),
+		genesis:     blockstore.NewGenesisStore(),
+		blocks:      blockstore.NewInMemory(),
+		tree:        blockstore.NewTreeCache(makeTree(t, filepath.Join(dir, "db"))),
+		verifierFac: fake.VerifierFactory{},
+		threshold:   2,
+	}
+	handler.genesis.Set(otypes.Genesis{})
+	handler.pbftsm = testSM{blocks: handler.blocks, tree: handler.tree}
+
+	chain, err := blocks.GetChain()
+	require.NoError(t, err)
+
+	// The chain is wrapped so that it passes the verification.
+	chain = fakeChain{Chain: chain, block: chain.GetBlock()}
+
+	recv := fake.NewReceiver(
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncMessage(makeChain(t, 2))),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncAck()),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSnapshotReply(chain)),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSnapshotChunk(proof)),
+	)
+
+	err = handler.Stream(fake.Sender{}, recv)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), handler.blocks.Len())
+
+	value, err := handler.tree.Get().Get([]byte("A"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("A"), value)
+
+	// When the snapshot is not available, the node falls back to the blocks.
+	link, err := blocks.GetByIndex(0)
+	require.NoError(t, err)
+
+	recv = fake.NewReceiver(
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncMessage(makeChain(t, 0))),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSnapshotReply(nil)),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncReply(link)),
+	)
+
+	logger, check := fake.CheckLog("snapshot failed")
+
+	handler.threshold = 1
+	handler.logger = logger
+	handler.blocks = blockstore.NewInMemory()
+	handler.pbftsm = testSM{blocks: handler.blocks, tree: handler.tree}
+	err = handler.Stream(fake.Sender{}, recv)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), handler.blocks.Len())
+	check(t)
+}
+
+func TestHandler_Stream_Download(t *testing.T) {
+	latest := uint64(0)
+	blocks := blockstore.NewInMemory()
+	storeBlocks(t, blocks, 3)
+
+	chain, err := blocks.GetChain()
+	require.NoError(t, err)
+
+	rpc := fake.NewRPC()
+
+	handler := &handler{
+		latest:      &latest,
+		catchUpLock: new(sync.Mutex),
+		genesis:     blockstore.NewGenesisStore(),
+		blocks:      blockstore.NewInMemory(),
+		verifierFac: fake.VerifierFactory{},
+		rpc:         rpc,
+		rangeSize:   5,
+		timeout:     time.Second,
+	}
+	handler.genesis.Set(otypes.Genesis{})
+	handler.pbftsm = testSM{blocks: handler.blocks}
+
+	links := make([]otypes.BlockLink, 3)
+	for i := range links {
+		links[i], err = blocks.GetByIndex(uint64(i))
+		require.NoError(t, err)
+	}
+
+	rpc.SendResponse(fake.NewAddress(0), types.NewBlocksReply(links))
+	rpc.Done()
+
+	recv := fake.NewReceiver(
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncMessage(fakeChain{Chain: chain, block: chain.GetBlock()})),
+	)
+
+	err = handler.Stream(fake.Sender{}, recv)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), handler.blocks.Len())
+
+	// When the download fails, the node falls back to the leader.
+	recv = fake.NewReceiver(
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncMessage(fakeChain{Chain: chain, block: chain.GetBlock()})),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncReply(links[0])),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncReply(links[1])),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewSyncReply(links[2])),
+	)
+
+	logger, check := fake.CheckLog("parallel download failed")
+
+	handler.logger = logger
+	handler.rpc = fake.NewBadRPC()
+	handler.blocks = blockstore.NewInMemory()
+	handler.pbftsm = testSM{blocks: handler.blocks}
+	err = handler.Stream(fake.Sender{}, recv)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), handler.blocks.Len())
+	check(t)
+}
+
+func TestHandler_Process(t *testing.T) {
+	handler := &handler{
+		blocks:    blockstore.NewInMemory(),
+		rangeSize: 4,
+	}
+
+	storeBlocks(t, handler.blocks, 3)
+
+	msg, err := handler.Process(mino.Request{Message: types.NewBlocksRequest(1, 5)})
+	require.NoError(t, err)
+	require.Len(t, msg.(types.BlocksReply).GetLinks(), 2)
+
+	msg, err = handler.Process(mino.Request{Message: types.NewBlocksRequest(3, 5)})
+	require.NoError(t, err)
+	require.Len(t, msg.(types.BlocksReply).GetLinks(), 0)
+
+	_, err = handler.Process(mino.Request{Message: fake.Message{}})
+	require.EqualError(t, err, "unsupported message 'fake.Message'")
+
+	_, err = handler.Process(mino.Request{Message: types.NewBlocksRequest(0, 5)})
+	require.EqualError(t, err, "range [0, 5) exceeds 4 blocks")
+
+	handler.rangeSize = 0
+	_, err = handler.Process(mino.Request{Message: types.NewBlocksRequest(1, 5)})
+	require.EqualError(t, err, "range requests are disabled")
+
+	handler.rangeSize = 4
+	handler.blocks = badBlockStore{}
+	_, err = handler.Process(mino.Request{Message: types.NewBlocksRequest(1, 5)})
+	require.EqualError(t, err, fake.Err("reading block 1"))
+}
+
+func TestHandler_Download(t *testing.T) {
+	blocks := blockstore.NewInMemory()
+	storeBlocks(t, blocks, 3)
+
+	chain, err := blocks.GetChain()
+	require.NoError(t, err)
+
+	handler := &handler{
+		blocks:    blockstore.NewInMemory(),
+		rangeSize: 2,
+		timeout:   time.Second,
+		me:        fake.NewAddress(1),
+	}
+	handler.pbftsm = testSM{blocks: handler.blocks}
+
+	handler.roster = func() (mino.Players, error) {
+		return nil, fake.GetError()
+	}
+
+	err = handler.download(chain, fake.NewAddress(0))
+	require.EqualError(t, err, fake.Err("failed to get peers: roster"))
+
+	handler.roster = func() (mino.Players, error) {
+		return mino.NewAddresses(fake.NewAddress(0), fake.NewAddress(1), fake.NewAddress(2)), nil
+	}
+
+	peers, err := handler.getPeers(fake.NewAddress(0))
+	require.NoError(t, err)
+	require.Equal(t, []mino.Address{fake.NewAddress(0), fake.NewAddress(2)}, peers)
+
+	handler.rpc = fake.NewBadRPC()
+	err = handler.download(chain, fake.NewAddress(0))
+	require.EqualError(t, err, "range [0, 2) failed: no peer among 2 could provide the range")
+
+	link, err := blocks.GetByIndex(0)
+	require.NoError(t, err)
+
+	rpc := fake.NewRPC()
+	rpc.SendResponse(fake.NewAddress(0), types.NewBlocksReply([]otypes.BlockLink{link}))
+	rpc.Done()
+
+	handler.rangeSize = 1
+	handler.roster = nil
+	handler.rpc = rpc
+	handler.pbftsm = testSM{err: fake.GetError()}
+
+	last := otypes.NewChain(link, nil)
+
+	err = handler.download(last, fake.NewAddress(0))
+	require.EqualError(t, err, fake.Err("pbft catch up failed"))
+}
+
+func TestHandler_FetchFrom(t *testing.T) {
+	blocks := blockstore.NewInMemory()
+	storeBlocks(t, blocks, 3)
+
+	chain, err := blocks.GetChain()
+	require.NoError(t, err)
+
+	first, err := blocks.GetByIndex(0)
+	require.NoError(t, err)
+
+	second, err := blocks.GetByIndex(1)
+	require.NoError(t, err)
+
+	handler := &handler{timeout: time.Second}
+
+	ctx := context.Background()
+	r := &blockRange{from: 0, to: 2}
+	addr := fake.NewAddress(0)
+
+	handler.rpc = fake.NewBadRPC()
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, fake.Err("call failed"))
+
+	rpc := fake.NewRPC()
+	rpc.Done()
+	handler.rpc = rpc
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, "no reply")
+
+	handler.rpc = fake.NewRPC()
+	handler.timeout = time.Millisecond
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, "no reply: context deadline exceeded")
+
+	handler.timeout = time.Second
+
+	rpc = fake.NewRPC()
+	rpc.SendResponseWithError(addr, fake.GetError())
+	handler.rpc = rpc
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, fake.Err("peer failed"))
+
+	rpc = fake.NewRPC()
+	rpc.SendResponse(addr, fake.Message{})
+	handler.rpc = rpc
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, "invalid reply 'fake.Message'")
+
+	rpc = fake.NewRPC()
+	rpc.SendResponse(addr, types.NewBlocksReply([]otypes.BlockLink{first}))
+	handler.rpc = rpc
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, "missing blocks: 1 != 2")
+
+	rpc = fake.NewRPC()
+	rpc.SendResponse(addr, types.NewBlocksReply([]otypes.BlockLink{second, first}))
+	handler.rpc = rpc
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, "unexpected block 1 at 0")
+
+	other := makeLink(t, otypes.Digest{}, 1)
+
+	rpc = fake.NewRPC()
+	rpc.SendResponse(addr, types.NewBlocksReply([]otypes.BlockLink{first, other}))
+	handler.rpc = rpc
+	_, err = handler.fetchFrom(ctx, r, addr, chain)
+	require.EqualError(t, err, fmt.Sprintf("mismatch link 1 '%v' != '%v'",
+		other.GetTo(), second.GetTo()))
+}
+
+func TestHandler_RestoreSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-blocksync")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	handler := &handler{
+		blocks:      blockstore.NewInMemory(),
+		tree:        blockstore.NewTreeCache(makeTree(t, filepath.Join(dir, "db"))),
+		verifierFac: fake.VerifierFactory{},
+	}
+	handler.pbftsm = testSM{blocks: handler.blocks, tree: handler.tree}
+
+	src := makeTree(t, filepath.Join(dir, "src"), "A", "B").(hashtree.RangeTree)
+
+	proof, err := src.GetSuffix(nil, 0)
+	require.NoError(t, err)
+
+	right, err := src.GetSuffix([]byte{1}, 1)
+	require.NoError(t, err)
+
+	other := makeTree(t, filepath.Join(dir, "other"), "C").(hashtree.RangeTree)
+
+	otherProof, err := other.GetSuffix(nil, 0)
+	require.NoError(t, err)
+
+	root := otypes.Digest{}
+	copy(root[:], src.GetRoot())
+
+	block, err := otypes.NewBlock(simple.NewResult(nil), otypes.WithTreeRoot(root))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	genesis := otypes.Genesis{}
+	reply := fake.NewRecvMsg(nil, types.NewSnapshotReply(fakeChain{block: block}))
+	chunk := fake.NewRecvMsg(nil, types.NewSnapshotChunk(proof))
+
+	err = handler.restoreSnapshot(ctx, genesis, fake.NewBadSender(), nil, nil)
+	require.EqualError(t, err, fake.Err("sending request failed"))
+
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, fake.NewBadReceiver(), nil)
+	require.EqualError(t, err, fake.Err("receiver failed"))
+
+	recv := fake.NewReceiver(fake.NewRecvMsg(nil, types.NewSnapshotReply(nil)))
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, "snapshot is not available")
+
+	recv = fake.NewReceiver(fake.NewRecvMsg(nil,
+		types.NewSnapshotReply(fakeChain{err: fake.GetError()})))
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fake.Err("failed to verify chain"))
+
+	recv = fake.NewBadReceiver(reply)
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fake.Err("failed to receive chunks: receiver failed"))
+
+	recv = fake.NewReceiver(reply, fake.NewRecvMsg(nil, types.NewSnapshotChunk(otherProof)))
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fmt.Sprintf(
+		"failed to receive chunks: mismatch chunk root %#x != %#x",
+		other.GetRoot(), src.GetRoot()))
+
+	recv = fake.NewReceiver(reply, fake.NewRecvMsg(nil, types.NewSnapshotChunk(right)))
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fmt.Sprintf(
+		"failed to receive chunks: invalid chunk: chunk at %#x does not follow 0x0",
+		new(big.Int).Lsh(big.NewInt(1), 255)))
+
+	handler.tree = blockstore.NewTreeCache(fakeTree{})
+	recv = fake.NewReceiver(reply, chunk)
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, "tree 'blocksync.fakeTree' is not iterable")
+
+	handler.tree = blockstore.NewTreeCache(fakeIterableTree{err: fake.GetError()})
+	recv = fake.NewReceiver(reply, chunk)
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fake.Err("failed to read tree"))
+
+	handler.tree = blockstore.NewTreeCache(fakeIterableTree{errStage: fake.GetError()})
+	recv = fake.NewReceiver(reply, chunk)
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fake.Err("failed to stage tree"))
+
+	handler.tree = blockstore.NewTreeCache(fakeIterableTree{})
+	handler.pbftsm = testSM{err: fake.GetError()}
+	recv = fake.NewReceiver(reply, chunk)
+	err = handler.restoreSnapshot(ctx, genesis, fake.Sender{}, recv, nil)
+	require.EqualError(t, err, fake.Err("pbft restore failed"))
+}
+
 // -----------------------------------------------------------------------------
 // Utility functions
 
@@ -258,7 +877,9 @@ func makeChain(t *testing.T, index uint64) otypes.Chain {
 	return fakeChain{block: block}
 }
 
-func makeNodes(t *testing.T, n int) ([]defaultSync, otypes.Genesis, mino.Players) {
+// makeNodes creates n synchronizers. The options can update the parameter of
+// the synchronizer of each node.
+func makeNodes(t *testing.T, n int, opts ...func(int, *SyncParam)) ([]defaultSync, otypes.Genesis, mino.Players) {
 	manager := minoch.NewManager()
 
 	syncs := make([]defaultSync, n)
@@ -292,18 +913,41 @@ func makeNodes(t *testing.T, n int) ([]defaultSync, otypes.Genesis, mino.Players
 			VerifierFactory: fake.VerifierFactory{},
 		}
 
+		for _, opt := range opts {
+			opt(i, &param)
+		}
+
 		syncs[i] = NewSynchronizer(param).(defaultSync)
 	}
 
 	return syncs, genesis, mino.NewAddresses(addrs...)
 }
 
+func makeLink(t *testing.T, from otypes.Digest, index uint64) otypes.BlockLink {
+	block, err := otypes.NewBlock(simple.NewResult(nil), otypes.WithIndex(index),
+		otypes.WithTreeRoot(otypes.Digest{1}))
+	require.NoError(t, err)
+
+	link, err := otypes.NewBlockLink(from, block)
+	require.NoError(t, err)
+
+	return link
+}
+
 func storeBlocks(t *testing.T, blocks blockstore.BlockStore, n int, from ...byte) {
+	storeTreeBlocks(t, blocks, n, otypes.Digest{}, from...)
+}
+
+// storeTreeBlocks stores n blocks that have the given tree root.
+func storeTreeBlocks(t *testing.T, blocks blockstore.BlockStore, n int,
+	root otypes.Digest, from ...byte) {
+
 	prev := otypes.Digest{}
 	copy(prev[:], from)
 
 	for i := 0; i < n; i++ {
-		block, err := otypes.NewBlock(simple.NewResult(nil), otypes.WithIndex(uint64(i)))
+		block, err := otypes.NewBlock(simple.NewResult(nil), otypes.WithIndex(uint64(i)),
+			otypes.WithTreeRoot(root))
 		require.NoError(t, err)
 
 		link, err := otypes.NewBlockLink(prev, block,
@@ -317,13 +961,45 @@ func storeBlocks(t *testing.T, blocks blockstore.BlockStore, n int, from ...byte
 	}
 }
 
+// makeTree creates a tree stored in a database at the path, with the keys set
+// to themselves.
+func makeTree(t *testing.T, path string, keys ...string) hashtree.Tree {
+	db, err := kv.New(path)
+	require.NoError(t, err)
+
+	tree := binprefix.NewMerkleTree(db, binprefix.Nonce{})
+
+	stage, err := tree.Stage(func(snap store.Snapshot) error {
+		for _, key := range keys {
+			err := snap.Set([]byte(key), []byte(key))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = stage.Commit()
+	require.NoError(t, err)
+
+	return stage
+}
+
 type testSM struct {
 	pbft.StateMachine
 
 	blocks blockstore.BlockStore
+	tree   blockstore.TreeCache
+	err    error
 }
 
 func (sm testSM) CatchUp(link otypes.BlockLink) error {
+	if sm.err != nil {
+		return sm.err
+	}
+
 	err := sm.blocks.Store(link)
 	if err != nil {
 		return err
@@ -332,6 +1008,26 @@ func (sm testSM) CatchUp(link otypes.BlockLink) error {
 	return nil
 }
 
+func (sm testSM) Restore(chain otypes.Chain, tree hashtree.StagingTree) error {
+	if sm.err != nil {
+		return sm.err
+	}
+
+	err := tree.Commit()
+	if err != nil {
+		return err
+	}
+
+	err = sm.blocks.StoreChain(chain)
+	if err != nil {
+		return err
+	}
+
+	sm.tree.Set(tree)
+
+	return nil
+}
+
 type badBlockStore struct {
 	blockstore.BlockStore
 
@@ -364,3 +1060,37 @@ func (c fakeChain) GetBlock() otypes.Block {
 func (c fakeChain) Verify(otypes.Genesis, crypto.VerifierFactory) error {
 	return c.err
 }
+
+type fakeTree struct {
+	hashtree.Tree
+}
+
+type fakeIterableTree struct {
+	hashtree.Tree
+
+	err      error
+	errStage error
+}
+
+func (t fakeIterableTree) ForEach(func(key, value []byte) error) error {
+	return t.err
+}
+
+func (t fakeIterableTree) Stage(func(store.Snapshot) error) (hashtree.StagingTree, error) {
+	return nil, t.errStage
+}
+
+type fakeRangeTree struct {
+	hashtree.RangeTree
+
+	err      error
+	errProof error
+}
+
+func (t fakeRangeTree) ForSuffix(suffix []byte, bits uint16, fn func(key, value []byte) error) error {
+	return t.err
+}
+
+func (t fakeRangeTree) GetSuffix(suffix []byte, bits uint16) (hashtree.SuffixProof, error) {
+	return nil, t.errProof
+}
@@ -0,0 +1,254 @@
+// This file contains the parallel download of the blocks from the members of
+// the roster.
+
+package blocksync
+
+import (
+	"context"
+	"time"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/blocksync/types"
+	otypes "go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// RangeTimeout is the maximum amount of time to wait for a range of blocks
+// from a member before asking another one.
+const RangeTimeout = 20 * time.Second
+
+// blockRange is a range of blocks from the first index included to the last
+// one excluded, alongside the channel that receives the result.
+type blockRange struct {
+	from   uint64
+	to     uint64
+	result chan rangeResult
+}
+
+type rangeResult struct {
+	links []otypes.BlockLink
+	err   error
+}
+
+// Process implements mino.Handler. It returns the blocks of the requested
+// range that the node has stored. A range larger than the configured size is
+// rejected so that a single request cannot make the node read and send its
+// whole chain.
+func (h *handler) Process(req mino.Request) (serde.Message, error) {
+	in, ok := req.Message.(types.BlocksRequest)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", req.Message)
+	}
+
+	if h.rangeSize == 0 {
+		return nil, xerrors.New("range requests are disabled")
+	}
+
+	if in.GetTo() > in.GetFrom() && in.GetTo()-in.GetFrom() > h.rangeSize {
+		return nil, xerrors.Errorf("range [%d, %d) exceeds %d blocks",
+			in.GetFrom(), in.GetTo(), h.rangeSize)
+	}
+
+	links := []otypes.BlockLink{}
+
+	for i := in.GetFrom(); i < in.GetTo() && i < h.blocks.Len(); i++ {
+		link, err := h.blocks.GetByIndex(i)
+		if err != nil {
+			return nil, xerrors.Errorf("reading block %d: %v", i, err)
+		}
+
+		links = append(links, link)
+	}
+
+	return types.NewBlocksReply(links), nil
+}
+
+// download fetches the missing blocks up to the latest block of the chain. The
+// ranges are requested in parallel to the members of the roster, and a range
+// that fails is requested to the next member. The blocks are caught up in
+// order as soon as the ranges arrive.
+func (h *handler) download(chain otypes.Chain, orch mino.Address) error {
+	peers, err := h.getPeers(orch)
+	if err != nil {
+		return xerrors.Errorf("failed to get peers: %v", err)
+	}
+
+	ranges := h.makeRanges(h.blocks.Len(), chain.GetBlock().GetIndex()+1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	jobs := make(chan *blockRange, len(ranges))
+	for _, r := range ranges {
+		jobs <- r
+	}
+
+	close(jobs)
+
+	workers := len(peers)
+	if workers > len(ranges) {
+		workers = len(ranges)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func(offset int) {
+			for r := range jobs {
+				links, err := h.fetchRange(ctx, r, peers, offset, chain)
+				r.result <- rangeResult{links: links, err: err}
+			}
+		}(i)
+	}
+
+	for _, r := range ranges {
+		res := <-r.result
+		if res.err != nil {
+			return xerrors.Errorf("range [%d, %d) failed: %v", r.from, r.to, res.err)
+		}
+
+		for _, link := range res.links {
+			err = h.pbftsm.CatchUp(link)
+			if err != nil {
+				return xerrors.Errorf("pbft catch up failed: %v", err)
+			}
+		}
+	}
+
+	h.logger.Debug().
+		Int("ranges", len(ranges)).
+		Int("peers", len(peers)).
+		Msg("download done")
+
+	return nil
+}
+
+// getPeers returns the members of the roster that can be asked for blocks,
+// which always include the orchestrator.
+func (h *handler) getPeers(orch mino.Address) ([]mino.Address, error) {
+	peers := []mino.Address{orch}
+
+	if h.roster == nil {
+		return peers, nil
+	}
+
+	roster, err := h.roster()
+	if err != nil {
+		return nil, xerrors.Errorf("roster: %v", err)
+	}
+
+	iter := roster.AddressIterator()
+	for iter.HasNext() {
+		addr := iter.GetNext()
+
+		if !addr.Equal(orch) && !addr.Equal(h.me) {
+			peers = append(peers, addr)
+		}
+	}
+
+	return peers, nil
+}
+
+func (h *handler) makeRanges(from, to uint64) []*blockRange {
+	ranges := []*blockRange{}
+
+	for start := from; start < to; start += h.rangeSize {
+		end := start + h.rangeSize
+		if end > to {
+			end = to
+		}
+
+		ranges = append(ranges, &blockRange{
+			from:   start,
+			to:     end,
+			result: make(chan rangeResult, 1),
+		})
+	}
+
+	return ranges
+}
+
+// fetchRange requests the range to the peers, starting from the one at the
+// offset, until one of them returns the valid blocks.
+func (h *handler) fetchRange(ctx context.Context, r *blockRange,
+	peers []mino.Address, offset int, chain otypes.Chain) ([]otypes.BlockLink, error) {
+
+	for i := range peers {
+		peer := peers[(offset+i)%len(peers)]
+
+		links, err := h.fetchFrom(ctx, r, peer, chain)
+		if err == nil {
+			return links, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		h.logger.Warn().Err(err).
+			Stringer("peer", peer).
+			Uint64("from", r.from).
+			Msg("range failed")
+	}
+
+	return nil, xerrors.Errorf("no peer among %d could provide the range", len(peers))
+}
+
+// fetchFrom requests the range to a single peer and verifies that the blocks
+// are the ones of the chain.
+func (h *handler) fetchFrom(ctx context.Context, r *blockRange,
+	peer mino.Address, chain otypes.Chain) ([]otypes.BlockLink, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	resps, err := h.rpc.Call(ctx, types.NewBlocksRequest(r.from, r.to), mino.NewAddresses(peer))
+	if err != nil {
+		return nil, xerrors.Errorf("call failed: %v", err)
+	}
+
+	var msg serde.Message
+
+	select {
+	case <-ctx.Done():
+		return nil, xerrors.Errorf("no reply: %v", ctx.Err())
+	case resp, more := <-resps:
+		if !more {
+			return nil, xerrors.New("no reply")
+		}
+
+		msg, err = resp.GetMessageOrError()
+		if err != nil {
+			return nil, xerrors.Errorf("peer failed: %v", err)
+		}
+	}
+
+	reply, ok := msg.(types.BlocksReply)
+	if !ok {
+		return nil, xerrors.Errorf("invalid reply '%T'", msg)
+	}
+
+	links := reply.GetLinks()
+	if uint64(len(links)) != r.to-r.from {
+		return nil, xerrors.Errorf("missing blocks: %d != %d", len(links), r.to-r.from)
+	}
+
+	expected := chain.GetLinks()
+
+	for i, link := range links {
+		index := r.from + uint64(i)
+
+		if link.GetBlock().GetIndex() != index || index >= uint64(len(expected)) {
+			return nil, xerrors.Errorf("unexpected block %d at %d",
+				link.GetBlock().GetIndex(), index)
+		}
+
+		// The chain is verified so the hash of the block guarantees that it
+		// is the one that has been signed.
+		if link.GetFrom() != expected[index].GetFrom() || link.GetTo() != expected[index].GetTo() {
+			return nil, xerrors.Errorf("mismatch link %d '%v' != '%v'",
+				index, link.GetTo(), expected[index].GetTo())
+		}
+	}
+
+	return links, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"go.dedis.ch/dela/core/ordering/cosipbft/blocksync/types"
 	otypes "go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/serde"
 	"golang.org/x/xerrors"
 )
@@ -31,12 +32,43 @@ type SyncReplyJSON struct {
 // SyncAckJSON is the JSON representation of a sync acknowledgement.
 type SyncAckJSON struct{}
 
+// SnapshotRequestJSON is the JSON representation of a snapshot request.
+type SnapshotRequestJSON struct{}
+
+// SnapshotReplyJSON is the JSON representation of a snapshot reply.
+type SnapshotReplyJSON struct {
+	Chain json.RawMessage `json:",omitempty"`
+}
+
+// SnapshotChunkJSON is the JSON representation of a chunk of a snapshot.
+type SnapshotChunkJSON struct {
+	Proof json.RawMessage
+}
+
+// BlocksRequestJSON is the JSON representation of a request for a range of
+// blocks.
+type BlocksRequestJSON struct {
+	From uint64
+	To   uint64
+}
+
+// BlocksReplyJSON is the JSON representation of the reply with a range of
+// blocks.
+type BlocksReplyJSON struct {
+	Links []json.RawMessage
+}
+
 // MessageJSON is the JSON representation of a sync message.
 type MessageJSON struct {
-	Message *SyncMessageJSON `json:",omitempty"`
-	Request *SyncRequestJSON `json:",omitempty"`
-	Reply   *SyncReplyJSON   `json:",omitempty"`
-	Ack     *SyncAckJSON     `json:",omitempty"`
+	Message         *SyncMessageJSON     `json:",omitempty"`
+	Request         *SyncRequestJSON     `json:",omitempty"`
+	Reply           *SyncReplyJSON       `json:",omitempty"`
+	Ack             *SyncAckJSON         `json:",omitempty"`
+	SnapshotRequest *SnapshotRequestJSON `json:",omitempty"`
+	SnapshotReply   *SnapshotReplyJSON   `json:",omitempty"`
+	SnapshotChunk   *SnapshotChunkJSON   `json:",omitempty"`
+	BlocksRequest   *BlocksRequestJSON   `json:",omitempty"`
+	BlocksReply     *BlocksReplyJSON     `json:",omitempty"`
 }
 
 // MsgFormat is the format engine to encode and decode sync messages.
@@ -80,6 +112,45 @@ func (fmt msgFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error
 		m.Reply = &reply
 	case types.SyncAck:
 		m.Ack = &SyncAckJSON{}
+	case types.SnapshotRequest:
+		m.SnapshotRequest = &SnapshotRequestJSON{}
+	case types.SnapshotReply:
+		reply, err := encodeSnapshot(ctx, in)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to encode snapshot: %v", err)
+		}
+
+		m.SnapshotReply = &reply
+	case types.SnapshotChunk:
+		proof, err := in.GetProof().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("proof serialization failed: %v", err)
+		}
+
+		m.SnapshotChunk = &SnapshotChunkJSON{
+			Proof: proof,
+		}
+	case types.BlocksRequest:
+		m.BlocksRequest = &BlocksRequestJSON{
+			From: in.GetFrom(),
+			To:   in.GetTo(),
+		}
+	case types.BlocksReply:
+		links := in.GetLinks()
+		reply := BlocksReplyJSON{
+			Links: make([]json.RawMessage, len(links)),
+		}
+
+		for i, link := range links {
+			data, err := link.Serialize(ctx)
+			if err != nil {
+				return nil, xerrors.Errorf("link serialization failed: %v", err)
+			}
+
+			reply.Links[i] = data
+		}
+
+		m.BlocksReply = &reply
 	default:
 		return nil, xerrors.Errorf("unsupported message '%T'", msg)
 	}
@@ -141,5 +212,96 @@ func (fmt msgFormat) Decode(ctx serde.Context, data []byte) (serde.Message, erro
 		return types.NewSyncAck(), nil
 	}
 
+	if m.SnapshotRequest != nil {
+		return types.NewSnapshotRequest(), nil
+	}
+
+	if m.SnapshotReply != nil {
+		reply, err := decodeSnapshot(ctx, *m.SnapshotReply)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to decode snapshot: %v", err)
+		}
+
+		return reply, nil
+	}
+
+	if m.SnapshotChunk != nil {
+		fac := ctx.GetFactory(types.ProofKey{})
+
+		factory, ok := fac.(hashtree.SuffixProofFactory)
+		if !ok {
+			return nil, xerrors.Errorf("invalid proof factory '%T'", fac)
+		}
+
+		proof, err := factory.SuffixProofOf(ctx, m.SnapshotChunk.Proof)
+		if err != nil {
+			return nil, xerrors.Errorf("couldn't decode proof: %v", err)
+		}
+
+		return types.NewSnapshotChunk(proof), nil
+	}
+
+	if m.BlocksRequest != nil {
+		return types.NewBlocksRequest(m.BlocksRequest.From, m.BlocksRequest.To), nil
+	}
+
+	if m.BlocksReply != nil {
+		fac := ctx.GetFactory(types.LinkKey{})
+
+		factory, ok := fac.(otypes.LinkFactory)
+		if !ok {
+			return nil, xerrors.Errorf("invalid link factory '%T'", fac)
+		}
+
+		links := make([]otypes.BlockLink, len(m.BlocksReply.Links))
+
+		for i, data := range m.BlocksReply.Links {
+			link, err := factory.BlockLinkOf(ctx, data)
+			if err != nil {
+				return nil, xerrors.Errorf("couldn't decode link: %v", err)
+			}
+
+			links[i] = link
+		}
+
+		return types.NewBlocksReply(links), nil
+	}
+
 	return nil, xerrors.New("message is empty")
 }
+
+func encodeSnapshot(ctx serde.Context, in types.SnapshotReply) (SnapshotReplyJSON, error) {
+	reply := SnapshotReplyJSON{}
+
+	if in.GetChain() != nil {
+		chain, err := in.GetChain().Serialize(ctx)
+		if err != nil {
+			return reply, xerrors.Errorf("chain serialization failed: %v", err)
+		}
+
+		reply.Chain = chain
+	}
+
+	return reply, nil
+}
+
+func decodeSnapshot(ctx serde.Context, m SnapshotReplyJSON) (types.SnapshotReply, error) {
+	var chain otypes.Chain
+
+	if m.Chain != nil {
+		fac := ctx.GetFactory(types.ChainKey{})
+
+		factory, ok := fac.(otypes.ChainFactory)
+		if !ok {
+			return types.SnapshotReply{}, xerrors.Errorf("invalid chain factory '%T'", fac)
+		}
+
+		var err error
+		chain, err = factory.ChainOf(ctx, m.Chain)
+		if err != nil {
+			return types.SnapshotReply{}, xerrors.Errorf("couldn't decode chain: %v", err)
+		}
+	}
+
+	return types.NewSnapshotReply(chain), nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/ordering/cosipbft/blocksync/types"
 	otypes "go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/serde"
 )
@@ -31,9 +32,43 @@ func TestMsgFormat_Encode(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, `{"Ack":{}}`, string(data))
 
+	data, err = format.Encode(ctx, types.NewSnapshotRequest())
+	require.NoError(t, err)
+	require.Equal(t, `{"SnapshotRequest":{}}`, string(data))
+
+	data, err = format.Encode(ctx, types.NewSnapshotReply(fakeChain{}))
+	require.NoError(t, err)
+	require.Equal(t, `{"SnapshotReply":{"Chain":{}}}`, string(data))
+
+	data, err = format.Encode(ctx, types.NewSnapshotReply(nil))
+	require.NoError(t, err)
+	require.Equal(t, `{"SnapshotReply":{}}`, string(data))
+
+	data, err = format.Encode(ctx, types.NewSnapshotChunk(fakeProof{}))
+	require.NoError(t, err)
+	require.Equal(t, `{"SnapshotChunk":{"Proof":{}}}`, string(data))
+
+	data, err = format.Encode(ctx, types.NewBlocksRequest(1, 3))
+	require.NoError(t, err)
+	require.Equal(t, `{"BlocksRequest":{"From":1,"To":3}}`, string(data))
+
+	data, err = format.Encode(ctx, types.NewBlocksReply([]otypes.BlockLink{fakeLink{}}))
+	require.NoError(t, err)
+	require.Equal(t, `{"BlocksReply":{"Links":[{}]}}`, string(data))
+
 	_, err = format.Encode(ctx, fake.Message{})
 	require.EqualError(t, err, "unsupported message 'fake.Message'")
 
+	_, err = format.Encode(ctx, types.NewBlocksReply([]otypes.BlockLink{fakeLink{err: fake.GetError()}}))
+	require.EqualError(t, err, fake.Err("link serialization failed"))
+
+	_, err = format.Encode(ctx, types.NewSnapshotReply(fakeChain{err: fake.GetError()}))
+	require.EqualError(t, err,
+		fake.Err("failed to encode snapshot: chain serialization failed"))
+
+	_, err = format.Encode(ctx, types.NewSnapshotChunk(fakeProof{err: fake.GetError()}))
+	require.EqualError(t, err, fake.Err("proof serialization failed"))
+
 	_, err = format.Encode(ctx, types.NewSyncMessage(fakeChain{err: fake.GetError()}))
 	require.EqualError(t, err, fake.Err("failed to encode chain"))
 
@@ -50,6 +85,7 @@ func TestMsgFormat_Decode(t *testing.T) {
 	ctx := fake.NewContext()
 	ctx = serde.WithFactory(ctx, types.LinkKey{}, fakeLinkFac{})
 	ctx = serde.WithFactory(ctx, types.ChainKey{}, fakeChainFac{})
+	ctx = serde.WithFactory(ctx, types.ProofKey{}, fakeProofFac{})
 
 	msg, err := format.Decode(ctx, []byte(`{"Message":{}}`))
 	require.NoError(t, err)
@@ -67,27 +103,73 @@ func TestMsgFormat_Decode(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, types.NewSyncAck(), msg)
 
+	msg, err = format.Decode(ctx, []byte(`{"SnapshotRequest":{}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewSnapshotRequest(), msg)
+
+	msg, err = format.Decode(ctx, []byte(`{"SnapshotReply":{"Chain":{}}}`))
+	require.NoError(t, err)
+	require.Equal(t, fakeChain{}, msg.(types.SnapshotReply).GetChain())
+
+	msg, err = format.Decode(ctx, []byte(`{"SnapshotReply":{}}`))
+	require.NoError(t, err)
+	require.Nil(t, msg.(types.SnapshotReply).GetChain())
+
+	msg, err = format.Decode(ctx, []byte(`{"SnapshotChunk":{"Proof":{}}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewSnapshotChunk(fakeProof{}), msg)
+
+	msg, err = format.Decode(ctx, []byte(`{"BlocksRequest":{"From":1,"To":3}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewBlocksRequest(1, 3), msg)
+
+	msg, err = format.Decode(ctx, []byte(`{"BlocksReply":{"Links":[{}]}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewBlocksReply([]otypes.BlockLink{fakeLink{}}), msg)
+
 	_, err = format.Decode(ctx, []byte(`{}`))
 	require.EqualError(t, err, "message is empty")
 
 	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
 	require.EqualError(t, err, fake.Err("unmarshal failed"))
 
+	ctx = serde.WithFactory(ctx, types.ProofKey{}, fakeProofFac{err: fake.GetError()})
+	_, err = format.Decode(ctx, []byte(`{"SnapshotChunk":{"Proof":{}}}`))
+	require.EqualError(t, err, fake.Err("couldn't decode proof"))
+
+	ctx = serde.WithFactory(ctx, types.ProofKey{}, nil)
+	_, err = format.Decode(ctx, []byte(`{"SnapshotChunk":{"Proof":{}}}`))
+	require.EqualError(t, err, "invalid proof factory '<nil>'")
+
 	ctx = serde.WithFactory(ctx, types.ChainKey{}, fakeChainFac{err: fake.GetError()})
 	_, err = format.Decode(ctx, []byte(`{"Message":{}}`))
 	require.EqualError(t, err, fake.Err("failed to decode chain"))
 
+	_, err = format.Decode(ctx, []byte(`{"SnapshotReply":{"Chain":{}}}`))
+	require.EqualError(t, err,
+		fake.Err("failed to decode snapshot: couldn't decode chain"))
+
 	ctx = serde.WithFactory(ctx, types.ChainKey{}, fake.MessageFactory{})
 	_, err = format.Decode(ctx, []byte(`{"Message":{}}`))
 	require.EqualError(t, err, "invalid chain factory 'fake.MessageFactory'")
 
+	_, err = format.Decode(ctx, []byte(`{"SnapshotReply":{"Chain":{}}}`))
+	require.EqualError(t, err,
+		"failed to decode snapshot: invalid chain factory 'fake.MessageFactory'")
+
 	ctx = serde.WithFactory(ctx, types.LinkKey{}, fakeLinkFac{err: fake.GetError()})
 	_, err = format.Decode(ctx, []byte(`{"Reply":{"Link":{}}}`))
 	require.EqualError(t, err, fake.Err("couldn't decode link"))
 
+	_, err = format.Decode(ctx, []byte(`{"BlocksReply":{"Links":[{}]}}`))
+	require.EqualError(t, err, fake.Err("couldn't decode link"))
+
 	ctx = serde.WithFactory(ctx, types.LinkKey{}, fake.MessageFactory{})
 	_, err = format.Decode(ctx, []byte(`{"Reply":{"Link":{}}}`))
 	require.EqualError(t, err, "invalid link factory 'fake.MessageFactory'")
+
+	_, err = format.Decode(ctx, []byte(`{"BlocksReply":{}}`))
+	require.EqualError(t, err, "invalid link factory 'fake.MessageFactory'")
 }
 
 // -----------------------------------------------------------------------------
@@ -132,3 +214,23 @@ type fakeLinkFac struct {
 func (fac fakeLinkFac) BlockLinkOf(serde.Context, []byte) (otypes.BlockLink, error) {
 	return fakeLink{}, fac.err
 }
+
+type fakeProof struct {
+	hashtree.SuffixProof
+
+	err error
+}
+
+func (proof fakeProof) Serialize(serde.Context) ([]byte, error) {
+	return []byte("{}"), proof.err
+}
+
+type fakeProofFac struct {
+	hashtree.SuffixProofFactory
+
+	err error
+}
+
+func (fac fakeProofFac) SuffixProofOf(serde.Context, []byte) (hashtree.SuffixProof, error) {
+	return fakeProof{}, fac.err
+}
@@ -4,6 +4,10 @@
 // announcement with the latest known block, and share the chain to the nodes
 // that have fallen behind.
 //
+// A node without any block can instead request a snapshot of the state of the
+// leader, alongside the chain that proves the tree root of the latest block,
+// when it misses enough blocks. The blocks are replayed if the snapshot fails.
+//
 // Documentation Last Review: 13.10.2020
 //
 package blocksync
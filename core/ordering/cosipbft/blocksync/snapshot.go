@@ -0,0 +1,112 @@
+// This file contains the split of a snapshot into chunks of the tree that are
+// verified independently against the tree root.
+
+package blocksync
+
+import (
+	"math/big"
+
+	"go.dedis.ch/dela/core/store/hashtree"
+	"golang.org/x/xerrors"
+)
+
+// SnapshotChunkSize is the maximum number of key/value pairs sent in a single
+// chunk of a snapshot, unless the pairs share a complete key.
+const SnapshotChunkSize = 1000
+
+// maxSuffixBits is the length in bits of the longest key of the tree, which is
+// the longest suffix of a chunk.
+const maxSuffixBits = 256
+
+// makeChunks returns the proofs of the subtrees of the suffix, that holds at
+// most the given number of pairs each, in the order of the tree.
+func makeChunks(tree hashtree.RangeTree, suffix *big.Int, bits uint16,
+	size int) ([]hashtree.SuffixProof, error) {
+
+	count := 0
+	full := false
+
+	err := tree.ForSuffix(suffix.Bytes(), bits, func(key, value []byte) error {
+		count++
+
+		if count > size {
+			// The iteration is stopped as soon as the subtree is too big.
+			full = true
+			return xerrors.New("chunk is full")
+		}
+
+		return nil
+	})
+
+	if err != nil && !full {
+		return nil, xerrors.Errorf("failed to count suffix: %v", err)
+	}
+
+	if !full || bits >= maxSuffixBits {
+		proof, err := tree.GetSuffix(suffix.Bytes(), bits)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to prove suffix: %v", err)
+		}
+
+		return []hashtree.SuffixProof{proof}, nil
+	}
+
+	left, err := makeChunks(tree, suffix, bits+1, size)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := makeChunks(tree, new(big.Int).SetBit(suffix, int(bits), 1),
+		bits+1, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(left, right...), nil
+}
+
+// chunkCursor verifies that the chunks of a snapshot are the subtrees of the
+// tree in order, so that every key is covered exactly once.
+type chunkCursor struct {
+	pos *big.Int
+	end *big.Int
+}
+
+func newChunkCursor() chunkCursor {
+	return chunkCursor{
+		pos: new(big.Int),
+		end: new(big.Int).Lsh(big.NewInt(1), maxSuffixBits),
+	}
+}
+
+// Done returns true when the chunks cover the whole tree.
+func (c chunkCursor) Done() bool {
+	return c.pos.Cmp(c.end) >= 0
+}
+
+// Next moves the cursor after the subtree of the suffix, or returns an error if
+// the subtree does not start at the cursor.
+func (c chunkCursor) Next(suffix []byte, bits uint16) error {
+	if bits > maxSuffixBits {
+		return xerrors.Errorf("suffix is too long: %d > %d", bits, maxSuffixBits)
+	}
+
+	// The tree branches on the last bit first, so the subtrees are in order
+	// when the bits of the suffix are reversed.
+	key := new(big.Int).SetBytes(suffix)
+	pos := new(big.Int)
+
+	for i := 0; i < int(bits); i++ {
+		pos.SetBit(pos, maxSuffixBits-1-i, key.Bit(i))
+	}
+
+	if pos.Cmp(c.pos) != 0 {
+		return xerrors.Errorf("chunk at %#x does not follow %#x", pos, c.pos)
+	}
+
+	width := new(big.Int).Lsh(big.NewInt(1), uint(maxSuffixBits-bits))
+
+	c.pos.Add(c.pos, width)
+
+	return nil
+}
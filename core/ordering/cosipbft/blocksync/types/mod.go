@@ -9,6 +9,7 @@ package types
 
 import (
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/dela/serde/registry"
 	"golang.org/x/xerrors"
@@ -148,25 +149,201 @@ func (m SyncAck) Serialize(ctx serde.Context) ([]byte, error) {
 	return data, nil
 }
 
+// SnapshotRequest is a message to request a snapshot of the state of the
+// leader instead of the missing blocks.
+//
+// - implements serde.Message
+type SnapshotRequest struct{}
+
+// NewSnapshotRequest creates a new snapshot request.
+func NewSnapshotRequest() SnapshotRequest {
+	return SnapshotRequest{}
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m SnapshotRequest) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// SnapshotReply is a message to announce the snapshot of the state of the
+// leader to a participant, with the chain that proves the tree root of the
+// state. The state follows in chunks. A reply without a chain means that the
+// leader could not create the snapshot.
+//
+// - implements serde.Message
+type SnapshotReply struct {
+	chain types.Chain
+}
+
+// NewSnapshotReply creates a new snapshot reply.
+func NewSnapshotReply(chain types.Chain) SnapshotReply {
+	return SnapshotReply{
+		chain: chain,
+	}
+}
+
+// GetChain returns the chain to the block of the snapshot, or nil if the
+// snapshot is not available.
+func (m SnapshotReply) GetChain() types.Chain {
+	return m.chain
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m SnapshotReply) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// SnapshotChunk is a message to send a part of the state of a snapshot. The
+// proof contains the key/value pairs of a subtree and proves that they are
+// complete against the tree root of the snapshot.
+//
+// - implements serde.Message
+type SnapshotChunk struct {
+	proof hashtree.SuffixProof
+}
+
+// NewSnapshotChunk creates a new chunk of a snapshot.
+func NewSnapshotChunk(proof hashtree.SuffixProof) SnapshotChunk {
+	return SnapshotChunk{
+		proof: proof,
+	}
+}
+
+// GetProof returns the proof of the key/value pairs of the chunk.
+func (m SnapshotChunk) GetProof() hashtree.SuffixProof {
+	return m.proof
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m SnapshotChunk) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// BlocksRequest is a message to request the blocks of a range to any member of
+// the roster.
+//
+// - implements serde.Message
+type BlocksRequest struct {
+	from uint64
+	to   uint64
+}
+
+// NewBlocksRequest creates a new request for the blocks from the first index
+// included to the second one excluded.
+func NewBlocksRequest(from, to uint64) BlocksRequest {
+	return BlocksRequest{
+		from: from,
+		to:   to,
+	}
+}
+
+// GetFrom returns the index of the first block of the range.
+func (m BlocksRequest) GetFrom() uint64 {
+	return m.from
+}
+
+// GetTo returns the index after the last block of the range.
+func (m BlocksRequest) GetTo() uint64 {
+	return m.to
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m BlocksRequest) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// BlocksReply is a message to send the blocks of a range. It can contain less
+// blocks than requested if the node does not know them.
+//
+// - implements serde.Message
+type BlocksReply struct {
+	links []types.BlockLink
+}
+
+// NewBlocksReply creates a new reply with the links of the blocks in order.
+func NewBlocksReply(links []types.BlockLink) BlocksReply {
+	return BlocksReply{
+		links: links,
+	}
+}
+
+// GetLinks returns the links of the blocks in order.
+func (m BlocksReply) GetLinks() []types.BlockLink {
+	return append([]types.BlockLink{}, m.links...)
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m BlocksReply) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
 // LinkKey is the key of the block link factory.
 type LinkKey struct{}
 
 // ChainKey is the key of the chain factory.
 type ChainKey struct{}
 
+// ProofKey is the key of the suffix proof factory.
+type ProofKey struct{}
+
 // MessageFactory is a message factory for sync messages.
 //
 // - implements serde.Factory
 type MessageFactory struct {
 	linkFac  types.LinkFactory
 	chainFac types.ChainFactory
+	proofFac hashtree.SuffixProofFactory
 }
 
-// NewMessageFactory createsa new message factory.
-func NewMessageFactory(fac types.LinkFactory, chainFac types.ChainFactory) MessageFactory {
+// NewMessageFactory createsa new message factory. The proof factory is only
+// required to receive the chunks of the snapshots.
+func NewMessageFactory(fac types.LinkFactory, chainFac types.ChainFactory,
+	proofFac hashtree.SuffixProofFactory) MessageFactory {
+
 	return MessageFactory{
 		linkFac:  fac,
 		chainFac: chainFac,
+		proofFac: proofFac,
 	}
 }
 
@@ -177,6 +354,7 @@ func (fac MessageFactory) Deserialize(ctx serde.Context, data []byte) (serde.Mes
 
 	ctx = serde.WithFactory(ctx, LinkKey{}, fac.linkFac)
 	ctx = serde.WithFactory(ctx, ChainKey{}, fac.chainFac)
+	ctx = serde.WithFactory(ctx, ProofKey{}, fac.proofFac)
 
 	msg, err := format.Decode(ctx, data)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/core/validation/simple"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/serde"
@@ -91,12 +92,99 @@ func TestSyncAck_Serialize(t *testing.T) {
 	require.EqualError(t, err, fake.Err("encoding failed"))
 }
 
+func TestSnapshotRequest_Serialize(t *testing.T) {
+	m := NewSnapshotRequest()
+
+	data, err := m.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = m.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestSnapshotReply_GetChain(t *testing.T) {
+	m := NewSnapshotReply(makeChain(t, 2))
+
+	require.NotNil(t, m.GetChain())
+
+	m = NewSnapshotReply(nil)
+
+	require.Nil(t, m.GetChain())
+}
+
+func TestSnapshotReply_Serialize(t *testing.T) {
+	m := NewSnapshotReply(makeChain(t, 2))
+
+	data, err := m.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = m.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestSnapshotChunk_GetProof(t *testing.T) {
+	m := NewSnapshotChunk(fakeProof{})
+
+	require.Equal(t, fakeProof{}, m.GetProof())
+}
+
+func TestSnapshotChunk_Serialize(t *testing.T) {
+	m := NewSnapshotChunk(fakeProof{})
+
+	data, err := m.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = m.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestBlocksRequest_Getters(t *testing.T) {
+	m := NewBlocksRequest(2, 5)
+
+	require.Equal(t, uint64(2), m.GetFrom())
+	require.Equal(t, uint64(5), m.GetTo())
+}
+
+func TestBlocksRequest_Serialize(t *testing.T) {
+	m := NewBlocksRequest(0, 1)
+
+	data, err := m.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = m.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestBlocksReply_GetLinks(t *testing.T) {
+	link, err := types.NewBlockLink(types.Digest{1}, types.Block{})
+	require.NoError(t, err)
+
+	m := NewBlocksReply([]types.BlockLink{link})
+
+	require.Equal(t, []types.BlockLink{link}, m.GetLinks())
+}
+
+func TestBlocksReply_Serialize(t *testing.T) {
+	m := NewBlocksReply(nil)
+
+	data, err := m.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = m.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
 func TestMessageFactory_Deserialize(t *testing.T) {
 	testCalls.Clear()
 
 	linkFac := types.NewLinkFactory(nil, nil, nil)
 
-	fac := NewMessageFactory(linkFac, types.NewChainFactory(linkFac))
+	fac := NewMessageFactory(linkFac, types.NewChainFactory(linkFac), nil)
 
 	msg, err := fac.Deserialize(fake.NewContext(), nil)
 	require.NoError(t, err)
@@ -105,6 +193,9 @@ func TestMessageFactory_Deserialize(t *testing.T) {
 	factory := testCalls.Get(0, 0).(serde.Context).GetFactory(LinkKey{})
 	require.NotNil(t, factory)
 
+	factory = testCalls.Get(0, 0).(serde.Context).GetFactory(ChainKey{})
+	require.NotNil(t, factory)
+
 	_, err = fac.Deserialize(fake.NewBadContext(), nil)
 	require.EqualError(t, err, fake.Err("decoding failed"))
 }
@@ -121,3 +212,7 @@ func makeChain(t *testing.T, index uint64) types.Chain {
 
 	return types.NewChain(link, nil)
 }
+
+type fakeProof struct {
+	hashtree.SuffixProof
+}
@@ -11,8 +11,12 @@ import (
 	"go.dedis.ch/dela/core/execution"
 	"go.dedis.ch/dela/core/execution/native"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/cosi/threshold"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/mino"
 	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/dela/serde/json"
 	"golang.org/x/xerrors"
@@ -25,6 +29,18 @@ const (
 	// AuthorityArg is the key of the argument for the new authority.
 	AuthorityArg = "viewchange:authority"
 
+	// RotationArg is the key of the argument for the signature of a public
+	// key rotation, which is created with the key being replaced.
+	RotationArg = "viewchange:rotation"
+
+	// LimitsArg is the key of the argument for the new limits of the blocks,
+	// which replaces the roster change in the transaction.
+	LimitsArg = "viewchange:limits"
+
+	// rotationDomain is the prefix of the messages that approve a rotation, so
+	// that the signature cannot be used for another purpose.
+	rotationDomain = "dela:rotation:"
+
 	messageOnlyOne          = "only one view change per block is allowed"
 	messageArgMissing       = "authority not found in transaction"
 	messageStorageEmpty     = "authority not found in storage"
@@ -33,6 +49,11 @@ const (
 	messageStorageFailure   = "storage failure"
 	messageDuplicate        = "duplicate in roster"
 	messageUnauthorized     = "unauthorized identity"
+	messageBelowThreshold   = "not enough members left"
+	messageRotationMissing  = "rotation signature not found in transaction"
+	messageInvalidRotation  = "invalid rotation signature"
+	messageInvalidLimits    = "invalid limits"
+	messageLimitsTooLow     = "limits too low"
 )
 
 // RegisterContract registers the view change contract to the given execution
@@ -64,15 +85,100 @@ func NewManager(mgr txn.Manager) Manager {
 // Make creates a new transaction using the provided manager. It contains the
 // new roster that the transaction should apply.
 func (mgr Manager) Make(roster authority.Authority) (txn.Transaction, error) {
+	return mgr.make(roster)
+}
+
+// MakeRemove creates a new transaction that requests the removal of the member
+// with the given address from the roster.
+func (mgr Manager) MakeRemove(roster authority.Authority, addr mino.Address) (txn.Transaction, error) {
+	_, index := roster.GetPublicKey(addr)
+	if index < 0 {
+		return nil, xerrors.Errorf("member '%v' not found in roster", addr)
+	}
+
+	cset := authority.NewChangeSet()
+	cset.Remove(uint(index))
+
+	tx, err := mgr.Make(roster.Apply(cset))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to make transaction: %v", err)
+	}
+
+	return tx, nil
+}
+
+// MakeRotate creates a new transaction that replaces the public key of the
+// member with the given address. The signer must be the one of the key being
+// replaced so that the contract can verify the member agrees on the rotation.
+// The signature is bound to the current roster so that it cannot be replayed
+// once the roster has changed.
+func (mgr Manager) MakeRotate(roster authority.Authority, addr mino.Address,
+	pubkey crypto.PublicKey, signer crypto.Signer) (txn.Transaction, error) {
+
+	_, index := roster.GetPublicKey(addr)
+	if index < 0 {
+		return nil, xerrors.Errorf("member '%v' not found in roster", addr)
+	}
+
+	msg, err := rotationMessage(roster, addr, pubkey)
+	if err != nil {
+		return nil, xerrors.Errorf("rotation message: %v", err)
+	}
+
+	sig, err := signer.Sign(msg)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to sign rotation: %v", err)
+	}
+
+	sigData, err := sig.Serialize(mgr.context)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize signature: %v", err)
+	}
+
+	cset := authority.NewChangeSet()
+	cset.Rotate(uint(index), pubkey)
+
+	tx, err := mgr.make(roster.Apply(cset), txn.Arg{Key: RotationArg, Value: sigData})
+	if err != nil {
+		return nil, xerrors.Errorf("failed to make transaction: %v", err)
+	}
+
+	return tx, nil
+}
+
+// MakeLimits creates a new transaction that replaces the limits of the blocks.
+// Zero values remove the limits.
+func (mgr Manager) MakeLimits(limits types.Limits) (txn.Transaction, error) {
+	data, err := limits.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal limits: %v", err)
+	}
+
+	args := []txn.Arg{
+		{Key: native.ContractArg, Value: []byte(ContractName)},
+		{Key: LimitsArg, Value: data},
+	}
+
+	tx, err := mgr.manager.Make(args...)
+	if err != nil {
+		return nil, xerrors.Errorf("creating transaction: %v", err)
+	}
+
+	return tx, nil
+}
+
+func (mgr Manager) make(roster authority.Authority, extra ...txn.Arg) (txn.Transaction, error) {
 	data, err := roster.Serialize(mgr.context)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to serialize roster: %v", err)
 	}
 
-	tx, err := mgr.manager.Make(
-		txn.Arg{Key: native.ContractArg, Value: []byte(ContractName)},
-		txn.Arg{Key: AuthorityArg, Value: data},
-	)
+	args := []txn.Arg{
+		{Key: native.ContractArg, Value: []byte(ContractName)},
+		{Key: AuthorityArg, Value: data},
+	}
+
+	tx, err := mgr.manager.Make(append(args, extra...)...)
 	if err != nil {
 		return nil, xerrors.Errorf("creating transaction: %v", err)
 	}
@@ -81,22 +187,31 @@ func (mgr Manager) Make(roster authority.Authority) (txn.Transaction, error) {
 }
 
 // Contract is a contract to update the roster at a given key in the storage. It
-// only allows one member change per transaction.
+// only allows one member change per transaction. It also updates the limits of
+// the blocks at another key.
 //
 // - implements native.Contract
 type Contract struct {
 	rosterKey []byte
+	limitsKey []byte
 	rosterFac authority.Factory
+	sigFac    crypto.SignatureFactory
 	accessKey []byte
 	access    access.Service
 	context   serde.Context
 }
 
-// NewContract creates a new viewchange contract.
-func NewContract(rKey, aKey []byte, rFac authority.Factory, srvc access.Service) Contract {
+// NewContract creates a new viewchange contract. The signature factory is used
+// to decode the signatures of the public key rotations, which are created by
+// the signers of the members.
+func NewContract(rKey, lKey, aKey []byte, rFac authority.Factory,
+	sigFac crypto.SignatureFactory, srvc access.Service) Contract {
+
 	return Contract{
 		rosterKey: rKey,
+		limitsKey: lKey,
 		rosterFac: rFac,
+		sigFac:    sigFac,
 		accessKey: aKey,
 		access:    srvc,
 		context:   json.NewContext(),
@@ -105,7 +220,10 @@ func NewContract(rKey, aKey []byte, rFac authority.Factory, srvc access.Service)
 
 // Execute implements native.Contract. It looks for the roster in the
 // transaction and updates the storage if there is at most one membership
-// change.
+// change. A removal is refused if the remaining members could not reach the
+// byzantine threshold of the current roster, and a public key rotation must be
+// signed by the key being replaced. A transaction with new limits updates the
+// limits of the blocks instead.
 func (c Contract) Execute(snap store.Snapshot, step execution.Step) error {
 	for _, tx := range step.Previous {
 		// Only one view change transaction is allowed per block to prevent
@@ -115,6 +233,10 @@ func (c Contract) Execute(snap store.Snapshot, step execution.Step) error {
 		}
 	}
 
+	if step.Current.GetArg(LimitsArg) != nil {
+		return c.executeLimits(snap, step.Current)
+	}
+
 	roster, err := c.rosterFac.AuthorityOf(c.context, step.Current.GetArg(AuthorityArg))
 	if err != nil {
 		reportErr(step.Current, xerrors.Errorf("incoming roster: %v", err))
@@ -142,6 +264,13 @@ func (c Contract) Execute(snap store.Snapshot, step execution.Step) error {
 		return xerrors.New(messageTooManyChanges)
 	}
 
+	// A removal must leave enough members so that the current participants
+	// would still be able to reach the threshold of signatures.
+	if roster.Len() < threshold.ByzantineThreshold(curr.Len()) {
+		return xerrors.Errorf("%s: %d < %d", messageBelowThreshold,
+			roster.Len(), threshold.ByzantineThreshold(curr.Len()))
+	}
+
 	for _, addr := range changeset.GetNewAddresses() {
 		_, index := curr.GetPublicKey(addr)
 		if index >= 0 {
@@ -149,6 +278,15 @@ func (c Contract) Execute(snap store.Snapshot, step execution.Step) error {
 		}
 	}
 
+	for _, index := range changeset.GetRotateIndices() {
+		err = c.verifyRotation(curr, roster, index, step.Current)
+		if err != nil {
+			reportErr(step.Current, xerrors.Errorf("rotation: %v", err))
+
+			return xerrors.New(messageInvalidRotation)
+		}
+	}
+
 	creds := NewCreds(c.accessKey)
 
 	err = c.access.Match(snap, creds, step.Current.GetIdentity())
@@ -168,6 +306,112 @@ func (c Contract) Execute(snap store.Snapshot, step execution.Step) error {
 	return nil
 }
 
+// executeLimits updates the limits of the blocks. The new limits must at least
+// allow the transaction itself so that the limits can always be changed again.
+func (c Contract) executeLimits(snap store.Snapshot, tx txn.Transaction) error {
+	limits := types.Limits{}
+
+	err := limits.UnmarshalBinary(tx.GetArg(LimitsArg))
+	if err != nil {
+		reportErr(tx, xerrors.Errorf("incoming limits: %v", err))
+
+		return xerrors.New(messageInvalidLimits)
+	}
+
+	err = limits.Check(c.context, []txn.Transaction{tx})
+	if err != nil {
+		return xerrors.Errorf("%s: %v", messageLimitsTooLow, err)
+	}
+
+	creds := NewCreds(c.accessKey)
+
+	err = c.access.Match(snap, creds, tx.GetIdentity())
+	if err != nil {
+		reportErr(tx, xerrors.Errorf("access control: %v", err))
+
+		return xerrors.Errorf("%s: %v", messageUnauthorized, tx.GetIdentity())
+	}
+
+	err = snap.Set(c.limitsKey, tx.GetArg(LimitsArg))
+	if err != nil {
+		reportErr(tx, xerrors.Errorf("writing store: %v", err))
+
+		return xerrors.New(messageStorageFailure)
+	}
+
+	return nil
+}
+
+// verifyRotation makes sure that the public key rotation of the member at the
+// given index is signed by the key being replaced.
+func (c Contract) verifyRotation(curr, next authority.Authority, index uint, tx txn.Transaction) error {
+	iter := curr.AddressIterator()
+	iter.Seek(int(index))
+
+	addr := iter.GetNext()
+	if addr == nil {
+		return xerrors.Errorf("index %d out of range", index)
+	}
+
+	prev, _ := curr.GetPublicKey(addr)
+
+	pubkey, _ := next.GetPublicKey(addr)
+	if pubkey == nil {
+		return xerrors.Errorf("missing public key for '%v'", addr)
+	}
+
+	sigData := tx.GetArg(RotationArg)
+	if len(sigData) == 0 {
+		return xerrors.New(messageRotationMissing)
+	}
+
+	sig, err := c.sigFac.SignatureOf(c.context, sigData)
+	if err != nil {
+		return xerrors.Errorf("failed to decode signature: %v", err)
+	}
+
+	msg, err := rotationMessage(curr, addr, pubkey)
+	if err != nil {
+		return xerrors.Errorf("rotation message: %v", err)
+	}
+
+	err = prev.Verify(msg, sig)
+	if err != nil {
+		return xerrors.Errorf("verify: %v", err)
+	}
+
+	return nil
+}
+
+// rotationMessage returns the message that the previous key of a member must
+// sign to approve the new public key. It includes the digest of the roster the
+// rotation applies to.
+func rotationMessage(roster authority.Authority, addr mino.Address,
+	pubkey crypto.PublicKey) ([]byte, error) {
+
+	h := crypto.NewSha256Factory().New()
+
+	err := roster.Fingerprint(h)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to fingerprint roster: %v", err)
+	}
+
+	addrData, err := addr.MarshalText()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal address: %v", err)
+	}
+
+	pubkeyData, err := pubkey.MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal public key: %v", err)
+	}
+
+	msg := append([]byte(rotationDomain), h.Sum(nil)...)
+	msg = append(msg, addrData...)
+
+	return append(msg, pubkeyData...), nil
+}
+
 // reportErr prints a log with the actual error while the transaction will
 // contain a simplified explanation.
 func reportErr(tx txn.Transaction, err error) {
@@ -8,11 +8,14 @@ import (
 	"go.dedis.ch/dela/core/execution"
 	"go.dedis.ch/dela/core/execution/native"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/txn"
 	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/crypto/bls"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
 )
 
 func TestRegisterContract(t *testing.T) {
@@ -37,12 +40,202 @@ func TestNewTransaction(t *testing.T) {
 	require.EqualError(t, err, fake.Err("creating transaction"))
 }
 
+func TestManager_MakeRemove(t *testing.T) {
+	mgr := NewManager(signed.NewManager(fake.NewSigner(), nil))
+
+	roster := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	tx, err := mgr.MakeRemove(roster, fake.NewAddress(1))
+	require.NoError(t, err)
+	require.NotNil(t, tx)
+
+	fac := authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
+	next, err := fac.AuthorityOf(mgr.context, tx.GetArg(AuthorityArg))
+	require.NoError(t, err)
+	require.Equal(t, 2, next.Len())
+
+	_, err = mgr.MakeRemove(roster, fake.NewAddress(5))
+	require.EqualError(t, err, "member 'fake.Address[5]' not found in roster")
+
+	mgr.manager = badManager{}
+	_, err = mgr.MakeRemove(roster, fake.NewAddress(0))
+	require.EqualError(t, err,
+		fake.Err("failed to make transaction: creating transaction"))
+}
+
+func TestManager_MakeRotate(t *testing.T) {
+	mgr := NewManager(signed.NewManager(fake.NewSigner(), nil))
+
+	roster := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	tx, err := mgr.MakeRotate(roster, fake.NewAddress(1), fake.PublicKey{}, fake.NewSigner())
+	require.NoError(t, err)
+	require.NotNil(t, tx.GetArg(AuthorityArg))
+	require.Equal(t, "{}", string(tx.GetArg(RotationArg)))
+
+	_, err = mgr.MakeRotate(roster, fake.NewAddress(5), fake.PublicKey{}, fake.NewSigner())
+	require.EqualError(t, err, "member 'fake.Address[5]' not found in roster")
+
+	_, err = mgr.MakeRotate(roster, fake.NewAddress(1), fake.NewBadPublicKey(), fake.NewSigner())
+	require.EqualError(t, err,
+		fake.Err("rotation message: failed to marshal public key"))
+
+	_, err = mgr.MakeRotate(roster, fake.NewAddress(1), fake.PublicKey{}, fake.NewBadSigner())
+	require.EqualError(t, err, fake.Err("failed to sign rotation"))
+
+	mgr.manager = badManager{}
+	_, err = mgr.MakeRotate(roster, fake.NewAddress(1), fake.PublicKey{}, fake.NewSigner())
+	require.EqualError(t, err,
+		fake.Err("failed to make transaction: creating transaction"))
+}
+
+func TestContract_Rotation_Execute(t *testing.T) {
+	ca := fake.NewAuthority(4, bls.Generate)
+	roster := authority.FromAuthority(ca)
+
+	data, err := roster.Serialize(json.NewContext())
+	require.NoError(t, err)
+
+	fac := authority.NewFactory(fake.AddressFactory{}, bls.NewPublicKeyFactory())
+	contract := NewContract([]byte("roster"), []byte("limits"), []byte("access"), fac,
+		bls.NewSignatureFactory(), fakeAccess{})
+
+	mgr := NewManager(signed.NewManager(fake.NewSigner(), nil))
+	next := bls.NewSigner()
+
+	tx, err := mgr.MakeRotate(roster, fake.NewAddress(2), next.GetPublicKey(), ca.GetSigner(2))
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{data: string(data)}, execution.Step{Current: tx})
+	require.NoError(t, err)
+
+	// The signature is bound to the roster, therefore it cannot be replayed
+	// once the roster has changed, even for the same rotation.
+	cset := authority.NewChangeSet()
+	cset.Rotate(1, bls.NewSigner().GetPublicKey())
+
+	curr := roster.Apply(cset)
+
+	currData, err := curr.Serialize(json.NewContext())
+	require.NoError(t, err)
+
+	cset = authority.NewChangeSet()
+	cset.Rotate(2, next.GetPublicKey())
+
+	nextData, err := curr.Apply(cset).Serialize(json.NewContext())
+	require.NoError(t, err)
+
+	replay, err := mgr.manager.Make(
+		txn.Arg{Key: native.ContractArg, Value: []byte(ContractName)},
+		txn.Arg{Key: AuthorityArg, Value: nextData},
+		txn.Arg{Key: RotationArg, Value: tx.GetArg(RotationArg)},
+	)
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{data: string(currData)}, execution.Step{Current: replay})
+	require.EqualError(t, err, messageInvalidRotation)
+
+	// The rotation must be signed by the key being replaced.
+	tx, err = mgr.MakeRotate(roster, fake.NewAddress(2), next.GetPublicKey(), next)
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{data: string(data)}, execution.Step{Current: tx})
+	require.EqualError(t, err, messageInvalidRotation)
+
+	cset = authority.NewChangeSet()
+	cset.Rotate(2, next.GetPublicKey())
+
+	tx, err = mgr.Make(roster.Apply(cset))
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{data: string(data)}, execution.Step{Current: tx})
+	require.EqualError(t, err, messageInvalidRotation)
+
+	tx, err = mgr.MakeRotate(roster, fake.NewAddress(2), next.GetPublicKey(), ca.GetSigner(2))
+	require.NoError(t, err)
+
+	contract.sigFac = fake.NewBadSignatureFactory()
+	err = contract.Execute(fakeStore{data: string(data)}, execution.Step{Current: tx})
+	require.EqualError(t, err, messageInvalidRotation)
+}
+
+func TestManager_MakeLimits(t *testing.T) {
+	mgr := NewManager(signed.NewManager(fake.NewSigner(), nil))
+
+	tx, err := mgr.MakeLimits(types.Limits{MaxTransactions: 2, MaxSize: 3})
+	require.NoError(t, err)
+	require.Nil(t, tx.GetArg(AuthorityArg))
+
+	limits := types.Limits{}
+	require.NoError(t, limits.UnmarshalBinary(tx.GetArg(LimitsArg)))
+	require.Equal(t, types.Limits{MaxTransactions: 2, MaxSize: 3}, limits)
+
+	mgr.manager = badManager{}
+	_, err = mgr.MakeLimits(types.Limits{})
+	require.EqualError(t, err, fake.Err("creating transaction"))
+}
+
+func TestContract_Limits_Execute(t *testing.T) {
+	contract := NewContract([]byte("roster"), []byte("limits"), []byte("access"), nil, nil, fakeAccess{})
+
+	err := contract.Execute(fakeStore{}, execution.Step{Current: makeLimitsTx(t, types.Limits{})})
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{}, execution.Step{Current: makeLimitsTx(t, types.Limits{MaxTransactions: 1})})
+	require.NoError(t, err)
+
+	step := execution.Step{
+		Previous: []txn.Transaction{makeTx(t, "")},
+		Current:  makeLimitsTx(t, types.Limits{}),
+	}
+
+	err = contract.Execute(fakeStore{}, step)
+	require.EqualError(t, err, messageOnlyOne)
+
+	tx, err := signed.NewTransaction(0, fake.PublicKey{}, signed.WithArg(LimitsArg, []byte{1}))
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{}, execution.Step{Current: tx})
+	require.EqualError(t, err, messageInvalidLimits)
+
+	// The limits must allow the transaction that sets them.
+	err = contract.Execute(fakeStore{}, execution.Step{Current: makeLimitsTx(t, types.Limits{MaxSize: 10})})
+	require.Error(t, err)
+	require.Regexp(t, "^limits too low: size [0-9]+ > 10$", err.Error())
+
+	err = contract.Execute(fakeStore{errSet: fake.GetError()}, execution.Step{Current: makeLimitsTx(t, types.Limits{})})
+	require.EqualError(t, err, messageStorageFailure)
+
+	contract.access = fakeAccess{err: fake.GetError()}
+	err = contract.Execute(fakeStore{}, execution.Step{Current: makeLimitsTx(t, types.Limits{})})
+	require.EqualError(t, err, "unauthorized identity: fake.PublicKey")
+}
+
+func TestContract_VerifyRotation(t *testing.T) {
+	contract := NewContract(nil, nil, nil, nil, nil, nil)
+
+	roster := authority.FromAuthority(fake.NewAuthority(2, fake.NewSigner))
+
+	err := contract.verifyRotation(roster, roster, 5, makeTx(t, ""))
+	require.EqualError(t, err, "index 5 out of range")
+
+	err = contract.verifyRotation(roster, authority.New(nil, nil), 1, makeTx(t, ""))
+	require.EqualError(t, err, "missing public key for 'fake.Address[1]'")
+
+	err = contract.verifyRotation(roster, roster, 1, makeTx(t, ""))
+	require.EqualError(t, err, messageRotationMissing)
+}
+
 func TestContract_Execute(t *testing.T) {
 	fac := authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
 
-	contract := NewContract([]byte("roster"), []byte("access"), fac, fakeAccess{})
+	contract := NewContract([]byte("roster"), []byte("limits"), []byte("access"), fac,
+		bls.NewSignatureFactory(), fakeAccess{})
 
-	err := contract.Execute(fakeStore{}, makeStep(t, "[]"))
+	err := contract.Execute(fakeStore{}, makeStep(t, "[{}]"))
+	require.NoError(t, err)
+
+	err = contract.Execute(fakeStore{data: "[{},{},{},{}]"}, makeStep(t, "[{},{},{}]"))
 	require.NoError(t, err)
 
 	err = contract.Execute(fakeStore{}, execution.Step{Previous: []txn.Transaction{makeTx(t, "")}})
@@ -67,11 +260,17 @@ func TestContract_Execute(t *testing.T) {
 	err = contract.Execute(fakeStore{}, makeStep(t, "[{},{}]"))
 	require.EqualError(t, err, "duplicate in roster: fake.Address[0]")
 
-	err = contract.Execute(fakeStore{errSet: fake.GetError()}, makeStep(t, "[]"))
+	err = contract.Execute(fakeStore{}, makeStep(t, "[]"))
+	require.EqualError(t, err, "not enough members left: 0 < 1")
+
+	err = contract.Execute(fakeStore{data: "[{},{},{}]"}, makeStep(t, "[{},{}]"))
+	require.EqualError(t, err, "not enough members left: 2 < 3")
+
+	err = contract.Execute(fakeStore{errSet: fake.GetError()}, makeStep(t, "[{}]"))
 	require.EqualError(t, err, messageStorageFailure)
 
 	contract.access = fakeAccess{err: fake.GetError()}
-	err = contract.Execute(fakeStore{}, makeStep(t, "[]"))
+	err = contract.Execute(fakeStore{}, makeStep(t, "[{}]"))
 	require.EqualError(t, err, "unauthorized identity: fake.PublicKey")
 }
 
@@ -94,15 +293,36 @@ func makeTx(t *testing.T, arg string) txn.Transaction {
 	return tx
 }
 
+func makeLimitsTx(t *testing.T, limits types.Limits) txn.Transaction {
+	data, err := limits.MarshalBinary()
+	require.NoError(t, err)
+
+	args := []signed.TransactionOption{
+		signed.WithArg(LimitsArg, data),
+		signed.WithArg(native.ContractArg, []byte(ContractName)),
+		signed.WithSignature(fake.Signature{}),
+	}
+
+	tx, err := signed.NewTransaction(0, fake.PublicKey{}, args...)
+	require.NoError(t, err)
+
+	return tx
+}
+
 type fakeStore struct {
 	store.Snapshot
 
+	data   string
 	errGet error
 	errSet error
 }
 
 func (snap fakeStore) Get(key []byte) ([]byte, error) {
-	return []byte("[{}]"), snap.errGet
+	if snap.data == "" {
+		return []byte("[{}]"), snap.errGet
+	}
+
+	return []byte(snap.data), snap.errGet
 }
 
 func (snap fakeStore) Set(key, value []byte) error {
@@ -9,24 +9,40 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/hex"
+	jsonlib "encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"go.dedis.ch/dela"
 	"go.dedis.ch/dela/cli/node"
 	"go.dedis.ch/dela/core/ordering"
+	"go.dedis.ch/dela/core/ordering/cosipbft"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
 	"go.dedis.ch/dela/core/ordering/cosipbft/contracts/viewchange"
+	"go.dedis.ch/dela/core/ordering/cosipbft/pbft"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/txn"
 	"go.dedis.ch/dela/core/txn/pool"
 	"go.dedis.ch/dela/cosi"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
 	"golang.org/x/xerrors"
 )
 
 const separator = ":"
 
+// errNotIncluded is returned when the transaction is not included in a block
+// before the end of the wait, which does not mean it is refused.
+var errNotIncluded = xerrors.New("transaction not found after timeout")
+
 // Service is the expected interface of the ordering service that is extended
 // with some additional functions.
 type Service interface {
@@ -34,7 +50,36 @@ type Service interface {
 
 	GetRoster() (authority.Authority, error)
 
-	Setup(ctx context.Context, ca crypto.CollectiveAuthority) error
+	Setup(ctx context.Context, ca crypto.CollectiveAuthority, opts ...cosipbft.SetupOption) error
+
+	MakeGenesis(ca crypto.CollectiveAuthority, opts ...cosipbft.SetupOption) (types.Genesis, error)
+
+	PrepareRotation(pubkey crypto.PublicKey, fn func())
+
+	CancelRotation(pubkey crypto.PublicKey)
+
+	GetProofAt(index uint64, key []byte) (ordering.Proof, error)
+
+	GetReceipt(id []byte) (blockstore.Receipt, error)
+
+	ExportChain(w io.Writer) error
+
+	ImportChain(r io.Reader) error
+
+	GetEvidences() ([]types.Evidence, error)
+}
+
+// signerSwitcher is the interface of a component that can have its signer
+// replaced when the key of the node is rotated.
+type signerSwitcher interface {
+	SetSigner(crypto.AggregateSigner)
+}
+
+// rotatingCosi is the expected interface of the collective signing to rotate
+// the key of the node.
+type rotatingCosi interface {
+	cosi.CollectiveSigning
+	signerSwitcher
 }
 
 // SetupAction is an action to create a new chain with a list of participants.
@@ -42,10 +87,77 @@ type Service interface {
 // - implements node.ActionTemplate
 type setupAction struct{}
 
+// genesisFile is the description of a new chain that can be shared among the
+// members so that each of them computes the digest of the genesis block on its
+// own before the chain is created.
+type genesisFile struct {
+	// Members is the list of members as exported by 'ordering export'.
+	Members []string
+
+	// Weights is the list of the weights of the members for the weighted
+	// leader policy, in the same order. The members without a weight have the
+	// default one.
+	Weights []uint32
+
+	// LeaderPolicy is the specification of the policy that rotates the
+	// leaders.
+	LeaderPolicy string
+
+	// State is the list of key/value pairs that the tree contains from the
+	// genesis block.
+	State []genesisEntry
+
+	// MaxTransactions is the maximum number of transactions in a block, or
+	// zero for no limit.
+	MaxTransactions uint64
+
+	// MaxSize is the maximum size of the transactions of a block in bytes, or
+	// zero for no limit.
+	MaxSize uint64
+}
+
+// genesisEntry is a key/value pair of the initial state. The key and the value
+// are decoded as hexadecimal strings if Hex is set.
+type genesisEntry struct {
+	Key   string
+	Value string
+	Hex   bool
+}
+
 // Execute implements node.ActionTemplate. It reads the list of members and
-// request the setup to the service.
+// request the setup to the service. When a genesis file is given, the members,
+// the leader policy, the initial state and the limits of the blocks are read
+// from it. A dry-run only
+// prints the digest of the genesis block that the setup would create.
 func (a setupAction) Execute(ctx node.Context) error {
-	roster, err := a.readMembers(ctx)
+	file, err := a.readGenesis(ctx)
+	if err != nil {
+		return xerrors.Errorf("failed to read genesis: %v", err)
+	}
+
+	members := ctx.Flags.StringSlice("member")
+	spec := ctx.Flags.String("leader-policy")
+
+	var weights []uint32
+
+	if file != nil {
+		if len(members) > 0 {
+			return xerrors.New("members must be given either by flag or by the genesis file")
+		}
+
+		members = file.Members
+		weights = file.Weights
+
+		if file.LeaderPolicy != "" {
+			spec = file.LeaderPolicy
+		}
+	}
+
+	if len(members) == 0 {
+		return xerrors.New("no member given")
+	}
+
+	roster, err := a.readMembers(ctx, members, weights)
 	if err != nil {
 		return xerrors.Errorf("failed to read roster: %v", err)
 	}
@@ -56,12 +168,53 @@ func (a setupAction) Execute(ctx node.Context) error {
 		return xerrors.Errorf("injector: %v", err)
 	}
 
+	policy, err := pbft.ParseLeaderPolicy(spec)
+	if err != nil {
+		return xerrors.Errorf("invalid leader policy: %v", err)
+	}
+
+	opts := []cosipbft.SetupOption{cosipbft.WithLeaderPolicy(policy)}
+
+	if file != nil {
+		state, err := file.getState()
+		if err != nil {
+			return xerrors.Errorf("invalid state: %v", err)
+		}
+
+		opts = append(opts, cosipbft.WithInitialState(state...))
+
+		opts = append(opts, cosipbft.WithLimits(types.Limits{
+			MaxTransactions: file.MaxTransactions,
+			MaxSize:         file.MaxSize,
+		}))
+	}
+
+	expected := ctx.Flags.String("digest")
+
+	if ctx.Flags.Bool("dry-run") || expected != "" {
+		genesis, err := srvc.MakeGenesis(roster, opts...)
+		if err != nil {
+			return xerrors.Errorf("failed to make genesis: %v", err)
+		}
+
+		digest := genesis.GetHash()
+
+		if ctx.Flags.Bool("dry-run") {
+			fmt.Fprintf(ctx.Out, "%x\n", digest[:])
+			return nil
+		}
+
+		if hex.EncodeToString(digest[:]) != strings.ToLower(expected) {
+			return xerrors.Errorf("mismatch genesis digest '%x' != '%s'", digest[:], expected)
+		}
+	}
+
 	timeout := ctx.Flags.Duration("timeout")
 
 	setupCtx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	err = srvc.Setup(setupCtx, roster)
+	err = srvc.Setup(setupCtx, roster, opts...)
 	if err != nil {
 		return xerrors.Errorf("failed to setup: %v", err)
 	}
@@ -69,8 +222,33 @@ func (a setupAction) Execute(ctx node.Context) error {
 	return nil
 }
 
-func (a setupAction) readMembers(ctx node.Context) (authority.Authority, error) {
-	members := ctx.Flags.StringSlice("member")
+func (a setupAction) readGenesis(ctx node.Context) (*genesisFile, error) {
+	path := ctx.Flags.String("genesis")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, xerrors.Errorf("while reading file: %v", err)
+	}
+
+	file := &genesisFile{}
+
+	err = jsonlib.Unmarshal(data, file)
+	if err != nil {
+		return nil, xerrors.Errorf("while decoding file: %v", err)
+	}
+
+	return file, nil
+}
+
+func (a setupAction) readMembers(ctx node.Context, members []string,
+	weights []uint32) (authority.Authority, error) {
+
+	if len(weights) > len(members) {
+		return nil, xerrors.Errorf("too many weights %d > %d", len(weights), len(members))
+	}
 
 	addrs := make([]mino.Address, len(members))
 	pubkeys := make([]crypto.PublicKey, len(members))
@@ -85,7 +263,44 @@ func (a setupAction) readMembers(ctx node.Context) (authority.Authority, error)
 		pubkeys[i] = pubkey
 	}
 
-	return authority.New(addrs, pubkeys), nil
+	return authority.New(addrs, pubkeys).WithWeights(weights), nil
+}
+
+// getState returns the entries of the initial state. A key can only be set
+// once.
+func (f genesisFile) getState() ([]types.StateEntry, error) {
+	state := make([]types.StateEntry, len(f.State))
+	keys := make(map[string]struct{})
+
+	for i, entry := range f.State {
+		key := []byte(entry.Key)
+		value := []byte(entry.Value)
+
+		if entry.Hex {
+			var err error
+
+			key, err = hex.DecodeString(entry.Key)
+			if err != nil {
+				return nil, xerrors.Errorf("failed to decode key: %v", err)
+			}
+
+			value, err = hex.DecodeString(entry.Value)
+			if err != nil {
+				return nil, xerrors.Errorf("failed to decode value: %v", err)
+			}
+		}
+
+		_, found := keys[string(key)]
+		if found {
+			return nil, xerrors.Errorf("duplicate key '%x'", key)
+		}
+
+		keys[string(key)] = struct{}{}
+
+		state[i] = types.NewStateEntry(key, value)
+	}
+
+	return state, nil
 }
 
 // ExportAction is an action to display a base64 string describing the node. It
@@ -147,8 +362,382 @@ func (rosterAddAction) Execute(ctx node.Context) error {
 		return xerrors.Errorf("while preparing tx: %v", err)
 	}
 
+	return addAndWait(ctx, srvc, tx)
+}
+
+// RosterRemoveAction is an action to require a roster change in the chain by
+// removing an existing member.
+//
+// - implements node.ActionTemplate
+type rosterRemoveAction struct{}
+
+// Execute implements node.ActionTemplate. It reads the member to remove and
+// sends a transaction to require a roster change.
+func (rosterRemoveAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	roster, err := srvc.GetRoster()
+	if err != nil {
+		return xerrors.Errorf("failed to read roster: %v", err)
+	}
+
+	addr, _, err := decodeMember(ctx, ctx.Flags.String("member"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode member: %v", err)
+	}
+
+	mgr, err := makeManager(ctx)
+	if err != nil {
+		return xerrors.Errorf("txn manager: %v", err)
+	}
+
+	tx, err := viewchange.NewManager(mgr).MakeRemove(roster, addr)
+	if err != nil {
+		return xerrors.Errorf("transaction: %v", err)
+	}
+
+	return addAndWait(ctx, srvc, tx)
+}
+
+// RosterRotateAction is an action to require a roster change in the chain by
+// replacing the public key of the node.
+//
+// - implements node.ActionTemplate
+type rosterRotateAction struct{}
+
+// Execute implements node.ActionTemplate. It generates the next private key of
+// the node, or reuses the pending one, and sends a transaction signed by the
+// current key to require the rotation. The node switches to the new key as soon
+// as the roster of a block has it, whether the action is still waiting for the
+// transaction or not.
+func (rosterRotateAction) Execute(ctx node.Context) error {
+	if ctx.Flags.Duration("wait") <= 0 {
+		return xerrors.New("a rotation must wait for the transaction to be included")
+	}
+
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	var c rotatingCosi
+	err = ctx.Injector.Resolve(&c)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	var m mino.Mino
+	err = ctx.Injector.Resolve(&m)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	roster, err := srvc.GetRoster()
+	if err != nil {
+		return xerrors.Errorf("failed to read roster: %v", err)
+	}
+
+	dir := ctx.Flags.Path("config")
+
+	next, err := loadSigner(filepath.Join(dir, nextKeyFile), blsSigner)
+	if err != nil {
+		return xerrors.Errorf("next signer: %v", err)
+	}
+
+	mgr, err := makeManager(ctx)
+	if err != nil {
+		return xerrors.Errorf("txn manager: %v", err)
+	}
+
+	tx, err := viewchange.NewManager(mgr).
+		MakeRotate(roster, m.GetAddress(), next.GetPublicKey(), c.GetSigner())
+	if err != nil {
+		return xerrors.Errorf("transaction: %v", err)
+	}
+
+	// The rotation is registered before the transaction is sent so that the
+	// service switches in the block that changes the roster. It is dropped
+	// only when the transaction is refused, as it might still be included
+	// after the wait.
+	srvc.PrepareRotation(next.GetPublicKey(), makeRotation(c, dir, next))
+
+	err = addAndWait(ctx, srvc, tx)
+	if err != nil {
+		if !xerrors.Is(err, errNotIncluded) {
+			srvc.CancelRotation(next.GetPublicKey())
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// LimitsAction is an action to require a change of the limits of the blocks of
+// the chain.
+//
+// - implements node.ActionTemplate
+type limitsAction struct{}
+
+// Execute implements node.ActionTemplate. It reads the new limits and sends a
+// transaction to require the change. Zero values remove the limits.
+func (limitsAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	maxTxs := ctx.Flags.Int("max-transactions")
+	maxSize := ctx.Flags.Int("max-size")
+
+	if maxTxs < 0 || maxSize < 0 {
+		return xerrors.New("limits must not be negative")
+	}
+
+	mgr, err := makeManager(ctx)
+	if err != nil {
+		return xerrors.Errorf("txn manager: %v", err)
+	}
+
+	limits := types.Limits{
+		MaxTransactions: uint64(maxTxs),
+		MaxSize:         uint64(maxSize),
+	}
+
+	tx, err := viewchange.NewManager(mgr).MakeLimits(limits)
+	if err != nil {
+		return xerrors.Errorf("transaction: %v", err)
+	}
+
+	return addAndWait(ctx, srvc, tx)
+}
+
+// ProofAction is an action to print the proof of the value of a key. The proof
+// can be verified later on by anyone knowing the genesis block.
+//
+// - implements node.ActionTemplate
+type proofAction struct{}
+
+// Execute implements node.ActionTemplate. It reads the key and prints the JSON
+// representation of the proof, for the latest block or the one at the index.
+func (proofAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	key := []byte(ctx.Flags.String("key"))
+
+	if ctx.Flags.Bool("hex") {
+		key, err = hex.DecodeString(string(key))
+		if err != nil {
+			return xerrors.Errorf("failed to decode key: %v", err)
+		}
+	}
+
+	var proof ordering.Proof
+
+	index := ctx.Flags.Int("index")
+	if index < 0 {
+		proof, err = srvc.GetProof(key)
+	} else {
+		proof, err = srvc.GetProofAt(uint64(index), key)
+	}
+
+	if err != nil {
+		return xerrors.Errorf("failed to get proof: %v", err)
+	}
+
+	msg, ok := proof.(serde.Message)
+	if !ok {
+		return xerrors.Errorf("proof '%T' is not serializable", proof)
+	}
+
+	data, err := msg.Serialize(json.NewContext())
+	if err != nil {
+		return xerrors.Errorf("failed to serialize proof: %v", err)
+	}
+
+	fmt.Fprint(ctx.Out, string(data))
+
+	return nil
+}
+
+// GenesisAction is an action to print the genesis block of the chain, which is
+// the root of trust to verify proofs.
+//
+// - implements node.ActionTemplate
+type genesisAction struct{}
+
+// Execute implements node.ActionTemplate. It prints the JSON representation of
+// the genesis block.
+func (genesisAction) Execute(ctx node.Context) error {
+	var store blockstore.GenesisStore
+	err := ctx.Injector.Resolve(&store)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	genesis, err := store.Get()
+	if err != nil {
+		return xerrors.Errorf("failed to read genesis: %v", err)
+	}
+
+	data, err := genesis.Serialize(json.NewContext())
+	if err != nil {
+		return xerrors.Errorf("failed to serialize genesis: %v", err)
+	}
+
+	fmt.Fprint(ctx.Out, string(data))
+
+	return nil
+}
+
+// ReceiptAction is an action to print where a transaction has been included in
+// the chain, and whether it has been accepted.
+//
+// - implements node.ActionTemplate
+type receiptAction struct{}
+
+// Execute implements node.ActionTemplate. It reads the hexadecimal identifier
+// of the transaction and prints its receipt.
+func (receiptAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	id, err := hex.DecodeString(ctx.Flags.String("id"))
+	if err != nil {
+		return xerrors.Errorf("failed to decode id: %v", err)
+	}
+
+	receipt, err := srvc.GetReceipt(id)
+	if err != nil {
+		return xerrors.Errorf("failed to get receipt: %v", err)
+	}
+
+	status := "accepted"
+	if !receipt.Accepted {
+		status = fmt.Sprintf("refused: %s", receipt.Message)
+	}
+
+	fmt.Fprintf(ctx.Out, "block %d, position %d, %s\n",
+		receipt.Index, receipt.Position, status)
+
+	return nil
+}
+
+// ChainExportAction is an action to write the chain of the node in an archive
+// file.
+//
+// - implements node.ActionTemplate
+type chainExportAction struct{}
+
+// Execute implements node.ActionTemplate. It creates the file and writes the
+// archive of the chain into it.
+func (chainExportAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	file, err := os.Create(ctx.Flags.String("out"))
+	if err != nil {
+		return xerrors.Errorf("failed to create file: %v", err)
+	}
+
+	defer file.Close()
+
+	err = srvc.ExportChain(file)
+	if err != nil {
+		return xerrors.Errorf("failed to export chain: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "chain exported to %s\n", file.Name())
+
+	return nil
+}
+
+// ChainImportAction is an action to read an archive file and apply the chain
+// to the node.
+//
+// - implements node.ActionTemplate
+type chainImportAction struct{}
+
+// Execute implements node.ActionTemplate. It opens the file and imports the
+// chain, verifying every block.
+func (chainImportAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	file, err := os.Open(ctx.Flags.String("in"))
+	if err != nil {
+		return xerrors.Errorf("failed to open file: %v", err)
+	}
+
+	defer file.Close()
+
+	err = srvc.ImportChain(file)
+	if err != nil {
+		return xerrors.Errorf("failed to import chain: %v", err)
+	}
+
+	fmt.Fprintln(ctx.Out, "chain imported")
+
+	return nil
+}
+
+// EvidenceListAction is an action to print the evidences of misbehavior that
+// the node has recorded.
+//
+// - implements node.ActionTemplate
+type evidenceListAction struct{}
+
+// Execute implements node.ActionTemplate. It prints a line per evidence with a
+// summary followed by the serialized record signed by the node.
+func (evidenceListAction) Execute(ctx node.Context) error {
+	var srvc Service
+	err := ctx.Injector.Resolve(&srvc)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	evidences, err := srvc.GetEvidences()
+	if err != nil {
+		return xerrors.Errorf("failed to get evidences: %v", err)
+	}
+
+	for _, ev := range evidences {
+		data, err := ev.Serialize(json.NewContext())
+		if err != nil {
+			return xerrors.Errorf("failed to serialize evidence: %v", err)
+		}
+
+		fmt.Fprintf(ctx.Out, "block %d, %v by %v: %s\n",
+			ev.GetIndex(), ev.GetKind(), ev.GetOffender(), data)
+	}
+
+	return nil
+}
+
+// addAndWait adds the transaction to the pool and, if requested by the flags,
+// waits for the transaction to be included in a block.
+func addAndWait(ctx node.Context, srvc Service, tx txn.Transaction) error {
 	var p pool.Pool
-	err = ctx.Injector.Resolve(&p)
+	err := ctx.Injector.Resolve(&p)
 	if err != nil {
 		return xerrors.Errorf("injector: %v", err)
 	}
@@ -191,7 +780,7 @@ func (rosterAddAction) Execute(ctx node.Context) error {
 			}
 		}
 
-		return xerrors.New("transaction not found after timeout")
+		return errNotIncluded
 	}
 
 	return nil
@@ -208,8 +797,13 @@ func prepareRosterTx(ctx node.Context, srvc Service) (txn.Transaction, error) {
 		return nil, xerrors.Errorf("failed to decode member: %v", err)
 	}
 
+	weight := ctx.Flags.Int("weight")
+	if weight < 0 {
+		return nil, xerrors.Errorf("invalid weight %d", weight)
+	}
+
 	cset := authority.NewChangeSet()
-	cset.Add(addr, pubkey)
+	cset.AddWeighted(addr, pubkey, uint32(weight))
 
 	mgr, err := makeManager(ctx)
 	if err != nil {
@@ -3,7 +3,12 @@ package controller
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -11,7 +16,10 @@ import (
 	"go.dedis.ch/dela/cli/node"
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/core/ordering"
+	"go.dedis.ch/dela/core/ordering/cosipbft"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/txn"
 	"go.dedis.ch/dela/core/txn/pool"
 	"go.dedis.ch/dela/core/txn/pool/mem"
@@ -20,6 +28,7 @@ import (
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
 )
 
 func TestSetupAction_Execute(t *testing.T) {
@@ -28,18 +37,30 @@ func TestSetupAction_Execute(t *testing.T) {
 	calls := &fake.Call{}
 	ctx := prepContext(calls)
 	ctx.Flags.(node.FlagSet)["member"] = []interface{}{"YQ==:YQ==", "YQ==:YQ=="}
+	ctx.Flags.(node.FlagSet)["leader-policy"] = "round-robin"
 
 	err := action.Execute(ctx)
 	require.NoError(t, err)
 	require.Equal(t, 1, calls.Len())
 	require.Equal(t, 2, calls.Get(0, 1).(mino.Players).Len())
+	require.Len(t, calls.Get(0, 2), 1)
+
+	ctx.Flags.(node.FlagSet)["leader-policy"] = "unknown"
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "invalid leader policy: unknown policy 'unknown'")
 
 	ctx.Flags.(node.FlagSet)["member"] = []interface{}{""}
 	err = action.Execute(ctx)
 	require.EqualError(t, err, "failed to read roster: failed to decode: invalid member base64 string")
 
 	ctx.Flags = make(node.FlagSet)
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "no member given")
+
+	ctx.Flags.(node.FlagSet)["member"] = []interface{}{"YQ==:YQ=="}
 	ctx.Injector = node.NewInjector()
+	ctx.Injector.Inject(fake.Mino{})
+	ctx.Injector.Inject(fakeCosi{})
 	err = action.Execute(ctx)
 	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
 
@@ -48,6 +69,126 @@ func TestSetupAction_Execute(t *testing.T) {
 	require.EqualError(t, err, fake.Err("failed to setup"))
 }
 
+func TestSetupAction_Genesis_Execute(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "genesis.json")
+
+	file := `{
+		"Members": ["YQ==:YQ==", "YQ==:YQ=="],
+		"Weights": [3],
+		"LeaderPolicy": "weighted",
+		"State": [
+			{"Key": "A", "Value": "B"},
+			{"Key": "0aff", "Value": "00", "Hex": true}
+		],
+		"MaxTransactions": 10
+	}`
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(file), 0600))
+
+	action := setupAction{}
+
+	calls := &fake.Call{}
+	ctx := prepContext(calls)
+	ctx.Flags.(node.FlagSet)["genesis"] = path
+	ctx.Flags.(node.FlagSet)["leader-policy"] = "unknown"
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls.Len())
+	require.Equal(t, 2, calls.Get(0, 1).(mino.Players).Len())
+	require.Equal(t, uint32(3), calls.Get(0, 1).(authority.Roster).GetWeight(0))
+	require.Equal(t, uint32(1), calls.Get(0, 1).(authority.Roster).GetWeight(1))
+	require.Len(t, calls.Get(0, 2), 3)
+
+	ctx.Flags.(node.FlagSet)["member"] = []interface{}{"YQ==:YQ=="}
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"members must be given either by flag or by the genesis file")
+
+	ctx.Flags.(node.FlagSet)["member"] = nil
+	ctx.Flags.(node.FlagSet)["leader-policy"] = "sticky"
+	ctx.Flags.(node.FlagSet)["genesis"] = filepath.Join(dir, "unknown.json")
+	err = action.Execute(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read genesis: while reading file: ")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("{"), 0600))
+
+	ctx.Flags.(node.FlagSet)["genesis"] = path
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"failed to read genesis: while decoding file: unexpected end of JSON input")
+
+	file = `{"Members": ["YQ==:YQ=="], "State": [{"Key": "zz", "Hex": true}]}`
+	require.NoError(t, ioutil.WriteFile(path, []byte(file), 0600))
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"invalid state: failed to decode key: encoding/hex: invalid byte: U+007A 'z'")
+
+	file = `{"Members": ["YQ==:YQ=="], "State": [{"Key": "a"}, {"Key": "61", "Hex": true}]}`
+	require.NoError(t, ioutil.WriteFile(path, []byte(file), 0600))
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "invalid state: duplicate key '61'")
+
+	file = `{"Members": ["YQ==:YQ=="], "Weights": [1, 2]}`
+	require.NoError(t, ioutil.WriteFile(path, []byte(file), 0600))
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "failed to read roster: too many weights 2 > 1")
+
+	file = `{"Members": ["YQ==:YQ=="], "State": [{"Value": "zz", "Hex": true}]}`
+	require.NoError(t, ioutil.WriteFile(path, []byte(file), 0600))
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"invalid state: failed to decode value: encoding/hex: invalid byte: U+007A 'z'")
+}
+
+func TestSetupAction_DryRun_Execute(t *testing.T) {
+	action := setupAction{}
+
+	genesis, err := types.NewGenesis(authority.New(nil, nil))
+	require.NoError(t, err)
+
+	digest := genesis.GetHash()
+
+	calls := &fake.Call{}
+	ctx := prepContext(calls)
+	ctx.Injector.Inject(fakeService{calls: calls, genesis: genesis})
+	ctx.Flags.(node.FlagSet)["member"] = []interface{}{"YQ==:YQ=="}
+	ctx.Flags.(node.FlagSet)["dry-run"] = true
+
+	out := new(bytes.Buffer)
+	ctx.Out = out
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("%x\n", digest[:]), out.String())
+	require.Equal(t, 0, calls.Len())
+
+	ctx.Flags.(node.FlagSet)["dry-run"] = false
+	ctx.Flags.(node.FlagSet)["digest"] = strings.ToUpper(fmt.Sprintf("%x", digest[:]))
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls.Len())
+
+	ctx.Flags.(node.FlagSet)["digest"] = "ab"
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		fmt.Sprintf("mismatch genesis digest '%x' != 'ab'", digest[:]))
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to make genesis"))
+}
+
 func TestExportAction_Execute(t *testing.T) {
 	action := exportAction{}
 
@@ -82,11 +223,19 @@ func TestRosterAddAction_Execute(t *testing.T) {
 
 	ctx := prepContext(nil)
 	ctx.Flags.(node.FlagSet)["member"] = "YQ==:YQ=="
+	ctx.Flags.(node.FlagSet)["weight"] = 2
 	ctx.Flags.(node.FlagSet)["wait"] = float64(time.Second)
 
 	err := action.Execute(ctx)
 	require.NoError(t, err)
 
+	ctx.Flags.(node.FlagSet)["weight"] = -1
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "while preparing tx: invalid weight -1")
+
+	ctx.Flags.(node.FlagSet)["weight"] = 1
+
 	ctx.Flags.(node.FlagSet)["wait"] = float64(0)
 
 	err = action.Execute(ctx)
@@ -146,6 +295,384 @@ func TestRosterAddAction_Execute(t *testing.T) {
 	require.EqualError(t, err, "transaction not found after timeout")
 }
 
+func TestRosterRemoveAction_Execute(t *testing.T) {
+	action := rosterRemoveAction{}
+
+	roster := authority.FromAuthority(fake.NewAuthority(4, fake.NewSigner))
+
+	ctx := prepContext(nil)
+	ctx.Injector.Inject(fakeService{roster: roster})
+	ctx.Flags.(node.FlagSet)["member"] = "YQ==:YQ=="
+
+	err := action.Execute(ctx)
+	require.NoError(t, err)
+
+	var p pool.Pool
+	require.NoError(t, ctx.Injector.Resolve(&p))
+	require.Equal(t, 1, p.Len())
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to read roster"))
+
+	ctx.Injector.Inject(fakeService{roster: roster})
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"failed to decode member: injector: couldn't find dependency for 'mino.Mino'")
+
+	ctx.Injector.Inject(fake.Mino{})
+	ctx.Injector.Inject(fakeCosi{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"txn manager: injector: couldn't find dependency for 'txn.Manager'")
+
+	ctx.Injector.Inject(fakeTxManager{})
+	ctx.Injector.Inject(fakeService{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"transaction: member 'fake.Address[0]' not found in roster")
+
+	ctx.Injector.Inject(fakeService{roster: roster})
+	ctx.Injector.Inject(badPool{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to add transaction"))
+}
+
+func TestRosterRotateAction_Execute(t *testing.T) {
+	action := rosterRotateAction{}
+
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	roster := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+	calls := &fake.Call{}
+
+	events := []ordering.Event{
+		{Transactions: []validation.TransactionResult{fakeResult{}}},
+	}
+
+	ctx := prepContext(nil)
+	ctx.Injector.Inject(fakeService{roster: roster, calls: calls, events: events})
+	ctx.Flags.(node.FlagSet)["config"] = dir
+	ctx.Flags.(node.FlagSet)["wait"] = float64(time.Second)
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.FileExists(t, filepath.Join(dir, nextKeyFile))
+	require.Equal(t, 1, calls.Len())
+
+	var p pool.Pool
+	require.NoError(t, ctx.Injector.Resolve(&p))
+	require.Equal(t, 1, p.Len())
+
+	// The pending key is reused if the rotation is requested again.
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, calls.Get(0, 0), calls.Get(1, 0))
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"injector: couldn't find dependency for 'controller.rotatingCosi'")
+
+	ctx.Injector.Inject(fakeCosi{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'mino.Mino'")
+
+	ctx.Injector.Inject(fake.Mino{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to read roster"))
+
+	ctx.Injector.Inject(fakeService{})
+	ctx.Flags.(node.FlagSet)["config"] = "/not/existing/path"
+	err = action.Execute(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "next signer: while loading: ")
+
+	ctx.Flags.(node.FlagSet)["config"] = dir
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"txn manager: injector: couldn't find dependency for 'txn.Manager'")
+
+	ctx.Injector.Inject(fakeTxManager{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"transaction: member 'fake.Address[0]' not found in roster")
+
+	ctx.Injector.Inject(fakeService{roster: roster})
+	ctx.Injector.Inject(badPool{})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to add transaction"))
+
+	// A refused rotation is registered before the transaction is sent, and
+	// then dropped.
+	calls = &fake.Call{}
+	events = []ordering.Event{
+		{Transactions: []validation.TransactionResult{fakeResult{refused: true}}},
+	}
+
+	ctx = prepContext(nil)
+	ctx.Injector.Inject(fakeService{roster: roster, calls: calls, events: events})
+	ctx.Flags.(node.FlagSet)["config"] = dir
+	ctx.Flags.(node.FlagSet)["wait"] = float64(time.Second)
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "transaction refused: message")
+	require.Equal(t, 2, calls.Len())
+	require.Equal(t, calls.Get(0, 0), calls.Get(1, 0))
+
+	// A rotation that is not included before the end of the wait is kept as
+	// it might still be.
+	calls = &fake.Call{}
+
+	ctx.Injector.Inject(fakeService{roster: roster, calls: calls})
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "transaction not found after timeout")
+	require.Equal(t, 1, calls.Len())
+
+	ctx.Flags.(node.FlagSet)["wait"] = float64(0)
+	err = action.Execute(ctx)
+	require.EqualError(t, err,
+		"a rotation must wait for the transaction to be included")
+}
+
+func TestLimitsAction_Execute(t *testing.T) {
+	action := limitsAction{}
+
+	ctx := prepContext(nil)
+	ctx.Flags.(node.FlagSet)["max-transactions"] = 10
+	ctx.Flags.(node.FlagSet)["max-size"] = 1000
+
+	err := action.Execute(ctx)
+	require.NoError(t, err)
+
+	var p pool.Pool
+	require.NoError(t, ctx.Injector.Resolve(&p))
+	require.Equal(t, 1, p.Len())
+
+	ctx.Flags.(node.FlagSet)["max-size"] = -1
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "limits must not be negative")
+
+	ctx.Flags.(node.FlagSet)["max-size"] = 0
+	ctx.Injector.Inject(fakeTxManager{errMake: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("transaction: creating transaction"))
+
+	ctx.Injector.Inject(fakeTxManager{errSync: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("txn manager: sync"))
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+}
+
+func TestProofAction_Execute(t *testing.T) {
+	action := proofAction{}
+
+	ctx := prepContext(nil)
+	ctx.Flags.(node.FlagSet)["key"] = "abc"
+	ctx.Flags.(node.FlagSet)["index"] = -1
+
+	buffer := new(bytes.Buffer)
+	ctx.Out = buffer
+
+	ctx.Injector.Inject(fakeService{proof: fakeProof{}})
+
+	err := action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "{}", buffer.String())
+
+	calls := fake.NewCall()
+	ctx.Flags.(node.FlagSet)["index"] = 2
+	ctx.Injector.Inject(fakeService{proof: fakeProof{}, calls: calls})
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, calls.Len())
+	require.Equal(t, uint64(2), calls.Get(0, 0))
+	require.Equal(t, []byte("abc"), calls.Get(0, 1))
+
+	ctx.Flags.(node.FlagSet)["index"] = -1
+
+	ctx.Flags.(node.FlagSet)["hex"] = true
+	err = action.Execute(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to decode key: ")
+
+	ctx.Flags.(node.FlagSet)["key"] = "abcd"
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to get proof"))
+
+	ctx.Injector.Inject(fakeService{proof: plainProof{}})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "proof 'controller.plainProof' is not serializable")
+
+	ctx.Injector.Inject(fakeService{proof: fakeProof{err: fake.GetError()}})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to serialize proof"))
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+}
+
+func TestReceiptAction_Execute(t *testing.T) {
+	action := receiptAction{}
+
+	ctx := prepContext(nil)
+	ctx.Flags.(node.FlagSet)["id"] = "aabb"
+
+	buffer := new(bytes.Buffer)
+	ctx.Out = buffer
+
+	calls := fake.NewCall()
+	receipt := blockstore.Receipt{Index: 2, Position: 1, Accepted: true}
+	ctx.Injector.Inject(fakeService{receipt: receipt, calls: calls})
+
+	err := action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "block 2, position 1, accepted\n", buffer.String())
+	require.Equal(t, []byte{0xaa, 0xbb}, calls.Get(0, 0))
+
+	buffer.Reset()
+	receipt = blockstore.Receipt{Index: 3, Message: "oops"}
+	ctx.Injector.Inject(fakeService{receipt: receipt})
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "block 3, position 0, refused: oops\n", buffer.String())
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to get receipt"))
+
+	ctx.Flags.(node.FlagSet)["id"] = "zz"
+	err = action.Execute(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to decode id: ")
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+}
+
+func TestChainExportAction_Execute(t *testing.T) {
+	action := chainExportAction{}
+
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ctx := prepContext(nil)
+	ctx.Flags.(node.FlagSet)["out"] = filepath.Join(dir, "chain.bin")
+
+	buffer := new(bytes.Buffer)
+	ctx.Out = buffer
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Contains(t, buffer.String(), "chain exported to ")
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "chain.bin"))
+	require.NoError(t, err)
+	require.Equal(t, "archive", string(data))
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to export chain"))
+
+	ctx.Flags.(node.FlagSet)["out"] = filepath.Join(dir, "unknown", "chain.bin")
+	err = action.Execute(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to create file: ")
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+}
+
+func TestChainImportAction_Execute(t *testing.T) {
+	action := chainImportAction{}
+
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "chain.bin")
+	require.NoError(t, ioutil.WriteFile(path, []byte("archive"), 0600))
+
+	ctx := prepContext(nil)
+	ctx.Flags.(node.FlagSet)["in"] = path
+
+	buffer := new(bytes.Buffer)
+	ctx.Out = buffer
+
+	calls := fake.NewCall()
+	ctx.Injector.Inject(fakeService{calls: calls})
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "chain imported\n", buffer.String())
+	require.Equal(t, []byte("archive"), calls.Get(0, 0))
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to import chain"))
+
+	ctx.Flags.(node.FlagSet)["in"] = filepath.Join(dir, "unknown.bin")
+	err = action.Execute(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to open file: ")
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+}
+
+func TestGenesisAction_Execute(t *testing.T) {
+	action := genesisAction{}
+
+	ctx := prepContext(nil)
+
+	buffer := new(bytes.Buffer)
+	ctx.Out = buffer
+
+	err := action.Execute(ctx)
+	require.EqualError(t, err,
+		"injector: couldn't find dependency for 'blockstore.GenesisStore'")
+
+	store := blockstore.NewGenesisStore()
+	ctx.Injector.Inject(store)
+
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "failed to read genesis: missing genesis block")
+
+	genesis, err := types.NewGenesis(authority.New(nil, nil))
+	require.NoError(t, err)
+
+	store.Set(genesis)
+
+	err = action.Execute(ctx)
+	require.NoError(t, err)
+	require.Contains(t, buffer.String(), `"Roster":`)
+}
+
 func TestDecodeMember(t *testing.T) {
 	ctx := prepContext(nil)
 
@@ -165,6 +692,45 @@ func TestDecodeMember(t *testing.T) {
 	require.EqualError(t, err, fake.Err("failed to decode public key"))
 }
 
+func TestEvidenceListAction_Execute(t *testing.T) {
+	action := evidenceListAction{}
+
+	ctx := prepContext(nil)
+
+	buffer := new(bytes.Buffer)
+	ctx.Out = buffer
+
+	param := types.EvidenceParam{
+		Kind:      types.ConflictingPrepare,
+		Index:     2,
+		Offender:  fake.NewAddress(1),
+		PublicKey: fake.PublicKey{},
+	}
+
+	evidences := []types.Evidence{types.NewEvidence(param, fake.Signature{})}
+	ctx.Injector.Inject(fakeService{evidences: evidences})
+
+	err := action.Execute(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "block 2, conflicting prepare by fake.Address[1]: "+
+		`{"Kind":1,"Index":2,"Offender":"AQAAAA==","PublicKey":{},"Statements":[],"Signature":{}}`+"\n",
+		buffer.String())
+
+	evidences = []types.Evidence{types.NewEvidence(param, fake.NewBadSignature())}
+	ctx.Injector.Inject(fakeService{evidences: evidences})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to serialize evidence: "+
+		"encoding failed: failed to serialize signature"))
+
+	ctx.Injector.Inject(fakeService{err: fake.GetError()})
+	err = action.Execute(ctx)
+	require.EqualError(t, err, fake.Err("failed to get evidences"))
+
+	ctx.Injector = node.NewInjector()
+	err = action.Execute(ctx)
+	require.EqualError(t, err, "injector: couldn't find dependency for 'controller.Service'")
+}
+
 // -----------------------------------------------------------------------------
 // Utility functions
 
@@ -190,20 +756,82 @@ func prepContext(calls *fake.Call) node.Context {
 
 type fakeService struct {
 	ordering.Service
-	calls  *fake.Call
-	events []ordering.Event
-	err    error
+	calls     *fake.Call
+	events    []ordering.Event
+	roster    authority.Authority
+	proof     ordering.Proof
+	receipt   blockstore.Receipt
+	genesis   types.Genesis
+	evidences []types.Evidence
+	err       error
+}
+
+func (s fakeService) GetProof(key []byte) (ordering.Proof, error) {
+	return s.proof, s.err
+}
+
+func (s fakeService) GetProofAt(index uint64, key []byte) (ordering.Proof, error) {
+	s.calls.Add(index, key)
+	return s.proof, s.err
+}
+
+func (s fakeService) GetReceipt(id []byte) (blockstore.Receipt, error) {
+	s.calls.Add(id)
+	return s.receipt, s.err
+}
+
+func (s fakeService) GetEvidences() ([]types.Evidence, error) {
+	return s.evidences, s.err
+}
+
+func (s fakeService) ExportChain(w io.Writer) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	_, err := w.Write([]byte("archive"))
+	return err
+}
+
+func (s fakeService) ImportChain(r io.Reader) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	s.calls.Add(data)
+
+	return err
 }
 
 func (s fakeService) GetRoster() (authority.Authority, error) {
+	if s.roster != nil {
+		return s.roster, s.err
+	}
+
 	return authority.New(nil, nil), s.err
 }
 
-func (s fakeService) Setup(ctx context.Context, ca crypto.CollectiveAuthority) error {
-	s.calls.Add(ctx, ca)
+func (s fakeService) MakeGenesis(ca crypto.CollectiveAuthority,
+	opts ...cosipbft.SetupOption) (types.Genesis, error) {
+
+	return s.genesis, s.err
+}
+
+func (s fakeService) Setup(ctx context.Context, ca crypto.CollectiveAuthority,
+	opts ...cosipbft.SetupOption) error {
+	s.calls.Add(ctx, ca, opts)
 	return s.err
 }
 
+func (s fakeService) PrepareRotation(pubkey crypto.PublicKey, fn func()) {
+	s.calls.Add(pubkey, fn)
+}
+
+func (s fakeService) CancelRotation(pubkey crypto.PublicKey) {
+	s.calls.Add(pubkey)
+}
+
 func (s fakeService) Watch(context.Context) <-chan ordering.Event {
 	ch := make(chan ordering.Event, len(s.events))
 	for _, evt := range s.events {
@@ -231,6 +859,8 @@ func (c fakeCosi) GetPublicKeyFactory() crypto.PublicKeyFactory {
 	return fake.NewPublicKeyFactory(fake.PublicKey{})
 }
 
+func (c fakeCosi) SetSigner(crypto.AggregateSigner) {}
+
 func (c fakeCosi) GetSigner() crypto.Signer {
 	if c.err {
 		return fake.NewSignerWithPublicKey(fake.NewBadPublicKey())
@@ -289,3 +919,17 @@ type badPool struct {
 func (p badPool) Add(txn.Transaction) error {
 	return fake.GetError()
 }
+
+type plainProof struct {
+	ordering.Proof
+}
+
+type fakeProof struct {
+	plainProof
+
+	err error
+}
+
+func (p fakeProof) Serialize(serde.Context) ([]byte, error) {
+	return []byte("{}"), p.err
+}
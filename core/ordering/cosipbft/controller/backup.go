@@ -0,0 +1,98 @@
+// This file contains the implementation of the verification of a backup of the
+// database before it is restored.
+
+package controller
+
+import (
+	"bytes"
+	"fmt"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree/binprefix"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// backupVerifier verifies that a backup of the database belongs to the same
+// chain as the node, and that the chain and the tree it contains are
+// consistent.
+//
+// - implements controller.BackupVerifier of the key/value controller
+type backupVerifier struct {
+	genesis     blockstore.GenesisStore
+	genesisFac  serde.Factory
+	linkFac     types.LinkFactory
+	verifierFac crypto.VerifierFactory
+}
+
+// VerifyBackup implements controller.BackupVerifier. It compares the genesis
+// block of the backup with the one of the node, then verifies the chain of
+// blocks and that the root of the tree matches the latest block. It returns a
+// description of the backup if it is valid, otherwise an error.
+func (v backupVerifier) VerifyBackup(db kv.DB) (string, error) {
+	expected, err := v.genesis.Get()
+	if err != nil {
+		return "", xerrors.Errorf("node genesis: %v", err)
+	}
+
+	genstore := blockstore.NewGenesisDiskStore(db, v.genesisFac)
+
+	err = genstore.Load()
+	if err != nil {
+		return "", xerrors.Errorf("failed to load genesis: %v", err)
+	}
+
+	genesis, err := genstore.Get()
+	if err != nil {
+		return "", xerrors.Errorf("backup genesis: %v", err)
+	}
+
+	if genesis.GetHash() != expected.GetHash() {
+		return "", xerrors.Errorf("mismatch genesis '%v' != '%v'",
+			genesis.GetHash(), expected.GetHash())
+	}
+
+	blocks := blockstore.NewDiskStore(db, v.linkFac)
+
+	err = blocks.Load()
+	if err != nil {
+		return "", xerrors.Errorf("failed to load blocks: %v", err)
+	}
+
+	root := genesis.GetRoot()
+	desc := fmt.Sprintf("genesis %v, no block", genesis.GetHash())
+
+	if blocks.Len() > 0 {
+		chain, err := blocks.GetChain()
+		if err != nil {
+			return "", xerrors.Errorf("failed to read chain: %v", err)
+		}
+
+		err = chain.Verify(genesis, v.verifierFac)
+		if err != nil {
+			return "", xerrors.Errorf("invalid chain: %v", err)
+		}
+
+		block := chain.GetBlock()
+		root = block.GetTreeRoot()
+		desc = fmt.Sprintf("genesis %v, latest block %d (%v)",
+			genesis.GetHash(), block.GetIndex(), block.GetHash())
+	}
+
+	tree := binprefix.NewMerkleTree(db, binprefix.Nonce{})
+
+	err = tree.Load()
+	if err != nil {
+		return "", xerrors.Errorf("failed to load tree: %v", err)
+	}
+
+	if !bytes.Equal(tree.GetRoot(), root[:]) {
+		return "", xerrors.Errorf("mismatch tree root %#x != %#x",
+			tree.GetRoot(), root[:])
+	}
+
+	return desc, nil
+}
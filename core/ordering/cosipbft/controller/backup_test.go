@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree/binprefix"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestBackupVerifier_VerifyBackup(t *testing.T) {
+	root := makeEmptyRoot(t)
+	genesis := makeBackupGenesis(t, root)
+
+	verifier := makeBackupVerifier(t, genesis)
+
+	db := kv.NewInMemory()
+	require.NoError(t, blockstore.NewGenesisDiskStore(db, verifier.genesisFac).Set(genesis))
+
+	desc, err := verifier.VerifyBackup(db)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("genesis %v, no block", genesis.GetHash()), desc)
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithIndex(0),
+		types.WithTreeRoot(root))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(genesis.GetHash(), block,
+		types.WithSignatures(fake.Signature{}, fake.Signature{}))
+	require.NoError(t, err)
+
+	require.NoError(t, blockstore.NewDiskStore(db, verifier.linkFac).Store(link))
+
+	desc, err = verifier.VerifyBackup(db)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("genesis %v, latest block 0 (%v)",
+		genesis.GetHash(), block.GetHash()), desc)
+
+	verifier.verifierFac = fake.NewBadVerifierFactory()
+	_, err = verifier.VerifyBackup(db)
+	require.EqualError(t, err,
+		fake.Err("invalid chain: verifier factory failed"))
+}
+
+func TestBackupVerifier_BadGenesis_VerifyBackup(t *testing.T) {
+	genesis := makeBackupGenesis(t, makeEmptyRoot(t))
+
+	verifier := makeBackupVerifier(t, genesis)
+	verifier.genesis = blockstore.NewGenesisStore()
+
+	_, err := verifier.VerifyBackup(kv.NewInMemory())
+	require.EqualError(t, err, "node genesis: missing genesis block")
+
+	verifier = makeBackupVerifier(t, genesis)
+
+	_, err = verifier.VerifyBackup(kv.NewInMemory())
+	require.EqualError(t, err, "backup genesis: missing genesis block")
+
+	other := makeBackupGenesis(t, types.Digest{1})
+
+	db := kv.NewInMemory()
+	require.NoError(t, blockstore.NewGenesisDiskStore(db, verifier.genesisFac).Set(other))
+
+	_, err = verifier.VerifyBackup(db)
+	require.EqualError(t, err, fmt.Sprintf("mismatch genesis '%v' != '%v'",
+		other.GetHash(), genesis.GetHash()))
+
+	verifier = makeBackupVerifier(t, other)
+
+	_, err = verifier.VerifyBackup(db)
+	require.Error(t, err)
+	require.Regexp(t, "^mismatch tree root 0x[0-9a-f]+ != 0x01", err.Error())
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeEmptyRoot(t *testing.T) types.Digest {
+	tree := binprefix.NewMerkleTree(kv.NewInMemory(), binprefix.Nonce{})
+	require.NoError(t, tree.Load())
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	return root
+}
+
+func makeBackupGenesis(t *testing.T, root types.Digest) types.Genesis {
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	genesis, err := types.NewGenesis(ro, types.WithGenesisRoot(root))
+	require.NoError(t, err)
+
+	return genesis
+}
+
+func makeBackupVerifier(t *testing.T, genesis types.Genesis) backupVerifier {
+	genstore := blockstore.NewGenesisStore()
+	require.NoError(t, genstore.Set(genesis))
+
+	rosterFac := authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
+	csFac := authority.NewChangeSetFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
+	resultFac := simple.NewResultFactory(signed.NewTransactionFactory())
+
+	return backupVerifier{
+		genesis:     genstore,
+		genesisFac:  types.NewGenesisFactory(rosterFac),
+		linkFac:     types.NewLinkFactory(types.NewBlockFactory(resultFac), fake.SignatureFactory{}, csFac),
+		verifierFac: fake.NewVerifierFactory(fake.Verifier{}),
+	}
+}
@@ -6,9 +6,14 @@ package controller
 
 import (
 	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"go.dedis.ch/dela"
 	"go.dedis.ch/dela/contracts/value"
 	"go.dedis.ch/dela/crypto"
 
@@ -36,7 +41,16 @@ import (
 	"golang.org/x/xerrors"
 )
 
-const privateKeyFile = "private.key"
+const (
+	privateKeyFile = "private.key"
+
+	// nextKeyFile is the file of the private key waiting for a rotation to be
+	// committed. It replaces the private key file once done.
+	nextKeyFile = "private.key.next"
+
+	defaultMinTimeout = time.Second
+	defaultMaxTimeout = time.Minute
+)
 
 // valueAccessKey is the access key used for the value contract.
 var valueAccessKey = [32]byte{2}
@@ -63,6 +77,77 @@ func NewController() node.Initializer {
 // SetCommands implements node.Initializer. It sets the command to control the
 // service.
 func (miniController) SetCommands(builder node.Builder) {
+	builder.SetStartFlags(
+		cli.DurationFlag{
+			Name:  "round-timeout",
+			Usage: "maximum amount of time to wait for a block before a view change",
+			Value: cosipbft.RoundTimeout,
+		},
+		cli.DurationFlag{
+			Name:  "round-timeout-failure",
+			Usage: "round timeout after a view change",
+			Value: cosipbft.RoundTimeout,
+		},
+		cli.DurationFlag{
+			Name:  "viewchange-timeout",
+			Usage: "maximum amount of time to wait for a view change",
+			Value: cosipbft.RoundTimeout,
+		},
+		cli.DurationFlag{
+			Name:  "round-wait",
+			Usage: "base value of the backoff between round failures",
+			Value: cosipbft.RoundWait,
+		},
+		cli.DurationFlag{
+			Name:  "round-max-wait",
+			Usage: "maximum value of the backoff between round failures",
+			Value: cosipbft.RoundMaxWait,
+		},
+		cli.BoolFlag{
+			Name:  "round-adaptive",
+			Usage: "derive the round timeout from the observed latency",
+		},
+		cli.DurationFlag{
+			Name:  "round-timeout-min",
+			Usage: "lower bound of the adaptive round timeout",
+			Value: defaultMinTimeout,
+		},
+		cli.DurationFlag{
+			Name:  "round-timeout-max",
+			Usage: "upper bound of the adaptive round timeout",
+			Value: defaultMaxTimeout,
+		},
+		cli.IntFlag{
+			Name:  "history",
+			Usage: "number of past states of the tree to retain (0 disables)",
+		},
+		cli.BoolFlag{
+			Name: "ordered-index",
+			Usage: "keep the keys of the tree in order to list and prove " +
+				"the key prefixes, which must be set by every member",
+		},
+		cli.IntFlag{
+			Name: "snapshot-sync",
+			Usage: "number of missing blocks from which a new node restores " +
+				"a snapshot of the state instead of the blocks (0 disables)",
+		},
+		cli.IntFlag{
+			Name: "sync-range",
+			Usage: "number of blocks requested at once to each member when " +
+				"downloading the missing blocks in parallel (0 disables)",
+		},
+		cli.StringSliceFlag{
+			Name: "observe",
+			Usage: "member to follow, as exported by 'ordering export', which " +
+				"makes the node an observer that does not take part in the consensus",
+		},
+		cli.StringFlag{
+			Name: "observe-digest",
+			Usage: "digest of the genesis block of the chain to observe, in " +
+				"hexadecimal, which is required with --observe",
+		},
+	)
+
 	cmd := builder.SetCommand("ordering")
 	cmd.SetDescription("Ordering service administration")
 
@@ -75,9 +160,27 @@ func (miniController) SetCommands(builder node.Builder) {
 			Value: 20 * time.Second,
 		},
 		cli.StringSliceFlag{
-			Name:     "member",
-			Required: true,
-			Usage:    "one or several member of the new chain",
+			Name:  "member",
+			Usage: "one or several member of the new chain",
+		},
+		cli.StringFlag{
+			Name: "leader-policy",
+			Usage: "policy that rotates the leaders: sticky, round-robin or " +
+				"weighted, where the weights are the ones of the roster",
+			Value: "sticky",
+		},
+		cli.StringFlag{
+			Name: "genesis",
+			Usage: "path to a JSON file with the members, the leader policy " +
+				"and the initial state of the new chain, instead of --member",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "prints the digest of the genesis block without creating the chain",
+		},
+		cli.StringFlag{
+			Name:  "digest",
+			Usage: "expected digest of the genesis block, in hexadecimal",
 		},
 	)
 	sub.SetAction(builder.MakeAction(setupAction{}))
@@ -89,7 +192,9 @@ func (miniController) SetCommands(builder node.Builder) {
 	sub = cmd.SetSubCommand("roster")
 	sub.SetDescription("Roster administration")
 
-	sub = sub.SetSubCommand("add")
+	rosterCmd := sub
+
+	sub = rosterCmd.SetSubCommand("add")
 	sub.SetDescription("Add a member to the chain")
 	sub.SetFlags(
 		cli.StringFlag{
@@ -97,12 +202,148 @@ func (miniController) SetCommands(builder node.Builder) {
 			Required: true,
 			Usage:    "base64 description of the member to add",
 		},
+		cli.IntFlag{
+			Name:  "weight",
+			Usage: "weight of the member for the weighted leader policy",
+			Value: authority.DefaultWeight,
+		},
 		cli.DurationFlag{
 			Name:  "wait",
 			Usage: "wait for the transaction to be processed",
 		},
 	)
 	sub.SetAction(builder.MakeAction(rosterAddAction{}))
+
+	sub = rosterCmd.SetSubCommand("remove")
+	sub.SetDescription("Remove a member from the chain")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "member",
+			Required: true,
+			Usage:    "base64 description of the member to remove",
+		},
+		cli.DurationFlag{
+			Name:  "wait",
+			Usage: "wait for the transaction to be processed",
+		},
+	)
+	sub.SetAction(builder.MakeAction(rosterRemoveAction{}))
+
+	sub = rosterCmd.SetSubCommand("rotate")
+	sub.SetDescription("Replace the public key of the node in the chain")
+	sub.SetFlags(
+		cli.DurationFlag{
+			Name:  "wait",
+			Usage: "wait for the transaction to be processed",
+			Value: 20 * time.Second,
+		},
+	)
+	sub.SetAction(builder.MakeAction(rosterRotateAction{}))
+
+	sub = cmd.SetSubCommand("limits")
+	sub.SetDescription("Change the limits of the blocks of the chain")
+	sub.SetFlags(
+		cli.IntFlag{
+			Name:  "max-transactions",
+			Usage: "maximum number of transactions in a block (0 removes the limit)",
+		},
+		cli.IntFlag{
+			Name:  "max-size",
+			Usage: "maximum size of the transactions of a block in bytes (0 removes the limit)",
+		},
+		cli.DurationFlag{
+			Name:  "wait",
+			Usage: "wait for the transaction to be processed",
+		},
+	)
+	sub.SetAction(builder.MakeAction(limitsAction{}))
+
+	sub = cmd.SetSubCommand("proof")
+	sub.SetDescription("Print the proof of the value of a key")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "key",
+			Required: true,
+			Usage:    "key to prove",
+		},
+		cli.BoolFlag{
+			Name:  "hex",
+			Usage: "decode the key as a hexadecimal string",
+		},
+		cli.IntFlag{
+			Name:  "index",
+			Usage: "index of the block to prove the value at, or the latest",
+			Value: -1,
+		},
+	)
+	sub.SetAction(builder.MakeAction(proofAction{}))
+
+	sub = cmd.SetSubCommand("receipt")
+	sub.SetDescription("Print the block including a transaction and its status")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "id",
+			Required: true,
+			Usage:    "hexadecimal identifier of the transaction",
+		},
+	)
+	sub.SetAction(builder.MakeAction(receiptAction{}))
+
+	sub = cmd.SetSubCommand("chain")
+	sub.SetDescription("Chain archives administration")
+
+	chainCmd := sub
+
+	sub = chainCmd.SetSubCommand("export")
+	sub.SetDescription("Write the chain in an archive file")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "out",
+			Required: true,
+			Usage:    "path to the archive file to create",
+		},
+	)
+	sub.SetAction(builder.MakeAction(chainExportAction{}))
+
+	sub = chainCmd.SetSubCommand("import")
+	sub.SetDescription("Verify and apply the chain of an archive file")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "in",
+			Required: true,
+			Usage:    "path to the archive file to import",
+		},
+	)
+	sub.SetAction(builder.MakeAction(chainImportAction{}))
+
+	sub = cmd.SetSubCommand("evidence")
+	sub.SetDescription("Misbehaviors of the roster members")
+
+	evidenceCmd := sub
+
+	sub = evidenceCmd.SetSubCommand("list")
+	sub.SetDescription("Print the evidences of misbehavior recorded by the node")
+	sub.SetAction(builder.MakeAction(evidenceListAction{}))
+
+	sub = cmd.SetSubCommand("genesis")
+	sub.SetDescription("Print the genesis block of the chain")
+	sub.SetAction(builder.MakeAction(genesisAction{}))
+
+	sub = cmd.SetSubCommand("verify")
+	sub.SetDescription("Verify a proof without a running node")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "proof",
+			Required: true,
+			Usage:    "path to the file of the proof",
+		},
+		cli.StringFlag{
+			Name:     "genesis",
+			Required: true,
+			Usage:    "path to the file of the trusted genesis block",
+		},
+	)
+	sub.SetAction(newVerifyAction(os.Stdout).Execute)
 }
 
 // OnStart implements node.Initializer. It starts the ordering components and
@@ -126,7 +367,7 @@ func (m miniController) OnStart(flags cli.Flags, inj node.Injector) error {
 	access := darc.NewService(json.NewContext())
 
 	rosterFac := authority.NewFactory(onet.GetAddressFactory(), cosi.GetPublicKeyFactory())
-	cosipbft.RegisterRosterContract(exec, rosterFac, access)
+	cosipbft.RegisterRosterContract(exec, rosterFac, cosi.GetSigner().GetSignatureFactory(), access)
 
 	value.RegisterContract(exec, value.NewContract(valueAccessKey[:], access))
 
@@ -144,7 +385,27 @@ func (m miniController) OnStart(flags cli.Flags, inj node.Injector) error {
 		return xerrors.Errorf("injector: %v", err)
 	}
 
-	tree := binprefix.NewMerkleTree(db, binprefix.Nonce{})
+	history := flags.Int("history")
+	if history < 0 {
+		return xerrors.Errorf("invalid history: %d < 0", history)
+	}
+
+	snapshot := flags.Int("snapshot-sync")
+	if snapshot < 0 {
+		return xerrors.Errorf("invalid snapshot threshold: %d < 0", snapshot)
+	}
+
+	syncRange := flags.Int("sync-range")
+	if syncRange < 0 {
+		return xerrors.Errorf("invalid sync range: %d < 0", syncRange)
+	}
+
+	treeOpts := []binprefix.TreeOption{binprefix.WithHistory(uint(history))}
+	if flags.Bool("ordered-index") {
+		treeOpts = append(treeOpts, binprefix.WithOrderedIndex())
+	}
+
+	tree := binprefix.NewMerkleTree(db, binprefix.Nonce{}, treeOpts...)
 
 	param := cosipbft.ServiceParam{
 		Mino:       onet,
@@ -179,13 +440,62 @@ func (m miniController) OnStart(flags cli.Flags, inj node.Injector) error {
 		return xerrors.Errorf("failed to load blocks: %v", err)
 	}
 
-	srvc, err := cosipbft.NewService(param, cosipbft.WithGenesisStore(genstore), cosipbft.WithBlockStore(blocks))
+	opts, err := makeTimeoutOptions(flags)
+	if err != nil {
+		return xerrors.Errorf("timeouts: %v", err)
+	}
+
+	opts = append(opts, cosipbft.WithGenesisStore(genstore), cosipbft.WithBlockStore(blocks),
+		cosipbft.WithSnapshotSync(uint64(snapshot), binprefix.NewSuffixProofFactory()),
+		cosipbft.WithParallelSync(uint64(syncRange)))
+
+	observed := flags.StringSlice("observe")
+	if len(observed) > 0 {
+		members := make([]mino.Address, len(observed))
+
+		for i, member := range observed {
+			members[i], err = decodeAddress(onet, member)
+			if err != nil {
+				return xerrors.Errorf("invalid member to observe: %v", err)
+			}
+		}
+
+		genesis, err := decodeDigest(flags.String("observe-digest"))
+		if err != nil {
+			return xerrors.Errorf("invalid genesis to observe: %v", err)
+		}
+
+		opts = append(opts, cosipbft.WithObserver(genesis, members...))
+	}
+
+	srvc, err := cosipbft.NewService(param, opts...)
 	if err != nil {
 		return xerrors.Errorf("service: %v", err)
 	}
 
+	// A rotation of the key might have been requested before the node stopped,
+	// in which case the service needs to know when to switch.
+	dir := flags.Path("config")
+
+	_, err = os.Stat(filepath.Join(dir, nextKeyFile))
+	if err == nil {
+		next, err := loadSigner(filepath.Join(dir, nextKeyFile), m.signerFn)
+		if err != nil {
+			return xerrors.Errorf("next signer: %v", err)
+		}
+
+		srvc.PrepareRotation(next.GetPublicKey(), makeRotation(cosi, dir, next))
+	}
+
 	inj.Inject(srvc)
+	inj.Inject(genstore)
 	inj.Inject(cosi)
+	inj.Inject(backupVerifier{
+		genesis:     genstore,
+		genesisFac:  types.NewGenesisFactory(rosterFac),
+		linkFac:     linkFac,
+		verifierFac: cosi.GetVerifierFactory(),
+	})
 	inj.Inject(pool)
 	inj.Inject(vs)
 	inj.Inject(exec)
@@ -222,10 +532,124 @@ func (miniController) OnStop(inj node.Injector) error {
 	return nil
 }
 
+// decodeDigest decodes the hexadecimal representation of a digest. The
+// digest is required.
+func decodeDigest(str string) (types.Digest, error) {
+	digest := types.Digest{}
+
+	if str == "" {
+		return digest, xerrors.New("missing digest")
+	}
+
+	buffer, err := hex.DecodeString(str)
+	if err != nil {
+		return digest, xerrors.Errorf("hex: %v", err)
+	}
+
+	if len(buffer) != len(digest) {
+		return digest, xerrors.Errorf("invalid length %d != %d", len(buffer), len(digest))
+	}
+
+	copy(digest[:], buffer)
+
+	return digest, nil
+}
+
+// decodeAddress returns the address of a member from the string exported by
+// the export command. The public key is ignored if present.
+func decodeAddress(m mino.Mino, str string) (mino.Address, error) {
+	parts := strings.Split(str, separator)
+
+	data, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, xerrors.Errorf("base64 address: %v", err)
+	}
+
+	return m.GetAddressFactory().FromText(data), nil
+}
+
+// makeTimeoutOptions returns the service options for the timeouts that are
+// set by the flags. Missing flags leave the default values of the service.
+func makeTimeoutOptions(flags cli.Flags) ([]cosipbft.ServiceOption, error) {
+	opts := []cosipbft.ServiceOption{}
+
+	timeout := flags.Duration("round-timeout")
+	if timeout > 0 {
+		opts = append(opts, cosipbft.WithRoundTimeout(timeout))
+	}
+
+	timeout = flags.Duration("round-timeout-failure")
+	if timeout > 0 {
+		opts = append(opts, cosipbft.WithFailedRoundTimeout(timeout))
+	}
+
+	timeout = flags.Duration("viewchange-timeout")
+	if timeout > 0 {
+		opts = append(opts, cosipbft.WithViewChangeTimeout(timeout))
+	}
+
+	wait := flags.Duration("round-wait")
+	maxWait := flags.Duration("round-max-wait")
+
+	if wait > 0 || maxWait > 0 {
+		if wait <= 0 {
+			wait = cosipbft.RoundWait
+		}
+
+		if maxWait <= 0 {
+			maxWait = cosipbft.RoundMaxWait
+		}
+
+		opts = append(opts, cosipbft.WithRoundBackoff(wait, maxWait))
+	}
+
+	if flags.Bool("round-adaptive") {
+		min := flags.Duration("round-timeout-min")
+		if min <= 0 {
+			min = defaultMinTimeout
+		}
+
+		max := flags.Duration("round-timeout-max")
+		if max <= 0 {
+			max = defaultMaxTimeout
+		}
+
+		if min > max {
+			return nil, xerrors.Errorf("invalid adaptive bounds: %v > %v", min, max)
+		}
+
+		opts = append(opts, cosipbft.WithAdaptiveTimeout(min, max))
+	}
+
+	return opts, nil
+}
+
 func (m miniController) getSigner(flags cli.Flags) (crypto.AggregateSigner, error) {
-	loader := loader.NewFileLoader(filepath.Join(flags.Path("config"), privateKeyFile))
+	signer, err := loadSigner(filepath.Join(flags.Path("config"), privateKeyFile), m.signerFn)
+	if err != nil {
+		return nil, err
+	}
+
+	return signer, nil
+}
+
+// makeRotation returns the function that switches the signer of the collective
+// signing to the next one, and that replaces the private key file of the node.
+func makeRotation(c signerSwitcher, dir string, next crypto.AggregateSigner) func() {
+	return func() {
+		c.SetSigner(next)
+
+		err := os.Rename(filepath.Join(dir, nextKeyFile), filepath.Join(dir, privateKeyFile))
+		if err != nil {
+			dela.Logger.Err(err).Msg("failed to replace the private key")
+		}
+	}
+}
+
+func loadSigner(path string, fn func() encoding.BinaryMarshaler) (crypto.AggregateSigner, error) {
+	loader := loader.NewFileLoader(path)
 
-	signerdata, err := loader.LoadOrCreate(generator{newFn: m.signerFn})
+	signerdata, err := loader.LoadOrCreate(generator{newFn: fn})
 	if err != nil {
 		return nil, xerrors.Errorf("while loading: %v", err)
 	}
@@ -5,13 +5,17 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/cli"
 	"go.dedis.ch/dela/cli/node"
 	"go.dedis.ch/dela/core/store/kv"
 	"go.dedis.ch/dela/core/txn/pool"
+	"go.dedis.ch/dela/cosi/threshold"
+	"go.dedis.ch/dela/crypto/bls"
 	"go.dedis.ch/dela/internal/testing/fake"
 )
 
@@ -26,6 +30,8 @@ func TestMinimal_OnStart(t *testing.T) {
 	flags, dir, clean := makeFlags(t)
 	defer clean()
 
+	flags.(node.FlagSet)["ordered-index"] = true
+
 	db, err := kv.New(filepath.Join(dir, "test.db"))
 	require.NoError(t, err)
 
@@ -77,6 +83,103 @@ func TestMinimal_MissingDB_OnStart(t *testing.T) {
 	require.EqualError(t, err, "injector: couldn't find dependency for 'kv.DB'")
 }
 
+func TestMinimal_InvalidHistory_OnStart(t *testing.T) {
+	flags, _, clean := makeFlags(t)
+	defer clean()
+
+	flags.(node.FlagSet)["history"] = -1
+
+	m := NewController().(miniController)
+
+	inj := node.NewInjector()
+	inj.Inject(fake.Mino{})
+	inj.Inject(fake.NewInMemoryDB())
+
+	err := m.OnStart(flags, inj)
+	require.EqualError(t, err, "invalid history: -1 < 0")
+}
+
+func TestMinimal_InvalidSnapshot_OnStart(t *testing.T) {
+	flags, _, clean := makeFlags(t)
+	defer clean()
+
+	flags.(node.FlagSet)["snapshot-sync"] = -1
+
+	m := NewController().(miniController)
+
+	inj := node.NewInjector()
+	inj.Inject(fake.Mino{})
+	inj.Inject(fake.NewInMemoryDB())
+
+	err := m.OnStart(flags, inj)
+	require.EqualError(t, err, "invalid snapshot threshold: -1 < 0")
+}
+
+func TestMinimal_InvalidSyncRange_OnStart(t *testing.T) {
+	flags, _, clean := makeFlags(t)
+	defer clean()
+
+	flags.(node.FlagSet)["sync-range"] = -1
+
+	m := NewController().(miniController)
+
+	inj := node.NewInjector()
+	inj.Inject(fake.Mino{})
+	inj.Inject(fake.NewInMemoryDB())
+
+	err := m.OnStart(flags, inj)
+	require.EqualError(t, err, "invalid sync range: -1 < 0")
+}
+
+func TestMinimal_InvalidObserve_OnStart(t *testing.T) {
+	flags, _, clean := makeFlags(t)
+	defer clean()
+
+	flags.(node.FlagSet)["observe"] = []interface{}{"!"}
+
+	m := NewController().(miniController)
+
+	inj := node.NewInjector()
+	inj.Inject(fake.Mino{})
+	inj.Inject(fake.NewInMemoryDB())
+
+	err := m.OnStart(flags, inj)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid member to observe: base64 address: ")
+
+	// The genesis of the chain to observe is required.
+	flags.(node.FlagSet)["observe"] = []interface{}{"AAAAAA==:AA=="}
+
+	err = m.OnStart(flags, inj)
+	require.EqualError(t, err, "invalid genesis to observe: missing digest")
+}
+
+func TestDecodeDigest(t *testing.T) {
+	digest, err := decodeDigest(strings.Repeat("ab", 32))
+	require.NoError(t, err)
+	require.Equal(t, byte(0xab), digest[31])
+
+	_, err = decodeDigest("")
+	require.EqualError(t, err, "missing digest")
+
+	_, err = decodeDigest("zz")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "hex: ")
+
+	_, err = decodeDigest("abcd")
+	require.EqualError(t, err, "invalid length 2 != 32")
+}
+
+func TestDecodeAddress(t *testing.T) {
+	addr, err := decodeAddress(fake.Mino{}, "AAAAAA==:AA==")
+	require.NoError(t, err)
+	require.Equal(t, fake.NewAddress(0), addr)
+
+	addr, err = decodeAddress(fake.Mino{}, "AQAAAA==")
+	require.NoError(t, err)
+	require.Equal(t, fake.NewAddress(1), addr)
+}
+
 func TestMinimal_MalformedKey_OnStart(t *testing.T) {
 	flags, dir, clean := makeFlags(t)
 	defer clean()
@@ -97,6 +200,75 @@ func TestMinimal_MalformedKey_OnStart(t *testing.T) {
 	require.Contains(t, err.Error(), "signer: while unmarshaling: ")
 }
 
+func TestMinimal_PendingRotation_OnStart(t *testing.T) {
+	flags, dir, clean := makeFlags(t)
+	defer clean()
+
+	m := NewController().(miniController)
+
+	inj := node.NewInjector()
+	inj.Inject(fake.Mino{})
+	inj.Inject(fake.NewInMemoryDB())
+
+	data, err := bls.NewSigner().MarshalBinary()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, nextKeyFile), data, 0400)
+	require.NoError(t, err)
+
+	err = m.OnStart(flags, inj)
+	require.NoError(t, err)
+
+	require.NoError(t, os.Remove(filepath.Join(dir, nextKeyFile)))
+
+	file, err := os.Create(filepath.Join(dir, nextKeyFile))
+	require.NoError(t, err)
+
+	file.Close()
+
+	err = m.OnStart(flags, node.NewInjector())
+	require.EqualError(t, err,
+		"injector: couldn't find dependency for 'mino.Mino'")
+
+	inj = node.NewInjector()
+	inj.Inject(fake.Mino{})
+	inj.Inject(fake.NewInMemoryDB())
+
+	err = m.OnStart(flags, inj)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "next signer: while unmarshaling: ")
+}
+
+func TestMakeRotation(t *testing.T) {
+	_, dir, clean := makeFlags(t)
+	defer clean()
+
+	c := threshold.NewThreshold(fake.Mino{}, bls.NewSigner())
+
+	next := bls.NewSigner()
+
+	data, err := next.MarshalBinary()
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, nextKeyFile), data, 0400)
+	require.NoError(t, err)
+
+	fn := makeRotation(c, dir, next)
+	fn()
+
+	require.Equal(t, next, c.GetSigner())
+	require.NoFileExists(t, filepath.Join(dir, nextKeyFile))
+
+	stored, err := ioutil.ReadFile(filepath.Join(dir, privateKeyFile))
+	require.NoError(t, err)
+	require.Equal(t, data, stored)
+
+	// The file is missing but the signer is still updated.
+	c.SetSigner(bls.NewSigner())
+	fn()
+	require.Equal(t, next, c.GetSigner())
+}
+
 func TestMinimal_OnStop(t *testing.T) {
 	dir, err := ioutil.TempDir(os.TempDir(), "dela-test-")
 	require.NoError(t, err)
@@ -143,6 +315,30 @@ func TestMinimal_OnStop(t *testing.T) {
 // -----------------------------------------------------------------------------
 // Utility functions
 
+func TestMakeTimeoutOptions(t *testing.T) {
+	opts, err := makeTimeoutOptions(make(node.FlagSet))
+	require.NoError(t, err)
+	require.Len(t, opts, 0)
+
+	flags := node.FlagSet{
+		"round-timeout":         float64(time.Second),
+		"round-timeout-failure": float64(time.Second),
+		"viewchange-timeout":    float64(time.Second),
+		"round-wait":            float64(time.Millisecond),
+		"round-adaptive":        true,
+	}
+
+	opts, err = makeTimeoutOptions(flags)
+	require.NoError(t, err)
+	require.Len(t, opts, 5)
+
+	flags["round-timeout-min"] = float64(time.Minute)
+	flags["round-timeout-max"] = float64(time.Second)
+
+	_, err = makeTimeoutOptions(flags)
+	require.EqualError(t, err, "invalid adaptive bounds: 1m0s > 1s")
+}
+
 func makeFlags(t *testing.T) (cli.Flags, string, func()) {
 	dir, err := ioutil.TempDir(os.TempDir(), "dela-")
 	require.NoError(t, err)
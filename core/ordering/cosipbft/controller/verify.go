@@ -0,0 +1,161 @@
+// This file contains the implementation of the offline verification of proofs.
+
+package controller
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"go.dedis.ch/dela/cli"
+	"go.dedis.ch/dela/core/ordering/cosipbft"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree/binprefix"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/core/validation/simple"
+	thresholdtypes "go.dedis.ch/dela/cosi/threshold/types"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"golang.org/x/xerrors"
+)
+
+// verifiableProof is the expected interface of a proof that can be verified
+// with only the genesis block.
+type verifiableProof interface {
+	GetKey() []byte
+	GetValue() []byte
+	Verify(genesis types.Genesis, fac crypto.VerifierFactory) error
+}
+
+// verifyAction is an action to verify a proof against a genesis block. It runs
+// locally and does not need a running node.
+type verifyAction struct {
+	out         io.Writer
+	readFile    func(string) ([]byte, error)
+	genesisFac  serde.Factory
+	proofFac    serde.Factory
+	verifierFac crypto.VerifierFactory
+}
+
+// newVerifyAction creates a verify action with the factories of the components
+// used by the controller.
+func newVerifyAction(out io.Writer) verifyAction {
+	var signer bls.Signer
+
+	addrFac := offlineAddressFactory{}
+	pubkeyFac := signer.GetPublicKeyFactory()
+
+	rosterFac := authority.NewFactory(addrFac, pubkeyFac)
+	csFac := authority.NewChangeSetFactory(addrFac, pubkeyFac)
+
+	resultFac := simple.NewResultFactory(signed.NewTransactionFactory())
+	blockFac := types.NewBlockFactory(resultFac)
+	sigFac := thresholdtypes.NewSignatureFactory(signer.GetSignatureFactory())
+	linkFac := types.NewLinkFactory(blockFac, sigFac, csFac)
+
+	return verifyAction{
+		out:        out,
+		readFile:   ioutil.ReadFile,
+		genesisFac: types.NewGenesisFactory(rosterFac),
+		proofFac: cosipbft.NewProofFactory(binprefix.NewPathFactory(),
+			types.NewChainFactory(linkFac)),
+		verifierFac: thresholdtypes.NewThresholdVerifierFactory(signer.GetVerifierFactory()),
+	}
+}
+
+// Execute reads the proof and the genesis block from the files, and verifies
+// that the proof is valid. It prints the key and the value if it exists.
+func (a verifyAction) Execute(flags cli.Flags) error {
+	ctx := json.NewContext()
+
+	data, err := a.readFile(flags.String("genesis"))
+	if err != nil {
+		return xerrors.Errorf("failed to read genesis: %v", err)
+	}
+
+	msg, err := a.genesisFac.Deserialize(ctx, data)
+	if err != nil {
+		return xerrors.Errorf("failed to decode genesis: %v", err)
+	}
+
+	genesis, ok := msg.(types.Genesis)
+	if !ok {
+		return xerrors.Errorf("invalid genesis '%T'", msg)
+	}
+
+	data, err = a.readFile(flags.String("proof"))
+	if err != nil {
+		return xerrors.Errorf("failed to read proof: %v", err)
+	}
+
+	msg, err = a.proofFac.Deserialize(ctx, data)
+	if err != nil {
+		return xerrors.Errorf("failed to decode proof: %v", err)
+	}
+
+	proof, ok := msg.(verifiableProof)
+	if !ok {
+		return xerrors.Errorf("invalid proof '%T'", msg)
+	}
+
+	err = proof.Verify(genesis, a.verifierFac)
+	if err != nil {
+		return xerrors.Errorf("invalid proof: %v", err)
+	}
+
+	if proof.GetValue() == nil {
+		fmt.Fprintf(a.out, "proof of absence: %q\n", proof.GetKey())
+	} else {
+		fmt.Fprintf(a.out, "proof of inclusion: %q=%q\n", proof.GetKey(), proof.GetValue())
+	}
+
+	return nil
+}
+
+// offlineAddress is an address that only knows its text representation, which
+// is enough to verify the chain without reaching the participants.
+//
+// - implements mino.Address
+type offlineAddress string
+
+// Equal implements mino.Address. It returns true if both addresses have the
+// same text representation.
+func (a offlineAddress) Equal(other mino.Address) bool {
+	text, err := other.MarshalText()
+	if err != nil {
+		return false
+	}
+
+	return string(a) == string(text)
+}
+
+// MarshalText implements encoding.TextMarshaler. It returns the original text
+// of the address.
+func (a offlineAddress) MarshalText() ([]byte, error) {
+	return []byte(a), nil
+}
+
+// String implements fmt.Stringer. It returns the text of the address.
+func (a offlineAddress) String() string {
+	return string(a)
+}
+
+// offlineAddressFactory is the factory of offline addresses.
+//
+// - implements mino.AddressFactory
+type offlineAddressFactory struct{}
+
+// Deserialize implements serde.Factory. It always returns an error as the
+// addresses of a chain are only read from their text.
+func (offlineAddressFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return nil, xerrors.New("offline addresses cannot be deserialized")
+}
+
+// FromText implements mino.AddressFactory. It returns the address of the text.
+func (offlineAddressFactory) FromText(text []byte) mino.Address {
+	return offlineAddress(text)
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/cli/node"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+)
+
+func TestNewVerifyAction(t *testing.T) {
+	action := newVerifyAction(nil)
+	require.NotNil(t, action.readFile)
+	require.NotNil(t, action.genesisFac)
+	require.NotNil(t, action.proofFac)
+	require.NotNil(t, action.verifierFac)
+}
+
+func TestVerifyAction_Execute(t *testing.T) {
+	buffer := new(bytes.Buffer)
+
+	action := verifyAction{
+		out:         buffer,
+		readFile:    func(string) ([]byte, error) { return nil, nil },
+		genesisFac:  fakeFactory{msg: types.Genesis{}},
+		proofFac:    fakeFactory{msg: fakeVerifiableProof{value: []byte("B")}},
+		verifierFac: fake.VerifierFactory{},
+	}
+
+	flags := node.FlagSet{
+		"genesis": "genesis.json",
+		"proof":   "proof.json",
+	}
+
+	err := action.Execute(flags)
+	require.NoError(t, err)
+	require.Equal(t, "proof of inclusion: \"A\"=\"B\"\n", buffer.String())
+
+	buffer.Reset()
+	action.proofFac = fakeFactory{msg: fakeVerifiableProof{}}
+	err = action.Execute(flags)
+	require.NoError(t, err)
+	require.Equal(t, "proof of absence: \"A\"\n", buffer.String())
+
+	action.proofFac = fakeFactory{msg: fakeVerifiableProof{err: fake.GetError()}}
+	err = action.Execute(flags)
+	require.EqualError(t, err, fake.Err("invalid proof"))
+
+	action.proofFac = fakeFactory{msg: fake.Message{}}
+	err = action.Execute(flags)
+	require.EqualError(t, err, "invalid proof 'fake.Message'")
+
+	action.proofFac = fakeFactory{err: fake.GetError()}
+	err = action.Execute(flags)
+	require.EqualError(t, err, fake.Err("failed to decode proof"))
+
+	action.genesisFac = fakeFactory{msg: fake.Message{}}
+	err = action.Execute(flags)
+	require.EqualError(t, err, "invalid genesis 'fake.Message'")
+
+	action.genesisFac = fakeFactory{err: fake.GetError()}
+	err = action.Execute(flags)
+	require.EqualError(t, err, fake.Err("failed to decode genesis"))
+
+	action.readFile = func(path string) ([]byte, error) {
+		if path == "proof.json" {
+			return nil, fake.GetError()
+		}
+
+		return nil, nil
+	}
+	action.genesisFac = fakeFactory{msg: types.Genesis{}}
+	err = action.Execute(flags)
+	require.EqualError(t, err, fake.Err("failed to read proof"))
+
+	action.readFile = func(string) ([]byte, error) { return nil, fake.GetError() }
+	err = action.Execute(flags)
+	require.EqualError(t, err, fake.Err("failed to read genesis"))
+}
+
+func TestOfflineAddress_Equal(t *testing.T) {
+	addr := offlineAddress("A")
+
+	require.True(t, addr.Equal(offlineAddress("A")))
+	require.False(t, addr.Equal(offlineAddress("B")))
+	require.False(t, addr.Equal(fake.NewBadAddress()))
+	require.Equal(t, "A", addr.String())
+
+	text, err := addr.MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, []byte("A"), text)
+}
+
+func TestOfflineAddressFactory_FromText(t *testing.T) {
+	fac := offlineAddressFactory{}
+
+	require.Equal(t, offlineAddress("A"), fac.FromText([]byte("A")))
+
+	_, err := fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "offline addresses cannot be deserialized")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type fakeFactory struct {
+	msg serde.Message
+	err error
+}
+
+func (f fakeFactory) Deserialize(serde.Context, []byte) (serde.Message, error) {
+	return f.msg, f.err
+}
+
+type fakeVerifiableProof struct {
+	serde.Message
+
+	value []byte
+	err   error
+}
+
+func (p fakeVerifiableProof) GetKey() []byte {
+	return []byte("A")
+}
+
+func (p fakeVerifiableProof) GetValue() []byte {
+	return p.value
+}
+
+func (p fakeVerifiableProof) Verify(types.Genesis, crypto.VerifierFactory) error {
+	return p.err
+}
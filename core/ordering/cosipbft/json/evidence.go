@@ -0,0 +1,144 @@
+package json
+
+import (
+	"encoding/json"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// StatementJSON is the JSON message for a statement of an evidence.
+type StatementJSON struct {
+	ID        []byte
+	Leader    uint16
+	Signature json.RawMessage
+}
+
+// EvidenceJSON is the JSON message for an evidence.
+type EvidenceJSON struct {
+	Kind       uint8
+	Index      uint64
+	Offender   []byte
+	PublicKey  json.RawMessage
+	Statements []StatementJSON
+	Signature  json.RawMessage
+}
+
+// EvidenceFormat is the JSON format to encode and decode evidences.
+//
+// - implements serde.FormatEngine
+type evidenceFormat struct{}
+
+// Encode implements serde.FormatEngine. It serializes the evidence if
+// appropriate, otherwise it returns an error.
+func (f evidenceFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	ev, ok := msg.(types.Evidence)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	offender, err := ev.GetOffender().MarshalText()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize address: %v", err)
+	}
+
+	pubkey, err := ev.GetPublicKey().Serialize(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize public key: %v", err)
+	}
+
+	sig, err := ev.GetSignature().Serialize(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize signature: %v", err)
+	}
+
+	statements := make([]StatementJSON, 0, len(ev.GetStatements()))
+	for _, st := range ev.GetStatements() {
+		stSig, err := st.GetSignature().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize statement: %v", err)
+		}
+
+		statements = append(statements, StatementJSON{
+			ID:        st.GetID().Bytes(),
+			Leader:    st.GetLeader(),
+			Signature: stSig,
+		})
+	}
+
+	m := EvidenceJSON{
+		Kind:       uint8(ev.GetKind()),
+		Index:      ev.GetIndex(),
+		Offender:   offender,
+		PublicKey:  pubkey,
+		Statements: statements,
+		Signature:  sig,
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+// Decode implements serde.FormatEngine. It populates the evidence if
+// appropriate, otherwise it returns an error.
+func (f evidenceFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := EvidenceJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	factory := ctx.GetFactory(types.AddressKey{})
+
+	fac, ok := factory.(mino.AddressFactory)
+	if !ok {
+		return nil, xerrors.Errorf("invalid address factory '%T'", factory)
+	}
+
+	factory = ctx.GetFactory(types.PublicKeyKey{})
+
+	pubkeyFac, ok := factory.(crypto.PublicKeyFactory)
+	if !ok {
+		return nil, xerrors.Errorf("invalid public key factory '%T'", factory)
+	}
+
+	pubkey, err := pubkeyFac.PublicKeyOf(ctx, m.PublicKey)
+	if err != nil {
+		return nil, xerrors.Errorf("public key: %v", err)
+	}
+
+	sig, err := decodeSignature(ctx, m.Signature, types.SignatureKey{})
+	if err != nil {
+		return nil, xerrors.Errorf("signature: %v", err)
+	}
+
+	statements := make([]types.Statement, len(m.Statements))
+	for i, raw := range m.Statements {
+		stSig, err := decodeSignature(ctx, raw.Signature, types.SignatureKey{})
+		if err != nil {
+			return nil, xerrors.Errorf("statement: %v", err)
+		}
+
+		id := types.Digest{}
+		copy(id[:], raw.ID)
+
+		statements[i] = types.NewStatement(id, raw.Leader, stSig)
+	}
+
+	param := types.EvidenceParam{
+		Kind:       types.EvidenceKind(m.Kind),
+		Index:      m.Index,
+		Offender:   fac.FromText(m.Offender),
+		PublicKey:  pubkey,
+		Statements: statements,
+	}
+
+	return types.NewEvidence(param, sig), nil
+}
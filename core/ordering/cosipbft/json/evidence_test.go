@@ -0,0 +1,99 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+)
+
+func TestEvidenceFormat_Encode(t *testing.T) {
+	format := evidenceFormat{}
+
+	ctx := fake.NewContext()
+
+	param := types.EvidenceParam{
+		Kind:       types.ConflictingPrepare,
+		Index:      3,
+		Offender:   fake.NewAddress(0),
+		PublicKey:  fake.PublicKey{},
+		Statements: []types.Statement{types.NewStatement(types.Digest{1}, 0, fake.Signature{})},
+	}
+
+	data, err := format.Encode(ctx, types.NewEvidence(param, fake.Signature{}))
+	require.NoError(t, err)
+	require.Regexp(t, `{"Kind":1,"Index":3,"Offender":"AAAAAA==","PublicKey":{},`+
+		`"Statements":\[{"ID":"[^"]+","Leader":0,"Signature":{}}\],"Signature":{}}`, string(data))
+
+	_, err = format.Encode(ctx, fake.Message{})
+	require.EqualError(t, err, "unsupported message 'fake.Message'")
+
+	param.Offender = fake.NewBadAddress()
+	_, err = format.Encode(ctx, types.NewEvidence(param, fake.Signature{}))
+	require.EqualError(t, err, fake.Err("failed to serialize address"))
+
+	param.Offender = fake.NewAddress(0)
+	param.PublicKey = fake.NewBadPublicKey()
+	_, err = format.Encode(ctx, types.NewEvidence(param, fake.Signature{}))
+	require.EqualError(t, err, fake.Err("failed to serialize public key"))
+
+	param.PublicKey = fake.PublicKey{}
+	_, err = format.Encode(ctx, types.NewEvidence(param, fake.NewBadSignature()))
+	require.EqualError(t, err, fake.Err("failed to serialize signature"))
+
+	param.Statements = []types.Statement{types.NewStatement(types.Digest{}, 0, fake.NewBadSignature())}
+	_, err = format.Encode(ctx, types.NewEvidence(param, fake.Signature{}))
+	require.EqualError(t, err, fake.Err("failed to serialize statement"))
+
+	param.Statements = nil
+	_, err = format.Encode(fake.NewBadContext(), types.NewEvidence(param, fake.Signature{}))
+	require.EqualError(t, err, fake.Err("failed to marshal"))
+}
+
+func TestEvidenceFormat_Decode(t *testing.T) {
+	format := evidenceFormat{}
+
+	ctx := fake.NewContext()
+	ctx = serde.WithFactory(ctx, types.AddressKey{}, fake.AddressFactory{})
+	ctx = serde.WithFactory(ctx, types.PublicKeyKey{}, fake.PublicKeyFactory{})
+	ctx = serde.WithFactory(ctx, types.SignatureKey{}, fake.SignatureFactory{})
+
+	param := types.EvidenceParam{
+		Kind:      types.ConflictingView,
+		Index:     2,
+		Offender:  fake.NewAddress(1),
+		PublicKey: fake.PublicKey{},
+		Statements: []types.Statement{
+			types.NewStatement(types.Digest{1}, 1, fake.Signature{}),
+			types.NewStatement(types.Digest{2}, 1, fake.Signature{}),
+		},
+	}
+
+	data, err := format.Encode(ctx, types.NewEvidence(param, fake.Signature{}))
+	require.NoError(t, err)
+
+	ev, err := format.Decode(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, types.NewEvidence(param, fake.Signature{}), ev)
+
+	_, err = format.Decode(fake.NewBadContext(), data)
+	require.EqualError(t, err, fake.Err("failed to unmarshal"))
+
+	badCtx := serde.WithFactory(ctx, types.AddressKey{}, nil)
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, "invalid address factory '<nil>'")
+
+	badCtx = serde.WithFactory(ctx, types.PublicKeyKey{}, nil)
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, "invalid public key factory '<nil>'")
+
+	badCtx = serde.WithFactory(ctx, types.PublicKeyKey{}, fake.NewBadPublicKeyFactory())
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, fake.Err("public key"))
+
+	badCtx = serde.WithFactory(ctx, types.SignatureKey{}, fake.NewBadSignatureFactory())
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, fake.Err("signature: factory failed"))
+}
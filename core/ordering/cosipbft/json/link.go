@@ -1,6 +1,8 @@
 package json
 
 import (
+	"encoding/json"
+
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/crypto"
@@ -75,9 +77,40 @@ func (fmt linkFormat) encodeLink(ctx serde.Context, link types.Link, m *LinkJSON
 	m.CommitSignature = commit
 	m.ChangeSet = changeset
 
+	vc := link.GetViewChange()
+	if vc != nil {
+		m.ViewChange, err = encodeViewChange(ctx, *vc)
+		if err != nil {
+			return xerrors.Errorf("couldn't serialize view change: %v", err)
+		}
+	}
+
 	return nil
 }
 
+func encodeViewChange(ctx serde.Context, vc types.ViewChange) (*ViewChangeJSON, error) {
+	sigs := vc.GetSignatures()
+	raws := make([]json.RawMessage, len(sigs))
+
+	for i, sig := range sigs {
+		raw, err := sig.Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("signature %d: %v", i, err)
+		}
+
+		raws[i] = raw
+	}
+
+	m := &ViewChangeJSON{
+		ID:         vc.GetID().Bytes(),
+		Leader:     vc.GetLeader(),
+		Indices:    vc.GetIndices(),
+		Signatures: raws,
+	}
+
+	return m, nil
+}
+
 // Decode implements serde.FormatEngine. It populates the link or the block link
 // if appropriate, otherwise it returns an error.
 func (fmt linkFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
@@ -110,6 +143,15 @@ func (fmt linkFormat) Decode(ctx serde.Context, data []byte) (serde.Message, err
 		types.WithChangeSet(changeset),
 	}
 
+	if m.ViewChange != nil {
+		vc, err := decodeViewChange(ctx, m.ViewChange)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to decode view change: %v", err)
+		}
+
+		opts = append(opts, types.WithViewChange(vc))
+	}
+
 	if fmt.hashFac != nil {
 		opts = append(opts, types.WithLinkHashFactory(fmt.hashFac))
 	}
@@ -149,6 +191,24 @@ func (fmt linkFormat) Decode(ctx serde.Context, data []byte) (serde.Message, err
 	return link, nil
 }
 
+func decodeViewChange(ctx serde.Context, m *ViewChangeJSON) (types.ViewChange, error) {
+	sigs := make([]crypto.Signature, len(m.Signatures))
+
+	for i, raw := range m.Signatures {
+		sig, err := decodeSignature(ctx, raw, types.SignatureKey{})
+		if err != nil {
+			return types.ViewChange{}, xerrors.Errorf("signature %d: %v", i, err)
+		}
+
+		sigs[i] = sig
+	}
+
+	id := types.Digest{}
+	copy(id[:], m.ID)
+
+	return types.NewViewChange(id, m.Leader, m.Indices, sigs), nil
+}
+
 func decodeChangeSet(ctx serde.Context, data []byte) (authority.ChangeSet, error) {
 	factory := ctx.GetFactory(types.ChangeSetKey{})
 
@@ -8,6 +8,7 @@ import (
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/validation"
+	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/serde"
 )
@@ -34,9 +35,19 @@ func TestLinkFormat_Encode(t *testing.T) {
 		`"CommitSignature":{},"ChangeSet":{},"Block":{}}`
 	require.Regexp(t, re, string(data))
 
+	vc := types.NewViewChange(types.Digest{1}, 2, []uint16{0}, []crypto.Signature{fake.Signature{}})
+	data, err = format.Encode(ctx, makeLink(t, types.WithViewChange(vc)))
+	require.NoError(t, err)
+	re = `"ViewChange":{"ID":"[^"]+","Leader":2,"Indices":\[0\],"Signatures":\[{}\]}`
+	require.Regexp(t, re, string(data))
+
 	_, err = format.Encode(ctx, fake.Message{})
 	require.EqualError(t, err, "unsupported message 'fake.Message'")
 
+	vc = types.NewViewChange(types.Digest{}, 0, []uint16{0}, []crypto.Signature{badSerializeSignature{}})
+	_, err = format.Encode(ctx, makeLink(t, types.WithViewChange(vc)))
+	require.EqualError(t, err, fake.Err("couldn't serialize view change: signature 0"))
+
 	opt := types.WithSignatures(fake.NewBadSignature(), fake.Signature{})
 	_, err = format.Encode(ctx, makeLink(t, opt))
 	require.EqualError(t, err, fake.Err("couldn't serialize prepare"))
@@ -63,11 +74,20 @@ func TestLinkFormat_Decode(t *testing.T) {
 	ctx = serde.WithFactory(ctx, types.AggregateKey{}, fake.SignatureFactory{})
 	ctx = serde.WithFactory(ctx, types.ChangeSetKey{}, fakeChangeSetFac{})
 	ctx = serde.WithFactory(ctx, types.BlockKey{}, types.BlockFactory{})
+	ctx = serde.WithFactory(ctx, types.SignatureKey{}, fake.SignatureFactory{})
 
 	msg, err := format.Decode(ctx, []byte(`{"From":[1],"To":[2]}`))
 	require.NoError(t, err)
 	require.Equal(t, makeLink(t), msg)
 
+	data := `{"From":[1],"To":[2],"ViewChange":{"ID":[1],"Leader":2,` +
+		`"Indices":[0],"Signatures":[{}]}}`
+	vc := types.NewViewChange(types.Digest{1}, 2, []uint16{0}, []crypto.Signature{fake.Signature{}})
+
+	msg, err = format.Decode(ctx, []byte(data))
+	require.NoError(t, err)
+	require.Equal(t, makeLink(t, types.WithViewChange(vc)), msg)
+
 	msg, err = format.Decode(ctx, []byte(`{"From":[1],"Block":{}}`))
 	require.NoError(t, err)
 	require.Equal(t, makeBlockLink(t), msg)
@@ -91,6 +111,11 @@ func TestLinkFormat_Decode(t *testing.T) {
 	_, err = format.Decode(badCtx, []byte(`{}`))
 	require.EqualError(t, err, fake.Err("failed to decode change set: factory failed"))
 
+	badCtx = serde.WithFactory(ctx, types.SignatureKey{}, fake.NewBadSignatureFactory())
+	_, err = format.Decode(badCtx, []byte(`{"ViewChange":{"Signatures":[{}]}}`))
+	require.EqualError(t, err,
+		fake.Err("failed to decode view change: signature 0: factory failed"))
+
 	badCtx = serde.WithFactory(ctx, types.BlockKey{}, nil)
 	_, err = format.Decode(badCtx, []byte(`{"Block":{}}`))
 	require.EqualError(t, err, "missing block factory")
@@ -116,6 +141,16 @@ func TestLinkFormat_Decode(t *testing.T) {
 // -----------------------------------------------------------------------------
 // Utility functions
 
+// badSerializeSignature is a signature that can be fingerprinted but fails to
+// be serialized.
+type badSerializeSignature struct {
+	fake.Signature
+}
+
+func (badSerializeSignature) Serialize(serde.Context) ([]byte, error) {
+	return nil, fake.GetError()
+}
+
 func makeLink(t *testing.T, opts ...types.LinkOption) types.Link {
 	sigs := types.WithSignatures(fake.Signature{}, fake.Signature{})
 	cs := types.WithChangeSet(fakeChangeSet{})
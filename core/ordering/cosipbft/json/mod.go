@@ -2,6 +2,7 @@ package json
 
 import (
 	"encoding/json"
+	"time"
 
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
@@ -18,19 +19,39 @@ func init() {
 	types.RegisterBlockFormat(serde.FormatJSON, blockFormat{})
 	types.RegisterLinkFormat(serde.FormatJSON, linkFormat{})
 	types.RegisterChainFormat(serde.FormatJSON, chainFormat{})
+	types.RegisterEvidenceFormat(serde.FormatJSON, evidenceFormat{})
+}
+
+// StateEntryJSON is the JSON message for a key/value pair of the initial state.
+type StateEntryJSON struct {
+	Key   []byte
+	Value []byte
 }
 
 // GenesisJSON is the JSON message for a genesis block.
 type GenesisJSON struct {
-	Roster   json.RawMessage
-	TreeRoot []byte
+	Roster          json.RawMessage
+	TreeRoot        []byte
+	LeaderPolicy    string           `json:",omitempty"`
+	MaxTransactions uint64           `json:",omitempty"`
+	MaxSize         uint64           `json:",omitempty"`
+	State           []StateEntryJSON `json:",omitempty"`
 }
 
 // BlockJSON is the JSON message for a block.
 type BlockJSON struct {
-	Index    uint64
-	TreeRoot []byte
-	Data     json.RawMessage
+	Index     uint64
+	TreeRoot  []byte
+	Timestamp int64 `json:",omitempty"`
+	Data      json.RawMessage
+}
+
+// ViewChangeJSON is the JSON message for a view-change certificate.
+type ViewChangeJSON struct {
+	ID         []byte
+	Leader     uint16
+	Indices    []uint16
+	Signatures []json.RawMessage
 }
 
 // LinkJSON is the JSON message for a link.
@@ -40,6 +61,7 @@ type LinkJSON struct {
 	PrepareSignature json.RawMessage
 	CommitSignature  json.RawMessage
 	ChangeSet        json.RawMessage
+	ViewChange       *ViewChangeJSON `json:",omitempty"`
 	Block            json.RawMessage `json:",omitempty"`
 }
 
@@ -55,8 +77,10 @@ type GenesisMessageJSON struct {
 
 // BlockMessageJSON is the JSON message to send a block.
 type BlockMessageJSON struct {
-	Block json.RawMessage
-	Views map[string]ViewMessageJSON
+	Block      json.RawMessage
+	Views      map[string]ViewMessageJSON
+	Signature  json.RawMessage
+	ViewChange *ViewChangeJSON `json:",omitempty"`
 }
 
 // CommitMessageJSON is the JSON message to send a commit request.
@@ -78,13 +102,26 @@ type ViewMessageJSON struct {
 	Signature json.RawMessage
 }
 
+// SubscribeMessageJSON is the JSON message to subscribe to the blocks of a
+// chain.
+type SubscribeMessageJSON struct {
+	From uint64
+}
+
+// LinkMessageJSON is the JSON message to send a block link to an observer.
+type LinkMessageJSON struct {
+	Link json.RawMessage
+}
+
 // MessageJSON is the JSON message that wraps the different kinds of messages.
 type MessageJSON struct {
-	Genesis *GenesisMessageJSON `json:",omitempty"`
-	Block   *BlockMessageJSON   `json:",omitempty"`
-	Commit  *CommitMessageJSON  `json:",omitempty"`
-	Done    *DoneMessageJSON    `json:",omitempty"`
-	View    *ViewMessageJSON    `json:",omitempty"`
+	Genesis   *GenesisMessageJSON   `json:",omitempty"`
+	Block     *BlockMessageJSON     `json:",omitempty"`
+	Commit    *CommitMessageJSON    `json:",omitempty"`
+	Done      *DoneMessageJSON      `json:",omitempty"`
+	View      *ViewMessageJSON      `json:",omitempty"`
+	Subscribe *SubscribeMessageJSON `json:",omitempty"`
+	Link      *LinkMessageJSON      `json:",omitempty"`
 }
 
 // GenesisFormat is a format engine to serialize and deserialize the genesis
@@ -109,8 +146,18 @@ func (f genesisFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, err
 	}
 
 	m := GenesisJSON{
-		Roster:   roster,
-		TreeRoot: genesis.GetRoot().Bytes(),
+		Roster:          roster,
+		TreeRoot:        genesis.GetRoot().Bytes(),
+		LeaderPolicy:    genesis.GetLeaderPolicy(),
+		MaxTransactions: genesis.GetLimits().MaxTransactions,
+		MaxSize:         genesis.GetLimits().MaxSize,
+	}
+
+	for _, entry := range genesis.GetInitialState() {
+		m.State = append(m.State, StateEntryJSON{
+			Key:   entry.GetKey(),
+			Value: entry.GetValue(),
+		})
 	}
 
 	data, err := ctx.Marshal(m)
@@ -145,7 +192,20 @@ func (f genesisFormat) Decode(ctx serde.Context, data []byte) (serde.Message, er
 	root := types.Digest{}
 	copy(root[:], m.TreeRoot)
 
-	opts := []types.GenesisOption{types.WithGenesisRoot(root)}
+	var state []types.StateEntry
+	for _, entry := range m.State {
+		state = append(state, types.NewStateEntry(entry.Key, entry.Value))
+	}
+
+	opts := []types.GenesisOption{
+		types.WithGenesisRoot(root),
+		types.WithLeaderPolicy(m.LeaderPolicy),
+		types.WithLimits(types.Limits{
+			MaxTransactions: m.MaxTransactions,
+			MaxSize:         m.MaxSize,
+		}),
+		types.WithInitialState(state...),
+	}
 
 	if f.hashFac != nil {
 		opts = append(opts, types.WithGenesisHashFactory(f.hashFac))
@@ -185,6 +245,10 @@ func (f blockFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error
 		Data:     blockdata,
 	}
 
+	if !block.GetTimestamp().IsZero() {
+		m.Timestamp = block.GetTimestamp().UnixNano()
+	}
+
 	data, err := ctx.Marshal(m)
 	if err != nil {
 		return nil, xerrors.Errorf("failed to marshal: %v", err)
@@ -222,6 +286,10 @@ func (f blockFormat) Decode(ctx serde.Context, data []byte) (serde.Message, erro
 		types.WithIndex(m.Index),
 	}
 
+	if m.Timestamp != 0 {
+		opts = append(opts, types.WithTimestamp(time.Unix(0, m.Timestamp)))
+	}
+
 	if f.hashFac != nil {
 		opts = append(opts, types.WithHashFactory(f.hashFac))
 	}
@@ -277,9 +345,23 @@ func (f msgFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error)
 			views[string(key)] = *rawView
 		}
 
+		sig, err := in.GetSignature().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize signature: %v", err)
+		}
+
 		bm := BlockMessageJSON{
-			Block: block,
-			Views: views,
+			Block:     block,
+			Views:     views,
+			Signature: sig,
+		}
+
+		vc := in.GetViewChange()
+		if vc != nil {
+			bm.ViewChange, err = encodeViewChange(ctx, *vc)
+			if err != nil {
+				return nil, xerrors.Errorf("view change: %v", err)
+			}
 		}
 
 		m = MessageJSON{Block: &bm}
@@ -314,6 +396,23 @@ func (f msgFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error)
 		}
 
 		m = MessageJSON{View: vm}
+	case types.SubscribeMessage:
+		sm := SubscribeMessageJSON{
+			From: in.GetFrom(),
+		}
+
+		m = MessageJSON{Subscribe: &sm}
+	case types.LinkMessage:
+		link, err := in.GetLink().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize link: %v", err)
+		}
+
+		lm := LinkMessageJSON{
+			Link: link,
+		}
+
+		m = MessageJSON{Link: &lm}
 	}
 
 	data, err := ctx.Marshal(m)
@@ -404,7 +503,23 @@ func (f msgFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error)
 			views[addr] = view
 		}
 
-		return types.NewBlockMessage(block, views), nil
+		sig, err := decodeSignature(ctx, m.Block.Signature, types.SignatureKey{})
+		if err != nil {
+			return nil, xerrors.Errorf("signature: %v", err)
+		}
+
+		var vc *types.ViewChange
+
+		if m.Block.ViewChange != nil {
+			cert, err := decodeViewChange(ctx, m.Block.ViewChange)
+			if err != nil {
+				return nil, xerrors.Errorf("view change: %v", err)
+			}
+
+			vc = &cert
+		}
+
+		return types.NewBlockMessage(block, views, sig, vc), nil
 	}
 
 	if m.Commit != nil {
@@ -435,6 +550,26 @@ func (f msgFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error)
 		return decodeView(ctx, m.View)
 	}
 
+	if m.Subscribe != nil {
+		return types.NewSubscribeMessage(m.Subscribe.From), nil
+	}
+
+	if m.Link != nil {
+		factory := ctx.GetFactory(types.LinkKey{})
+
+		fac, ok := factory.(types.LinkFactory)
+		if !ok {
+			return nil, xerrors.Errorf("invalid link factory '%T'", factory)
+		}
+
+		link, err := fac.BlockLinkOf(ctx, m.Link.Link)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to deserialize link: %v", err)
+		}
+
+		return types.NewLinkMessage(link), nil
+	}
+
 	return nil, xerrors.New("message is empty")
 }
 
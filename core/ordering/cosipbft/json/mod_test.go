@@ -3,10 +3,12 @@ package json
 import (
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/mino"
 	"go.dedis.ch/dela/serde"
@@ -29,6 +31,20 @@ func TestGenesisFormat_Encode(t *testing.T) {
 	require.NoError(t, err)
 	require.Regexp(t, `{"Roster":{},"TreeRoot":"[^"]+"}`, string(data))
 
+	genesis, err = types.NewGenesis(fakeRoster{}, types.WithLeaderPolicy("sticky"))
+	require.NoError(t, err)
+
+	data, err = format.Encode(ctx, genesis)
+	require.NoError(t, err)
+	require.Regexp(t, `{"Roster":{},"TreeRoot":"[^"]+","LeaderPolicy":"sticky"}`, string(data))
+
+	genesis, err = types.NewGenesis(fakeRoster{}, types.WithLimits(types.Limits{MaxTransactions: 2, MaxSize: 3}))
+	require.NoError(t, err)
+
+	data, err = format.Encode(ctx, genesis)
+	require.NoError(t, err)
+	require.Regexp(t, `{"Roster":{},"TreeRoot":"[^"]+","MaxTransactions":2,"MaxSize":3}`, string(data))
+
 	_, err = format.Encode(ctx, fake.Message{})
 	require.EqualError(t, err, "invalid genesis 'fake.Message'")
 
@@ -55,6 +71,14 @@ func TestGenesisFormat_Decode(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, msg, genesis)
 
+	msg, err = format.Decode(ctx, []byte(`{"LeaderPolicy":"round-robin"}`))
+	require.NoError(t, err)
+	require.Equal(t, "round-robin", msg.(types.Genesis).GetLeaderPolicy())
+
+	msg, err = format.Decode(ctx, []byte(`{"MaxTransactions":2,"MaxSize":3}`))
+	require.NoError(t, err)
+	require.Equal(t, types.Limits{MaxTransactions: 2, MaxSize: 3}, msg.(types.Genesis).GetLimits())
+
 	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
 	require.EqualError(t, err, fake.Err("failed to unmarshal"))
 
@@ -84,6 +108,13 @@ func TestBlockFormat_Encode(t *testing.T) {
 	require.NoError(t, err)
 	require.Regexp(t, `{"Index":0,"TreeRoot":"[^"]+","Data":{}}`, string(data))
 
+	block, err = types.NewBlock(fakeResult{}, types.WithTimestamp(time.Unix(0, 42)))
+	require.NoError(t, err)
+
+	data, err = format.Encode(ctx, block)
+	require.NoError(t, err)
+	require.Regexp(t, `{"Index":0,"TreeRoot":"[^"]+","Timestamp":42,"Data":{}}`, string(data))
+
 	_, err = format.Encode(ctx, fake.Message{})
 	require.EqualError(t, err, "invalid block 'fake.Message'")
 
@@ -110,6 +141,13 @@ func TestBlockFormat_Decode(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, block, msg)
 
+	block, err = types.NewBlock(fakeResult{}, types.WithTimestamp(time.Unix(0, 42)))
+	require.NoError(t, err)
+
+	msg, err = format.Decode(ctx, []byte(`{"Timestamp":42}`))
+	require.NoError(t, err)
+	require.Equal(t, block, msg)
+
 	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
 	require.EqualError(t, err, fake.Err("failed to unmarshal"))
 
@@ -148,21 +186,34 @@ func TestMsgFormat_Encode(t *testing.T) {
 	views := map[mino.Address]types.ViewMessage{
 		fake.NewAddress(0): types.NewViewMessage(types.Digest{1}, 5, fake.Signature{}),
 	}
-	data, err = format.Encode(ctx, types.NewBlockMessage(block, views))
+	data, err = format.Encode(ctx, types.NewBlockMessage(block, views, fake.Signature{}, nil))
 	require.NoError(t, err)
 	require.Regexp(t,
-		`{"Block":{"Block":{},"Views":{"[^"]+":{"Leader":5,"ID":"[^"]+","Signature":{}}}}}`, string(data))
+		`{"Block":{"Block":{},"Views":{"[^"]+":{"Leader":5,"ID":"[^"]+","Signature":{}}},"Signature":{}}}`, string(data))
+
+	_, err = format.Encode(ctx, types.NewBlockMessage(block, nil, fake.NewBadSignature(), nil))
+	require.EqualError(t, err, fake.Err("failed to serialize signature"))
+
+	vc := types.NewViewChange(types.Digest{}, 2, []uint16{0}, []crypto.Signature{fake.Signature{}})
+	data, err = format.Encode(ctx, types.NewBlockMessage(block, nil, fake.Signature{}, &vc))
+	require.NoError(t, err)
+	require.Regexp(t,
+		`"ViewChange":{"ID":"[^"]+","Leader":2,"Indices":\[0\],"Signatures":\[{}\]}`, string(data))
+
+	vc = types.NewViewChange(types.Digest{}, 2, []uint16{0}, []crypto.Signature{fake.NewBadSignature()})
+	_, err = format.Encode(ctx, types.NewBlockMessage(block, nil, fake.Signature{}, &vc))
+	require.EqualError(t, err, fake.Err("view change: signature 0"))
 
 	views[fake.NewAddress(0)] = types.NewViewMessage(types.Digest{}, 0, fake.NewBadSignature())
-	_, err = format.Encode(ctx, types.NewBlockMessage(block, views))
+	_, err = format.Encode(ctx, types.NewBlockMessage(block, views, fake.Signature{}, nil))
 	require.EqualError(t, err, fake.Err("view: failed to serialize signature"))
 
 	delete(views, fake.NewAddress(0))
 	views[fake.NewBadAddress()] = types.NewViewMessage(types.Digest{}, 0, fake.Signature{})
-	_, err = format.Encode(ctx, types.NewBlockMessage(block, views))
+	_, err = format.Encode(ctx, types.NewBlockMessage(block, views, fake.Signature{}, nil))
 	require.EqualError(t, err, fake.Err("failed to serialize address"))
 
-	_, err = format.Encode(fake.NewBadContext(), types.NewBlockMessage(block, nil))
+	_, err = format.Encode(fake.NewBadContext(), types.NewBlockMessage(block, nil, fake.Signature{}, nil))
 	require.EqualError(t, err, fake.Err("block: encoding failed"))
 
 	data, err = format.Encode(ctx, types.NewCommit(types.Digest{}, fake.Signature{}))
@@ -186,6 +237,17 @@ func TestMsgFormat_Encode(t *testing.T) {
 	_, err = format.Encode(ctx, types.NewViewMessage(types.Digest{}, 0, fake.NewBadSignature()))
 	require.EqualError(t, err, fake.Err("view: failed to serialize signature"))
 
+	data, err = format.Encode(ctx, types.NewSubscribeMessage(2))
+	require.NoError(t, err)
+	require.Equal(t, `{"Subscribe":{"From":2}}`, string(data))
+
+	data, err = format.Encode(ctx, types.NewLinkMessage(fakeLink{}))
+	require.NoError(t, err)
+	require.Equal(t, `{"Link":{"Link":{}}}`, string(data))
+
+	_, err = format.Encode(ctx, types.NewLinkMessage(fakeLink{err: fake.GetError()}))
+	require.EqualError(t, err, fake.Err("failed to serialize link"))
+
 	_, err = format.Encode(fake.NewBadContext(), types.NewViewMessage(types.Digest{}, 0, fake.Signature{}))
 	require.EqualError(t, err, fake.Err("failed to marshal"))
 }
@@ -220,6 +282,15 @@ func TestMsgFormat_Decode(t *testing.T) {
 	require.NoError(t, err)
 	require.IsType(t, types.BlockMessage{}, msg)
 	require.Len(t, msg.(types.BlockMessage).GetViews(), 1)
+	require.Nil(t, msg.(types.BlockMessage).GetViewChange())
+
+	msg, err = format.Decode(ctx, []byte(`{"Block":{"ViewChange":{"Leader":2,"Signatures":[{}]}}}`))
+	require.NoError(t, err)
+	require.Equal(t, uint16(2), msg.(types.BlockMessage).GetViewChange().GetLeader())
+
+	badCtx = serde.WithFactory(ctx, types.SignatureKey{}, fake.NewBadSignatureFactoryWithDelay(1))
+	_, err = format.Decode(badCtx, []byte(`{"Block":{"ViewChange":{"Signatures":[{}]}}}`))
+	require.EqualError(t, err, fake.Err("view change: signature 0: factory failed"))
 
 	badCtx = serde.WithFactory(ctx, types.BlockKey{}, nil)
 	_, err = format.Decode(badCtx, []byte(`{"Block":{}}`))
@@ -241,6 +312,10 @@ func TestMsgFormat_Decode(t *testing.T) {
 	_, err = format.Decode(badCtx, []byte(`{"Block":{"Views":{"":{}}}}`))
 	require.EqualError(t, err, "view: signature: invalid signature factory '<nil>'")
 
+	badCtx = serde.WithFactory(ctx, types.SignatureKey{}, fake.NewBadSignatureFactory())
+	_, err = format.Decode(badCtx, []byte(`{"Block":{}}`))
+	require.EqualError(t, err, fake.Err("signature: factory failed"))
+
 	msg, err = format.Decode(ctx, []byte(`{"Commit":{}}`))
 	require.NoError(t, err)
 	require.IsType(t, types.CommitMessage{}, msg)
@@ -264,6 +339,22 @@ func TestMsgFormat_Decode(t *testing.T) {
 	_, err = format.Decode(badCtx, []byte(`{"View":{}}`))
 	require.EqualError(t, err, "signature: invalid signature factory '<nil>'")
 
+	msg, err = format.Decode(ctx, []byte(`{"Subscribe":{"From":2}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewSubscribeMessage(2), msg)
+
+	linkCtx := serde.WithFactory(ctx, types.LinkKey{}, fakeLinkFac{})
+	msg, err = format.Decode(linkCtx, []byte(`{"Link":{}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewLinkMessage(fakeLink{}), msg)
+
+	_, err = format.Decode(ctx, []byte(`{"Link":{}}`))
+	require.EqualError(t, err, "invalid link factory '<nil>'")
+
+	badCtx = serde.WithFactory(ctx, types.LinkKey{}, fakeLinkFac{errBlockLink: fake.GetError()})
+	_, err = format.Decode(badCtx, []byte(`{"Link":{}}`))
+	require.EqualError(t, err, fake.Err("failed to deserialize link"))
+
 	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
 	require.EqualError(t, err, fake.Err("failed to unmarshal"))
 
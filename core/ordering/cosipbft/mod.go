@@ -18,7 +18,17 @@
 // Before each PBFT round, a synchronization is run from the leader to allow
 // nodes that have fallen behind (or are new) to catch missing blocks. Only a
 // PBFT threshold of nodes needs to confirm a hard synchronization (having all
-// the blocks) for the round to proceed, but others will keep catching up.
+// the blocks) for the round to proceed, but others will keep catching up. The
+// missing blocks can optionally be downloaded from several members in
+// parallel, and a new node can restore a snapshot of the state instead of
+// replaying the blocks. A node can also catch up from an archive of the chain
+// exported by another node, in which case the blocks are verified the same
+// way.
+//
+// A node can also follow the chain as an observer without being a member of
+// the roster. It subscribes to a member that streams the blocks as they are
+// committed, and it verifies the collective signatures before applying them.
+// It never takes part in the consensus nor in the synchronization.
 //
 // Related Papers:
 //
@@ -34,9 +44,11 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"sync"
 	"time"
 
 	"go.dedis.ch/dela"
+	"go.dedis.ch/dela/core"
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/core/execution/native"
 	"go.dedis.ch/dela/core/ordering"
@@ -56,28 +68,35 @@ import (
 	"go.dedis.ch/dela/cosi/threshold"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
 	"golang.org/x/xerrors"
 )
 
 const (
-	// RoundTimeout is the maximum of time the service waits for an event to
-	// happen.
+	// RoundTimeout is the default maximum of time the service waits for an
+	// event to happen.
 	RoundTimeout = 10 * time.Second
 
-	// RoundWait is the constant value of the exponential backoff use between
-	// round failures.
+	// RoundWait is the default constant value of the exponential backoff use
+	// between round failures.
 	RoundWait = 5 * time.Millisecond
 
-	// RoundMaxWait is the maximum amount for the backoff.
+	// RoundMaxWait is the default maximum amount for the backoff.
 	RoundMaxWait = 5 * time.Minute
 
+	// TimestampDrift is the default maximum difference between the timestamp
+	// of a block candidate and the clock of a follower.
+	TimestampDrift = 30 * time.Second
+
 	rpcName = "cosipbft"
 )
 
 // RegisterRosterContract registers the native smart contract to update the
 // roster to the given service.
-func RegisterRosterContract(exec *native.Service, rFac authority.Factory, srvc access.Service) {
-	contract := viewchange.NewContract(keyRoster[:], keyAccess[:], rFac, srvc)
+func RegisterRosterContract(exec *native.Service, rFac authority.Factory,
+	sigFac crypto.SignatureFactory, srvc access.Service) {
+
+	contract := viewchange.NewContract(keyRoster[:], keyLimits[:], keyAccess[:], rFac, sigFac, srvc)
 
 	viewchange.RegisterContract(exec, contract)
 }
@@ -92,23 +111,57 @@ type Service struct {
 	me          mino.Address
 	rpc         mino.RPC
 	actor       cosi.Actor
+	signer      crypto.Signer
 	val         validation.Service
 	verifierFac crypto.VerifierFactory
+	genesisFac  serde.Factory
+	linkFac     types.LinkFactory
 
 	timeoutRound             time.Duration
 	timeoutRoundAfterFailure time.Duration
 	timeoutViewchange        time.Duration
+	adaptive                 *adaptiveTimeout
+	roundWait                time.Duration
+	roundMaxWait             time.Duration
+
+	events       chan ordering.Event
+	closing      chan struct{}
+	closed       chan struct{}
+	failedRounds uint
 
-	events      chan ordering.Event
-	closing     chan struct{}
-	closed      chan struct{}
-	failedRound bool
+	rotationLock sync.Mutex
+	rotation     *pendingRotation
+
+	observed     []mino.Address
+	observedHash types.Digest
+}
+
+// pendingRotation is a public key rotation of the node that waits for the
+// roster change to be committed.
+type pendingRotation struct {
+	pubkey crypto.PublicKey
+	fn     func()
 }
 
 type serviceTemplate struct {
 	hashFac crypto.HashFactory
 	blocks  blockstore.BlockStore
 	genesis blockstore.GenesisStore
+
+	timeoutRound             time.Duration
+	timeoutRoundAfterFailure time.Duration
+	timeoutViewchange        time.Duration
+	adaptive                 *adaptiveTimeout
+	roundWait                time.Duration
+	roundMaxWait             time.Duration
+	timestampDrift           time.Duration
+	snapshotThreshold        uint64
+	snapshotProofFac         hashtree.SuffixProofFactory
+	syncRange                uint64
+	observing                bool
+	observed                 []mino.Address
+	observedHash             types.Digest
+	clock                    core.Clock
 }
 
 // ServiceOption is the type of option to set some fields of the service.
@@ -135,6 +188,103 @@ func WithHashFactory(fac crypto.HashFactory) ServiceOption {
 	}
 }
 
+// WithRoundTimeout is an option to set the maximum amount of time a follower
+// waits for a block before it triggers a view change.
+func WithRoundTimeout(timeout time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.timeoutRound = timeout
+	}
+}
+
+// WithFailedRoundTimeout is an option to set the round timeout used after a
+// view change happened, until a block is committed.
+func WithFailedRoundTimeout(timeout time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.timeoutRoundAfterFailure = timeout
+	}
+}
+
+// WithViewChangeTimeout is an option to set the maximum amount of time the
+// service waits for a view change to happen.
+func WithViewChangeTimeout(timeout time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.timeoutViewchange = timeout
+	}
+}
+
+// WithRoundBackoff is an option to set the base value and the maximum of the
+// exponential backoff between round failures.
+func WithRoundBackoff(wait, maxWait time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.roundWait = wait
+		tmpl.roundMaxWait = maxWait
+	}
+}
+
+// WithTimestampDrift is an option to set the maximum difference between the
+// timestamp of a block candidate and the clock of a follower. A candidate
+// beyond the bound is refused. Zero disables the check.
+func WithTimestampDrift(drift time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.timestampDrift = drift
+	}
+}
+
+// WithAdaptiveTimeout is an option to derive the round timeout from the
+// latency of the prepare and commit phases observed by the node. The timeout
+// is kept between the bounds and it is doubled for every consecutive failed
+// round. The round timeout is used until a latency is observed, and the
+// failed round timeout is ignored.
+func WithAdaptiveTimeout(min, max time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.adaptive = &adaptiveTimeout{min: min, max: max}
+	}
+}
+
+// WithSnapshotSync is an option to let a node without any block restore a
+// snapshot of the state from the leader, instead of replaying the blocks, when
+// it misses at least the given number of blocks. Zero disables the snapshots.
+// The snapshot is sent in chunks that are proven against the tree root, and the
+// factory deserializes those proofs.
+func WithSnapshotSync(threshold uint64, fac hashtree.SuffixProofFactory) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.snapshotThreshold = threshold
+		tmpl.snapshotProofFac = fac
+	}
+}
+
+// WithParallelSync is an option to let a node that has fallen behind download
+// the missing blocks from several members of the roster in parallel, by ranges
+// of the given size. Zero disables it and the leader sends the blocks.
+func WithParallelSync(rangeSize uint64) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.syncRange = rangeSize
+	}
+}
+
+// WithObserver is an option to make the node an observer of the chain. The
+// node verifies and applies the blocks it receives from a member of the roster
+// but it never takes part in the consensus, nor in the synchronization of the
+// members. The digest is the one of the genesis block of the chain to follow,
+// which is the only one the node accepts. The addresses are the members it
+// subscribes to until the genesis is known, after which the current roster is
+// used.
+func WithObserver(genesis types.Digest, members ...mino.Address) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.observing = true
+		tmpl.observed = members
+		tmpl.observedHash = genesis
+	}
+}
+
+// WithClock is an option to set the clock used for the timeouts and the
+// timestamps of the blocks. It allows a simulation to control the time.
+func WithClock(clock core.Clock) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.clock = clock
+	}
+}
+
 // ServiceParam is the different components to provide to the service. All the
 // fields are mandatory and it will panic if any is nil.
 type ServiceParam struct {
@@ -153,6 +303,14 @@ func NewService(param ServiceParam, opts ...ServiceOption) (*Service, error) {
 		hashFac: crypto.NewSha256Factory(),
 		genesis: blockstore.NewGenesisStore(),
 		blocks:  blockstore.NewInMemory(),
+
+		timeoutRound:             RoundTimeout,
+		timeoutRoundAfterFailure: RoundTimeout,
+		timeoutViewchange:        RoundTimeout,
+		roundWait:                RoundWait,
+		roundMaxWait:             RoundMaxWait,
+		timestampDrift:           TimestampDrift,
+		clock:                    core.SystemClock{},
 	}
 
 	for _, opt := range opts {
@@ -167,43 +325,73 @@ func NewService(param ServiceParam, opts ...ServiceOption) (*Service, error) {
 	proc.rosterFac = authority.NewFactory(param.Mino.GetAddressFactory(), param.Cosi.GetPublicKeyFactory())
 	proc.tree = blockstore.NewTreeCache(param.Tree)
 	proc.access = param.Access
+	proc.observing = tmpl.observing
+	proc.clock = tmpl.clock
+	proc.latency.clock = tmpl.clock
 	proc.logger = dela.Logger.With().Str("addr", param.Mino.GetAddress().String()).Logger()
 
+	blockFac := types.NewBlockFactory(param.Validation.GetFactory())
+
 	pcparam := pbft.StateMachineParam{
-		Logger:          proc.logger,
-		Validation:      param.Validation,
-		Signer:          param.Cosi.GetSigner(),
-		VerifierFactory: param.Cosi.GetVerifierFactory(),
-		Blocks:          tmpl.blocks,
-		Genesis:         tmpl.genesis,
-		Tree:            proc.tree,
-		AuthorityReader: proc.readRoster,
-		DB:              param.DB,
+		Logger:           proc.logger,
+		Validation:       param.Validation,
+		Signer:           cosiSigner{cosi: param.Cosi},
+		VerifierFactory:  param.Cosi.GetVerifierFactory(),
+		Blocks:           tmpl.blocks,
+		Genesis:          tmpl.genesis,
+		Tree:             proc.tree,
+		AuthorityReader:  proc.readRoster,
+		PolicyReader:     proc.readPolicy,
+		LimitsReader:     proc.readLimits,
+		TimestampDrift:   tmpl.timestampDrift,
+		Clock:            tmpl.clock,
+		DB:               param.DB,
+		BlockFactory:     blockFac,
+		SignatureFactory: param.Cosi.GetSignatureFactory(),
+		AddressFactory:   param.Mino.GetAddressFactory(),
 	}
 
 	proc.pbftsm = pbft.NewStateMachine(pcparam)
 
-	blockFac := types.NewBlockFactory(param.Validation.GetFactory())
+	if tmpl.genesis.Exists() {
+		// The round is restored from the journal so that a node that restarts
+		// does not contradict what it said before stopping.
+		err := proc.pbftsm.Load()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to load pbft: %v", err)
+		}
+	}
+
 	csFac := authority.NewChangeSetFactory(param.Mino.GetAddressFactory(), param.Cosi.GetPublicKeyFactory())
 	linkFac := types.NewLinkFactory(blockFac, param.Cosi.GetSignatureFactory(), csFac)
 	chainFac := types.NewChainFactory(linkFac)
 
-	syncparam := blocksync.SyncParam{
-		Mino:            param.Mino,
-		Blocks:          tmpl.blocks,
-		Genesis:         tmpl.genesis,
-		PBFT:            proc.pbftsm,
-		LinkFactory:     linkFac,
-		ChainFactory:    chainFac,
-		VerifierFactory: param.Cosi.GetVerifierFactory(),
-	}
+	if !tmpl.observing {
+		syncparam := blocksync.SyncParam{
+			Mino:            param.Mino,
+			Blocks:          tmpl.blocks,
+			Genesis:         tmpl.genesis,
+			PBFT:            proc.pbftsm,
+			LinkFactory:     linkFac,
+			ChainFactory:    chainFac,
+			VerifierFactory: param.Cosi.GetVerifierFactory(),
+
+			Tree:              proc.tree,
+			ProofFactory:      tmpl.snapshotProofFac,
+			SnapshotThreshold: tmpl.snapshotThreshold,
+			RangeSize:         tmpl.syncRange,
+			Roster: func() (mino.Players, error) {
+				return proc.readRoster(proc.tree.Get())
+			},
+		}
 
-	blocksync := blocksync.NewSynchronizer(syncparam)
+		proc.sync = blocksync.NewSynchronizer(syncparam)
+	}
 
-	proc.sync = blocksync
+	genesisFac := types.NewGenesisFactory(proc.rosterFac)
 
 	fac := types.NewMessageFactory(
-		types.NewGenesisFactory(proc.rosterFac),
+		genesisFac,
 		blockFac,
 		param.Mino.GetAddressFactory(),
 		param.Cosi.GetSignatureFactory(),
@@ -212,9 +400,16 @@ func NewService(param ServiceParam, opts ...ServiceOption) (*Service, error) {
 
 	proc.MessageFactory = fac
 
-	actor, err := param.Cosi.Listen(proc)
-	if err != nil {
-		return nil, xerrors.Errorf("creating cosi failed: %v", err)
+	var actor cosi.Actor
+	var err error
+
+	if !tmpl.observing {
+		// An observer never signs the blocks so it does not listen to the
+		// collective signing requests.
+		actor, err = param.Cosi.Listen(proc)
+		if err != nil {
+			return nil, xerrors.Errorf("creating cosi failed: %v", err)
+		}
 	}
 
 	s := &Service{
@@ -222,21 +417,38 @@ func NewService(param ServiceParam, opts ...ServiceOption) (*Service, error) {
 		me:                       param.Mino.GetAddress(),
 		rpc:                      mino.MustCreateRPC(param.Mino, rpcName, proc, fac),
 		actor:                    actor,
+		signer:                   cosiSigner{cosi: param.Cosi},
 		val:                      param.Validation,
 		verifierFac:              param.Cosi.GetVerifierFactory(),
-		timeoutRound:             RoundTimeout,
-		timeoutRoundAfterFailure: RoundTimeout,
-		timeoutViewchange:        RoundTimeout,
+		genesisFac:               genesisFac,
+		linkFac:                  linkFac,
+		timeoutRound:             tmpl.timeoutRound,
+		timeoutRoundAfterFailure: tmpl.timeoutRoundAfterFailure,
+		timeoutViewchange:        tmpl.timeoutViewchange,
+		adaptive:                 tmpl.adaptive,
+		roundWait:                tmpl.roundWait,
+		roundMaxWait:             tmpl.roundMaxWait,
 		events:                   make(chan ordering.Event, 1),
 		closing:                  make(chan struct{}),
 		closed:                   make(chan struct{}),
+		observed:                 tmpl.observed,
+		observedHash:             tmpl.observedHash,
 	}
 
 	// Pool will filter the transaction that are already accepted by this
 	// service.
-	param.Pool.AddFilter(poolFilter{tree: proc.tree, srvc: param.Validation})
+	param.Pool.AddFilter(poolFilter{
+		tree:    proc.tree,
+		srvc:    param.Validation,
+		limits:  proc.readLimits,
+		context: proc.context,
+	})
 
-	go s.main()
+	if tmpl.observing {
+		go s.observe()
+	} else {
+		go s.main()
+	}
 
 	go s.watchBlocks()
 
@@ -249,9 +461,80 @@ func NewService(param ServiceParam, opts ...ServiceOption) (*Service, error) {
 	return s, nil
 }
 
+// SetupOption is the type of option to set some parameters of a new chain.
+type SetupOption func(*setupTemplate)
+
+type setupTemplate struct {
+	policy pbft.LeaderPolicy
+	limits types.Limits
+	state  []types.StateEntry
+}
+
+// WithLeaderPolicy is an option to set the policy that rotates the leaders of
+// the new chain. The leader is kept until a view change by default.
+func WithLeaderPolicy(policy pbft.LeaderPolicy) SetupOption {
+	return func(tmpl *setupTemplate) {
+		tmpl.policy = policy
+	}
+}
+
+// WithLimits is an option to bound the number of transactions and their size
+// in the blocks of the new chain. The limits can later be changed through a
+// transaction of the view change contract.
+func WithLimits(limits types.Limits) SetupOption {
+	return func(tmpl *setupTemplate) {
+		tmpl.limits = limits
+	}
+}
+
+// WithInitialState is an option to set key/value pairs that the tree of the
+// new chain contains from the genesis block.
+func WithInitialState(entries ...types.StateEntry) SetupOption {
+	return func(tmpl *setupTemplate) {
+		tmpl.state = append(tmpl.state, entries...)
+	}
+}
+
+// MakeGenesis returns the genesis block that a setup with the same arguments
+// would create, without creating the chain. As the tree root only depends on
+// the parameters, every member can compute the digest of the genesis block on
+// its own and compare it before the chain starts.
+func (s *Service) MakeGenesis(ca crypto.CollectiveAuthority, opts ...SetupOption) (types.Genesis, error) {
+	if s.genesis.Exists() {
+		return types.Genesis{}, xerrors.New("chain already exists")
+	}
+
+	genesis, _, err := s.makeGenesis(newSetupParam(ca, opts))
+	if err != nil {
+		return types.Genesis{}, xerrors.Errorf("creating genesis: %v", err)
+	}
+
+	return genesis, nil
+}
+
+func newSetupParam(ca crypto.CollectiveAuthority, opts []SetupOption) genesisParam {
+	tmpl := setupTemplate{}
+
+	for _, opt := range opts {
+		opt(&tmpl)
+	}
+
+	param := genesisParam{
+		roster: authority.FromAuthority(ca),
+		limits: tmpl.limits,
+		state:  tmpl.state,
+	}
+
+	if tmpl.policy != nil {
+		param.policy = tmpl.policy.String()
+	}
+
+	return param
+}
+
 // Setup creates a genesis block and sends it to the collective authority.
-func (s *Service) Setup(ctx context.Context, ca crypto.CollectiveAuthority) error {
-	err := s.storeGenesis(authority.FromAuthority(ca), nil)
+func (s *Service) Setup(ctx context.Context, ca crypto.CollectiveAuthority, opts ...SetupOption) error {
+	err := s.storeGenesis(newSetupParam(ca, opts), nil)
 	if err != nil {
 		return xerrors.Errorf("creating genesis: %v", err)
 	}
@@ -305,12 +588,101 @@ func (s *Service) GetProof(key []byte) (ordering.Proof, error) {
 	return newProof(path, chain), nil
 }
 
+// GetProofAt returns the proof of absence or inclusion of the key for the state
+// of the tree after the block at the given index. The proof contains the chain
+// up to that block. It requires the tree to retain the version.
+func (s *Service) GetProofAt(index uint64, key []byte) (ordering.Proof, error) {
+	tree, err := s.getTreeAt(index)
+	if err != nil {
+		return nil, xerrors.Errorf("reading tree: %v", err)
+	}
+
+	path, err := tree.GetPath(key)
+	if err != nil {
+		return nil, xerrors.Errorf("reading path: %v", err)
+	}
+
+	chain, err := s.blocks.GetChainAt(index)
+	if err != nil {
+		return nil, xerrors.Errorf("reading chain: %v", err)
+	}
+
+	return newProof(path, chain), nil
+}
+
+// PrepareRotation registers a rotation of the public key of the node. The
+// function is called once the roster of the chain contains the new public key
+// for this node, which means the node must start signing with the new key from
+// that block on.
+func (s *Service) PrepareRotation(pubkey crypto.PublicKey, fn func()) {
+	s.rotationLock.Lock()
+	s.rotation = &pendingRotation{
+		pubkey: pubkey,
+		fn:     fn,
+	}
+	s.rotationLock.Unlock()
+
+	// The rotation might have already been committed, for instance when the
+	// node restarts.
+	roster, err := s.getCurrentRoster()
+	if err == nil {
+		s.checkRotation(roster)
+	}
+}
+
+// CancelRotation drops the pending rotation of the public key of the node if
+// it is the given one, for instance when the roster change is refused.
+func (s *Service) CancelRotation(pubkey crypto.PublicKey) {
+	s.rotationLock.Lock()
+	defer s.rotationLock.Unlock()
+
+	if s.rotation != nil && s.rotation.pubkey.Equal(pubkey) {
+		s.rotation = nil
+	}
+}
+
 // GetStore implements ordering.Service. It returns the current tree as a
 // read-only storage.
 func (s *Service) GetStore() store.Readable {
 	return s.tree.Get()
 }
 
+// GetStoreAt returns the tree as it was after the block at the given index as
+// a read-only storage. It requires the tree to retain the version.
+func (s *Service) GetStoreAt(index uint64) (store.Readable, error) {
+	tree, err := s.getTreeAt(index)
+	if err != nil {
+		return nil, xerrors.Errorf("reading tree: %v", err)
+	}
+
+	return tree, nil
+}
+
+// GetReceipt returns the receipt of the transaction with the given identifier,
+// which tells in which block it has been included and whether it has been
+// accepted. It returns an error wrapping blockstore.ErrNoTransaction if the
+// transaction is not included in the chain.
+func (s *Service) GetReceipt(id []byte) (blockstore.Receipt, error) {
+	receipt, err := s.blocks.GetReceipt(id)
+	if err != nil {
+		return receipt, xerrors.Errorf("reading receipt: %w", err)
+	}
+
+	return receipt, nil
+}
+
+// GetEvidences returns the evidences of misbehavior of the members of the roster
+// that the node has detected. They are signed by the node so that they can be
+// shared and verified with its public key.
+func (s *Service) GetEvidences() ([]types.Evidence, error) {
+	evidences, err := s.pbftsm.GetEvidences()
+	if err != nil {
+		return nil, xerrors.Errorf("pbft: %v", err)
+	}
+
+	return evidences, nil
+}
+
 // GetRoster returns the current roster of the service.
 func (s *Service) GetRoster() (authority.Authority, error) {
 	return s.getCurrentRoster()
@@ -334,6 +706,26 @@ func (s *Service) Watch(ctx context.Context) <-chan ordering.Event {
 	return obs.ch
 }
 
+// WatchFrom implements ordering.Service. It returns a channel that is first
+// populated with the events of the stored blocks from the given index, and then
+// with the events of new incoming blocks. The channel is closed when the node
+// was restored from a snapshot and misses the blocks from the index. The
+// context must be closed when done.
+func (s *Service) WatchFrom(ctx context.Context, index uint64) <-chan ordering.Event {
+	return ordering.Replay(ctx, s.watcher, index, func(index uint64) (ordering.Event, error) {
+		link, err := s.blocks.GetByIndex(index)
+		if xerrors.Is(err, blockstore.ErrNoBlock) {
+			return ordering.Event{}, xerrors.Errorf("reading block: %w", ordering.ErrNoEvent)
+		}
+
+		if err != nil {
+			return ordering.Event{}, xerrors.Errorf("reading block: %v", err)
+		}
+
+		return makeEvent(link), nil
+	})
+}
+
 // Close implements ordering.Service. It gracefully closes the service. It will
 // announce the closing request and wait for the current to end before
 // returning.
@@ -366,10 +758,7 @@ func (s *Service) watchBlocks() {
 			s.logger.Err(err).Msg("roster refresh failed")
 		}
 
-		event := ordering.Event{
-			Index:        link.GetBlock().GetIndex(),
-			Transactions: link.GetBlock().GetData().GetTransactionResults(),
-		}
+		event := makeEvent(link)
 
 		// 3. Notify the main loop that a new block has been created, but ignore
 		// if the channel is busy.
@@ -388,6 +777,28 @@ func (s *Service) watchBlocks() {
 	}
 }
 
+// getTreeAt returns the version of the tree after the block at the given index.
+func (s *Service) getTreeAt(index uint64) (hashtree.Tree, error) {
+	link, err := s.blocks.GetByIndex(index)
+	if err != nil {
+		return nil, xerrors.Errorf("reading block: %v", err)
+	}
+
+	tree, ok := s.tree.Get().(hashtree.HistoricalTree)
+	if !ok {
+		return nil, xerrors.Errorf("tree '%T' does not retain history", s.tree.Get())
+	}
+
+	root := link.GetBlock().GetTreeRoot()
+
+	version, err := tree.GetVersion(root[:])
+	if err != nil {
+		return nil, xerrors.Errorf("version of block %d: %v", index, err)
+	}
+
+	return version, nil
+}
+
 func (s *Service) refreshRoster() error {
 	roster, err := s.getCurrentRoster()
 	if err != nil {
@@ -399,9 +810,34 @@ func (s *Service) refreshRoster() error {
 		return xerrors.Errorf("updating tx pool: %v", err)
 	}
 
+	s.checkRotation(roster)
+
 	return nil
 }
 
+// checkRotation triggers the pending rotation if the public key of the node in
+// the roster is the new one.
+func (s *Service) checkRotation(roster authority.Authority) {
+	s.rotationLock.Lock()
+	defer s.rotationLock.Unlock()
+
+	if s.rotation == nil {
+		return
+	}
+
+	pubkey, _ := roster.GetPublicKey(s.me)
+	if pubkey == nil || !pubkey.Equal(s.rotation.pubkey) {
+		return
+	}
+
+	s.logger.Info().
+		Str("pubkey", fmt.Sprintf("%v", s.rotation.pubkey)).
+		Msg("public key rotation committed")
+
+	s.rotation.fn()
+	s.rotation = nil
+}
+
 func (s *Service) main() error {
 	defer close(s.closed)
 
@@ -428,7 +864,7 @@ func (s *Service) main() error {
 	for {
 		// When a round failure occurs, it sleeps with a given backoff to give a
 		// chance to the system to recover without exhausting the resources.
-		time.Sleep(calculateBackoff(backoff))
+		<-s.clock.After(calculateBackoff(backoff, s.roundWait))
 
 		select {
 		case <-s.closing:
@@ -448,7 +884,7 @@ func (s *Service) main() error {
 			cancel()
 
 			if err != nil {
-				if calculateBackoff(backoff+1) < RoundMaxWait {
+				if calculateBackoff(backoff+1, s.roundWait) < s.roundMaxWait {
 					backoff++
 				}
 
@@ -471,17 +907,14 @@ func (s *Service) doRound(ctx context.Context) error {
 		return xerrors.Errorf("reading leader: %v", err)
 	}
 
-	timeout := s.timeoutRound
-	if s.failedRound {
-		timeout = s.timeoutRoundAfterFailure
-	}
+	timeout := s.getRoundTimeout()
 
 	for !s.me.Equal(leader) {
 		// Only enters the loop if the node is not the leader. It has to wait
 		// for the new block, or the round timeout, to proceed.
 
 		select {
-		case <-time.After(timeout):
+		case <-s.clock.After(timeout):
 			if s.pool.Len() == 0 {
 				// When the pool of transactions is empty, the round is aborted
 				// and everything restart.
@@ -491,9 +924,9 @@ func (s *Service) doRound(ctx context.Context) error {
 			s.logger.Warn().Msg("round reached the timeout")
 
 			// Mark that the view change happened during this round.
-			s.failedRound = true
+			s.failedRounds++
 
-			ctx, cancel := context.WithTimeout(ctx, s.timeoutViewchange)
+			ctx, cancel := core.WithTimeout(ctx, s.clock, s.timeoutViewchange)
 
 			view, err := s.pbftsm.Expire(s.me)
 			if err != nil {
@@ -536,7 +969,7 @@ func (s *Service) doRound(ctx context.Context) error {
 		case <-s.events:
 			// As a child, a block has been committed thus the previous view
 			// change succeeded.
-			s.failedRound = false
+			s.failedRounds = 0
 
 			// A block has been created meaning that the round is over.
 			return nil
@@ -545,7 +978,7 @@ func (s *Service) doRound(ctx context.Context) error {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	ctx, cancel := core.WithTimeout(ctx, s.clock, timeout)
 	defer cancel()
 
 	s.logger.Debug().Uint64("index", s.blocks.Len()).Msg("round has started")
@@ -566,21 +999,66 @@ func (s *Service) doRound(ctx context.Context) error {
 
 	// The leader can be a new leader coming from a view change, so it resets
 	// the value as a round has finished.
-	s.failedRound = false
+	s.failedRounds = 0
 
 	return nil
 }
 
+// getRoundTimeout returns the amount of time to wait for a block according to
+// the configuration of the service.
+func (s *Service) getRoundTimeout() time.Duration {
+	if s.adaptive != nil {
+		return s.adaptive.compute(s.latency.get(), s.timeoutRound, s.failedRounds)
+	}
+
+	if s.failedRounds > 0 {
+		return s.timeoutRoundAfterFailure
+	}
+
+	return s.timeoutRound
+}
+
 func (s *Service) doPBFT(ctx context.Context) error {
 	var id types.Digest
 	var block types.Block
+	var proposal crypto.Signature
+
+	// A leader elected by a view change sends the certificate with its
+	// proposal so that the members can hold it accountable.
+	vc, err := s.pbftsm.GetViewChange()
+	if err != nil {
+		return xerrors.Errorf("view change certificate: %v", err)
+	}
 
-	if s.pbftsm.GetState() >= pbft.CommitState {
-		// The node is already committed to a block, which means enough nodes
-		// have accepted, but somehow the finalization failed.
+	if s.pbftsm.GetState() >= pbft.PrepareState {
+		// The node is already prepared for a block, either because the round
+		// failed or because the node restarted, so it must propose the same
+		// one again, otherwise it equivocates. The proposal is signed again
+		// when the block comes from the leader before a view change.
 		id, block = s.pbftsm.GetCommit()
+		proposal = s.pbftsm.GetProposal()
+
+		msg := types.ProposalBytes(block.GetIndex(), block.GetHash())
+
+		if proposal == nil || s.signer.GetPublicKey().Verify(msg, proposal) != nil {
+			proposal, err = s.signProposal(block)
+			if err != nil {
+				return err
+			}
+		}
 	} else {
-		txs := s.pool.Gather(ctx, pool.Config{Min: 1})
+		limits, err := s.readLimits(s.tree.Get())
+		if err != nil {
+			return xerrors.Errorf("failed to read limits: %v", err)
+		}
+
+		txs := s.pool.Gather(ctx, pool.Config{
+			Min:     1,
+			Max:     int(limits.MaxTransactions),
+			MaxSize: int(limits.MaxSize),
+			Context: s.context,
+		})
+
 		if len(txs) == 0 {
 			s.logger.Debug().Msg("no transaction in pool")
 
@@ -596,7 +1074,12 @@ func (s *Service) doPBFT(ctx context.Context) error {
 			return ctx.Err()
 		}
 
-		data, root, err := s.prepareData(txs)
+		timestamp, err := s.nextTimestamp()
+		if err != nil {
+			return xerrors.Errorf("failed to read timestamp: %v", err)
+		}
+
+		data, root, err := s.prepareData(txs, timestamp)
 		if err != nil {
 			return xerrors.Errorf("failed to prepare data: %v", err)
 		}
@@ -605,13 +1088,19 @@ func (s *Service) doPBFT(ctx context.Context) error {
 			data,
 			types.WithTreeRoot(root),
 			types.WithIndex(uint64(s.blocks.Len())),
+			types.WithTimestamp(timestamp),
 			types.WithHashFactory(s.hashFactory))
 
 		if err != nil {
 			return xerrors.Errorf("creating block failed: %v", err)
 		}
 
-		id, err = s.pbftsm.Prepare(s.me, block)
+		proposal, err = s.signProposal(block)
+		if err != nil {
+			return err
+		}
+
+		id, err = s.pbftsm.Prepare(s.me, block, proposal, vc)
 		if err != nil {
 			return xerrors.Errorf("pbft prepare failed: %v", err)
 		}
@@ -623,7 +1112,7 @@ func (s *Service) doPBFT(ctx context.Context) error {
 	}
 
 	// 1. Prepare phase
-	req := types.NewBlockMessage(block, s.prepareViews())
+	req := types.NewBlockMessage(block, s.prepareViews(), proposal, vc)
 
 	sig, err := s.actor.Sign(ctx, req, roster)
 	if err != nil {
@@ -666,6 +1155,19 @@ func (s *Service) doPBFT(ctx context.Context) error {
 	return nil
 }
 
+// signProposal signs the block with the key of the node so that the members
+// can prove an equivocation of the leader.
+func (s *Service) signProposal(block types.Block) (crypto.Signature, error) {
+	msg := types.ProposalBytes(block.GetIndex(), block.GetHash())
+
+	sig, err := s.signer.Sign(msg)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to sign proposal: %v", err)
+	}
+
+	return sig, nil
+}
+
 func (s *Service) prepareViews() map[mino.Address]types.ViewMessage {
 	views := s.pbftsm.GetViews()
 	msgs := make(map[mino.Address]types.ViewMessage)
@@ -677,11 +1179,35 @@ func (s *Service) prepareViews() map[mino.Address]types.ViewMessage {
 	return msgs
 }
 
-func (s *Service) prepareData(txs []txn.Transaction) (data validation.Result, id types.Digest, err error) {
+// nextTimestamp returns the timestamp of a new block, which is the local clock
+// unless it is behind the timestamp of the previous block.
+func (s *Service) nextTimestamp() (time.Time, error) {
+	// The monotonic clock is stripped so that the timestamp is the same as the
+	// one the followers decode.
+	now := time.Unix(0, s.clock.Now().UnixNano())
+
+	if s.blocks.Len() == 0 {
+		return now, nil
+	}
+
+	last, err := s.blocks.Last()
+	if err != nil {
+		return now, xerrors.Errorf("couldn't read last block: %v", err)
+	}
+
+	prev := last.GetBlock().GetTimestamp()
+	if now.Before(prev) {
+		return prev, nil
+	}
+
+	return now, nil
+}
+
+func (s *Service) prepareData(txs []txn.Transaction, ts time.Time) (data validation.Result, id types.Digest, err error) {
 	var stageTree hashtree.StagingTree
 
 	stageTree, err = s.tree.Get().Stage(func(snap store.Snapshot) error {
-		data, err = s.val.Validate(snap, txs)
+		data, err = s.val.Validate(snap, txs, validation.WithTimestamp(ts))
 		if err != nil {
 			return xerrors.Errorf("validation failed: %v", err)
 		}
@@ -735,21 +1261,61 @@ func (obs observer) NotifyCallback(event interface{}) {
 	obs.ch <- event.(ordering.Event)
 }
 
-func calculateBackoff(backoff float64) time.Duration {
-	return time.Duration(math.Pow(2, backoff)) * RoundWait
+func makeEvent(link types.BlockLink) ordering.Event {
+	return ordering.Event{
+		Index:        link.GetBlock().GetIndex(),
+		Transactions: link.GetBlock().GetData().GetTransactionResults(),
+	}
+}
+
+// cosiSigner is a signer that always uses the signer of the collective signing
+// so that the state machine signs with the new key after a rotation.
+//
+// - implements crypto.Signer
+type cosiSigner struct {
+	cosi cosi.CollectiveSigning
+}
+
+// GetPublicKeyFactory implements crypto.Signer. It returns the public key
+// factory of the current signer.
+func (s cosiSigner) GetPublicKeyFactory() crypto.PublicKeyFactory {
+	return s.cosi.GetSigner().GetPublicKeyFactory()
+}
+
+// GetSignatureFactory implements crypto.Signer. It returns the signature
+// factory of the current signer.
+func (s cosiSigner) GetSignatureFactory() crypto.SignatureFactory {
+	return s.cosi.GetSigner().GetSignatureFactory()
+}
+
+// GetPublicKey implements crypto.Signer. It returns the public key of the
+// current signer.
+func (s cosiSigner) GetPublicKey() crypto.PublicKey {
+	return s.cosi.GetSigner().GetPublicKey()
+}
+
+// Sign implements crypto.Signer. It signs the message with the current signer.
+func (s cosiSigner) Sign(msg []byte) (crypto.Signature, error) {
+	return s.cosi.GetSigner().Sign(msg)
+}
+
+func calculateBackoff(backoff float64, wait time.Duration) time.Duration {
+	return time.Duration(math.Pow(2, backoff)) * wait
 }
 
 // PoolFilter is a filter to drop transactions which are already included in the
-// block or simply with an invalid nonce.
+// block, simply with an invalid nonce, or too big to fit in a block.
 //
 // - implements pool.Filter
 type poolFilter struct {
-	tree blockstore.TreeCache
-	srvc validation.Service
+	tree    blockstore.TreeCache
+	srvc    validation.Service
+	limits  func(hashtree.Tree) (types.Limits, error)
+	context serde.Context
 }
 
 // Accept implements pool.Filter. It returns an error if the transaction exists
-// already or the nonce is invalid.
+// already, the nonce is invalid, or if it exceeds the size of a block.
 func (f poolFilter) Accept(tx txn.Transaction, leeway validation.Leeway) error {
 	store := f.tree.Get()
 
@@ -758,5 +1324,22 @@ func (f poolFilter) Accept(tx txn.Transaction, leeway validation.Leeway) error {
 		return xerrors.Errorf("unacceptable transaction: %v", err)
 	}
 
+	if f.limits == nil {
+		return nil
+	}
+
+	limits, err := f.limits(store)
+	if err != nil {
+		return xerrors.Errorf("failed to read limits: %v", err)
+	}
+
+	// The size alone is verified as the count is always within the limits.
+	limits.MaxTransactions = 0
+
+	err = limits.Check(f.context, []txn.Transaction{tx})
+	if err != nil {
+		return xerrors.Errorf("oversized transaction: %v", err)
+	}
+
 	return nil
 }
@@ -1,6 +1,7 @@
 package cosipbft
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
@@ -21,6 +22,7 @@ import (
 	"go.dedis.ch/dela/core/ordering/cosipbft/pbft"
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/core/store/hashtree/binprefix"
 	"go.dedis.ch/dela/core/store/kv"
 	"go.dedis.ch/dela/core/txn"
@@ -41,6 +43,7 @@ import (
 	"go.dedis.ch/dela/mino/minoch"
 	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/dela/serde/json"
+	"golang.org/x/xerrors"
 )
 
 func TestService_Scenario_Basic(t *testing.T) {
@@ -59,12 +62,18 @@ func TestService_Scenario_Basic(t *testing.T) {
 
 	events := nodes[2].service.Watch(ctx)
 
-	err = nodes[0].pool.Add(makeTx(t, 0, signer))
+	tx := makeTx(t, 0, signer)
+
+	err = nodes[0].pool.Add(tx)
 	require.NoError(t, err)
 
 	evt := waitEvent(t, events)
 	require.Equal(t, uint64(0), evt.Index)
 
+	receipt, err := nodes[2].service.GetReceipt(tx.GetID())
+	require.NoError(t, err)
+	require.Equal(t, blockstore.Receipt{Index: 0, Position: 0, Accepted: true}, receipt)
+
 	err = nodes[1].pool.Add(makeTx(t, 1, signer))
 	require.NoError(t, err)
 
@@ -93,6 +102,465 @@ func TestService_Scenario_Basic(t *testing.T) {
 	require.NotNil(t, proof.GetValue())
 
 	checkProof(t, proof.(Proof), nodes[0].service)
+
+	// The proof must be verifiable once it has been transmitted.
+	data, err := proof.(Proof).Serialize(json.NewContext())
+	require.NoError(t, err)
+
+	fac := makeProofFactory(nodes[1])
+
+	proof, err = fac.ProofOf(json.NewContext(), data)
+	require.NoError(t, err)
+	require.Equal(t, keyRoster[:], proof.GetKey())
+
+	checkProof(t, proof.(Proof), nodes[1].service)
+
+	// The roster has changed at index 2, so that the previous state must hold
+	// the initial roster.
+	proof, err = nodes[0].service.GetProofAt(1, keyRoster[:])
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), proof.(Proof).GetChain().GetBlock().GetIndex())
+
+	checkProof(t, proof.(Proof), nodes[0].service)
+
+	prev, err := nodes[0].service.GetStoreAt(1)
+	require.NoError(t, err)
+
+	value, err := prev.Get(keyRoster[:])
+	require.NoError(t, err)
+	require.Equal(t, proof.GetValue(), value)
+
+	value, err = nodes[0].service.GetStore().Get(keyRoster[:])
+	require.NoError(t, err)
+	require.NotEqual(t, proof.GetValue(), value)
+
+	// A late watcher gets the past blocks before the new ones.
+	replay := nodes[2].service.WatchFrom(ctx, 1)
+
+	err = nodes[1].pool.Add(makeTx(t, 6, signer))
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 6; i++ {
+		evt = waitEvent(t, replay)
+		require.Equal(t, i, evt.Index)
+		require.Len(t, evt.Transactions, 1)
+	}
+}
+
+// Test that a new member restores a snapshot of the state instead of replaying
+// the blocks, and that it then participates to the consensus.
+func TestService_Scenario_SnapshotSync(t *testing.T) {
+	opt := WithSnapshotSync(2, binprefix.NewSuffixProofFactory())
+
+	nodes, ro, clean := makeAuthority(t, 5, opt)
+	defer clean()
+
+	signer := nodes[0].signer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial := ro.Take(mino.RangeFilter(0, 4)).(crypto.CollectiveAuthority)
+
+	err := nodes[0].service.Setup(ctx, initial)
+	require.NoError(t, err)
+
+	events := nodes[0].service.Watch(ctx)
+
+	for i := 0; i < 3; i++ {
+		err = nodes[0].pool.Add(makeTx(t, uint64(i), signer))
+		require.NoError(t, err)
+
+		waitEvent(t, events)
+	}
+
+	newcomer := nodes[4].service.Watch(ctx)
+
+	err = nodes[0].pool.Add(makeRosterTx(t, 3, ro, signer))
+	require.NoError(t, err)
+
+	waitEvent(t, events)
+
+	err = nodes[0].pool.Add(makeTx(t, 4, signer))
+	require.NoError(t, err)
+
+	// The first event of the new member is the block of the snapshot.
+	evt := waitEvent(t, newcomer)
+	require.Equal(t, uint64(3), evt.Index)
+
+	evt = waitEvent(t, newcomer)
+	require.Equal(t, uint64(4), evt.Index)
+
+	require.Equal(t, nodes[0].service.tree.Get().GetRoot(),
+		nodes[4].service.tree.Get().GetRoot())
+
+	// The blocks before the snapshot are not available but the chain still
+	// proves the latest block.
+	_, err = nodes[4].service.blocks.GetByIndex(2)
+	require.True(t, xerrors.Is(err, blockstore.ErrNoHistory))
+
+	err = nodes[4].service.ExportChain(new(bytes.Buffer))
+	require.Error(t, err)
+
+	// A replay from before the snapshot is interrupted.
+	replay := nodes[4].service.WatchFrom(ctx, 0)
+
+	select {
+	case _, more := <-replay:
+		require.False(t, more)
+	case <-time.After(time.Second):
+		t.Fatal("replay not interrupted")
+	}
+
+	chain, err := nodes[4].service.blocks.GetChain()
+	require.NoError(t, err)
+	require.Len(t, chain.GetLinks(), 5)
+
+	proof, err := nodes[4].service.GetProof(keyRoster[:])
+	require.NoError(t, err)
+
+	checkProof(t, proof.(Proof), nodes[4].service)
+}
+
+// Test that the leader policy of the genesis block rotates the leader after
+// each block on every node.
+func TestService_Scenario_RoundRobin(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 4)
+	defer clean()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := nodes[0].service.Setup(ctx, ro, WithLeaderPolicy(pbft.NewRoundRobinPolicy()))
+	require.NoError(t, err)
+
+	genesis, err := nodes[1].service.genesis.Get()
+	require.NoError(t, err)
+	require.Equal(t, "round-robin", genesis.GetLeaderPolicy())
+
+	events := nodes[0].service.Watch(ctx)
+
+	for i := 0; i < 5; i++ {
+		err = nodes[0].pool.Add(makeTx(t, uint64(i), nodes[0].signer))
+		require.NoError(t, err)
+
+		waitEvent(t, events)
+
+		leader, err := nodes[0].service.pbftsm.GetLeader()
+		require.NoError(t, err)
+		require.Equal(t, nodes[(i+1)%4].service.me, leader)
+	}
+}
+
+// Test that the blocks carry the timestamp proposed by the leader, which never
+// goes backwards.
+func TestService_Scenario_Timestamps(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 3, WithTimestampDrift(time.Minute))
+	defer clean()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := nodes[0].service.Setup(ctx, ro)
+	require.NoError(t, err)
+
+	events := nodes[0].service.Watch(ctx)
+
+	for i := 0; i < 3; i++ {
+		err = nodes[0].pool.Add(makeTx(t, uint64(i), nodes[0].signer))
+		require.NoError(t, err)
+
+		waitEvent(t, events)
+	}
+
+	prev := time.Time{}
+
+	for i := uint64(0); i < 3; i++ {
+		link, err := nodes[2].service.blocks.GetByIndex(i)
+		require.NoError(t, err)
+
+		ts := link.GetBlock().GetTimestamp()
+		require.WithinDuration(t, time.Now(), ts, time.Minute)
+		require.False(t, ts.Before(prev))
+
+		prev = ts
+	}
+}
+
+// Test that the blocks are bounded by the limits of the chain, and that the
+// limits are changed by a governance transaction.
+func TestService_Scenario_Limits(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 3)
+	defer clean()
+
+	signer := nodes[0].signer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := nodes[0].service.Setup(ctx, ro, WithLimits(types.Limits{MaxTransactions: 1}))
+	require.NoError(t, err)
+
+	// The events of a follower are watched so that the tree is read once the
+	// block is committed by the follower.
+	events := nodes[2].service.Watch(ctx)
+
+	err = nodes[0].pool.Add(makeTx(t, 0, signer))
+	require.NoError(t, err)
+
+	err = nodes[0].pool.Add(makeTx(t, 1, signer))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		evt := waitEvent(t, events)
+		require.Len(t, evt.Transactions, 1)
+	}
+
+	txMgr := signed.NewManager(signer, fakeClient{nonce: 2})
+	require.NoError(t, txMgr.Sync())
+
+	tx, err := viewchange.NewManager(txMgr).MakeLimits(types.Limits{MaxSize: 1 << 20})
+	require.NoError(t, err)
+
+	err = nodes[0].pool.Add(tx)
+	require.NoError(t, err)
+
+	evt := waitEvent(t, events)
+	requireAccepted(t, evt)
+
+	limits, err := nodes[2].service.readLimits(nodes[2].service.tree.Get())
+	require.NoError(t, err)
+	require.Equal(t, types.Limits{MaxSize: 1 << 20}, limits)
+}
+
+// Test that the initial state of the genesis block is written in the tree of
+// every node, and that the digest of the genesis block is known beforehand.
+func TestService_Scenario_InitialState(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 3)
+	defer clean()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := []SetupOption{
+		WithLeaderPolicy(pbft.NewRoundRobinPolicy()),
+		WithInitialState(types.NewStateEntry([]byte("ping"), []byte("pong"))),
+	}
+
+	expected, err := nodes[2].service.MakeGenesis(ro, opts...)
+	require.NoError(t, err)
+
+	err = nodes[0].service.Setup(ctx, ro, opts...)
+	require.NoError(t, err)
+
+	for _, node := range nodes {
+		genesis, err := node.service.genesis.Get()
+		require.NoError(t, err)
+		require.Equal(t, expected.GetHash(), genesis.GetHash())
+		require.Equal(t, expected.GetRoot(), genesis.GetRoot())
+
+		value, err := node.service.GetStore().Get([]byte("ping"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("pong"), value)
+	}
+
+	_, err = nodes[2].service.MakeGenesis(ro, opts...)
+	require.EqualError(t, err, "chain already exists")
+}
+
+// Test that a new member downloads the missing blocks by ranges from the
+// members of the roster.
+func TestService_Scenario_ParallelSync(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 5, WithParallelSync(2))
+	defer clean()
+
+	signer := nodes[0].signer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	initial := ro.Take(mino.RangeFilter(0, 4)).(crypto.CollectiveAuthority)
+
+	err := nodes[0].service.Setup(ctx, initial)
+	require.NoError(t, err)
+
+	events := nodes[0].service.Watch(ctx)
+
+	for i := 0; i < 3; i++ {
+		err = nodes[0].pool.Add(makeTx(t, uint64(i), signer))
+		require.NoError(t, err)
+
+		waitEvent(t, events)
+	}
+
+	newcomer := nodes[4].service.Watch(ctx)
+
+	err = nodes[0].pool.Add(makeRosterTx(t, 3, ro, signer))
+	require.NoError(t, err)
+
+	waitEvent(t, events)
+
+	err = nodes[0].pool.Add(makeTx(t, 4, signer))
+	require.NoError(t, err)
+
+	for i := uint64(0); i <= 4; i++ {
+		evt := waitEvent(t, newcomer)
+		require.Equal(t, i, evt.Index)
+	}
+
+	require.Equal(t, nodes[0].service.tree.Get().GetRoot(),
+		nodes[4].service.tree.Get().GetRoot())
+}
+
+// Test that the chain keeps producing blocks after members are removed, which
+// includes the current leader. It also checks that a removal which would break
+// the byzantine threshold is refused.
+func TestService_Scenario_RemoveMembers(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 5)
+	defer clean()
+
+	signer := nodes[0].signer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := nodes[0].service.Setup(ctx, ro)
+	require.NoError(t, err)
+
+	events := nodes[2].service.Watch(ctx)
+
+	// 1. Remove a follower.
+	cset := authority.NewChangeSet()
+	cset.Remove(4)
+	ro = ro.Apply(cset)
+
+	err = nodes[0].pool.Add(makeRosterTx(t, 0, ro, signer))
+	require.NoError(t, err)
+
+	evt := waitEvent(t, events)
+	require.Equal(t, uint64(0), evt.Index)
+	requireAccepted(t, evt)
+
+	err = nodes[1].pool.Add(makeTx(t, 1, signer))
+	require.NoError(t, err)
+
+	evt = waitEvent(t, events)
+	require.Equal(t, uint64(1), evt.Index)
+
+	// 2. Remove the leader, which means the next member in the list takes
+	// over.
+	cset = authority.NewChangeSet()
+	cset.Remove(0)
+	ro = ro.Apply(cset)
+
+	err = nodes[1].pool.Add(makeRosterTx(t, 2, ro, signer))
+	require.NoError(t, err)
+
+	evt = waitEvent(t, events)
+	require.Equal(t, uint64(2), evt.Index)
+	requireAccepted(t, evt)
+
+	leader, err := nodes[2].service.pbftsm.GetLeader()
+	require.NoError(t, err)
+	require.Equal(t, nodes[1].service.me, leader)
+
+	for i := 0; i < 2; i++ {
+		err = nodes[1].pool.Add(makeTx(t, uint64(i+3), signer))
+		require.NoError(t, err)
+
+		evt = waitEvent(t, events)
+		require.Equal(t, uint64(i+3), evt.Index)
+	}
+
+	// 3. The remaining three members are all needed to reach the threshold,
+	// so any further removal is refused.
+	cset = authority.NewChangeSet()
+	cset.Remove(2)
+
+	err = nodes[1].pool.Add(makeRosterTx(t, 5, ro.Apply(cset), signer))
+	require.NoError(t, err)
+
+	evt = waitEvent(t, events)
+	require.Equal(t, uint64(5), evt.Index)
+	require.Len(t, evt.Transactions, 1)
+
+	accepted, _ := evt.Transactions[0].GetStatus()
+	require.False(t, accepted)
+
+	roster, err := nodes[1].service.GetRoster()
+	require.NoError(t, err)
+	require.Equal(t, 3, roster.Len())
+}
+
+// Test that the nodes switch to their new key when a rotation is committed. The
+// roster has only three members so that every signature is required to create
+// a block.
+func TestService_Scenario_RotateKeys(t *testing.T) {
+	nodes, ro, clean := makeAuthority(t, 3)
+	defer clean()
+
+	signer := nodes[0].signer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := nodes[0].service.Setup(ctx, ro)
+	require.NoError(t, err)
+
+	events := nodes[1].service.Watch(ctx)
+
+	nonce := uint64(0)
+
+	// Rotate the key of a follower, and then of the leader.
+	for _, i := range []int{2, 0} {
+		roster, err := nodes[i].service.GetRoster()
+		require.NoError(t, err)
+
+		next := bls.NewSigner()
+		txMgr := signed.NewManager(signer, fakeClient{nonce: nonce})
+		require.NoError(t, txMgr.Sync())
+
+		mgr := viewchange.NewManager(txMgr)
+
+		tx, err := mgr.MakeRotate(roster, nodes[i].service.me, next.GetPublicKey(), nodes[i].cosi.GetSigner())
+		require.NoError(t, err)
+
+		done := make(chan struct{})
+		nodes[i].service.PrepareRotation(next.GetPublicKey(), func() {
+			nodes[i].cosi.SetSigner(next)
+			close(done)
+		})
+
+		err = nodes[1].pool.Add(tx)
+		require.NoError(t, err)
+
+		evt := waitEvent(t, events)
+		require.Equal(t, nonce, evt.Index)
+		requireAccepted(t, evt)
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("rotation not triggered")
+		}
+
+		require.Equal(t, next.GetPublicKey(), nodes[i].cosi.GetSigner().GetPublicKey())
+
+		nonce++
+
+		err = nodes[1].pool.Add(makeTx(t, nonce, signer))
+		require.NoError(t, err)
+
+		evt = waitEvent(t, events)
+		require.Equal(t, nonce, evt.Index)
+
+		nonce++
+	}
+
+	proof, err := nodes[1].service.GetProof(keyRoster[:])
+	require.NoError(t, err)
+
+	checkProof(t, proof.(Proof), nodes[1].service)
 }
 
 func TestService_Scenario_ViewChange(t *testing.T) {
@@ -125,6 +593,22 @@ func TestService_Scenario_ViewChange(t *testing.T) {
 
 	evt := waitEvent(t, events)
 	require.Equal(t, uint64(0), evt.Index)
+
+	// The link of the block proves that the view has changed to the new
+	// leader.
+	link, err := nodes[2].service.blocks.Last()
+	require.NoError(t, err)
+	require.NotNil(t, link.GetViewChange())
+	require.Equal(t, uint16(1), link.GetViewChange().GetLeader())
+
+	chain, err := nodes[2].service.blocks.GetChain()
+	require.NoError(t, err)
+
+	genesis, err := nodes[2].service.genesis.Get()
+	require.NoError(t, err)
+
+	err = chain.Verify(genesis, nodes[2].service.verifierFac)
+	require.NoError(t, err)
 }
 
 // Test that a block committed will be eventually finalized even if the
@@ -186,11 +670,22 @@ func TestService_New(t *testing.T) {
 		WithHashFactory(fake.NewHashFactory(&fake.Hash{})),
 		WithGenesisStore(genesis),
 		WithBlockStore(blockstore.NewInMemory()),
+		WithRoundTimeout(time.Second),
+		WithFailedRoundTimeout(2 * time.Second),
+		WithViewChangeTimeout(3 * time.Second),
+		WithRoundBackoff(time.Millisecond, time.Minute),
+		WithAdaptiveTimeout(time.Second, time.Minute),
 	}
 
 	srvc, err := NewService(param, opts...)
 	require.NoError(t, err)
 	require.NotNil(t, srvc)
+	require.Equal(t, time.Second, srvc.timeoutRound)
+	require.Equal(t, 2*time.Second, srvc.timeoutRoundAfterFailure)
+	require.Equal(t, 3*time.Second, srvc.timeoutViewchange)
+	require.Equal(t, time.Millisecond, srvc.roundWait)
+	require.Equal(t, time.Minute, srvc.roundMaxWait)
+	require.Equal(t, &adaptiveTimeout{min: time.Second, max: time.Minute}, srvc.adaptive)
 
 	<-srvc.closed
 
@@ -225,6 +720,23 @@ func TestService_Setup(t *testing.T) {
 	require.Equal(t, 3, genesis.GetRoster().Len())
 }
 
+func TestService_MakeGenesis(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.access = fakeAccess{}
+
+	ro := fake.NewAuthority(3, fake.NewSigner)
+
+	genesis, err := srvc.MakeGenesis(ro)
+	require.NoError(t, err)
+	require.Equal(t, 3, genesis.GetRoster().Len())
+	require.False(t, srvc.genesis.Exists())
+
+	_, err = srvc.MakeGenesis(ro, WithInitialState(types.NewStateEntry(keyAccess[:], nil)))
+	require.EqualError(t, err, fmt.Sprintf("creating genesis: reserved key '%x'", keyAccess[:]))
+}
+
 func TestService_AlreadySet_Setup(t *testing.T) {
 	srvc := &Service{
 		processor: newProcessor(),
@@ -242,7 +754,7 @@ func TestService_AlreadySet_Setup(t *testing.T) {
 
 	err := srvc.Setup(ctx, authority)
 	require.EqualError(t, err,
-		"creating genesis: set genesis failed: genesis block is already set")
+		"creating genesis: chain already exists")
 }
 
 func TestService_FailReadGenesis_Setup(t *testing.T) {
@@ -305,7 +817,11 @@ func TestService_RequestFailure_Setup(t *testing.T) {
 }
 
 func TestService_Main(t *testing.T) {
-	srvc := &Service{processor: newProcessor()}
+	srvc := &Service{
+		processor:    newProcessor(),
+		roundWait:    RoundWait,
+		roundMaxWait: RoundMaxWait,
+	}
 	srvc.rosterFac = authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
 	srvc.closing = make(chan struct{})
 	srvc.closed = make(chan struct{})
@@ -559,6 +1075,28 @@ func TestService_FailPBFT_DoRound(t *testing.T) {
 		fake.Err("pbft failed: failed to prepare data: staging tree failed: validation failed"))
 }
 
+func TestService_GetRoundTimeout(t *testing.T) {
+	srvc := &Service{
+		processor:                newProcessor(),
+		timeoutRound:             time.Second,
+		timeoutRoundAfterFailure: 2 * time.Second,
+	}
+
+	require.Equal(t, time.Second, srvc.getRoundTimeout())
+
+	srvc.failedRounds = 1
+	require.Equal(t, 2*time.Second, srvc.getRoundTimeout())
+
+	srvc.adaptive = &adaptiveTimeout{min: time.Millisecond, max: time.Minute}
+	require.Equal(t, 2*time.Second, srvc.getRoundTimeout())
+
+	srvc.latency.observe(100 * time.Millisecond)
+	require.Equal(t, 800*time.Millisecond, srvc.getRoundTimeout())
+
+	srvc.failedRounds = 0
+	require.Equal(t, 400*time.Millisecond, srvc.getRoundTimeout())
+}
+
 func TestService_DoPBFT(t *testing.T) {
 	rpc := fake.NewRPC()
 
@@ -566,6 +1104,7 @@ func TestService_DoPBFT(t *testing.T) {
 	srvc.tree = blockstore.NewTreeCache(fakeTree{})
 	srvc.val = fakeValidation{}
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 	srvc.genesis = blockstore.NewGenesisStore()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.pbftsm = fakeSM{}
@@ -612,6 +1151,7 @@ func TestService_FailValidation_DoPBFT(t *testing.T) {
 	srvc := &Service{processor: newProcessor()}
 	srvc.val = fakeValidation{err: fake.GetError()}
 	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+	srvc.blocks = blockstore.NewInMemory()
 	srvc.pbftsm = fakeSM{}
 	srvc.pool = mem.NewPool()
 
@@ -645,6 +1185,76 @@ func TestService_FailCreateBlock_DoPBFT(t *testing.T) {
 		fake.Err("creating block failed: fingerprint failed: couldn't write index"))
 }
 
+func TestService_FailReadLimits_DoPBFT(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.pbftsm = fakeSM{}
+	srvc.tree = blockstore.NewTreeCache(fakeTree{limits: []byte{1}})
+
+	err := srvc.doPBFT(context.Background())
+	require.EqualError(t, err,
+		"failed to read limits: invalid limits: invalid length 1 != 16")
+}
+
+func TestService_FailReadTimestamp_DoPBFT(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.pbftsm = fakeSM{}
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+	srvc.pool = mem.NewPool()
+	srvc.blocks = badBlockStore{length: 1}
+
+	srvc.pool.Add(makeTx(t, 0, fake.NewSigner()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := srvc.doPBFT(ctx)
+	require.EqualError(t, err,
+		fake.Err("failed to read timestamp: couldn't read last block"))
+}
+
+func TestService_NextTimestamp(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.blocks = blockstore.NewInMemory()
+
+	ts, err := srvc.nextTimestamp()
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), ts, time.Minute)
+
+	// The timestamp never goes backwards even if the clock of the leader is
+	// behind the previous one.
+	future := time.Unix(0, time.Now().Add(time.Hour).UnixNano())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTimestamp(future))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(types.Digest{}, block)
+	require.NoError(t, err)
+	require.NoError(t, srvc.blocks.Store(link))
+
+	ts, err = srvc.nextTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, future, ts)
+}
+
+func TestService_FailSignProposal_DoPBFT(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.val = fakeValidation{}
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+	srvc.pbftsm = fakeSM{}
+	srvc.pool = mem.NewPool()
+	srvc.hashFactory = crypto.NewSha256Factory()
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewBadSigner()
+
+	srvc.pool.Add(makeTx(t, 0, fake.NewSigner()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := srvc.doPBFT(ctx)
+	require.EqualError(t, err, fake.Err("failed to sign proposal"))
+}
+
 func TestService_FailPrepare_DoPBFT(t *testing.T) {
 	srvc := &Service{processor: newProcessor()}
 	srvc.val = fakeValidation{}
@@ -653,6 +1263,7 @@ func TestService_FailPrepare_DoPBFT(t *testing.T) {
 	srvc.pool = mem.NewPool()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 
 	srvc.pool.Add(makeTx(t, 0, fake.NewSigner()))
 
@@ -663,6 +1274,35 @@ func TestService_FailPrepare_DoPBFT(t *testing.T) {
 	require.EqualError(t, err, fake.Err("pbft prepare failed"))
 }
 
+func TestService_PreparedRound_DoPBFT(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+	srvc.pool = mem.NewPool()
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewBadSigner()
+	srvc.actor = fakeCosiActor{err: fake.GetError()}
+	srvc.rosterFac = authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
+
+	// The leader already prepared a block, so it proposes the same one again
+	// without gathering transactions nor signing a new proposal.
+	srvc.pbftsm = fakeSM{
+		err:      fake.GetError(),
+		state:    pbft.PrepareState,
+		proposal: fake.Signature{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := srvc.doPBFT(ctx)
+	require.EqualError(t, err, fake.Err("prepare signature failed"))
+
+	srvc.pbftsm = fakeSM{state: pbft.PrepareState}
+
+	err = srvc.doPBFT(ctx)
+	require.EqualError(t, err, fake.Err("failed to sign proposal"))
+}
+
 func TestService_FailReadRoster_DoPBFT(t *testing.T) {
 	srvc := &Service{processor: newProcessor()}
 	srvc.val = fakeValidation{}
@@ -671,6 +1311,7 @@ func TestService_FailReadRoster_DoPBFT(t *testing.T) {
 	srvc.pool = mem.NewPool()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 
 	srvc.pool.Add(makeTx(t, 0, fake.NewSigner()))
 
@@ -689,6 +1330,7 @@ func TestService_FailPrepareSig_DoPBFT(t *testing.T) {
 	srvc.pool = mem.NewPool()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 	srvc.actor = fakeCosiActor{err: fake.GetError()}
 	srvc.rosterFac = authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
 
@@ -709,6 +1351,7 @@ func TestService_FailCommitSign_DoPBFT(t *testing.T) {
 	srvc.pool = mem.NewPool()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 	srvc.actor = fakeCosiActor{
 		err:     fake.GetError(),
 		counter: fake.NewCounter(1),
@@ -732,6 +1375,7 @@ func TestService_FailPropagation_DoPBFT(t *testing.T) {
 	srvc.pool = mem.NewPool()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 	srvc.actor = fakeCosiActor{}
 	srvc.rosterFac = authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
 	srvc.rpc = fake.NewBadRPC()
@@ -756,6 +1400,7 @@ func TestService_FailWakeUp_DoPBFT(t *testing.T) {
 	srvc.pool = mem.NewPool()
 	srvc.hashFactory = crypto.NewSha256Factory()
 	srvc.blocks = blockstore.NewInMemory()
+	srvc.signer = fake.NewSigner()
 	srvc.actor = fakeCosiActor{}
 	srvc.rosterFac = authority.NewFactory(fake.AddressFactory{}, fake.PublicKeyFactory{})
 	srvc.rpc = rpc
@@ -826,6 +1471,77 @@ func TestService_GetStore(t *testing.T) {
 	require.IsType(t, fakeTree{}, srvc.GetStore())
 }
 
+func TestService_GetProofAt(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.tree = blockstore.NewTreeCache(fakeHistoricalTree{})
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.blocks.Store(makeBlock(t, types.Digest{}))
+
+	proof, err := srvc.GetProofAt(0, []byte("A"))
+	require.NoError(t, err)
+	require.NotNil(t, proof)
+
+	srvc.tree.Set(fakeHistoricalTree{fakeTree: fakeTree{err: fake.GetError()}})
+	_, err = srvc.GetProofAt(0, []byte("A"))
+	require.EqualError(t, err, fake.Err("reading path"))
+
+	srvc.tree.Set(fakeTree{})
+	_, err = srvc.GetProofAt(0, []byte("A"))
+	require.EqualError(t, err,
+		"reading tree: tree 'cosipbft.fakeTree' does not retain history")
+
+	_, err = srvc.GetProofAt(1, []byte("A"))
+	require.EqualError(t, err,
+		"reading tree: reading block: block not found: no block")
+
+	srvc.tree.Set(fakeHistoricalTree{errVersion: fake.GetError()})
+	_, err = srvc.GetProofAt(0, []byte("A"))
+	require.EqualError(t, err, fake.Err("reading tree: version of block 0"))
+
+	srvc.tree.Set(fakeHistoricalTree{})
+	srvc.blocks = badChainStore{BlockStore: srvc.blocks}
+	_, err = srvc.GetProofAt(0, []byte("A"))
+	require.EqualError(t, err, fake.Err("reading chain"))
+}
+
+func TestService_GetStoreAt(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.tree = blockstore.NewTreeCache(fakeHistoricalTree{})
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.blocks.Store(makeBlock(t, types.Digest{}))
+
+	store, err := srvc.GetStoreAt(0)
+	require.NoError(t, err)
+	require.NotNil(t, store)
+
+	_, err = srvc.GetStoreAt(1)
+	require.EqualError(t, err,
+		"reading tree: reading block: block not found: no block")
+}
+
+func TestService_GetReceipt(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.blocks = blockstore.NewInMemory()
+
+	_, err := srvc.GetReceipt([]byte{0xaa})
+	require.EqualError(t, err,
+		"reading receipt: transaction 0xaa not found: no transaction")
+	require.True(t, xerrors.Is(err, blockstore.ErrNoTransaction))
+}
+
+func TestService_GetEvidences(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.pbftsm = fakeSM{}
+
+	evidences, err := srvc.GetEvidences()
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+
+	srvc.pbftsm = fakeSM{err: fake.GetError()}
+	_, err = srvc.GetEvidences()
+	require.EqualError(t, err, fake.Err("pbft"))
+}
+
 func TestService_GetRoster(t *testing.T) {
 	srvc := &Service{processor: newProcessor()}
 	srvc.tree = blockstore.NewTreeCache(fakeTree{})
@@ -836,6 +1552,69 @@ func TestService_GetRoster(t *testing.T) {
 	require.Equal(t, 3, roster.Len())
 }
 
+func TestService_PrepareRotation(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+	srvc.rosterFac = fakeRosterFac{}
+	srvc.pool = mem.NewPool()
+	srvc.me = fake.NewAddress(1)
+
+	calls := &fake.Call{}
+	fn := func() { calls.Add() }
+
+	// The roster already contains the public key.
+	srvc.PrepareRotation(fake.PublicKey{}, fn)
+	require.Equal(t, 1, calls.Len())
+	require.Nil(t, srvc.rotation)
+
+	srvc.PrepareRotation(bls.NewSigner().GetPublicKey(), fn)
+	require.Equal(t, 1, calls.Len())
+	require.NotNil(t, srvc.rotation)
+
+	require.NoError(t, srvc.refreshRoster())
+	require.Equal(t, 1, calls.Len())
+
+	srvc.me = fake.NewAddress(5)
+	srvc.PrepareRotation(fake.PublicKey{}, fn)
+	require.Equal(t, 1, calls.Len())
+
+	srvc.me = fake.NewAddress(2)
+	require.NoError(t, srvc.refreshRoster())
+	require.Equal(t, 2, calls.Len())
+	require.Nil(t, srvc.rotation)
+
+	srvc.tree.Set(fakeTree{err: fake.GetError()})
+	srvc.PrepareRotation(fake.PublicKey{}, fn)
+	require.Equal(t, 2, calls.Len())
+	require.NotNil(t, srvc.rotation)
+
+	// Only the rotation to the given key is dropped.
+	srvc.CancelRotation(bls.NewSigner().GetPublicKey())
+	require.NotNil(t, srvc.rotation)
+
+	srvc.CancelRotation(fake.PublicKey{})
+	require.Nil(t, srvc.rotation)
+
+	srvc.CancelRotation(fake.PublicKey{})
+	require.Nil(t, srvc.rotation)
+}
+
+func TestCosiSigner(t *testing.T) {
+	c := threshold.NewThreshold(fake.Mino{}, bls.NewSigner())
+
+	signer := cosiSigner{cosi: c}
+	require.NotNil(t, signer.GetPublicKeyFactory())
+	require.NotNil(t, signer.GetSignatureFactory())
+
+	next := bls.NewSigner()
+	c.SetSigner(next)
+	require.Equal(t, next.GetPublicKey(), signer.GetPublicKey())
+
+	sig, err := signer.Sign([]byte("ping"))
+	require.NoError(t, err)
+	require.NoError(t, next.GetPublicKey().Verify([]byte("ping"), sig))
+}
+
 func TestService_PoolFilter(t *testing.T) {
 	filter := poolFilter{
 		tree: blockstore.NewTreeCache(fakeTree{}),
@@ -848,6 +1627,34 @@ func TestService_PoolFilter(t *testing.T) {
 	filter.srvc = fakeValidation{err: fake.GetError()}
 	err = filter.Accept(makeTx(t, 0, fake.NewSigner()), validation.Leeway{})
 	require.EqualError(t, err, fake.Err("unacceptable transaction"))
+
+	// Only the size is verified as a single transaction never exceeds the
+	// number of transactions.
+	filter.srvc = fakeValidation{}
+	filter.context = json.NewContext()
+	filter.limits = func(hashtree.Tree) (types.Limits, error) {
+		return types.Limits{MaxTransactions: 1, MaxSize: 1 << 20}, nil
+	}
+
+	tx := makeTx(t, 0, bls.NewSigner())
+
+	err = filter.Accept(tx, validation.Leeway{})
+	require.NoError(t, err)
+
+	filter.limits = func(hashtree.Tree) (types.Limits, error) {
+		return types.Limits{MaxSize: 1}, nil
+	}
+
+	err = filter.Accept(tx, validation.Leeway{})
+	require.Error(t, err)
+	require.Regexp(t, "^oversized transaction: size [0-9]+ > 1$", err.Error())
+
+	filter.limits = func(hashtree.Tree) (types.Limits, error) {
+		return types.Limits{}, fake.GetError()
+	}
+
+	err = filter.Accept(tx, validation.Leeway{})
+	require.EqualError(t, err, fake.Err("failed to read limits"))
 }
 
 // -----------------------------------------------------------------------------
@@ -861,6 +1668,14 @@ func checkProof(t *testing.T, p Proof, s *Service) {
 	require.NoError(t, err)
 }
 
+func makeProofFactory(node testNode) ProofFactory {
+	csFac := authority.NewChangeSetFactory(node.onet.GetAddressFactory(), node.cosi.GetPublicKeyFactory())
+	blockFac := types.NewBlockFactory(node.service.val.GetFactory())
+	linkFac := types.NewLinkFactory(blockFac, node.cosi.GetSignatureFactory(), csFac)
+
+	return NewProofFactory(binprefix.NewPathFactory(), types.NewChainFactory(linkFac))
+}
+
 type testNode struct {
 	onet    *minoch.Minoch
 	service *Service
@@ -868,6 +1683,7 @@ type testNode struct {
 	db      kv.DB
 	dbpath  string
 	signer  crypto.Signer
+	cosi    *threshold.Threshold
 }
 
 const testContractName = "abc"
@@ -910,6 +1726,13 @@ func makeRosterTx(t *testing.T, nonce uint64, roster authority.Authority, signer
 	return tx
 }
 
+func requireAccepted(t *testing.T, evt ordering.Event) {
+	for _, res := range evt.Transactions {
+		accepted, reason := res.GetStatus()
+		require.True(t, accepted, reason)
+	}
+}
+
 func waitEvent(t *testing.T, events <-chan ordering.Event) ordering.Event {
 	select {
 	case <-time.After(15 * time.Second):
@@ -920,7 +1743,7 @@ func waitEvent(t *testing.T, events <-chan ordering.Event) ordering.Event {
 	}
 }
 
-func makeAuthority(t *testing.T, n int) ([]testNode, authority.Authority, func()) {
+func makeAuthority(t *testing.T, n int, opts ...ServiceOption) ([]testNode, authority.Authority, func()) {
 	manager := minoch.NewManager()
 
 	addrs := make([]mino.Address, n)
@@ -928,73 +1751,82 @@ func makeAuthority(t *testing.T, n int) ([]testNode, authority.Authority, func()
 	nodes := make([]testNode, n)
 
 	for i := 0; i < n; i++ {
-		m := minoch.MustCreate(manager, fmt.Sprintf("node%d", i))
+		nodes[i] = makeNode(t, manager, fmt.Sprintf("node%d", i), opts...)
 
-		addrs[i] = m.GetAddress()
+		addrs[i] = nodes[i].onet.GetAddress()
+		pubkeys[i] = nodes[i].signer.GetPublicKey()
+	}
 
-		signer := bls.NewSigner()
-		pubkeys[i] = signer.GetPublicKey()
+	ro := authority.New(addrs, pubkeys)
 
-		c := threshold.NewThreshold(m, signer)
-		c.SetThreshold(threshold.ByzantineThreshold)
+	clean := func() {
+		for _, node := range nodes {
+			node.close(t)
+		}
+	}
 
-		dir, err := ioutil.TempDir(os.TempDir(), "cosipbft")
-		require.NoError(t, err)
+	return nodes, ro, clean
+}
 
-		db, err := kv.New(filepath.Join(dir, "test.db"))
-		require.NoError(t, err)
+func makeNode(t *testing.T, manager *minoch.Manager, name string, opts ...ServiceOption) testNode {
+	m := minoch.MustCreate(manager, name)
 
-		txFac := signed.NewTransactionFactory()
+	signer := bls.NewSigner()
 
-		pool, err := poolimpl.NewPool(gossip.NewFlat(m, txFac))
-		require.NoError(t, err)
+	c := threshold.NewThreshold(m, signer)
+	c.SetThreshold(threshold.ByzantineThreshold)
 
-		tree := binprefix.NewMerkleTree(db, binprefix.Nonce{})
+	dir, err := ioutil.TempDir(os.TempDir(), "cosipbft")
+	require.NoError(t, err)
 
-		exec := native.NewExecution()
-		exec.Set(testContractName, testExec{})
+	db, err := kv.New(filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
 
-		accessSrvc := darc.NewService(json.NewContext())
+	txFac := signed.NewTransactionFactory()
 
-		rosterFac := authority.NewFactory(m.GetAddressFactory(), c.GetPublicKeyFactory())
-		RegisterRosterContract(exec, rosterFac, accessSrvc)
+	pool, err := poolimpl.NewPool(gossip.NewFlat(m, txFac))
+	require.NoError(t, err)
 
-		vs := simple.NewService(exec, txFac)
+	tree := binprefix.NewMerkleTree(db, binprefix.Nonce{}, binprefix.WithHistory(10))
 
-		param := ServiceParam{
-			Mino:       m,
-			Cosi:       c,
-			Validation: vs,
-			Access:     accessSrvc,
-			Pool:       pool,
-			Tree:       tree,
-			DB:         db,
-		}
+	exec := native.NewExecution()
+	exec.Set(testContractName, testExec{})
 
-		srv, err := NewService(param)
-		require.NoError(t, err)
+	accessSrvc := darc.NewService(json.NewContext())
 
-		nodes[i] = testNode{
-			onet:    m,
-			service: srv,
-			pool:    pool,
-			db:      db,
-			dbpath:  dir,
-			signer:  c.GetSigner(),
-		}
+	rosterFac := authority.NewFactory(m.GetAddressFactory(), c.GetPublicKeyFactory())
+	RegisterRosterContract(exec, rosterFac, signer.GetSignatureFactory(), accessSrvc)
+
+	vs := simple.NewService(exec, txFac)
+
+	param := ServiceParam{
+		Mino:       m,
+		Cosi:       c,
+		Validation: vs,
+		Access:     accessSrvc,
+		Pool:       pool,
+		Tree:       tree,
+		DB:         db,
 	}
 
-	ro := authority.New(addrs, pubkeys)
+	srv, err := NewService(param, opts...)
+	require.NoError(t, err)
 
-	clean := func() {
-		for _, node := range nodes {
-			require.NoError(t, node.service.Close())
-			require.NoError(t, node.db.Close())
-			require.NoError(t, os.RemoveAll(node.dbpath))
-		}
+	return testNode{
+		onet:    m,
+		service: srv,
+		pool:    pool,
+		db:      db,
+		dbpath:  dir,
+		signer:  c.GetSigner(),
+		cosi:    c,
 	}
+}
 
-	return nodes, ro, clean
+func (node testNode) close(t *testing.T) {
+	require.NoError(t, node.service.Close())
+	require.NoError(t, node.db.Close())
+	require.NoError(t, os.RemoveAll(node.dbpath))
 }
 
 type badRosterFac struct {
@@ -1049,7 +1881,7 @@ func (val fakeValidation) Accept(store.Readable, txn.Transaction, validation.Lee
 	return val.err
 }
 
-func (val fakeValidation) Validate(store.Snapshot, []txn.Transaction) (validation.Result, error) {
+func (val fakeValidation) Validate(store.Snapshot, []txn.Transaction, ...validation.Option) (validation.Result, error) {
 	return simple.NewResult(nil), val.err
 }
 
@@ -1088,3 +1920,29 @@ type fakeAccess struct {
 func (srvc fakeAccess) Grant(store.Snapshot, access.Credential, ...access.Identity) error {
 	return srvc.err
 }
+
+type fakeClient struct {
+	nonce uint64
+}
+
+func (c fakeClient) GetNonce(access.Identity) (uint64, error) {
+	return c.nonce, nil
+}
+
+type fakeHistoricalTree struct {
+	fakeTree
+
+	errVersion error
+}
+
+func (t fakeHistoricalTree) GetVersion(root []byte) (hashtree.Tree, error) {
+	return t.fakeTree, t.errVersion
+}
+
+type badChainStore struct {
+	blockstore.BlockStore
+}
+
+func (s badChainStore) GetChainAt(uint64) (types.Chain, error) {
+	return nil, fake.GetError()
+}
@@ -0,0 +1,243 @@
+// This file contains the implementation of the observers, which are nodes that
+// follow the chain without being members of the roster.
+
+package cosipbft
+
+import (
+	"context"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/mino"
+	"golang.org/x/xerrors"
+)
+
+// Stream implements mino.Handler. It serves an observer that subscribes to the
+// chain. The genesis is sent first, then the blocks from the requested index,
+// and the new blocks as they are committed until the observer leaves.
+func (h *processor) Stream(out mino.Sender, in mino.Receiver) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	from, msg, err := in.Recv(ctx)
+	if err != nil {
+		return xerrors.Errorf("receiver failed: %v", err)
+	}
+
+	req, ok := msg.(types.SubscribeMessage)
+	if !ok {
+		return xerrors.Errorf("unexpected message '%T'", msg)
+	}
+
+	genesis, err := h.genesis.Get()
+	if err != nil {
+		return xerrors.Errorf("reading genesis: %v", err)
+	}
+
+	go func() {
+		// The subscription ends when the observer closes the stream.
+		for {
+			_, _, err := in.Recv(ctx)
+			if err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	// The watch starts before the stored blocks are read so that a block
+	// committed in between is not missed.
+	links := h.blocks.Watch(ctx)
+
+	err = <-out.Send(types.NewGenesisMessage(genesis), from)
+	if err != nil {
+		return xerrors.Errorf("failed to send genesis: %v", err)
+	}
+
+	next := req.GetFrom()
+
+	for ; next < h.blocks.Len(); next++ {
+		link, err := h.blocks.GetByIndex(next)
+		if err != nil {
+			return xerrors.Errorf("reading block %d: %v", next, err)
+		}
+
+		err = <-out.Send(types.NewLinkMessage(link), from)
+		if err != nil {
+			return xerrors.Errorf("failed to send block %d: %v", next, err)
+		}
+	}
+
+	for link := range links {
+		index := link.GetBlock().GetIndex()
+		if index < next {
+			continue
+		}
+
+		err = <-out.Send(types.NewLinkMessage(link), from)
+		if err != nil {
+			return xerrors.Errorf("failed to send block %d: %v", index, err)
+		}
+
+		next = index + 1
+	}
+
+	return nil
+}
+
+// observe follows the chain without taking part in the consensus. It subscribes
+// to one member at a time and moves to the next one when the subscription ends
+// or fails, until the service is closed.
+func (s *Service) observe() {
+	defer close(s.closed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-s.closing
+		cancel()
+	}()
+
+	backoff := float64(0)
+
+	for i := 0; ; i++ {
+		length := s.blocks.Len()
+
+		err := s.followNext(ctx, i)
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.logger.Warn().Err(err).Msg("subscription ended")
+
+		// The backoff is reset as long as the member sends new blocks.
+		if s.blocks.Len() > length {
+			backoff = 0
+		} else if calculateBackoff(backoff+1, s.roundWait) < s.roundMaxWait {
+			backoff++
+		}
+
+		select {
+		case <-s.clock.After(calculateBackoff(backoff, s.roundWait)):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// followNext subscribes to the member at the given position among the members
+// that can be followed.
+func (s *Service) followNext(ctx context.Context, i int) error {
+	members := s.observed
+
+	if s.genesis.Exists() {
+		roster, err := s.getCurrentRoster()
+		if err != nil {
+			return xerrors.Errorf("reading roster: %v", err)
+		}
+
+		members = nil
+
+		iter := roster.AddressIterator()
+		for iter.HasNext() {
+			members = append(members, iter.GetNext())
+		}
+	}
+
+	if len(members) == 0 {
+		return xerrors.New("no member to follow")
+	}
+
+	return s.follow(ctx, members[i%len(members)])
+}
+
+// follow subscribes to the member and applies the blocks it receives until the
+// stream ends. A block that cannot be verified ends the subscription.
+func (s *Service) follow(ctx context.Context, member mino.Address) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sender, rcvr, err := s.rpc.Stream(ctx, mino.NewAddresses(member))
+	if err != nil {
+		return xerrors.Errorf("stream failed: %v", err)
+	}
+
+	err = <-sender.Send(types.NewSubscribeMessage(s.blocks.Len()), member)
+	if err != nil {
+		return xerrors.Errorf("failed to subscribe: %v", err)
+	}
+
+	s.logger.Debug().Stringer("member", member).Msg("observer has subscribed")
+
+	for {
+		_, msg, err := rcvr.Recv(ctx)
+		if err != nil {
+			return xerrors.Errorf("receiver failed: %v", err)
+		}
+
+		switch in := msg.(type) {
+		case types.GenesisMessage:
+			err = s.observeGenesis(*in.GetGenesis())
+			if err != nil {
+				return xerrors.Errorf("genesis: %v", err)
+			}
+		case types.LinkMessage:
+			err = s.observeLink(in.GetLink())
+			if err != nil {
+				return xerrors.Errorf("block: %v", err)
+			}
+		default:
+			return xerrors.Errorf("unexpected message '%T'", msg)
+		}
+	}
+}
+
+// observeGenesis stores the genesis if the observer does not know the chain
+// yet, otherwise it makes sure the member follows the same chain. A genesis is
+// only learnt if it is the expected one, as a member could otherwise make the
+// observer follow any chain.
+func (s *Service) observeGenesis(genesis types.Genesis) error {
+	if s.genesis.Exists() {
+		return s.matchGenesis(genesis)
+	}
+
+	if genesis.GetHash() != s.observedHash {
+		return xerrors.Errorf("mismatch genesis '%v' != '%v'",
+			genesis.GetHash(), s.observedHash)
+	}
+
+	root := genesis.GetRoot()
+
+	err := s.storeGenesis(newGenesisParam(genesis), &root)
+	if xerrors.Is(err, errGenesisExists) {
+		return s.matchGenesis(genesis)
+	}
+
+	if err != nil {
+		return xerrors.Errorf("failed to store genesis: %v", err)
+	}
+
+	s.logger.Info().
+		Stringer("digest", genesis.GetHash()).
+		Msg("observer has learnt the genesis")
+
+	return nil
+}
+
+// observeLink verifies the collective signatures of the block link against the
+// current roster, and then applies the block to the tree and the block store.
+func (s *Service) observeLink(link types.BlockLink) error {
+	index := link.GetBlock().GetIndex()
+
+	if index < s.blocks.Len() {
+		// The block is already stored.
+		return nil
+	}
+
+	err := s.pbftsm.CatchUp(link)
+	if err != nil {
+		return xerrors.Errorf("invalid block %d: %v", index, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package cosipbft
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/minoch"
+	"go.dedis.ch/dela/serde"
+)
+
+func TestService_Scenario_Observer(t *testing.T) {
+	manager := minoch.NewManager()
+
+	nodes := make([]testNode, 3)
+	addrs := make([]mino.Address, len(nodes))
+	pubkeys := make([]crypto.PublicKey, len(nodes))
+
+	for i := range nodes {
+		nodes[i] = makeNode(t, manager, fmt.Sprintf("node%d", i))
+		defer nodes[i].close(t)
+
+		addrs[i] = nodes[i].onet.GetAddress()
+		pubkeys[i] = nodes[i].signer.GetPublicKey()
+	}
+
+	ro := authority.New(addrs, pubkeys)
+
+	genesis, err := nodes[0].service.MakeGenesis(ro)
+	require.NoError(t, err)
+
+	// The observer only knows about one member before it learns the genesis.
+	observer := makeNode(t, manager, "observer", WithObserver(genesis.GetHash(), addrs[1]))
+	defer observer.close(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := observer.service.Watch(ctx)
+
+	err = nodes[0].service.Setup(ctx, ro)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		err = nodes[0].pool.Add(makeTx(t, uint64(i), nodes[0].signer))
+		require.NoError(t, err)
+
+		evt := waitEvent(t, events)
+		require.Equal(t, uint64(i), evt.Index)
+	}
+
+	require.Equal(t, nodes[0].service.tree.Get().GetRoot(),
+		observer.service.tree.Get().GetRoot())
+
+	proof, err := observer.service.GetProof(keyRoster[:])
+	require.NoError(t, err)
+
+	checkProof(t, proof.(Proof), observer.service)
+
+	roster, err := nodes[0].service.GetRoster()
+	require.NoError(t, err)
+	require.Equal(t, 3, roster.Len())
+
+	_, err = observer.service.Process(mino.Request{Message: types.NewDone(types.Digest{}, nil)})
+	require.EqualError(t, err, "observer does not take part in the consensus")
+}
+
+func TestProcessor_Stream(t *testing.T) {
+	proc := newProcessor()
+	proc.genesis = blockstore.NewGenesisStore()
+	proc.blocks = blockstore.NewInMemory()
+
+	first := makeBlock(t, types.Digest{})
+	second := makeBlock(t, first.GetTo())
+
+	proc.blocks.Store(first)
+	proc.blocks.Store(second)
+
+	recv := fake.NewReceiver(fake.NewRecvMsg(fake.NewAddress(0), types.NewSubscribeMessage(1)))
+
+	err := proc.Stream(fake.Sender{}, recv)
+	require.EqualError(t, err, "reading genesis: missing genesis block")
+
+	proc.genesis.Set(types.Genesis{})
+
+	sender := &recordSender{}
+
+	recv = fake.NewReceiver(fake.NewRecvMsg(fake.NewAddress(0), types.NewSubscribeMessage(1)))
+
+	// The stream is closed by the receiver once the subscription is read, so
+	// that only the stored blocks are sent.
+	err = proc.Stream(sender, recv)
+	require.NoError(t, err)
+	require.Len(t, sender.msgs, 2)
+	require.IsType(t, types.GenesisMessage{}, sender.msgs[0])
+	require.Equal(t, types.NewLinkMessage(second), sender.msgs[1])
+
+	err = proc.Stream(fake.Sender{}, fake.NewBadReceiver())
+	require.EqualError(t, err, fake.Err("receiver failed"))
+
+	recv = fake.NewReceiver(fake.NewRecvMsg(fake.NewAddress(0), fake.Message{}))
+	err = proc.Stream(fake.Sender{}, recv)
+	require.EqualError(t, err, "unexpected message 'fake.Message'")
+
+	recv = fake.NewReceiver(fake.NewRecvMsg(fake.NewAddress(0), types.NewSubscribeMessage(1)))
+	err = proc.Stream(fake.NewBadSender(), recv)
+	require.EqualError(t, err, fake.Err("failed to send genesis"))
+
+	recv = fake.NewReceiver(fake.NewRecvMsg(fake.NewAddress(0), types.NewSubscribeMessage(1)))
+	err = proc.Stream(&recordSender{errAt: 1}, recv)
+	require.EqualError(t, err, fake.Err("failed to send block 1"))
+}
+
+func TestService_FollowNext(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.genesis = blockstore.NewGenesisStore()
+
+	err := srvc.followNext(context.Background(), 0)
+	require.EqualError(t, err, "no member to follow")
+
+	srvc.genesis.Set(types.Genesis{})
+	srvc.tree = blockstore.NewTreeCache(fakeTree{err: fake.GetError()})
+
+	err = srvc.followNext(context.Background(), 0)
+	require.EqualError(t, err, fake.Err("reading roster: read from tree"))
+}
+
+func TestService_Follow(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.pbftsm = fakeSM{}
+
+	srvc.genesis.Set(types.Genesis{})
+
+	ctx := context.Background()
+
+	msgs := []fake.ReceiverMessage{
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewGenesisMessage(types.Genesis{})),
+		fake.NewRecvMsg(fake.NewAddress(0), types.NewLinkMessage(makeBlock(t, types.Digest{}))),
+	}
+
+	srvc.rpc = fake.NewStreamRPC(fake.NewReceiver(msgs...), fake.Sender{})
+	err := srvc.follow(ctx, fake.NewAddress(0))
+	require.EqualError(t, err, "receiver failed: "+io.EOF.Error())
+
+	srvc.rpc = fake.NewBadRPC()
+	err = srvc.follow(ctx, fake.NewAddress(0))
+	require.EqualError(t, err, fake.Err("stream failed"))
+
+	srvc.rpc = fake.NewStreamRPC(fake.NewReceiver(), fake.NewBadSender())
+	err = srvc.follow(ctx, fake.NewAddress(0))
+	require.EqualError(t, err, fake.Err("failed to subscribe"))
+
+	srvc.rpc = fake.NewStreamRPC(fake.NewReceiver(fake.NewRecvMsg(nil, fake.Message{})), fake.Sender{})
+	err = srvc.follow(ctx, fake.NewAddress(0))
+	require.EqualError(t, err, "unexpected message 'fake.Message'")
+
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.genesis.Set(types.Genesis{})
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+
+	genesis, err := types.NewGenesis(authority.New(nil, nil))
+	require.NoError(t, err)
+
+	srvc.rpc = fake.NewStreamRPC(fake.NewReceiver(
+		fake.NewRecvMsg(nil, types.NewGenesisMessage(genesis))), fake.Sender{})
+	err = srvc.follow(ctx, fake.NewAddress(0))
+	require.EqualError(t, err,
+		fmt.Sprintf("genesis: mismatch genesis '%v' != '%v'", genesis.GetHash(), types.Digest{}))
+
+	srvc.pbftsm = fakeSM{err: fake.GetError()}
+	srvc.rpc = fake.NewStreamRPC(fake.NewReceiver(msgs[1]), fake.Sender{})
+	err = srvc.follow(ctx, fake.NewAddress(0))
+	require.EqualError(t, err, fake.Err("block: invalid block 0"))
+}
+
+func TestService_ObserveGenesis(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.genesis = blockstore.NewGenesisStore()
+	srvc.tree = blockstore.NewTreeCache(fakeTree{})
+
+	genesis, err := types.NewGenesis(authority.New(nil, nil))
+	require.NoError(t, err)
+
+	// Only the genesis of the chain to observe is accepted.
+	err = srvc.observeGenesis(genesis)
+	require.EqualError(t, err, fmt.Sprintf("mismatch genesis '%v' != '00000000'",
+		genesis.GetHash()))
+
+	// The tree root of the genesis does not match the one of the roster.
+	srvc.observedHash = genesis.GetHash()
+
+	err = srvc.observeGenesis(genesis)
+	require.EqualError(t, err, "failed to store genesis: mismatch tree root '00000000' != '726f6f74'")
+}
+
+func TestService_ObserveLink(t *testing.T) {
+	srvc := &Service{processor: newProcessor()}
+	srvc.blocks = blockstore.NewInMemory()
+	srvc.pbftsm = fakeSM{err: fake.GetError()}
+
+	link := makeBlock(t, types.Digest{})
+	srvc.blocks.Store(link)
+
+	// A block already stored is ignored.
+	err := srvc.observeLink(link)
+	require.NoError(t, err)
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type recordSender struct {
+	mino.Sender
+
+	msgs  []serde.Message
+	errAt int
+}
+
+func (s *recordSender) Send(msg serde.Message, addrs ...mino.Address) <-chan error {
+	errs := make(chan error, 1)
+
+	if s.errAt > 0 && len(s.msgs) == s.errAt {
+		errs <- fake.GetError()
+	}
+
+	s.msgs = append(s.msgs, msg)
+	close(errs)
+
+	return errs
+}
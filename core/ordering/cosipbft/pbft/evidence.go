@@ -0,0 +1,189 @@
+// This file contains the detection and the records of the misbehaviors of the
+// members of the roster.
+
+package pbft
+
+import (
+	"bytes"
+	"sort"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/kv"
+	"golang.org/x/xerrors"
+)
+
+var evidenceBucket = []byte("evidence")
+
+// GetEvidences implements pbft.StateMachine. It returns the evidences recorded
+// by the state machine, sorted by block index.
+func (m *pbftsm) GetEvidences() ([]types.Evidence, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	evidences := make([]types.Evidence, 0, len(m.evidences))
+
+	if m.db == nil {
+		for _, ev := range m.evidences {
+			evidences = append(evidences, ev)
+		}
+	} else {
+		fac := types.NewEvidenceFactory(m.addrFac, m.signer.GetPublicKeyFactory(),
+			m.signer.GetSignatureFactory())
+
+		err := m.db.View(func(tx kv.ReadableTx) error {
+			bucket := tx.GetBucket(evidenceBucket)
+			if bucket == nil {
+				return nil
+			}
+
+			return bucket.ForEach(func(key, value []byte) error {
+				ev, err := fac.EvidenceOf(m.context, value)
+				if err != nil {
+					return xerrors.Errorf("malformed evidence: %v", err)
+				}
+
+				evidences = append(evidences, ev)
+
+				return nil
+			})
+		})
+
+		if err != nil {
+			return nil, xerrors.Errorf("while reading: %v", err)
+		}
+	}
+
+	sort.SliceStable(evidences, func(i, j int) bool {
+		return evidences[i].GetIndex() < evidences[j].GetIndex()
+	})
+
+	return evidences, nil
+}
+
+// inspectView looks for a misbehavior of the author of a view, which is a
+// second view for a different leader after the same block. Views from unknown
+// peers are ignored as they are not members.
+func (m *pbftsm) inspectView(view View) {
+	roster, err := m.authReader(m.tree.Get())
+	if err != nil {
+		// The error is returned by the verification of the view.
+		return
+	}
+
+	pubkey, _ := roster.GetPublicKey(view.from)
+	if pubkey == nil {
+		return
+	}
+
+	// A view with an invalid signature proves nothing about the member as
+	// anyone can forge it.
+	err = view.Verify(pubkey)
+	if err != nil {
+		return
+	}
+
+	prev, found := m.round.views[view.from]
+	if !found || prev.id != view.id || prev.leader == view.leader {
+		return
+	}
+
+	// The evidence is about the round that follows the block of the views.
+	height, err := m.getHeight(view.id)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("failed to read height of view")
+		return
+	}
+
+	m.report(types.EvidenceParam{
+		Kind:      types.ConflictingView,
+		Index:     height,
+		Offender:  view.from,
+		PublicKey: pubkey,
+		Statements: []types.Statement{
+			types.NewStatement(prev.id, prev.leader, prev.signature),
+			types.NewStatement(view.id, view.leader, view.signature),
+		},
+	})
+}
+
+// getHeight returns the number of blocks of the chain up to the block with the
+// given digest, which is zero for the genesis block.
+func (m *pbftsm) getHeight(id types.Digest) (uint64, error) {
+	genesis, err := m.genesis.Get()
+	if err != nil {
+		return 0, xerrors.Errorf("failed to read genesis: %v", err)
+	}
+
+	if genesis.GetHash() == id {
+		return 0, nil
+	}
+
+	link, err := m.blocks.Get(id)
+	if err != nil {
+		return 0, xerrors.Errorf("failed to read block: %v", err)
+	}
+
+	return link.GetBlock().GetIndex() + 1, nil
+}
+
+// report verifies the statements of the evidence, signs it and records it. The
+// same evidence is recorded only once. A failure is only logged so that the
+// round is not disturbed.
+func (m *pbftsm) report(param types.EvidenceParam) {
+	ev, err := types.NewEvidenceAndSign(param, m.signer)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("failed to sign evidence")
+		return
+	}
+
+	err = ev.VerifyStatements()
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("invalid evidence")
+		return
+	}
+
+	m.logger.Warn().
+		Stringer("kind", param.Kind).
+		Uint64("index", param.Index).
+		Stringer("offender", param.Offender).
+		Msg("misbehavior detected")
+
+	// The fingerprint is used as the key so that an evidence is not recorded
+	// twice when a message is received again.
+	key := new(bytes.Buffer)
+
+	err = ev.Fingerprint(key)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("failed to fingerprint evidence")
+		return
+	}
+
+	if m.db == nil {
+		if m.evidences == nil {
+			m.evidences = make(map[string]types.Evidence)
+		}
+
+		m.evidences[key.String()] = ev
+
+		return
+	}
+
+	data, err := ev.Serialize(m.context)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("failed to serialize evidence")
+		return
+	}
+
+	err = m.db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(evidenceBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket: %v", err)
+		}
+
+		return bucket.Set(key.Bytes(), data)
+	})
+
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("failed to record evidence")
+	}
+}
@@ -0,0 +1,278 @@
+package pbft
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/mino"
+)
+
+func TestStateMachine_ConflictingPrepare(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	leader := bls.NewSigner()
+	witness := bls.NewSigner()
+
+	ro := authority.New(
+		[]mino.Address{fake.NewAddress(0), fake.NewAddress(1), fake.NewAddress(2)},
+		[]crypto.PublicKey{leader.GetPublicKey(), fake.PublicKey{}, fake.PublicKey{}},
+	)
+
+	param := makeJournalParam(tree, db)
+	param.Signer = witness
+	param.AuthorityReader = func(hashtree.Tree) (authority.Authority, error) {
+		return ro, nil
+	}
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = InitialState
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root), types.WithIndex(0))
+	require.NoError(t, err)
+
+	other, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(types.Digest{1}), types.WithIndex(0))
+	require.NoError(t, err)
+
+	sig, err := leader.Sign(types.ProposalBytes(0, block.GetHash()))
+	require.NoError(t, err)
+
+	otherSig, err := leader.Sign(types.ProposalBytes(0, other.GetHash()))
+	require.NoError(t, err)
+
+	_, err = sm.Prepare(fake.NewAddress(0), block, otherSig, nil)
+	require.Error(t, err)
+	require.Regexp(t, "^invalid proposal signature: ", err.Error())
+
+	id, err := sm.Prepare(fake.NewAddress(0), block, sig, nil)
+	require.NoError(t, err)
+
+	evidences, err := sm.GetEvidences()
+	require.NoError(t, err)
+	require.Empty(t, evidences)
+
+	// The candidate accepted first is kept, and the evidence is recorded only
+	// once even if the conflicting block is received again.
+	for i := 0; i < 2; i++ {
+		res, err := sm.Prepare(fake.NewAddress(0), other, otherSig, nil)
+		require.NoError(t, err)
+		require.Equal(t, id, res)
+	}
+
+	evidences, err = sm.GetEvidences()
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+	require.Equal(t, types.ConflictingPrepare, evidences[0].GetKind())
+	require.Equal(t, uint64(0), evidences[0].GetIndex())
+	require.Equal(t, fake.NewAddress(0), evidences[0].GetOffender())
+	require.True(t, leader.GetPublicKey().Equal(evidences[0].GetPublicKey()))
+
+	statements := evidences[0].GetStatements()
+	require.Len(t, statements, 2)
+	require.Equal(t, block.GetHash(), statements[0].GetID())
+	require.Equal(t, other.GetHash(), statements[1].GetID())
+	require.NoError(t, evidences[0].VerifyStatements())
+	require.NoError(t, evidences[0].Verify(witness.GetPublicKey()))
+
+	// The leader does not report itself when its state machine sees another
+	// proposal for the same index.
+	sm.signer = leader
+
+	third, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(types.Digest{2}), types.WithIndex(0))
+	require.NoError(t, err)
+
+	thirdSig, err := leader.Sign(types.ProposalBytes(0, third.GetHash()))
+	require.NoError(t, err)
+
+	_, err = sm.Prepare(fake.NewAddress(0), third, thirdSig, nil)
+	require.NoError(t, err)
+
+	evidences, err = sm.GetEvidences()
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+}
+
+func TestStateMachine_Views_Evidence(t *testing.T) {
+	ro := authority.FromAuthority(fake.NewAuthority(4, fake.NewSigner))
+
+	sm := &pbftsm{
+		state:   ViewChangeState,
+		blocks:  blockstore.NewInMemory(),
+		genesis: blockstore.NewGenesisStore(),
+		watcher: core.NewWatcher(),
+		signer:  fake.NewSigner(),
+		tree:    blockstore.NewTreeCache(badTree{}),
+		authReader: func(hashtree.Tree) (authority.Authority, error) {
+			return ro, nil
+		},
+	}
+
+	sm.genesis.Set(types.Genesis{})
+	sm.round.threshold = 2
+
+	err := sm.Accept(View{from: fake.NewAddress(1), leader: 1})
+	require.NoError(t, err)
+
+	// A view for the same leader after another block is not an evidence.
+	err = sm.Accept(View{from: fake.NewAddress(1), leader: 1, id: types.Digest{1}})
+	require.EqualError(t, err, "invalid view: mismatch id 01000000 != 00000000")
+
+	err = sm.Accept(View{from: fake.NewAddress(1), leader: 2})
+	require.EqualError(t, err, "invalid view: mismatch leader 2 != 1")
+
+	// Views from unknown peers are not an evidence.
+	err = sm.Accept(View{from: fake.NewAddress(5), leader: 1})
+	require.EqualError(t, err, "invalid view: unknown peer: fake.Address[5]")
+
+	sm.authReader = func(hashtree.Tree) (authority.Authority, error) {
+		ro := authority.New(
+			[]mino.Address{fake.NewAddress(2)},
+			[]crypto.PublicKey{fake.NewBadPublicKey()},
+		)
+		return ro, nil
+	}
+
+	// A view with an invalid signature is not an evidence as anyone could
+	// have forged it.
+	err = sm.Accept(View{from: fake.NewAddress(2), leader: 1})
+	require.EqualError(t, err, fake.Err("invalid view: invalid signature: verify"))
+
+	evidences, err := sm.GetEvidences()
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+	require.Equal(t, types.ConflictingView, evidences[0].GetKind())
+	require.Equal(t, uint64(0), evidences[0].GetIndex())
+	require.Equal(t, fake.NewAddress(1), evidences[0].GetOffender())
+
+	statements := evidences[0].GetStatements()
+	require.Len(t, statements, 2)
+	require.Equal(t, types.Digest{}, statements[0].GetID())
+	require.Equal(t, uint16(1), statements[0].GetLeader())
+	require.Equal(t, types.Digest{}, statements[1].GetID())
+	require.Equal(t, uint16(2), statements[1].GetLeader())
+	require.NoError(t, evidences[0].VerifyStatements())
+
+	// The views after a block that is not known are not reported.
+	sm.authReader = func(hashtree.Tree) (authority.Authority, error) {
+		return ro, nil
+	}
+
+	sm.round.views[fake.NewAddress(2)] = View{from: fake.NewAddress(2), leader: 1,
+		id: types.Digest{2}}
+
+	logger, check := fake.CheckLog("failed to read height of view")
+	sm.logger = logger
+
+	sm.inspectView(View{from: fake.NewAddress(2), leader: 2, id: types.Digest{2}})
+	check(t)
+	require.Len(t, sm.evidences, 1)
+
+	// A roster that cannot be read stops the inspection of the views.
+	sm.authReader = badReader
+	sm.inspectView(View{from: fake.NewAddress(2), leader: 1})
+	require.Len(t, sm.evidences, 1)
+}
+
+func TestStateMachine_GetHeight(t *testing.T) {
+	sm := &pbftsm{
+		blocks:  blockstore.NewInMemory(),
+		genesis: blockstore.NewGenesisStore(),
+	}
+
+	_, err := sm.getHeight(types.Digest{})
+	require.EqualError(t, err, "failed to read genesis: missing genesis block")
+
+	sm.genesis.Set(types.Genesis{})
+
+	height, err := sm.getHeight(types.Digest{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), height)
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithIndex(0))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(types.Digest{}, block)
+	require.NoError(t, err)
+	require.NoError(t, sm.blocks.Store(link))
+
+	height, err = sm.getHeight(block.GetHash())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), height)
+
+	_, err = sm.getHeight(types.Digest{1})
+	require.EqualError(t, err, "failed to read block: block not found: "+
+		"no block")
+}
+
+func TestStateMachine_GetEvidences(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	sm := NewStateMachine(makeJournalParam(tree, db)).(*pbftsm)
+
+	err := db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(evidenceBucket)
+		require.NoError(t, err)
+
+		return bucket.Set([]byte("key"), []byte("{"))
+	})
+	require.NoError(t, err)
+
+	_, err = sm.GetEvidences()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "while reading: malformed evidence: ")
+}
+
+func TestStateMachine_Report(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	sm := NewStateMachine(makeJournalParam(tree, db)).(*pbftsm)
+
+	param := types.EvidenceParam{
+		Kind:     types.ConflictingView,
+		Offender: fake.NewAddress(0),
+		Statements: []types.Statement{
+			types.NewStatement(types.Digest{1}, 1, fake.Signature{}),
+			types.NewStatement(types.Digest{1}, 2, fake.Signature{}),
+		},
+	}
+
+	sm.signer = fake.NewBadSigner()
+	sm.report(param)
+
+	sm.signer = fake.NewSignerWithSignatureFactory(fake.NewSignatureFactory(fake.Signature{}))
+
+	// The evidence is not recorded when the statements cannot be verified.
+	logger, check := fake.CheckLog("invalid evidence")
+	sm.logger = logger
+	sm.report(param)
+	check(t)
+
+	param.PublicKey = fake.PublicKey{}
+	sm.db = fake.NewBadDB()
+	sm.report(param)
+
+	sm.db = db
+	evidences, err := sm.GetEvidences()
+	require.NoError(t, err)
+	require.Empty(t, evidences)
+
+	sm.report(param)
+	evidences, err = sm.GetEvidences()
+	require.NoError(t, err)
+	require.Len(t, evidences, 1)
+}
@@ -0,0 +1,360 @@
+// This file contains the journal of the rounds so that a node that restarts
+// resumes the round where it stopped.
+
+package pbft
+
+import (
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/mino"
+	"golang.org/x/xerrors"
+)
+
+var (
+	journalBucket = []byte("pbft")
+	journalKey    = []byte("round")
+)
+
+// journalJSON is the representation of the round written to the database.
+type journalJSON struct {
+	// Index is the index of the block the round is working on, which allows
+	// to detect that the round has been finalized.
+	Index      uint64
+	State      State
+	Leader     uint16
+	Committed  bool
+	Block      []byte          `json:",omitempty"`
+	PrepareSig []byte          `json:",omitempty"`
+	Proposal   []byte          `json:",omitempty"`
+	Views      []viewJSON      `json:",omitempty"`
+	PrevViews  []viewJSON      `json:",omitempty"`
+	ViewChange *viewChangeJSON `json:",omitempty"`
+}
+
+type viewJSON struct {
+	From      []byte
+	ID        []byte
+	Leader    uint16
+	Signature []byte
+}
+
+type viewChangeJSON struct {
+	ID         []byte
+	Leader     uint16
+	Indices    []uint16
+	Signatures [][]byte
+}
+
+// Load implements pbft.StateMachine. It restores the round from the journal if
+// any. A candidate that has been accepted is verified again so that the round
+// can be completed.
+func (m *pbftsm) Load() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.db == nil {
+		return nil
+	}
+
+	var data []byte
+
+	err := m.db.View(func(tx kv.ReadableTx) error {
+		bucket := tx.GetBucket(journalBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		value := bucket.Get(journalKey)
+		if value != nil {
+			data = append([]byte{}, value...)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return xerrors.Errorf("while reading journal: %v", err)
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	var j journalJSON
+
+	err = m.context.Unmarshal(data, &j)
+	if err != nil {
+		return xerrors.Errorf("malformed journal: %v", err)
+	}
+
+	m.round.leader = j.Leader
+
+	if j.Index != m.blocks.Len() || j.State == NoneState {
+		// The block of the round has been stored before the node stopped so
+		// only the leader is still relevant.
+		if j.Index != m.blocks.Len() {
+			err = m.rotateLeader()
+			if err != nil {
+				return xerrors.Errorf("leader policy: %v", err)
+			}
+		}
+
+		err = m.refreshRound()
+		if err != nil {
+			return xerrors.Errorf("refresh round: %v", err)
+		}
+
+		if j.State != NoneState {
+			m.setState(InitialState)
+		}
+
+		return nil
+	}
+
+	roster, err := m.authReader(m.tree.Get())
+	if err != nil {
+		return xerrors.Errorf("failed to read roster: %v", err)
+	}
+
+	m.round.threshold = calculateThreshold(roster.Len())
+
+	m.round.views, err = m.decodeViews(j.Views)
+	if err != nil {
+		return xerrors.Errorf("views: %v", err)
+	}
+
+	m.round.prevViews, err = m.decodeViews(j.PrevViews)
+	if err != nil {
+		return xerrors.Errorf("previous views: %v", err)
+	}
+
+	if j.Block != nil {
+		msg, err := m.blockFac.Deserialize(m.context, j.Block)
+		if err != nil {
+			return xerrors.Errorf("malformed block: %v", err)
+		}
+
+		block, ok := msg.(types.Block)
+		if !ok {
+			return xerrors.Errorf("invalid block '%T'", msg)
+		}
+
+		vc, err := m.decodeViewChange(j.ViewChange)
+		if err != nil {
+			return xerrors.Errorf("view change: %v", err)
+		}
+
+		// The candidate is applied again to recover the staged tree that will
+		// be committed when the block is finalized.
+		err = m.verifyPrepare(m.tree.Get(), block, vc, &m.round, roster)
+		if err != nil {
+			return xerrors.Errorf("replaying candidate: %v", err)
+		}
+	}
+
+	if j.Proposal != nil {
+		sigFac := m.signer.GetSignatureFactory()
+
+		m.round.proposal, err = sigFac.SignatureOf(m.context, j.Proposal)
+		if err != nil {
+			return xerrors.Errorf("malformed proposal: %v", err)
+		}
+	}
+
+	if j.PrepareSig != nil {
+		m.round.prepareSig, err = m.sigFac.SignatureOf(m.context, j.PrepareSig)
+		if err != nil {
+			return xerrors.Errorf("malformed signature: %v", err)
+		}
+	}
+
+	m.round.committed = j.Committed
+
+	m.setState(j.State)
+
+	m.logger.Info().
+		Stringer("state", j.State).
+		Uint16("leader", j.Leader).
+		Msg("round restored")
+
+	return nil
+}
+
+// journal writes the current round with the given state to the database. It
+// must be called before the node shares anything about the new state, so that
+// it does not contradict itself after a restart.
+func (m *pbftsm) journal(state State) error {
+	if m.db == nil {
+		return nil
+	}
+
+	j := journalJSON{
+		Index:     m.blocks.Len(),
+		State:     state,
+		Leader:    m.round.leader,
+		Committed: m.round.committed,
+	}
+
+	var err error
+
+	if state == PrepareState || state == CommitState || m.round.committed {
+		j.Block, err = m.round.block.Serialize(m.context)
+		if err != nil {
+			return xerrors.Errorf("failed to serialize block: %v", err)
+		}
+
+		if m.round.proposal != nil {
+			j.Proposal, err = m.round.proposal.Serialize(m.context)
+			if err != nil {
+				return xerrors.Errorf("failed to serialize proposal: %v", err)
+			}
+		}
+
+		j.ViewChange, err = m.encodeViewChange(m.round.viewChange)
+		if err != nil {
+			return xerrors.Errorf("view change: %v", err)
+		}
+	}
+
+	if m.round.committed {
+		j.PrepareSig, err = m.round.prepareSig.Serialize(m.context)
+		if err != nil {
+			return xerrors.Errorf("failed to serialize signature: %v", err)
+		}
+	}
+
+	j.Views, err = m.encodeViews(m.round.views)
+	if err != nil {
+		return xerrors.Errorf("views: %v", err)
+	}
+
+	j.PrevViews, err = m.encodeViews(m.round.prevViews)
+	if err != nil {
+		return xerrors.Errorf("previous views: %v", err)
+	}
+
+	data, err := m.context.Marshal(j)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	err = m.db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(journalBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket: %v", err)
+		}
+
+		return bucket.Set(journalKey, data)
+	})
+
+	if err != nil {
+		return xerrors.Errorf("while writing: %v", err)
+	}
+
+	return nil
+}
+
+func (m *pbftsm) encodeViews(views map[mino.Address]View) ([]viewJSON, error) {
+	res := make([]viewJSON, 0, len(views))
+
+	for from, view := range views {
+		addr, err := from.MarshalText()
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize address: %v", err)
+		}
+
+		sig, err := view.signature.Serialize(m.context)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize signature: %v", err)
+		}
+
+		res = append(res, viewJSON{
+			From:      addr,
+			ID:        view.id.Bytes(),
+			Leader:    view.leader,
+			Signature: sig,
+		})
+	}
+
+	return res, nil
+}
+
+func (m *pbftsm) decodeViews(raws []viewJSON) (map[mino.Address]View, error) {
+	if len(raws) == 0 {
+		return nil, nil
+	}
+
+	views := make(map[mino.Address]View)
+
+	for _, raw := range raws {
+		sig, err := m.signer.GetSignatureFactory().SignatureOf(m.context, raw.Signature)
+		if err != nil {
+			return nil, xerrors.Errorf("malformed signature: %v", err)
+		}
+
+		id := types.Digest{}
+		copy(id[:], raw.ID)
+
+		param := ViewParam{
+			From:   m.addrFac.FromText(raw.From),
+			ID:     id,
+			Leader: raw.Leader,
+		}
+
+		views[param.From] = NewView(param, sig)
+	}
+
+	return views, nil
+}
+
+func (m *pbftsm) encodeViewChange(vc *types.ViewChange) (*viewChangeJSON, error) {
+	if vc == nil {
+		return nil, nil
+	}
+
+	sigs := vc.GetSignatures()
+	raws := make([][]byte, len(sigs))
+
+	for i, sig := range sigs {
+		raw, err := sig.Serialize(m.context)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize signature: %v", err)
+		}
+
+		raws[i] = raw
+	}
+
+	res := &viewChangeJSON{
+		ID:         vc.GetID().Bytes(),
+		Leader:     vc.GetLeader(),
+		Indices:    vc.GetIndices(),
+		Signatures: raws,
+	}
+
+	return res, nil
+}
+
+func (m *pbftsm) decodeViewChange(raw *viewChangeJSON) (*types.ViewChange, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	sigs := make([]crypto.Signature, len(raw.Signatures))
+
+	for i, data := range raw.Signatures {
+		sig, err := m.signer.GetSignatureFactory().SignatureOf(m.context, data)
+		if err != nil {
+			return nil, xerrors.Errorf("malformed signature: %v", err)
+		}
+
+		sigs[i] = sig
+	}
+
+	id := types.Digest{}
+	copy(id[:], raw.ID)
+
+	vc := types.NewViewChange(id, raw.Leader, raw.Indices, sigs)
+
+	return &vc, nil
+}
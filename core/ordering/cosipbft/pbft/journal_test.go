@@ -0,0 +1,262 @@
+package pbft
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde/json"
+)
+
+func TestStateMachine_Load(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	param := makeJournalParam(tree, db)
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root), types.WithIndex(0))
+	require.NoError(t, err)
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = InitialState
+
+	// Nothing has been written yet so the machine is left untouched.
+	err = sm.Load()
+	require.NoError(t, err)
+	require.Equal(t, InitialState, sm.state)
+
+	id, err := sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
+	require.NoError(t, err)
+
+	restarted := NewStateMachine(param).(*pbftsm)
+	err = restarted.Load()
+	require.NoError(t, err)
+	require.Equal(t, PrepareState, restarted.state)
+	require.Equal(t, id, restarted.round.id)
+	require.Equal(t, 2, restarted.round.threshold)
+	require.False(t, restarted.round.committed)
+
+	err = sm.Commit(id, fake.Signature{})
+	require.NoError(t, err)
+
+	restarted = NewStateMachine(param).(*pbftsm)
+	err = restarted.Load()
+	require.NoError(t, err)
+	require.Equal(t, CommitState, restarted.state)
+	require.Equal(t, id, restarted.round.id)
+	require.True(t, restarted.round.committed)
+	require.NotNil(t, restarted.round.prepareSig)
+
+	// The restored round can be finalized.
+	err = restarted.Finalize(id, fake.Signature{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), param.Blocks.Len())
+
+	restarted = NewStateMachine(param).(*pbftsm)
+	err = restarted.Load()
+	require.NoError(t, err)
+	require.Equal(t, InitialState, restarted.state)
+
+	view, err := restarted.Expire(fake.NewAddress(0))
+	require.NoError(t, err)
+
+	restarted = NewStateMachine(param).(*pbftsm)
+	err = restarted.Load()
+	require.NoError(t, err)
+	require.Equal(t, ViewChangeState, restarted.state)
+	require.Equal(t, uint16(0), restarted.round.leader)
+	require.Len(t, restarted.round.views, 1)
+	require.Equal(t, view.GetLeader(), restarted.round.views[fake.NewAddress(0)].GetLeader())
+	require.Equal(t, view.GetID(), restarted.round.views[fake.NewAddress(0)].GetID())
+
+	// The journal is about a block that is not stored yet, which means the
+	// database is behind the journal, and only the leader is restored.
+	param.Blocks = blockstore.NewInMemory()
+	restarted = NewStateMachine(param).(*pbftsm)
+	err = restarted.Load()
+	require.NoError(t, err)
+	require.Equal(t, InitialState, restarted.state)
+	require.Nil(t, restarted.round.views)
+}
+
+func TestStateMachine_ViewChange_Load(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	param := makeJournalParam(tree, db)
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root), types.WithIndex(0))
+	require.NoError(t, err)
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = InitialState
+	sm.round.leader = 1
+	sm.round.prevViews = map[mino.Address]View{
+		fake.NewAddress(0): {leader: 1, signature: fake.Signature{}},
+	}
+
+	sigs := []crypto.Signature{fake.Signature{}, fake.Signature{}, fake.Signature{}}
+	vc := types.NewViewChange(types.Digest{}, 1, []uint16{0, 2, 3}, sigs)
+
+	id, err := sm.Prepare(fake.NewAddress(1), block, fake.Signature{}, &vc)
+	require.NoError(t, err)
+
+	// The certificate is restored so that the candidate has the same digest.
+	restarted := NewStateMachine(param).(*pbftsm)
+	err = restarted.Load()
+	require.NoError(t, err)
+	require.Equal(t, PrepareState, restarted.state)
+	require.Equal(t, id, restarted.round.id)
+	require.Equal(t, &vc, restarted.round.viewChange)
+
+	restarted = NewStateMachine(param).(*pbftsm)
+	restarted.signer = fake.NewSignerWithSignatureFactory(fake.NewBadSignatureFactory())
+	err = restarted.Load()
+	require.EqualError(t, err, fake.Err("previous views: malformed signature"))
+}
+
+func TestStateMachine_WithoutDB_Load(t *testing.T) {
+	sm := NewStateMachine(StateMachineParam{}).(*pbftsm)
+
+	err := sm.Load()
+	require.NoError(t, err)
+	require.Equal(t, NoneState, sm.state)
+}
+
+func TestStateMachine_MalformedJournal_Load(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	param := makeJournalParam(tree, db)
+
+	writeJournal(t, db, []byte("{"))
+
+	sm := NewStateMachine(param).(*pbftsm)
+	err := sm.Load()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed journal: ")
+
+	writeJournal(t, db, []byte(`{"Index":0,"State":3,"Block":"AA=="}`))
+	err = sm.Load()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "malformed block: ")
+
+	writeJournal(t, db, []byte(`{"Index":0,"State":4,"Views":[{"Signature":"e30="}]}`))
+	sm.signer = fake.NewSignerWithSignatureFactory(fake.NewBadSignatureFactory())
+	err = sm.Load()
+	require.EqualError(t, err, fake.Err("views: malformed signature"))
+
+	sm.authReader = badReader
+	err = sm.Load()
+	require.EqualError(t, err, fake.Err("failed to read roster"))
+
+	writeJournal(t, db, []byte(`{"Index":1,"State":3}`))
+	err = sm.Load()
+	require.EqualError(t, err, fake.Err("refresh round: failed to read roster"))
+}
+
+func TestStateMachine_FailWrite_Journal(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	sm := NewStateMachine(makeJournalParam(tree, db)).(*pbftsm)
+	sm.state = InitialState
+
+	sm.round.views = map[mino.Address]View{
+		fake.NewBadAddress(): {signature: fake.Signature{}},
+	}
+	err := sm.journal(InitialState)
+	require.EqualError(t, err, fake.Err("views: failed to serialize address"))
+
+	sm.round.views = map[mino.Address]View{
+		fake.NewAddress(0): {signature: fake.NewBadSignature()},
+	}
+	err = sm.journal(InitialState)
+	require.EqualError(t, err, fake.Err("views: failed to serialize signature"))
+
+	sm.round.views = nil
+	sm.round.committed = true
+	sm.round.block, err = types.NewBlock(simple.NewResult(nil))
+	require.NoError(t, err)
+
+	sm.round.prepareSig = fake.NewBadSignature()
+	sm.context = fake.NewBadContext()
+	err = sm.journal(InitialState)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to serialize block: ")
+
+	sm.context = json.NewContext()
+
+	vc := types.NewViewChange(types.Digest{}, 0, []uint16{0}, []crypto.Signature{fake.NewBadSignature()})
+	sm.round.viewChange = &vc
+	err = sm.journal(InitialState)
+	require.EqualError(t, err, fake.Err("view change: failed to serialize signature"))
+
+	sm.round.viewChange = nil
+	err = sm.journal(InitialState)
+	require.EqualError(t, err, fake.Err("failed to serialize signature"))
+
+	sm.round.committed = false
+	sm.db = fake.NewBadDB()
+	err = sm.journal(InitialState)
+	require.EqualError(t, err, fake.Err("while writing: bucket"))
+
+	_, err = sm.Expire(fake.NewAddress(0))
+	require.EqualError(t, err, fake.Err("journal failed: while writing: bucket"))
+
+	// The state and the round are left untouched when the journal fails.
+	require.Equal(t, InitialState, sm.state)
+	require.Nil(t, sm.round.views)
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeJournalParam(tree hashtree.Tree, db kv.DB) StateMachineParam {
+	ro := authority.FromAuthority(fake.NewAuthority(4, fake.NewSigner))
+
+	param := StateMachineParam{
+		Validation:      simple.NewService(fakeExec{}, nil),
+		VerifierFactory: fake.NewVerifierFactory(fake.Verifier{}),
+		Signer:          fake.NewSignerWithSignatureFactory(fake.NewSignatureFactory(fake.Signature{})),
+		Blocks:          blockstore.NewInMemory(),
+		Genesis:         blockstore.NewGenesisStore(),
+		Tree:            blockstore.NewTreeCache(tree),
+		AuthorityReader: func(hashtree.Tree) (authority.Authority, error) {
+			return ro, nil
+		},
+		DB:               db,
+		BlockFactory:     types.NewBlockFactory(simple.NewResultFactory(signed.NewTransactionFactory())),
+		SignatureFactory: fake.NewSignatureFactory(fake.Signature{}),
+		AddressFactory:   fake.AddressFactory{},
+	}
+
+	param.Genesis.Set(types.Genesis{})
+
+	return param
+}
+
+func writeJournal(t *testing.T, db kv.DB, data []byte) {
+	err := db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(journalBucket)
+		require.NoError(t, err)
+
+		return bucket.Set(journalKey, data)
+	})
+	require.NoError(t, err)
+}
@@ -10,13 +10,19 @@
 // participants to comply to the 2f threshold, or if a catch up that provides a
 // proof of acceptance of the block.
 //
+// When a database is provided, every transition of the round is written to a
+// journal before the node can share it, so that a node that restarts resumes
+// the round instead of signing a conflicting candidate.
+//
 // Documentation Last Review: 13.10.2020
 //
 package pbft
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 	"go.dedis.ch/dela/core"
@@ -29,6 +35,8 @@ import (
 	"go.dedis.ch/dela/core/validation"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
 	"golang.org/x/xerrors"
 )
 
@@ -91,9 +99,22 @@ type StateMachine interface {
 	// undefined.
 	GetCommit() (types.Digest, types.Block)
 
+	// GetProposal returns the signature of the leader over the candidate
+	// block if the state machine is at least in the prepare state, otherwise
+	// the behaviour is undefined.
+	GetProposal() crypto.Signature
+
+	// GetViewChange returns the certificate of the view change that elected
+	// the leader of the round, or nil if the leader did not change. It is the
+	// one of the candidate if the state machine is at least in the prepare
+	// state.
+	GetViewChange() (*types.ViewChange, error)
+
 	// Prepare processes the candidate block and moves the state machine if it
-	// is valid and from the correct leader.
-	Prepare(from mino.Address, block types.Block) (types.Digest, error)
+	// is valid and signed by the correct leader. The certificate of the view
+	// change is required if the leader has been elected by one.
+	Prepare(from mino.Address, block types.Block, sig crypto.Signature,
+		vc *types.ViewChange) (types.Digest, error)
 
 	// Commit moves the state machine to the next state if the signature is
 	// valid for the candidate.
@@ -118,6 +139,19 @@ type StateMachine interface {
 	// doing the intermediate phases.
 	CatchUp(types.BlockLink) error
 
+	// GetEvidences returns the evidences of misbehavior of the members of the
+	// roster that have been detected.
+	GetEvidences() ([]types.Evidence, error)
+
+	// Load restores the round that the state machine was working on before
+	// the node stopped, if any.
+	Load() error
+
+	// Restore forces the state machine to start from the latest block of a
+	// verified chain and the tree it refers to, without replaying the previous
+	// blocks.
+	Restore(chain types.Chain, tree hashtree.StagingTree) error
+
 	// Watch returns a channel that is populated with the changes of states from
 	// the state machine.
 	Watch(context.Context) <-chan State
@@ -134,12 +168,47 @@ type round struct {
 	committed  bool
 	prevViews  map[mino.Address]View
 	views      map[mino.Address]View
+
+	// proposal is the signature of the block by the leader, which proves an
+	// equivocation if it proposes another block for the same index.
+	proposal crypto.Signature
+
+	// viewChange is the certificate of the view change that elected the
+	// leader of the round, if any. It is sent by the leader with its proposal
+	// and it is part of the link of the block.
+	viewChange *types.ViewChange
+}
+
+// clone returns a copy of the round that is not affected by the changes of the
+// views of the original.
+func (r round) clone() round {
+	r.views = copyViews(r.views)
+	r.prevViews = copyViews(r.prevViews)
+
+	return r
+}
+
+func copyViews(views map[mino.Address]View) map[mino.Address]View {
+	if views == nil {
+		return nil
+	}
+
+	cp := make(map[mino.Address]View, len(views))
+	for from, view := range views {
+		cp[from] = view
+	}
+
+	return cp
 }
 
 // AuthorityReader is a function to help the state machine to read the current
 // authority for a given tree.
 type AuthorityReader func(tree hashtree.Tree) (authority.Authority, error)
 
+// LimitsReader is a function to help the state machine to read the limits of
+// the blocks for a given tree.
+type LimitsReader func(tree hashtree.Tree) (types.Limits, error)
+
 // pbftsm is an implementation of a state machine to perform PBFT rounds.
 //
 // - implements pbft.Statemachine
@@ -154,15 +223,29 @@ type pbftsm struct {
 	genesis    blockstore.GenesisStore
 	tree       blockstore.TreeCache
 	authReader AuthorityReader
+	policy     PolicyReader
+	limits     LimitsReader
 	db         kv.DB
+	context    serde.Context
+	blockFac   serde.Factory
+	sigFac     crypto.SignatureFactory
+	addrFac    mino.AddressFactory
 
 	// verifierFac creates a verifier for the aggregated signature.
 	verifierFac crypto.VerifierFactory
 	// signer signs and verify single signature for the view change.
 	signer crypto.Signer
 
+	// timestampDrift is the maximum difference between the timestamp of a
+	// candidate and the local clock, or zero to accept any.
+	timestampDrift time.Duration
+	clock          core.Clock
+
 	state State
 	round round
+
+	// evidences are kept in memory when the state machine has no database.
+	evidences map[string]types.Evidence
 }
 
 // StateMachineParam is a structure to pass the different components of the PBFT
@@ -177,10 +260,36 @@ type StateMachineParam struct {
 	Tree            blockstore.TreeCache
 	AuthorityReader AuthorityReader
 	DB              kv.DB
+
+	// PolicyReader returns the policy that rotates the leader after each
+	// block. The leader is kept until a view change when it is not set.
+	PolicyReader PolicyReader
+
+	// LimitsReader returns the limits that bound the blocks. The blocks are
+	// not bounded when it is not set.
+	LimitsReader LimitsReader
+
+	// TimestampDrift is the maximum difference between the timestamp of a
+	// candidate and the local clock. Zero disables the check.
+	TimestampDrift time.Duration
+
+	// Clock is the local clock. The system clock is used when it is not set.
+	Clock core.Clock
+
+	// The factories are used to restore the round from the journal. The
+	// signature factory is the one of the collective signatures.
+	BlockFactory     serde.Factory
+	SignatureFactory crypto.SignatureFactory
+	AddressFactory   mino.AddressFactory
 }
 
 // NewStateMachine returns a new state machine.
 func NewStateMachine(param StateMachineParam) StateMachine {
+	clock := param.Clock
+	if clock == nil {
+		clock = core.SystemClock{}
+	}
+
 	return &pbftsm{
 		logger:      param.Logger,
 		watcher:     core.NewWatcher(),
@@ -192,8 +301,17 @@ func NewStateMachine(param StateMachineParam) StateMachine {
 		genesis:     param.Genesis,
 		tree:        param.Tree,
 		db:          param.DB,
+		context:     json.NewContext(),
+		blockFac:    param.BlockFactory,
+		sigFac:      param.SignatureFactory,
+		addrFac:     param.AddressFactory,
 		state:       NoneState,
 		authReader:  param.AuthorityReader,
+		policy:      param.PolicyReader,
+		limits:      param.LimitsReader,
+
+		timestampDrift: param.TimestampDrift,
+		clock:          clock,
 	}
 }
 
@@ -248,10 +366,41 @@ func (m *pbftsm) GetCommit() (types.Digest, types.Block) {
 	return m.round.id, m.round.block
 }
 
+// GetProposal implements pbft.StateMachine. It returns the signature of the
+// leader over the candidate block. The value is valid only if the state is at
+// least PrepareState.
+func (m *pbftsm) GetProposal() crypto.Signature {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.round.proposal
+}
+
+// GetViewChange implements pbft.StateMachine. It returns the certificate of the
+// candidate if there is one, otherwise it makes the certificate from the views
+// that elected the current leader.
+func (m *pbftsm) GetViewChange() (*types.ViewChange, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.state >= PrepareState {
+		return m.round.viewChange, nil
+	}
+
+	roster, err := m.authReader(m.tree.Get())
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read roster: %v", err)
+	}
+
+	return m.makeViewChange(roster), nil
+}
+
 // Prepare implements pbft.StateMachine. It receives the proposal from the
 // leader and the current tree, and produces the next tree alongside the ID of
 // the proposal that will be signed.
-func (m *pbftsm) Prepare(from mino.Address, block types.Block) (types.Digest, error) {
+func (m *pbftsm) Prepare(from mino.Address, block types.Block,
+	sig crypto.Signature, vc *types.ViewChange) (types.Digest, error) {
+
 	m.Lock()
 	defer m.Unlock()
 
@@ -268,28 +417,73 @@ func (m *pbftsm) Prepare(from mino.Address, block types.Block) (types.Digest, er
 		return id, xerrors.Errorf("failed to read roster: %v", err)
 	}
 
-	_, index := roster.GetPublicKey(from)
+	pubkey, index := roster.GetPublicKey(from)
 
 	if uint16(index) != m.round.leader {
 		return id, xerrors.Errorf("'%v' is not the leader", from)
 	}
 
+	// The proposal is signed by the leader so that a second proposal for the
+	// same index proves an equivocation.
+	err = pubkey.Verify(types.ProposalBytes(block.GetIndex(), block.GetHash()), sig)
+	if err != nil {
+		return id, xerrors.Errorf("invalid proposal signature: %v", err)
+	}
+
 	// Check the state after verifying that the proposal comes from the right
 	// leader.
 	if m.state == PrepareState || m.state == CommitState {
+		current := m.round.block
+
+		// A node never reports itself, as a leader that restarts the round
+		// goes through its own state machine again.
+		if block.GetIndex() == current.GetIndex() && block.GetHash() != current.GetHash() &&
+			m.round.proposal != nil && !m.isSelf(pubkey) {
+
+			m.report(types.EvidenceParam{
+				Kind:      types.ConflictingPrepare,
+				Index:     block.GetIndex(),
+				Offender:  from,
+				PublicKey: pubkey,
+				Statements: []types.Statement{
+					types.NewStatement(current.GetHash(), 0, m.round.proposal),
+					types.NewStatement(block.GetHash(), 0, sig),
+				},
+			})
+		}
+
 		// The leader should only propose one block, therefore the accepted
 		// proposal identifier is sent back, whatever the input is.
 		return id, nil
 	}
 
+	prev := m.round.clone()
+
 	m.round.threshold = calculateThreshold(roster.Len())
 
-	err = m.verifyPrepare(m.tree.Get(), block, &m.round, roster)
+	// The clock is only compared for a live round, as the blocks that are
+	// caught up or replayed can be older than the bound.
+	err = m.verifyDrift(block)
 	if err != nil {
 		return id, err
 	}
 
-	m.setState(PrepareState)
+	err = m.verifyViewChange(vc, roster)
+	if err != nil {
+		return id, err
+	}
+
+	err = m.verifyPrepare(m.tree.Get(), block, vc, &m.round, roster)
+	if err != nil {
+		return id, err
+	}
+
+	m.round.proposal = sig
+
+	err = m.enter(prev, PrepareState)
+	if err != nil {
+		return id, xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return m.round.id, nil
 }
@@ -318,10 +512,15 @@ func (m *pbftsm) Commit(id types.Digest, sig crypto.Signature) error {
 		return err
 	}
 
+	prev := m.round.clone()
+
 	// At this point, the proposal must be finalized whatever happens.
 	m.round.committed = true
 
-	m.setState(CommitState)
+	err = m.enter(prev, CommitState)
+	if err != nil {
+		return xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return nil
 }
@@ -346,11 +545,27 @@ func (m *pbftsm) Finalize(id types.Digest, sig crypto.Signature) error {
 		return err
 	}
 
+	prev := m.round.clone()
+
+	err = m.rotateLeader()
+	if err != nil {
+		return xerrors.Errorf("leader policy: %v", err)
+	}
+
+	err = m.refreshRound()
+	if err != nil {
+		return xerrors.Errorf("refresh round: %v", err)
+	}
+
 	m.round.prevViews = nil
 	m.round.views = nil
+	m.round.viewChange = nil
 	m.round.committed = false
 
-	m.setState(InitialState)
+	err = m.enter(prev, InitialState)
+	if err != nil {
+		return xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return nil
 }
@@ -372,11 +587,15 @@ func (m *pbftsm) Accept(view View) error {
 		return nil
 	}
 
+	m.inspectView(view)
+
 	err = m.verifyViews(false, view)
 	if err != nil {
 		return xerrors.Errorf("invalid view: %v", err)
 	}
 
+	prev := m.round.clone()
+
 	if m.round.views == nil {
 		m.round.views = make(map[mino.Address]View)
 	}
@@ -387,7 +606,10 @@ func (m *pbftsm) Accept(view View) error {
 
 	m.round.views[view.from] = view
 
-	m.checkViewChange(view)
+	err = m.enter(prev, m.checkViewChange(view, m.state))
+	if err != nil {
+		return xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return nil
 }
@@ -424,9 +646,14 @@ func (m *pbftsm) AcceptAll(views []View) error {
 		set[view.from] = view
 	}
 
+	prev := m.round.clone()
+
 	m.round.views = set
-	m.state = ViewChangeState
-	m.checkViewChange(views[0])
+
+	err = m.enter(prev, m.checkViewChange(views[0], ViewChangeState))
+	if err != nil {
+		return xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return nil
 }
@@ -502,7 +729,7 @@ func (m *pbftsm) Expire(addr mino.Address) (View, error) {
 		return view, xerrors.Errorf("create view: %v", err)
 	}
 
-	m.setState(ViewChangeState)
+	prev := m.round.clone()
 
 	if m.round.views == nil {
 		m.round.views = make(map[mino.Address]View)
@@ -510,7 +737,10 @@ func (m *pbftsm) Expire(addr mino.Address) (View, error) {
 
 	m.round.views[addr] = view
 
-	m.checkViewChange(view)
+	err = m.enter(prev, m.checkViewChange(view, ViewChangeState))
+	if err != nil {
+		return view, xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return view, nil
 }
@@ -534,7 +764,7 @@ func (m *pbftsm) CatchUp(link types.BlockLink) error {
 		return xerrors.Errorf("failed to read roster: %v", err)
 	}
 
-	err = m.verifyPrepare(m.tree.Get(), link.GetBlock(), &r, roster)
+	err = m.verifyPrepare(m.tree.Get(), link.GetBlock(), link.GetViewChange(), &r, roster)
 	if err != nil {
 		return xerrors.Errorf("prepare failed: %v", err)
 	}
@@ -544,14 +774,123 @@ func (m *pbftsm) CatchUp(link types.BlockLink) error {
 		return xerrors.Errorf("commit failed: %v", err)
 	}
 
+	if r.viewChange != nil {
+		err = r.viewChange.Verify(link.GetFrom(), roster)
+		if err != nil {
+			return xerrors.Errorf("invalid view change: %v", err)
+		}
+	}
+
 	err = m.verifyFinalize(&r, link.GetCommitSignature(), roster)
 	if err != nil {
 		return xerrors.Errorf("finalize failed: %v", err)
 	}
 
+	prev := m.round.clone()
+
+	if r.viewChange != nil {
+		// The block has been proposed by the leader of the view change, which
+		// is the reference of the policy for the next block.
+		m.round.leader = r.viewChange.GetLeader()
+	}
+
+	err = m.rotateLeader()
+	if err != nil {
+		return xerrors.Errorf("leader policy: %v", err)
+	}
+
+	err = m.refreshRound()
+	if err != nil {
+		return xerrors.Errorf("refresh round: %v", err)
+	}
+
 	m.round.views = nil
 	m.round.prevViews = nil
-	m.setState(InitialState)
+	m.round.viewChange = nil
+
+	err = m.enter(prev, InitialState)
+	if err != nil {
+		return xerrors.Errorf("journal failed: %v", err)
+	}
+
+	return nil
+}
+
+// Restore implements pbft.StateMachine. It stores the chain and the tree in
+// the same database transaction when the tree root matches the one of the
+// latest block. The chain is expected to be verified beforehand.
+func (m *pbftsm) Restore(chain types.Chain, tree hashtree.StagingTree) error {
+	m.Lock()
+	defer m.Unlock()
+
+	block := chain.GetBlock()
+
+	if m.blocks.Len() > block.GetIndex() {
+		return xerrors.Errorf("chain is behind: %d > %d", m.blocks.Len(), block.GetIndex())
+	}
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	if root != block.GetTreeRoot() {
+		return xerrors.Errorf("mismatch tree root '%v' != '%v'", root, block.GetTreeRoot())
+	}
+
+	err := m.db.Update(func(txn kv.WritableTx) error {
+		err := tree.WithTx(txn).Commit()
+		if err != nil {
+			return xerrors.Errorf("while committing tree: %v", err)
+		}
+
+		var unlock func()
+
+		txn.OnCommit(func() {
+			unlock = m.tree.SetWithLock(tree)
+		})
+
+		err = m.blocks.WithTx(txn).StoreChain(chain)
+		if err != nil {
+			return xerrors.Errorf("store chain: %v", err)
+		}
+
+		txn.OnCommit(func() {
+			unlock()
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return xerrors.Errorf("database failed: %v", err)
+	}
+
+	prev := m.round.clone()
+
+	links := chain.GetLinks()
+	if len(links) > 0 && links[len(links)-1].GetViewChange() != nil {
+		// As for a catch up, the leader of the view change that proposed the
+		// last block is the reference of the policy.
+		m.round.leader = links[len(links)-1].GetViewChange().GetLeader()
+	}
+
+	err = m.refreshRound()
+	if err != nil {
+		return xerrors.Errorf("refresh round: %v", err)
+	}
+
+	err = m.rotateLeader()
+	if err != nil {
+		return xerrors.Errorf("leader policy: %v", err)
+	}
+
+	m.round.views = nil
+	m.round.prevViews = nil
+	m.round.viewChange = nil
+
+	err = m.enter(prev, InitialState)
+	if err != nil {
+		return xerrors.Errorf("journal failed: %v", err)
+	}
 
 	return nil
 }
@@ -573,9 +912,24 @@ func (m *pbftsm) Watch(ctx context.Context) <-chan State {
 	return ch
 }
 
-func (m *pbftsm) verifyPrepare(tree hashtree.Tree, block types.Block, r *round, ro authority.Authority) error {
+func (m *pbftsm) verifyPrepare(tree hashtree.Tree, block types.Block,
+	vc *types.ViewChange, r *round, ro authority.Authority) error {
+
+	if m.limits != nil {
+		limits, err := m.limits(tree)
+		if err != nil {
+			return xerrors.Errorf("failed to read limits: %v", err)
+		}
+
+		err = limits.Check(m.context, block.GetTransactions())
+		if err != nil {
+			return xerrors.Errorf("oversized block: %v", err)
+		}
+	}
+
 	stageTree, err := tree.Stage(func(snap store.Snapshot) error {
-		res, err := m.val.Validate(snap, block.GetTransactions())
+		res, err := m.val.Validate(snap, block.GetTransactions(),
+			validation.WithTimestamp(block.GetTimestamp()))
 		if err != nil {
 			return xerrors.Errorf("validation failed: %v", err)
 		}
@@ -605,6 +959,15 @@ func (m *pbftsm) verifyPrepare(tree hashtree.Tree, block types.Block, r *round,
 		return xerrors.Errorf("mismatch index %d != %d", block.GetIndex(), m.blocks.Len())
 	}
 
+	prev, err := m.getLatestTimestamp()
+	if err != nil {
+		return xerrors.Errorf("couldn't get latest timestamp: %v", err)
+	}
+
+	if block.GetTimestamp().Before(prev) {
+		return xerrors.Errorf("timestamp goes backwards by %v", prev.Sub(block.GetTimestamp()))
+	}
+
 	lastID, err := m.getLatestID()
 	if err != nil {
 		return xerrors.Errorf("couldn't get latest digest: %v", err)
@@ -623,6 +986,11 @@ func (m *pbftsm) verifyPrepare(tree hashtree.Tree, block types.Block, r *round,
 		types.WithLinkHashFactory(m.hashFac),
 	}
 
+	// The certificate is part of the digest so that the members sign it.
+	if vc != nil {
+		opts = append(opts, types.WithViewChange(*vc))
+	}
+
 	link, err := types.NewForwardLink(lastID, block.GetHash(), opts...)
 	if err != nil {
 		return xerrors.Errorf("failed to create link: %v", err)
@@ -632,6 +1000,37 @@ func (m *pbftsm) verifyPrepare(tree hashtree.Tree, block types.Block, r *round,
 	r.tree = stageTree
 	r.block = block
 	r.changeset = changeset
+	r.viewChange = vc
+
+	return nil
+}
+
+// verifyViewChange makes sure that the certificate elected the leader of the
+// round after the latest block. A leader elected by a view change must prove
+// it, so the certificate is required when the views moved the round.
+func (m *pbftsm) verifyViewChange(vc *types.ViewChange, ro authority.Authority) error {
+	if vc == nil {
+		if len(m.round.prevViews) > 0 {
+			return xerrors.New("missing view change certificate")
+		}
+
+		return nil
+	}
+
+	if vc.GetLeader() != m.round.leader {
+		return xerrors.Errorf("mismatch view change leader %d != %d",
+			vc.GetLeader(), m.round.leader)
+	}
+
+	latestID, err := m.getLatestID()
+	if err != nil {
+		return xerrors.Errorf("couldn't get latest digest: %v", err)
+	}
+
+	err = vc.Verify(latestID, ro)
+	if err != nil {
+		return xerrors.Errorf("invalid view change: %v", err)
+	}
 
 	return nil
 }
@@ -697,6 +1096,10 @@ func (m *pbftsm) verifyFinalize(r *round, sig crypto.Signature, ro authority.Aut
 			types.WithLinkHashFactory(m.hashFac),
 		}
 
+		if r.viewChange != nil {
+			opts = append(opts, types.WithViewChange(*r.viewChange))
+		}
+
 		link, err := types.NewBlockLink(lastID, r.block, opts...)
 		if err != nil {
 			return xerrors.Errorf("creating link: %v", err)
@@ -723,6 +1126,45 @@ func (m *pbftsm) verifyFinalize(r *round, sig crypto.Signature, ro authority.Aut
 	return nil
 }
 
+// makeViewChange returns the certificate of the view change that elected the
+// leader of the round, or nil if there was none. Only the views of members of
+// the roster for the leader are kept.
+func (m *pbftsm) makeViewChange(ro authority.Authority) *types.ViewChange {
+	views := make(map[int]View)
+
+	for addr, view := range m.round.prevViews {
+		_, index := ro.GetPublicKey(addr)
+		if index >= 0 && view.leader == m.round.leader {
+			views[index] = view
+		}
+	}
+
+	if len(views) <= m.round.threshold {
+		return nil
+	}
+
+	indices := make([]int, 0, len(views))
+	for index := range views {
+		indices = append(indices, index)
+	}
+
+	sort.Ints(indices)
+
+	var id types.Digest
+	sigs := make([]crypto.Signature, len(indices))
+	signers := make([]uint16, len(indices))
+
+	for i, index := range indices {
+		id = views[index].id
+		sigs[i] = views[index].signature
+		signers[i] = uint16(index)
+	}
+
+	vc := types.NewViewChange(id, m.round.leader, signers, sigs)
+
+	return &vc
+}
+
 func (m *pbftsm) init() (authority.Authority, error) {
 	roster, err := m.authReader(m.tree.Get())
 	if err != nil {
@@ -740,23 +1182,124 @@ func (m *pbftsm) init() (authority.Authority, error) {
 	return roster, nil
 }
 
+// refreshRound updates the round parameters according to the roster of the
+// latest tree. The leader index is wrapped around the new roster length so
+// that it always points to an existing member after a removal.
+func (m *pbftsm) refreshRound() error {
+	roster, err := m.authReader(m.tree.Get())
+	if err != nil {
+		return xerrors.Errorf("failed to read roster: %v", err)
+	}
+
+	if roster.Len() > 0 {
+		m.round.leader %= uint16(roster.Len())
+	}
+
+	m.round.threshold = calculateThreshold(roster.Len())
+
+	return nil
+}
+
+// rotateLeader moves to the leader of the next block according to the policy
+// of the latest tree. It must be called after a new block is stored.
+func (m *pbftsm) rotateLeader() error {
+	if m.policy == nil {
+		return nil
+	}
+
+	tree := m.tree.Get()
+
+	policy, err := m.policy(tree)
+	if err != nil {
+		return xerrors.Errorf("failed to read policy: %v", err)
+	}
+
+	roster, err := m.authReader(tree)
+	if err != nil {
+		return xerrors.Errorf("failed to read roster: %v", err)
+	}
+
+	m.round.leader = policy.NextLeader(roster, m.round.leader, m.blocks.Len())
+
+	return nil
+}
+
+// isSelf returns true if the public key is the one of the node.
+func (m *pbftsm) isSelf(pubkey crypto.PublicKey) bool {
+	return m.signer != nil && m.signer.GetPublicKey().Equal(pubkey)
+}
+
 func (m *pbftsm) setState(s State) {
 	m.state = s
 	m.watcher.Notify(s)
 }
 
-func (m *pbftsm) checkViewChange(view View) {
-	if m.state == ViewChangeState && len(m.round.views) > m.round.threshold {
-		m.round.prevViews = m.round.views
-		m.round.views = nil
-		m.round.leader = view.leader
+// enter journals the round with the given state and only then moves the state
+// machine to it, so that the new state is not shared before it is persisted.
+// The round is restored to the previous one when the journal fails.
+func (m *pbftsm) enter(prev round, state State) error {
+	err := m.journal(state)
+	if err != nil {
+		m.round = prev
+		return err
+	}
 
-		if m.round.committed {
-			m.setState(CommitState)
-		} else {
-			m.setState(InitialState)
-		}
+	if state != m.state {
+		m.setState(state)
+	}
+
+	return nil
+}
+
+// checkViewChange moves the round to the leader of the view when enough views
+// have been received during a view change. It returns the state that the state
+// machine must enter.
+func (m *pbftsm) checkViewChange(view View, state State) State {
+	if state != ViewChangeState || len(m.round.views) <= m.round.threshold {
+		return state
 	}
+
+	m.round.prevViews = m.round.views
+	m.round.views = nil
+	m.round.leader = view.leader
+
+	if m.round.committed {
+		return CommitState
+	}
+
+	return InitialState
+}
+
+// verifyDrift returns an error if the timestamp of the block is too far from
+// the local clock, in the past or in the future.
+func (m *pbftsm) verifyDrift(block types.Block) error {
+	if m.timestampDrift <= 0 {
+		return nil
+	}
+
+	drift := m.clock.Now().Sub(block.GetTimestamp())
+	if drift < 0 {
+		drift = -drift
+	}
+
+	if drift > m.timestampDrift {
+		return xerrors.Errorf("timestamp drifts by %v beyond %v", drift, m.timestampDrift)
+	}
+
+	return nil
+}
+
+func (m *pbftsm) getLatestTimestamp() (time.Time, error) {
+	if m.blocks.Len() == 0 {
+		return time.Time{}, nil
+	}
+
+	last, err := m.blocks.Last()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return last.GetBlock().GetTimestamp(), nil
 }
 
 func (m *pbftsm) getLatestID() (types.Digest, error) {
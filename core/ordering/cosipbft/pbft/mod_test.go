@@ -2,10 +2,12 @@ package pbft
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core"
@@ -18,6 +20,7 @@ import (
 	"go.dedis.ch/dela/core/store/hashtree/binprefix"
 	"go.dedis.ch/dela/core/store/kv"
 	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/core/txn/signed"
 	"go.dedis.ch/dela/core/validation"
 	"go.dedis.ch/dela/core/validation/simple"
 	"go.dedis.ch/dela/crypto"
@@ -127,23 +130,91 @@ func TestStateMachine_Prepare(t *testing.T) {
 	sm := NewStateMachine(param).(*pbftsm)
 	sm.state = InitialState
 
-	id, err := sm.Prepare(from, block)
+	id, err := sm.Prepare(from, block, fake.Signature{}, nil)
 	require.NoError(t, err)
 	require.NotEqual(t, types.Digest{}, id)
 	require.Equal(t, PrepareState, sm.state)
 	require.Equal(t, id, sm.round.id)
 
-	id, err = sm.Prepare(from, block)
+	id, err = sm.Prepare(from, block, fake.Signature{}, nil)
 	require.NoError(t, err)
 	require.Equal(t, sm.round.id, id)
 }
 
+func TestStateMachine_ViewChange_Prepare(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	ro := authority.FromAuthority(fake.NewAuthority(4, fake.NewSigner))
+
+	param := StateMachineParam{
+		Validation: simple.NewService(fakeExec{}, nil),
+		Blocks:     blockstore.NewInMemory(),
+		Genesis:    blockstore.NewGenesisStore(),
+		Tree:       blockstore.NewTreeCache(tree),
+		AuthorityReader: func(hashtree.Tree) (authority.Authority, error) {
+			return ro, nil
+		},
+		DB: db,
+	}
+
+	param.Genesis.Set(types.Genesis{})
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root), types.WithIndex(0))
+	require.NoError(t, err)
+
+	from := fake.NewAddress(1)
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = InitialState
+	sm.round.leader = 1
+	sm.round.prevViews = map[mino.Address]View{
+		fake.NewAddress(0): {leader: 1, signature: fake.Signature{}},
+	}
+
+	// The leader has been elected by a view change so it must prove it.
+	_, err = sm.Prepare(from, block, fake.Signature{}, nil)
+	require.EqualError(t, err, "missing view change certificate")
+
+	sigs := []crypto.Signature{fake.Signature{}, fake.Signature{}, fake.Signature{}}
+
+	vc := types.NewViewChange(types.Digest{}, 2, []uint16{0, 2, 3}, sigs)
+	_, err = sm.Prepare(from, block, fake.Signature{}, &vc)
+	require.EqualError(t, err, "mismatch view change leader 2 != 1")
+
+	vc = types.NewViewChange(types.Digest{}, 1, []uint16{0, 2}, sigs[:2])
+	_, err = sm.Prepare(from, block, fake.Signature{}, &vc)
+	require.EqualError(t, err, "invalid view change: not enough views: 2 <= 2")
+
+	vc = types.NewViewChange(types.Digest{1}, 1, []uint16{0, 2, 3}, sigs)
+	_, err = sm.Prepare(from, block, fake.Signature{}, &vc)
+	require.Error(t, err)
+	require.Regexp(t, "^invalid view change: mismatch id", err.Error())
+
+	vc = types.NewViewChange(types.Digest{}, 1, []uint16{0, 2, 3}, sigs)
+	id, err := sm.Prepare(from, block, fake.Signature{}, &vc)
+	require.NoError(t, err)
+	require.Equal(t, &vc, sm.round.viewChange)
+
+	// The certificate is part of the digest that the members sign.
+	link, err := types.NewForwardLink(types.Digest{}, block.GetHash(), types.WithViewChange(vc))
+	require.NoError(t, err)
+	require.Equal(t, link.GetHash(), id)
+
+	res, err := sm.GetViewChange()
+	require.NoError(t, err)
+	require.Equal(t, &vc, res)
+}
+
 func TestStateMachine_WhileViewChange_Prepare(t *testing.T) {
 	sm := &pbftsm{
 		state: ViewChangeState,
 	}
 
-	_, err := sm.Prepare(fake.NewAddress(0), types.Block{})
+	_, err := sm.Prepare(fake.NewAddress(0), types.Block{}, fake.Signature{}, nil)
 	require.EqualError(t, err, "cannot be in view change state during prepare")
 }
 
@@ -159,7 +230,7 @@ func TestStateMachine_WrongLeader_Prepare(t *testing.T) {
 
 	link := makeLink(t)
 
-	_, err := sm.Prepare(fake.NewAddress(1), link.GetBlock())
+	_, err := sm.Prepare(fake.NewAddress(1), link.GetBlock(), fake.Signature{}, nil)
 	require.EqualError(t, err, "'fake.Address[1]' is not the leader")
 }
 
@@ -177,7 +248,7 @@ func TestStateMachine_FailedValidation_Prepare(t *testing.T) {
 
 	link := makeLink(t)
 
-	_, err := sm.Prepare(fake.NewAddress(0), link.GetBlock())
+	_, err := sm.Prepare(fake.NewAddress(0), link.GetBlock(), fake.Signature{}, nil)
 	require.EqualError(t, err, fake.Err("while updating tree: callback failed: validation failed"))
 }
 
@@ -197,7 +268,7 @@ func TestStateMachine_MismatchTreeRoot_Prepare(t *testing.T) {
 	require.NoError(t, err)
 
 	sm.val = simple.NewService(fakeExec{}, nil)
-	_, err = sm.Prepare(fake.NewAddress(0), other)
+	_, err = sm.Prepare(fake.NewAddress(0), other, fake.Signature{}, nil)
 	require.EqualError(t, err, "mismatch tree root '71b6c1d5' != '00000000'")
 }
 
@@ -221,10 +292,103 @@ func TestStateMachine_MissingGenesis_Prepare(t *testing.T) {
 	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root))
 	require.NoError(t, err)
 
-	_, err = sm.Prepare(fake.NewAddress(0), block)
+	_, err = sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
 	require.EqualError(t, err, "couldn't get latest digest: missing genesis block")
 }
 
+func TestStateMachine_Limits_Prepare(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	sm := &pbftsm{
+		state:      InitialState,
+		val:        simple.NewService(fakeExec{}, nil),
+		tree:       blockstore.NewTreeCache(tree),
+		db:         db,
+		authReader: goodReader,
+		genesis:    blockstore.NewGenesisStore(),
+		blocks:     blockstore.NewInMemory(),
+		limits: func(hashtree.Tree) (types.Limits, error) {
+			return types.Limits{MaxTransactions: 1}, nil
+		},
+	}
+
+	sm.genesis.Set(types.Genesis{})
+
+	results := make([]simple.TransactionResult, 2)
+	for i := range results {
+		tx, err := signed.NewTransaction(uint64(i), fake.PublicKey{})
+		require.NoError(t, err)
+
+		results[i] = simple.NewTransactionResult(tx, true, "")
+	}
+
+	block, err := types.NewBlock(simple.NewResult(results))
+	require.NoError(t, err)
+
+	_, err = sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
+	require.EqualError(t, err, "oversized block: too many transactions 2 > 1")
+
+	sm.limits = func(hashtree.Tree) (types.Limits, error) {
+		return types.Limits{}, fake.GetError()
+	}
+
+	_, err = sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
+	require.EqualError(t, err, fake.Err("failed to read limits"))
+}
+
+func TestStateMachine_Timestamp_Prepare(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	sm := &pbftsm{
+		state:          InitialState,
+		val:            simple.NewService(fakeExec{}, nil),
+		tree:           blockstore.NewTreeCache(tree),
+		db:             db,
+		authReader:     goodReader,
+		genesis:        blockstore.NewGenesisStore(),
+		blocks:         blockstore.NewInMemory(),
+		timestampDrift: time.Minute,
+		clock:          core.SystemClock{},
+	}
+
+	sm.genesis.Set(types.Genesis{})
+
+	prev, err := types.NewBlock(simple.NewResult(nil), types.WithTimestamp(time.Now()))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(types.Digest{}, prev)
+	require.NoError(t, err)
+	require.NoError(t, sm.blocks.Store(link))
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	makeBlock := func(ts time.Time) types.Block {
+		block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root),
+			types.WithIndex(1), types.WithTimestamp(ts))
+		require.NoError(t, err)
+
+		return block
+	}
+
+	_, err = sm.Prepare(fake.NewAddress(0), makeBlock(time.Now().Add(time.Hour)), fake.Signature{}, nil)
+	require.Error(t, err)
+	require.Regexp(t, "^timestamp drifts by 59m59.[0-9]+s beyond 1m0s$", err.Error())
+
+	_, err = sm.Prepare(fake.NewAddress(0), makeBlock(time.Time{}), fake.Signature{}, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "timestamp drifts by ")
+
+	_, err = sm.Prepare(fake.NewAddress(0), makeBlock(prev.GetTimestamp().Add(-time.Second)), fake.Signature{}, nil)
+	require.EqualError(t, err, "timestamp goes backwards by 1s")
+
+	sm.blocks = badBlockStore{length: 1}
+	_, err = sm.Prepare(fake.NewAddress(0), makeBlock(time.Now()), fake.Signature{}, nil)
+	require.EqualError(t, err, fake.Err("couldn't get latest timestamp"))
+}
+
 func TestStateMachine_FailReadCurrentRoster_Prepare(t *testing.T) {
 	tree, db, clean := makeTree(t)
 	defer clean()
@@ -247,7 +411,7 @@ func TestStateMachine_FailReadCurrentRoster_Prepare(t *testing.T) {
 	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root))
 	require.NoError(t, err)
 
-	_, err = sm.Prepare(fake.NewAddress(0), block)
+	_, err = sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
 	require.EqualError(t, err, fake.Err("failed to read roster"))
 }
 
@@ -285,7 +449,7 @@ func TestStateMachine_FailReadRosterInStageTree_Prepare(t *testing.T) {
 	require.NoError(t, err)
 
 	// Failure to read the roster of the staging tree.
-	_, err = sm.Prepare(fake.NewAddress(0), block)
+	_, err = sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
 	require.EqualError(t, err, fake.Err("failed to read next roster"))
 }
 
@@ -313,7 +477,7 @@ func TestStateMachine_FailCreateLink_Prepare(t *testing.T) {
 	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root))
 	require.NoError(t, err)
 
-	_, err = sm.Prepare(fake.NewAddress(0), block)
+	_, err = sm.Prepare(fake.NewAddress(0), block, fake.Signature{}, nil)
 	require.EqualError(t, err,
 		fake.Err("failed to create link: failed to fingerprint: couldn't write from"))
 }
@@ -421,6 +585,187 @@ func TestStateMachine_Finalize(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestStateMachine_ViewChange_Finalize(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	param := StateMachineParam{
+		VerifierFactory: fake.NewVerifierFactory(fake.Verifier{}),
+		Blocks:          blockstore.NewInMemory(),
+		Genesis:         blockstore.NewGenesisStore(),
+		Tree:            blockstore.NewTreeCache(tree),
+		AuthorityReader: func(hashtree.Tree) (authority.Authority, error) {
+			return ro, nil
+		},
+		DB: db,
+	}
+
+	param.Genesis.Set(types.Genesis{})
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = InitialState
+	sm.round.tree = tree.(hashtree.StagingTree)
+	sm.round.prepareSig = fake.Signature{}
+	sm.round.leader = 1
+	sm.round.threshold = 1
+
+	// Only the views of the members for the leader are kept in the
+	// certificate.
+	sm.round.prevViews = map[mino.Address]View{
+		fake.NewAddress(2): {id: types.Digest{}, leader: 1, signature: fake.Signature{}},
+		fake.NewAddress(0): {id: types.Digest{}, leader: 1, signature: fake.Signature{}},
+		fake.NewAddress(1): {id: types.Digest{}, leader: 2, signature: fake.Signature{}},
+		fake.NewAddress(5): {id: types.Digest{}, leader: 1, signature: fake.Signature{}},
+	}
+
+	vc, err := sm.GetViewChange()
+	require.NoError(t, err)
+
+	expected := types.NewViewChange(types.Digest{}, 1, []uint16{0, 2},
+		[]crypto.Signature{fake.Signature{}, fake.Signature{}})
+	require.Equal(t, &expected, vc)
+
+	// The certificate of the candidate is stored in the link.
+	sm.state = CommitState
+	sm.round.viewChange = vc
+
+	err = sm.Finalize(types.Digest{1}, fake.Signature{})
+	require.NoError(t, err)
+	require.Nil(t, sm.round.viewChange)
+
+	link, err := sm.blocks.Last()
+	require.NoError(t, err)
+	require.Equal(t, &expected, link.GetViewChange())
+
+	// Not enough views to make a certificate.
+	sm.round.leader = 1
+	sm.round.threshold = 2
+	sm.round.prevViews = map[mino.Address]View{
+		fake.NewAddress(0): {id: types.Digest{}, leader: 1, signature: fake.Signature{}},
+		fake.NewAddress(1): {id: types.Digest{}, leader: 1, signature: fake.Signature{}},
+	}
+
+	vc, err = sm.GetViewChange()
+	require.NoError(t, err)
+	require.Nil(t, vc)
+
+	sm.state = CommitState
+
+	err = sm.Finalize(types.Digest{1}, fake.Signature{})
+	require.NoError(t, err)
+
+	link, err = sm.blocks.Last()
+	require.NoError(t, err)
+	require.Nil(t, link.GetViewChange())
+
+	sm.authReader = badReader
+	_, err = sm.GetViewChange()
+	require.EqualError(t, err, fake.Err("failed to read roster"))
+}
+
+func TestStateMachine_LeaderPolicy_Finalize(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	param := StateMachineParam{
+		VerifierFactory: fake.NewVerifierFactory(fake.Verifier{}),
+		Blocks:          blockstore.NewInMemory(),
+		Genesis:         blockstore.NewGenesisStore(),
+		Tree:            blockstore.NewTreeCache(tree),
+		AuthorityReader: func(hashtree.Tree) (authority.Authority, error) {
+			return ro, nil
+		},
+		PolicyReader: func(hashtree.Tree) (LeaderPolicy, error) {
+			return NewRoundRobinPolicy(), nil
+		},
+		DB: db,
+	}
+
+	param.Genesis.Set(types.Genesis{})
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = CommitState
+	sm.round.tree = tree.(hashtree.StagingTree)
+	sm.round.prepareSig = fake.Signature{}
+
+	err := sm.Finalize(types.Digest{1}, fake.Signature{})
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), sm.round.leader)
+
+	sm.policy = func(hashtree.Tree) (LeaderPolicy, error) {
+		return nil, fake.GetError()
+	}
+
+	err = sm.rotateLeader()
+	require.EqualError(t, err, fake.Err("failed to read policy"))
+
+	sm.policy = param.PolicyReader
+	sm.authReader = badReader
+
+	err = sm.rotateLeader()
+	require.EqualError(t, err, fake.Err("failed to read roster"))
+}
+
+func TestStateMachine_RosterShrunk_Finalize(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	ro := authority.FromAuthority(fake.NewAuthority(4, fake.NewSigner))
+	counter := fake.NewCounter(1)
+
+	param := StateMachineParam{
+		VerifierFactory: fake.NewVerifierFactory(fake.Verifier{}),
+		Blocks:          blockstore.NewInMemory(),
+		Genesis:         blockstore.NewGenesisStore(),
+		Tree:            blockstore.NewTreeCache(tree),
+		AuthorityReader: func(hashtree.Tree) (authority.Authority, error) {
+			if counter.Done() {
+				// The last member has been removed by the block.
+				return ro.Take(mino.RangeFilter(0, 3)).(authority.Authority), nil
+			}
+
+			counter.Decrease()
+			return ro, nil
+		},
+		DB: db,
+	}
+
+	param.Genesis.Set(types.Genesis{})
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = CommitState
+	sm.round.leader = 3
+	sm.round.tree = tree.(hashtree.StagingTree)
+	sm.round.prepareSig = fake.Signature{}
+
+	err := sm.Finalize(types.Digest{1}, fake.Signature{})
+	require.NoError(t, err)
+	require.Equal(t, uint16(0), sm.round.leader)
+	require.Equal(t, 0, sm.round.threshold)
+
+	leader, err := sm.GetLeader()
+	require.NoError(t, err)
+	require.Equal(t, fake.NewAddress(0), leader)
+
+	sm.state = CommitState
+	sm.authReader = func(hashtree.Tree) (authority.Authority, error) {
+		if counter.Done() {
+			return nil, fake.GetError()
+		}
+
+		counter.Decrease()
+		return ro, nil
+	}
+	counter = fake.NewCounter(1)
+
+	err = sm.Finalize(types.Digest{1}, fake.Signature{})
+	require.EqualError(t, err, fake.Err("refresh round: failed to read roster"))
+}
+
 func TestStateMachine_NotCommitted_Finalize(t *testing.T) {
 	sm := &pbftsm{
 		state: InitialState,
@@ -812,6 +1157,154 @@ func TestStateMachine_CatchUp(t *testing.T) {
 	sm.verifierFac = fake.VerifierFactory{}
 	err = sm.CatchUp(link)
 	require.EqualError(t, err, fake.Err("finalize failed: couldn't marshal signature"))
+
+	vc := types.NewViewChange(types.Digest{}, 1, nil, nil)
+	opts = []types.LinkOption{
+		types.WithSignatures(fake.Signature{}, fake.Signature{}),
+		types.WithViewChange(vc),
+	}
+
+	link, err = types.NewBlockLink(types.Digest{}, block, opts...)
+	require.NoError(t, err)
+	err = sm.CatchUp(link)
+	require.EqualError(t, err, "invalid view change: not enough views: 0 <= 0")
+
+	vc = types.NewViewChange(types.Digest{}, 1, []uint16{1}, []crypto.Signature{fake.Signature{}})
+	opts[1] = types.WithViewChange(vc)
+
+	link, err = types.NewBlockLink(types.Digest{}, block, opts...)
+	require.NoError(t, err)
+	err = sm.CatchUp(link)
+	require.NoError(t, err)
+
+	last, err := sm.blocks.Last()
+	require.NoError(t, err)
+	require.Equal(t, &vc, last.GetViewChange())
+	// The leader of the view change proposed the block.
+	require.Equal(t, uint16(1), sm.round.leader)
+}
+
+func TestStateMachine_Restore(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	param := StateMachineParam{
+		Blocks:          blockstore.NewInMemory(),
+		Genesis:         blockstore.NewGenesisStore(),
+		Tree:            blockstore.NewTreeCache(tree),
+		AuthorityReader: goodReader,
+		DB:              db,
+	}
+
+	stage, err := tree.Stage(func(snap store.Snapshot) error {
+		return snap.Set([]byte("ping"), []byte("pong"))
+	})
+	require.NoError(t, err)
+
+	root := types.Digest{}
+	copy(root[:], stage.GetRoot())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root), types.WithIndex(1))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(types.Digest{1}, block)
+	require.NoError(t, err)
+
+	first, err := types.NewForwardLink(types.Digest{}, types.Digest{1})
+	require.NoError(t, err)
+
+	chain := types.NewChain(link, []types.Link{first})
+
+	sm := NewStateMachine(param).(*pbftsm)
+	sm.state = ViewChangeState
+	sm.round.threshold = 5
+
+	err = sm.Restore(chain, stage)
+	require.NoError(t, err)
+	require.Equal(t, InitialState, sm.state)
+	require.Equal(t, uint64(2), sm.blocks.Len())
+	require.Equal(t, stage.GetRoot(), sm.tree.Get().GetRoot())
+	require.Equal(t, 0, sm.round.threshold)
+
+	err = sm.Restore(types.NewChain(makeLink(t), nil), stage)
+	require.EqualError(t, err, "chain is behind: 2 > 0")
+
+	sm.blocks = blockstore.NewInMemory()
+	empty := types.Digest{}
+	copy(empty[:], tree.GetRoot())
+
+	err = sm.Restore(chain, tree.(hashtree.StagingTree))
+	require.EqualError(t, err, fmt.Sprintf("mismatch tree root '%v' != '%v'", empty, root))
+
+	err = sm.Restore(chain, badTree{StagingTree: stage})
+	require.EqualError(t, err, fake.Err("database failed: while committing tree"))
+
+	sm.blocks = badBlockStore{}
+	err = sm.Restore(chain, stage)
+	require.EqualError(t, err, fake.Err("database failed: store chain"))
+
+	sm.blocks = blockstore.NewInMemory()
+	sm.authReader = badReader
+	err = sm.Restore(chain, stage)
+	require.EqualError(t, err, fake.Err("refresh round: failed to read roster"))
+
+	sm.blocks = blockstore.NewInMemory()
+	sm.authReader = goodReader
+	sm.policy = func(hashtree.Tree) (LeaderPolicy, error) {
+		return nil, fake.GetError()
+	}
+	err = sm.Restore(chain, stage)
+	require.EqualError(t, err, fake.Err("leader policy: failed to read policy"))
+}
+
+func TestStateMachine_LeaderPolicy_Restore(t *testing.T) {
+	tree, db, clean := makeTree(t)
+	defer clean()
+
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	param := StateMachineParam{
+		Blocks:  blockstore.NewInMemory(),
+		Genesis: blockstore.NewGenesisStore(),
+		Tree:    blockstore.NewTreeCache(tree),
+		AuthorityReader: func(hashtree.Tree) (authority.Authority, error) {
+			return ro, nil
+		},
+		PolicyReader: func(hashtree.Tree) (LeaderPolicy, error) {
+			return NewRoundRobinPolicy(), nil
+		},
+		DB: db,
+	}
+
+	root := types.Digest{}
+	copy(root[:], tree.GetRoot())
+
+	block, err := types.NewBlock(simple.NewResult(nil), types.WithTreeRoot(root), types.WithIndex(1))
+	require.NoError(t, err)
+
+	link, err := types.NewBlockLink(types.Digest{1}, block)
+	require.NoError(t, err)
+
+	first, err := types.NewForwardLink(types.Digest{}, types.Digest{1})
+	require.NoError(t, err)
+
+	sm := NewStateMachine(param).(*pbftsm)
+
+	// The leader moves to the one of the next block, as after a catch up.
+	err = sm.Restore(types.NewChain(link, []types.Link{first}), tree.(hashtree.StagingTree))
+	require.NoError(t, err)
+	require.Equal(t, uint16(1), sm.round.leader)
+
+	// The leader of a view change is the reference of the policy.
+	vc := types.NewViewChange(types.Digest{}, 2, nil, nil)
+
+	link, err = types.NewBlockLink(types.Digest{1}, block, types.WithViewChange(vc))
+	require.NoError(t, err)
+
+	sm.blocks = blockstore.NewInMemory()
+	err = sm.Restore(types.NewChain(link, []types.Link{first}), tree.(hashtree.StagingTree))
+	require.NoError(t, err)
+	require.Equal(t, uint16(0), sm.round.leader)
 }
 
 func TestStateMachine_Watch(t *testing.T) {
@@ -872,7 +1365,7 @@ type badValidation struct {
 	validation.Service
 }
 
-func (v badValidation) Validate(store.Snapshot, []txn.Transaction) (validation.Result, error) {
+func (v badValidation) Validate(store.Snapshot, []txn.Transaction, ...validation.Option) (validation.Result, error) {
 	return nil, fake.GetError()
 }
 
@@ -897,6 +1390,10 @@ func (s badBlockStore) Store(types.BlockLink) error {
 	return fake.GetError()
 }
 
+func (s badBlockStore) StoreChain(types.Chain) error {
+	return fake.GetError()
+}
+
 type badTree struct {
 	hashtree.StagingTree
 }
@@ -0,0 +1,212 @@
+// This file contains the policies that decide which member of the roster leads
+// the round of the next block.
+
+package pbft
+
+import (
+	"fmt"
+	"strings"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/store/hashtree"
+	"golang.org/x/xerrors"
+)
+
+const (
+	stickyName     = "sticky"
+	roundRobinName = "round-robin"
+	weightedName   = "weighted"
+)
+
+// LeaderPolicy is the interface of the policy that decides the leader of the
+// round of a new block. A view change still moves to the next member when the
+// leader fails, whatever the policy.
+type LeaderPolicy interface {
+	fmt.Stringer
+
+	// NextLeader returns the index in the roster of the leader of the round
+	// for the block at the given index, knowing the leader of the previous
+	// block.
+	NextLeader(roster authority.Authority, previous uint16, index uint64) uint16
+}
+
+// PolicyReader is a function to help the state machine to read the leader
+// policy for a given tree.
+type PolicyReader func(tree hashtree.Tree) (LeaderPolicy, error)
+
+// ParseLeaderPolicy returns the policy described by the specification, which
+// is the string representation of a policy. An empty specification is the
+// sticky policy.
+//
+// The specifications are "sticky", "round-robin" and "weighted". The weights
+// of the members are part of the roster so that they follow the roster
+// changes.
+func ParseLeaderPolicy(spec string) (LeaderPolicy, error) {
+	sep := strings.Index(spec, ":")
+	if sep >= 0 {
+		return nil, xerrors.Errorf("unexpected arguments '%s'", spec[sep+1:])
+	}
+
+	switch spec {
+	case "", stickyName:
+		return NewStickyPolicy(), nil
+	case roundRobinName:
+		return NewRoundRobinPolicy(), nil
+	case weightedName:
+		return NewWeightedPolicy(), nil
+	default:
+		return nil, xerrors.Errorf("unknown policy '%s'", spec)
+	}
+}
+
+// stickyPolicy keeps the same leader as long as it is healthy.
+//
+// - implements pbft.LeaderPolicy
+type stickyPolicy struct{}
+
+// NewStickyPolicy returns a policy that keeps the same leader until a view
+// change happens.
+func NewStickyPolicy() LeaderPolicy {
+	return stickyPolicy{}
+}
+
+// NextLeader implements pbft.LeaderPolicy. It returns the previous leader.
+func (stickyPolicy) NextLeader(ro authority.Authority, previous uint16, index uint64) uint16 {
+	return previous
+}
+
+// String implements fmt.Stringer. It returns the specification of the policy.
+func (stickyPolicy) String() string {
+	return stickyName
+}
+
+// roundRobinPolicy moves to the next member of the roster for every block.
+//
+// - implements pbft.LeaderPolicy
+type roundRobinPolicy struct{}
+
+// NewRoundRobinPolicy returns a policy where the members of the roster lead
+// the rounds in turn.
+func NewRoundRobinPolicy() LeaderPolicy {
+	return roundRobinPolicy{}
+}
+
+// NextLeader implements pbft.LeaderPolicy. It returns the member that follows
+// the previous leader.
+func (roundRobinPolicy) NextLeader(ro authority.Authority, previous uint16, index uint64) uint16 {
+	if ro.Len() == 0 {
+		return 0
+	}
+
+	return (previous + 1) % uint16(ro.Len())
+}
+
+// String implements fmt.Stringer. It returns the specification of the policy.
+func (roundRobinPolicy) String() string {
+	return roundRobinName
+}
+
+// weightedAuthority is the interface of an authority that gives a weight to
+// each of its members.
+type weightedAuthority interface {
+	GetWeight(index int) uint32
+}
+
+// weightedPolicy selects the leaders so that each member leads a number of
+// rounds proportional to its weight.
+//
+// - implements pbft.LeaderPolicy
+type weightedPolicy struct{}
+
+// NewWeightedPolicy returns a policy where the members lead the rounds in
+// proportion of their weights. The weights are read from the roster, and the
+// members of a roster that has no weight have a weight of 1.
+func NewWeightedPolicy() LeaderPolicy {
+	return weightedPolicy{}
+}
+
+// NextLeader implements pbft.LeaderPolicy. It walks through the members of the
+// roster for as many slots as the block index, each member having as many
+// slots as its weight. The schedule is then shifted by the distance between
+// the previous leader and the one of the previous slot, so that a view change
+// hands over the slots of the member that failed to the one that replaced it,
+// instead of giving them back every round.
+func (p weightedPolicy) NextLeader(ro authority.Authority, previous uint16, index uint64) uint16 {
+	weights := make([]uint64, 0, ro.Len())
+	total := uint64(0)
+
+	weighted, ok := ro.(weightedAuthority)
+
+	for i := 0; i < ro.Len(); i++ {
+		weight := uint64(1)
+		if ok {
+			weight = uint64(weighted.GetWeight(i))
+		}
+
+		weights = append(weights, weight)
+		total += weight
+	}
+
+	if total == 0 {
+		// Every member has a zero weight, which falls back to a round-robin.
+		return NewRoundRobinPolicy().NextLeader(ro, previous, index)
+	}
+
+	// Only the members with a positive weight can lead a round, therefore the
+	// shift is computed among them.
+	eligible := make([]uint16, 0, len(weights))
+	for i, weight := range weights {
+		if weight > 0 {
+			eligible = append(eligible, uint16(i))
+		}
+	}
+
+	slot := p.slotOf(weights, total, index)
+
+	if index == 0 {
+		return eligible[slot]
+	}
+
+	expected := p.slotOf(weights, total, index-1)
+
+	// A view change can move to a member that never leads, in which case the
+	// following eligible member is the reference.
+	current := 0
+	for i, member := range eligible {
+		if member >= previous {
+			current = i
+			break
+		}
+	}
+
+	shift := (current - expected + len(eligible)) % len(eligible)
+
+	return eligible[(slot+shift)%len(eligible)]
+}
+
+// slotOf returns the position among the members with a positive weight of the
+// owner of the slot for the block index.
+func (p weightedPolicy) slotOf(weights []uint64, total uint64, index uint64) int {
+	slot := index % total
+	pos := 0
+
+	for _, weight := range weights {
+		if weight == 0 {
+			continue
+		}
+
+		if slot < weight {
+			return pos
+		}
+
+		slot -= weight
+		pos++
+	}
+
+	return 0
+}
+
+// String implements fmt.Stringer. It returns the specification of the policy.
+func (p weightedPolicy) String() string {
+	return weightedName
+}
@@ -0,0 +1,135 @@
+package pbft
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestParseLeaderPolicy(t *testing.T) {
+	policy, err := ParseLeaderPolicy("")
+	require.NoError(t, err)
+	require.Equal(t, NewStickyPolicy(), policy)
+
+	policy, err = ParseLeaderPolicy("sticky")
+	require.NoError(t, err)
+	require.Equal(t, "sticky", policy.String())
+
+	policy, err = ParseLeaderPolicy("round-robin")
+	require.NoError(t, err)
+	require.Equal(t, "round-robin", policy.String())
+
+	policy, err = ParseLeaderPolicy("weighted")
+	require.NoError(t, err)
+	require.Equal(t, "weighted", policy.String())
+
+	// The specification of a policy gives back the same policy.
+	same, err := ParseLeaderPolicy(policy.String())
+	require.NoError(t, err)
+	require.Equal(t, policy, same)
+
+	_, err = ParseLeaderPolicy("unknown")
+	require.EqualError(t, err, "unknown policy 'unknown'")
+
+	_, err = ParseLeaderPolicy("weighted:a=2")
+	require.EqualError(t, err, "unexpected arguments 'a=2'")
+}
+
+func TestStickyPolicy_NextLeader(t *testing.T) {
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	policy := NewStickyPolicy()
+
+	require.Equal(t, uint16(2), policy.NextLeader(ro, 2, 5))
+}
+
+func TestRoundRobinPolicy_NextLeader(t *testing.T) {
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	policy := NewRoundRobinPolicy()
+
+	require.Equal(t, uint16(1), policy.NextLeader(ro, 0, 1))
+	require.Equal(t, uint16(0), policy.NextLeader(ro, 2, 1))
+	require.Equal(t, uint16(0), policy.NextLeader(authority.New(nil, nil), 2, 1))
+}
+
+func TestWeightedPolicy_NextLeader(t *testing.T) {
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner)).
+		WithWeights([]uint32{2, 1, 0})
+
+	policy := NewWeightedPolicy()
+
+	leaders := make([]uint16, 6)
+	previous := uint16(0)
+	for i := range leaders {
+		leaders[i] = policy.NextLeader(ro, previous, uint64(i))
+		previous = leaders[i]
+	}
+
+	// The first member has twice the weight of the second one and the last one
+	// never leads.
+	require.Equal(t, []uint16{0, 0, 1, 0, 0, 1}, leaders)
+
+	ro = ro.WithWeights([]uint32{0, 0, 0})
+
+	require.Equal(t, uint16(1), policy.NextLeader(ro, 0, 5))
+	require.Equal(t, uint16(0), policy.NextLeader(authority.New(nil, nil), 0, 5))
+}
+
+func TestWeightedPolicy_RosterChange_NextLeader(t *testing.T) {
+	ro := authority.FromAuthority(fake.NewAuthority(2, fake.NewSigner))
+
+	policy := NewWeightedPolicy()
+
+	// A member added later keeps the weight given by the change set.
+	cset := authority.NewChangeSet()
+	cset.AddWeighted(fake.NewAddress(2), fake.PublicKey{}, 3)
+
+	next := ro.Apply(cset)
+
+	leaders := make([]uint16, 5)
+	previous := uint16(0)
+	for i := range leaders {
+		leaders[i] = policy.NextLeader(next, previous, uint64(i))
+		previous = leaders[i]
+	}
+
+	require.Equal(t, []uint16{0, 1, 2, 2, 2}, leaders)
+}
+
+func TestWeightedPolicy_ViewChange_NextLeader(t *testing.T) {
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	ro = ro.WithWeights([]uint32{3})
+
+	policy := NewWeightedPolicy()
+
+	// The heaviest member crashed so that a view change moves to the next
+	// member every time it is the leader.
+	failures := 0
+	leaders := make([]uint16, 10)
+	previous := uint16(0)
+	for i := range leaders {
+		previous = policy.NextLeader(ro, previous, uint64(i))
+		if previous == 0 {
+			failures++
+			previous = 1
+		}
+
+		leaders[i] = previous
+	}
+
+	// The slots of the crashed member are handed over to the member that
+	// replaced it.
+	require.Equal(t, []uint16{1, 1, 1, 2, 1, 2, 2, 2, 1, 2}, leaders)
+	require.Equal(t, 3, failures)
+
+	// A view change to a member that never leads uses the next one as the
+	// reference.
+	ro = ro.WithWeights([]uint32{1, 0})
+
+	require.Equal(t, uint16(0), policy.NextLeader(ro, 1, 1))
+	require.Equal(t, uint16(0), policy.NextLeader(ro, 2, 1))
+}
@@ -8,6 +8,9 @@ package cosipbft
 
 import (
 	"context"
+	"errors"
+	"math/big"
+	"sync"
 
 	"github.com/rs/zerolog"
 	"go.dedis.ch/dela/core"
@@ -28,9 +31,15 @@ import (
 	"golang.org/x/xerrors"
 )
 
+// errGenesisExists is returned when a genesis block is stored while the node
+// already has one.
+var errGenesisExists = errors.New("chain already exists")
+
 var (
 	keyRoster = [32]byte{}
 	keyAccess = [32]byte{1}
+	keyPolicy = [32]byte{2}
+	keyLimits = [32]byte{3}
 )
 
 // Processor processes the messages to run a collective signing PBFT consensus.
@@ -50,12 +59,18 @@ type processor struct {
 	rosterFac   authority.Factory
 	hashFactory crypto.HashFactory
 	access      access.Service
+	latency     *roundLatency
+	clock       core.Clock
+	observing   bool
 
 	context serde.Context
 	genesis blockstore.GenesisStore
 	blocks  blockstore.BlockStore
 
-	started chan struct{}
+	// genesisLock makes sure that only one genesis block is stored, as the
+	// setup, the import and the network can try to store one concurrently.
+	genesisLock sync.Mutex
+	started     chan struct{}
 }
 
 func newProcessor() *processor {
@@ -63,6 +78,8 @@ func newProcessor() *processor {
 		watcher: core.NewWatcher(),
 		context: json.NewContext(),
 		started: make(chan struct{}),
+		latency: &roundLatency{clock: core.SystemClock{}},
+		clock:   core.SystemClock{},
 	}
 }
 
@@ -112,11 +129,14 @@ func (h *processor) Invoke(from mino.Address, msg serde.Message) ([]byte, error)
 			}
 		}
 
-		digest, err := h.pbftsm.Prepare(from, in.GetBlock())
+		digest, err := h.pbftsm.Prepare(from, in.GetBlock(), in.GetSignature(),
+			in.GetViewChange())
 		if err != nil {
 			return nil, xerrors.Errorf("pbft prepare failed: %v", err)
 		}
 
+		h.latency.begin()
+
 		return digest[:], nil
 	case types.CommitMessage:
 		err := h.pbftsm.Commit(in.GetID(), in.GetSignature())
@@ -139,20 +159,33 @@ func (h *processor) Invoke(from mino.Address, msg serde.Message) ([]byte, error)
 
 // Process implements mino.Handler. It processes the messages from the RPC.
 func (h *processor) Process(req mino.Request) (serde.Message, error) {
+	if h.observing {
+		return nil, xerrors.New("observer does not take part in the consensus")
+	}
+
 	switch msg := req.Message.(type) {
 	case types.GenesisMessage:
 		if h.genesis.Exists() {
 			return nil, nil
 		}
 
-		root := msg.GetGenesis().GetRoot()
+		genesis := msg.GetGenesis()
+		root := genesis.GetRoot()
 
-		return nil, h.storeGenesis(msg.GetGenesis().GetRoster(), &root)
+		err := h.storeGenesis(newGenesisParam(*genesis), &root)
+		if xerrors.Is(err, errGenesisExists) {
+			// Another request has stored the genesis in the meantime.
+			return nil, nil
+		}
+
+		return nil, err
 	case types.DoneMessage:
 		err := h.pbftsm.Finalize(msg.GetID(), msg.GetSignature())
 		if err != nil {
 			return nil, xerrors.Errorf("pbftsm finalized failed: %v", err)
 		}
+
+		h.latency.end()
 	case types.ViewMessage:
 		param := pbft.ViewParam{
 			From:   req.Address,
@@ -189,14 +222,103 @@ func (h *processor) readRoster(tree hashtree.Tree) (authority.Authority, error)
 	return roster, nil
 }
 
-func (h *processor) storeGenesis(roster authority.Authority, match *types.Digest) error {
-	value, err := roster.Serialize(h.context)
+// readPolicy returns the leader policy of the tree. The chains created without
+// a policy use the sticky one.
+func (h *processor) readPolicy(tree hashtree.Tree) (pbft.LeaderPolicy, error) {
+	data, err := tree.Get(keyPolicy[:])
+	if err != nil {
+		return nil, xerrors.Errorf("read from tree: %v", err)
+	}
+
+	policy, err := pbft.ParseLeaderPolicy(string(data))
+	if err != nil {
+		return nil, xerrors.Errorf("invalid policy: %v", err)
+	}
+
+	return policy, nil
+}
+
+// readLimits returns the limits of the blocks stored in the tree, or no limit
+// if they have never been set.
+func (h *processor) readLimits(tree hashtree.Tree) (types.Limits, error) {
+	data, err := tree.Get(keyLimits[:])
 	if err != nil {
-		return xerrors.Errorf("failed to serialize roster: %v", err)
+		return types.Limits{}, xerrors.Errorf("read from tree: %v", err)
+	}
+
+	limits := types.Limits{}
+
+	if len(data) == 0 {
+		return limits, nil
+	}
+
+	err = limits.UnmarshalBinary(data)
+	if err != nil {
+		return limits, xerrors.Errorf("invalid limits: %v", err)
+	}
+
+	return limits, nil
+}
+
+// genesisParam contains the parameters of the genesis block of a chain.
+type genesisParam struct {
+	roster authority.Authority
+	policy string
+	limits types.Limits
+	state  []types.StateEntry
+}
+
+// newGenesisParam returns the parameters to create the same genesis block as
+// the given one.
+func newGenesisParam(genesis types.Genesis) genesisParam {
+	return genesisParam{
+		roster: genesis.GetRoster(),
+		policy: genesis.GetLeaderPolicy(),
+		limits: genesis.GetLimits(),
+		state:  genesis.GetInitialState(),
+	}
+}
+
+// makeGenesis stages the tree of a new chain and returns the genesis block with
+// the resulting tree root. The tree is not committed.
+func (h *processor) makeGenesis(param genesisParam) (types.Genesis, hashtree.StagingTree, error) {
+	value, err := param.roster.Serialize(h.context)
+	if err != nil {
+		return types.Genesis{}, nil, xerrors.Errorf("failed to serialize roster: %v", err)
+	}
+
+	_, err = pbft.ParseLeaderPolicy(param.policy)
+	if err != nil {
+		return types.Genesis{}, nil, xerrors.Errorf("invalid leader policy: %v", err)
+	}
+
+	keys := make(map[string]struct{})
+
+	for _, entry := range param.state {
+		if isReserved(entry.GetKey()) {
+			return types.Genesis{}, nil, xerrors.Errorf("reserved key '%x'", entry.GetKey())
+		}
+
+		// An entry would silently overwrite a previous one with the same key.
+		key := treeKey(entry.GetKey()).String()
+
+		_, found := keys[key]
+		if found {
+			return types.Genesis{}, nil, xerrors.Errorf("duplicate key '%x'", entry.GetKey())
+		}
+
+		keys[key] = struct{}{}
 	}
 
 	stageTree, err := h.tree.Get().Stage(func(snap store.Snapshot) error {
-		err := h.makeAccess(snap, roster)
+		for _, entry := range param.state {
+			err := snap.Set(entry.GetKey(), entry.GetValue())
+			if err != nil {
+				return xerrors.Errorf("failed to store entry: %v", err)
+			}
+		}
+
+		err := h.makeAccess(snap, param.roster)
 		if err != nil {
 			return xerrors.Errorf("failed to set access: %v", err)
 		}
@@ -206,22 +328,63 @@ func (h *processor) storeGenesis(roster authority.Authority, match *types.Digest
 			return xerrors.Errorf("failed to store roster: %v", err)
 		}
 
+		// The keys are left empty when the parameters are not set so that the
+		// tree root is the same as the chains created before them.
+		if param.policy != "" {
+			err = snap.Set(keyPolicy[:], []byte(param.policy))
+			if err != nil {
+				return xerrors.Errorf("failed to store policy: %v", err)
+			}
+		}
+
+		if !param.limits.IsZero() {
+			limits, _ := param.limits.MarshalBinary()
+
+			err = snap.Set(keyLimits[:], limits)
+			if err != nil {
+				return xerrors.Errorf("failed to store limits: %v", err)
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
-		return xerrors.Errorf("while updating tree: %v", err)
+		return types.Genesis{}, nil, xerrors.Errorf("while updating tree: %v", err)
 	}
 
 	root := types.Digest{}
 	copy(root[:], stageTree.GetRoot())
 
-	if match != nil && *match != root {
-		return xerrors.Errorf("mismatch tree root '%v' != '%v'", match, root)
+	genesis, err := types.NewGenesis(param.roster, types.WithGenesisRoot(root),
+		types.WithLeaderPolicy(param.policy), types.WithLimits(param.limits),
+		types.WithInitialState(param.state...))
+	if err != nil {
+		return types.Genesis{}, nil, xerrors.Errorf("creating genesis: %v", err)
+	}
+
+	return genesis, stageTree, nil
+}
+
+// storeGenesis creates the genesis block from the parameters and stores it. It
+// returns errGenesisExists if the node already has a genesis block, as the
+// service starts only once.
+func (h *processor) storeGenesis(param genesisParam, match *types.Digest) error {
+	h.genesisLock.Lock()
+	defer h.genesisLock.Unlock()
+
+	if h.genesis.Exists() {
+		return errGenesisExists
 	}
 
-	genesis, err := types.NewGenesis(roster, types.WithGenesisRoot(root))
+	genesis, stageTree, err := h.makeGenesis(param)
 	if err != nil {
-		return xerrors.Errorf("creating genesis: %v", err)
+		return err
+	}
+
+	root := genesis.GetRoot()
+
+	if match != nil && *match != root {
+		return xerrors.Errorf("mismatch tree root '%v' != '%v'", match, root)
 	}
 
 	err = stageTree.Commit()
@@ -241,6 +404,43 @@ func (h *processor) storeGenesis(roster authority.Authority, match *types.Digest
 	return nil
 }
 
+// matchGenesis returns an error if the genesis block of the node is not the
+// given one.
+func (h *processor) matchGenesis(genesis types.Genesis) error {
+	current, err := h.genesis.Get()
+	if err != nil {
+		return xerrors.Errorf("reading genesis: %v", err)
+	}
+
+	if current.GetHash() != genesis.GetHash() {
+		return xerrors.Errorf("mismatch genesis '%v' != '%v'",
+			genesis.GetHash(), current.GetHash())
+	}
+
+	return nil
+}
+
+// isReserved returns true if the key is one of the keys that the service
+// writes in the tree. The tree indexes the keys by their numerical value, so
+// that a key with a different length can still be the same.
+func isReserved(key []byte) bool {
+	value := treeKey(key)
+
+	for _, reserved := range [][32]byte{keyRoster, keyAccess, keyPolicy, keyLimits} {
+		if value.Cmp(treeKey(reserved[:])) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// treeKey returns the numerical value of the key that the tree uses as the
+// index, where "" and "\x00" are the same key for instance.
+func treeKey(key []byte) *big.Int {
+	return new(big.Int).SetBytes(key)
+}
+
 func (h *processor) makeAccess(store store.Snapshot, roster authority.Authority) error {
 	creds := viewchange.NewCreds(keyAccess[:])
 
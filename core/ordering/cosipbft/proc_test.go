@@ -1,7 +1,9 @@
 package cosipbft
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -31,7 +33,7 @@ func TestProcessor_BlockMessage_Invoke(t *testing.T) {
 		id:    expected,
 	}
 
-	msg := types.NewBlockMessage(types.Block{}, nil)
+	msg := types.NewBlockMessage(types.Block{}, nil, fake.Signature{}, nil)
 
 	id, err := proc.Invoke(fake.NewAddress(0), msg)
 	require.NoError(t, err)
@@ -42,7 +44,7 @@ func TestProcessor_BlockMessage_Invoke(t *testing.T) {
 	require.EqualError(t, err, fake.Err("pbft prepare failed"))
 
 	views := map[mino.Address]types.ViewMessage{fake.NewAddress(0): {}}
-	msg = types.NewBlockMessage(types.Block{}, views)
+	msg = types.NewBlockMessage(types.Block{}, views, fake.Signature{}, nil)
 	proc.pbftsm = fakeSM{err: fake.GetError()}
 	_, err = proc.Invoke(fake.NewAddress(0), msg)
 	require.EqualError(t, err, fake.Err("accept all"))
@@ -123,6 +125,116 @@ func TestProcessor_GenesisMessage_Process(t *testing.T) {
 	proc.genesis = fakeGenesisStore{errSet: fake.GetError()}
 	_, err = proc.Process(req)
 	require.EqualError(t, err, fake.Err("set genesis failed"))
+
+	badPolicy, err := types.NewGenesis(ro, types.WithLeaderPolicy("unknown"))
+	require.NoError(t, err)
+
+	_, err = proc.Process(mino.Request{Message: types.NewGenesisMessage(badPolicy)})
+	require.EqualError(t, err, "invalid leader policy: unknown policy 'unknown'")
+
+	badArgs, err := types.NewGenesis(ro, types.WithLeaderPolicy("weighted:unknown=2"))
+	require.NoError(t, err)
+
+	_, err = proc.Process(mino.Request{Message: types.NewGenesisMessage(badArgs)})
+	require.EqualError(t, err, "invalid leader policy: unexpected arguments 'unknown=2'")
+
+	reserved, err := types.NewGenesis(ro,
+		types.WithInitialState(types.NewStateEntry(keyRoster[:], nil)))
+	require.NoError(t, err)
+
+	_, err = proc.Process(mino.Request{Message: types.NewGenesisMessage(reserved)})
+	require.EqualError(t, err, fmt.Sprintf("reserved key '%x'", keyRoster[:]))
+
+	// The tree indexes the keys by their numerical value, so that a shorter
+	// key can be the same as a reserved one.
+	reserved, err = types.NewGenesis(ro,
+		types.WithInitialState(types.NewStateEntry([]byte{0}, nil)))
+	require.NoError(t, err)
+
+	_, err = proc.Process(mino.Request{Message: types.NewGenesisMessage(reserved)})
+	require.EqualError(t, err, "reserved key '00'")
+
+	duplicate, err := types.NewGenesis(ro, types.WithInitialState(
+		types.NewStateEntry([]byte("A"), []byte("B")),
+		types.NewStateEntry([]byte("\x00A"), []byte("C"))))
+	require.NoError(t, err)
+
+	_, err = proc.Process(mino.Request{Message: types.NewGenesisMessage(duplicate)})
+	require.EqualError(t, err, "duplicate key '0041'")
+
+	state, err := types.NewGenesis(ro,
+		types.WithInitialState(types.NewStateEntry([]byte("A"), []byte("B"))))
+	require.NoError(t, err)
+
+	proc.tree = blockstore.NewTreeCache(fakeTree{errStore: fake.GetError()})
+	_, err = proc.Process(mino.Request{Message: types.NewGenesisMessage(state)})
+	require.EqualError(t, err, fake.Err("while updating tree: failed to store entry"))
+}
+
+func TestProcessor_Concurrent_StoreGenesis(t *testing.T) {
+	proc := newProcessor()
+	proc.tree = blockstore.NewTreeCache(fakeTree{})
+	proc.genesis = blockstore.NewGenesisStore()
+	proc.access = fakeAccess{}
+
+	root := types.Digest{}
+	copy(root[:], []byte("root"))
+
+	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
+
+	genesis, err := types.NewGenesis(ro, types.WithGenesisRoot(root))
+	require.NoError(t, err)
+
+	n := 10
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- proc.storeGenesis(newGenesisParam(genesis), &root)
+		}()
+	}
+
+	stored := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			stored++
+		} else {
+			require.Equal(t, errGenesisExists, err)
+		}
+	}
+
+	require.Equal(t, 1, stored)
+
+	select {
+	case <-proc.started:
+	default:
+		t.Fatal("processor not started")
+	}
+}
+
+func TestProcessor_ReadPolicy(t *testing.T) {
+	proc := newProcessor()
+
+	_, err := proc.readPolicy(fakeTree{err: fake.GetError()})
+	require.EqualError(t, err, fake.Err("read from tree"))
+
+	_, err = proc.readPolicy(fakeTree{})
+	require.EqualError(t, err, "invalid policy: unknown policy '[]'")
+}
+
+func TestProcessor_ReadLimits(t *testing.T) {
+	proc := newProcessor()
+
+	_, err := proc.readLimits(fakeTree{errLimits: fake.GetError()})
+	require.EqualError(t, err, fake.Err("read from tree"))
+
+	limits, err := proc.readLimits(fakeTree{})
+	require.NoError(t, err)
+	require.True(t, limits.IsZero())
+
+	_, err = proc.readLimits(fakeTree{limits: []byte{1}})
+	require.EqualError(t, err, "invalid limits: invalid length 1 != 16")
 }
 
 func TestProcessor_DoneMessage_Process(t *testing.T) {
@@ -135,9 +247,13 @@ func TestProcessor_DoneMessage_Process(t *testing.T) {
 		Message: types.NewDone(types.Digest{}, fake.Signature{}),
 	}
 
+	proc.latency.begin()
+
 	resp, err := proc.Process(req)
 	require.NoError(t, err)
 	require.Nil(t, resp)
+	require.True(t, proc.latency.start.IsZero())
+	require.NotZero(t, proc.latency.get())
 
 	proc.pbftsm = fakeSM{err: fake.GetError()}
 	_, err = proc.Process(req)
@@ -190,6 +306,8 @@ type fakeSM struct {
 	errLeader error
 	state     pbft.State
 	id        types.Digest
+	block     types.Block
+	proposal  crypto.Signature
 	ch        chan pbft.State
 }
 
@@ -205,11 +323,25 @@ func (sm fakeSM) GetViews() map[mino.Address]pbft.View {
 	return nil
 }
 
+func (sm fakeSM) GetCommit() (types.Digest, types.Block) {
+	return sm.id, sm.block
+}
+
+func (sm fakeSM) GetProposal() crypto.Signature {
+	return sm.proposal
+}
+
 func (sm fakeSM) PrePrepare(authority.Authority) error {
 	return sm.err
 }
 
-func (sm fakeSM) Prepare(mino.Address, types.Block) (types.Digest, error) {
+func (sm fakeSM) GetViewChange() (*types.ViewChange, error) {
+	return nil, nil
+}
+
+func (sm fakeSM) Prepare(mino.Address, types.Block, crypto.Signature,
+	*types.ViewChange) (types.Digest, error) {
+
 	return sm.id, sm.err
 }
 
@@ -233,6 +365,18 @@ func (sm fakeSM) AcceptAll([]pbft.View) error {
 	return sm.err
 }
 
+func (sm fakeSM) CatchUp(types.BlockLink) error {
+	return sm.err
+}
+
+func (sm fakeSM) GetEvidences() ([]types.Evidence, error) {
+	return []types.Evidence{{}}, sm.err
+}
+
+func (sm fakeSM) Restore(types.Chain, hashtree.StagingTree) error {
+	return sm.err
+}
+
 func (sm fakeSM) Watch(context.Context) <-chan pbft.State {
 	return sm.ch
 }
@@ -277,6 +421,8 @@ type fakeTree struct {
 	errStage  error
 	errCommit error
 	errStore  error
+	errLimits error
+	limits    []byte
 }
 
 func (t fakeTree) GetRoot() []byte {
@@ -288,6 +434,10 @@ func (t fakeTree) GetPath(key []byte) (hashtree.Path, error) {
 }
 
 func (t fakeTree) Get(key []byte) ([]byte, error) {
+	if bytes.Equal(key, keyLimits[:]) {
+		return t.limits, t.errLimits
+	}
+
 	return []byte("[]"), t.err
 }
 
@@ -6,16 +6,29 @@
 package cosipbft
 
 import (
+	"encoding/json"
+
 	"go.dedis.ch/dela/core/ordering/cosipbft/types"
 	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/registry"
 	"golang.org/x/xerrors"
 )
 
+var proofFormats = registry.NewSimpleRegistry()
+
+func init() {
+	// The JSON format of the proof lives in this package as the JSON package
+	// of the module cannot depend on the service.
+	proofFormats.Register(serde.FormatJSON, proofFormat{})
+}
+
 // Proof is a combination of elements that will prove the inclusion or the
 // absence of a key/value pair in the given block.
 //
 // - implements ordering.Proof
+// - implements serde.Message
 type Proof struct {
 	path  hashtree.Path
 	chain types.Chain
@@ -39,6 +52,17 @@ func (p Proof) GetValue() []byte {
 	return p.path.GetValue()
 }
 
+// GetPath returns the path of the key in the tree.
+func (p Proof) GetPath() hashtree.Path {
+	return p.path
+}
+
+// GetChain returns the chain from the genesis block to the block that contains
+// the tree root of the path.
+func (p Proof) GetChain() types.Chain {
+	return p.chain
+}
+
 // Verify takes the genesis block and the verifier factory to verify the chain
 // up to the latest block.
 func (p Proof) Verify(genesis types.Genesis, fac crypto.VerifierFactory) error {
@@ -64,3 +88,144 @@ func (p Proof) Verify(genesis types.Genesis, fac crypto.VerifierFactory) error {
 
 	return nil
 }
+
+// Serialize implements serde.Message. It returns the data of the serialized
+// proof.
+func (p Proof) Serialize(ctx serde.Context) ([]byte, error) {
+	format := proofFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, p)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding proof failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// PathKey is the key of the path factory.
+type PathKey struct{}
+
+// ChainKey is the key of the chain factory.
+type ChainKey struct{}
+
+// ProofFactory is the factory to deserialize proofs.
+//
+// - implements serde.Factory
+type ProofFactory struct {
+	pathFac  hashtree.PathFactory
+	chainFac types.ChainFactory
+}
+
+// NewProofFactory creates a new proof factory from the path and the chain
+// factories.
+func NewProofFactory(pathFac hashtree.PathFactory, chainFac types.ChainFactory) ProofFactory {
+	return ProofFactory{
+		pathFac:  pathFac,
+		chainFac: chainFac,
+	}
+}
+
+// Deserialize implements serde.Factory. It returns the proof from the data if
+// appropriate, otherwise it returns an error.
+func (f ProofFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.ProofOf(ctx, data)
+}
+
+// ProofOf returns the proof from the data if appropriate, otherwise it returns
+// an error. The proof is not verified.
+func (f ProofFactory) ProofOf(ctx serde.Context, data []byte) (Proof, error) {
+	format := proofFormats.Get(ctx.GetFormat())
+
+	ctx = serde.WithFactory(ctx, PathKey{}, f.pathFac)
+	ctx = serde.WithFactory(ctx, ChainKey{}, f.chainFac)
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return Proof{}, xerrors.Errorf("decoding proof failed: %v", err)
+	}
+
+	proof, ok := msg.(Proof)
+	if !ok {
+		return Proof{}, xerrors.Errorf("invalid proof '%T'", msg)
+	}
+
+	return proof, nil
+}
+
+// ProofJSON is the JSON message for a proof.
+type ProofJSON struct {
+	Path  json.RawMessage
+	Chain json.RawMessage
+}
+
+// proofFormat is the JSON format to encode and decode proofs.
+//
+// - implements serde.FormatEngine
+type proofFormat struct{}
+
+// Encode implements serde.FormatEngine. It serializes the proof if
+// appropriate, otherwise it returns an error.
+func (f proofFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	proof, ok := msg.(Proof)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	path, err := proof.path.Serialize(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't serialize path: %v", err)
+	}
+
+	chain, err := proof.chain.Serialize(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't serialize chain: %v", err)
+	}
+
+	m := ProofJSON{
+		Path:  path,
+		Chain: chain,
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+// Decode implements serde.FormatEngine. It deserializes the proof if
+// appropriate, otherwise it returns an error.
+func (f proofFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := ProofJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	fac := ctx.GetFactory(PathKey{})
+
+	pathFac, ok := fac.(hashtree.PathFactory)
+	if !ok {
+		return nil, xerrors.Errorf("invalid path factory '%T'", fac)
+	}
+
+	path, err := pathFac.PathOf(ctx, m.Path)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't deserialize path: %v", err)
+	}
+
+	fac = ctx.GetFactory(ChainKey{})
+
+	chainFac, ok := fac.(types.ChainFactory)
+	if !ok {
+		return nil, xerrors.Errorf("invalid chain factory '%T'", fac)
+	}
+
+	chain, err := chainFac.ChainOf(ctx, m.Chain)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't deserialize chain: %v", err)
+	}
+
+	return newProof(path, chain), nil
+}
@@ -10,8 +10,17 @@ import (
 	"go.dedis.ch/dela/core/validation/simple"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
 )
 
+var testCtx = json.NewContext()
+
+func init() {
+	proofFormats.Register(fake.BadFormat, fake.NewBadFormat())
+	proofFormats.Register(fake.GoodFormat, fake.Format{Msg: fake.Message{}})
+}
+
 func TestProof_GetKey(t *testing.T) {
 	p := Proof{
 		path: fakePath{},
@@ -50,11 +59,93 @@ func TestProof_Verify(t *testing.T) {
 	require.EqualError(t, err, fake.Err("failed to verify chain"))
 }
 
+func TestProof_Serialize(t *testing.T) {
+	p := Proof{
+		path:  fakePath{},
+		chain: fakeChain{},
+	}
+
+	data, err := p.Serialize(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, `{"Path":{},"Chain":{}}`, string(data))
+
+	_, err = p.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding proof failed"))
+}
+
+func TestProofFactory_Deserialize(t *testing.T) {
+	fac := NewProofFactory(fakePathFac{}, fakeChainFac{})
+
+	msg, err := fac.Deserialize(testCtx, []byte(`{"Path":{},"Chain":{}}`))
+	require.NoError(t, err)
+	require.Equal(t, newProof(fakePath{}, fakeChain{}), msg)
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("decoding proof failed"))
+
+	_, err = fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "invalid proof 'fake.Message'")
+}
+
+func TestProofFormat_Encode(t *testing.T) {
+	format := proofFormat{}
+
+	_, err := format.Encode(testCtx, fake.Message{})
+	require.EqualError(t, err, "unsupported message 'fake.Message'")
+
+	p := Proof{path: fakePath{err: fake.GetError()}, chain: fakeChain{}}
+	_, err = format.Encode(testCtx, p)
+	require.EqualError(t, err, fake.Err("couldn't serialize path"))
+
+	p = Proof{path: fakePath{}, chain: fakeChain{err: fake.GetError()}}
+	_, err = format.Encode(testCtx, p)
+	require.EqualError(t, err, fake.Err("couldn't serialize chain"))
+
+	p = Proof{path: fakePath{}, chain: fakeChain{}}
+	_, err = format.Encode(fake.NewBadContext(), p)
+	require.EqualError(t, err, fake.Err("failed to marshal"))
+}
+
+func TestProofFormat_Decode(t *testing.T) {
+	format := proofFormat{}
+	data := []byte(`{"Path":{},"Chain":{}}`)
+
+	ctx := serde.WithFactory(testCtx, PathKey{}, fakePathFac{})
+	ctx = serde.WithFactory(ctx, ChainKey{}, fakeChainFac{})
+
+	_, err := format.Decode(ctx, data)
+	require.NoError(t, err)
+
+	_, err = format.Decode(fake.NewBadContext(), data)
+	require.EqualError(t, err, fake.Err("failed to unmarshal"))
+
+	_, err = format.Decode(testCtx, data)
+	require.EqualError(t, err, "invalid path factory '<nil>'")
+
+	badCtx := serde.WithFactory(ctx, PathKey{}, fakePathFac{err: fake.GetError()})
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, fake.Err("couldn't deserialize path"))
+
+	badCtx = serde.WithFactory(ctx, ChainKey{}, nil)
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, "invalid chain factory '<nil>'")
+
+	badCtx = serde.WithFactory(ctx, ChainKey{}, fakeChainFac{err: fake.GetError()})
+	_, err = format.Decode(badCtx, data)
+	require.EqualError(t, err, fake.Err("couldn't deserialize chain"))
+}
+
 // -----------------------------------------------------------------------------
 // Utility functions
 
 type fakePath struct {
 	hashtree.Path
+
+	err error
+}
+
+func (p fakePath) Serialize(serde.Context) ([]byte, error) {
+	return []byte("{}"), p.err
 }
 
 func (p fakePath) GetKey() []byte {
@@ -83,3 +174,27 @@ func (c fakeChain) GetBlock() types.Block {
 func (c fakeChain) Verify(types.Genesis, crypto.VerifierFactory) error {
 	return c.err
 }
+
+func (c fakeChain) Serialize(serde.Context) ([]byte, error) {
+	return []byte("{}"), c.err
+}
+
+type fakePathFac struct {
+	hashtree.PathFactory
+
+	err error
+}
+
+func (f fakePathFac) PathOf(serde.Context, []byte) (hashtree.Path, error) {
+	return fakePath{}, f.err
+}
+
+type fakeChainFac struct {
+	types.ChainFactory
+
+	err error
+}
+
+func (f fakeChainFac) ChainOf(serde.Context, []byte) (types.Chain, error) {
+	return fakeChain{}, f.err
+}
@@ -0,0 +1,135 @@
+// This file contains the implementation of the virtual clock of a simulation.
+
+package simulation
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// timer is a channel waiting for the clock to reach the deadline.
+type timer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// Clock is a virtual clock that only moves forward when it is advanced. The
+// timers fire in the order of their deadline.
+//
+// - implements core.Clock
+type Clock struct {
+	sync.Mutex
+
+	now      time.Time
+	timers   []timer
+	released bool
+
+	// counter is incremented for every new timer so that a simulation can
+	// detect that the nodes are still busy.
+	counter uint64
+}
+
+// NewClock creates a new virtual clock starting at the given time.
+func NewClock(start time.Time) *Clock {
+	return &Clock{
+		now: start,
+	}
+}
+
+// Now implements core.Clock. It returns the virtual time.
+func (c *Clock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// After implements core.Clock. It returns a channel that receives the virtual
+// time when the clock reaches the deadline.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	ch := make(chan time.Time, 1)
+
+	if d <= 0 || c.released {
+		ch <- c.now
+		return ch
+	}
+
+	c.counter++
+	c.timers = append(c.timers, timer{deadline: c.now.Add(d), ch: ch})
+
+	// The sort is stable so that the timers with the same deadline fire in
+	// the order they have been created.
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+
+	return ch
+}
+
+// Next returns the deadline of the earliest timer, or false if there is none.
+func (c *Clock) Next() (time.Time, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.timers) == 0 {
+		return time.Time{}, false
+	}
+
+	return c.timers[0].deadline, true
+}
+
+// Advance moves the clock forward by the given duration and fires the timers
+// that have reached their deadline.
+func (c *Clock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.advanceTo(c.now.Add(d))
+}
+
+// AdvanceTo moves the clock forward to the given time and fires the timers
+// that have reached their deadline. It does nothing if the time is in the
+// past.
+func (c *Clock) AdvanceTo(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.advanceTo(t)
+}
+
+// Release fires all the timers and makes the new ones fire immediately, which
+// lets the nodes stop without waiting for the time to pass.
+func (c *Clock) Release() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.released = true
+
+	for _, t := range c.timers {
+		t.ch <- c.now
+	}
+
+	c.timers = nil
+}
+
+func (c *Clock) advanceTo(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+
+	for len(c.timers) > 0 && !c.timers[0].deadline.After(c.now) {
+		c.timers[0].ch <- c.now
+		c.timers = c.timers[1:]
+	}
+}
+
+func (c *Clock) getCounter() uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.counter
+}
@@ -0,0 +1,49 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock_After(t *testing.T) {
+	clock := NewClock(Start)
+
+	ch := clock.After(0)
+	require.Equal(t, Start, <-ch)
+
+	first := clock.After(2 * time.Second)
+	second := clock.After(time.Second)
+	require.Equal(t, uint64(2), clock.getCounter())
+
+	next, found := clock.Next()
+	require.True(t, found)
+	require.Equal(t, Start.Add(time.Second), next)
+
+	clock.Advance(time.Second)
+	require.Equal(t, Start.Add(time.Second), <-second)
+	require.Len(t, first, 0)
+
+	clock.AdvanceTo(Start)
+	require.Equal(t, Start.Add(time.Second), clock.Now())
+
+	clock.AdvanceTo(Start.Add(time.Minute))
+	require.Equal(t, Start.Add(time.Minute), <-first)
+
+	_, found = clock.Next()
+	require.False(t, found)
+}
+
+func TestClock_Release(t *testing.T) {
+	clock := NewClock(Start)
+
+	ch := clock.After(time.Hour)
+
+	clock.Release()
+	require.Equal(t, Start, <-ch)
+
+	ch = clock.After(time.Hour)
+	require.Equal(t, Start, <-ch)
+	require.Equal(t, Start, clock.Now())
+}
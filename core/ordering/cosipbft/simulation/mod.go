@@ -0,0 +1,660 @@
+// Package simulation implements a harness that runs a set of CoSiPBFT nodes
+// over Minoch with a virtual clock and a scheduler that controls the delivery
+// of every message.
+//
+// The messages of the calls and the streams are intercepted on their way to
+// the recipient. The simulation moves forward one step at a time: a step
+// either delivers, drops or delays one of the pending messages, or it moves
+// the virtual clock to the next timer when no message is pending. The choices
+// are drawn from a random source seeded at creation, and the nodes use keys
+// derived from the same seed, so that a failure can be replayed by creating a
+// simulation with the same seed.
+//
+// A step waits for the nodes to settle before it decides, i.e. for every
+// goroutine to be blocked on a pending message, on a timer of the virtual clock
+// or on another blocked goroutine, and the pending messages are sorted before
+// one is drawn. The scheduling is therefore reproducible whatever the speed of
+// the nodes.
+//
+// The invariants are checked after every step: two nodes never have different
+// blocks at the same index. The convergence of the chains can be awaited with
+// Converged.
+package simulation
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela/core/access/darc"
+	"go.dedis.ch/dela/core/execution"
+	"go.dedis.ch/dela/core/execution/native"
+	"go.dedis.ch/dela/core/ordering/cosipbft"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/core/ordering/cosipbft/blockstore"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/hashtree/binprefix"
+	"go.dedis.ch/dela/core/store/kv"
+	poolimpl "go.dedis.ch/dela/core/txn/pool/gossip"
+	"go.dedis.ch/dela/core/txn/signed"
+	"go.dedis.ch/dela/core/validation/simple"
+	"go.dedis.ch/dela/cosi/threshold"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/gossip"
+	"go.dedis.ch/dela/mino/minoch"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"go.dedis.ch/kyber/v3/pairing"
+	"go.dedis.ch/kyber/v3/xof/blake2xb"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// ContractName is the name of the contract that executes the transactions
+	// of the simulation. It stores the value at the key of the transaction.
+	ContractName = "simulation"
+
+	// KeyArg is the argument of the transactions with the key to set.
+	KeyArg = "simulation:key"
+
+	// ValueArg is the argument of the transactions with the value to set.
+	ValueArg = "simulation:value"
+
+	// awaitSteps is the maximum number of steps to complete an operation on
+	// the nodes, like the setup of the chain.
+	awaitSteps = 10000
+)
+
+// Start is the time of the virtual clock when a simulation starts.
+var Start = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var suite = pairing.NewSuiteBn256()
+
+type template struct {
+	seed      int64
+	nodes     int
+	dropRate  float64
+	delayRate float64
+	maxDelay  time.Duration
+	opts      []cosipbft.ServiceOption
+}
+
+// Option is the type of options to create a simulation.
+type Option func(*template)
+
+// WithSeed is an option to set the seed of the random source. A random seed is
+// used by default.
+func WithSeed(seed int64) Option {
+	return func(tmpl *template) {
+		tmpl.seed = seed
+	}
+}
+
+// WithNodes is an option to set the number of nodes.
+func WithNodes(n int) Option {
+	return func(tmpl *template) {
+		tmpl.nodes = n
+	}
+}
+
+// WithDrops is an option to drop a message with the given probability.
+func WithDrops(rate float64) Option {
+	return func(tmpl *template) {
+		tmpl.dropRate = rate
+	}
+}
+
+// WithDelays is an option to delay a message with the given probability, by a
+// random amount of virtual time up to the maximum.
+func WithDelays(rate float64, max time.Duration) Option {
+	return func(tmpl *template) {
+		tmpl.delayRate = rate
+		tmpl.maxDelay = max
+	}
+}
+
+// WithServiceOptions is an option to set the options of the services of the
+// nodes.
+func WithServiceOptions(opts ...cosipbft.ServiceOption) Option {
+	return func(tmpl *template) {
+		tmpl.opts = append(tmpl.opts, opts...)
+	}
+}
+
+// node is a member of the simulation.
+type node struct {
+	mino    *minoch.Minoch
+	service *cosipbft.Service
+	pool    *poolimpl.Pool
+	blocks  blockstore.BlockStore
+	db      kv.DB
+	signer  crypto.Signer
+
+	// checked is the number of blocks of the node already checked against the
+	// invariants.
+	checked uint64
+}
+
+// Simulation is a set of CoSiPBFT nodes connected through a network that is
+// controlled by the simulation.
+type Simulation struct {
+	sync.Mutex
+
+	seed    int64
+	rand    *rand.Rand
+	clock   *Clock
+	dir     string
+	context serde.Context
+	nodes   []*node
+	indices map[string]int
+
+	dropRate  float64
+	delayRate float64
+	maxDelay  time.Duration
+
+	signer crypto.Signer
+	nonce  uint64
+
+	step     uint64
+	received uint64
+	pending  []*envelope
+	delayed  []*envelope
+	groups   map[int]int
+	closing  bool
+	trace    []string
+
+	// digests are the hashes of the blocks seen at each index.
+	digests map[uint64]types.Digest
+}
+
+// New creates a new simulation. The nodes are created but the chain needs to
+// be set up.
+func New(opts ...Option) (*Simulation, error) {
+	tmpl := template{
+		seed:  time.Now().UnixNano(),
+		nodes: 4,
+	}
+
+	for _, opt := range opts {
+		opt(&tmpl)
+	}
+
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-simulation")
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create directory: %v", err)
+	}
+
+	s := &Simulation{
+		seed:      tmpl.seed,
+		rand:      rand.New(rand.NewSource(tmpl.seed)),
+		clock:     NewClock(Start),
+		dir:       dir,
+		context:   json.NewContext(),
+		indices:   make(map[string]int),
+		dropRate:  tmpl.dropRate,
+		delayRate: tmpl.delayRate,
+		maxDelay:  tmpl.maxDelay,
+		groups:    make(map[int]int),
+		digests:   make(map[uint64]types.Digest),
+	}
+
+	s.signer, err = s.makeSigner()
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, xerrors.Errorf("failed to create signer: %v", err)
+	}
+
+	manager := minoch.NewManager()
+
+	for i := 0; i < tmpl.nodes; i++ {
+		n, err := s.makeNode(manager, i, tmpl.opts)
+		if err != nil {
+			s.Close()
+			return nil, xerrors.Errorf("failed to create node %d: %v", i, err)
+		}
+
+		s.nodes = append(s.nodes, n)
+		s.indices[n.mino.GetAddress().String()] = i
+	}
+
+	return s, nil
+}
+
+// GetSeed returns the seed of the simulation.
+func (s *Simulation) GetSeed() int64 {
+	return s.seed
+}
+
+// GetClock returns the virtual clock of the simulation.
+func (s *Simulation) GetClock() *Clock {
+	return s.clock
+}
+
+// Len returns the number of nodes.
+func (s *Simulation) Len() int {
+	return len(s.nodes)
+}
+
+// GetService returns the ordering service of the node.
+func (s *Simulation) GetService(index int) *cosipbft.Service {
+	return s.nodes[index].service
+}
+
+// GetBlockStore returns the block store of the node.
+func (s *Simulation) GetBlockStore(index int) blockstore.BlockStore {
+	return s.nodes[index].blocks
+}
+
+// GetTrace returns the list of the decisions taken by the simulation.
+func (s *Simulation) GetTrace() []string {
+	s.Lock()
+	defer s.Unlock()
+
+	return append([]string{}, s.trace...)
+}
+
+// Setup creates the chain with all the nodes in the roster.
+func (s *Simulation) Setup() error {
+	addrs := make([]mino.Address, len(s.nodes))
+	pubkeys := make([]crypto.PublicKey, len(s.nodes))
+
+	for i, n := range s.nodes {
+		addrs[i] = n.mino.GetAddress()
+		pubkeys[i] = n.signer.GetPublicKey()
+	}
+
+	roster := authority.New(addrs, pubkeys)
+
+	err := s.await(func() error {
+		return s.nodes[0].service.Setup(context.Background(), roster)
+	})
+	if err != nil {
+		return xerrors.Errorf("setup failed: %v", err)
+	}
+
+	return nil
+}
+
+// AddTransaction adds a new transaction to the pool of the node, which then
+// gossips it to the others.
+func (s *Simulation) AddTransaction(index int) error {
+	s.Lock()
+	nonce := s.nonce
+	s.nonce++
+	s.Unlock()
+
+	tx, err := signed.NewTransaction(
+		nonce,
+		s.signer.GetPublicKey(),
+		signed.WithArg(native.ContractArg, []byte(ContractName)),
+		signed.WithArg(KeyArg, []byte(fmt.Sprintf("key%d", nonce))),
+		signed.WithArg(ValueArg, []byte(fmt.Sprintf("value%d", nonce))),
+	)
+	if err != nil {
+		return xerrors.Errorf("failed to create transaction: %v", err)
+	}
+
+	err = tx.Sign(s.signer)
+	if err != nil {
+		return xerrors.Errorf("failed to sign transaction: %v", err)
+	}
+
+	err = s.await(func() error {
+		return s.nodes[index].pool.Add(tx)
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to add transaction: %v", err)
+	}
+
+	return nil
+}
+
+// Partition splits the nodes in groups that cannot communicate with each
+// other. The nodes that are not in any group form a group together.
+func (s *Simulation) Partition(groups ...[]int) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.groups = make(map[int]int)
+
+	for i, group := range groups {
+		for _, index := range group {
+			s.groups[index] = i + 1
+		}
+	}
+
+	s.record("partition %v", groups)
+}
+
+// Heal removes the partitions.
+func (s *Simulation) Heal() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.groups = make(map[int]int)
+
+	s.record("heal")
+}
+
+// Step waits for the nodes to settle and then either decides the fate of one
+// of the pending messages, or moves the clock to the next event. It returns an
+// error if an invariant is broken.
+func (s *Simulation) Step() error {
+	s.waitSettle()
+
+	return s.next()
+}
+
+// Run performs the given number of steps.
+func (s *Simulation) Run(steps int) error {
+	for i := 0; i < steps; i++ {
+		err := s.Step()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunUntil performs steps until the condition is true. It returns an error if
+// the condition is still false after the maximum number of steps. The
+// condition is evaluated once the nodes have settled so that it is reached at
+// the same step for the same seed.
+func (s *Simulation) RunUntil(cond func() bool, maxSteps int) error {
+	for i := 0; i <= maxSteps; i++ {
+		s.waitSettle()
+
+		if cond() {
+			return nil
+		}
+
+		if i == maxSteps {
+			break
+		}
+
+		err := s.next()
+		if err != nil {
+			return err
+		}
+	}
+
+	return xerrors.Errorf("seed %d: condition not reached after %d steps",
+		s.seed, maxSteps)
+}
+
+// next decides the fate of one of the pending messages, or moves the clock to
+// the next event, and then checks the invariants.
+func (s *Simulation) next() error {
+	s.Lock()
+
+	s.step++
+
+	if len(s.pending) > 0 {
+		sortEnvelopes(s.pending)
+
+		i := s.rand.Intn(len(s.pending))
+		env := s.pending[i]
+		s.pending = append(s.pending[:i], s.pending[i+1:]...)
+
+		s.decide(env)
+	} else {
+		s.tick()
+	}
+
+	s.Unlock()
+
+	err := s.check()
+	if err != nil {
+		return xerrors.Errorf("seed %d: step %d: %v", s.seed, s.step, err)
+	}
+
+	return nil
+}
+
+// Converged returns true when all the nodes have the same chain with at least
+// the given number of blocks.
+func (s *Simulation) Converged(min uint64) bool {
+	var last types.Digest
+
+	for i, n := range s.nodes {
+		if n.blocks.Len() < min || n.blocks.Len() != s.nodes[0].blocks.Len() {
+			return false
+		}
+
+		if n.blocks.Len() == 0 {
+			continue
+		}
+
+		link, err := n.blocks.Last()
+		if err != nil {
+			return false
+		}
+
+		if i > 0 && link.GetBlock().GetHash() != last {
+			return false
+		}
+
+		last = link.GetBlock().GetHash()
+	}
+
+	return true
+}
+
+// Close drops the pending messages and stops the nodes.
+func (s *Simulation) Close() error {
+	s.Lock()
+
+	s.closing = true
+
+	for _, env := range append(s.pending, s.delayed...) {
+		env.ch <- false
+	}
+
+	s.pending = nil
+	s.delayed = nil
+
+	s.Unlock()
+
+	s.clock.Release()
+
+	for _, n := range s.nodes {
+		err := n.service.Close()
+		if err != nil {
+			return xerrors.Errorf("failed to close service: %v", err)
+		}
+
+		err = n.pool.Close()
+		if err != nil {
+			return xerrors.Errorf("failed to close pool: %v", err)
+		}
+
+		err = n.db.Close()
+		if err != nil {
+			return xerrors.Errorf("failed to close database: %v", err)
+		}
+	}
+
+	err := os.RemoveAll(s.dir)
+	if err != nil {
+		return xerrors.Errorf("failed to remove directory: %v", err)
+	}
+
+	return nil
+}
+
+// check verifies that no two nodes have different blocks at the same index.
+func (s *Simulation) check() error {
+	for i, n := range s.nodes {
+		for ; n.checked < n.blocks.Len(); n.checked++ {
+			link, err := n.blocks.GetByIndex(n.checked)
+			if err != nil {
+				return xerrors.Errorf("node %d: failed to read block %d: %v", i, n.checked, err)
+			}
+
+			digest := link.GetBlock().GetHash()
+
+			known, found := s.digests[n.checked]
+			if !found {
+				s.digests[n.checked] = digest
+				continue
+			}
+
+			if known != digest {
+				return xerrors.Errorf("fork at index %d: node %d has %v instead of %v",
+					n.checked, i, digest, known)
+			}
+		}
+	}
+
+	return nil
+}
+
+// await runs the function while performing steps until it returns.
+func (s *Simulation) await(fn func() error) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- fn()
+	}()
+
+	for i := 0; i < awaitSteps; i++ {
+		s.waitSettle()
+
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+
+		err := s.next()
+		if err != nil {
+			return err
+		}
+	}
+
+	return xerrors.Errorf("seed %d: not done after %d steps", s.seed, awaitSteps)
+}
+
+// record appends a decision to the trace. The lock must be held.
+func (s *Simulation) record(format string, args ...interface{}) {
+	s.trace = append(s.trace, fmt.Sprintf("%d %v: ", s.step, s.clock.Now().Sub(Start))+
+		fmt.Sprintf(format, args...))
+}
+
+func (s *Simulation) makeSigner() (crypto.AggregateSigner, error) {
+	seed := make([]byte, 32)
+	s.rand.Read(seed)
+
+	data, err := suite.Scalar().Pick(blake2xb.New(seed)).MarshalBinary()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal scalar: %v", err)
+	}
+
+	return bls.NewSignerFromBytes(data)
+}
+
+func (s *Simulation) makeNode(manager *minoch.Manager, index int,
+	opts []cosipbft.ServiceOption) (*node, error) {
+
+	m, err := minoch.NewMinoch(manager, fmt.Sprintf("node%d", index))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create mino: %v", err)
+	}
+
+	m.AddFilter(func(req mino.Request) bool {
+		return s.intercept(req.Address, index, req.Message)
+	})
+
+	signer, err := s.makeSigner()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create signer: %v", err)
+	}
+
+	c := threshold.NewThreshold(m, signer)
+	c.SetThreshold(threshold.ByzantineThreshold)
+
+	db, err := kv.New(filepath.Join(s.dir, fmt.Sprintf("node%d.db", index)))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to open database: %v", err)
+	}
+
+	txFac := signed.NewTransactionFactory()
+
+	pool, err := poolimpl.NewPool(gossip.NewFlat(m, txFac))
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create pool: %v", err)
+	}
+
+	tree := binprefix.NewMerkleTree(db, binprefix.Nonce{})
+
+	exec := native.NewExecution()
+	exec.Set(ContractName, contract{})
+
+	access := darc.NewService(json.NewContext())
+
+	rosterFac := authority.NewFactory(m.GetAddressFactory(), c.GetPublicKeyFactory())
+	cosipbft.RegisterRosterContract(exec, rosterFac, c.GetSigner().GetSignatureFactory(), access)
+
+	blocks := blockstore.NewInMemory()
+
+	param := cosipbft.ServiceParam{
+		Mino:       m,
+		Cosi:       c,
+		Validation: simple.NewService(exec, txFac),
+		Access:     access,
+		Pool:       pool,
+		Tree:       tree,
+		DB:         db,
+	}
+
+	opts = append([]cosipbft.ServiceOption{
+		cosipbft.WithClock(s.clock),
+		cosipbft.WithBlockStore(blocks),
+	}, opts...)
+
+	srvc, err := cosipbft.NewService(param, opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create service: %v", err)
+	}
+
+	n := &node{
+		mino:    m,
+		service: srvc,
+		pool:    pool,
+		blocks:  blocks,
+		db:      db,
+		signer:  signer,
+	}
+
+	return n, nil
+}
+
+// contract is the contract of the transactions of the simulation.
+//
+// - implements native.Contract
+type contract struct{}
+
+// Execute implements native.Contract. It stores the value of the transaction
+// at its key.
+func (contract) Execute(snap store.Snapshot, step execution.Step) error {
+	key := step.Current.GetArg(KeyArg)
+	if len(key) == 0 {
+		return xerrors.New("missing key")
+	}
+
+	err := snap.Set(key, step.Current.GetArg(ValueArg))
+	if err != nil {
+		return xerrors.Errorf("failed to set value: %v", err)
+	}
+
+	return nil
+}
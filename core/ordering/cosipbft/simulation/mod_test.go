@@ -0,0 +1,115 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/types"
+)
+
+func TestSimulation_Scenario_Faults(t *testing.T) {
+	run := func() []string {
+		sim, err := New(WithSeed(2), WithDrops(0.05),
+			WithDelays(0.1, 500*time.Millisecond))
+		require.NoError(t, err)
+
+		defer sim.Close()
+
+		require.Equal(t, int64(2), sim.GetSeed())
+
+		err = sim.Setup()
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			err = sim.AddTransaction(i % sim.Len())
+			require.NoError(t, err)
+
+			err = sim.RunUntil(converged(sim, uint64(i+1)), 1000)
+			require.NoError(t, err)
+		}
+
+		return sim.GetTrace()
+	}
+
+	// The same seed must produce the same execution.
+	require.Equal(t, run(), run())
+}
+
+func TestSimulation_Scenario_Partition(t *testing.T) {
+	sim, err := New(WithSeed(3), WithNodes(4))
+	require.NoError(t, err)
+
+	defer sim.Close()
+
+	err = sim.Setup()
+	require.NoError(t, err)
+
+	sim.Partition([]int{3})
+
+	err = sim.AddTransaction(0)
+	require.NoError(t, err)
+
+	err = sim.RunUntil(func() bool {
+		return sim.GetBlockStore(0).Len() == 1 &&
+			sim.GetBlockStore(1).Len() == 1 &&
+			sim.GetBlockStore(2).Len() == 1
+	}, 1000)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), sim.GetBlockStore(3).Len())
+
+	sim.Heal()
+
+	err = sim.AddTransaction(1)
+	require.NoError(t, err)
+
+	err = sim.RunUntil(converged(sim, 2), 1000)
+	require.NoError(t, err)
+}
+
+func TestSimulation_RunUntil(t *testing.T) {
+	sim, err := New(WithSeed(1), WithNodes(1))
+	require.NoError(t, err)
+
+	defer sim.Close()
+
+	err = sim.RunUntil(func() bool { return false }, 2)
+	require.EqualError(t, err, "seed 1: condition not reached after 2 steps")
+
+	err = sim.Run(2)
+	require.NoError(t, err)
+}
+
+func TestSimulation_Check(t *testing.T) {
+	sim, err := New(WithSeed(1), WithNodes(2))
+	require.NoError(t, err)
+
+	defer sim.Close()
+
+	err = sim.Setup()
+	require.NoError(t, err)
+
+	err = sim.AddTransaction(0)
+	require.NoError(t, err)
+
+	err = sim.RunUntil(converged(sim, 1), 100)
+	require.NoError(t, err)
+
+	sim.Lock()
+	sim.digests[0] = types.Digest{1}
+	sim.nodes[0].checked = 0
+	sim.Unlock()
+
+	err = sim.Step()
+	require.Error(t, err)
+	require.Regexp(t, "^seed 1: step [0-9]+: fork at index 0: node 0 has", err.Error())
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func converged(sim *Simulation, min uint64) func() bool {
+	return func() bool {
+		return sim.Converged(min)
+	}
+}
@@ -0,0 +1,169 @@
+// This file contains the interception of the messages and the scheduling of
+// their delivery.
+
+package simulation
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+)
+
+// envelope is a message intercepted on its way to a node, which waits for the
+// simulation to decide if it is delivered.
+type envelope struct {
+	from int
+	to   int
+	kind string
+	key  string
+
+	// at is the virtual time when a delayed message is delivered.
+	at time.Time
+
+	ch chan bool
+}
+
+// intercept blocks until the simulation decides if the message is delivered,
+// and it returns true if it is.
+func (s *Simulation) intercept(from mino.Address, to int, msg serde.Message) bool {
+	env := &envelope{
+		to:   to,
+		kind: fmt.Sprintf("%T", msg),
+		ch:   make(chan bool, 1),
+	}
+
+	// The pending messages are sorted by their content so that the order in
+	// which they arrive does not change the scheduling.
+	data, err := msg.Serialize(s.context)
+	if err == nil {
+		env.key = fmt.Sprintf("%x", sha256.Sum256(data))
+	}
+
+	s.Lock()
+
+	index, found := s.indices[from.String()]
+	if !found {
+		index = -1
+	}
+
+	env.from = index
+
+	if s.closing {
+		s.Unlock()
+		return false
+	}
+
+	s.received++
+	s.pending = append(s.pending, env)
+
+	s.Unlock()
+
+	return <-env.ch
+}
+
+// decide delivers, drops or delays the message. The lock must be held.
+func (s *Simulation) decide(env *envelope) {
+	if env.from == env.to {
+		// A node always receives its own messages.
+		s.deliver(env, true)
+		return
+	}
+
+	if s.groups[env.from] != s.groups[env.to] {
+		s.deliver(env, false)
+		return
+	}
+
+	draw := s.rand.Float64()
+
+	switch {
+	case draw < s.dropRate:
+		s.deliver(env, false)
+	case draw < s.dropRate+s.delayRate && s.maxDelay > 0:
+		env.at = s.clock.Now().Add(time.Duration(s.rand.Int63n(int64(s.maxDelay))) + 1)
+		s.delayed = append(s.delayed, env)
+
+		s.record("delay %d->%d %s until %v", env.from, env.to, env.kind, env.at.Sub(Start))
+	default:
+		s.deliver(env, true)
+	}
+}
+
+// tick moves the clock to the next timer or delayed message, and delivers the
+// delayed messages that are due. The lock must be held.
+func (s *Simulation) tick() {
+	next, found := s.clock.Next()
+
+	for _, env := range s.delayed {
+		if !found || env.at.Before(next) {
+			next = env.at
+			found = true
+		}
+	}
+
+	if !found {
+		// Nothing is going to happen anymore.
+		return
+	}
+
+	s.clock.AdvanceTo(next)
+
+	now := s.clock.Now()
+
+	s.record("tick")
+
+	sortEnvelopes(s.delayed)
+
+	delayed := s.delayed[:0]
+	for _, env := range s.delayed {
+		if env.at.After(now) {
+			delayed = append(delayed, env)
+		} else {
+			s.deliver(env, true)
+		}
+	}
+
+	s.delayed = delayed
+}
+
+// deliver releases the message. The lock must be held.
+func (s *Simulation) deliver(env *envelope, accept bool) {
+	action := "deliver"
+	if !accept {
+		action = "drop"
+	}
+
+	s.record("%s %d->%d %s", action, env.from, env.to, env.kind)
+
+	env.ch <- accept
+}
+
+// sortEnvelopes sorts the envelopes by their delivery time, and then by their
+// content.
+func sortEnvelopes(envs []*envelope) {
+	sort.SliceStable(envs, func(i, j int) bool {
+		a, b := envs[i], envs[j]
+
+		if !a.at.Equal(b.at) {
+			return a.at.Before(b.at)
+		}
+
+		if a.from != b.from {
+			return a.from < b.from
+		}
+
+		if a.to != b.to {
+			return a.to < b.to
+		}
+
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+
+		return a.key < b.key
+	})
+}
@@ -0,0 +1,129 @@
+// This file contains the detection of the moment when the nodes cannot make
+// progress anymore without a decision of the simulation.
+
+package simulation
+
+import (
+	"bytes"
+	"runtime"
+	"time"
+)
+
+// pollInterval is the amount of real time between two inspections of the
+// goroutines while some of them are still busy. It only changes how fast the
+// simulation runs, not the decisions it takes.
+const pollInterval = 100 * time.Microsecond
+
+// busyStates are the states of a goroutine that makes progress on its own, as
+// opposed to a goroutine waiting for another one. A goroutine waiting for a
+// message or for the virtual clock is blocked on a channel, whereas a
+// goroutine waiting for the runtime will resume without any help.
+var busyStates = []string{
+	"running",
+	"runnable",
+	"syscall",
+	"sleep",
+	"IO wait",
+	"GC assist wait",
+	"semacquire",
+}
+
+// waitSettle waits until the nodes are settled, which means that every
+// goroutine is blocked either on a message waiting for a decision, on a timer
+// of the virtual clock or on another blocked goroutine. Nothing can happen
+// anymore until the simulation takes a decision, so the decision does not
+// depend on how fast the nodes are.
+func (s *Simulation) waitSettle() {
+	last := s.activity()
+
+	for {
+		for countBusy() > 0 {
+			time.Sleep(pollInterval)
+		}
+
+		// A goroutine could be woken up in between two inspections, so the
+		// nodes are only settled if nothing has happened in the meantime.
+		curr := s.activity()
+		if curr == last {
+			return
+		}
+
+		last = curr
+	}
+}
+
+// activity returns the number of messages received and timers created so far.
+func (s *Simulation) activity() uint64 {
+	s.Lock()
+	received := s.received
+	s.Unlock()
+
+	return received + s.clock.getCounter()
+}
+
+// countBusy returns the number of goroutines other than the caller that are
+// making progress.
+func countBusy() int {
+	buf := make([]byte, 1<<16)
+
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return parseBusy(buf[:n])
+		}
+
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseBusy returns the number of goroutines of the dump that are making
+// progress. The first goroutine is the caller and it is ignored.
+func parseBusy(dump []byte) int {
+	busy := 0
+
+	for i, g := range bytes.Split(dump, []byte("\n\n")) {
+		if i == 0 {
+			continue
+		}
+
+		if isBusy(g) {
+			busy++
+		}
+	}
+
+	return busy
+}
+
+// isBusy returns true if the state in the header of the goroutine is one of
+// the busy states.
+func isBusy(g []byte) bool {
+	start := bytes.IndexByte(g, '[')
+	end := bytes.IndexByte(g, ']')
+
+	if start < 0 || end < start {
+		return false
+	}
+
+	// The state can be followed by the time spent in it, e.g. "select, 2
+	// minutes".
+	state := string(bytes.SplitN(g[start+1:end], []byte(","), 2)[0])
+
+	switch {
+	case state == "syscall" && bytes.Contains(g, []byte("os/signal.signal_recv")):
+		// The goroutine of the signals always waits in a system call.
+		return false
+	case state == "semacquire" && bytes.Contains(g, []byte("]:\nsync.runtime_")):
+		// The older versions of Go report the same state for the primitives
+		// of the sync package and for the semaphores of the runtime, like
+		// when the goroutine waits for the garbage collector.
+		return false
+	}
+
+	for _, busy := range busyStates {
+		if state == busy {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulation_WaitSettle(t *testing.T) {
+	sim, err := New(WithSeed(1), WithNodes(1))
+	require.NoError(t, err)
+
+	defer sim.Close()
+
+	done := make(chan struct{})
+
+	go func() {
+		<-sim.clock.After(time.Second)
+		close(done)
+	}()
+
+	sim.waitSettle()
+	require.Equal(t, uint64(1), sim.clock.getCounter())
+
+	sim.clock.Advance(time.Second)
+	<-done
+}
+
+func TestParseBusy(t *testing.T) {
+	dump := "goroutine 1 [running]:\nmain.main()\n\n" +
+		"goroutine 2 [chan receive]:\nmain.wait()\n\n" +
+		"goroutine 3 [select, 2 minutes]:\nmain.loop()\n\n" +
+		"goroutine 4 [runnable]:\nmain.work()\n\n" +
+		"goroutine 5 [syscall]:\nos/signal.signal_recv()\n\n" +
+		"goroutine 6 [syscall]:\nsyscall.Fdatasync()\n\n" +
+		"goroutine 7 [semacquire]:\nsync.runtime_SemacquireMutex()\n\n" +
+		"goroutine 8 [semacquire]:\nmain.alloc()\n\n" +
+		"goroutine 9 [sync.Mutex.Lock]:\nmain.lock()"
+
+	require.Equal(t, 3, parseBusy([]byte(dump)))
+	require.Equal(t, 0, parseBusy([]byte("goroutine 1 [running]:\n")))
+	require.False(t, isBusy([]byte("malformed")))
+}
@@ -0,0 +1,99 @@
+// This file contains the implementation of the round timeout of the service,
+// either static or adapting to the latency observed on the network.
+
+package cosipbft
+
+import (
+	"sync"
+	"time"
+
+	"go.dedis.ch/dela/core"
+)
+
+const (
+	// adaptiveFactor is the multiplier applied to the average latency of the
+	// prepare and commit phases to get the round timeout.
+	adaptiveFactor = 4
+
+	// latencyWeight is the inverse of the weight given to a new observation in
+	// the moving average of the latency.
+	latencyWeight = 8
+)
+
+// roundLatency keeps track of the time spent between the prepare phase and the
+// finalization of a block, as an exponentially weighted moving average.
+type roundLatency struct {
+	sync.Mutex
+	clock   core.Clock
+	start   time.Time
+	average time.Duration
+}
+
+// begin marks the beginning of a round, i.e. the block has been prepared.
+func (l *roundLatency) begin() {
+	l.Lock()
+	l.start = l.clock.Now()
+	l.Unlock()
+}
+
+// end marks the end of a round if one has begun, and updates the average.
+func (l *roundLatency) end() {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.start.IsZero() {
+		return
+	}
+
+	l.observe(l.clock.Now().Sub(l.start))
+	l.start = time.Time{}
+}
+
+func (l *roundLatency) observe(d time.Duration) {
+	if l.average == 0 {
+		l.average = d
+		return
+	}
+
+	l.average += (d - l.average) / latencyWeight
+}
+
+// get returns the current average, or zero when nothing has been observed yet.
+func (l *roundLatency) get() time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	return l.average
+}
+
+// adaptiveTimeout is the configuration of the adaptive round timeout. The
+// timeout is derived from the average latency of the rounds and is always kept
+// between the bounds.
+type adaptiveTimeout struct {
+	min time.Duration
+	max time.Duration
+}
+
+// compute returns the timeout for the given latency. The initial value is used
+// when no latency has been observed yet. The timeout is doubled for each
+// consecutive failed round until it reaches the upper bound.
+func (a adaptiveTimeout) compute(latency, initial time.Duration, failures uint) time.Duration {
+	timeout := initial
+	if latency > 0 {
+		timeout = latency * adaptiveFactor
+	}
+
+	for i := uint(0); i < failures && timeout < a.max; i++ {
+		timeout *= 2
+	}
+
+	if timeout < a.min {
+		return a.min
+	}
+
+	if timeout > a.max {
+		return a.max
+	}
+
+	return timeout
+}
@@ -0,0 +1,43 @@
+package cosipbft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core"
+)
+
+func TestRoundLatency_Observe(t *testing.T) {
+	latency := &roundLatency{clock: core.SystemClock{}}
+
+	latency.end()
+	require.Zero(t, latency.get())
+
+	latency.observe(80 * time.Millisecond)
+	require.Equal(t, 80*time.Millisecond, latency.get())
+
+	latency.observe(160 * time.Millisecond)
+	require.Equal(t, 90*time.Millisecond, latency.get())
+
+	latency.begin()
+	require.False(t, latency.start.IsZero())
+
+	latency.end()
+	require.True(t, latency.start.IsZero())
+}
+
+func TestAdaptiveTimeout_Compute(t *testing.T) {
+	timeout := adaptiveTimeout{min: time.Second, max: 10 * time.Second}
+
+	require.Equal(t, 5*time.Second, timeout.compute(0, 5*time.Second, 0))
+	require.Equal(t, 2*time.Second, timeout.compute(500*time.Millisecond, 0, 0))
+	require.Equal(t, time.Second, timeout.compute(time.Millisecond, 0, 0))
+	require.Equal(t, 10*time.Second, timeout.compute(time.Minute, 0, 0))
+
+	// Exponential backoff on consecutive failures.
+	require.Equal(t, 4*time.Second, timeout.compute(500*time.Millisecond, 0, 1))
+	require.Equal(t, 8*time.Second, timeout.compute(500*time.Millisecond, 0, 2))
+	require.Equal(t, 10*time.Second, timeout.compute(500*time.Millisecond, 0, 3))
+	require.Equal(t, 10*time.Second, timeout.compute(500*time.Millisecond, 0, 100))
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"time"
 
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/core/txn"
@@ -50,14 +51,43 @@ func (d Digest) Bytes() []byte {
 	return d[:]
 }
 
+// StateEntry is a key/value pair of the initial state of a chain.
+type StateEntry struct {
+	key   []byte
+	value []byte
+}
+
+// NewStateEntry creates a new entry of the initial state.
+func NewStateEntry(key, value []byte) StateEntry {
+	return StateEntry{
+		key:   key,
+		value: value,
+	}
+}
+
+// GetKey returns the key of the entry.
+func (e StateEntry) GetKey() []byte {
+	return e.key
+}
+
+// GetValue returns the value of the entry.
+func (e StateEntry) GetValue() []byte {
+	return e.value
+}
+
 // Genesis is the very first block of a chain. It contains the initial roster
-// and tree root.
+// and tree root. It also tells the policy that rotates the leaders, the limits
+// of the blocks and the initial state, which are written in the tree so that
+// they are covered by the tree root.
 //
 // - implements serde.Message
 type Genesis struct {
-	digest   Digest
-	roster   authority.Authority
-	treeRoot Digest
+	digest       Digest
+	roster       authority.Authority
+	treeRoot     Digest
+	leaderPolicy string
+	limits       Limits
+	state        []StateEntry
 }
 
 type genesisTemplate struct {
@@ -75,6 +105,29 @@ func WithGenesisRoot(root Digest) GenesisOption {
 	}
 }
 
+// WithLeaderPolicy is an option to set the specification of the leader policy
+// of the chain.
+func WithLeaderPolicy(spec string) GenesisOption {
+	return func(tmpl *genesisTemplate) {
+		tmpl.leaderPolicy = spec
+	}
+}
+
+// WithLimits is an option to set the initial limits of the blocks of the chain.
+func WithLimits(limits Limits) GenesisOption {
+	return func(tmpl *genesisTemplate) {
+		tmpl.limits = limits
+	}
+}
+
+// WithInitialState is an option to set the key/value pairs that the tree of
+// the chain contains from the genesis block.
+func WithInitialState(entries ...StateEntry) GenesisOption {
+	return func(tmpl *genesisTemplate) {
+		tmpl.state = entries
+	}
+}
+
 // WithGenesisHashFactory is an option to set the hash factory.
 func WithGenesisHashFactory(fac crypto.HashFactory) GenesisOption {
 	return func(tmpl *genesisTemplate) {
@@ -122,6 +175,22 @@ func (g Genesis) GetRoot() Digest {
 	return g.treeRoot
 }
 
+// GetLeaderPolicy returns the specification of the leader policy, or an empty
+// string for the default one.
+func (g Genesis) GetLeaderPolicy() string {
+	return g.leaderPolicy
+}
+
+// GetLimits returns the initial limits of the blocks.
+func (g Genesis) GetLimits() Limits {
+	return g.limits
+}
+
+// GetInitialState returns the key/value pairs of the initial state.
+func (g Genesis) GetInitialState() []StateEntry {
+	return append([]StateEntry{}, g.state...)
+}
+
 // Serialize implements serde.Message. It returns the serialized data for this
 // genesis block.
 func (g Genesis) Serialize(ctx serde.Context) ([]byte, error) {
@@ -184,15 +253,16 @@ func (f GenesisFactory) Deserialize(ctx serde.Context, data []byte) (serde.Messa
 }
 
 // Block is a block of a chain. It holds an index which is the height of the
-// block from the genesis block, the Merkle tree root and the validation result
-// of the transactions.
+// block from the genesis block, the Merkle tree root, the timestamp proposed by
+// the leader and the validation result of the transactions.
 //
 // - implements serde.Message
 type Block struct {
-	digest   Digest
-	index    uint64
-	data     validation.Result
-	treeRoot Digest
+	digest    Digest
+	index     uint64
+	data      validation.Result
+	treeRoot  Digest
+	timestamp int64
 }
 
 type blockTemplate struct {
@@ -217,6 +287,18 @@ func WithTreeRoot(root Digest) BlockOption {
 	}
 }
 
+// WithTimestamp is an option to set the time at which the leader proposed the
+// block.
+func WithTimestamp(ts time.Time) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.timestamp = 0
+
+		if !ts.IsZero() {
+			tmpl.timestamp = ts.UnixNano()
+		}
+	}
+}
+
 // WithHashFactory is an option to set the hash factory for the block.
 func WithHashFactory(fac crypto.HashFactory) BlockOption {
 	return func(tmpl *blockTemplate) {
@@ -282,8 +364,20 @@ func (b Block) GetTreeRoot() Digest {
 	return b.treeRoot
 }
 
+// GetTimestamp returns the time at which the leader proposed the block, or the
+// zero time if it is not set.
+func (b Block) GetTimestamp() time.Time {
+	if b.timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, b.timestamp)
+}
+
 // Fingerprint implements serde.Fingerprinter. It deterministically writes a
-// binary representation of the block into the writer.
+// binary representation of the block into the writer. The timestamp is only
+// written when it is set so that the blocks created without one keep the same
+// digest.
 func (b Block) Fingerprint(w io.Writer) error {
 	buffer := make([]byte, 8)
 	binary.LittleEndian.PutUint64(buffer, b.index)
@@ -297,6 +391,14 @@ func (b Block) Fingerprint(w io.Writer) error {
 		return xerrors.Errorf("couldn't write root: %v", err)
 	}
 
+	if b.timestamp != 0 {
+		binary.LittleEndian.PutUint64(buffer, uint64(b.timestamp))
+		_, err = w.Write(buffer)
+		if err != nil {
+			return xerrors.Errorf("couldn't write timestamp: %v", err)
+		}
+	}
+
 	err = b.data.Fingerprint(w)
 	if err != nil {
 		return xerrors.Errorf("data fingerprint failed: %v", err)
@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
@@ -61,6 +62,24 @@ func TestGenesis_GetRoot(t *testing.T) {
 	require.Equal(t, Digest{5}, genesis.GetRoot())
 }
 
+func TestGenesis_GetLeaderPolicy(t *testing.T) {
+	genesis := Genesis{}
+	require.Equal(t, "", genesis.GetLeaderPolicy())
+
+	genesis, err := NewGenesis(authority.New(nil, nil), WithLeaderPolicy("round-robin"))
+	require.NoError(t, err)
+	require.Equal(t, "round-robin", genesis.GetLeaderPolicy())
+}
+
+func TestGenesis_GetLimits(t *testing.T) {
+	genesis := Genesis{}
+	require.True(t, genesis.GetLimits().IsZero())
+
+	genesis, err := NewGenesis(authority.New(nil, nil), WithLimits(Limits{MaxSize: 5}))
+	require.NoError(t, err)
+	require.Equal(t, Limits{MaxSize: 5}, genesis.GetLimits())
+}
+
 func TestGenesis_Serialize(t *testing.T) {
 	ro := authority.FromAuthority(fake.NewAuthority(3, fake.NewSigner))
 
@@ -137,18 +156,36 @@ func TestBlock_GetTreeRoot(t *testing.T) {
 	require.Equal(t, Digest{3}, block.GetTreeRoot())
 }
 
+func TestBlock_GetTimestamp(t *testing.T) {
+	block, err := NewBlock(simple.NewResult(nil))
+	require.NoError(t, err)
+	require.True(t, block.GetTimestamp().IsZero())
+
+	ts := time.Unix(0, 1234)
+
+	other, err := NewBlock(simple.NewResult(nil), WithTimestamp(ts))
+	require.NoError(t, err)
+	require.True(t, ts.Equal(other.GetTimestamp()))
+	require.NotEqual(t, block.GetHash(), other.GetHash())
+
+	other, err = NewBlock(simple.NewResult(nil), WithTimestamp(time.Time{}))
+	require.NoError(t, err)
+	require.Equal(t, block, other)
+}
+
 func TestBlock_Fingerprint(t *testing.T) {
 	block := Block{
-		index:    3,
-		treeRoot: Digest{4},
-		data:     simple.NewResult(nil),
+		index:     3,
+		treeRoot:  Digest{4},
+		timestamp: 5,
+		data:      simple.NewResult(nil),
 	}
 
 	buffer := new(bytes.Buffer)
 
 	err := block.Fingerprint(buffer)
 	require.NoError(t, err)
-	require.Regexp(t, "^\x03(\x00){7}\x04(\x00){31}$", buffer.String())
+	require.Regexp(t, "^\x03(\x00){7}\x04(\x00){31}\x05(\x00){7}$", buffer.String())
 
 	err = block.Fingerprint(fake.NewBadHash())
 	require.EqualError(t, err, fake.Err("couldn't write index"))
@@ -156,6 +193,18 @@ func TestBlock_Fingerprint(t *testing.T) {
 	err = block.Fingerprint(fake.NewBadHashWithDelay(1))
 	require.EqualError(t, err, fake.Err("couldn't write root"))
 
+	err = block.Fingerprint(fake.NewBadHashWithDelay(2))
+	require.EqualError(t, err, fake.Err("couldn't write timestamp"))
+
+	// A block without a timestamp has the same fingerprint as before the
+	// timestamps were introduced.
+	block.timestamp = 0
+	buffer.Reset()
+
+	err = block.Fingerprint(buffer)
+	require.NoError(t, err)
+	require.Regexp(t, "^\x03(\x00){7}\x04(\x00){31}$", buffer.String())
+
 	block.data = badData{}
 	err = block.Fingerprint(ioutil.Discard)
 	require.EqualError(t, err, fake.Err("data fingerprint failed"))
@@ -10,6 +10,7 @@ import (
 
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/common"
 	"go.dedis.ch/dela/serde"
 	"go.dedis.ch/dela/serde/registry"
 	"golang.org/x/xerrors"
@@ -42,6 +43,11 @@ type forwardLink struct {
 	changeset  authority.ChangeSet
 	prepareSig crypto.Signature
 	commitSig  crypto.Signature
+
+	// viewChange is the certificate of the view change that elected the
+	// leader of the block, if any. It is part of the fingerprint so that the
+	// collective signatures cover it.
+	viewChange *ViewChange
 }
 
 type linkTemplate struct {
@@ -70,6 +76,14 @@ func WithChangeSet(cs authority.ChangeSet) LinkOption {
 	}
 }
 
+// WithViewChange is the option to set the certificate of the view change that
+// happened before the block.
+func WithViewChange(vc ViewChange) LinkOption {
+	return func(tmpl *linkTemplate) {
+		tmpl.viewChange = &vc
+	}
+}
+
 // WithLinkHashFactory is the option to set the hash factory for the link.
 func WithLinkHashFactory(fac crypto.HashFactory) LinkOption {
 	return func(tmpl *linkTemplate) {
@@ -136,6 +150,12 @@ func (link forwardLink) GetChangeSet() authority.ChangeSet {
 	return link.changeset
 }
 
+// GetViewChange implements types.Link. It returns the certificate of the view
+// change that happened before the block, or nil if the leader did not change.
+func (link forwardLink) GetViewChange() *ViewChange {
+	return link.viewChange
+}
+
 // Fingerprint implements serde.Fingerprinter. It deterministically writes a
 // binary representation of the block link.
 func (link forwardLink) Fingerprint(w io.Writer) error {
@@ -151,6 +171,13 @@ func (link forwardLink) Fingerprint(w io.Writer) error {
 		return xerrors.Errorf("couldn't write to: %v", err)
 	}
 
+	if link.viewChange != nil {
+		err = link.viewChange.Fingerprint(w)
+		if err != nil {
+			return xerrors.Errorf("couldn't fingerprint view change: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -248,6 +275,7 @@ func (fac linkFac) Deserialize(ctx serde.Context, data []byte) (serde.Message, e
 	ctx = serde.WithFactory(ctx, BlockKey{}, fac.blockFac)
 	ctx = serde.WithFactory(ctx, AggregateKey{}, fac.sigFac)
 	ctx = serde.WithFactory(ctx, ChangeSetKey{}, fac.csFac)
+	ctx = serde.WithFactory(ctx, SignatureKey{}, common.NewSignatureFactory())
 
 	msg, err := format.Decode(ctx, data)
 	if err != nil {
@@ -367,6 +395,16 @@ func (c chain) Verify(genesis Genesis, fac crypto.VerifierFactory) error {
 			return xerrors.Errorf("invalid commit signature: %v", err)
 		}
 
+		// 3. Verify the certificate of the view change, if any, that must be
+		// signed by the roster of the previous block.
+		vc := link.GetViewChange()
+		if vc != nil {
+			err = vc.Verify(prev, authority)
+			if err != nil {
+				return xerrors.Errorf("invalid view change: %v", err)
+			}
+		}
+
 		prev = link.GetTo()
 
 		authority = authority.Apply(link.GetChangeSet())
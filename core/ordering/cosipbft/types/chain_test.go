@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/internal/testing/fake"
 	"go.dedis.ch/dela/serde"
 )
@@ -75,6 +76,16 @@ func TestForwardLink_GetCommitSignature(t *testing.T) {
 	require.Nil(t, link.GetPrepareSignature())
 }
 
+func TestForwardLink_GetViewChange(t *testing.T) {
+	link, err := NewForwardLink(Digest{1}, Digest{2})
+	require.NoError(t, err)
+	require.Nil(t, link.GetViewChange())
+
+	link, err = NewForwardLink(Digest{1}, Digest{2}, WithViewChange(ViewChange{leader: 1}))
+	require.NoError(t, err)
+	require.Equal(t, &ViewChange{leader: 1}, link.GetViewChange())
+}
+
 func TestForwardLink_GetChangeSet(t *testing.T) {
 	link := forwardLink{
 		changeset: authority.NewChangeSet(),
@@ -109,6 +120,17 @@ func TestForwardLink_Fingerprint(t *testing.T) {
 
 	err = link.Fingerprint(fake.NewBadHashWithDelay(1))
 	require.EqualError(t, err, fake.Err("couldn't write to"))
+
+	// The certificate of the view change is covered by the digest.
+	vc := NewViewChange(Digest{}, 1, []uint16{0}, []crypto.Signature{fake.Signature{}})
+
+	other, err := NewForwardLink(Digest{1}, Digest{2}, WithViewChange(vc))
+	require.NoError(t, err)
+	require.NotEqual(t, link.GetHash(), other.GetHash())
+
+	err = other.(forwardLink).Fingerprint(fake.NewBadHashWithDelay(2))
+	require.EqualError(t, err,
+		fake.Err("couldn't fingerprint view change: couldn't write view"))
 }
 
 func TestBlockLink_New(t *testing.T) {
@@ -243,6 +265,24 @@ func TestChain_Verify(t *testing.T) {
 	c = NewChain(makeLink(t, genesis.digest), nil)
 	err = c.Verify(genesis, fake.NewVerifierFactory(fake.NewBadVerifierWithDelay(1)))
 	require.EqualError(t, err, fake.Err("invalid commit signature"))
+
+	link = makeLink(t, genesis.digest).(blockLink)
+	link.viewChange = &ViewChange{id: genesis.digest}
+	c = NewChain(link, nil)
+	err = c.Verify(genesis, fake.VerifierFactory{})
+	require.EqualError(t, err, "invalid view change: not enough views: 0 <= 0")
+
+	vcRoster, signers := makeViewRoster(t, 1)
+
+	genesis, err = NewGenesis(vcRoster)
+	require.NoError(t, err)
+
+	link = makeLink(t, genesis.digest).(blockLink)
+	vc := makeViewChange(t, genesis.digest, 0, signers, 0)
+	link.viewChange = &vc
+	c = NewChain(link, nil)
+	err = c.Verify(genesis, fake.VerifierFactory{})
+	require.NoError(t, err)
 }
 
 func TestChain_Serialize(t *testing.T) {
@@ -0,0 +1,353 @@
+// This file contains the implementation of the evidences of misbehavior that a
+// node records about the members of the roster.
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/registry"
+	"golang.org/x/xerrors"
+)
+
+var evidenceFormats = registry.NewSimpleRegistry()
+
+// RegisterEvidenceFormat registers the engine for the provided format.
+func RegisterEvidenceFormat(f serde.Format, e serde.FormatEngine) {
+	evidenceFormats.Register(f, e)
+}
+
+// EvidenceKind is the kind of misbehavior that an evidence is about.
+type EvidenceKind uint8
+
+const (
+	// ConflictingPrepare is the kind of evidence of a leader that proposed two
+	// different blocks for the same index.
+	ConflictingPrepare EvidenceKind = iota + 1
+
+	// ConflictingView is the kind of evidence of a member that signed two
+	// views for different leaders after the same block.
+	ConflictingView
+)
+
+// String implements fmt.Stringer. It returns a human readable name of the kind.
+func (k EvidenceKind) String() string {
+	switch k {
+	case ConflictingPrepare:
+		return "conflicting prepare"
+	case ConflictingView:
+		return "conflicting view"
+	default:
+		return "unknown"
+	}
+}
+
+// Statement is a message signed by the offender that is part of the proof of a
+// misbehavior. It is either the proposal of a block, or a view for a leader.
+type Statement struct {
+	id        Digest
+	leader    uint16
+	signature crypto.Signature
+}
+
+// NewStatement creates a new statement about the block or the view of the
+// given digest. The leader is only relevant for a view.
+func NewStatement(id Digest, leader uint16, sig crypto.Signature) Statement {
+	return Statement{
+		id:        id,
+		leader:    leader,
+		signature: sig,
+	}
+}
+
+// GetID returns the digest of the block the statement is about.
+func (st Statement) GetID() Digest {
+	return st.id
+}
+
+// GetLeader returns the index of the leader of a view.
+func (st Statement) GetLeader() uint16 {
+	return st.leader
+}
+
+// GetSignature returns the signature of the offender.
+func (st Statement) GetSignature() crypto.Signature {
+	return st.signature
+}
+
+// EvidenceParam contains the parameters to create an evidence.
+type EvidenceParam struct {
+	Kind       EvidenceKind
+	Index      uint64
+	Offender   mino.Address
+	PublicKey  crypto.PublicKey
+	Statements []Statement
+}
+
+// Evidence is the record of a misbehavior of a member of the roster. It
+// embeds the conflicting statements signed by the offender alongside its
+// public key in the roster, so that anyone can verify the misbehavior. It is
+// also signed by the node that observed it.
+//
+// - implements serde.Message
+// - implements serde.Fingerprinter
+type Evidence struct {
+	kind       EvidenceKind
+	index      uint64
+	offender   mino.Address
+	pubkey     crypto.PublicKey
+	statements []Statement
+	signature  crypto.Signature
+}
+
+// NewEvidence creates a new evidence from the parameters and its signature.
+func NewEvidence(param EvidenceParam, sig crypto.Signature) Evidence {
+	return Evidence{
+		kind:       param.Kind,
+		index:      param.Index,
+		offender:   param.Offender,
+		pubkey:     param.PublicKey,
+		statements: param.Statements,
+		signature:  sig,
+	}
+}
+
+// NewEvidenceAndSign creates a new evidence and uses the signer to make the
+// signature of the witness.
+func NewEvidenceAndSign(param EvidenceParam, signer crypto.Signer) (Evidence, error) {
+	ev := NewEvidence(param, nil)
+
+	buffer := new(bytes.Buffer)
+
+	err := ev.Fingerprint(buffer)
+	if err != nil {
+		return ev, xerrors.Errorf("fingerprint failed: %v", err)
+	}
+
+	ev.signature, err = signer.Sign(buffer.Bytes())
+	if err != nil {
+		return ev, xerrors.Errorf("signer: %v", err)
+	}
+
+	return ev, nil
+}
+
+// GetKind returns the kind of misbehavior.
+func (ev Evidence) GetKind() EvidenceKind {
+	return ev.kind
+}
+
+// GetIndex returns the index of the block the round was working on.
+func (ev Evidence) GetIndex() uint64 {
+	return ev.index
+}
+
+// GetOffender returns the address of the member that misbehaved.
+func (ev Evidence) GetOffender() mino.Address {
+	return ev.offender
+}
+
+// GetPublicKey returns the public key of the offender in the roster.
+func (ev Evidence) GetPublicKey() crypto.PublicKey {
+	return ev.pubkey
+}
+
+// GetStatements returns the conflicting statements signed by the offender.
+func (ev Evidence) GetStatements() []Statement {
+	return append([]Statement{}, ev.statements...)
+}
+
+// GetSignature returns the signature of the witness.
+func (ev Evidence) GetSignature() crypto.Signature {
+	return ev.signature
+}
+
+// Verify takes the public key of the witness and verifies the signature of the
+// evidence.
+func (ev Evidence) Verify(pubkey crypto.PublicKey) error {
+	buffer := new(bytes.Buffer)
+
+	err := ev.Fingerprint(buffer)
+	if err != nil {
+		return xerrors.Errorf("fingerprint failed: %v", err)
+	}
+
+	err = pubkey.Verify(buffer.Bytes(), ev.signature)
+	if err != nil {
+		return xerrors.Errorf("verify: %v", err)
+	}
+
+	return nil
+}
+
+// VerifyStatements verifies that the statements are signed by the public key
+// of the offender and that they conflict with each other, which proves the
+// misbehavior.
+func (ev Evidence) VerifyStatements() error {
+	if ev.pubkey == nil {
+		return xerrors.New("missing public key")
+	}
+
+	if len(ev.statements) != 2 {
+		return xerrors.Errorf("expected 2 statements, got %d", len(ev.statements))
+	}
+
+	first, second := ev.statements[0], ev.statements[1]
+
+	switch ev.kind {
+	case ConflictingPrepare:
+		if first.id == second.id {
+			return xerrors.Errorf("statements are about the same block '%v'", first.id)
+		}
+	case ConflictingView:
+		if first.id != second.id {
+			return xerrors.Errorf("views are after different blocks '%v' != '%v'",
+				first.id, second.id)
+		}
+
+		if first.leader == second.leader {
+			return xerrors.Errorf("views are for the same leader %d", first.leader)
+		}
+	default:
+		return xerrors.Errorf("unknown kind %d", ev.kind)
+	}
+
+	for i, st := range ev.statements {
+		msg := ProposalBytes(ev.index, st.id)
+		if ev.kind == ConflictingView {
+			msg = viewBytes(st.leader, st.id)
+		}
+
+		err := ev.pubkey.Verify(msg, st.signature)
+		if err != nil {
+			return xerrors.Errorf("statement %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Serialize implements serde.Message. It returns the serialized data of the
+// evidence.
+func (ev Evidence) Serialize(ctx serde.Context) ([]byte, error) {
+	format := evidenceFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, ev)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// Fingerprint implements serde.Fingerprinter. It deterministically writes a
+// binary representation of the evidence into the writer.
+func (ev Evidence) Fingerprint(w io.Writer) error {
+	addr, err := ev.offender.MarshalText()
+	if err != nil {
+		return xerrors.Errorf("couldn't marshal address: %v", err)
+	}
+
+	// The length of the address is written so that it cannot be confused
+	// with the statements.
+	buffer := make([]byte, 13)
+	buffer[0] = byte(ev.kind)
+	binary.LittleEndian.PutUint64(buffer[1:], ev.index)
+	binary.LittleEndian.PutUint32(buffer[9:], uint32(len(addr)))
+
+	_, err = w.Write(append(buffer, addr...))
+	if err != nil {
+		return xerrors.Errorf("couldn't write header: %v", err)
+	}
+
+	for _, st := range ev.statements {
+		sig := []byte{}
+
+		if st.signature != nil {
+			sig, err = st.signature.MarshalBinary()
+			if err != nil {
+				return xerrors.Errorf("couldn't marshal signature: %v", err)
+			}
+		}
+
+		buffer := make([]byte, 6)
+		binary.LittleEndian.PutUint16(buffer, st.leader)
+		binary.LittleEndian.PutUint32(buffer[2:], uint32(len(sig)))
+
+		_, err = w.Write(append(append(st.id.Bytes(), buffer...), sig...))
+		if err != nil {
+			return xerrors.Errorf("couldn't write statement: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// viewBytes returns the message signed by a view, which is the same as the
+// one of the views of the state machine.
+func viewBytes(leader uint16, id Digest) []byte {
+	buffer := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buffer, leader)
+
+	return append(buffer, id.Bytes()...)
+}
+
+// EvidenceFactory is the factory to deserialize evidences.
+//
+// - implements serde.Factory
+type EvidenceFactory struct {
+	addrFac   mino.AddressFactory
+	pubkeyFac crypto.PublicKeyFactory
+	sigFac    crypto.SignatureFactory
+}
+
+// NewEvidenceFactory creates a new evidence factory. The factories are the
+// ones of the public keys and the signatures of the members.
+func NewEvidenceFactory(addrFac mino.AddressFactory, pubkeyFac crypto.PublicKeyFactory,
+	sigFac crypto.SignatureFactory) EvidenceFactory {
+
+	return EvidenceFactory{
+		addrFac:   addrFac,
+		pubkeyFac: pubkeyFac,
+		sigFac:    sigFac,
+	}
+}
+
+// Deserialize implements serde.Factory. It populates the evidence from the data
+// if appropriate, otherwise it returns an error.
+func (f EvidenceFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.EvidenceOf(ctx, data)
+}
+
+// EvidenceOf returns the evidence from the data if appropriate, otherwise it
+// returns an error. The statements of the evidence are verified so that a
+// forged evidence is rejected.
+func (f EvidenceFactory) EvidenceOf(ctx serde.Context, data []byte) (Evidence, error) {
+	format := evidenceFormats.Get(ctx.GetFormat())
+
+	ctx = serde.WithFactory(ctx, AddressKey{}, f.addrFac)
+	ctx = serde.WithFactory(ctx, PublicKeyKey{}, f.pubkeyFac)
+	ctx = serde.WithFactory(ctx, SignatureKey{}, f.sigFac)
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return Evidence{}, xerrors.Errorf("decoding failed: %v", err)
+	}
+
+	ev, ok := msg.(Evidence)
+	if !ok {
+		return Evidence{}, xerrors.Errorf("invalid evidence '%T'", msg)
+	}
+
+	err = ev.VerifyStatements()
+	if err != nil {
+		return Evidence{}, xerrors.Errorf("invalid statements: %v", err)
+	}
+
+	return ev, nil
+}
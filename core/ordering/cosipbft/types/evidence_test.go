@@ -0,0 +1,246 @@
+package types
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+)
+
+func init() {
+	RegisterEvidenceFormat(fake.GoodFormat, fake.Format{Msg: Evidence{}})
+	RegisterEvidenceFormat(fake.BadFormat, fake.NewBadFormat())
+	RegisterEvidenceFormat(serde.Format("badtype"), fake.Format{Msg: fake.Message{}})
+}
+
+func TestEvidenceKind_String(t *testing.T) {
+	require.Equal(t, "conflicting prepare", ConflictingPrepare.String())
+	require.Equal(t, "conflicting view", ConflictingView.String())
+	require.Equal(t, "unknown", EvidenceKind(0).String())
+}
+
+func TestStatement_Getters(t *testing.T) {
+	st := NewStatement(Digest{1}, 2, fake.Signature{})
+
+	require.Equal(t, Digest{1}, st.GetID())
+	require.Equal(t, uint16(2), st.GetLeader())
+	require.Equal(t, fake.Signature{}, st.GetSignature())
+}
+
+func TestEvidence_Getters(t *testing.T) {
+	param := EvidenceParam{
+		Kind:       ConflictingPrepare,
+		Index:      2,
+		Offender:   fake.NewAddress(1),
+		PublicKey:  fake.PublicKey{},
+		Statements: []Statement{NewStatement(Digest{1}, 0, nil), NewStatement(Digest{2}, 0, nil)},
+	}
+
+	ev := NewEvidence(param, fake.Signature{})
+
+	require.Equal(t, ConflictingPrepare, ev.GetKind())
+	require.Equal(t, uint64(2), ev.GetIndex())
+	require.Equal(t, fake.NewAddress(1), ev.GetOffender())
+	require.Equal(t, fake.PublicKey{}, ev.GetPublicKey())
+	require.Equal(t, param.Statements, ev.GetStatements())
+	require.Equal(t, fake.Signature{}, ev.GetSignature())
+}
+
+func TestEvidence_Verify(t *testing.T) {
+	param := EvidenceParam{
+		Kind:       ConflictingView,
+		Index:      5,
+		Offender:   fake.NewAddress(0),
+		Statements: []Statement{NewStatement(Digest{3}, 1, fake.Signature{})},
+	}
+
+	signer := bls.NewSigner()
+
+	ev, err := NewEvidenceAndSign(param, signer)
+	require.NoError(t, err)
+	require.NoError(t, ev.Verify(signer.GetPublicKey()))
+
+	// The signature does not match any longer if the evidence is altered.
+	param.Index = 6
+	err = NewEvidence(param, ev.GetSignature()).Verify(signer.GetPublicKey())
+	require.Error(t, err)
+
+	_, err = NewEvidenceAndSign(param, fake.NewBadSigner())
+	require.EqualError(t, err, fake.Err("signer"))
+
+	param.Offender = fake.NewBadAddress()
+	_, err = NewEvidenceAndSign(param, signer)
+	require.EqualError(t, err, fake.Err("fingerprint failed: couldn't marshal address"))
+
+	err = NewEvidence(param, nil).Verify(signer.GetPublicKey())
+	require.EqualError(t, err, fake.Err("fingerprint failed: couldn't marshal address"))
+
+	err = ev.Verify(fake.NewBadPublicKey())
+	require.EqualError(t, err, fake.Err("verify"))
+}
+
+func TestEvidence_ConflictingPrepare_VerifyStatements(t *testing.T) {
+	offender := bls.NewSigner()
+
+	first := NewStatement(Digest{1}, 0, nil)
+	second := NewStatement(Digest{2}, 0, nil)
+
+	ev := makeEvidence(t, offender, ConflictingPrepare, first, second,
+		ProposalBytes(3, Digest{1}), ProposalBytes(3, Digest{2}))
+	require.NoError(t, ev.VerifyStatements())
+
+	// The proposals must be signed for the index of the evidence.
+	ev = makeEvidence(t, offender, ConflictingPrepare, first, second,
+		ProposalBytes(3, Digest{1}), ProposalBytes(4, Digest{2}))
+	err := ev.VerifyStatements()
+	require.Error(t, err)
+	require.Regexp(t, "^statement 1: ", err.Error())
+
+	// A statement signed by another key proves nothing about the offender.
+	ev = makeEvidence(t, offender, ConflictingPrepare, first, second,
+		ProposalBytes(3, Digest{1}), ProposalBytes(3, Digest{2}))
+	ev.pubkey = bls.NewSigner().GetPublicKey()
+	err = ev.VerifyStatements()
+	require.Error(t, err)
+	require.Regexp(t, "^statement 0: ", err.Error())
+}
+
+func TestEvidence_ConflictingView_VerifyStatements(t *testing.T) {
+	offender := bls.NewSigner()
+
+	first := NewStatement(Digest{1}, 1, nil)
+	second := NewStatement(Digest{1}, 2, nil)
+
+	ev := makeEvidence(t, offender, ConflictingView, first, second,
+		viewBytes(1, Digest{1}), viewBytes(2, Digest{1}))
+	require.NoError(t, ev.VerifyStatements())
+
+	// Views for the same leader after different blocks happen when the chain
+	// moves forward.
+	ev = makeEvidence(t, offender, ConflictingView,
+		NewStatement(Digest{1}, 1, nil), NewStatement(Digest{2}, 1, nil),
+		viewBytes(1, Digest{1}), viewBytes(1, Digest{2}))
+	err := ev.VerifyStatements()
+	require.EqualError(t, err,
+		"views are after different blocks '01000000' != '02000000'")
+
+	ev = makeEvidence(t, offender, ConflictingView, first, first,
+		viewBytes(1, Digest{1}), viewBytes(1, Digest{1}))
+	err = ev.VerifyStatements()
+	require.EqualError(t, err, "views are for the same leader 1")
+
+	ev = makeEvidence(t, offender, ConflictingView, first, second,
+		viewBytes(1, Digest{1}), viewBytes(3, Digest{1}))
+	err = ev.VerifyStatements()
+	require.Error(t, err)
+	require.Regexp(t, "^statement 1: ", err.Error())
+}
+
+func TestEvidence_Bad_VerifyStatements(t *testing.T) {
+	st := NewStatement(Digest{1}, 0, fake.Signature{})
+	other := NewStatement(Digest{2}, 0, fake.Signature{})
+
+	ev := NewEvidence(EvidenceParam{Statements: []Statement{st, other}}, nil)
+	err := ev.VerifyStatements()
+	require.EqualError(t, err, "missing public key")
+
+	ev.pubkey = fake.PublicKey{}
+	err = ev.VerifyStatements()
+	require.EqualError(t, err, "unknown kind 0")
+
+	ev.statements = []Statement{st}
+	err = ev.VerifyStatements()
+	require.EqualError(t, err, "expected 2 statements, got 1")
+
+	ev.kind = ConflictingPrepare
+	ev.statements = []Statement{st, st}
+	err = ev.VerifyStatements()
+	require.EqualError(t, err,
+		"statements are about the same block '01000000'")
+}
+
+func TestEvidence_Serialize(t *testing.T) {
+	ev := NewEvidence(EvidenceParam{}, nil)
+
+	data, err := ev.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = ev.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestEvidence_Fingerprint(t *testing.T) {
+	param := EvidenceParam{
+		Kind:       ConflictingView,
+		Offender:   fake.NewAddress(0),
+		Statements: []Statement{NewStatement(Digest{1}, 0, fake.Signature{})},
+	}
+
+	ev := NewEvidence(param, nil)
+
+	err := ev.Fingerprint(fake.NewBadHash())
+	require.EqualError(t, err, fake.Err("couldn't write header"))
+
+	err = ev.Fingerprint(fake.NewBadHashWithDelay(1))
+	require.EqualError(t, err, fake.Err("couldn't write statement"))
+
+	ev.statements = []Statement{NewStatement(Digest{1}, 0, fake.NewBadSignature())}
+	err = ev.Fingerprint(new(bytes.Buffer))
+	require.EqualError(t, err, fake.Err("couldn't marshal signature"))
+}
+
+func TestEvidenceFactory_Deserialize(t *testing.T) {
+	fac := NewEvidenceFactory(fake.AddressFactory{}, fake.PublicKeyFactory{},
+		fake.SignatureFactory{})
+
+	ev := makeEvidence(t, bls.NewSigner(), ConflictingView,
+		NewStatement(Digest{1}, 1, nil), NewStatement(Digest{1}, 2, nil),
+		viewBytes(1, Digest{1}), viewBytes(2, Digest{1}))
+
+	RegisterEvidenceFormat(serde.Format("evidence"), fake.Format{Msg: ev})
+
+	msg, err := fac.Deserialize(fake.NewContextWithFormat(serde.Format("evidence")), nil)
+	require.NoError(t, err)
+	require.Equal(t, ev, msg)
+
+	// A forged evidence is rejected.
+	_, err = fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "invalid statements: missing public key")
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("decoding failed"))
+
+	_, err = fac.Deserialize(fake.NewContextWithFormat(serde.Format("badtype")), nil)
+	require.EqualError(t, err, "invalid evidence 'fake.Message'")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeEvidence(t *testing.T, offender crypto.Signer, kind EvidenceKind,
+	first, second Statement, msgs ...[]byte) Evidence {
+
+	statements := []Statement{first, second}
+
+	for i, msg := range msgs {
+		sig, err := offender.Sign(msg)
+		require.NoError(t, err)
+
+		statements[i].signature = sig
+	}
+
+	param := EvidenceParam{
+		Kind:       kind,
+		Index:      3,
+		Offender:   fake.NewAddress(0),
+		PublicKey:  offender.GetPublicKey(),
+		Statements: statements,
+	}
+
+	return NewEvidence(param, fake.Signature{})
+}
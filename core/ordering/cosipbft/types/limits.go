@@ -0,0 +1,81 @@
+// This file contains the limits of the blocks of a chain.
+
+package types
+
+import (
+	"encoding/binary"
+
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+const limitsLength = 16
+
+// Limits are the parameters of a chain that bound the blocks. A zero value
+// means that there is no limit.
+type Limits struct {
+	// MaxTransactions is the maximum number of transactions in a block.
+	MaxTransactions uint64
+
+	// MaxSize is the maximum sum of the encoded size of the transactions of a
+	// block, in bytes.
+	MaxSize uint64
+}
+
+// IsZero returns true if no limit is set.
+func (l Limits) IsZero() bool {
+	return l.MaxTransactions == 0 && l.MaxSize == 0
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. It returns the fixed-size
+// representation of the limits that is written in the tree.
+func (l Limits) MarshalBinary() ([]byte, error) {
+	data := make([]byte, limitsLength)
+	binary.LittleEndian.PutUint64(data, l.MaxTransactions)
+	binary.LittleEndian.PutUint64(data[8:], l.MaxSize)
+
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It populates the
+// limits from the data if appropriate, otherwise it returns an error.
+func (l *Limits) UnmarshalBinary(data []byte) error {
+	if len(data) != limitsLength {
+		return xerrors.Errorf("invalid length %d != %d", len(data), limitsLength)
+	}
+
+	l.MaxTransactions = binary.LittleEndian.Uint64(data)
+	l.MaxSize = binary.LittleEndian.Uint64(data[8:])
+
+	return nil
+}
+
+// Check returns an error if the list of transactions exceeds the limits. The
+// size of a transaction is the length of its serialization in the context.
+func (l Limits) Check(ctx serde.Context, txs []txn.Transaction) error {
+	if l.MaxTransactions > 0 && uint64(len(txs)) > l.MaxTransactions {
+		return xerrors.Errorf("too many transactions %d > %d", len(txs), l.MaxTransactions)
+	}
+
+	if l.MaxSize == 0 {
+		return nil
+	}
+
+	size := uint64(0)
+
+	for _, tx := range txs {
+		data, err := tx.Serialize(ctx)
+		if err != nil {
+			return xerrors.Errorf("failed to serialize transaction: %v", err)
+		}
+
+		size += uint64(len(data))
+	}
+
+	if size > l.MaxSize {
+		return xerrors.Errorf("size %d > %d", size, l.MaxSize)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+)
+
+func TestLimits_IsZero(t *testing.T) {
+	require.True(t, Limits{}.IsZero())
+	require.False(t, Limits{MaxTransactions: 1}.IsZero())
+	require.False(t, Limits{MaxSize: 1}.IsZero())
+}
+
+func TestLimits_MarshalBinary(t *testing.T) {
+	limits := Limits{MaxTransactions: 2, MaxSize: 3}
+
+	data, err := limits.MarshalBinary()
+	require.NoError(t, err)
+	require.Len(t, data, limitsLength)
+
+	other := Limits{}
+	err = other.UnmarshalBinary(data)
+	require.NoError(t, err)
+	require.Equal(t, limits, other)
+
+	err = other.UnmarshalBinary([]byte{1})
+	require.EqualError(t, err, "invalid length 1 != 16")
+}
+
+func TestLimits_Check(t *testing.T) {
+	txs := []txn.Transaction{fakeTx{size: 3}, fakeTx{size: 4}}
+
+	err := Limits{}.Check(fake.NewContext(), txs)
+	require.NoError(t, err)
+
+	err = Limits{MaxTransactions: 2, MaxSize: 7}.Check(fake.NewContext(), txs)
+	require.NoError(t, err)
+
+	err = Limits{MaxTransactions: 1}.Check(fake.NewContext(), txs)
+	require.EqualError(t, err, "too many transactions 2 > 1")
+
+	err = Limits{MaxSize: 6}.Check(fake.NewContext(), txs)
+	require.EqualError(t, err, "size 7 > 6")
+
+	err = Limits{MaxSize: 1}.Check(fake.NewContext(), []txn.Transaction{fakeTx{err: fake.GetError()}})
+	require.EqualError(t, err, fake.Err("failed to serialize transaction"))
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type fakeTx struct {
+	txn.Transaction
+
+	size int
+	err  error
+}
+
+func (tx fakeTx) Serialize(serde.Context) ([]byte, error) {
+	return make([]byte, tx.size), tx.err
+}
@@ -6,6 +6,8 @@
 package types
 
 import (
+	"encoding/binary"
+
 	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/crypto/common"
@@ -58,15 +60,23 @@ func (m GenesisMessage) Serialize(ctx serde.Context) ([]byte, error) {
 //
 // - implements serde.Message
 type BlockMessage struct {
-	block Block
-	views map[mino.Address]ViewMessage
+	block      Block
+	views      map[mino.Address]ViewMessage
+	signature  crypto.Signature
+	viewChange *ViewChange
 }
 
-// NewBlockMessage creates a new block message with the provided block.
-func NewBlockMessage(block Block, views map[mino.Address]ViewMessage) BlockMessage {
+// NewBlockMessage creates a new block message with the provided block, the
+// signature of the proposal by the leader and the certificate of the view
+// change that elected the leader, if any.
+func NewBlockMessage(block Block, views map[mino.Address]ViewMessage,
+	sig crypto.Signature, vc *ViewChange) BlockMessage {
+
 	return BlockMessage{
-		block: block,
-		views: views,
+		block:      block,
+		views:      views,
+		signature:  sig,
+		viewChange: vc,
 	}
 }
 
@@ -80,6 +90,17 @@ func (m BlockMessage) GetViews() map[mino.Address]ViewMessage {
 	return m.views
 }
 
+// GetSignature returns the signature of the proposal by the leader.
+func (m BlockMessage) GetSignature() crypto.Signature {
+	return m.signature
+}
+
+// GetViewChange returns the certificate of the view change that elected the
+// leader, or nil if the leader did not change.
+func (m BlockMessage) GetViewChange() *ViewChange {
+	return m.viewChange
+}
+
 // Serialize implements serde.Message. It returns the serialized data of the
 // block.
 func (m BlockMessage) Serialize(ctx serde.Context) ([]byte, error) {
@@ -93,6 +114,16 @@ func (m BlockMessage) Serialize(ctx serde.Context) ([]byte, error) {
 	return data, nil
 }
 
+// ProposalBytes returns the message that the leader signs to propose the block
+// of the given digest at the given index, so that two proposals for the same
+// index prove an equivocation.
+func ProposalBytes(index uint64, id Digest) []byte {
+	buffer := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buffer, index)
+
+	return append(buffer, id.Bytes()...)
+}
+
 // CommitMessage is a message containing the signature of the prepare phase of a
 // PBFT execution.
 //
@@ -220,6 +251,72 @@ func (m ViewMessage) Serialize(ctx serde.Context) ([]byte, error) {
 	return data, nil
 }
 
+// SubscribeMessage is a message sent by an observer to a member of the roster
+// to receive the blocks of the chain from the given index.
+//
+// - implements serde.Message
+type SubscribeMessage struct {
+	from uint64
+}
+
+// NewSubscribeMessage creates a new subscribe message.
+func NewSubscribeMessage(from uint64) SubscribeMessage {
+	return SubscribeMessage{
+		from: from,
+	}
+}
+
+// GetFrom returns the index of the first block the observer is missing.
+func (m SubscribeMessage) GetFrom() uint64 {
+	return m.from
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m SubscribeMessage) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// LinkMessage is a message sent to an observer with a block and the collective
+// signatures that prove it has been committed.
+//
+// - implements serde.Message
+type LinkMessage struct {
+	link BlockLink
+}
+
+// NewLinkMessage creates a new link message.
+func NewLinkMessage(link BlockLink) LinkMessage {
+	return LinkMessage{
+		link: link,
+	}
+}
+
+// GetLink returns the block link of the message.
+func (m LinkMessage) GetLink() BlockLink {
+	return m.link
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m LinkMessage) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
 // GenesisKey is the key of the genesis factory.
 type GenesisKey struct{}
 
@@ -238,6 +335,9 @@ type SignatureKey struct{}
 // AddressKey is the key of the address factory.
 type AddressKey struct{}
 
+// PublicKeyKey is the key of the public key factory.
+type PublicKeyKey struct{}
+
 // MessageFactory is the factory to deserialize messages.
 //
 // - implements serde.Factory
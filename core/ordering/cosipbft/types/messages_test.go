@@ -33,22 +33,35 @@ func TestGenesisMessage_Serialize(t *testing.T) {
 
 func TestBlockMessage_GetBlock(t *testing.T) {
 	expected := Block{index: 1}
-	msg := NewBlockMessage(expected, nil)
+	msg := NewBlockMessage(expected, nil, fake.Signature{}, nil)
 
 	block := msg.GetBlock()
 	require.Equal(t, expected, block)
 }
 
 func TestBlockMessage_GetViews(t *testing.T) {
-	msg := NewBlockMessage(Block{}, nil)
+	msg := NewBlockMessage(Block{}, nil, fake.Signature{}, nil)
 	require.Len(t, msg.GetViews(), 0)
 
-	msg = NewBlockMessage(Block{}, map[mino.Address]ViewMessage{fake.NewAddress(0): {}})
+	msg = NewBlockMessage(Block{}, map[mino.Address]ViewMessage{fake.NewAddress(0): {}}, fake.Signature{}, nil)
 	require.Len(t, msg.GetViews(), 1)
 }
 
+func TestBlockMessage_GetSignature(t *testing.T) {
+	msg := NewBlockMessage(Block{}, nil, fake.Signature{}, nil)
+	require.Equal(t, fake.Signature{}, msg.GetSignature())
+}
+
+func TestProposalBytes(t *testing.T) {
+	msg := ProposalBytes(1, Digest{2})
+	require.Len(t, msg, 40)
+	require.Equal(t, byte(1), msg[0])
+	require.Equal(t, byte(2), msg[8])
+	require.NotEqual(t, msg, ProposalBytes(2, Digest{2}))
+}
+
 func TestBlockMessage_Serialize(t *testing.T) {
-	msg := NewBlockMessage(Block{}, nil)
+	msg := NewBlockMessage(Block{}, nil, fake.Signature{}, nil)
 
 	data, err := msg.Serialize(fake.NewContext())
 	require.NoError(t, err)
@@ -133,6 +146,41 @@ func TestViewMessage_Serialize(t *testing.T) {
 	require.EqualError(t, err, fake.Err("encoding failed"))
 }
 
+func TestSubscribeMessage_GetFrom(t *testing.T) {
+	msg := NewSubscribeMessage(3)
+
+	require.Equal(t, uint64(3), msg.GetFrom())
+}
+
+func TestSubscribeMessage_Serialize(t *testing.T) {
+	msg := NewSubscribeMessage(0)
+
+	data, err := msg.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = msg.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestLinkMessage_GetLink(t *testing.T) {
+	link := blockLink{forwardLink: forwardLink{from: Digest{1}}}
+	msg := NewLinkMessage(link)
+
+	require.Equal(t, link, msg.GetLink())
+}
+
+func TestLinkMessage_Serialize(t *testing.T) {
+	msg := NewLinkMessage(blockLink{})
+
+	data, err := msg.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = msg.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
 func TestMessageFactory_Deserialize(t *testing.T) {
 	fac := NewMessageFactory(
 		GenesisFactory{},
@@ -38,6 +38,10 @@ type Link interface {
 
 	// GetChangeSet returns the roster change set for this link.
 	GetChangeSet() authority.ChangeSet
+
+	// GetViewChange returns the certificate of the view change that elected
+	// the leader of the block, or nil if there was none.
+	GetViewChange() *ViewChange
 }
 
 // BlockLink is an extension of the Link interface to include the block the link
@@ -0,0 +1,153 @@
+// This file contains the implementation of the view-change certificates that
+// justify a change of leader.
+
+package types
+
+import (
+	"encoding/binary"
+	"io"
+
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/crypto"
+	"golang.org/x/xerrors"
+)
+
+// ViewChange is a certificate that proves that enough participants asked for
+// a new leader after the block it refers to. It gathers the signed views of
+// the participants, which are identified by their index in the roster.
+type ViewChange struct {
+	id         Digest
+	leader     uint16
+	indices    []uint16
+	signatures []crypto.Signature
+}
+
+// NewViewChange creates a new view-change certificate for the block digest and
+// the leader. The signatures must be in the same order as the indices.
+func NewViewChange(id Digest, leader uint16, indices []uint16,
+	sigs []crypto.Signature) ViewChange {
+
+	return ViewChange{
+		id:         id,
+		leader:     leader,
+		indices:    indices,
+		signatures: sigs,
+	}
+}
+
+// GetID returns the digest of the block after which the view has changed.
+func (vc ViewChange) GetID() Digest {
+	return vc.id
+}
+
+// GetLeader returns the index of the new leader.
+func (vc ViewChange) GetLeader() uint16 {
+	return vc.leader
+}
+
+// GetIndices returns the roster indices of the participants that signed a
+// view.
+func (vc ViewChange) GetIndices() []uint16 {
+	return append([]uint16{}, vc.indices...)
+}
+
+// GetSignatures returns the signatures of the views.
+func (vc ViewChange) GetSignatures() []crypto.Signature {
+	return append([]crypto.Signature{}, vc.signatures...)
+}
+
+// Verify makes sure that the certificate refers to the previous block and that
+// it contains more views than the threshold, signed by distinct members of the
+// authority.
+func (vc ViewChange) Verify(prev Digest, ro authority.Authority) error {
+	if vc.id != prev {
+		return xerrors.Errorf("mismatch id '%v' != '%v'", vc.id, prev)
+	}
+
+	if int(vc.leader) >= ro.Len() {
+		return xerrors.Errorf("leader %d out of range", vc.leader)
+	}
+
+	if len(vc.indices) != len(vc.signatures) {
+		return xerrors.Errorf("mismatch signatures %d != %d",
+			len(vc.signatures), len(vc.indices))
+	}
+
+	threshold := viewChangeThreshold(ro.Len())
+	if len(vc.indices) <= threshold {
+		return xerrors.Errorf("not enough views: %d <= %d", len(vc.indices), threshold)
+	}
+
+	msg := vc.bytes()
+
+	for i, index := range vc.indices {
+		// Indices are strictly increasing so that a participant is not counted
+		// twice.
+		if i > 0 && index <= vc.indices[i-1] {
+			return xerrors.Errorf("indices not sorted at %d", i)
+		}
+
+		if int(index) >= ro.Len() {
+			return xerrors.Errorf("index %d out of range", index)
+		}
+
+		iter := ro.PublicKeyIterator()
+		iter.Seek(int(index))
+
+		err := iter.GetNext().Verify(msg, vc.signatures[i])
+		if err != nil {
+			return xerrors.Errorf("invalid view %d: %v", index, err)
+		}
+	}
+
+	return nil
+}
+
+// Fingerprint implements serde.Fingerprinter. It deterministically writes a
+// binary representation of the certificate.
+func (vc ViewChange) Fingerprint(w io.Writer) error {
+	if len(vc.indices) != len(vc.signatures) {
+		return xerrors.Errorf("mismatch signatures %d != %d",
+			len(vc.signatures), len(vc.indices))
+	}
+
+	_, err := w.Write(vc.bytes())
+	if err != nil {
+		return xerrors.Errorf("couldn't write view: %v", err)
+	}
+
+	for i, index := range vc.indices {
+		buffer := make([]byte, 2)
+		binary.LittleEndian.PutUint16(buffer, index)
+
+		_, err = w.Write(buffer)
+		if err != nil {
+			return xerrors.Errorf("couldn't write index: %v", err)
+		}
+
+		data, err := vc.signatures[i].MarshalBinary()
+		if err != nil {
+			return xerrors.Errorf("couldn't marshal signature: %v", err)
+		}
+
+		_, err = w.Write(data)
+		if err != nil {
+			return xerrors.Errorf("couldn't write signature: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// bytes returns the message signed by each view, which is the same as the one
+// of the views of the state machine.
+func (vc ViewChange) bytes() []byte {
+	return viewBytes(vc.leader, vc.id)
+}
+
+// viewChangeThreshold returns the number of views that must be exceeded for a
+// view change. It is 2*f where n = 3*f+1 is the number of participants.
+func viewChangeThreshold(n int) int {
+	f := (n - 1) / 3
+	return 2 * f
+}
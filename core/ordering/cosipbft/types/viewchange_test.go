@@ -0,0 +1,123 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/cosipbft/authority"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/crypto/bls"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/mino"
+)
+
+func TestViewChange_Getters(t *testing.T) {
+	vc := NewViewChange(Digest{1}, 2, []uint16{0, 1}, []crypto.Signature{fake.Signature{}})
+
+	require.Equal(t, Digest{1}, vc.GetID())
+	require.Equal(t, uint16(2), vc.GetLeader())
+	require.Equal(t, []uint16{0, 1}, vc.GetIndices())
+	require.Equal(t, []crypto.Signature{fake.Signature{}}, vc.GetSignatures())
+}
+
+func TestViewChange_Fingerprint(t *testing.T) {
+	vc := NewViewChange(Digest{1}, 2, []uint16{0, 3}, []crypto.Signature{fake.Signature{}, fake.Signature{}})
+
+	buffer := new(bytes.Buffer)
+
+	err := vc.Fingerprint(buffer)
+	require.NoError(t, err)
+	expected := append(viewBytes(2, Digest{1}), 0, 0, fake.SignatureByte, 3, 0, fake.SignatureByte)
+	require.Equal(t, expected, buffer.Bytes())
+
+	err = vc.Fingerprint(fake.NewBadHash())
+	require.EqualError(t, err, fake.Err("couldn't write view"))
+
+	err = vc.Fingerprint(fake.NewBadHashWithDelay(1))
+	require.EqualError(t, err, fake.Err("couldn't write index"))
+
+	err = vc.Fingerprint(fake.NewBadHashWithDelay(2))
+	require.EqualError(t, err, fake.Err("couldn't write signature"))
+
+	vc = NewViewChange(Digest{}, 0, []uint16{0}, []crypto.Signature{fake.NewBadSignature()})
+	err = vc.Fingerprint(buffer)
+	require.EqualError(t, err, fake.Err("couldn't marshal signature"))
+
+	vc = NewViewChange(Digest{}, 0, []uint16{0, 1}, []crypto.Signature{fake.Signature{}})
+	err = vc.Fingerprint(buffer)
+	require.EqualError(t, err, "mismatch signatures 1 != 2")
+}
+
+func TestViewChange_Verify(t *testing.T) {
+	ro, signers := makeViewRoster(t, 4)
+
+	vc := makeViewChange(t, Digest{1}, 1, signers, 0, 2, 3)
+
+	err := vc.Verify(Digest{1}, ro)
+	require.NoError(t, err)
+
+	err = vc.Verify(Digest{2}, ro)
+	require.EqualError(t, err, fmt.Sprintf("mismatch id '%v' != '%v'", Digest{1}, Digest{2}))
+
+	vc.leader = 4
+	err = vc.Verify(Digest{1}, ro)
+	require.EqualError(t, err, "leader 4 out of range")
+
+	vc = makeViewChange(t, Digest{1}, 1, signers, 0, 2, 3)
+	vc.signatures = vc.signatures[:2]
+	err = vc.Verify(Digest{1}, ro)
+	require.EqualError(t, err, "mismatch signatures 2 != 3")
+
+	vc = makeViewChange(t, Digest{1}, 1, signers, 0, 2)
+	err = vc.Verify(Digest{1}, ro)
+	require.EqualError(t, err, "not enough views: 2 <= 2")
+
+	vc = makeViewChange(t, Digest{1}, 1, signers, 0, 2, 2)
+	err = vc.Verify(Digest{1}, ro)
+	require.EqualError(t, err, "indices not sorted at 2")
+
+	vc = makeViewChange(t, Digest{1}, 1, signers, 0, 2, 3)
+	vc.indices[2] = 5
+	err = vc.Verify(Digest{1}, ro)
+	require.EqualError(t, err, "index 5 out of range")
+
+	vc = makeViewChange(t, Digest{1}, 1, signers, 0, 2, 3)
+	vc.signatures[1] = vc.signatures[0]
+	err = vc.Verify(Digest{1}, ro)
+	require.Error(t, err)
+	require.Regexp(t, "^invalid view 2: ", err.Error())
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeViewRoster(t *testing.T, n int) (authority.Authority, []crypto.Signer) {
+	addrs := make([]mino.Address, n)
+	pubkeys := make([]crypto.PublicKey, n)
+	signers := make([]crypto.Signer, n)
+
+	for i := range signers {
+		signers[i] = bls.NewSigner()
+		addrs[i] = fake.NewAddress(i)
+		pubkeys[i] = signers[i].GetPublicKey()
+	}
+
+	return authority.New(addrs, pubkeys), signers
+}
+
+func makeViewChange(t *testing.T, id Digest, leader uint16,
+	signers []crypto.Signer, indices ...uint16) ViewChange {
+
+	vc := ViewChange{id: id, leader: leader, indices: indices}
+
+	for _, index := range indices {
+		sig, err := signers[index].Sign(vc.bytes())
+		require.NoError(t, err)
+
+		vc.signatures = append(vc.signatures, sig)
+	}
+
+	return vc
+}
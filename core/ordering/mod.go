@@ -28,6 +28,11 @@ type Proof interface {
 type Event struct {
 	Index        uint64
 	Transactions []validation.TransactionResult
+
+	// Rollback is true when the blocks from the index onwards have left the
+	// chain, in which case the event has no transaction. The events of the
+	// blocks that replace them follow from the same index.
+	Rollback bool
 }
 
 // Service is the interface of an ordering service. It provides the primitives
@@ -43,6 +48,10 @@ type Service interface {
 	// accepted.
 	Watch(ctx context.Context) <-chan Event
 
+	// WatchFrom returns a channel populated with the events of the blocks from
+	// the given index, followed by the events of new blocks.
+	WatchFrom(ctx context.Context, index uint64) <-chan Event
+
 	// Close closes the service and cleans the resources.
 	Close() error
 }
@@ -0,0 +1,482 @@
+// This file contains the tree of the blocks known by the service, and the
+// choice of the chain with the most work.
+
+package pow
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"time"
+
+	"go.dedis.ch/dela/core/ordering/pow/types"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/core/txn"
+	"go.dedis.ch/dela/core/validation"
+	"golang.org/x/xerrors"
+)
+
+var (
+	blockBucket = []byte("pow-blocks")
+	finalBucket = []byte("pow-final")
+	finalKey    = []byte("final")
+)
+
+// node is a block known by the service, alongside the state after the block
+// and the total work of its branch. The state is released once the next block
+// is final.
+type node struct {
+	block  types.Block
+	tree   hashtree.Tree
+	work   *big.Int
+	parent *node
+}
+
+// update is the change of the chain after new blocks.
+type update struct {
+	removed []*node
+	added   []*node
+}
+
+// head returns the last block of the chain. The lock must be held.
+func (s *Service) head() *node {
+	return s.chain[len(s.chain)-1]
+}
+
+// best returns the block with the most work. The blocks of the chain win the
+// ties so that the chain only changes for a branch with more work. The lock
+// must be held.
+func (s *Service) best() *node {
+	best := s.head()
+
+	for _, n := range s.nodes {
+		if n.work.Cmp(best.work) > 0 {
+			best = n
+		}
+	}
+
+	return best
+}
+
+// finalized returns the index of the last final block of the chain, which is
+// the finality depth below the head. The lock must be held.
+func (s *Service) finalized() uint64 {
+	index := s.head().block.GetIndex()
+
+	if s.finality == 0 || index < s.finality {
+		return 0
+	}
+
+	return index - s.finality
+}
+
+// retargets returns true if the difficulty of the block after the one at the
+// given index is adjusted.
+func (s *Service) retargets(index uint64) bool {
+	return s.window > 0 && index > s.window && (index-1)%s.window == 0
+}
+
+// nextDifficulty returns the difficulty of the block after the parent. It is
+// adjusted every window of blocks according to the time it took to mine the
+// window compared to the expected interval between two blocks.
+func (s *Service) nextDifficulty(parent *node) uint {
+	diff := parent.block.GetDifficulty()
+	index := parent.block.GetIndex()
+
+	if !s.retargets(index) {
+		return diff
+	}
+
+	first := parent
+	for i := uint64(0); i < s.window; i++ {
+		first = first.parent
+	}
+
+	elapsed := parent.block.GetTimestamp().Sub(first.block.GetTimestamp())
+	expected := s.interval * time.Duration(s.window)
+
+	if elapsed < expected/2 {
+		return diff + 1
+	}
+
+	if elapsed > expected*2 && diff > 1 {
+		return diff - 1
+	}
+
+	return diff
+}
+
+// add verifies the block and inserts it in the tree of blocks. The parent must
+// be known. The lock must be held.
+func (s *Service) add(block types.Block) (*node, error) {
+	n, found := s.nodes[string(block.GetHash())]
+	if found {
+		return n, nil
+	}
+
+	parent, found := s.nodes[string(block.GetPrevious())]
+	if !found {
+		return nil, xerrors.Errorf("unknown parent %#x", block.GetPrevious())
+	}
+
+	tree, err := s.verify(parent, block)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid block: %v", err)
+	}
+
+	n, err = s.insert(parent, block, tree)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't insert block: %v", err)
+	}
+
+	return n, nil
+}
+
+// verify returns the state after the block if it is a valid successor of the
+// parent, otherwise it returns an error.
+func (s *Service) verify(parent *node, block types.Block) (hashtree.Tree, error) {
+	if block.GetIndex() != parent.block.GetIndex()+1 {
+		return nil, xerrors.Errorf("mismatch index %d != %d",
+			block.GetIndex(), parent.block.GetIndex()+1)
+	}
+
+	// A branch that forks before the finalized block can never replace the
+	// chain.
+	final := s.finalized()
+	if block.GetIndex() <= final {
+		return nil, xerrors.Errorf("block %d is before the finalized block %d",
+			block.GetIndex(), final)
+	}
+
+	diff := s.nextDifficulty(parent)
+	if block.GetDifficulty() != diff {
+		return nil, xerrors.Errorf("mismatch difficulty %d != %d", block.GetDifficulty(), diff)
+	}
+
+	err := block.Verify()
+	if err != nil {
+		return nil, xerrors.Errorf("invalid proof of work: %v", err)
+	}
+
+	timestamp := block.GetTimestamp()
+
+	if timestamp.Before(parent.block.GetTimestamp()) {
+		return nil, xerrors.Errorf("timestamp goes backwards by %v",
+			parent.block.GetTimestamp().Sub(timestamp))
+	}
+
+	if timestamp.After(time.Now().Add(TimestampDrift)) {
+		return nil, xerrors.Errorf("timestamp in the future by %v", time.Until(timestamp))
+	}
+
+	results := block.GetData().GetTransactionResults()
+
+	tree, err := parent.tree.Stage(func(snap store.Snapshot) error {
+		txs := make([]txn.Transaction, len(results))
+		for i, res := range results {
+			txs[i] = res.GetTransaction()
+		}
+
+		_, err := s.validation.Validate(snap, txs, validation.WithTimestamp(timestamp))
+		if err != nil {
+			return xerrors.Errorf("validation failed: %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("while updating tree: %v", err)
+	}
+
+	if !bytes.Equal(tree.GetRoot(), block.GetRoot()) {
+		return nil, xerrors.Errorf("mismatch tree root %#x != %#x", tree.GetRoot(), block.GetRoot())
+	}
+
+	return tree, nil
+}
+
+// insert persists the block and inserts it in the tree of blocks. The lock
+// must be held.
+func (s *Service) insert(parent *node, block types.Block, tree hashtree.Tree) (*node, error) {
+	err := s.storeBlock(block)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to store block: %v", err)
+	}
+
+	n := &node{
+		block:  block,
+		tree:   tree,
+		work:   new(big.Int).Add(parent.work, block.GetWork()),
+		parent: parent,
+	}
+
+	s.nodes[string(block.GetHash())] = n
+
+	return n, nil
+}
+
+// commit makes the block the head of the chain if its branch has more work,
+// and it returns the change of the chain. The lock must be held.
+func (s *Service) commit(n *node) update {
+	if n == nil || n.work.Cmp(s.head().work) <= 0 {
+		return update{}
+	}
+
+	branch := []*node{}
+
+	// Look for the common ancestor of the new head and the chain.
+	curr := n
+	for uint64(len(s.chain)) <= curr.block.GetIndex() || s.chain[curr.block.GetIndex()] != curr {
+		branch = append([]*node{curr}, branch...)
+		curr = curr.parent
+	}
+
+	fork := curr.block.GetIndex() + 1
+
+	upd := update{
+		removed: append([]*node{}, s.chain[fork:]...),
+		added:   branch,
+	}
+
+	s.chain = append(s.chain[:fork], branch...)
+
+	s.prune()
+	s.finalize()
+
+	return upd
+}
+
+// finalize commits the states of the blocks that are now final, in order, and
+// it releases the states before the last one, which is the parent of the next
+// blocks. The lock must be held.
+func (s *Service) finalize() {
+	final := s.finalized()
+
+	for s.committed < final {
+		n := s.chain[s.committed+1]
+
+		staging, ok := n.tree.(hashtree.StagingTree)
+		if !ok {
+			s.logger.Warn().Uint64("index", n.block.GetIndex()).Msg("state cannot be committed")
+			return
+		}
+
+		err := staging.Commit()
+		if err != nil {
+			// The commit is tried again with the next block.
+			s.logger.Warn().Err(err).Msg("failed to commit state")
+			return
+		}
+
+		err = s.storeFinal(n.block)
+		if err != nil {
+			// The blocks after the previous final one are executed again on
+			// restart.
+			s.logger.Warn().Err(err).Msg("failed to store final block")
+		}
+
+		s.chain[s.committed].tree = nil
+		s.committed++
+	}
+}
+
+// restore rebuilds the chain from the genesis block to the final block with
+// the given hash, without executing the blocks again, as the tree is expected
+// to be in the state of the final block. The blocks have been verified before
+// they were stored. The lock must be held.
+func (s *Service) restore(blocks []types.Block, final []byte) error {
+	known := make(map[string]types.Block, len(blocks))
+	for _, block := range blocks {
+		known[string(block.GetHash())] = block
+	}
+
+	genesis := s.chain[0]
+	branch := []types.Block{}
+
+	id := final
+	for !bytes.Equal(id, genesis.block.GetHash()) {
+		block, found := known[string(id)]
+		if !found {
+			return xerrors.Errorf("missing block %#x", id)
+		}
+
+		branch = append([]types.Block{block}, branch...)
+		id = block.GetPrevious()
+	}
+
+	if len(branch) == 0 {
+		return xerrors.New("final block is the genesis block")
+	}
+
+	last := branch[len(branch)-1]
+	if !bytes.Equal(genesis.tree.GetRoot(), last.GetRoot()) {
+		return xerrors.Errorf("mismatch tree root %#x != %#x",
+			genesis.tree.GetRoot(), last.GetRoot())
+	}
+
+	parent := genesis
+
+	for i, block := range branch {
+		if block.GetIndex() != uint64(i+1) {
+			return xerrors.Errorf("mismatch index %d != %d", block.GetIndex(), i+1)
+		}
+
+		n := &node{
+			block:  block,
+			work:   new(big.Int).Add(parent.work, block.GetWork()),
+			parent: parent,
+		}
+
+		s.nodes[string(block.GetHash())] = n
+		s.chain = append(s.chain, n)
+
+		parent = n
+	}
+
+	parent.tree = genesis.tree
+	genesis.tree = nil
+
+	s.committed = last.GetIndex()
+
+	return nil
+}
+
+// prune drops the blocks of the branches that fork before the finalized block,
+// as they can never replace the chain. The lock must be held.
+func (s *Service) prune() {
+	final := s.finalized()
+
+	keys := [][]byte{}
+
+	for key, n := range s.nodes {
+		ancestor := n
+		for ancestor.block.GetIndex() > final {
+			ancestor = ancestor.parent
+		}
+
+		if s.chain[ancestor.block.GetIndex()] != ancestor {
+			delete(s.nodes, key)
+			keys = append(keys, []byte(key))
+		}
+	}
+
+	if len(keys) == 0 || s.db == nil {
+		return
+	}
+
+	err := s.db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(blockBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket: %v", err)
+		}
+
+		for _, key := range keys {
+			err = bucket.Delete(key)
+			if err != nil {
+				return xerrors.Errorf("delete: %v", err)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		// The blocks are dropped again when the chain is restored.
+		s.logger.Warn().Err(err).Msg("failed to delete pruned blocks")
+	}
+}
+
+// storeFinal writes the hash of the final block, which the chain is restored
+// from.
+func (s *Service) storeFinal(block types.Block) error {
+	if s.db == nil {
+		return nil
+	}
+
+	return s.db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(finalBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket: %v", err)
+		}
+
+		return bucket.Set(finalKey, block.GetHash())
+	})
+}
+
+// readFinal returns the hash of the final block of the database, or nil if
+// there is none.
+func (s *Service) readFinal() ([]byte, error) {
+	var final []byte
+
+	err := s.db.View(func(tx kv.ReadableTx) error {
+		bucket := tx.GetBucket(finalBucket)
+		if bucket != nil {
+			final = append(final, bucket.Get(finalKey)...)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return final, nil
+}
+
+func (s *Service) storeBlock(block types.Block) error {
+	if s.db == nil {
+		return nil
+	}
+
+	data, err := block.Serialize(s.context)
+	if err != nil {
+		return xerrors.Errorf("failed to serialize: %v", err)
+	}
+
+	return s.db.Update(func(tx kv.WritableTx) error {
+		bucket, err := tx.GetBucketOrCreate(blockBucket)
+		if err != nil {
+			return xerrors.Errorf("bucket: %v", err)
+		}
+
+		return bucket.Set(block.GetHash(), data)
+	})
+}
+
+// readBlocks returns the blocks of the database sorted by index, so that a
+// parent is always before its children.
+func (s *Service) readBlocks() ([]types.Block, error) {
+	blocks := []types.Block{}
+
+	err := s.db.View(func(tx kv.ReadableTx) error {
+		bucket := tx.GetBucket(blockBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(key, value []byte) error {
+			block, err := s.blockFac.BlockOf(s.context, value)
+			if err != nil {
+				return xerrors.Errorf("malformed block: %v", err)
+			}
+
+			blocks = append(blocks, block)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].GetIndex() < blocks[j].GetIndex()
+	})
+
+	return blocks, nil
+}
@@ -0,0 +1,197 @@
+// Package json implements the JSON formats of the Proof-of-Work blocks and
+// messages.
+package json
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.dedis.ch/dela/core/ordering/pow/types"
+	"go.dedis.ch/dela/core/validation"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+func init() {
+	types.RegisterBlockFormat(serde.FormatJSON, blockFormat{})
+	types.RegisterMessageFormat(serde.FormatJSON, msgFormat{})
+}
+
+// BlockJSON is the JSON message for a block.
+type BlockJSON struct {
+	Index      uint64
+	Nonce      uint64
+	Previous   []byte
+	Root       []byte
+	Timestamp  int64 `json:",omitempty"`
+	Difficulty uint
+	Data       json.RawMessage
+}
+
+// BlockMessageJSON is the JSON message to send a block.
+type BlockMessageJSON struct {
+	Block json.RawMessage
+}
+
+// BlockRequestJSON is the JSON message to request a block.
+type BlockRequestJSON struct {
+	ID []byte
+}
+
+// MessageJSON is the JSON message that wraps the different kinds of messages.
+type MessageJSON struct {
+	Block   *BlockMessageJSON `json:",omitempty"`
+	Request *BlockRequestJSON `json:",omitempty"`
+}
+
+// BlockFormat is the format engine to encode and decode blocks.
+//
+// - implements serde.FormatEngine
+type blockFormat struct{}
+
+// Encode implements serde.FormatEngine. It returns the serialized data of the
+// block if appropriate, otherwise it returns an error.
+func (f blockFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	block, ok := msg.(types.Block)
+	if !ok {
+		return nil, xerrors.Errorf("invalid block '%T'", msg)
+	}
+
+	blockdata, err := block.GetData().Serialize(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize data: %v", err)
+	}
+
+	m := BlockJSON{
+		Index:      block.GetIndex(),
+		Nonce:      block.GetNonce(),
+		Previous:   block.GetPrevious(),
+		Root:       block.GetRoot(),
+		Difficulty: block.GetDifficulty(),
+		Data:       blockdata,
+	}
+
+	if !block.GetTimestamp().IsZero() {
+		m.Timestamp = block.GetTimestamp().UnixNano()
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+// Decode implements serde.FormatEngine. It populates the block if appropriate,
+// otherwise it returns an error. The hash is calculated from the nonce.
+func (f blockFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := BlockJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	factory := ctx.GetFactory(types.DataKey{})
+
+	fac, ok := factory.(validation.ResultFactory)
+	if !ok {
+		return nil, xerrors.Errorf("invalid data factory '%T'", factory)
+	}
+
+	blockdata, err := fac.ResultOf(ctx, m.Data)
+	if err != nil {
+		return nil, xerrors.Errorf("data factory failed: %v", err)
+	}
+
+	opts := []types.BlockOption{
+		types.WithIndex(m.Index),
+		types.WithNonce(m.Nonce),
+		types.WithPrevious(m.Previous),
+		types.WithRoot(m.Root),
+		types.WithDifficulty(m.Difficulty),
+	}
+
+	if m.Timestamp != 0 {
+		opts = append(opts, types.WithTimestamp(time.Unix(0, m.Timestamp)))
+	}
+
+	block, err := types.NewBlock(context.Background(), blockdata, opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to create block: %v", err)
+	}
+
+	return block, nil
+}
+
+// MsgFormat is the format engine to encode and decode the network messages.
+//
+// - implements serde.FormatEngine
+type msgFormat struct{}
+
+// Encode implements serde.FormatEngine. It returns the JSON data of the message
+// if appropriate, otherwise an error.
+func (f msgFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	var m MessageJSON
+
+	switch in := msg.(type) {
+	case types.BlockMessage:
+		block, err := in.GetBlock().Serialize(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to serialize block: %v", err)
+		}
+
+		m.Block = &BlockMessageJSON{
+			Block: block,
+		}
+	case types.BlockRequest:
+		m.Request = &BlockRequestJSON{
+			ID: in.GetID(),
+		}
+	default:
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+// Decode implements serde.FormatEngine. It returns the message associated to
+// the data if appropriate, otherwise an error.
+func (f msgFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := MessageJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	if m.Block != nil {
+		factory := ctx.GetFactory(types.BlockKey{})
+		if factory == nil {
+			return nil, xerrors.New("missing block factory")
+		}
+
+		msg, err := factory.Deserialize(ctx, m.Block.Block)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to decode block: %v", err)
+		}
+
+		block, ok := msg.(types.Block)
+		if !ok {
+			return nil, xerrors.Errorf("invalid block '%T'", msg)
+		}
+
+		return types.NewBlockMessage(block), nil
+	}
+
+	if m.Request != nil {
+		return types.NewBlockRequest(m.Request.ID), nil
+	}
+
+	return nil, xerrors.New("message is empty")
+}
@@ -0,0 +1,198 @@
+package json
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/ordering/pow/types"
+	"go.dedis.ch/dela/core/validation"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+)
+
+func init() {
+	types.RegisterBlockFormat(fake.GoodFormat, fakeBlockFormat{})
+	types.RegisterBlockFormat(fake.BadFormat, fake.NewBadFormat())
+}
+
+func TestBlockFormat_Encode(t *testing.T) {
+	format := blockFormat{}
+
+	ctx := fake.NewContext()
+
+	block := makeBlock(t, fakeResult{}, types.WithIndex(1), types.WithRoot([]byte{2}))
+
+	data, err := format.Encode(ctx, block)
+	require.NoError(t, err)
+	require.Regexp(t,
+		`{"Index":1,"Nonce":\d+,"Previous":"","Root":"Ag==","Difficulty":1,"Data":{}}`,
+		string(data))
+
+	block = makeBlock(t, fakeResult{}, types.WithTimestamp(time.Unix(0, 42)))
+
+	data, err = format.Encode(ctx, block)
+	require.NoError(t, err)
+	require.Regexp(t, `"Timestamp":42,`, string(data))
+
+	_, err = format.Encode(ctx, fake.Message{})
+	require.EqualError(t, err, "invalid block 'fake.Message'")
+
+	_, err = format.Encode(fake.NewBadContext(), block)
+	require.EqualError(t, err, fake.Err("failed to marshal"))
+
+	block = makeBlock(t, fakeResult{err: fake.GetError()})
+
+	_, err = format.Encode(ctx, block)
+	require.EqualError(t, err, fake.Err("failed to serialize data"))
+}
+
+func TestBlockFormat_Decode(t *testing.T) {
+	format := blockFormat{}
+
+	ctx := fake.NewContext()
+	ctx = serde.WithFactory(ctx, types.DataKey{}, fakeResultFac{})
+
+	block := makeBlock(t, fakeResult{},
+		types.WithIndex(1),
+		types.WithPrevious([]byte{1}),
+		types.WithRoot([]byte{2}),
+		types.WithTimestamp(time.Unix(0, 42)),
+		types.WithDifficulty(4))
+
+	data, err := format.Encode(ctx, block)
+	require.NoError(t, err)
+
+	msg, err := format.Decode(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, block, msg)
+	require.NoError(t, msg.(types.Block).Verify())
+
+	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
+	require.EqualError(t, err, fake.Err("failed to unmarshal"))
+
+	badCtx := serde.WithFactory(ctx, types.DataKey{}, nil)
+	_, err = format.Decode(badCtx, []byte(`{}`))
+	require.EqualError(t, err, "invalid data factory '<nil>'")
+
+	badCtx = serde.WithFactory(ctx, types.DataKey{}, fakeResultFac{err: fake.GetError()})
+	_, err = format.Decode(badCtx, []byte(`{}`))
+	require.EqualError(t, err, fake.Err("data factory failed"))
+
+	badCtx = serde.WithFactory(ctx, types.DataKey{}, fakeResultFac{bad: true})
+	_, err = format.Decode(badCtx, []byte(`{}`))
+	require.EqualError(t, err, fake.Err("failed to create block: "+
+		"couldn't prepare block: failed to fingerprint data"))
+}
+
+func TestMsgFormat_Encode(t *testing.T) {
+	format := msgFormat{}
+
+	ctx := fake.NewContext()
+
+	data, err := format.Encode(ctx, types.NewBlockMessage(types.Block{}))
+	require.NoError(t, err)
+	require.Equal(t, `{"Block":{"Block":{}}}`, string(data))
+
+	_, err = format.Encode(fake.NewBadContext(), types.NewBlockMessage(types.Block{}))
+	require.EqualError(t, err, fake.Err("failed to serialize block: encoding failed"))
+
+	data, err = format.Encode(ctx, types.NewBlockRequest([]byte{1}))
+	require.NoError(t, err)
+	require.Equal(t, `{"Request":{"ID":"AQ=="}}`, string(data))
+
+	_, err = format.Encode(ctx, fake.Message{})
+	require.EqualError(t, err, "unsupported message 'fake.Message'")
+
+	_, err = format.Encode(fake.NewBadContext(), types.NewBlockRequest(nil))
+	require.EqualError(t, err, fake.Err("failed to marshal"))
+}
+
+func TestMsgFormat_Decode(t *testing.T) {
+	format := msgFormat{}
+
+	ctx := fake.NewContext()
+	ctx = serde.WithFactory(ctx, types.BlockKey{}, fakeBlockFac{})
+
+	msg, err := format.Decode(ctx, []byte(`{"Block":{"Block":{}}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewBlockMessage(types.Block{}), msg)
+
+	badCtx := serde.WithFactory(ctx, types.BlockKey{}, nil)
+	_, err = format.Decode(badCtx, []byte(`{"Block":{}}`))
+	require.EqualError(t, err, "missing block factory")
+
+	badCtx = serde.WithFactory(ctx, types.BlockKey{}, fake.NewBadMessageFactory())
+	_, err = format.Decode(badCtx, []byte(`{"Block":{}}`))
+	require.EqualError(t, err, fake.Err("failed to decode block"))
+
+	badCtx = serde.WithFactory(ctx, types.BlockKey{}, fake.MessageFactory{})
+	_, err = format.Decode(badCtx, []byte(`{"Block":{}}`))
+	require.EqualError(t, err, "invalid block 'fake.Message'")
+
+	msg, err = format.Decode(ctx, []byte(`{"Request":{"ID":"AQ=="}}`))
+	require.NoError(t, err)
+	require.Equal(t, types.NewBlockRequest([]byte{1}), msg)
+
+	_, err = format.Decode(ctx, []byte(`{}`))
+	require.EqualError(t, err, "message is empty")
+
+	_, err = format.Decode(fake.NewBadContext(), []byte(`{}`))
+	require.EqualError(t, err, fake.Err("failed to unmarshal"))
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeBlock(t *testing.T, data validation.Result, opts ...types.BlockOption) types.Block {
+	block, err := types.NewBlock(context.Background(), data, opts...)
+	require.NoError(t, err)
+
+	return block
+}
+
+type fakeResult struct {
+	validation.Result
+
+	err   error
+	errFp error
+}
+
+func (data fakeResult) Serialize(serde.Context) ([]byte, error) {
+	return []byte(`{}`), data.err
+}
+
+func (data fakeResult) Fingerprint(io.Writer) error {
+	return data.errFp
+}
+
+type fakeResultFac struct {
+	validation.ResultFactory
+
+	err error
+	bad bool
+}
+
+func (fac fakeResultFac) ResultOf(serde.Context, []byte) (validation.Result, error) {
+	if fac.bad {
+		return fakeResult{errFp: fake.GetError()}, nil
+	}
+
+	return fakeResult{}, fac.err
+}
+
+type fakeBlockFormat struct {
+	serde.FormatEngine
+}
+
+func (fakeBlockFormat) Encode(serde.Context, serde.Message) ([]byte, error) {
+	return []byte(`{}`), nil
+}
+
+type fakeBlockFac struct{}
+
+func (fakeBlockFac) Deserialize(serde.Context, []byte) (serde.Message, error) {
+	return types.Block{}, nil
+}
@@ -1,28 +1,133 @@
-// Package pow implements a Proof-of-Work ordering service. This implementation
-// very naive and only support one single node. It demonstrates a permissionless
-// blockchain.
+// Package pow implements a Proof-of-Work ordering service, which demonstrates a
+// permissionless blockchain.
 //
-// TODO: later improve or remove
+// Each node mines the blocks on top of the chain with the most work and
+// gossips them to the participants. A block that extends a competing branch
+// is kept, and if the branch gets more work than the current chain, the node
+// switches to it, which rolls back the state to the common ancestor. The
+// missing ancestors of a block are downloaded from the node that sent it. The
+// branches that fork before a final block, which is deep enough in the chain,
+// are dropped.
+//
+// The difficulty of the proof of work is retargeted from the time it took to
+// mine the previous blocks. The states of the blocks that are not final are
+// kept in memory. The state of a block is committed to the tree once the block
+// is final, and the states of the previous blocks are released. The blocks are
+// persisted when a database is given so that the chain is restored on restart
+// on top of the tree of the last final block, which is loaded by the caller.
+// Only the blocks after it are executed again. Every node must start from the
+// same tree.
 package pow
 
 import (
 	"context"
+	"math/big"
 	"sync"
+	"time"
 
+	"github.com/rs/zerolog"
 	"go.dedis.ch/dela"
 	"go.dedis.ch/dela/core"
 	"go.dedis.ch/dela/core/ordering"
+	"go.dedis.ch/dela/core/ordering/pow/types"
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/core/store/kv"
 	"go.dedis.ch/dela/core/txn/pool"
 	"go.dedis.ch/dela/core/validation"
 	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
 	"golang.org/x/xerrors"
 )
 
-type epoch struct {
-	block Block
-	store hashtree.Tree
+const (
+	// BlockInterval is the default expected amount of time between two blocks.
+	BlockInterval = 10 * time.Second
+
+	// RetargetWindow is the default number of blocks between two adjustments
+	// of the difficulty.
+	RetargetWindow = 10
+
+	// TimestampDrift is the maximum amount of time that the timestamp of a
+	// block can be ahead of the local clock.
+	TimestampDrift = 2 * time.Minute
+
+	// FinalityDepth is the default number of blocks after which a block of the
+	// chain is final.
+	FinalityDepth = 100
+
+	rpcName = "pow"
+
+	gossipTimeout = 10 * time.Second
+)
+
+// serviceTemplate is the list of parameters of the service that the options
+// can change.
+type serviceTemplate struct {
+	mino       mino.Mino
+	db         kv.DB
+	difficulty uint
+	interval   time.Duration
+	window     uint64
+	finality   uint64
+}
+
+// ServiceOption is the type of options to create a service.
+type ServiceOption func(*serviceTemplate)
+
+// WithMino is an option to gossip the blocks to the participants set with
+// SetPlayers. The service only works locally otherwise.
+func WithMino(m mino.Mino) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.mino = m
+	}
+}
+
+// WithDB is an option to persist the blocks in the database, so that the chain
+// is restored when the service starts.
+func WithDB(db kv.DB) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.db = db
+	}
+}
+
+// WithDifficulty is an option to set the difficulty of the first block. Every
+// node must use the same value.
+func WithDifficulty(diff uint) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.difficulty = diff
+	}
+}
+
+// WithBlockInterval is an option to set the expected amount of time between
+// two blocks, which the difficulty is retargeted to. Every node must use the
+// same value.
+func WithBlockInterval(interval time.Duration) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.interval = interval
+	}
+}
+
+// WithRetargetWindow is an option to set the number of blocks between two
+// adjustments of the difficulty. Zero keeps the difficulty. Every node must use
+// the same value.
+func WithRetargetWindow(window uint64) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.window = window
+	}
+}
+
+// WithFinalityDepth is an option to set the number of blocks after which a
+// block of the chain is final. The branches that fork before a final block are
+// dropped, and a block announced further ahead of the chain than the depth is
+// refused. Zero disables the finality, in which case the states of every block
+// are kept in memory and the whole chain is executed again on restart.
+func WithFinalityDepth(depth uint64) ServiceOption {
+	return func(tmpl *serviceTemplate) {
+		tmpl.finality = depth
+	}
 }
 
 // Service is an ordering service powered by a Proof-of-Work consensus
@@ -30,34 +135,103 @@ type epoch struct {
 //
 // - implements ordering.Service
 type Service struct {
+	sync.Mutex
+
+	logger      zerolog.Logger
 	pool        pool.Pool
 	validation  validation.Service
-	epochs      []epoch
 	hashFactory crypto.HashFactory
-	difficulty  uint
-	watcher     core.Observable
-	closing     chan struct{}
-	closed      sync.WaitGroup
+	rpc         mino.RPC
+	players     mino.Players
+	db          kv.DB
+	context     serde.Context
+	blockFac    types.BlockFactory
+	interval    time.Duration
+	window      uint64
+	finality    uint64
+
+	// nodes are the blocks known by the service indexed by their hash, and the
+	// chain is the branch with the most work from the genesis block.
+	nodes map[string]*node
+	chain []*node
+
+	// committed is the index of the last final block of the chain, whose
+	// state is committed to the tree.
+	committed uint64
+
+	// abort stops the mining of the current block when the chain changes.
+	abort context.CancelFunc
+
+	watcher core.Observable
+	closing chan struct{}
+	closed  sync.WaitGroup
 }
 
-// NewService creates a new service.
-func NewService(pool pool.Pool, val validation.Service, trie hashtree.Tree) *Service {
-	return &Service{
+// NewService creates a new service. The tree is the state before the first
+// block.
+func NewService(pool pool.Pool, val validation.Service, trie hashtree.Tree, opts ...ServiceOption) *Service {
+	tmpl := serviceTemplate{
+		difficulty: types.Difficulty,
+		interval:   BlockInterval,
+		window:     RetargetWindow,
+		finality:   FinalityDepth,
+	}
+
+	for _, opt := range opts {
+		opt(&tmpl)
+	}
+
+	genesis := &node{
+		block: types.NewGenesis(trie.GetRoot(), tmpl.difficulty),
+		tree:  trie,
+		work:  new(big.Int),
+	}
+
+	s := &Service{
+		logger:      dela.Logger,
 		pool:        pool,
 		validation:  val,
-		epochs:      []epoch{{store: trie}},
 		hashFactory: crypto.NewSha256Factory(),
-		difficulty:  1,
+		db:          tmpl.db,
+		context:     json.NewContext(),
+		blockFac:    types.NewBlockFactory(val.GetFactory()),
+		interval:    tmpl.interval,
+		window:      tmpl.window,
+		finality:    tmpl.finality,
+		nodes:       map[string]*node{string(genesis.block.GetHash()): genesis},
+		chain:       []*node{genesis},
 		watcher:     core.NewWatcher(),
 	}
+
+	if tmpl.mino != nil {
+		s.logger = s.logger.With().Str("addr", tmpl.mino.GetAddress().String()).Logger()
+
+		fac := types.NewMessageFactory(s.blockFac)
+		s.rpc = mino.MustCreateRPC(tmpl.mino, rpcName, handler{Service: s}, fac)
+	}
+
+	return s
+}
+
+// SetPlayers changes the list of participants that the blocks are gossiped to.
+func (s *Service) SetPlayers(players mino.Players) {
+	s.Lock()
+	s.players = players
+	s.Unlock()
 }
 
-// Listen implements ordering.Service.
+// Listen implements ordering.Service. It restores the chain from the database,
+// if any, and starts to mine the blocks.
 func (s *Service) Listen() error {
 	if s.closing != nil {
 		return xerrors.New("service already started")
 	}
 
+	err := s.load()
+	if err != nil {
+		return xerrors.Errorf("failed to load blocks: %v", err)
+	}
+
 	s.closing = make(chan struct{})
 	s.closed = sync.WaitGroup{}
 	s.closed.Add(1)
@@ -85,7 +259,7 @@ func (s *Service) Listen() error {
 				if err != nil {
 					// Something went wrong when creating the block. The main
 					// loop is stopped as this is a critical error.
-					dela.Logger.Err(err).Msg("failed to create block")
+					s.logger.Err(err).Msg("failed to create block")
 					return
 				}
 
@@ -110,18 +284,37 @@ func (s *Service) Stop() error {
 	return nil
 }
 
+// Close implements ordering.Service. It stops the service if it is started.
+func (s *Service) Close() error {
+	if s.closing == nil {
+		return nil
+	}
+
+	return s.Stop()
+}
+
+// GetStore implements ordering.Service. It returns the state after the last
+// block of the chain.
+func (s *Service) GetStore() store.Readable {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.head().tree
+}
+
 // GetProof implements ordering.Service.
 func (s *Service) GetProof(key []byte) (ordering.Proof, error) {
-	last := s.epochs[len(s.epochs)-1]
+	s.Lock()
+	defer s.Unlock()
 
-	path, err := last.store.GetPath(key)
+	path, err := s.head().tree.GetPath(key)
 	if err != nil {
 		return nil, xerrors.Errorf("couldn't read share: %v", err)
 	}
 
-	blocks := make([]Block, len(s.epochs))
-	for i, epoch := range s.epochs {
-		blocks[i] = epoch.block
+	blocks := make([]types.Block, len(s.chain))
+	for i, n := range s.chain {
+		blocks[i] = n.block
 	}
 
 	pr, err := NewProof(blocks, path)
@@ -132,7 +325,9 @@ func (s *Service) GetProof(key []byte) (ordering.Proof, error) {
 	return pr, nil
 }
 
-// Watch implements ordering.Service.
+// Watch implements ordering.Service. When the chain switches to another
+// branch, a rollback event with the index of the first block that left the
+// chain is notified, followed by the events of the blocks of the new branch.
 func (s *Service) Watch(ctx context.Context) <-chan ordering.Event {
 	events := make(chan ordering.Event, 1)
 
@@ -147,21 +342,55 @@ func (s *Service) Watch(ctx context.Context) <-chan ordering.Event {
 	return events
 }
 
+// WatchFrom implements ordering.Service. It returns a channel populated with
+// the events of the blocks from the given index, followed by the events of new
+// blocks. A reorganization of the blocks already sent is announced with a
+// rollback event.
+func (s *Service) WatchFrom(ctx context.Context, index uint64) <-chan ordering.Event {
+	if index == 0 {
+		// The genesis block has no transaction.
+		index = 1
+	}
+
+	return ordering.Replay(ctx, s.watcher, index, func(index uint64) (ordering.Event, error) {
+		s.Lock()
+		defer s.Unlock()
+
+		if index >= uint64(len(s.chain)) {
+			return ordering.Event{}, xerrors.Errorf("block %d: %w", index, ordering.ErrNoEvent)
+		}
+
+		return makeEvent(s.chain[index].block), nil
+	})
+}
+
 func (s *Service) createBlock(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.Lock()
+	s.abort = cancel
+	parent := s.head()
+	s.Unlock()
+
 	// Wait for at least one transaction before creating a block.
 	txs := s.pool.Gather(ctx, pool.Config{Min: 1})
 
 	if ctx.Err() != nil {
-		// Context is closed so we don't proceed in the block creation.
+		// Context is closed, or the chain has changed, so we don't proceed in
+		// the block creation.
 		return nil
 	}
 
-	latestEpoch := s.epochs[len(s.epochs)-1]
+	timestamp := time.Now()
+	if timestamp.Before(parent.block.GetTimestamp()) {
+		timestamp = parent.block.GetTimestamp()
+	}
 
 	var data validation.Result
-	newTrie, err := latestEpoch.store.Stage(func(rwt store.Snapshot) error {
+	newTrie, err := parent.tree.Stage(func(rwt store.Snapshot) error {
 		var err error
-		data, err = s.validation.Validate(rwt, txs)
+		data, err = s.validation.Validate(rwt, txs, validation.WithTimestamp(timestamp))
 		if err != nil {
 			return xerrors.Errorf("failed to validate: %v", err)
 		}
@@ -173,29 +402,354 @@ func (s *Service) createBlock(ctx context.Context) error {
 		return xerrors.Errorf("couldn't stage store: %v", err)
 	}
 
-	block, err := NewBlock(
+	block, err := types.NewBlock(
 		ctx,
 		data,
-		WithIndex(uint64(len(s.epochs))),
-		WithRoot(newTrie.GetRoot()),
-		WithDifficulty(s.difficulty),
+		types.WithIndex(parent.block.GetIndex()+1),
+		types.WithPrevious(parent.block.GetHash()),
+		types.WithRoot(newTrie.GetRoot()),
+		types.WithTimestamp(timestamp),
+		types.WithDifficulty(s.nextDifficulty(parent)),
+		types.WithHashFactory(s.hashFactory),
 	)
 	if err != nil {
+		if ctx.Err() != nil {
+			// The chain has changed while mining.
+			return nil
+		}
+
 		return xerrors.Errorf("couldn't create block: %v", err)
 	}
 
-	s.epochs = append(s.epochs, epoch{
-		block: block,
-		store: newTrie,
-	})
+	s.Lock()
+
+	if s.head() != parent {
+		// Another block has been appended in the meantime.
+		s.Unlock()
+		return nil
+	}
+
+	n, err := s.insert(parent, block, newTrie)
+	if err != nil {
+		s.Unlock()
+		return xerrors.Errorf("couldn't insert block: %v", err)
+	}
+
+	upd := s.commit(n)
+
+	s.Unlock()
+
+	s.publish(upd)
+
+	s.logger.Trace().Uint64("index", block.GetIndex()).Msg("block append")
+
+	s.gossip(block)
+
+	return nil
+}
 
-	for _, txres := range block.data.GetTransactionResults() {
-		s.pool.Remove(txres.GetTransaction())
+// receive verifies and inserts a block received from a participant, after the
+// ancestors that are missing.
+func (s *Service) receive(from mino.Address, block types.Block) error {
+	blocks, err := s.fetchAncestors(from, block)
+	if err != nil {
+		return xerrors.Errorf("failed to fetch ancestors: %v", err)
 	}
 
-	s.watcher.Notify(ordering.Event{Index: block.index})
+	s.Lock()
+
+	var last *node
+
+	for _, block := range blocks {
+		last, err = s.add(block)
+		if err != nil {
+			s.Unlock()
+			return xerrors.Errorf("block %d: %v", block.GetIndex(), err)
+		}
+	}
 
-	dela.Logger.Trace().Uint64("index", block.index).Msg("block append")
+	upd := s.commit(last)
+
+	if len(upd.added) > 0 && s.abort != nil {
+		s.abort()
+	}
+
+	s.Unlock()
+
+	s.publish(upd)
 
 	return nil
 }
+
+// fetchAncestors returns the list of blocks that must be inserted for the
+// given one, starting from the first block with a known parent. The walk is
+// bounded by the finality depth around the head, and the proof of work of each
+// block is verified before its parent is requested, so that a participant must
+// provide the work of the blocks it makes the node download.
+func (s *Service) fetchAncestors(from mino.Address, block types.Block) ([]types.Block, error) {
+	s.Lock()
+	head := s.head().block.GetIndex()
+	final := s.finalized()
+	s.Unlock()
+
+	if s.finality > 0 && block.GetIndex() > head+s.finality {
+		return nil, xerrors.Errorf("block %d is too far ahead of the head %d",
+			block.GetIndex(), head)
+	}
+
+	err := block.Verify()
+	if err != nil {
+		return nil, xerrors.Errorf("invalid proof of work: %v", err)
+	}
+
+	blocks := []types.Block{block}
+
+	for {
+		first := blocks[0]
+
+		s.Lock()
+		_, known := s.nodes[string(first.GetPrevious())]
+		s.Unlock()
+
+		if known {
+			return blocks, nil
+		}
+
+		if first.GetIndex() <= 1 {
+			return nil, xerrors.Errorf("unknown genesis %#x", first.GetPrevious())
+		}
+
+		if first.GetIndex()-1 <= final {
+			return nil, xerrors.Errorf("block %d is before the finalized block %d",
+				first.GetIndex()-1, final)
+		}
+
+		prev, err := s.fetch(from, first.GetPrevious())
+		if err != nil {
+			return nil, xerrors.Errorf("failed to fetch block %d: %v", first.GetIndex()-1, err)
+		}
+
+		if prev.GetIndex()+1 != first.GetIndex() {
+			return nil, xerrors.Errorf("mismatch index %d != %d", prev.GetIndex(), first.GetIndex()-1)
+		}
+
+		err = s.checkAncestor(prev, first)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid block %d: %v", prev.GetIndex(), err)
+		}
+
+		blocks = append([]types.Block{prev}, blocks...)
+	}
+}
+
+// checkAncestor verifies the proof of work of the block and that its
+// difficulty is consistent with the one of the child. The exact difficulty is
+// only known when the parent is, but it changes by one at most when the child
+// starts a new window, and it is otherwise the same.
+func (s *Service) checkAncestor(block, child types.Block) error {
+	err := block.Verify()
+	if err != nil {
+		return xerrors.Errorf("invalid proof of work: %v", err)
+	}
+
+	diff := block.GetDifficulty()
+	next := child.GetDifficulty()
+
+	if !s.retargets(block.GetIndex()) {
+		if diff != next {
+			return xerrors.Errorf("mismatch difficulty %d != %d", diff, next)
+		}
+
+		return nil
+	}
+
+	if diff+1 < next || next+1 < diff {
+		return xerrors.Errorf("difficulty %d is too far from %d", diff, next)
+	}
+
+	return nil
+}
+
+// fetch requests the block with the given hash to the participant.
+func (s *Service) fetch(from mino.Address, id []byte) (types.Block, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gossipTimeout)
+	defer cancel()
+
+	resps, err := s.rpc.Call(ctx, types.NewBlockRequest(id), mino.NewAddresses(from))
+	if err != nil {
+		return types.Block{}, xerrors.Errorf("call failed: %v", err)
+	}
+
+	resp, more := <-resps
+	if !more {
+		return types.Block{}, xerrors.New("no reply")
+	}
+
+	msg, err := resp.GetMessageOrError()
+	if err != nil {
+		return types.Block{}, xerrors.Errorf("reply: %v", err)
+	}
+
+	reply, ok := msg.(types.BlockMessage)
+	if !ok {
+		return types.Block{}, xerrors.Errorf("invalid reply '%T'", msg)
+	}
+
+	block := reply.GetBlock()
+
+	hash := block.GetHash()
+	if string(hash) != string(id) {
+		return types.Block{}, xerrors.Errorf("mismatch block %#x != %#x", hash, id)
+	}
+
+	return block, nil
+}
+
+// gossip sends the block to the participants. A failure is only logged as the
+// block will be downloaded with the next one.
+func (s *Service) gossip(block types.Block) {
+	s.Lock()
+	players := s.players
+	s.Unlock()
+
+	if s.rpc == nil || players == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gossipTimeout)
+	defer cancel()
+
+	resps, err := s.rpc.Call(ctx, types.NewBlockMessage(block), players)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("failed to gossip block")
+		return
+	}
+
+	for resp := range resps {
+		_, err := resp.GetMessageOrError()
+		if err != nil {
+			s.logger.Warn().Err(err).Msg("block not accepted")
+		}
+	}
+}
+
+// publish updates the pool and notifies the events of the new blocks of the
+// chain, after a rollback event if blocks left the chain. The transactions of
+// those blocks are returned to the pool.
+func (s *Service) publish(upd update) {
+	if len(upd.removed) > 0 {
+		s.logger.Info().
+			Int("removed", len(upd.removed)).
+			Int("added", len(upd.added)).
+			Msg("chain reorganization")
+	}
+
+	for _, n := range upd.removed {
+		for _, res := range n.block.GetData().GetTransactionResults() {
+			// The transactions that are included again are removed later on.
+			s.pool.Add(res.GetTransaction())
+		}
+	}
+
+	for _, n := range upd.added {
+		for _, res := range n.block.GetData().GetTransactionResults() {
+			s.pool.Remove(res.GetTransaction())
+		}
+	}
+
+	if len(upd.removed) > 0 {
+		s.watcher.Notify(ordering.Event{
+			Index:    upd.removed[0].block.GetIndex(),
+			Rollback: true,
+		})
+	}
+
+	for _, n := range upd.added {
+		s.watcher.Notify(makeEvent(n.block))
+	}
+}
+
+// load restores the blocks from the database, if any.
+func (s *Service) load() error {
+	if s.db == nil {
+		return nil
+	}
+
+	blocks, err := s.readBlocks()
+	if err != nil {
+		return xerrors.Errorf("while reading: %v", err)
+	}
+
+	final, err := s.readFinal()
+	if err != nil {
+		return xerrors.Errorf("while reading final block: %v", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if final != nil {
+		err = s.restore(blocks, final)
+		if err != nil {
+			return xerrors.Errorf("failed to restore final block: %v", err)
+		}
+	}
+
+	for _, block := range blocks {
+		if block.GetIndex() <= s.committed {
+			// The block is either part of the chain restored up to the final
+			// block, or of a branch that forks before it.
+			continue
+		}
+
+		_, err := s.add(block)
+		if err != nil {
+			return xerrors.Errorf("block %d: %v", block.GetIndex(), err)
+		}
+	}
+
+	s.commit(s.best())
+
+	return nil
+}
+
+// handler processes the messages of the participants.
+//
+// - implements mino.Handler
+type handler struct {
+	*Service
+	mino.UnsupportedHandler
+}
+
+// Process implements mino.Handler. It inserts the announced blocks, and it
+// replies with the requested blocks.
+func (h handler) Process(req mino.Request) (serde.Message, error) {
+	switch msg := req.Message.(type) {
+	case types.BlockMessage:
+		err := h.receive(req.Address, msg.GetBlock())
+		if err != nil {
+			return nil, xerrors.Errorf("invalid block: %v", err)
+		}
+
+		return nil, nil
+	case types.BlockRequest:
+		h.Lock()
+		n := h.nodes[string(msg.GetID())]
+		h.Unlock()
+
+		if n == nil {
+			return nil, xerrors.Errorf("block %#x not found", msg.GetID())
+		}
+
+		return types.NewBlockMessage(n.block), nil
+	default:
+		return nil, xerrors.Errorf("unsupported message '%T'", req.Message)
+	}
+}
+
+func makeEvent(block types.Block) ordering.Event {
+	return ordering.Event{
+		Index:        block.GetIndex(),
+		Transactions: block.GetData().GetTransactionResults(),
+	}
+}
@@ -1,15 +1,21 @@
 package pow
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/execution"
 	"go.dedis.ch/dela/core/execution/native"
+	"go.dedis.ch/dela/core/ordering/pow/types"
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/store/hashtree"
 	tree "go.dedis.ch/dela/core/store/hashtree/binprefix"
@@ -22,6 +28,8 @@ import (
 	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/crypto/bls"
 	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/mino/minoch"
 	"golang.org/x/xerrors"
 )
 
@@ -68,6 +76,18 @@ func TestService_Basic(t *testing.T) {
 
 	evt = <-evts
 	require.Equal(t, uint64(2), evt.Index)
+	require.Len(t, evt.Transactions, 1)
+
+	// 5. A late watcher receives the past blocks and then the new ones.
+	replay := srvc.WatchFrom(ctx, 0)
+
+	require.NoError(t, pool.Add(makeTx(t, 2, signer)))
+
+	for i := uint64(1); i <= 3; i++ {
+		evt = <-replay
+		require.Equal(t, i, evt.Index)
+		require.Len(t, evt.Transactions, 1)
+	}
 }
 
 func TestService_Listen(t *testing.T) {
@@ -99,33 +119,626 @@ func TestService_Listen(t *testing.T) {
 	srvc.closed.Wait()
 }
 
+func TestService_Scenario_Gossip(t *testing.T) {
+	manager := minoch.NewManager()
+
+	nodes := make([]testNode, 3)
+	for i := range nodes {
+		nodes[i] = makeNode(t, WithMino(minoch.MustCreate(manager, fmt.Sprintf("node%d", i))))
+		defer nodes[i].clean()
+	}
+
+	nodes[0].srvc.SetPlayers(mino.NewAddresses(nodes[1].addr, nodes[2].addr))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := nodes[2].srvc.Watch(ctx)
+
+	signer := bls.NewSigner()
+	other := bls.NewSigner()
+
+	// 1. The first node mines a block that is announced to the others.
+	require.NoError(t, nodes[0].pool.Add(makeTx(t, 0, signer)))
+	require.NoError(t, nodes[0].srvc.createBlock(ctx))
+
+	evt := <-events
+	require.Equal(t, uint64(1), evt.Index)
+
+	pr, err := nodes[2].srvc.GetProof([]byte("ping"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), pr.GetValue())
+
+	// 2. The second node mines a block on its own branch, which is ignored by
+	// the others as it does not have more work than the current chain.
+	require.NoError(t, nodes[1].pool.Add(makeTx(t, 1, signer)))
+	require.NoError(t, nodes[1].srvc.createBlock(ctx))
+	require.Len(t, nodes[1].srvc.chain, 3)
+
+	// 3. The third node mines two blocks without announcing them. When the
+	// second one is announced, the others download the missing one and switch
+	// to the branch with the most work.
+	for i := uint64(2); i <= 3; i++ {
+		require.NoError(t, nodes[2].pool.Add(makeTx(t, i-2, other)))
+		require.NoError(t, nodes[2].srvc.createBlock(ctx))
+
+		evt = <-events
+		require.Equal(t, i, evt.Index)
+	}
+
+	last := nodes[2].srvc.chain[3].block
+	nodes[2].srvc.SetPlayers(mino.NewAddresses(nodes[0].addr, nodes[1].addr))
+	nodes[2].srvc.gossip(last)
+
+	for _, n := range nodes {
+		require.Len(t, n.srvc.chain, 4)
+		require.Equal(t, last.GetHash(), n.srvc.head().block.GetHash())
+	}
+
+	// The transaction of the second node was in the abandoned branch, so it is
+	// back in its pool.
+	require.Equal(t, 1, nodes[1].pool.Len())
+}
+
+func TestService_Scenario_Reorganization(t *testing.T) {
+	local := makeNode(t, WithDB(kv.NewInMemory()), WithFinalityDepth(2))
+	defer local.clean()
+
+	srvc := local.srvc
+	genesis := srvc.head()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := srvc.Watch(ctx)
+
+	signer := bls.NewSigner()
+	other := bls.NewSigner()
+
+	// 1. The chain grows with two blocks that set the key A.
+	chain := []*node{genesis}
+	for i := uint64(0); i < 2; i++ {
+		block := mineBlock(t, srvc, chain[i], makeSetTx(t, i, signer, "A", fmt.Sprint(i)))
+
+		n, err := srvc.add(block)
+		require.NoError(t, err)
+
+		go srvc.publish(srvc.commit(n))
+
+		evt := <-events
+		require.Equal(t, i+1, evt.Index)
+
+		chain = append(chain, n)
+	}
+
+	// 2. A branch from the genesis block that sets the key B does not replace
+	// the chain as long as it does not have more work.
+	branch := []*node{genesis}
+	for i := uint64(0); i < 3; i++ {
+		block := mineBlock(t, srvc, branch[i], makeSetTx(t, i, other, "B", fmt.Sprint(i)))
+
+		n, err := srvc.add(block)
+		require.NoError(t, err)
+
+		branch = append(branch, n)
+
+		if i < 2 {
+			upd := srvc.commit(n)
+			require.Empty(t, upd.added)
+			require.Equal(t, chain[2], srvc.head())
+		}
+	}
+
+	// 3. The third block gives more work to the branch, which replaces the
+	// chain from the common ancestor.
+	upd := srvc.commit(branch[3])
+	require.Equal(t, chain[1:], upd.removed)
+	require.Equal(t, branch[1:], upd.added)
+	require.Equal(t, branch, srvc.chain)
+
+	go srvc.publish(upd)
+
+	evt := <-events
+	require.True(t, evt.Rollback)
+	require.Equal(t, uint64(1), evt.Index)
+
+	for i := uint64(1); i <= 3; i++ {
+		evt := <-events
+		require.Equal(t, i, evt.Index)
+		require.Len(t, evt.Transactions, 1)
+	}
+
+	// The state is the one of the branch only.
+	value, err := srvc.GetStore().Get([]byte("A"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	value, err = srvc.GetStore().Get([]byte("B"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), value)
+
+	// The transactions of the abandoned blocks are back in the pool.
+	require.Equal(t, 2, local.pool.Len())
+
+	// 4. The abandoned blocks fork before the finalized block, so they are
+	// dropped from the memory and the database.
+	require.Len(t, srvc.nodes, 4)
+	require.Nil(t, srvc.nodes[string(chain[2].block.GetHash())])
+
+	blocks, err := srvc.readBlocks()
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+
+	block := makeBlock(t, types.WithIndex(1), types.WithPrevious(genesis.block.GetHash()))
+	_, err = srvc.add(block)
+	require.EqualError(t, err, "invalid block: block 1 is before the finalized block 1")
+
+	// The state of the final block is committed, and the one before is
+	// released.
+	require.Equal(t, uint64(1), srvc.committed)
+	require.Nil(t, genesis.tree)
+	require.NotNil(t, branch[1].tree)
+}
+
+func TestService_Prune(t *testing.T) {
+	node := makeNode(t, WithFinalityDepth(1))
+	defer node.clean()
+
+	srvc := node.srvc
+	genesis := srvc.head()
+
+	first, err := srvc.add(mineBlock(t, srvc, genesis))
+	require.NoError(t, err)
+
+	_, err = srvc.add(mineBlock(t, srvc, genesis, makeTx(t, 0, bls.NewSigner())))
+	require.NoError(t, err)
+
+	second, err := srvc.add(mineBlock(t, srvc, first))
+	require.NoError(t, err)
+
+	buffer := new(bytes.Buffer)
+	srvc.logger = zerolog.New(buffer)
+	srvc.db = fake.NewBadDB()
+
+	srvc.commit(second)
+	require.Len(t, srvc.nodes, 3)
+	require.Contains(t, buffer.String(), "failed to delete pruned blocks")
+
+	// The state is committed even if the final block cannot be stored.
+	require.Contains(t, buffer.String(), "failed to store final block")
+	require.Equal(t, uint64(1), srvc.committed)
+}
+
+func TestService_Finalize(t *testing.T) {
+	node := makeNode(t, WithFinalityDepth(1))
+	defer node.clean()
+
+	srvc := node.srvc
+	genesis := srvc.head()
+
+	first, err := srvc.add(mineBlock(t, srvc, genesis))
+	require.NoError(t, err)
+
+	second, err := srvc.add(mineBlock(t, srvc, first))
+	require.NoError(t, err)
+
+	buffer := new(bytes.Buffer)
+	srvc.logger = zerolog.New(buffer)
+
+	first.tree = badTrie{}
+	srvc.commit(second)
+	require.Contains(t, buffer.String(), "state cannot be committed")
+	require.Equal(t, uint64(0), srvc.committed)
+	require.NotNil(t, genesis.tree)
+
+	first.tree = badStagingTree{}
+	srvc.finalize()
+	require.Contains(t, buffer.String(), "failed to commit state")
+	require.Equal(t, uint64(0), srvc.committed)
+}
+
+func TestService_FetchAncestors(t *testing.T) {
+	manager := minoch.NewManager()
+
+	nodes := make([]testNode, 2)
+	for i := range nodes {
+		nodes[i] = makeNode(t, WithMino(minoch.MustCreate(manager, fmt.Sprintf("node%d", i))),
+			WithFinalityDepth(4))
+		defer nodes[i].clean()
+	}
+
+	srvc := nodes[0].srvc
+	remote := nodes[1].srvc
+
+	// The remote node has a chain of blocks that the node does not know.
+	parent := remote.head()
+	for i := 0; i < 4; i++ {
+		n, err := remote.add(mineBlock(t, remote, parent))
+		require.NoError(t, err)
+
+		remote.commit(n)
+		parent = n
+	}
+
+	blocks, err := srvc.fetchAncestors(nodes[1].addr, remote.head().block)
+	require.NoError(t, err)
+	require.Len(t, blocks, 4)
+
+	// The announced block is too far ahead of the head.
+	ahead := makeBlock(t, types.WithIndex(5))
+	_, err = srvc.fetchAncestors(nodes[1].addr, ahead)
+	require.EqualError(t, err, "block 5 is too far ahead of the head 0")
+
+	invalid := makeInvalidBlock(t, nil)
+	_, err = srvc.fetchAncestors(nodes[1].addr, invalid)
+	require.EqualError(t, err, "invalid proof of work: "+invalid.Verify().Error())
+
+	// The ancestors must have a valid proof of work and a difficulty
+	// consistent with their children.
+	bad := makeInvalidBlock(t, nil)
+	remote.nodes[string(bad.GetHash())] = &node{block: bad}
+
+	child := makeBlock(t, types.WithIndex(2), types.WithPrevious(bad.GetHash()))
+	_, err = srvc.fetchAncestors(nodes[1].addr, child)
+	require.EqualError(t, err, "invalid block 1: invalid proof of work: "+bad.Verify().Error())
+
+	harder := makeBlock(t, types.WithIndex(1), types.WithDifficulty(2))
+	remote.nodes[string(harder.GetHash())] = &node{block: harder}
+
+	child = makeBlock(t, types.WithIndex(2), types.WithPrevious(harder.GetHash()))
+	_, err = srvc.fetchAncestors(nodes[1].addr, child)
+	require.EqualError(t, err, "invalid block 1: mismatch difficulty 2 != 1")
+
+	// The walk stops at the finalized block.
+	parent = srvc.head()
+	for i := 0; i < 5; i++ {
+		n, err := srvc.add(mineBlock(t, srvc, parent))
+		require.NoError(t, err)
+
+		srvc.commit(n)
+		parent = n
+	}
+
+	_, err = srvc.fetchAncestors(nodes[1].addr, remote.head().block)
+	require.EqualError(t, err, "block 1 is before the finalized block 1")
+}
+
+func TestService_CheckAncestor(t *testing.T) {
+	srvc := &Service{window: 2}
+
+	parent := makeBlock(t, types.WithIndex(3), types.WithDifficulty(2))
+
+	err := srvc.checkAncestor(parent, makeBlock(t, types.WithDifficulty(1)))
+	require.NoError(t, err)
+
+	err = srvc.checkAncestor(parent, makeBlock(t, types.WithDifficulty(3)))
+	require.NoError(t, err)
+
+	err = srvc.checkAncestor(parent, makeBlock(t, types.WithDifficulty(4)))
+	require.EqualError(t, err, "difficulty 2 is too far from 4")
+
+	parent = makeBlock(t, types.WithIndex(2), types.WithDifficulty(2))
+
+	err = srvc.checkAncestor(parent, makeBlock(t, types.WithDifficulty(1)))
+	require.EqualError(t, err, "mismatch difficulty 2 != 1")
+}
+
+func TestService_Scenario_Restart(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-pow")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	db, err := kv.New(filepath.Join(dir, "blocks.db"))
+	require.NoError(t, err)
+
+	node := makeNode(t, WithDB(db))
+	defer node.clean()
+
+	signer := bls.NewSigner()
+
+	ctx := context.Background()
+
+	require.NoError(t, node.pool.Add(makeTx(t, 0, signer)))
+	require.NoError(t, node.srvc.createBlock(ctx))
+	require.NoError(t, node.pool.Add(makeTx(t, 1, signer)))
+	require.NoError(t, node.srvc.createBlock(ctx))
+
+	// The chain is replayed from the database on top of the initial tree.
+	srvc := NewService(node.pool, node.srvc.validation, node.tree, WithDB(db))
+	require.NoError(t, srvc.Listen())
+	defer srvc.Close()
+
+	require.Len(t, srvc.chain, 3)
+	require.Equal(t, node.srvc.head().block.GetHash(), srvc.head().block.GetHash())
+
+	pr, err := srvc.GetProof([]byte("ping"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), pr.GetValue())
+
+	evt := <-srvc.WatchFrom(ctx, 0)
+	require.Equal(t, uint64(1), evt.Index)
+
+	// The chain is restored up to the final block on top of the tree loaded
+	// from its database, and the blocks after it are executed again.
+	db, err = kv.New(filepath.Join(dir, "final.db"))
+	require.NoError(t, err)
+
+	treeDB, err := kv.New(filepath.Join(dir, "tree.db"))
+	require.NoError(t, err)
+
+	opts := []ServiceOption{WithDB(db), WithFinalityDepth(1)}
+
+	first := NewService(node.pool, node.srvc.validation, tree.NewMerkleTree(treeDB, tree.Nonce{}), opts...)
+
+	for i := uint64(0); i < 3; i++ {
+		require.NoError(t, node.pool.Add(makeTx(t, i, signer)))
+		require.NoError(t, first.createBlock(ctx))
+	}
+
+	require.Equal(t, uint64(2), first.committed)
+
+	trie := tree.NewMerkleTree(treeDB, tree.Nonce{})
+	require.NoError(t, trie.Load())
+
+	srvc = NewService(node.pool, node.srvc.validation, trie, opts...)
+	require.NoError(t, srvc.Listen())
+	defer srvc.Close()
+
+	require.Len(t, srvc.chain, 4)
+	require.Equal(t, first.head().block.GetHash(), srvc.head().block.GetHash())
+	require.Equal(t, uint64(2), srvc.committed)
+	require.Nil(t, srvc.chain[1].tree)
+
+	pr, err = srvc.GetProof([]byte("ping"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), pr.GetValue())
+
+	srvc = NewService(node.pool, node.srvc.validation, node.tree, opts...)
+	err = srvc.Listen()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to restore final block: mismatch tree root")
+
+	srvc = NewService(node.pool, node.srvc.validation, node.tree, WithDB(fake.NewBadViewDB()))
+	err = srvc.Listen()
+	require.EqualError(t, err, fake.Err("failed to load blocks: while reading"))
+}
+
+func TestService_GetStore(t *testing.T) {
+	tree, clean := makeTree(t)
+	defer clean()
+
+	srvc := NewService(nil, fakeValidation{}, tree)
+
+	require.Equal(t, tree, srvc.GetStore())
+	require.NoError(t, srvc.Close())
+}
+
 func TestService_GetProof(t *testing.T) {
 	tree, clean := makeTree(t)
 	defer clean()
 
-	srvc := &Service{
-		epochs: []epoch{{store: tree}},
-	}
+	srvc := NewService(nil, fakeValidation{}, tree)
 
 	pr, err := srvc.GetProof([]byte("A"))
 	require.NoError(t, err)
 	require.Equal(t, []byte("A"), pr.GetKey())
 
-	srvc.epochs[0].block.root = []byte{1}
+	srvc.chain[0].block = types.NewGenesis([]byte{1}, 1)
 	_, err = srvc.GetProof([]byte("A"))
 	require.EqualError(t, err,
 		"couldn't create proof: mismatch block and share store root 0x01 != ")
 
-	srvc.epochs[0].store = badTrie{}
+	srvc.chain[0].tree = badTrie{}
 	_, err = srvc.GetProof([]byte("A"))
 	require.EqualError(t, err, fake.Err("couldn't read share"))
 }
 
+func TestService_NextDifficulty(t *testing.T) {
+	srvc := &Service{
+		interval: 10 * time.Second,
+		window:   2,
+	}
+
+	start := time.Now()
+
+	makeChain := func(elapsed time.Duration) *node {
+		parent := &node{block: types.NewGenesis(nil, 2)}
+
+		for i := uint64(1); i <= 3; i++ {
+			ts := start
+			if i == 3 {
+				ts = start.Add(elapsed)
+			}
+
+			parent = &node{
+				block:  makeBlock(t, types.WithIndex(i), types.WithTimestamp(ts), types.WithDifficulty(2)),
+				parent: parent,
+			}
+		}
+
+		return parent
+	}
+
+	require.Equal(t, uint(3), srvc.nextDifficulty(makeChain(5*time.Second)))
+	require.Equal(t, uint(2), srvc.nextDifficulty(makeChain(20*time.Second)))
+	require.Equal(t, uint(1), srvc.nextDifficulty(makeChain(50*time.Second)))
+
+	// The difficulty is adjusted only every window.
+	require.Equal(t, uint(2), srvc.nextDifficulty(makeChain(0).parent))
+
+	srvc.window = 0
+	require.Equal(t, uint(2), srvc.nextDifficulty(makeChain(0)))
+}
+
+func TestService_Add(t *testing.T) {
+	node := makeNode(t)
+	defer node.clean()
+
+	srvc := node.srvc
+	genesis := srvc.head()
+
+	next, err := genesis.tree.Stage(func(store.Snapshot) error { return nil })
+	require.NoError(t, err)
+
+	now := time.Now()
+
+	block := makeBlock(t,
+		types.WithIndex(1),
+		types.WithPrevious(genesis.block.GetHash()),
+		types.WithRoot(next.GetRoot()),
+		types.WithTimestamp(now))
+
+	n, err := srvc.add(block)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(2), n.work)
+
+	same, err := srvc.add(block)
+	require.NoError(t, err)
+	require.Equal(t, n, same)
+
+	_, err = srvc.add(makeBlock(t, types.WithIndex(1)))
+	require.EqualError(t, err, "unknown parent ")
+
+	_, err = srvc.add(makeBlock(t, types.WithPrevious(genesis.block.GetHash())))
+	require.EqualError(t, err, "invalid block: mismatch index 0 != 1")
+
+	_, err = srvc.add(makeBlock(t,
+		types.WithIndex(1),
+		types.WithPrevious(genesis.block.GetHash()),
+		types.WithDifficulty(2)))
+	require.EqualError(t, err, "invalid block: mismatch difficulty 2 != 1")
+
+	invalid := makeInvalidBlock(t, genesis.block.GetHash())
+	_, err = srvc.add(invalid)
+	require.EqualError(t, err, "invalid block: invalid proof of work: "+invalid.Verify().Error())
+
+	_, err = srvc.add(makeBlock(t,
+		types.WithIndex(2),
+		types.WithPrevious(block.GetHash()),
+		types.WithTimestamp(now.Add(-time.Second))))
+	require.EqualError(t, err, "invalid block: timestamp goes backwards by 1s")
+
+	_, err = srvc.add(makeBlock(t,
+		types.WithIndex(2),
+		types.WithPrevious(block.GetHash()),
+		types.WithTimestamp(now.Add(time.Hour))))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid block: timestamp in the future by ")
+
+	_, err = srvc.add(makeBlock(t,
+		types.WithIndex(2),
+		types.WithPrevious(block.GetHash()),
+		types.WithTimestamp(now),
+		types.WithRoot([]byte{1})))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid block: mismatch tree root ")
+
+	vs := srvc.validation
+	srvc.validation = badValidation{}
+	_, err = srvc.add(makeBlock(t,
+		types.WithIndex(2),
+		types.WithPrevious(block.GetHash()),
+		types.WithTimestamp(now)))
+	require.EqualError(t, err,
+		fake.Err("invalid block: while updating tree: callback failed: validation failed"))
+
+	srvc.validation = vs
+	srvc.db = fake.NewBadDB()
+	_, err = srvc.add(makeBlock(t,
+		types.WithIndex(2),
+		types.WithPrevious(block.GetHash()),
+		types.WithRoot(next.GetRoot()),
+		types.WithTimestamp(now)))
+	require.EqualError(t, err, fake.Err("couldn't insert block: failed to store block: bucket"))
+}
+
+func TestHandler_Process(t *testing.T) {
+	node := makeNode(t)
+	defer node.clean()
+
+	h := handler{Service: node.srvc}
+
+	genesis := node.srvc.head().block
+
+	msg, err := h.Process(mino.Request{Message: types.NewBlockRequest(genesis.GetHash())})
+	require.NoError(t, err)
+	require.Equal(t, types.NewBlockMessage(genesis), msg)
+
+	_, err = h.Process(mino.Request{Message: types.NewBlockRequest([]byte{1})})
+	require.EqualError(t, err, "block 0x01 not found")
+
+	_, err = h.Process(mino.Request{Message: types.NewBlockMessage(makeBlock(t, types.WithIndex(1)))})
+	require.EqualError(t, err, "invalid block: failed to fetch ancestors: unknown genesis ")
+
+	_, err = h.Process(mino.Request{Message: fake.Message{}})
+	require.EqualError(t, err, "unsupported message 'fake.Message'")
+}
+
 // -----------------------------------------------------------------------------
 // Utility functions
 
 const testContractName = "abc"
 
+type testNode struct {
+	addr  mino.Address
+	pool  *pool.Pool
+	tree  hashtree.Tree
+	srvc  *Service
+	clean func()
+}
+
+func makeNode(t *testing.T, opts ...ServiceOption) testNode {
+	tree, clean := makeTree(t)
+
+	exec := native.NewExecution()
+	exec.Set(testContractName, testExec{})
+
+	pool := pool.NewPool()
+	srvc := NewService(pool, val.NewService(exec, signed.NewTransactionFactory()), tree, opts...)
+
+	node := testNode{
+		pool:  pool,
+		tree:  tree,
+		srvc:  srvc,
+		clean: clean,
+	}
+
+	tmpl := serviceTemplate{}
+	for _, opt := range opts {
+		opt(&tmpl)
+	}
+
+	if tmpl.mino != nil {
+		node.addr = tmpl.mino.GetAddress()
+	}
+
+	return node
+}
+
+func makeBlock(t *testing.T, opts ...types.BlockOption) types.Block {
+	block, err := types.NewBlock(context.Background(), val.NewResult(nil), opts...)
+	require.NoError(t, err)
+
+	return block
+}
+
+// makeInvalidBlock returns a block with a nonce that does not match the
+// difficulty.
+func makeInvalidBlock(t *testing.T, previous []byte) types.Block {
+	for nonce := uint64(0); ; nonce++ {
+		block := makeBlock(t,
+			types.WithIndex(1),
+			types.WithPrevious(previous),
+			types.WithNonce(nonce),
+			types.WithDifficulty(1))
+
+		if block.Verify() != nil {
+			return block
+		}
+	}
+}
+
 func makeTree(t *testing.T) (hashtree.Tree, func()) {
 	dir, err := ioutil.TempDir(os.TempDir(), "dela-pow")
 	require.NoError(t, err)
@@ -139,16 +752,47 @@ func makeTree(t *testing.T) (hashtree.Tree, func()) {
 	return tree, func() { os.RemoveAll(dir) }
 }
 
+// mineBlock returns a valid block on top of the parent with the transactions.
+func mineBlock(t *testing.T, srvc *Service, parent *node, txs ...txn.Transaction) types.Block {
+	timestamp := time.Now()
+
+	var data validation.Result
+	next, err := parent.tree.Stage(func(snap store.Snapshot) error {
+		var err error
+		data, err = srvc.validation.Validate(snap, txs, validation.WithTimestamp(timestamp))
+		return err
+	})
+	require.NoError(t, err)
+
+	block, err := types.NewBlock(
+		context.Background(),
+		data,
+		types.WithIndex(parent.block.GetIndex()+1),
+		types.WithPrevious(parent.block.GetHash()),
+		types.WithRoot(next.GetRoot()),
+		types.WithTimestamp(timestamp),
+		types.WithDifficulty(srvc.nextDifficulty(parent)))
+	require.NoError(t, err)
+
+	return block
+}
+
 func makeTx(t *testing.T, nonce uint64, signer crypto.Signer) txn.Transaction {
+	return makeSetTx(t, nonce, signer, "ping", "pong")
+}
+
+func makeSetTx(t *testing.T, nonce uint64, signer crypto.Signer, key, value string) txn.Transaction {
 	tx, err := signed.NewTransaction(
 		nonce,
 		signer.GetPublicKey(),
-		signed.WithArg("key", []byte("ping")),
-		signed.WithArg("value", []byte("pong")),
+		signed.WithArg("key", []byte(key)),
+		signed.WithArg("value", []byte(value)),
 		signed.WithArg(native.ContractArg, []byte(testContractName)),
 	)
 	require.NoError(t, err)
 
+	require.NoError(t, tx.Sign(signer))
+
 	return tx
 }
 
@@ -174,14 +818,34 @@ type badValidation struct {
 	validation.Service
 }
 
-func (v badValidation) Validate(store.Snapshot, []txn.Transaction) (validation.Result, error) {
+func (v badValidation) GetFactory() validation.ResultFactory {
+	return nil
+}
+
+func (v badValidation) Validate(store.Snapshot, []txn.Transaction, ...validation.Option) (validation.Result, error) {
 	return nil, fake.GetError()
 }
 
+type fakeValidation struct {
+	validation.Service
+}
+
+func (v fakeValidation) GetFactory() validation.ResultFactory {
+	return nil
+}
+
 type badTrie struct {
 	hashtree.Tree
 }
 
+type badStagingTree struct {
+	hashtree.StagingTree
+}
+
+func (t badStagingTree) Commit() error {
+	return fake.GetError()
+}
+
 func (s badTrie) GetPath([]byte) (hashtree.Path, error) {
 	return nil, fake.GetError()
 }
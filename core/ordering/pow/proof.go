@@ -3,6 +3,7 @@ package pow
 import (
 	"bytes"
 
+	"go.dedis.ch/dela/core/ordering/pow/types"
 	"go.dedis.ch/dela/core/store/hashtree"
 	"golang.org/x/xerrors"
 )
@@ -12,12 +13,12 @@ import (
 //
 // - implements ordering.Proof
 type Proof struct {
-	blocks []Block
+	blocks []types.Block
 	path   hashtree.Path
 }
 
 // NewProof creates a new valid proof.
-func NewProof(blocks []Block, path hashtree.Path) (Proof, error) {
+func NewProof(blocks []types.Block, path hashtree.Path) (Proof, error) {
 	pr := Proof{
 		blocks: blocks,
 		path:   path,
@@ -28,8 +29,8 @@ func NewProof(blocks []Block, path hashtree.Path) (Proof, error) {
 	}
 
 	last := blocks[len(blocks)-1]
-	if !bytes.Equal(last.root, path.GetRoot()) {
-		return pr, xerrors.Errorf("mismatch block and share store root %#x != %#x", last.root, path.GetRoot())
+	if !bytes.Equal(last.GetRoot(), path.GetRoot()) {
+		return pr, xerrors.Errorf("mismatch block and share store root %#x != %#x", last.GetRoot(), path.GetRoot())
 	}
 
 	return pr, nil
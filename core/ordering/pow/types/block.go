@@ -0,0 +1,370 @@
+// Package types implements the blocks and the network messages of the
+// Proof-of-Work ordering service.
+//
+// The types are implemented in a different package to prevent cycle imports
+// when importing the serde formats.
+package types
+
+import (
+	"context"
+	"encoding"
+	"encoding/binary"
+	"math/big"
+	"time"
+
+	"go.dedis.ch/dela/core/validation"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/registry"
+	"golang.org/x/xerrors"
+)
+
+// Difficulty is the default difficulty, which is the number of leading zero
+// bits that the hash of a block must have.
+const Difficulty = 1
+
+var blockFormats = registry.NewSimpleRegistry()
+
+// RegisterBlockFormat registers the engine for the provided format.
+func RegisterBlockFormat(f serde.Format, e serde.FormatEngine) {
+	blockFormats.Register(f, e)
+}
+
+// Block is a representation of a batch of transactions for a Proof-of-Work
+// consensus. Each block has a fingerprint as a proof of correctness.
+//
+// - implements serde.Message
+type Block struct {
+	index      uint64
+	nonce      uint64
+	previous   []byte
+	root       []byte
+	timestamp  int64
+	difficulty uint
+	data       validation.Result
+	hash       []byte
+}
+
+type blockTemplate struct {
+	Block
+
+	hashFactory crypto.HashFactory
+	mine        bool
+}
+
+// BlockOption is the type of options to create a block.
+type BlockOption func(*blockTemplate)
+
+// WithIndex is an option to set the block index.
+func WithIndex(index uint64) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.index = index
+	}
+}
+
+// WithNonce is an option to set the nonce of a block. The hash is then
+// calculated from the nonce instead of being mined.
+func WithNonce(nonce uint64) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.nonce = nonce
+		tmpl.mine = false
+	}
+}
+
+// WithPrevious is an option to set the hash of the previous block.
+func WithPrevious(hash []byte) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.previous = hash
+	}
+}
+
+// WithRoot is an option to set the root of a block.
+func WithRoot(root []byte) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.root = root
+	}
+}
+
+// WithTimestamp is an option to set the time at which the block is mined.
+func WithTimestamp(ts time.Time) BlockOption {
+	return func(tmpl *blockTemplate) {
+		if ts.IsZero() {
+			tmpl.timestamp = 0
+		} else {
+			tmpl.timestamp = ts.UnixNano()
+		}
+	}
+}
+
+// WithDifficulty is an option to set the difficulty of the proof of work.
+func WithDifficulty(diff uint) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.difficulty = diff
+	}
+}
+
+// WithHashFactory is an option to set the hash factory used to calculate the
+// hash of the block.
+func WithHashFactory(fac crypto.HashFactory) BlockOption {
+	return func(tmpl *blockTemplate) {
+		tmpl.hashFactory = fac
+	}
+}
+
+// NewBlock creates a new block. It mines the block to find a nonce that
+// matches the difficulty, unless the nonce is given.
+func NewBlock(ctx context.Context, data validation.Result, opts ...BlockOption) (Block, error) {
+	tmpl := blockTemplate{
+		Block: Block{
+			data:       data,
+			difficulty: Difficulty,
+		},
+		hashFactory: crypto.NewSha256Factory(),
+		mine:        true,
+	}
+
+	for _, opt := range opts {
+		opt(&tmpl)
+	}
+
+	diff := tmpl.difficulty
+	if !tmpl.mine {
+		diff = 0
+	}
+
+	err := tmpl.Block.prepare(ctx, tmpl.hashFactory, diff)
+	if err != nil {
+		return tmpl.Block, xerrors.Errorf("couldn't prepare block: %v", err)
+	}
+
+	return tmpl.Block, nil
+}
+
+// NewGenesis creates the block that starts a chain. It has no transaction and
+// a hash made of zeros, and it sets the state root and the difficulty of the
+// first block.
+func NewGenesis(root []byte, difficulty uint) Block {
+	return Block{
+		root:       root,
+		difficulty: difficulty,
+		hash:       make([]byte, 32),
+	}
+}
+
+// GetIndex returns the index of the block.
+func (b Block) GetIndex() uint64 {
+	return b.index
+}
+
+// GetNonce returns the nonce of the block.
+func (b Block) GetNonce() uint64 {
+	return b.nonce
+}
+
+// GetPrevious returns the hash of the previous block.
+func (b Block) GetPrevious() []byte {
+	return append([]byte{}, b.previous...)
+}
+
+// GetRoot returns the root of the state after the block.
+func (b Block) GetRoot() []byte {
+	return append([]byte{}, b.root...)
+}
+
+// GetTimestamp returns the time at which the block is mined, or the zero time
+// if it is not set.
+func (b Block) GetTimestamp() time.Time {
+	if b.timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, b.timestamp)
+}
+
+// GetDifficulty returns the difficulty of the proof of work.
+func (b Block) GetDifficulty() uint {
+	return b.difficulty
+}
+
+// GetData returns the validated data of the block.
+func (b Block) GetData() validation.Result {
+	return b.data
+}
+
+// GetHash returns the hash of the block.
+func (b Block) GetHash() []byte {
+	return append([]byte{}, b.hash...)
+}
+
+// GetWork returns the amount of work that the block represents, which is the
+// expected number of hashes to find it.
+func (b Block) GetWork() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), b.difficulty)
+}
+
+// Verify returns an error if the hash of the block does not match its
+// difficulty.
+func (b Block) Verify() error {
+	if !matchDifficulty(b.hash, makeTarget(len(b.hash), b.difficulty)) {
+		return xerrors.Errorf("hash %#x does not match difficulty %d", b.hash, b.difficulty)
+	}
+
+	return nil
+}
+
+// Serialize implements serde.Message. It returns the serialized data of the
+// block.
+func (b Block) Serialize(ctx serde.Context) ([]byte, error) {
+	format := blockFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, b)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// Prepare is the actual proof of work on the block. It will find the nonce to
+// match the difficulty level.
+func (b *Block) prepare(ctx context.Context, fac crypto.HashFactory, diff uint) error {
+	h := fac.New()
+
+	buffer := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buffer, b.index)
+	_, err := h.Write(buffer)
+	if err != nil {
+		return xerrors.Errorf("failed to write index: %v", err)
+	}
+
+	_, err = h.Write(b.previous)
+	if err != nil {
+		return xerrors.Errorf("failed to write previous: %v", err)
+	}
+
+	_, err = h.Write(b.root)
+	if err != nil {
+		return xerrors.Errorf("failed to write root: %v", err)
+	}
+
+	binary.LittleEndian.PutUint64(buffer, uint64(b.timestamp))
+	_, err = h.Write(buffer)
+	if err != nil {
+		return xerrors.Errorf("failed to write timestamp: %v", err)
+	}
+
+	binary.LittleEndian.PutUint64(buffer, uint64(b.difficulty))
+	_, err = h.Write(buffer)
+	if err != nil {
+		return xerrors.Errorf("failed to write difficulty: %v", err)
+	}
+
+	err = b.data.Fingerprint(h)
+	if err != nil {
+		return xerrors.Errorf("failed to fingerprint data: %v", err)
+	}
+
+	// The state before writing the nonce is saved so it does not need to be
+	// computed all the time.
+	inter, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return xerrors.Errorf("couldn't marshal digest: %v", err)
+	}
+
+	target := makeTarget(h.Size(), diff)
+
+	for {
+		// Allow the proof of work to be aborted at any time if the context is
+		// cancelled earlier.
+		if ctx.Err() != nil {
+			return xerrors.Errorf("context error: %v", ctx.Err())
+		}
+
+		// Copy h to get the state before the nonce is written.
+		err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(inter)
+		if err != nil {
+			return xerrors.Errorf("couldn't unmarshal digest: %v", err)
+		}
+
+		binary.LittleEndian.PutUint64(buffer, b.nonce)
+		_, err = h.Write(buffer)
+		if err != nil {
+			return xerrors.Errorf("failed to write nonce: %v", err)
+		}
+
+		res := h.Sum(nil)
+		// If no difficulty is set, the provided nonce defines the hash,
+		// otherwise it looks for a hash that matches the difficulty.
+		if diff == 0 || matchDifficulty(res, target) {
+			b.hash = res
+			return nil
+		}
+
+		b.nonce++
+	}
+}
+
+// DataKey is the key for the validated data factory.
+type DataKey struct{}
+
+// BlockFactory is a factory to deserialize blocks.
+//
+// - implements serde.Factory
+type BlockFactory struct {
+	dataFac validation.ResultFactory
+}
+
+// NewBlockFactory creates a new block factory.
+func NewBlockFactory(fac validation.ResultFactory) BlockFactory {
+	return BlockFactory{
+		dataFac: fac,
+	}
+}
+
+// Deserialize implements serde.Factory. It populates the block from the data if
+// appropriate, otherwise it returns an error.
+func (f BlockFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.BlockOf(ctx, data)
+}
+
+// BlockOf returns the block of the data if appropriate, otherwise it returns
+// an error.
+func (f BlockFactory) BlockOf(ctx serde.Context, data []byte) (Block, error) {
+	format := blockFormats.Get(ctx.GetFormat())
+
+	ctx = serde.WithFactory(ctx, DataKey{}, f.dataFac)
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return Block{}, xerrors.Errorf("decoding block failed: %v", err)
+	}
+
+	block, ok := msg.(Block)
+	if !ok {
+		return Block{}, xerrors.Errorf("invalid block '%T'", msg)
+	}
+
+	return block, nil
+}
+
+// makeTarget returns the value that a hash of the given size must be below to
+// match the difficulty.
+func makeTarget(size int, diff uint) *big.Int {
+	bitstring := make([]byte, size)
+	for i := range bitstring {
+		bitstring[i] = 0xff
+	}
+
+	target := new(big.Int)
+	target.SetBytes(bitstring)
+	target.Rsh(target, diff)
+
+	return target
+}
+
+func matchDifficulty(hash []byte, limit *big.Int) bool {
+	value := new(big.Int)
+	value.SetBytes(hash)
+
+	return value.Cmp(limit) == -1
+}
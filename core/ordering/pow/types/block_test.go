@@ -0,0 +1,176 @@
+package types
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/validation"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
+)
+
+func init() {
+	RegisterBlockFormat(fake.GoodFormat, fake.Format{Msg: Block{}})
+	RegisterBlockFormat(fake.BadFormat, fake.NewBadFormat())
+	RegisterBlockFormat(serde.Format("BAD_TYPE"), fake.Format{Msg: fake.Message{}})
+}
+
+func TestBlock_New(t *testing.T) {
+	now := time.Now()
+
+	block, err := NewBlock(context.Background(), fakeData{},
+		WithIndex(1),
+		WithNonce(2),
+		WithPrevious([]byte{4}),
+		WithRoot([]byte{3}),
+		WithTimestamp(now),
+		WithDifficulty(5),
+		WithHashFactory(crypto.NewSha256Factory()))
+
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), block.GetIndex())
+	require.Equal(t, uint64(2), block.GetNonce())
+	require.Equal(t, []byte{4}, block.GetPrevious())
+	require.Equal(t, []byte{3}, block.GetRoot())
+	require.True(t, now.Equal(block.GetTimestamp()))
+	require.Equal(t, uint(5), block.GetDifficulty())
+	require.Equal(t, fakeData{}, block.GetData())
+	require.Len(t, block.GetHash(), 32)
+
+	block, err = NewBlock(context.Background(), fakeData{}, WithDifficulty(8))
+	require.NoError(t, err)
+	require.NoError(t, block.Verify())
+	require.True(t, block.GetTimestamp().IsZero())
+
+	block, err = NewBlock(context.Background(), fakeData{}, WithTimestamp(time.Time{}))
+	require.NoError(t, err)
+	require.True(t, block.GetTimestamp().IsZero())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = NewBlock(ctx, fakeData{})
+	require.EqualError(t, err, "couldn't prepare block: context error: context canceled")
+}
+
+func TestBlock_NewGenesis(t *testing.T) {
+	block := NewGenesis([]byte{1}, 3)
+	require.Equal(t, uint64(0), block.GetIndex())
+	require.Equal(t, []byte{1}, block.GetRoot())
+	require.Equal(t, uint(3), block.GetDifficulty())
+	require.Equal(t, make([]byte, 32), block.GetHash())
+}
+
+func TestBlock_GetWork(t *testing.T) {
+	block := Block{difficulty: 0}
+	require.Equal(t, big.NewInt(1), block.GetWork())
+
+	block.difficulty = 10
+	require.Equal(t, big.NewInt(1024), block.GetWork())
+}
+
+func TestBlock_Verify(t *testing.T) {
+	block, err := NewBlock(context.Background(), fakeData{}, WithDifficulty(4))
+	require.NoError(t, err)
+
+	err = block.Verify()
+	require.NoError(t, err)
+
+	block.hash = make([]byte, 32)
+	block.hash[0] = 0xff
+	err = block.Verify()
+	require.EqualError(t, err, "hash 0xff"+
+		"00000000000000000000000000000000000000000000000000000000000000 "+
+		"does not match difficulty 4")
+}
+
+func TestBlock_Serialize(t *testing.T) {
+	block := Block{}
+
+	data, err := block.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = block.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestBlock_Prepare(t *testing.T) {
+	block := &Block{
+		data: fakeData{},
+	}
+
+	ctx := context.Background()
+
+	err := block.prepare(ctx, crypto.NewSha256Factory(), 1)
+	require.NoError(t, err)
+	require.Len(t, block.hash, 32)
+
+	err = block.prepare(ctx, crypto.NewSha256Factory(), 0)
+	require.NoError(t, err)
+	require.Len(t, block.hash, 32)
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHash()), 0)
+	require.EqualError(t, err, fake.Err("failed to write index"))
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(1)), 0)
+	require.EqualError(t, err, fake.Err("failed to write previous"))
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(2)), 0)
+	require.EqualError(t, err, fake.Err("failed to write root"))
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(3)), 0)
+	require.EqualError(t, err, fake.Err("failed to write timestamp"))
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(4)), 0)
+	require.EqualError(t, err, fake.Err("failed to write difficulty"))
+
+	block.data = fakeData{err: fake.GetError()}
+	err = block.prepare(ctx, crypto.NewSha256Factory(), 0)
+	require.EqualError(t, err, fake.Err("failed to fingerprint data"))
+
+	block.data = fakeData{}
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(5)), 0)
+	require.EqualError(t, err, fake.Err("couldn't marshal digest"))
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(6)), 0)
+	require.EqualError(t, err, fake.Err("couldn't unmarshal digest"))
+
+	err = block.prepare(ctx, fake.NewHashFactory(fake.NewBadHashWithDelay(7)), 0)
+	require.EqualError(t, err, fake.Err("failed to write nonce"))
+}
+
+func TestBlockFactory_Deserialize(t *testing.T) {
+	fac := NewBlockFactory(nil)
+
+	msg, err := fac.Deserialize(fake.NewContext(), nil)
+	require.NoError(t, err)
+	require.Equal(t, Block{}, msg)
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("decoding block failed"))
+
+	_, err = fac.Deserialize(fake.NewContextWithFormat(serde.Format("BAD_TYPE")), nil)
+	require.EqualError(t, err, "invalid block 'fake.Message'")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type fakeData struct {
+	validation.Result
+	err error
+}
+
+func (d fakeData) GetTransactionResults() []validation.TransactionResult {
+	return nil
+}
+
+func (d fakeData) Fingerprint(io.Writer) error {
+	return d.err
+}
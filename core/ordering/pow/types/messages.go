@@ -0,0 +1,112 @@
+package types
+
+import (
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/registry"
+	"golang.org/x/xerrors"
+)
+
+var msgFormats = registry.NewSimpleRegistry()
+
+// RegisterMessageFormat registers the engine for the provided format.
+func RegisterMessageFormat(f serde.Format, e serde.FormatEngine) {
+	msgFormats.Register(f, e)
+}
+
+// BlockMessage is a message to announce a new block, or to reply to a request
+// for a block.
+//
+// - implements serde.Message
+type BlockMessage struct {
+	block Block
+}
+
+// NewBlockMessage creates a new block message.
+func NewBlockMessage(block Block) BlockMessage {
+	return BlockMessage{
+		block: block,
+	}
+}
+
+// GetBlock returns the block of the message.
+func (m BlockMessage) GetBlock() Block {
+	return m.block
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m BlockMessage) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// BlockRequest is a message to request a block by its hash, which is used to
+// download the missing ancestors of a block.
+//
+// - implements serde.Message
+type BlockRequest struct {
+	id []byte
+}
+
+// NewBlockRequest creates a new request for the block with the given hash.
+func NewBlockRequest(id []byte) BlockRequest {
+	return BlockRequest{
+		id: id,
+	}
+}
+
+// GetID returns the hash of the requested block.
+func (m BlockRequest) GetID() []byte {
+	return append([]byte{}, m.id...)
+}
+
+// Serialize implements serde.Message. It returns the serialized data for this
+// message.
+func (m BlockRequest) Serialize(ctx serde.Context) ([]byte, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, m)
+	if err != nil {
+		return nil, xerrors.Errorf("encoding failed: %v", err)
+	}
+
+	return data, nil
+}
+
+// BlockKey is the key of the block factory.
+type BlockKey struct{}
+
+// MessageFactory is a factory to deserialize the network messages.
+//
+// - implements serde.Factory
+type MessageFactory struct {
+	blockFac serde.Factory
+}
+
+// NewMessageFactory creates a new message factory.
+func NewMessageFactory(blockFac serde.Factory) MessageFactory {
+	return MessageFactory{
+		blockFac: blockFac,
+	}
+}
+
+// Deserialize implements serde.Factory. It returns the message associated to
+// the data if appropriate, otherwise an error.
+func (f MessageFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	format := msgFormats.Get(ctx.GetFormat())
+
+	ctx = serde.WithFactory(ctx, BlockKey{}, f.blockFac)
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("decoding failed: %v", err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func init() {
+	RegisterMessageFormat(fake.GoodFormat, fake.Format{Msg: BlockRequest{}})
+	RegisterMessageFormat(fake.BadFormat, fake.NewBadFormat())
+}
+
+func TestBlockMessage_GetBlock(t *testing.T) {
+	expected := Block{index: 1}
+	msg := NewBlockMessage(expected)
+
+	require.Equal(t, expected, msg.GetBlock())
+}
+
+func TestBlockMessage_Serialize(t *testing.T) {
+	msg := NewBlockMessage(Block{})
+
+	data, err := msg.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = msg.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestBlockRequest_GetID(t *testing.T) {
+	msg := NewBlockRequest([]byte{1, 2})
+
+	require.Equal(t, []byte{1, 2}, msg.GetID())
+}
+
+func TestBlockRequest_Serialize(t *testing.T) {
+	msg := NewBlockRequest(nil)
+
+	data, err := msg.Serialize(fake.NewContext())
+	require.NoError(t, err)
+	require.Equal(t, fake.GetFakeFormatValue(), data)
+
+	_, err = msg.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("encoding failed"))
+}
+
+func TestMessageFactory_Deserialize(t *testing.T) {
+	fac := NewMessageFactory(NewBlockFactory(nil))
+
+	msg, err := fac.Deserialize(fake.NewContext(), nil)
+	require.NoError(t, err)
+	require.Equal(t, BlockRequest{}, msg)
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("decoding failed"))
+}
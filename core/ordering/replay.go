@@ -0,0 +1,175 @@
+package ordering
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.dedis.ch/dela"
+	"go.dedis.ch/dela/core"
+	"golang.org/x/xerrors"
+)
+
+// ErrNoEvent is the error that an event reader returns, possibly wrapped, when
+// the block at the given index does not exist yet.
+var ErrNoEvent = errors.New("no event")
+
+// EventReader is the function called to read the event of the block at the
+// given index. It must return an error wrapping ErrNoEvent if the block does
+// not exist yet, and any other error if the event cannot be read.
+type EventReader func(index uint64) (Event, error)
+
+// Replay returns a channel populated with the events of the blocks from the
+// given index, read with the reader, followed by the events notified by the
+// watcher. Events are delivered in order without gaps nor duplicates, except
+// after a rollback event, which is passed through when it concerns blocks that
+// have already been sent so that the events start again from its index. The
+// replay ends when the reader reaches the latest block. The channel is closed
+// when the context is done, or earlier if an event cannot be read, in which
+// case the error is logged.
+func Replay(ctx context.Context, watcher core.Observable, index uint64, read EventReader) <-chan Event {
+	// The observer is added before reading the past events so that no new
+	// event can be missed in between.
+	obs := &queueObserver{signal: make(chan struct{}, 1)}
+	watcher.Add(obs)
+
+	ch := make(chan Event, 1)
+
+	go func() {
+		defer close(ch)
+		defer watcher.Remove(obs)
+
+		next := index
+
+		// Past events are sent until the reader reaches the latest block.
+		for {
+			evt, err := read(next)
+			if xerrors.Is(err, ErrNoEvent) {
+				break
+			}
+
+			if err != nil {
+				logReadErr(next, err)
+				return
+			}
+
+			if !send(ctx, ch, evt) {
+				return
+			}
+
+			next++
+		}
+
+		for {
+			evt, ok := obs.pop(ctx)
+			if !ok {
+				return
+			}
+
+			if evt.Rollback {
+				if evt.Index >= next {
+					// The blocks rolled back have not been sent yet.
+					continue
+				}
+
+				if !send(ctx, ch, evt) {
+					return
+				}
+
+				next = evt.Index
+
+				continue
+			}
+
+			if evt.Index < next {
+				// The event has already been sent while replaying.
+				continue
+			}
+
+			// The gap between the replay and the event is filled, in case the
+			// block was stored after the end of the replay but notified before
+			// the observer was added.
+			for ; next < evt.Index; next++ {
+				prev, err := read(next)
+				if err != nil {
+					logReadErr(next, err)
+					return
+				}
+
+				if !send(ctx, ch, prev) {
+					return
+				}
+			}
+
+			if !send(ctx, ch, evt) {
+				return
+			}
+
+			next = evt.Index + 1
+		}
+	}()
+
+	return ch
+}
+
+// logReadErr logs the error that interrupts a replay so that it is not mistaken
+// for the end of the events.
+func logReadErr(index uint64, err error) {
+	dela.Logger.Warn().
+		Uint64("index", index).
+		Err(err).
+		Msg("replay interrupted: failed to read event")
+}
+
+func send(ctx context.Context, ch chan Event, evt Event) bool {
+	select {
+	case ch <- evt:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// queueObserver is an observer that queues the events so that the notifier is
+// never blocked.
+//
+// - implements core.Observer
+type queueObserver struct {
+	sync.Mutex
+	queue  []Event
+	signal chan struct{}
+}
+
+// NotifyCallback implements core.Observer. It appends the event to the queue.
+func (o *queueObserver) NotifyCallback(event interface{}) {
+	o.Lock()
+	o.queue = append(o.queue, event.(Event))
+	o.Unlock()
+
+	select {
+	case o.signal <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for an event and removes it from the queue. It returns false if the
+// context is done before.
+func (o *queueObserver) pop(ctx context.Context) (Event, bool) {
+	for {
+		o.Lock()
+		if len(o.queue) > 0 {
+			evt := o.queue[0]
+			o.queue = o.queue[1:]
+			o.Unlock()
+
+			return evt, true
+		}
+		o.Unlock()
+
+		select {
+		case <-o.signal:
+		case <-ctx.Done():
+			return Event{}, false
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package ordering
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core"
+	"go.dedis.ch/dela/internal/testing/fake"
+	"golang.org/x/xerrors"
+)
+
+func TestReplay_Basic(t *testing.T) {
+	watcher := core.NewWatcher()
+	history := newFakeHistory(5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := Replay(ctx, watcher, 2, history.read)
+
+	waitEvent(t, ch, 2)
+	waitEvent(t, ch, 3)
+
+	// The live event of a block already replayed must be ignored.
+	history.add()
+	watcher.Notify(Event{Index: 4})
+	watcher.Notify(Event{Index: 5})
+
+	waitEvent(t, ch, 4)
+	waitEvent(t, ch, 5)
+
+	history.add()
+	history.add()
+	watcher.Notify(Event{Index: 6})
+	watcher.Notify(Event{Index: 6})
+	watcher.Notify(Event{Index: 7})
+
+	waitEvent(t, ch, 6)
+	waitEvent(t, ch, 7)
+
+	cancel()
+
+	_, more := <-ch
+	require.False(t, more)
+}
+
+func TestReplay_FillGap(t *testing.T) {
+	watcher := core.NewWatcher()
+	history := newFakeHistory(2)
+
+	ch := Replay(context.Background(), watcher, 0, history.read)
+
+	waitEvent(t, ch, 0)
+	waitEvent(t, ch, 1)
+
+	history.add()
+	history.add()
+	watcher.Notify(Event{Index: 4})
+
+	waitEvent(t, ch, 2)
+	waitEvent(t, ch, 3)
+	waitEvent(t, ch, 4)
+
+	// The gap cannot be filled so the channel is closed.
+	history.Lock()
+	history.bad = 5
+	history.Unlock()
+	watcher.Notify(Event{Index: 10})
+
+	_, more := <-ch
+	require.False(t, more)
+}
+
+func TestReplay_ReadFailure(t *testing.T) {
+	watcher := core.NewWatcher()
+	history := newFakeHistory(5)
+	history.bad = 2
+
+	ch := Replay(context.Background(), watcher, 0, history.read)
+
+	waitEvent(t, ch, 0)
+	waitEvent(t, ch, 1)
+
+	// The replay does not continue with the new events as if it had reached
+	// the latest block.
+	watcher.Notify(Event{Index: 5})
+
+	_, more := <-ch
+	require.False(t, more)
+}
+
+func TestReplay_FutureIndex(t *testing.T) {
+	watcher := core.NewWatcher()
+	history := newFakeHistory(2)
+
+	ch := Replay(context.Background(), watcher, 3, history.read)
+
+	history.add()
+	watcher.Notify(Event{Index: 2})
+
+	history.add()
+	watcher.Notify(Event{Index: 3})
+
+	waitEvent(t, ch, 3)
+}
+
+func TestReplay_Rollback(t *testing.T) {
+	watcher := core.NewWatcher()
+	history := newFakeHistory(4)
+
+	ch := Replay(context.Background(), watcher, 1, history.read)
+
+	waitEvent(t, ch, 1)
+	waitEvent(t, ch, 2)
+	waitEvent(t, ch, 3)
+
+	// A rollback of blocks that have not been sent yet is ignored.
+	watcher.Notify(Event{Index: 5, Rollback: true})
+
+	// The blocks 2 and 3 leave the chain so the events start again from 2.
+	watcher.Notify(Event{Index: 2, Rollback: true})
+	watcher.Notify(Event{Index: 2})
+	watcher.Notify(Event{Index: 3})
+
+	select {
+	case evt := <-ch:
+		require.True(t, evt.Rollback)
+		require.Equal(t, uint64(2), evt.Index)
+	case <-time.After(time.Second):
+		t.Fatal("rollback not received")
+	}
+
+	waitEvent(t, ch, 2)
+	waitEvent(t, ch, 3)
+}
+
+func TestReplay_ContextDone(t *testing.T) {
+	watcher := core.NewWatcher()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ch := Replay(ctx, watcher, 0, newFakeHistory(5).read)
+
+	// Some events might be sent before the context is checked but the channel
+	// is eventually closed.
+	for range ch {
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func waitEvent(t *testing.T, ch <-chan Event, index uint64) {
+	select {
+	case evt, ok := <-ch:
+		require.True(t, ok)
+		require.Equal(t, index, evt.Index)
+	case <-time.After(time.Second):
+		t.Fatalf("event %d not received", index)
+	}
+}
+
+type fakeHistory struct {
+	sync.Mutex
+	len uint64
+	bad uint64
+}
+
+func newFakeHistory(len uint64) *fakeHistory {
+	return &fakeHistory{len: len, bad: math.MaxUint64}
+}
+
+func (h *fakeHistory) add() {
+	h.Lock()
+	h.len++
+	h.Unlock()
+}
+
+func (h *fakeHistory) read(index uint64) (Event, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	if index == h.bad {
+		return Event{}, fake.GetError()
+	}
+
+	if index >= h.len {
+		return Event{}, xerrors.Errorf("index %d: %w", index, ErrNoEvent)
+	}
+
+	return Event{Index: index}, nil
+}
@@ -1,6 +1,7 @@
 package binprefix
 
 import (
+	"encoding/json"
 	"math/big"
 
 	"go.dedis.ch/dela/serde"
@@ -36,6 +37,89 @@ type NodeJSON struct {
 	Empty    *EmptyNodeJSON    `json:",omitempty"`
 }
 
+// PathLeafJSON is the JSON representation of the leaf of another key at the
+// end of a path.
+type PathLeafJSON struct {
+	Key   []byte
+	Value []byte
+}
+
+// PathJSON is the JSON representation of a path. The root is not part of it as
+// it is calculated from the other fields.
+type PathJSON struct {
+	Nonce     []byte
+	Key       []byte
+	Value     []byte
+	Interiors [][]byte
+	Leaf      *PathLeafJSON `json:",omitempty"`
+}
+
+// SuffixLeafJSON is the JSON representation of a leaf of a suffix proof.
+type SuffixLeafJSON struct {
+	Key   []byte
+	Value []byte
+}
+
+// SuffixNodeJSON is the JSON representation of a node of the subtree of a
+// suffix proof. A node that is neither an interior node nor a leaf is an empty
+// node.
+type SuffixNodeJSON struct {
+	Interior bool            `json:",omitempty"`
+	Leaf     *SuffixLeafJSON `json:",omitempty"`
+}
+
+// SuffixProofJSON is the JSON representation of a suffix proof. The root is
+// not part of it as it is calculated from the other fields.
+type SuffixProofJSON struct {
+	Nonce     []byte
+	Suffix    []byte
+	Bits      uint16
+	Interiors [][]byte
+	Nodes     []SuffixNodeJSON
+}
+
+// PrefixProofJSON is the JSON representation of a prefix proof. The position of
+// the subtree and the root are not part of it as they are calculated from the
+// other fields.
+type PrefixProofJSON struct {
+	Nonce     []byte
+	Prefix    []byte
+	Interiors [][]byte
+	Nodes     []SuffixNodeJSON
+}
+
+// MultiPathLeafJSON is the JSON representation of a leaf of a multi-key proof.
+type MultiPathLeafJSON struct {
+	Key   []byte
+	Value []byte
+}
+
+// MultiPathNodeJSON is the JSON representation of a node of a multi-key proof.
+// A pruned subtree only has its digest, and a node that has none of the fields
+// is an empty node.
+type MultiPathNodeJSON struct {
+	Interior bool               `json:",omitempty"`
+	Leaf     *MultiPathLeafJSON `json:",omitempty"`
+	Digest   []byte             `json:",omitempty"`
+}
+
+// MultiPathJSON is the JSON representation of a multi-key proof. The root and
+// the values are not part of it as they are calculated from the nodes.
+type MultiPathJSON struct {
+	Nonce []byte
+	Keys  [][]byte
+	Nodes []MultiPathNodeJSON
+}
+
+// RecordJSON is the JSON representation of a node retained in the history of
+// the tree. Interior nodes also hold the digests of their children.
+type RecordJSON struct {
+	Count uint64
+	Node  json.RawMessage
+	Left  []byte `json:",omitempty"`
+	Right []byte `json:",omitempty"`
+}
+
 type nodeFormat struct{}
 
 func (f nodeFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
@@ -121,3 +205,268 @@ func (f nodeFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error
 
 	return nil, xerrors.New("message is empty")
 }
+
+type pathFormat struct{}
+
+func (f pathFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	path, ok := msg.(Path)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	m := PathJSON{
+		Nonce:     path.nonce,
+		Key:       path.key,
+		Value:     path.value,
+		Interiors: path.interiors,
+	}
+
+	if path.leafKey != nil {
+		m.Leaf = &PathLeafJSON{
+			Key:   path.leafKey,
+			Value: path.leafValue,
+		}
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+func (f pathFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := PathJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	path := Path{
+		nonce:     m.Nonce,
+		key:       m.Key,
+		value:     m.Value,
+		interiors: m.Interiors,
+	}
+
+	if m.Leaf != nil {
+		// The key of the other leaf is never nil so that it is distinguished
+		// from a path without one.
+		path.leafKey = append([]byte{}, m.Leaf.Key...)
+		path.leafValue = m.Leaf.Value
+	}
+
+	return path, nil
+}
+
+type suffixFormat struct{}
+
+func (f suffixFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	proof, ok := msg.(SuffixProof)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	m := SuffixProofJSON{
+		Nonce:     proof.nonce,
+		Suffix:    proof.suffix,
+		Bits:      proof.bits,
+		Interiors: proof.interiors,
+		Nodes:     encodeSuffixNodes(proof.nodes),
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+func (f suffixFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := SuffixProofJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	nodes, err := decodeSuffixNodes(m.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := SuffixProof{
+		nonce:     m.Nonce,
+		suffix:    m.Suffix,
+		bits:      m.Bits,
+		interiors: m.Interiors,
+		nodes:     nodes,
+	}
+
+	return proof, nil
+}
+
+type prefixFormat struct{}
+
+func (f prefixFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	proof, ok := msg.(PrefixProof)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	m := PrefixProofJSON{
+		Nonce:     proof.suffix.nonce,
+		Prefix:    proof.prefix,
+		Interiors: proof.suffix.interiors,
+		Nodes:     encodeSuffixNodes(proof.suffix.nodes),
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+func (f prefixFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := PrefixProofJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	nodes, err := decodeSuffixNodes(m.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := newPrefixProof(m.Nonce, m.Prefix)
+	proof.suffix.interiors = m.Interiors
+	proof.suffix.nodes = nodes
+
+	return proof, nil
+}
+
+func encodeSuffixNodes(nodes []suffixNode) []SuffixNodeJSON {
+	res := make([]SuffixNodeJSON, len(nodes))
+	for i, node := range nodes {
+		switch node.typ {
+		case interiorNodeType:
+			res[i].Interior = true
+		case leafNodeType:
+			res[i].Leaf = &SuffixLeafJSON{
+				Key:   node.key,
+				Value: node.value,
+			}
+		}
+	}
+
+	return res
+}
+
+func decodeSuffixNodes(nodes []SuffixNodeJSON) ([]suffixNode, error) {
+	res := make([]suffixNode, len(nodes))
+	for i, node := range nodes {
+		switch {
+		case node.Interior && node.Leaf != nil:
+			return nil, xerrors.Errorf("node %d is both interior and leaf", i)
+		case node.Interior:
+			res[i].typ = interiorNodeType
+		case node.Leaf != nil:
+			res[i] = suffixNode{
+				typ:   leafNodeType,
+				key:   node.Leaf.Key,
+				value: node.Leaf.Value,
+			}
+		default:
+			res[i].typ = emptyNodeType
+		}
+	}
+
+	return res, nil
+}
+
+type multiPathFormat struct{}
+
+func (f multiPathFormat) Encode(ctx serde.Context, msg serde.Message) ([]byte, error) {
+	proof, ok := msg.(MultiPath)
+	if !ok {
+		return nil, xerrors.Errorf("unsupported message '%T'", msg)
+	}
+
+	nodes := make([]MultiPathNodeJSON, len(proof.nodes))
+	for i, node := range proof.nodes {
+		switch node.typ {
+		case interiorNodeType:
+			nodes[i].Interior = true
+		case leafNodeType:
+			nodes[i].Leaf = &MultiPathLeafJSON{
+				Key:   node.key,
+				Value: node.value,
+			}
+		case diskNodeType:
+			nodes[i].Digest = node.hash
+		}
+	}
+
+	m := MultiPathJSON{
+		Nonce: proof.nonce,
+		Keys:  proof.keys,
+		Nodes: nodes,
+	}
+
+	data, err := ctx.Marshal(m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return data, nil
+}
+
+func (f multiPathFormat) Decode(ctx serde.Context, data []byte) (serde.Message, error) {
+	m := MultiPathJSON{}
+	err := ctx.Unmarshal(data, &m)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	nodes := make([]pathNode, len(m.Nodes))
+	for i, node := range m.Nodes {
+		count := 0
+		if node.Interior {
+			nodes[i].typ = interiorNodeType
+			count++
+		}
+
+		if node.Leaf != nil {
+			nodes[i] = pathNode{
+				typ:   leafNodeType,
+				key:   node.Leaf.Key,
+				value: node.Leaf.Value,
+			}
+			count++
+		}
+
+		if node.Digest != nil {
+			nodes[i] = pathNode{
+				typ:  diskNodeType,
+				hash: node.Digest,
+			}
+			count++
+		}
+
+		if count > 1 {
+			return nil, xerrors.Errorf("node %d has more than one type", i)
+		}
+	}
+
+	proof := MultiPath{
+		nonce: m.Nonce,
+		keys:  m.Keys,
+		nodes: nodes,
+	}
+
+	return proof, nil
+}
@@ -0,0 +1,365 @@
+// This file contains the implementation of the history of a Merkle tree, which
+// retains the previous versions of the tree so that they can still be read
+// after new modifications have been committed.
+//
+// Every node of a retained version is stored in a bucket indexed by its digest
+// alongside a reference counter, so that the versions share the nodes that did
+// not change from one to another. When a version expires, its nodes are
+// released and the ones that are not referenced anymore are deleted.
+
+package binprefix
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"go.dedis.ch/dela/serde/json"
+	"golang.org/x/xerrors"
+)
+
+var (
+	historyNodePrefix = []byte("n")
+	historyRootPrefix = []byte("r")
+)
+
+// TreeOption is the type of options to create a Merkle tree.
+type TreeOption func(*MerkleTree)
+
+// WithHistory is an option to retain the given number of latest versions of
+// the tree, a version being created at each commit. Older versions are garbage
+// collected. A retention of zero disables the history.
+func WithHistory(retention uint) TreeOption {
+	return func(t *MerkleTree) {
+		if retention == 0 {
+			t.history = nil
+			return
+		}
+
+		t.history = &history{
+			bucket:    append(append([]byte{}, t.bucket...), "-history"...),
+			retention: uint64(retention),
+			context:   json.NewContext(),
+		}
+	}
+}
+
+// history is the configuration of the retention of the versions of a tree.
+type history struct {
+	bucket    []byte
+	retention uint64
+	context   serde.Context
+}
+
+// push stores the nodes of the tree that are not yet known and creates a new
+// version. The versions that are out of the retention window are released.
+func (h *history) push(tx kv.WritableTx, tree *Tree, b kv.Bucket, fac crypto.HashFactory) error {
+	hb, err := tx.GetBucketOrCreate(h.bucket)
+	if err != nil {
+		return xerrors.Errorf("read bucket failed: %v", err)
+	}
+
+	// The root might not be calculated if the tree has not been staged.
+	err = tree.CalculateRoot(fac, b)
+	if err != nil {
+		return xerrors.Errorf("couldn't update tree: %v", err)
+	}
+
+	root, err := h.retain(tree.root, new(big.Int), b, hb)
+	if err != nil {
+		return xerrors.Errorf("failed to store nodes: %v", err)
+	}
+
+	version := uint64(0)
+	expired := [][]byte{}
+
+	err = hb.Scan(historyRootPrefix, func(key, _ []byte) error {
+		version = binary.BigEndian.Uint64(key[len(historyRootPrefix):]) + 1
+		expired = append(expired, append([]byte{}, key...))
+
+		return nil
+	})
+	if err != nil {
+		return xerrors.Errorf("failed to scan versions: %v", err)
+	}
+
+	err = hb.Set(h.makeRootKey(version), root)
+	if err != nil {
+		return xerrors.Errorf("failed to store version: %v", err)
+	}
+
+	// Only the versions before the retention window are released, knowing
+	// that the new one is part of it.
+	num := uint64(len(expired)) + 1
+	if num <= h.retention {
+		return nil
+	}
+
+	for _, key := range expired[:num-h.retention] {
+		err = h.release(hb, hb.Get(key))
+		if err != nil {
+			return xerrors.Errorf("failed to release version: %v", err)
+		}
+
+		err = hb.Delete(key)
+		if err != nil {
+			return xerrors.Errorf("failed to delete version: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// retain stores the node and its subtree if they are not yet known, otherwise
+// it increases the reference counter of the node. It returns the digest of the
+// node.
+func (h *history) retain(node TreeNode, prefix *big.Int, b, hb kv.Bucket) ([]byte, error) {
+	disk, ok := node.(*DiskNode)
+	if ok && len(disk.hash) > 0 && len(hb.Get(h.makeNodeKey(disk.hash))) > 0 {
+		// Fast path to avoid loading a node that is already known.
+		return disk.hash, h.reference(hb, disk.hash)
+	}
+
+	if ok {
+		var err error
+		node, err = disk.load(prefix, b)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to load node: %v", err)
+		}
+	}
+
+	hash := node.GetHash()
+
+	if len(hb.Get(h.makeNodeKey(hash))) > 0 {
+		return hash, h.reference(hb, hash)
+	}
+
+	record := RecordJSON{Count: 1}
+
+	interior, ok := node.(*InteriorNode)
+	if ok {
+		var err error
+		depth := int(interior.depth)
+
+		record.Left, err = h.retain(interior.left,
+			new(big.Int).SetBit(prefix, depth, 0), b, hb)
+		if err != nil {
+			// No wrapping to prevent long error message from recursive calls.
+			return nil, err
+		}
+
+		record.Right, err = h.retain(interior.right,
+			new(big.Int).SetBit(prefix, depth, 1), b, hb)
+		if err != nil {
+			// No wrapping to prevent long error message from recursive calls.
+			return nil, err
+		}
+	}
+
+	var err error
+	record.Node, err = node.Serialize(h.context)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to serialize node: %v", err)
+	}
+
+	err = h.write(hb, hash, record)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to write node: %v", err)
+	}
+
+	return hash, nil
+}
+
+// reference increases the reference counter of a known node.
+func (h *history) reference(hb kv.Bucket, hash []byte) error {
+	record, err := h.read(hb, hash)
+	if err != nil {
+		return xerrors.Errorf("failed to read node: %v", err)
+	}
+
+	record.Count++
+
+	return h.write(hb, hash, record)
+}
+
+// release decreases the reference counter of the node, and deletes it when it
+// reaches zero, in which case the children are also released.
+func (h *history) release(hb kv.Bucket, hash []byte) error {
+	record, err := h.read(hb, hash)
+	if err != nil {
+		return xerrors.Errorf("failed to read node: %v", err)
+	}
+
+	if record.Count > 1 {
+		record.Count--
+
+		return h.write(hb, hash, record)
+	}
+
+	err = hb.Delete(h.makeNodeKey(hash))
+	if err != nil {
+		return xerrors.Errorf("failed to delete node: %v", err)
+	}
+
+	if record.Left == nil {
+		return nil
+	}
+
+	err = h.release(hb, record.Left)
+	if err != nil {
+		// No wrapping to prevent long error message from recursive calls.
+		return err
+	}
+
+	return h.release(hb, record.Right)
+}
+
+func (h *history) read(hb kv.Bucket, hash []byte) (RecordJSON, error) {
+	var record RecordJSON
+
+	data := hb.Get(h.makeNodeKey(hash))
+	if len(data) == 0 {
+		return record, xerrors.Errorf("node %#x not found", hash)
+	}
+
+	err := h.context.Unmarshal(data, &record)
+	if err != nil {
+		return record, xerrors.Errorf("failed to unmarshal: %v", err)
+	}
+
+	return record, nil
+}
+
+func (h *history) write(hb kv.Bucket, hash []byte, record RecordJSON) error {
+	data, err := h.context.Marshal(record)
+	if err != nil {
+		return xerrors.Errorf("failed to marshal: %v", err)
+	}
+
+	return hb.Set(h.makeNodeKey(hash), data)
+}
+
+func (h *history) makeNodeKey(hash []byte) []byte {
+	return append(append([]byte{}, historyNodePrefix...), hash...)
+}
+
+func (h *history) makeRootKey(version uint64) []byte {
+	key := make([]byte, len(historyRootPrefix)+8)
+	copy(key, historyRootPrefix)
+	binary.BigEndian.PutUint64(key[len(historyRootPrefix):], version)
+
+	return key
+}
+
+// versionTree is a read-only tree of a version retained in the history.
+//
+// - implements hashtree.Tree
+type versionTree struct {
+	db       kv.DB
+	history  *history
+	nonce    Nonce
+	root     []byte
+	maxDepth int
+	factory  serde.Factory
+}
+
+// Get implements store.Readable. It returns the value associated with the key
+// in this version of the tree if it exists, otherwise it returns nil.
+func (t versionTree) Get(key []byte) ([]byte, error) {
+	value, err := t.search(key, nil)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't search key: %v", err)
+	}
+
+	return value, nil
+}
+
+// GetRoot implements hashtree.Tree. It returns the root of the version.
+func (t versionTree) GetRoot() []byte {
+	return append([]byte{}, t.root...)
+}
+
+// GetPath implements hashtree.Tree. It returns the path to the key in this
+// version of the tree.
+func (t versionTree) GetPath(key []byte) (hashtree.Path, error) {
+	path := newPath(t.nonce[:], key)
+
+	_, err := t.search(key, &path)
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't search key: %v", err)
+	}
+
+	return path, nil
+}
+
+// Stage implements hashtree.Tree. It always returns an error as a previous
+// version cannot be modified.
+func (t versionTree) Stage(func(store.Snapshot) error) (hashtree.StagingTree, error) {
+	return nil, xerrors.New("version is read-only")
+}
+
+func (t versionTree) search(key []byte, path *Path) ([]byte, error) {
+	if len(key) > t.maxDepth {
+		return nil, xerrors.Errorf("mismatch key length %d > %d", len(key), t.maxDepth)
+	}
+
+	index := makeKey(key)
+
+	var value []byte
+
+	err := t.db.View(func(tx kv.ReadableTx) error {
+		hb := tx.GetBucket(t.history.bucket)
+		if hb == nil {
+			return xerrors.New("history is empty")
+		}
+
+		hash := t.root
+
+		for {
+			record, err := t.history.read(hb, hash)
+			if err != nil {
+				return xerrors.Errorf("failed to read node: %v", err)
+			}
+
+			msg, err := t.factory.Deserialize(t.history.context, record.Node)
+			if err != nil {
+				return xerrors.Errorf("failed to deserialize node: %v", err)
+			}
+
+			switch node := msg.(type) {
+			case *InteriorNode:
+				next, sibling := record.Left, record.Right
+				if index.Bit(int(node.depth)) == 1 {
+					next, sibling = record.Right, record.Left
+				}
+
+				if path != nil {
+					path.interiors = append(path.interiors, sibling)
+				}
+
+				hash = next
+			case *LeafNode:
+				value, err = node.Search(index, path, nil)
+				return err
+			case *EmptyNode:
+				return nil
+			default:
+				return xerrors.Errorf("invalid node '%T'", msg)
+			}
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if path != nil {
+		path.root = t.GetRoot()
+	}
+
+	return value, nil
+}
@@ -0,0 +1,177 @@
+package binprefix
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/core/store/kv"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestHistory_IntegrationTest(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	var tree = NewMerkleTree(db, Nonce{1}, WithHistory(3))
+	// A low memory depth forces the interior nodes to be stored on the disk so
+	// that they need to be loaded when retaining a version.
+	tree.tree.memDepth = 2
+
+	roots := make([][]byte, 6)
+	values := make([]map[string][]byte, 6)
+
+	for i := range roots {
+		next, err := tree.Stage(func(snap store.Snapshot) error {
+			for j := 0; j < 20; j++ {
+				key := []byte(fmt.Sprintf("key%d", (i*7+j)%30))
+
+				err := snap.Set(key, []byte(fmt.Sprintf("value%d:%d", i, j)))
+				require.NoError(t, err)
+			}
+
+			return snap.Delete([]byte(fmt.Sprintf("key%d", i)))
+		})
+		require.NoError(t, err)
+
+		tree = next.(*MerkleTree)
+		require.NoError(t, tree.Commit())
+
+		roots[i] = tree.GetRoot()
+		values[i] = make(map[string][]byte)
+
+		for k := 0; k < 30; k++ {
+			key := fmt.Sprintf("key%d", k)
+
+			values[i][key], err = tree.Get([]byte(key))
+			require.NoError(t, err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := tree.GetVersion(roots[i])
+		require.EqualError(t, err, fmt.Sprintf("version %#x is not retained", roots[i]))
+	}
+
+	for i := 3; i < 6; i++ {
+		version, err := tree.GetVersion(roots[i])
+		require.NoError(t, err)
+		require.Equal(t, roots[i], version.GetRoot())
+
+		for key, value := range values[i] {
+			path, err := version.GetPath([]byte(key))
+			require.NoError(t, err)
+			require.Equal(t, roots[i], path.GetRoot())
+
+			root, err := path.(Path).computeRoot(tree.hashFactory)
+			require.NoError(t, err)
+			require.Equal(t, roots[i], root)
+
+			res, err := version.Get([]byte(key))
+			require.NoError(t, err)
+			require.Equal(t, value, res)
+		}
+	}
+
+	// Retaining a single version must collect every node of the previous ones
+	// that are not part of the latest tree.
+	tree.history.retention = 1
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for k := 0; k < 30; k++ {
+			err := snap.Delete([]byte(fmt.Sprintf("key%d", k)))
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	err = db.View(func(tx kv.ReadableTx) error {
+		count := 0
+		err := tx.GetBucket(tree.history.bucket).ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		})
+		require.NoError(t, err)
+
+		// The empty root and the single version.
+		require.Equal(t, 2, count)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWithHistory(t *testing.T) {
+	tree := NewMerkleTree(fakeDB{}, Nonce{}, WithHistory(5))
+	require.NotNil(t, tree.history)
+	require.Equal(t, uint64(5), tree.history.retention)
+	require.Equal(t, []byte("hashtree-history"), tree.history.bucket)
+
+	tree = NewMerkleTree(fakeDB{}, Nonce{}, WithHistory(5), WithHistory(0))
+	require.Nil(t, tree.history)
+}
+
+func TestHistory_Push(t *testing.T) {
+	tree := NewMerkleTree(fakeDB{}, Nonce{}, WithHistory(1))
+
+	err := tree.history.push(badTx{}, tree.tree, nil, tree.hashFactory)
+	require.EqualError(t, err, fake.Err("read bucket failed"))
+
+	tx := fakeTx{bucket: &fakeBucket{}}
+
+	tree.hashFactory = fake.NewHashFactory(fake.NewBadHash())
+	err = tree.history.push(tx, tree.tree, nil, tree.hashFactory)
+	require.EqualError(t, err,
+		fake.Err("couldn't update tree: failed to prepare: empty node failed"))
+
+	tree.tree.root = NewDiskNode(0, []byte{1}, tree.tree.context, tree.tree.factory)
+	err = tree.history.push(tx, tree.tree, &fakeBucket{}, tree.hashFactory)
+	require.EqualError(t, err,
+		"failed to store nodes: failed to load node: prefix 0 (depth 0) not in database")
+
+	tree.tree.root = NewEmptyNodeWithDigest(0, big.NewInt(0), []byte{1})
+	tx.bucket = badScanBucket{fakeBucket: &fakeBucket{}}
+	err = tree.history.push(tx, tree.tree, nil, tree.hashFactory)
+	require.EqualError(t, err, fake.Err("failed to scan versions"))
+}
+
+func TestVersionTree_Get(t *testing.T) {
+	tree := versionTree{db: fakeDB{}, maxDepth: MaxDepth}
+
+	_, err := tree.Get(make([]byte, MaxDepth+1))
+	require.EqualError(t, err, "couldn't search key: mismatch key length 33 > 32")
+
+	tree.history = &history{bucket: []byte("history")}
+	_, err = tree.Get([]byte("A"))
+	require.EqualError(t, err, "couldn't search key: history is empty")
+}
+
+func TestVersionTree_GetPath(t *testing.T) {
+	tree := versionTree{db: fakeDB{}, maxDepth: MaxDepth}
+
+	_, err := tree.GetPath(make([]byte, MaxDepth+1))
+	require.EqualError(t, err, "couldn't search key: mismatch key length 33 > 32")
+}
+
+func TestVersionTree_Stage(t *testing.T) {
+	tree := versionTree{}
+
+	_, err := tree.Stage(nil)
+	require.EqualError(t, err, "version is read-only")
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type badScanBucket struct {
+	*fakeBucket
+}
+
+func (b badScanBucket) Scan([]byte, func(k, v []byte) error) error {
+	return fake.GetError()
+}
@@ -0,0 +1,240 @@
+// This file contains the ordered index of the keys of the tree.
+//
+// The tree branches on the least significant bit of the keys first, so that the
+// keys with the same byte prefix are spread over the whole tree. When the index
+// is enabled, the tree holds a second leaf for every key, at a position derived
+// from the key so that the leaves are sorted in the lexicographic order of the
+// keys, and that the keys with the same prefix share a subtree. A range of keys
+// is then read from the subtrees between its bounds only, and the subtree of a
+// prefix is proven complete like a suffix.
+
+package binprefix
+
+import (
+	"bytes"
+	"math/big"
+
+	"go.dedis.ch/dela/core/store/kv"
+	"golang.org/x/xerrors"
+)
+
+const (
+	// indexTag is the value of the first bits of the entries of the index, so
+	// that the subtrees of the index rarely contain regular keys.
+	indexTag     = 0xd1e5
+	indexTagBits = 16
+
+	// indexMarker is the position of the bit that is set in every entry of the
+	// index. It is above the bits of the regular keys so that an entry can
+	// never be mistaken for one of them.
+	indexMarker = (MaxDepth + 8) * 8
+
+	// maxNodeDepth is the deepest a node can be, when two keys differ only by
+	// the marker of the index.
+	maxNodeDepth = indexMarker + 1
+)
+
+// WithOrderedIndex is an option to maintain the ordered index of the keys, so
+// that the ranges of keys are read in order and that the prefixes can be proven
+// complete. It doubles the number of leaves and changes the root, therefore it
+// must be set from the creation of the tree by every member of a chain.
+func WithOrderedIndex() TreeOption {
+	return func(t *MerkleTree) {
+		t.tree.indexed = true
+	}
+}
+
+// ForRange calls the function with the key and the value of every leaf of the
+// tree whose key is between start, included, and end, excluded, in the
+// lexicographic order of the keys. A nil end means there is no upper bound. It
+// walks the ordered index and skips the subtrees that are outside of the
+// bounds. Disk nodes are loaded from the bucket but the tree is not modified.
+func (t *Tree) ForRange(start, end []byte, fn func(key, value []byte) error, b kv.Bucket) error {
+	if !t.indexed {
+		return xerrors.New("ordered index is disabled")
+	}
+
+	r := indexRange{
+		start: start,
+		end:   end,
+		lower: indexKey(start),
+	}
+
+	if end != nil {
+		r.upper = indexKey(end)
+	}
+
+	return r.walk(t.root, new(big.Int), b, fn)
+}
+
+// GetPrefix fills the prefix proof with the interior nodes from the root to the
+// subtree of the prefix in the ordered index, and with every node of the
+// subtree.
+func (t *Tree) GetPrefix(proof *PrefixProof, b kv.Bucket) error {
+	if !t.indexed {
+		return xerrors.New("ordered index is disabled")
+	}
+
+	if len(proof.prefix) > t.maxDepth {
+		return xerrors.Errorf("mismatch prefix length %d > %d", len(proof.prefix), t.maxDepth)
+	}
+
+	key, bits := indexPrefix(proof.prefix)
+
+	node, curr, err := t.descend(key, bits, maxNodeDepth, &proof.suffix, b)
+	if err != nil {
+		return xerrors.Errorf("failed to descend: %v", err)
+	}
+
+	proof.suffix.nodes, err = appendSuffix(proof.suffix.nodes, node, curr, b)
+	if err != nil {
+		return xerrors.Errorf("failed to read subtree: %v", err)
+	}
+
+	proof.suffix.root = t.root.GetHash()
+
+	return nil
+}
+
+// indexRange is a range of keys of the ordered index. The bounds are also kept
+// as entries of the index to compare them with the prefixes of the nodes.
+type indexRange struct {
+	start []byte
+	end   []byte
+	lower *big.Int
+	upper *big.Int
+}
+
+// walk calls the function in order with the entries of the subtree that are
+// in the range. A subtree is skipped when its prefix is not the one of the
+// index, or when all its keys are before or after the range.
+func (r indexRange) walk(node TreeNode, prefix *big.Int, b kv.Bucket,
+	fn func(key, value []byte) error) error {
+
+	node, err := loadNode(node, prefix, b)
+	if err != nil {
+		return err
+	}
+
+	switch n := node.(type) {
+	case *InteriorNode:
+		depth := int(n.depth)
+
+		tag := depth
+		if tag > indexTagBits {
+			tag = indexTagBits
+		}
+
+		if compareBits(prefix, r.lower, tag) != 0 || compareBits(prefix, r.lower, depth) < 0 {
+			return nil
+		}
+
+		if r.upper != nil && compareBits(prefix, r.upper, depth) > 0 {
+			return nil
+		}
+
+		err := r.walk(n.left, new(big.Int).SetBit(prefix, depth, 0), b, fn)
+		if err != nil {
+			// No wrapping to prevent long error message from recursive calls.
+			return err
+		}
+
+		return r.walk(n.right, new(big.Int).SetBit(prefix, depth, 1), b, fn)
+	case *LeafNode:
+		key, ok := fromIndexKey(n.key)
+		if !ok || bytes.Compare(key, r.start) < 0 {
+			return nil
+		}
+
+		if r.end != nil && bytes.Compare(key, r.end) >= 0 {
+			return nil
+		}
+
+		return fn(key, n.GetValue())
+	}
+
+	return nil
+}
+
+// indexPrefix returns the first bits of the entries of the index of the keys
+// starting with the prefix, and the number of bits. Every byte is written from
+// its most significant bit, and preceded by a set bit.
+func indexPrefix(prefix []byte) (*big.Int, uint16) {
+	bits := new(big.Int).SetUint64(indexTag)
+	pos := indexTagBits
+
+	for _, b := range prefix {
+		bits.SetBit(bits, pos, 1)
+		pos++
+
+		for i := 7; i >= 0; i-- {
+			bits.SetBit(bits, pos, uint(b>>i)&1)
+			pos++
+		}
+	}
+
+	return bits, uint16(pos)
+}
+
+// indexKey returns the key of the entry of the index for the key. The bit that
+// follows the bytes of the key is unset so that a key comes before the longer
+// keys that it is a prefix of.
+func indexKey(key []byte) *big.Int {
+	bits, _ := indexPrefix(key)
+
+	return bits.SetBit(bits, indexMarker, 1)
+}
+
+// fromIndexKey returns the key of an entry of the index, or false if the key is
+// not an entry of the index.
+func fromIndexKey(index *big.Int) ([]byte, bool) {
+	if index.BitLen() != indexMarker+1 {
+		return nil, false
+	}
+
+	key := []byte{}
+	pos := indexTagBits
+
+	for pos+9 <= indexMarker && index.Bit(pos) == 1 {
+		var b byte
+		for i := 1; i <= 8; i++ {
+			b = b<<1 | byte(index.Bit(pos+i))
+		}
+
+		key = append(key, b)
+		pos += 9
+	}
+
+	// The bits after the key must be unset, and the tag must match.
+	if indexKey(key).Cmp(index) != 0 {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// isIndexKey returns true if the key is an entry of the ordered index, which
+// is the case of any key longer than the regular ones.
+func isIndexKey(key *big.Int) bool {
+	return key.BitLen() > MaxDepth*8
+}
+
+// compareBits compares the first bits of the keys in the order of the tree, and
+// returns -1, 0 or 1 if the first key is respectively before, equal or after
+// the second one.
+func compareBits(a, b *big.Int, bits int) int {
+	for i := 0; i < bits; i++ {
+		x := a.Bit(i)
+		y := b.Bit(i)
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,210 @@
+package binprefix
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestWithOrderedIndex(t *testing.T) {
+	tree := NewMerkleTree(fakeDB{}, Nonce{})
+	require.False(t, tree.tree.indexed)
+
+	tree = NewMerkleTree(fakeDB{}, Nonce{}, WithOrderedIndex())
+	require.True(t, tree.tree.indexed)
+	require.True(t, tree.tree.Clone().indexed)
+}
+
+func TestTree_ForRange(t *testing.T) {
+	bucket := &countBucket{fakeBucket: &fakeBucket{}}
+
+	tree := NewTree(Nonce{})
+	tree.indexed = true
+	tree.memDepth = 2
+
+	for i := 1; i <= 200; i++ {
+		err := tree.Insert([]byte{byte(i)}, []byte{byte(i)}, bucket)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 200, tree.Len())
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), bucket)
+	require.NoError(t, err)
+
+	err = tree.Persist(bucket)
+	require.NoError(t, err)
+
+	bucket.gets = 0
+
+	// The pairs are sorted even though the tree branches on the last bit.
+	var keys []byte
+	err = tree.ForRange([]byte{3}, []byte{8}, func(key, value []byte) error {
+		keys = append(keys, key[0])
+		return nil
+	}, bucket)
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 4, 5, 6, 7}, keys)
+
+	narrow := bucket.gets
+	bucket.gets = 0
+
+	keys = nil
+	err = tree.ForRange(nil, nil, func(key, value []byte) error {
+		keys = append(keys, key[0])
+		return nil
+	}, bucket)
+	require.NoError(t, err)
+	require.Len(t, keys, 200)
+
+	// Only the subtrees around the range are loaded.
+	require.Less(t, narrow*4, bucket.gets)
+
+	err = tree.ForRange(nil, nil, func(key, value []byte) error {
+		return fake.GetError()
+	}, bucket)
+	require.EqualError(t, err, fake.GetError().Error())
+
+	err = tree.ForRange(nil, nil, nil, nil)
+	require.EqualError(t, err, "bucket is nil")
+
+	tree.indexed = false
+	err = tree.ForRange(nil, nil, nil, bucket)
+	require.EqualError(t, err, "ordered index is disabled")
+}
+
+func TestTree_GetPrefix(t *testing.T) {
+	tree := NewTree(Nonce{})
+	tree.indexed = true
+
+	for _, key := range []string{"a", "ab", "abc", "b"} {
+		err := tree.Insert([]byte(key), []byte(key), &fakeBucket{})
+		require.NoError(t, err)
+	}
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{})
+	require.NoError(t, err)
+
+	proof := newPrefixProof(tree.nonce[:], []byte("ab"))
+
+	err = tree.GetPrefix(&proof, &fakeBucket{})
+	require.NoError(t, err)
+	require.Equal(t, tree.root.GetHash(), proof.GetRoot())
+
+	keys := []string{}
+	err = proof.ForEach(func(key, value []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ab", "abc"}, keys)
+
+	proof = newPrefixProof(tree.nonce[:], make([]byte, MaxDepth+1))
+	err = tree.GetPrefix(&proof, &fakeBucket{})
+	require.EqualError(t, err, "mismatch prefix length 33 > 32")
+
+	tree.root = NewDiskNode(0, nil, testCtx, NodeFactory{})
+	proof = newPrefixProof(tree.nonce[:], nil)
+	err = tree.GetPrefix(&proof, nil)
+	require.EqualError(t, err, "failed to descend: bucket is nil")
+
+	tree.indexed = false
+	err = tree.GetPrefix(&proof, &fakeBucket{})
+	require.EqualError(t, err, "ordered index is disabled")
+}
+
+func TestIndexKey(t *testing.T) {
+	key, ok := fromIndexKey(indexKey(nil))
+	require.True(t, ok)
+	require.Equal(t, []byte{}, key)
+
+	f := func(key []byte) bool {
+		if len(key) > MaxDepth {
+			key = key[:MaxDepth]
+		}
+
+		index := indexKey(key)
+		if !isIndexKey(index) {
+			return false
+		}
+
+		decoded, ok := fromIndexKey(index)
+
+		return ok && bytes.Equal(decoded, key)
+	}
+
+	err := quick.Check(f, nil)
+	require.NoError(t, err)
+
+	_, ok = fromIndexKey(big.NewInt(1))
+	require.False(t, ok)
+
+	_, ok = fromIndexKey(new(big.Int).SetBit(indexKey(nil), 0, 0))
+	require.False(t, ok)
+
+	require.False(t, isIndexKey(makeKey(make([]byte, MaxDepth))))
+}
+
+func TestIndexKey_Order(t *testing.T) {
+	keys := [][]byte{{}, {0}, {0, 0}, {0, 1}, {1}, {1, 0}, {0xff}, {0xff, 0xff}}
+
+	indices := make([]*big.Int, len(keys))
+	for i, key := range keys {
+		indices[i] = indexKey(key)
+	}
+
+	// The order of the tree compares the bits from the least significant one.
+	sort.Slice(indices, func(i, j int) bool {
+		return compareBits(indices[i], indices[j], maxNodeDepth) < 0
+	})
+
+	for i, index := range indices {
+		key, ok := fromIndexKey(index)
+		require.True(t, ok)
+		require.Equal(t, keys[i], key)
+	}
+}
+
+func TestIndexPrefix(t *testing.T) {
+	prefix, bits := indexPrefix([]byte{0xab})
+	require.Equal(t, uint16(indexTagBits+9), bits)
+
+	f := func(key []byte) bool {
+		if len(key) > MaxDepth {
+			key = key[:MaxDepth]
+		}
+
+		return compareBits(prefix, indexKey(key), int(bits)) == 0 ==
+			bytes.HasPrefix(key, []byte{0xab})
+	}
+
+	err := quick.Check(f, nil)
+	require.NoError(t, err)
+}
+
+func TestCompareBits(t *testing.T) {
+	require.Equal(t, 0, compareBits(big.NewInt(0b01), big.NewInt(0b11), 1))
+	require.Equal(t, -1, compareBits(big.NewInt(0b01), big.NewInt(0b11), 2))
+	require.Equal(t, 1, compareBits(big.NewInt(0b11), big.NewInt(0b01), 2))
+	require.Equal(t, 0, compareBits(big.NewInt(0b11), big.NewInt(0b01), 0))
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type countBucket struct {
+	*fakeBucket
+
+	gets int
+}
+
+func (b *countBucket) Get(key []byte) []byte {
+	b.gets++
+	return b.fakeBucket.Get(key)
+}
@@ -50,6 +50,10 @@ import (
 // Modifications on a staged tree are done in-memory.
 //
 // - implements hashtree.Tree
+// - implements hashtree.HistoricalTree
+// - implements hashtree.IterableTree
+// - implements hashtree.RangeTree
+// - implements hashtree.MultiPathTree
 type MerkleTree struct {
 	sync.Mutex
 
@@ -58,16 +62,23 @@ type MerkleTree struct {
 	tx          store.Transaction
 	bucket      []byte
 	hashFactory crypto.HashFactory
+	history     *history
 }
 
 // NewMerkleTree creates a new Merkle tree-based storage.
-func NewMerkleTree(db kv.DB, nonce Nonce) *MerkleTree {
-	return &MerkleTree{
+func NewMerkleTree(db kv.DB, nonce Nonce, opts ...TreeOption) *MerkleTree {
+	t := &MerkleTree{
 		tree:        NewTree(nonce),
 		db:          db,
 		bucket:      []byte("hashtree"),
 		hashFactory: crypto.NewSha256Factory(),
 	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
 }
 
 // Load tries to read the bucket and scan it for existing leafs and populate the
@@ -147,8 +158,158 @@ func (t *MerkleTree) GetPath(key []byte) (hashtree.Path, error) {
 	return path, nil
 }
 
+// GetPaths implements hashtree.MultiPathTree. It returns a single proof for all
+// the keys where the nodes shared by their paths appear only once.
+func (t *MerkleTree) GetPaths(keys ...[]byte) (hashtree.MultiPath, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	proof := newMultiPath(t.tree.nonce[:], keys)
+
+	err := t.doView(func(tx kv.ReadableTx) error {
+		return t.tree.GetPaths(&proof, tx.GetBucket(t.bucket))
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't search keys: %v", err)
+	}
+
+	return proof, nil
+}
+
+// ForEach implements hashtree.IterableTree. It calls the function with every
+// key/value pair of the tree, and stops at the first error.
+func (t *MerkleTree) ForEach(fn func(key, value []byte) error) error {
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.doView(func(tx kv.ReadableTx) error {
+		return t.tree.ForEach(fn, tx.GetBucket(t.bucket))
+	})
+
+	if err != nil {
+		return xerrors.Errorf("while visiting: %v", err)
+	}
+
+	return nil
+}
+
+// ForRange implements store.RangeReadable. It calls the function with every
+// key/value pair between start and end in the lexicographic order of the keys,
+// and stops at the first error. The ordered index must be enabled.
+func (t *MerkleTree) ForRange(start, end []byte, fn func(key, value []byte) error) error {
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.doView(func(tx kv.ReadableTx) error {
+		return t.tree.ForRange(start, end, fn, tx.GetBucket(t.bucket))
+	})
+
+	if err != nil {
+		return xerrors.Errorf("while visiting: %v", err)
+	}
+
+	return nil
+}
+
+// ForSuffix implements hashtree.RangeTree. It calls the function with every
+// key/value pair ending with the suffix in the order of the tree, and stops at
+// the first error.
+func (t *MerkleTree) ForSuffix(suffix []byte, bits uint16, fn func(key, value []byte) error) error {
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.doView(func(tx kv.ReadableTx) error {
+		return t.tree.ForSuffix(suffix, bits, fn, tx.GetBucket(t.bucket))
+	})
+
+	if err != nil {
+		return xerrors.Errorf("while visiting: %v", err)
+	}
+
+	return nil
+}
+
+// GetSuffix implements hashtree.RangeTree. It returns the key/value pairs
+// ending with the suffix with a proof that the list is complete.
+func (t *MerkleTree) GetSuffix(suffix []byte, bits uint16) (hashtree.SuffixProof, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	proof := newSuffixProof(t.tree.nonce[:], suffix, bits)
+
+	err := t.doView(func(tx kv.ReadableTx) error {
+		return t.tree.GetSuffix(&proof, tx.GetBucket(t.bucket))
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't read suffix: %v", err)
+	}
+
+	return proof, nil
+}
+
+// GetPrefix implements hashtree.RangeTree. It returns the key/value pairs
+// starting with the prefix with a proof that the list is complete. The ordered
+// index must be enabled.
+func (t *MerkleTree) GetPrefix(prefix []byte) (hashtree.PrefixProof, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	proof := newPrefixProof(t.tree.nonce[:], prefix)
+
+	err := t.doView(func(tx kv.ReadableTx) error {
+		return t.tree.GetPrefix(&proof, tx.GetBucket(t.bucket))
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("couldn't read prefix: %v", err)
+	}
+
+	return proof, nil
+}
+
+// GetVersion implements hashtree.HistoricalTree. It returns a read-only tree
+// of the version identified by the root if it is still retained, otherwise it
+// returns an error.
+func (t *MerkleTree) GetVersion(root []byte) (hashtree.Tree, error) {
+	if t.history == nil {
+		return nil, xerrors.New("history is disabled")
+	}
+
+	found := false
+
+	err := t.db.View(func(tx kv.ReadableTx) error {
+		bucket := tx.GetBucket(t.history.bucket)
+
+		found = bucket != nil && len(bucket.Get(t.history.makeNodeKey(root))) > 0
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, xerrors.Errorf("while reading database: %v", err)
+	}
+
+	if !found {
+		return nil, xerrors.Errorf("version %#x is not retained", root)
+	}
+
+	tree := versionTree{
+		db:       t.db,
+		history:  t.history,
+		nonce:    t.tree.nonce,
+		root:     append([]byte{}, root...),
+		maxDepth: t.tree.maxDepth,
+		factory:  t.tree.factory,
+	}
+
+	return tree, nil
+}
+
 // Stage implements hashtree.Tree. It executes the callback over a clone of the
-// current tree and return the clone with the root calculated.
+// current tree and return the clone with the root calculated. The clone shares
+// the nodes that the callback does not change.
 func (t *MerkleTree) Stage(fn func(store.Snapshot) error) (hashtree.StagingTree, error) {
 	clone := t.clone()
 
@@ -179,7 +340,8 @@ func (t *MerkleTree) Stage(fn func(store.Snapshot) error) (hashtree.StagingTree,
 }
 
 // Commit implements hashtree.StagingTree. It writes the leaf nodes to the disk
-// and a trade-off of other nodes.
+// and a trade-off of other nodes. When the history is enabled, a new version of
+// the tree is also retained.
 func (t *MerkleTree) Commit() error {
 	t.Lock()
 	defer t.Unlock()
@@ -190,6 +352,13 @@ func (t *MerkleTree) Commit() error {
 			return xerrors.Errorf("read bucket failed: %v", err)
 		}
 
+		if t.history != nil {
+			err = t.history.push(tx, t.tree, bucket, t.hashFactory)
+			if err != nil {
+				return xerrors.Errorf("failed to update history: %v", err)
+			}
+		}
+
 		return t.tree.Persist(bucket)
 	})
 
@@ -210,6 +379,7 @@ func (t *MerkleTree) WithTx(tx store.Transaction) hashtree.StagingTree {
 		tx:          tx,
 		bucket:      t.bucket,
 		hashFactory: t.hashFactory,
+		history:     t.history,
 	}
 }
 
@@ -220,6 +390,7 @@ func (t *MerkleTree) clone() *MerkleTree {
 		tx:          t.tx,
 		bucket:      t.bucket,
 		hashFactory: t.hashFactory,
+		history:     t.history,
 	}
 }
 
@@ -253,6 +424,7 @@ func (t *MerkleTree) doView(fn func(kv.ReadableTx) error) error {
 // it can be written into but the tree is not updated at every operation.
 //
 // - implements store.Writable
+// - implements store.RangeReadable
 type writableMerkleTree struct {
 	*MerkleTree
 
@@ -285,3 +457,18 @@ func (t writableMerkleTree) Delete(key []byte) error {
 
 	return nil
 }
+
+// ForRange implements store.RangeReadable. It calls the function with every
+// key/value pair between start and end, including the pairs that are not
+// committed yet. The ordered index must be enabled.
+func (t writableMerkleTree) ForRange(start, end []byte, fn func(key, value []byte) error) error {
+	t.Lock()
+	defer t.Unlock()
+
+	err := t.tree.ForRange(start, end, fn, t.bucket)
+	if err != nil {
+		return xerrors.Errorf("while visiting: %v", err)
+	}
+
+	return nil
+}
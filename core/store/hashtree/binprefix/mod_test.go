@@ -3,9 +3,7 @@ package binprefix
 import (
 	"bytes"
 	"crypto/rand"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"sort"
 	"testing"
 	"testing/quick"
 
@@ -258,6 +256,433 @@ func TestMerkleTree_GetPath(t *testing.T) {
 	require.EqualError(t, err, "couldn't search key: mismatch key length 33 > 32")
 }
 
+func TestMerkleTree_GetPaths(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	tree := NewMerkleTree(db, Nonce{1})
+	tree.tree.memDepth = 3
+
+	keys := [][]byte{}
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for i := 0; i < 200; i++ {
+			key := make([]byte, 8)
+			rand.Read(key)
+			// Keys are compared as numbers so leading zeros are ignored.
+			key[0] |= 1
+
+			keys = append(keys, key)
+
+			err := snap.Set(key, key)
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	// Half of the keys are proven, and one that is not set.
+	proven := append(keys[:100:100], []byte("unknown"))
+
+	proof, err := next.(*MerkleTree).GetPaths(proven...)
+	require.NoError(t, err)
+	require.Equal(t, next.GetRoot(), proof.GetRoot())
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+
+	verified, err := NewMultiPathFactory().MultiPathOf(testCtx, data)
+	require.NoError(t, err)
+	require.Equal(t, next.GetRoot(), verified.GetRoot())
+	require.Equal(t, proven, verified.GetKeys())
+	require.Len(t, verified.GetValues(), len(proven))
+
+	for i, value := range verified.GetValues()[:100] {
+		require.Equal(t, keys[i], value)
+	}
+	require.Nil(t, verified.GetValues()[100])
+
+	// The shared nodes appear only once in the proof.
+	digests := 0
+	for _, node := range proof.(MultiPath).nodes {
+		if node.typ == diskNodeType {
+			digests++
+		}
+	}
+
+	interiors := 0
+	for _, key := range proven {
+		path, err := next.GetPath(key)
+		require.NoError(t, err)
+
+		interiors += len(path.(Path).interiors)
+	}
+
+	require.Less(t, digests, interiors)
+
+	_, err = next.(*MerkleTree).GetPaths(make([]byte, MaxDepth+1))
+	require.EqualError(t, err, "couldn't search keys: mismatch key length 33 > 32")
+
+	tree.tx = wrongTx{}
+	_, err = tree.GetPaths()
+	require.EqualError(t, err,
+		"couldn't search keys: transaction 'binprefix.wrongTx' is not readable")
+}
+
+func TestMerkleTree_ForEach(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	tree := NewMerkleTree(db, Nonce{})
+	tree.tree.memDepth = 3
+
+	values := map[string][]byte{}
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for i := 0; i < 100; i++ {
+			key := make([]byte, 8)
+			rand.Read(key)
+			// Keys are compared as numbers so leading zeros are ignored.
+			key[0] |= 1
+
+			values[string(key)] = []byte{byte(i)}
+
+			err := snap.Set(key, []byte{byte(i)})
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	// The pairs are enough to rebuild the same tree.
+	other := NewMerkleTree(fakeDB{}, Nonce{})
+	count := 0
+
+	rebuilt, err := other.Stage(func(snap store.Snapshot) error {
+		return next.(*MerkleTree).ForEach(func(key, value []byte) error {
+			count++
+			require.Equal(t, values[string(key)], value)
+
+			return snap.Set(key, value)
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, len(values), count)
+	require.Equal(t, next.GetRoot(), rebuilt.GetRoot())
+
+	err = next.(*MerkleTree).ForEach(func(key, value []byte) error {
+		return fake.GetError()
+	})
+	require.EqualError(t, err, fake.Err("while visiting"))
+
+	tree.tx = wrongTx{}
+	err = tree.ForEach(nil)
+	require.EqualError(t, err,
+		"while visiting: transaction 'binprefix.wrongTx' is not readable")
+}
+
+func TestMerkleTree_ForRange(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	tree := NewMerkleTree(db, Nonce{}, WithOrderedIndex())
+	tree.tree.memDepth = 3
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for _, key := range []string{"b2", "a", "b10", "c", "b", "a1"} {
+			err := snap.Set([]byte(key), []byte("value:"+key))
+			require.NoError(t, err)
+		}
+
+		// The pairs that are not committed yet are visited.
+		var keys []string
+		err := snap.(store.RangeReadable).ForRange(nil, nil, func(key, value []byte) error {
+			keys = append(keys, string(key))
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "a1", "b", "b10", "b2", "c"}, keys)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	var keys []string
+	err = next.(*MerkleTree).ForRange([]byte("a1"), []byte("c"), func(key, value []byte) error {
+		require.Equal(t, "value:"+string(key), string(value))
+
+		keys = append(keys, string(key))
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a1", "b", "b10", "b2"}, keys)
+
+	keys = nil
+	err = next.(*MerkleTree).ForRange([]byte("b"), nil, func(key, value []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "b10", "b2", "c"}, keys)
+
+	err = next.(*MerkleTree).ForRange(nil, nil, func(key, value []byte) error {
+		return fake.GetError()
+	})
+	require.EqualError(t, err, fake.Err("while visiting"))
+
+	tree.tx = wrongTx{}
+	err = tree.ForRange(nil, nil, nil)
+	require.EqualError(t, err,
+		"while visiting: transaction 'binprefix.wrongTx' is not readable")
+}
+
+func TestMerkleTree_ForRange_Disabled(t *testing.T) {
+	tree := NewMerkleTree(fakeDB{}, Nonce{})
+
+	err := tree.ForRange(nil, nil, nil)
+	require.EqualError(t, err, "while visiting: ordered index is disabled")
+}
+
+func TestMerkleTree_ForSuffix(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	tree := NewMerkleTree(db, Nonce{})
+	tree.tree.memDepth = 3
+
+	values := map[string][]byte{}
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for i := 0; i < 100; i++ {
+			key := []byte{byte(i + 1), byte(i % 4)}
+
+			values[string(key)] = []byte{byte(i)}
+
+			err := snap.Set(key, []byte{byte(i)})
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	// The first byte of the tree is the last byte of the keys.
+	var keys [][]byte
+	err = next.(*MerkleTree).ForSuffix([]byte{2}, 8, func(key, value []byte) error {
+		require.Equal(t, byte(2), key[1])
+		require.Equal(t, values[string(key)], value)
+
+		keys = append(keys, key)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, keys, 25)
+
+	// The order is the one of the tree, which is the same as the full
+	// iteration.
+	var all [][]byte
+	err = next.(*MerkleTree).ForEach(func(key, value []byte) error {
+		if key[1] == 2 {
+			all = append(all, key)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, all, keys)
+
+	err = next.(*MerkleTree).ForSuffix(nil, 0, func(key, value []byte) error {
+		return fake.GetError()
+	})
+	require.EqualError(t, err, fake.Err("while visiting"))
+
+	err = next.(*MerkleTree).ForSuffix(nil, MaxDepth*8+1, nil)
+	require.EqualError(t, err, "while visiting: failed to descend: "+
+		"mismatch suffix length 257 > 256")
+
+	tree.tx = wrongTx{}
+	err = tree.ForSuffix(nil, 0, nil)
+	require.EqualError(t, err,
+		"while visiting: transaction 'binprefix.wrongTx' is not readable")
+}
+
+func TestMerkleTree_GetSuffix(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	tree := NewMerkleTree(db, Nonce{1})
+	tree.tree.memDepth = 3
+
+	keys := [][]byte{}
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for i := 0; i < 100; i++ {
+			key := make([]byte, 8)
+			rand.Read(key)
+			// Keys are compared as numbers so leading zeros are ignored.
+			key[0] |= 1
+
+			keys = append(keys, key)
+
+			err := snap.Set(key, key)
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	fac := NewSuffixProofFactory()
+
+	for _, bits := range []uint16{0, 1, 4, 8, 12, 64} {
+		suffix := keys[0][8-(bits+7)/8:]
+
+		expected := 0
+		for _, key := range keys {
+			if hasSuffix(makeKey(key), makeKey(suffix), bits) {
+				expected++
+			}
+		}
+
+		proof, err := next.(*MerkleTree).GetSuffix(suffix, bits)
+		require.NoError(t, err)
+		require.Equal(t, next.GetRoot(), proof.GetRoot())
+
+		data, err := proof.Serialize(testCtx)
+		require.NoError(t, err)
+
+		verified, err := fac.SuffixProofOf(testCtx, data)
+		require.NoError(t, err)
+		require.Equal(t, next.GetRoot(), verified.GetRoot())
+
+		count := 0
+		err = verified.ForEach(func(key, value []byte) error {
+			count++
+			require.Equal(t, key, value)
+			require.True(t, hasSuffix(makeKey(key), makeKey(suffix), bits))
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, count)
+	}
+
+	// A suffix that is not in the tree ends with a leaf or an empty node which
+	// proves that no key ends with it.
+	proof, err := next.(*MerkleTree).GetSuffix([]byte{0xaa, 0xbb, 0xcc}, 24)
+	require.NoError(t, err)
+	require.NoError(t, proof.ForEach(func(key, value []byte) error {
+		return fake.GetError()
+	}))
+
+	_, err = next.(*MerkleTree).GetSuffix(nil, MaxDepth*8+1)
+	require.EqualError(t, err, "couldn't read suffix: failed to descend: "+
+		"mismatch suffix length 257 > 256")
+}
+
+func TestMerkleTree_GetPrefix(t *testing.T) {
+	db, clean := makeDB(t)
+	defer clean()
+
+	tree := NewMerkleTree(db, Nonce{1}, WithOrderedIndex())
+	tree.tree.memDepth = 3
+
+	keys := [][]byte{}
+
+	next, err := tree.Stage(func(snap store.Snapshot) error {
+		for i := 0; i < 100; i++ {
+			key := make([]byte, 8)
+			rand.Read(key)
+			// Keys are compared as numbers so leading zeros are ignored.
+			key[0] |= 1
+
+			keys = append(keys, key)
+
+			err := snap.Set(key, key)
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, next.Commit())
+
+	fac := NewPrefixProofFactory()
+
+	for _, n := range []int{0, 1, 2, 8} {
+		prefix := keys[0][:n]
+
+		expected := [][]byte{}
+		for _, key := range keys {
+			if bytes.HasPrefix(key, prefix) {
+				expected = append(expected, key)
+			}
+		}
+
+		sort.Slice(expected, func(i, j int) bool {
+			return bytes.Compare(expected[i], expected[j]) < 0
+		})
+
+		proof, err := next.(*MerkleTree).GetPrefix(prefix)
+		require.NoError(t, err)
+		require.Equal(t, next.GetRoot(), proof.GetRoot())
+
+		data, err := proof.Serialize(testCtx)
+		require.NoError(t, err)
+
+		verified, err := fac.PrefixProofOf(testCtx, data)
+		require.NoError(t, err)
+		require.Equal(t, next.GetRoot(), verified.GetRoot())
+
+		found := [][]byte{}
+		err = verified.ForEach(func(key, value []byte) error {
+			require.Equal(t, key, value)
+
+			found = append(found, key)
+
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, expected, found)
+	}
+
+	// A prefix that is not in the tree ends with a leaf or an empty node which
+	// proves that no key starts with it.
+	proof, err := next.(*MerkleTree).GetPrefix([]byte{0xaa, 0xbb, 0xcc, 0xdd})
+	require.NoError(t, err)
+	require.NoError(t, proof.ForEach(func(key, value []byte) error {
+		return fake.GetError()
+	}))
+
+	_, err = next.(*MerkleTree).GetPrefix(make([]byte, MaxDepth+1))
+	require.EqualError(t, err, "couldn't read prefix: "+
+		"mismatch prefix length 33 > 32")
+
+	_, err = NewMerkleTree(fakeDB{}, Nonce{}).GetPrefix(nil)
+	require.EqualError(t, err, "couldn't read prefix: ordered index is disabled")
+}
+
+func TestMerkleTree_GetVersion(t *testing.T) {
+	tree := NewMerkleTree(fakeDB{}, Nonce{})
+
+	_, err := tree.GetVersion([]byte{1})
+	require.EqualError(t, err, "history is disabled")
+
+	tree = NewMerkleTree(fakeDB{}, Nonce{}, WithHistory(1))
+
+	_, err = tree.GetVersion([]byte{1})
+	require.EqualError(t, err, "version 0x01 is not retained")
+}
+
 func TestMerkleTree_Stage(t *testing.T) {
 	tree := NewMerkleTree(fakeDB{}, Nonce{})
 
@@ -291,6 +716,12 @@ func TestMerkleTree_Commit(t *testing.T) {
 	tree.tx = badTx{}
 	err = tree.Commit()
 	require.EqualError(t, err, fake.Err("failed to persist tree: read bucket failed"))
+
+	WithHistory(1)(tree)
+	tree.tx = fakeTx{bucket: badScanBucket{fakeBucket: &fakeBucket{}}}
+	err = tree.Commit()
+	require.EqualError(t, err,
+		fake.Err("failed to persist tree: failed to update history: failed to scan versions"))
 }
 
 func TestWritableMerkleTree_Set(t *testing.T) {
@@ -318,13 +749,9 @@ func TestWritableMerkleTree_Delete(t *testing.T) {
 // Utility functions
 
 func makeDB(t *testing.T) (kv.DB, func()) {
-	dir, err := ioutil.TempDir(os.TempDir(), "dela-pow")
-	require.NoError(t, err)
-
-	db, err := kv.New(filepath.Join(dir, "test.db"))
-	require.NoError(t, err)
+	db := kv.NewInMemory()
 
-	return db, func() { os.RemoveAll(dir) }
+	return db, func() { db.Close() }
 }
 
 type badTx struct {
@@ -0,0 +1,223 @@
+package binprefix
+
+import (
+	"math/big"
+
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// pathNode is a node of a multi-key proof. The subtrees that none of the keys
+// goes through are pruned and only their hash is kept.
+type pathNode struct {
+	typ   byte
+	key   []byte
+	value []byte
+	hash  []byte
+}
+
+// MultiPath is a proof for several keys at once. It contains the union of the
+// paths to the keys, in depth-first order, where a node shared by several
+// paths appears only once. The root is therefore calculated once for all the
+// keys.
+//
+// - implements hashtree.MultiPath
+// - implements serde.Message
+type MultiPath struct {
+	nonce []byte
+	keys  [][]byte
+	nodes []pathNode
+	// Root and values are not serialized and reproduced from the nodes when
+	// deserializing.
+	root   []byte
+	values [][]byte
+}
+
+// newMultiPath creates an empty proof for the keys. It must be filled to be
+// valid.
+func newMultiPath(nonce []byte, keys [][]byte) MultiPath {
+	return MultiPath{
+		nonce: nonce,
+		keys:  keys,
+	}
+}
+
+// GetKeys implements hashtree.MultiPath. It returns the keys of the proof.
+func (p MultiPath) GetKeys() [][]byte {
+	return p.keys
+}
+
+// GetValues implements hashtree.MultiPath. It returns the values of the keys,
+// or nil for the keys that are not set.
+func (p MultiPath) GetValues() [][]byte {
+	return p.values
+}
+
+// GetRoot implements hashtree.MultiPath. It returns the hash of the root node
+// calculated from the nodes of the proof.
+func (p MultiPath) GetRoot() []byte {
+	return p.root
+}
+
+// Serialize implements serde.Message. It returns the data of the serialized
+// proof.
+func (p MultiPath) Serialize(ctx serde.Context) ([]byte, error) {
+	format := multiPathFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, p)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode: %v", err)
+	}
+
+	return data, nil
+}
+
+// build returns the root of the partial tree described by the nodes.
+func (p MultiPath) build() (TreeNode, error) {
+	cursor := 0
+
+	root, err := p.buildNode(&cursor, 0, new(big.Int))
+	if err != nil {
+		return nil, err
+	}
+
+	if cursor != len(p.nodes) {
+		return nil, xerrors.Errorf("%d unused nodes", len(p.nodes)-cursor)
+	}
+
+	return root, nil
+}
+
+func (p MultiPath) buildNode(cursor *int, depth uint16, prefix *big.Int) (TreeNode, error) {
+	if *cursor >= len(p.nodes) {
+		return nil, xerrors.Errorf("missing node at depth %d", depth)
+	}
+
+	if int(depth) > maxNodeDepth {
+		return nil, xerrors.Errorf("depth %d out of range", depth)
+	}
+
+	node := p.nodes[*cursor]
+	*cursor++
+
+	switch node.typ {
+	case emptyNodeType:
+		return NewEmptyNode(depth, prefix), nil
+	case leafNodeType:
+		return NewLeafNode(depth, makeKey(node.key), node.value), nil
+	case diskNodeType:
+		if len(node.hash) == 0 {
+			return nil, xerrors.Errorf("missing digest at depth %d", depth)
+		}
+
+		// A pruned subtree is known only by its hash, like a node that has
+		// not been loaded yet.
+		return NewDiskNode(depth, node.hash, serde.Context{}, nil), nil
+	case interiorNodeType:
+		left, err := p.buildNode(cursor, depth+1, new(big.Int).SetBit(prefix, int(depth), 0))
+		if err != nil {
+			// No wrapping to prevent recursive calls to create huge error
+			// messages.
+			return nil, err
+		}
+
+		right, err := p.buildNode(cursor, depth+1, new(big.Int).SetBit(prefix, int(depth), 1))
+		if err != nil {
+			return nil, err
+		}
+
+		return NewInteriorNodeWithChildren(depth, prefix, nil, left, right), nil
+	default:
+		return nil, xerrors.Errorf("unknown node type %d", node.typ)
+	}
+}
+
+// lookup returns the values of the keys by following their path in the
+// partial tree. It returns an error if a path goes through a pruned subtree.
+func (p MultiPath) lookup(root TreeNode) ([][]byte, error) {
+	values := make([][]byte, len(p.keys))
+
+	for i, k := range p.keys {
+		key := makeKey(k)
+		node := root
+
+		for node.GetType() == interiorNodeType {
+			interior := node.(*InteriorNode)
+
+			node = interior.left
+			if key.Bit(int(interior.depth)) == 1 {
+				node = interior.right
+			}
+		}
+
+		switch n := node.(type) {
+		case *LeafNode:
+			if n.key.Cmp(key) == 0 {
+				values[i] = n.value
+			}
+		case *DiskNode:
+			return nil, xerrors.Errorf("key %#x is not part of the proof", k)
+		}
+	}
+
+	return values, nil
+}
+
+// MultiPathFactory is the factory to deserialize multi-key proofs. The root of
+// a proof is calculated from the nodes so that it can be compared to the root
+// of a trusted tree.
+//
+// - implements hashtree.MultiPathFactory
+type MultiPathFactory struct {
+	hashFactory crypto.HashFactory
+}
+
+// NewMultiPathFactory creates a new multi-key proof factory using the same
+// hash algorithm as the Merkle tree.
+func NewMultiPathFactory() MultiPathFactory {
+	return MultiPathFactory{
+		hashFactory: crypto.NewSha256Factory(),
+	}
+}
+
+// Deserialize implements serde.Factory. It populates the proof from the data if
+// appropriate, otherwise it returns an error.
+func (f MultiPathFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.MultiPathOf(ctx, data)
+}
+
+// MultiPathOf implements hashtree.MultiPathFactory. It populates the proof from
+// the data, then calculates its root and the values of the keys if
+// appropriate, otherwise it returns an error.
+func (f MultiPathFactory) MultiPathOf(ctx serde.Context, data []byte) (hashtree.MultiPath, error) {
+	format := multiPathFormats.Get(ctx.GetFormat())
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("format failed: %v", err)
+	}
+
+	proof, ok := msg.(MultiPath)
+	if !ok {
+		return nil, xerrors.Errorf("invalid multi path '%T'", msg)
+	}
+
+	root, err := proof.build()
+	if err != nil {
+		return nil, xerrors.Errorf("failed to build: %v", err)
+	}
+
+	proof.root, err = root.Prepare(proof.nonce, new(big.Int), nil, f.hashFactory)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute root: %v", err)
+	}
+
+	proof.values, err = proof.lookup(root)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to lookup: %v", err)
+	}
+
+	return proof, nil
+}
@@ -0,0 +1,157 @@
+package binprefix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestMultiPath_GetKeys(t *testing.T) {
+	proof := newMultiPath([]byte{}, [][]byte{[]byte("A"), []byte("B")})
+
+	require.Equal(t, [][]byte{[]byte("A"), []byte("B")}, proof.GetKeys())
+}
+
+func TestMultiPath_GetValues(t *testing.T) {
+	proof := newMultiPath([]byte{}, nil)
+
+	require.Nil(t, proof.GetValues())
+
+	proof.values = [][]byte{[]byte("A")}
+	require.Equal(t, [][]byte{[]byte("A")}, proof.GetValues())
+}
+
+func TestMultiPath_GetRoot(t *testing.T) {
+	proof := newMultiPath([]byte{}, nil)
+
+	require.Nil(t, proof.GetRoot())
+
+	proof.root = []byte("root")
+	require.Equal(t, []byte("root"), proof.GetRoot())
+}
+
+func TestMultiPath_Serialize(t *testing.T) {
+	proof := newMultiPath([]byte{1}, [][]byte{[]byte("A")})
+	proof.nodes = []pathNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte("A"), value: []byte("B")},
+		{typ: interiorNodeType},
+		{typ: emptyNodeType},
+		{typ: diskNodeType, hash: []byte{2}},
+	}
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"Nonce":"AQ==","Keys":["QQ=="],"Nodes":[{"Interior":true},`+
+			`{"Leaf":{"Key":"QQ==","Value":"Qg=="}},{"Interior":true},{},`+
+			`{"Digest":"Ag=="}]}`,
+		string(data))
+
+	_, err = proof.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("failed to encode"))
+}
+
+func TestMultiPath_Build(t *testing.T) {
+	proof := newMultiPath([]byte{}, nil)
+	proof.nodes = []pathNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte{0}},
+		{typ: diskNodeType, hash: []byte{1}},
+	}
+
+	root, err := proof.build()
+	require.NoError(t, err)
+	require.IsType(t, &InteriorNode{}, root)
+
+	proof.nodes = append(proof.nodes, pathNode{typ: emptyNodeType})
+	_, err = proof.build()
+	require.EqualError(t, err, "1 unused nodes")
+
+	proof.nodes = proof.nodes[:2]
+	_, err = proof.build()
+	require.EqualError(t, err, "missing node at depth 1")
+
+	proof.nodes = []pathNode{{typ: diskNodeType}}
+	_, err = proof.build()
+	require.EqualError(t, err, "missing digest at depth 0")
+
+	proof.nodes = []pathNode{{typ: 0xff}}
+	_, err = proof.build()
+	require.EqualError(t, err, "unknown node type 255")
+}
+
+func TestMultiPath_Lookup(t *testing.T) {
+	proof := newMultiPath([]byte{}, [][]byte{{0b10}, {0b100}, {0b11}})
+	proof.nodes = []pathNode{
+		{typ: interiorNodeType},
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte{0b100}, value: []byte("A")},
+		{typ: emptyNodeType},
+		{typ: diskNodeType, hash: []byte{1}},
+	}
+
+	root, err := proof.build()
+	require.NoError(t, err)
+
+	// 0b11 goes through the pruned subtree.
+	_, err = proof.lookup(root)
+	require.EqualError(t, err, "key 0x03 is not part of the proof")
+
+	// 0b10 ends on an empty node which proves its absence.
+	proof.keys = proof.keys[:2]
+	values, err := proof.lookup(root)
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{nil, []byte("A")}, values)
+}
+
+func TestMultiPathFactory_Deserialize(t *testing.T) {
+	tree := NewTree(Nonce{1})
+
+	for _, key := range []string{"A", "Q", "a", "b"} {
+		err := tree.Insert([]byte(key), []byte(key), &fakeBucket{})
+		require.NoError(t, err)
+	}
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{})
+	require.NoError(t, err)
+
+	fac := NewMultiPathFactory()
+
+	proof := newMultiPath(tree.nonce[:], [][]byte{[]byte("A"), []byte("@")})
+
+	err = tree.GetPaths(&proof, &fakeBucket{})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("A"), nil}, proof.GetValues())
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+
+	msg, err := fac.Deserialize(testCtx, data)
+	require.NoError(t, err)
+	require.Equal(t, tree.root.GetHash(), msg.(MultiPath).GetRoot())
+	require.Equal(t, proof.GetValues(), msg.(MultiPath).GetValues())
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("format failed"))
+
+	_, err = fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "invalid multi path 'fake.Message'")
+
+	_, err = fac.Deserialize(testCtx, []byte(`{"Nodes":[{"Interior":true,"Digest":"AQ=="}]}`))
+	require.EqualError(t, err,
+		"format failed: node 0 has more than one type")
+
+	_, err = fac.Deserialize(testCtx, []byte(`{}`))
+	require.EqualError(t, err, "failed to build: missing node at depth 0")
+
+	_, err = fac.Deserialize(testCtx, []byte(`{"Keys":["AQ=="],"Nodes":[{"Digest":"AQ=="}]}`))
+	require.EqualError(t, err, "failed to lookup: key 0x01 is not part of the proof")
+
+	fac.hashFactory = fake.NewHashFactory(fake.NewBadHash())
+	_, err = fac.Deserialize(testCtx, []byte(`{"Nodes":[{}]}`))
+	require.EqualError(t, err,
+		fake.Err("failed to compute root: empty node failed"))
+}
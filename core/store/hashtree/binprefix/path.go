@@ -5,17 +5,22 @@
 package binprefix
 
 import (
+	"bytes"
 	"math/big"
 
+	"go.dedis.ch/dela/core/store/hashtree"
 	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
 	"golang.org/x/xerrors"
 )
 
 // Path is a path from the root to a leaf, represented as a series of interior
 // nodes hashes. The end of the path is either a leaf with a key holding a
-// value, or an empty node.
+// value, an empty node, or the leaf of another key that shares the same
+// prefix.
 //
 // - implements hashtree.Path
+// - implements serde.Message
 type Path struct {
 	nonce []byte
 	key   []byte
@@ -24,6 +29,10 @@ type Path struct {
 	// reproduced from the leaf and the interior nodes when deserializing.
 	root      []byte
 	interiors [][]byte
+	// The leaf key and value are set when the path ends with the leaf of
+	// another key, which proves the absence of the key.
+	leafKey   []byte
+	leafValue []byte
 }
 
 // newPath creates an empty path for the provided key. It must be filled to be
@@ -51,14 +60,65 @@ func (s Path) GetRoot() []byte {
 	return s.root
 }
 
+// Serialize implements serde.Message. It returns the data of the serialized
+// path.
+func (s Path) Serialize(ctx serde.Context) ([]byte, error) {
+	format := pathFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, s)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode: %v", err)
+	}
+
+	return data, nil
+}
+
+// VerifyPath verifies the path against the nonce and the root of a trusted
+// tree. It returns true if the path proves the inclusion of its key, or false
+// if it proves its absence, otherwise it returns an error.
+func VerifyPath(p hashtree.Path, nonce Nonce, root []byte) (bool, error) {
+	path, ok := p.(Path)
+	if !ok {
+		return false, xerrors.Errorf("invalid path '%T'", p)
+	}
+
+	if !bytes.Equal(path.nonce, nonce[:]) {
+		return false, xerrors.Errorf("mismatch nonce %#x != %#x", path.nonce, nonce[:])
+	}
+
+	computed, err := path.computeRoot(crypto.NewSha256Factory())
+	if err != nil {
+		return false, xerrors.Errorf("failed to compute root: %v", err)
+	}
+
+	if !bytes.Equal(computed, root) {
+		return false, xerrors.Errorf("mismatch root %#x != %#x", computed, root)
+	}
+
+	return path.value != nil, nil
+}
+
 func (s Path) computeRoot(fac crypto.HashFactory) ([]byte, error) {
 	key := new(big.Int)
 	key.SetBytes(s.key)
 
 	var node TreeNode
-	if s.value != nil {
+
+	switch {
+	case s.leafKey != nil:
+		if s.value != nil {
+			return nil, xerrors.New("path has both a value and another leaf")
+		}
+
+		other := makeKey(s.leafKey)
+		if other.Cmp(key) == 0 {
+			return nil, xerrors.New("other leaf has the same key")
+		}
+
+		node = NewLeafNode(uint16(len(s.interiors)), other, s.leafValue)
+	case s.value != nil:
 		node = NewLeafNode(uint16(len(s.interiors)), key, s.value)
-	} else {
+	default:
 		node = NewEmptyNode(uint16(len(s.interiors)), key)
 	}
 
@@ -89,3 +149,49 @@ func (s Path) computeRoot(fac crypto.HashFactory) ([]byte, error) {
 
 	return curr, nil
 }
+
+// PathFactory is the factory to deserialize paths. The root of a path is
+// calculated from the leaf and the interior nodes so that it can be compared
+// to the root of a trusted tree.
+//
+// - implements hashtree.PathFactory
+type PathFactory struct {
+	hashFactory crypto.HashFactory
+}
+
+// NewPathFactory creates a new path factory using the same hash algorithm as
+// the Merkle tree.
+func NewPathFactory() PathFactory {
+	return PathFactory{
+		hashFactory: crypto.NewSha256Factory(),
+	}
+}
+
+// Deserialize implements serde.Factory. It populates the path from the data if
+// appropriate, otherwise it returns an error.
+func (f PathFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.PathOf(ctx, data)
+}
+
+// PathOf implements hashtree.PathFactory. It populates the path from the data
+// and calculates its root if appropriate, otherwise it returns an error.
+func (f PathFactory) PathOf(ctx serde.Context, data []byte) (hashtree.Path, error) {
+	format := pathFormats.Get(ctx.GetFormat())
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("format failed: %v", err)
+	}
+
+	path, ok := msg.(Path)
+	if !ok {
+		return nil, xerrors.Errorf("invalid path '%T'", msg)
+	}
+
+	path.root, err = path.computeRoot(f.hashFactory)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute root: %v", err)
+	}
+
+	return path, nil
+}
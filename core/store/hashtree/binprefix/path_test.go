@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
 	"go.dedis.ch/dela/internal/testing/fake"
 )
 
@@ -40,4 +41,138 @@ func TestPath_ComputeRoot(t *testing.T) {
 
 	_, err = path.computeRoot(fake.NewHashFactory(fake.NewBadHash()))
 	require.EqualError(t, err, fake.Err("while preparing: empty node failed"))
+
+	path.leafKey = []byte("B")
+	root, err = path.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.NoError(t, err)
+	require.NotEmpty(t, root)
+
+	path.value = []byte("A")
+	_, err = path.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.EqualError(t, err, "path has both a value and another leaf")
+
+	path.value = nil
+	path.leafKey = []byte("A")
+	_, err = path.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.EqualError(t, err, "other leaf has the same key")
+}
+
+func TestPath_Serialize(t *testing.T) {
+	path := newPath([]byte{1}, []byte("ping"))
+	path.value = []byte("pong")
+	path.interiors = [][]byte{{2}}
+
+	data, err := path.Serialize(testCtx)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"Nonce":"AQ==","Key":"cGluZw==","Value":"cG9uZw==","Interiors":["Ag=="]}`,
+		string(data))
+
+	path.value = nil
+	path.leafKey = []byte("pong")
+	path.leafValue = []byte("ping")
+
+	data, err = path.Serialize(testCtx)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"Nonce":"AQ==","Key":"cGluZw==","Value":null,"Interiors":["Ag=="],`+
+			`"Leaf":{"Key":"cG9uZw==","Value":"cGluZw=="}}`,
+		string(data))
+
+	_, err = path.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("failed to encode"))
+}
+
+func TestVerifyPath(t *testing.T) {
+	tree := NewTree(Nonce{1})
+
+	for _, key := range []string{"A", "B"} {
+		err := tree.Insert([]byte(key), []byte(key), &fakeBucket{})
+		require.NoError(t, err)
+	}
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{})
+	require.NoError(t, err)
+
+	root := tree.root.GetHash()
+
+	// A is included, while C and D end on the leaves of A and B.
+	for key, included := range map[string]bool{"A": true, "C": false, "D": false} {
+		path := newPath(tree.nonce[:], []byte(key))
+
+		_, err = tree.Search([]byte(key), &path, &fakeBucket{})
+		require.NoError(t, err)
+
+		data, err := path.Serialize(testCtx)
+		require.NoError(t, err)
+
+		msg, err := NewPathFactory().PathOf(testCtx, data)
+		require.NoError(t, err)
+
+		found, err := VerifyPath(msg, tree.nonce, root)
+		require.NoError(t, err)
+		require.Equal(t, included, found, key)
+	}
+
+	path := newPath(tree.nonce[:], []byte("A"))
+	_, err = tree.Search([]byte("A"), &path, &fakeBucket{})
+	require.NoError(t, err)
+
+	_, err = VerifyPath(nil, tree.nonce, root)
+	require.EqualError(t, err, "invalid path '<nil>'")
+
+	_, err = VerifyPath(path, Nonce{2}, root)
+	require.EqualError(t, err,
+		"mismatch nonce 0x0100000000000000 != 0x0200000000000000")
+
+	_, err = VerifyPath(path, tree.nonce, []byte{1})
+	require.Error(t, err)
+	require.Regexp(t, "^mismatch root 0x[0-9a-f]+ != 0x01$", err.Error())
+
+	path.leafKey = []byte("A")
+	_, err = VerifyPath(path, tree.nonce, root)
+	require.EqualError(t, err,
+		"failed to compute root: path has both a value and another leaf")
+}
+
+func TestPathFactory_Deserialize(t *testing.T) {
+	tree := NewTree(Nonce{1})
+
+	for _, key := range []string{"A", "Q"} {
+		err := tree.Insert([]byte(key), []byte(key), &fakeBucket{})
+		require.NoError(t, err)
+	}
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{})
+	require.NoError(t, err)
+
+	fac := NewPathFactory()
+
+	// Proof of inclusion for A, and proof of absence for @ which ends with an
+	// empty node as A and Q share the same first bits.
+	for _, key := range []string{"A", "@"} {
+		path := newPath(tree.nonce[:], []byte(key))
+
+		_, err = tree.Search([]byte(key), &path, &fakeBucket{})
+		require.NoError(t, err)
+
+		data, err := path.Serialize(testCtx)
+		require.NoError(t, err)
+
+		msg, err := fac.Deserialize(testCtx, data)
+		require.NoError(t, err)
+		require.Equal(t, path, msg)
+		require.Equal(t, tree.root.GetHash(), msg.(Path).GetRoot())
+	}
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("format failed"))
+
+	_, err = fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "invalid path 'fake.Message'")
+
+	fac.hashFactory = fake.NewHashFactory(fake.NewBadHash())
+	_, err = fac.Deserialize(testCtx, []byte(`{}`))
+	require.EqualError(t, err,
+		fake.Err("failed to compute root: while preparing: empty node failed"))
 }
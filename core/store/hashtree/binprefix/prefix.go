@@ -0,0 +1,134 @@
+package binprefix
+
+import (
+	"bytes"
+
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// PrefixProof is a proof that a list of key/value pairs is the complete set of
+// keys of the tree starting with the same bytes. The keys with the same prefix
+// share a subtree of the ordered index, which is proven like the subtree of a
+// suffix.
+//
+// - implements hashtree.PrefixProof
+// - implements serde.Message
+type PrefixProof struct {
+	prefix []byte
+	suffix SuffixProof
+}
+
+// newPrefixProof creates an empty prefix proof for the prefix. It must be
+// filled to be valid.
+func newPrefixProof(nonce, prefix []byte) PrefixProof {
+	key, bits := indexPrefix(prefix)
+
+	return PrefixProof{
+		prefix: prefix,
+		suffix: newSuffixProof(nonce, key.Bytes(), bits),
+	}
+}
+
+// GetPrefix implements hashtree.PrefixProof. It returns the prefix of the keys.
+func (p PrefixProof) GetPrefix() []byte {
+	return p.prefix
+}
+
+// ForEach implements hashtree.PrefixProof. It calls the function with every
+// key/value pair starting with the prefix in the lexicographic order of the
+// keys.
+func (p PrefixProof) ForEach(fn func(key, value []byte) error) error {
+	for _, node := range p.suffix.nodes {
+		if node.typ != leafNodeType {
+			continue
+		}
+
+		// The subtree might contain regular keys, or be a single entry above
+		// the prefix which does not necessarily start with it.
+		key, ok := fromIndexKey(makeKey(node.key))
+		if !ok || !bytes.HasPrefix(key, p.prefix) {
+			continue
+		}
+
+		err := fn(key, node.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetRoot implements hashtree.PrefixProof. It returns the hash of the root node
+// calculated from the subtree up to the root.
+func (p PrefixProof) GetRoot() []byte {
+	return p.suffix.root
+}
+
+// Serialize implements serde.Message. It returns the data of the serialized
+// prefix proof.
+func (p PrefixProof) Serialize(ctx serde.Context) ([]byte, error) {
+	format := prefixFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, p)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode: %v", err)
+	}
+
+	return data, nil
+}
+
+// PrefixProofFactory is the factory to deserialize prefix proofs. The root of a
+// proof is calculated from the subtree and the interior nodes so that it can
+// be compared to the root of a trusted tree.
+//
+// - implements hashtree.PrefixProofFactory
+type PrefixProofFactory struct {
+	hashFactory crypto.HashFactory
+}
+
+// NewPrefixProofFactory creates a new prefix proof factory using the same hash
+// algorithm as the Merkle tree.
+func NewPrefixProofFactory() PrefixProofFactory {
+	return PrefixProofFactory{
+		hashFactory: crypto.NewSha256Factory(),
+	}
+}
+
+// Deserialize implements serde.Factory. It populates the prefix proof from the
+// data if appropriate, otherwise it returns an error.
+func (f PrefixProofFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.PrefixProofOf(ctx, data)
+}
+
+// PrefixProofOf implements hashtree.PrefixProofFactory. It populates the prefix
+// proof from the data and calculates its root if appropriate, otherwise it
+// returns an error. The position of the subtree is derived from the prefix so
+// that the proof cannot be about other keys.
+func (f PrefixProofFactory) PrefixProofOf(ctx serde.Context, data []byte) (hashtree.PrefixProof, error) {
+	format := prefixFormats.Get(ctx.GetFormat())
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("format failed: %v", err)
+	}
+
+	proof, ok := msg.(PrefixProof)
+	if !ok {
+		return nil, xerrors.Errorf("invalid prefix proof '%T'", msg)
+	}
+
+	if len(proof.prefix) > MaxDepth {
+		return nil, xerrors.Errorf("mismatch prefix length %d > %d", len(proof.prefix), MaxDepth)
+	}
+
+	proof.suffix.root, err = proof.suffix.computeRoot(f.hashFactory)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute root: %v", err)
+	}
+
+	return proof, nil
+}
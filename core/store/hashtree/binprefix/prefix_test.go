@@ -0,0 +1,140 @@
+package binprefix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestPrefixProof_GetPrefix(t *testing.T) {
+	proof := newPrefixProof([]byte{}, []byte("A"))
+
+	require.Equal(t, []byte("A"), proof.GetPrefix())
+}
+
+func TestPrefixProof_ForEach(t *testing.T) {
+	proof := newPrefixProof([]byte{}, []byte("A"))
+	proof.suffix.nodes = []suffixNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: indexKey([]byte("AB")).Bytes(), value: []byte("1")},
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: indexKey([]byte("B")).Bytes(), value: []byte("2")},
+		{typ: leafNodeType, key: []byte("A"), value: []byte("3")},
+		{typ: emptyNodeType},
+	}
+
+	values := map[string][]byte{}
+	err := proof.ForEach(func(key, value []byte) error {
+		values[string(key)] = value
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"AB": []byte("1")}, values)
+
+	err = proof.ForEach(func(key, value []byte) error {
+		return fake.GetError()
+	})
+	require.EqualError(t, err, fake.GetError().Error())
+}
+
+func TestPrefixProof_GetRoot(t *testing.T) {
+	proof := newPrefixProof([]byte{}, nil)
+
+	require.Nil(t, proof.GetRoot())
+
+	proof.suffix.root = []byte("root")
+	require.Equal(t, []byte("root"), proof.GetRoot())
+}
+
+func TestPrefixProof_Serialize(t *testing.T) {
+	proof := newPrefixProof([]byte{1}, []byte{2})
+	proof.suffix.interiors = [][]byte{{3}}
+	proof.suffix.nodes = []suffixNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte("A"), value: []byte("B")},
+		{typ: emptyNodeType},
+	}
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"Nonce":"AQ==","Prefix":"Ag==","Interiors":["Aw=="],`+
+			`"Nodes":[{"Interior":true},{"Leaf":{"Key":"QQ==","Value":"Qg=="}},{}]}`,
+		string(data))
+
+	_, err = proof.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("failed to encode"))
+}
+
+func TestPrefixProofFactory_Deserialize(t *testing.T) {
+	tree := NewTree(Nonce{1})
+	tree.indexed = true
+
+	for _, key := range []string{"A", "AB", "Q", "a"} {
+		err := tree.Insert([]byte(key), []byte(key), &fakeBucket{})
+		require.NoError(t, err)
+	}
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{})
+	require.NoError(t, err)
+
+	fac := NewPrefixProofFactory()
+
+	proof := newPrefixProof(tree.nonce[:], []byte("A"))
+
+	err = tree.GetPrefix(&proof, &fakeBucket{})
+	require.NoError(t, err)
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+
+	msg, err := fac.Deserialize(testCtx, data)
+	require.NoError(t, err)
+	require.Equal(t, proof, msg)
+	require.Equal(t, tree.root.GetHash(), msg.(PrefixProof).GetRoot())
+
+	// A proof for another prefix is placed elsewhere in the tree and does not
+	// match the root.
+	other := proof
+	other.prefix = []byte("Q")
+
+	data, err = other.Serialize(testCtx)
+	require.NoError(t, err)
+
+	msg, err = fac.Deserialize(testCtx, data)
+	require.NoError(t, err)
+	require.NotEqual(t, tree.root.GetHash(), msg.(PrefixProof).GetRoot())
+
+	// A proof that hides a leaf of the prefix does not match the root.
+	for i, node := range proof.suffix.nodes {
+		if node.typ == leafNodeType {
+			proof.suffix.nodes[i] = suffixNode{typ: emptyNodeType}
+			break
+		}
+	}
+
+	root, err := proof.suffix.computeRoot(crypto.NewSha256Factory())
+	require.NoError(t, err)
+	require.NotEqual(t, tree.root.GetHash(), root)
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("format failed"))
+
+	_, err = fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "invalid prefix proof 'fake.Message'")
+
+	_, err = fac.Deserialize(testCtx, []byte(`{"Prefix":"`+
+		"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"+`"}`))
+	require.EqualError(t, err, "mismatch prefix length 33 > 32")
+
+	_, err = fac.Deserialize(testCtx, []byte(`{"Nodes":[{"Interior":true,"Leaf":{}}]}`))
+	require.EqualError(t, err,
+		"format failed: node 0 is both interior and leaf")
+
+	fac.hashFactory = fake.NewHashFactory(fake.NewBadHash())
+	_, err = fac.Deserialize(testCtx, []byte(`{"Nodes":[{}]}`))
+	require.EqualError(t, err,
+		fake.Err("failed to compute root: while preparing: empty node failed"))
+}
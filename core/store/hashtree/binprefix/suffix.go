@@ -0,0 +1,251 @@
+package binprefix
+
+import (
+	"math/big"
+
+	"go.dedis.ch/dela/core/store/hashtree"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/serde"
+	"golang.org/x/xerrors"
+)
+
+// suffixNode is a node of the subtree of a suffix proof. Interior and empty
+// nodes only mark the shape of the subtree as their hashes are calculated from
+// their position.
+type suffixNode struct {
+	typ   byte
+	key   []byte
+	value []byte
+}
+
+// SuffixProof is a proof that a list of key/value pairs is the complete set of
+// keys of the tree ending with the same bits. It contains the interior nodes
+// hashes from the root to the suffix, like a path, followed by every node of
+// the subtree of the suffix in depth-first order so that its hash can be
+// calculated.
+//
+// The tree branches on the least significant bit of the keys first, which
+// means that the subtree of a node contains the keys ending with the same bits.
+// A suffix of n bytes therefore contains the keys that end with the same n
+// bytes, and the keys shorter than the suffix are padded with zeros.
+//
+// - implements hashtree.SuffixProof
+// - implements serde.Message
+type SuffixProof struct {
+	nonce  []byte
+	suffix []byte
+	bits   uint16
+	// Root is the root of the hash tree. This value is not serialized and
+	// reproduced from the subtree and the interior nodes when deserializing.
+	root      []byte
+	interiors [][]byte
+	nodes     []suffixNode
+}
+
+// newSuffixProof creates an empty suffix proof for the suffix. It must be
+// filled to be valid.
+func newSuffixProof(nonce, suffix []byte, bits uint16) SuffixProof {
+	return SuffixProof{
+		nonce:  nonce,
+		suffix: suffix,
+		bits:   bits,
+	}
+}
+
+// GetSuffix implements hashtree.SuffixProof. It returns the suffix of the keys
+// and its length in bits.
+func (p SuffixProof) GetSuffix() ([]byte, uint16) {
+	return p.suffix, p.bits
+}
+
+// ForEach implements hashtree.SuffixProof. It calls the function with every
+// key/value pair of the suffix in the order of the tree. The entries of the
+// ordered index are skipped.
+func (p SuffixProof) ForEach(fn func(key, value []byte) error) error {
+	suffix := makeKey(p.suffix)
+
+	for _, node := range p.nodes {
+		// The subtree might be a single leaf above the suffix which does not
+		// necessarily end with it.
+		if node.typ != leafNodeType || !hasSuffix(makeKey(node.key), suffix, p.bits) {
+			continue
+		}
+
+		if isIndexKey(makeKey(node.key)) {
+			continue
+		}
+
+		err := fn(node.key, node.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetRoot implements hashtree.SuffixProof. It returns the hash of the root node
+// calculated from the subtree up to the root.
+func (p SuffixProof) GetRoot() []byte {
+	return p.root
+}
+
+// Serialize implements serde.Message. It returns the data of the serialized
+// suffix proof.
+func (p SuffixProof) Serialize(ctx serde.Context) ([]byte, error) {
+	format := suffixFormats.Get(ctx.GetFormat())
+
+	data, err := format.Encode(ctx, p)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to encode: %v", err)
+	}
+
+	return data, nil
+}
+
+func (p SuffixProof) computeRoot(fac crypto.HashFactory) ([]byte, error) {
+	if len(p.interiors) > int(p.bits) {
+		return nil, xerrors.Errorf("path too long: %d > %d", len(p.interiors), p.bits)
+	}
+
+	key := makeKey(p.suffix)
+
+	// Reproduce the prefix of the subtree.
+	prefix := new(big.Int)
+	for i := 0; i < len(p.interiors); i++ {
+		prefix.SetBit(prefix, i, key.Bit(i))
+	}
+
+	cursor := 0
+
+	curr, err := p.computeNode(&cursor, uint16(len(p.interiors)), prefix, fac)
+	if err != nil {
+		return nil, xerrors.Errorf("while preparing: %v", err)
+	}
+
+	if cursor != len(p.nodes) {
+		return nil, xerrors.Errorf("%d unused nodes", len(p.nodes)-cursor)
+	}
+
+	for i := len(p.interiors) - 1; i >= 0; i-- {
+		h := fac.New()
+
+		if key.Bit(i) == 0 {
+			h.Write(curr)
+			h.Write(p.interiors[i])
+		} else {
+			h.Write(p.interiors[i])
+			h.Write(curr)
+		}
+
+		curr = h.Sum(nil)
+	}
+
+	return curr, nil
+}
+
+func (p SuffixProof) computeNode(cursor *int, depth uint16, prefix *big.Int,
+	fac crypto.HashFactory) ([]byte, error) {
+
+	if *cursor >= len(p.nodes) {
+		return nil, xerrors.Errorf("missing node at depth %d", depth)
+	}
+
+	if int(depth) > maxNodeDepth {
+		return nil, xerrors.Errorf("depth %d out of range", depth)
+	}
+
+	node := p.nodes[*cursor]
+	*cursor++
+
+	switch node.typ {
+	case emptyNodeType:
+		return NewEmptyNode(depth, prefix).Prepare(p.nonce, prefix, nil, fac)
+	case leafNodeType:
+		leaf := NewLeafNode(depth, makeKey(node.key), node.value)
+
+		return leaf.Prepare(p.nonce, prefix, nil, fac)
+	case interiorNodeType:
+		left, err := p.computeNode(cursor, depth+1, new(big.Int).SetBit(prefix, int(depth), 0), fac)
+		if err != nil {
+			// No wrapping to prevent recursive calls to create huge error
+			// messages.
+			return nil, err
+		}
+
+		right, err := p.computeNode(cursor, depth+1, new(big.Int).SetBit(prefix, int(depth), 1), fac)
+		if err != nil {
+			return nil, err
+		}
+
+		h := fac.New()
+
+		_, err = h.Write(append(left, right...))
+		if err != nil {
+			return nil, xerrors.Errorf("interior node failed: %v", err)
+		}
+
+		return h.Sum(nil), nil
+	default:
+		return nil, xerrors.Errorf("unknown node type %d", node.typ)
+	}
+}
+
+// SuffixProofFactory is the factory to deserialize suffix proofs. The root of a
+// proof is calculated from the subtree and the interior nodes so that it can
+// be compared to the root of a trusted tree.
+//
+// - implements hashtree.SuffixProofFactory
+type SuffixProofFactory struct {
+	hashFactory crypto.HashFactory
+}
+
+// NewSuffixProofFactory creates a new suffix proof factory using the same hash
+// algorithm as the Merkle tree.
+func NewSuffixProofFactory() SuffixProofFactory {
+	return SuffixProofFactory{
+		hashFactory: crypto.NewSha256Factory(),
+	}
+}
+
+// Deserialize implements serde.Factory. It populates the suffix proof from the
+// data if appropriate, otherwise it returns an error.
+func (f SuffixProofFactory) Deserialize(ctx serde.Context, data []byte) (serde.Message, error) {
+	return f.SuffixProofOf(ctx, data)
+}
+
+// SuffixProofOf implements hashtree.SuffixProofFactory. It populates the
+// suffix proof from the data and calculates its root if appropriate, otherwise it
+// returns an error.
+func (f SuffixProofFactory) SuffixProofOf(ctx serde.Context, data []byte) (hashtree.SuffixProof, error) {
+	format := suffixFormats.Get(ctx.GetFormat())
+
+	msg, err := format.Decode(ctx, data)
+	if err != nil {
+		return nil, xerrors.Errorf("format failed: %v", err)
+	}
+
+	proof, ok := msg.(SuffixProof)
+	if !ok {
+		return nil, xerrors.Errorf("invalid suffix proof '%T'", msg)
+	}
+
+	proof.root, err = proof.computeRoot(f.hashFactory)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to compute root: %v", err)
+	}
+
+	return proof, nil
+}
+
+// hasSuffix returns true if the last bits of the key are the same as the
+// suffix.
+func hasSuffix(key, suffix *big.Int, bits uint16) bool {
+	for i := 0; i < int(bits); i++ {
+		if key.Bit(i) != suffix.Bit(i) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,157 @@
+package binprefix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/crypto"
+	"go.dedis.ch/dela/internal/testing/fake"
+)
+
+func TestSuffixProof_GetSuffix(t *testing.T) {
+	proof := newSuffixProof([]byte{}, []byte("A"), 4)
+
+	suffix, bits := proof.GetSuffix()
+	require.Equal(t, []byte("A"), suffix)
+	require.Equal(t, uint16(4), bits)
+}
+
+func TestSuffixProof_ForEach(t *testing.T) {
+	proof := newSuffixProof([]byte{}, []byte{0b01}, 2)
+	proof.nodes = []suffixNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte{0b101}, value: []byte("A")},
+		{typ: leafNodeType, key: []byte{0b111}, value: []byte("B")},
+		{typ: emptyNodeType},
+	}
+
+	values := map[string][]byte{}
+	err := proof.ForEach(func(key, value []byte) error {
+		values[string(key)] = value
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{string([]byte{0b101}): []byte("A")}, values)
+
+	err = proof.ForEach(func(key, value []byte) error {
+		return fake.GetError()
+	})
+	require.EqualError(t, err, fake.GetError().Error())
+}
+
+func TestSuffixProof_GetRoot(t *testing.T) {
+	proof := newSuffixProof([]byte{}, nil, 0)
+
+	require.Nil(t, proof.GetRoot())
+
+	proof.root = []byte("root")
+	require.Equal(t, []byte("root"), proof.GetRoot())
+}
+
+func TestSuffixProof_ComputeRoot(t *testing.T) {
+	proof := newSuffixProof([]byte{1}, []byte{1}, 8)
+	proof.interiors = [][]byte{{2}}
+	proof.nodes = []suffixNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte{1}, value: []byte("A")},
+		{typ: emptyNodeType},
+	}
+
+	root, err := proof.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.NoError(t, err)
+	require.NotEmpty(t, root)
+
+	_, err = proof.computeRoot(fake.NewHashFactory(fake.NewBadHash()))
+	require.EqualError(t, err, fake.Err("while preparing: leaf node failed"))
+
+	proof.bits = 0
+	_, err = proof.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.EqualError(t, err, "path too long: 1 > 0")
+
+	proof.bits = 8
+	proof.nodes = append(proof.nodes, suffixNode{typ: emptyNodeType})
+	_, err = proof.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.EqualError(t, err, "1 unused nodes")
+
+	proof.nodes = proof.nodes[:2]
+	_, err = proof.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.EqualError(t, err, "while preparing: missing node at depth 2")
+
+	proof.nodes = []suffixNode{{typ: diskNodeType}}
+	_, err = proof.computeRoot(fake.NewHashFactory(&fake.Hash{}))
+	require.EqualError(t, err, "while preparing: unknown node type 3")
+}
+
+func TestSuffixProof_Serialize(t *testing.T) {
+	proof := newSuffixProof([]byte{1}, []byte{2}, 8)
+	proof.interiors = [][]byte{{3}}
+	proof.nodes = []suffixNode{
+		{typ: interiorNodeType},
+		{typ: leafNodeType, key: []byte("A"), value: []byte("B")},
+		{typ: emptyNodeType},
+	}
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+	require.Equal(t,
+		`{"Nonce":"AQ==","Suffix":"Ag==","Bits":8,"Interiors":["Aw=="],`+
+			`"Nodes":[{"Interior":true},{"Leaf":{"Key":"QQ==","Value":"Qg=="}},{}]}`,
+		string(data))
+
+	_, err = proof.Serialize(fake.NewBadContext())
+	require.EqualError(t, err, fake.Err("failed to encode"))
+}
+
+func TestSuffixProofFactory_Deserialize(t *testing.T) {
+	tree := NewTree(Nonce{1})
+
+	for _, key := range []string{"A", "Q", "a"} {
+		err := tree.Insert([]byte(key), []byte(key), &fakeBucket{})
+		require.NoError(t, err)
+	}
+
+	err := tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{})
+	require.NoError(t, err)
+
+	fac := NewSuffixProofFactory()
+
+	proof := newSuffixProof(tree.nonce[:], []byte{1}, 4)
+
+	err = tree.GetSuffix(&proof, &fakeBucket{})
+	require.NoError(t, err)
+
+	data, err := proof.Serialize(testCtx)
+	require.NoError(t, err)
+
+	msg, err := fac.Deserialize(testCtx, data)
+	require.NoError(t, err)
+	require.Equal(t, proof, msg)
+	require.Equal(t, tree.root.GetHash(), msg.(SuffixProof).GetRoot())
+
+	// A proof that hides a leaf of the suffix does not match the root.
+	for i, node := range proof.nodes {
+		if node.typ == leafNodeType {
+			proof.nodes[i] = suffixNode{typ: emptyNodeType}
+			break
+		}
+	}
+
+	root, err := proof.computeRoot(crypto.NewSha256Factory())
+	require.NoError(t, err)
+	require.NotEqual(t, tree.root.GetHash(), root)
+
+	_, err = fac.Deserialize(fake.NewBadContext(), nil)
+	require.EqualError(t, err, fake.Err("format failed"))
+
+	_, err = fac.Deserialize(fake.NewContext(), nil)
+	require.EqualError(t, err, "invalid suffix proof 'fake.Message'")
+
+	_, err = fac.Deserialize(testCtx, []byte(`{"Nodes":[{"Interior":true,"Leaf":{}}]}`))
+	require.EqualError(t, err,
+		"format failed: node 0 is both interior and leaf")
+
+	fac.hashFactory = fake.NewHashFactory(fake.NewBadHash())
+	_, err = fac.Deserialize(testCtx, []byte(`{"Nodes":[{}]}`))
+	require.EqualError(t, err,
+		fake.Err("failed to compute root: while preparing: empty node failed"))
+}
@@ -19,6 +19,10 @@ import (
 
 func init() {
 	nodeFormats.Register(serde.FormatJSON, nodeFormat{})
+	pathFormats.Register(serde.FormatJSON, pathFormat{})
+	suffixFormats.Register(serde.FormatJSON, suffixFormat{})
+	prefixFormats.Register(serde.FormatJSON, prefixFormat{})
+	multiPathFormats.Register(serde.FormatJSON, multiPathFormat{})
 }
 
 // Nonce is the type of the tree nonce.
@@ -41,7 +45,13 @@ const (
 	diskNodeType
 )
 
-var nodeFormats = registry.NewSimpleRegistry()
+var (
+	nodeFormats      = registry.NewSimpleRegistry()
+	pathFormats      = registry.NewSimpleRegistry()
+	suffixFormats    = registry.NewSimpleRegistry()
+	prefixFormats    = registry.NewSimpleRegistry()
+	multiPathFormats = registry.NewSimpleRegistry()
+)
 
 // TreeNode is the interface for the different types of nodes that a Merkle tree
 // could have.
@@ -79,6 +89,8 @@ type Tree struct {
 	root     TreeNode
 	context  serde.Context
 	factory  serde.Factory
+	// indexed is true when the tree maintains the ordered index of the keys.
+	indexed bool
 }
 
 // NewTree creates a new empty tree.
@@ -93,12 +105,14 @@ func NewTree(nonce Nonce) *Tree {
 	}
 }
 
-// Len returns the number of leaves in the tree.
+// Len returns the number of leaves in the tree, without the entries of the
+// ordered index.
 func (t *Tree) Len() int {
 	counter := 0
 
 	t.root.Visit(func(n TreeNode) error {
-		if n.GetType() == leafNodeType {
+		leaf, ok := n.(*LeafNode)
+		if ok && !(t.indexed && isIndexKey(leaf.key)) {
 			counter++
 		}
 
@@ -198,33 +212,54 @@ func (t *Tree) Search(key []byte, path *Path, b kv.Bucket) ([]byte, error) {
 	return value, nil
 }
 
-// Insert inserts the key in the tree.
+// Insert inserts the key in the tree, and in the ordered index if it is
+// enabled.
 func (t *Tree) Insert(key, value []byte, b kv.Bucket) error {
 	if len(key) > t.maxDepth {
 		return xerrors.Errorf("mismatch key length %d > %d", len(key), t.maxDepth)
 	}
 
+	index := makeKey(key)
+
 	var err error
-	t.root, err = t.root.Insert(makeKey(key), value, b)
+	t.root, err = t.root.Insert(index, value, b)
 	if err != nil {
 		return xerrors.Errorf("failed to insert: %v", err)
 	}
 
+	if t.indexed {
+		// The index uses the key of the leaf, which has no leading zeros.
+		t.root, err = t.root.Insert(indexKey(index.Bytes()), value, b)
+		if err != nil {
+			return xerrors.Errorf("failed to index: %v", err)
+		}
+	}
+
 	return nil
 }
 
-// Delete removes a key from the tree.
+// Delete removes a key from the tree, and from the ordered index if it is
+// enabled.
 func (t *Tree) Delete(key []byte, b kv.Bucket) error {
 	if len(key) > t.maxDepth {
 		return xerrors.Errorf("mismatch key length %d > %d", len(key), t.maxDepth)
 	}
 
+	index := makeKey(key)
+
 	var err error
-	t.root, err = t.root.Delete(makeKey(key), b)
+	t.root, err = t.root.Delete(index, b)
 	if err != nil {
 		return xerrors.Errorf("failed to delete: %v", err)
 	}
 
+	if t.indexed {
+		t.root, err = t.root.Delete(indexKey(index.Bytes()), b)
+		if err != nil {
+			return xerrors.Errorf("failed to unindex: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -240,26 +275,271 @@ func (t *Tree) CalculateRoot(fac crypto.HashFactory, b kv.Bucket) error {
 	return nil
 }
 
+// ForEach calls the function with the key and the value of every leaf of the
+// tree, except the entries of the ordered index. Disk nodes are loaded from the
+// bucket but the tree is not modified.
+func (t *Tree) ForEach(fn func(key, value []byte) error, b kv.Bucket) error {
+	return forEach(t.root, new(big.Int), b, fn)
+}
+
+func forEach(node TreeNode, prefix *big.Int, b kv.Bucket, fn func(key, value []byte) error) error {
+	switch n := node.(type) {
+	case *InteriorNode:
+		err := forEach(n.left, new(big.Int).SetBit(prefix, int(n.depth), 0), b, fn)
+		if err != nil {
+			// No wrapping to prevent long error message from recursive calls.
+			return err
+		}
+
+		return forEach(n.right, new(big.Int).SetBit(prefix, int(n.depth), 1), b, fn)
+	case *DiskNode:
+		if b == nil {
+			return xerrors.New("bucket is nil")
+		}
+
+		loaded, err := n.load(prefix, b)
+		if err != nil {
+			return xerrors.Errorf("failed to load node: %v", err)
+		}
+
+		return forEach(loaded, prefix, b, fn)
+	case *LeafNode:
+		if isIndexKey(n.key) {
+			return nil
+		}
+
+		return fn(n.GetKey(), n.GetValue())
+	}
+
+	return nil
+}
+
+// ForSuffix calls the function with the key and the value of every leaf of the
+// tree whose key ends with the suffix of the given length in bits. Disk nodes
+// are loaded from the bucket but the tree is not modified.
+func (t *Tree) ForSuffix(suffix []byte, bits uint16, fn func(key, value []byte) error, b kv.Bucket) error {
+	key := makeKey(suffix)
+
+	node, curr, err := t.descend(key, bits, t.maxDepth*8, nil, b)
+	if err != nil {
+		return xerrors.Errorf("failed to descend: %v", err)
+	}
+
+	return forEach(node, curr, b, func(k, v []byte) error {
+		// The subtree might be a single leaf above the suffix which does not
+		// necessarily end with it.
+		if !hasSuffix(makeKey(k), key, bits) {
+			return nil
+		}
+
+		return fn(k, v)
+	})
+}
+
+// GetSuffix fills the suffix proof with the interior nodes from the root to the
+// suffix, and with every node of the subtree of the suffix.
+func (t *Tree) GetSuffix(proof *SuffixProof, b kv.Bucket) error {
+	node, curr, err := t.descend(makeKey(proof.suffix), proof.bits, t.maxDepth*8, proof, b)
+	if err != nil {
+		return xerrors.Errorf("failed to descend: %v", err)
+	}
+
+	proof.nodes, err = appendSuffix(proof.nodes, node, curr, b)
+	if err != nil {
+		return xerrors.Errorf("failed to read subtree: %v", err)
+	}
+
+	proof.root = t.root.GetHash()
+
+	return nil
+}
+
+// GetPaths fills the proof with the nodes of the paths to its keys. A subtree
+// that none of the keys goes through is replaced by its hash.
+func (t *Tree) GetPaths(proof *MultiPath, b kv.Bucket) error {
+	keys := make([]*big.Int, len(proof.keys))
+	for i, key := range proof.keys {
+		if len(key) > t.maxDepth {
+			return xerrors.Errorf("mismatch key length %d > %d", len(key), t.maxDepth)
+		}
+
+		keys[i] = makeKey(key)
+	}
+
+	var err error
+	proof.nodes, err = appendPaths(proof.nodes, t.root, new(big.Int), keys, b)
+	if err != nil {
+		return xerrors.Errorf("failed to read paths: %v", err)
+	}
+
+	root, err := proof.build()
+	if err != nil {
+		return xerrors.Errorf("failed to build: %v", err)
+	}
+
+	proof.values, err = proof.lookup(root)
+	if err != nil {
+		return xerrors.Errorf("failed to lookup: %v", err)
+	}
+
+	proof.root = t.root.GetHash()
+
+	return nil
+}
+
+func appendPaths(nodes []pathNode, node TreeNode, prefix *big.Int,
+	keys []*big.Int, b kv.Bucket) ([]pathNode, error) {
+
+	if len(keys) == 0 && len(node.GetHash()) > 0 {
+		return append(nodes, pathNode{typ: diskNodeType, hash: node.GetHash()}), nil
+	}
+
+	node, err := loadNode(node, prefix, b)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return append(nodes, pathNode{typ: diskNodeType, hash: node.GetHash()}), nil
+	}
+
+	switch n := node.(type) {
+	case *InteriorNode:
+		nodes = append(nodes, pathNode{typ: interiorNodeType})
+
+		var left, right []*big.Int
+		for _, key := range keys {
+			if key.Bit(int(n.depth)) == 0 {
+				left = append(left, key)
+			} else {
+				right = append(right, key)
+			}
+		}
+
+		nodes, err = appendPaths(nodes, n.left, new(big.Int).SetBit(prefix, int(n.depth), 0), left, b)
+		if err != nil {
+			// No wrapping to prevent long error message from recursive calls.
+			return nil, err
+		}
+
+		return appendPaths(nodes, n.right, new(big.Int).SetBit(prefix, int(n.depth), 1), right, b)
+	case *LeafNode:
+		return append(nodes, pathNode{
+			typ:   leafNodeType,
+			key:   n.GetKey(),
+			value: n.GetValue(),
+		}), nil
+	default:
+		return append(nodes, pathNode{typ: emptyNodeType}), nil
+	}
+}
+
+// descend follows the suffix from the root until it reaches the given depth, or
+// a leaf or an empty node above it. It returns the node and its prefix, and
+// fills the proof with the hashes of the siblings if it is defined.
+func (t *Tree) descend(key *big.Int, bits uint16, limit int, proof *SuffixProof,
+	b kv.Bucket) (TreeNode, *big.Int, error) {
+
+	if int(bits) > limit {
+		return nil, nil, xerrors.Errorf("mismatch suffix length %d > %d", bits, limit)
+	}
+
+	node := t.root
+	curr := new(big.Int)
+
+	for {
+		loaded, err := loadNode(node, curr, b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		interior, ok := loaded.(*InteriorNode)
+		if !ok || interior.depth >= bits {
+			return loaded, curr, nil
+		}
+
+		depth := int(interior.depth)
+
+		next, sibling := interior.left, interior.right
+		if key.Bit(depth) == 1 {
+			next, sibling = interior.right, interior.left
+		}
+
+		if proof != nil {
+			sibling, err = loadNode(sibling, new(big.Int).SetBit(curr, depth, 1-key.Bit(depth)), b)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			proof.interiors = append(proof.interiors, sibling.GetHash())
+		}
+
+		node = next
+		curr = new(big.Int).SetBit(curr, depth, key.Bit(depth))
+	}
+}
+
+func appendSuffix(nodes []suffixNode, node TreeNode, prefix *big.Int,
+	b kv.Bucket) ([]suffixNode, error) {
+
+	node, err := loadNode(node, prefix, b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n := node.(type) {
+	case *InteriorNode:
+		nodes = append(nodes, suffixNode{typ: interiorNodeType})
+
+		nodes, err = appendSuffix(nodes, n.left, new(big.Int).SetBit(prefix, int(n.depth), 0), b)
+		if err != nil {
+			// No wrapping to prevent long error message from recursive calls.
+			return nil, err
+		}
+
+		return appendSuffix(nodes, n.right, new(big.Int).SetBit(prefix, int(n.depth), 1), b)
+	case *LeafNode:
+		return append(nodes, suffixNode{
+			typ:   leafNodeType,
+			key:   n.GetKey(),
+			value: n.GetValue(),
+		}), nil
+	default:
+		return append(nodes, suffixNode{typ: emptyNodeType}), nil
+	}
+}
+
+// loadNode returns the node itself, or the node stored in the bucket if it is
+// a disk node.
+func loadNode(node TreeNode, prefix *big.Int, b kv.Bucket) (TreeNode, error) {
+	diskn, ok := node.(*DiskNode)
+	if !ok {
+		return node, nil
+	}
+
+	if b == nil {
+		return nil, xerrors.New("bucket is nil")
+	}
+
+	loaded, err := diskn.load(prefix, b)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to load node: %v", err)
+	}
+
+	return loaded, nil
+}
+
 // Persist visits the whole tree and stores the leaf node in the database and
 // replaces the node with disk nodes. Depending of the parameter, it also stores
-// intermediate nodes on the disk.
+// intermediate nodes on the disk. The nodes are replaced by copies so that the
+// trees sharing them are not modified.
 func (t *Tree) Persist(b kv.Bucket) error {
-	return t.root.Visit(func(n TreeNode) error {
+	err := t.root.Visit(func(n TreeNode) error {
 		switch node := n.(type) {
 		case *InteriorNode:
 			if int(node.depth) > t.memDepth {
 				return t.toDisk(node.depth, node.prefix, node, b, false, true)
 			}
-
-			_, ok := node.left.(*LeafNode)
-			if ok || int(node.depth) == t.memDepth {
-				node.left = NewDiskNode(node.depth+1, node.left.GetHash(), t.context, t.factory)
-			}
-
-			_, ok = node.right.(*LeafNode)
-			if ok || int(node.depth) == t.memDepth {
-				node.right = NewDiskNode(node.depth+1, node.right.GetHash(), t.context, t.factory)
-			}
 		case *LeafNode:
 			return t.toDisk(node.depth, node.key, node, b, true, true)
 		case *EmptyNode:
@@ -270,6 +550,46 @@ func (t *Tree) Persist(b kv.Bucket) error {
 
 		return nil
 	})
+
+	if err != nil {
+		return err
+	}
+
+	t.root, _ = t.detach(t.root)
+
+	return nil
+}
+
+// detach returns the node where the leaves and the children below the memory
+// depth are replaced by disk nodes. A node is copied only if its subtree
+// changes. It returns true if the node has been replaced.
+func (t *Tree) detach(node TreeNode) (TreeNode, bool) {
+	interior, ok := node.(*InteriorNode)
+	if !ok || int(interior.depth) > t.memDepth {
+		return node, false
+	}
+
+	left, leftChanged := t.detachChild(interior, interior.left)
+	right, rightChanged := t.detachChild(interior, interior.right)
+
+	if !leftChanged && !rightChanged {
+		return interior, false
+	}
+
+	next := NewInteriorNodeWithChildren(interior.depth, interior.prefix, interior.hash, left, right)
+
+	return next, true
+}
+
+func (t *Tree) detachChild(parent *InteriorNode, child TreeNode) (TreeNode, bool) {
+	_, isDisk := child.(*DiskNode)
+	_, isLeaf := child.(*LeafNode)
+
+	if !isDisk && (isLeaf || int(parent.depth) == t.memDepth) {
+		return NewDiskNode(parent.depth+1, child.GetHash(), t.context, t.factory), true
+	}
+
+	return t.detach(child)
 }
 
 func (t *Tree) toDisk(depth uint16, prefix *big.Int, node TreeNode, b kv.Bucket, clean, store bool) error {
@@ -292,15 +612,25 @@ func (t *Tree) toDisk(depth uint16, prefix *big.Int, node TreeNode, b kv.Bucket,
 	return nil
 }
 
-// Clone returns a deep copy of the tree.
+// Clone returns a copy of the tree that shares the nodes with the original.
+// The operations create new nodes along the path to the key instead of
+// modifying the existing ones, so that the cost of a copy does not depend on
+// the size of the tree. The nodes are deeply copied only if the root is not
+// calculated, as the hashes would otherwise be set in the shared nodes.
 func (t *Tree) Clone() *Tree {
+	root := t.root
+	if len(root.GetHash()) == 0 {
+		root = root.Clone()
+	}
+
 	return &Tree{
 		nonce:    t.nonce,
 		maxDepth: t.maxDepth,
 		memDepth: t.memDepth,
-		root:     t.root.Clone(),
+		root:     root,
 		context:  t.context,
 		factory:  t.factory,
+		indexed:  t.indexed,
 	}
 }
 
@@ -486,44 +816,44 @@ func (n *InteriorNode) GetPrefix() *big.Int {
 // the correct child.
 func (n *InteriorNode) Search(key *big.Int, path *Path, b kv.Bucket) ([]byte, error) {
 	if key.Bit(int(n.depth)) == 0 {
-		n.right, _ = n.load(n.right, key, 1, b)
-
 		if path != nil {
-			path.interiors = append(path.interiors, n.right.GetHash())
+			sibling, _ := n.load(n.right, key, 1, b)
+			path.interiors = append(path.interiors, sibling.GetHash())
 		}
 
 		return n.left.Search(key, path, b)
 	}
 
-	n.left, _ = n.load(n.left, key, 0, b)
-
 	if path != nil {
-		path.interiors = append(path.interiors, n.left.GetHash())
+		sibling, _ := n.load(n.left, key, 0, b)
+		path.interiors = append(path.interiors, sibling.GetHash())
 	}
 
 	return n.right.Search(key, path, b)
 }
 
-// Insert implements binprefix.TreeNode. It inserts the key/value pair to the
-// right path.
+// Insert implements binprefix.TreeNode. It returns a copy of the node with the
+// key/value pair inserted in the right path.
 func (n *InteriorNode) Insert(key *big.Int, value []byte, b kv.Bucket) (TreeNode, error) {
+	// The hash is reset as the subtree changes.
+	next := NewInteriorNodeWithChildren(n.depth, n.prefix, nil, n.left, n.right)
+
 	var err error
 	if key.Bit(int(n.depth)) == 0 {
-		n.left, err = n.left.Insert(key, value, b)
+		next.left, err = n.left.Insert(key, value, b)
 	} else {
-		n.right, err = n.right.Insert(key, value, b)
+		next.right, err = n.right.Insert(key, value, b)
 	}
 
-	// Reset the hash as the subtree will change and thus invalidate this
-	// current value.
-	n.hash = nil
-
-	return n, err
+	return next, err
 }
 
-// Delete implements binprefix.TreeNode. It deletes the leaf node associated to
-// the key if it exists, otherwise nothing will change.
+// Delete implements binprefix.TreeNode. It returns a copy of the node without
+// the leaf node associated to the key if it exists, otherwise nothing will
+// change.
 func (n *InteriorNode) Delete(key *big.Int, b kv.Bucket) (TreeNode, error) {
+	next := NewInteriorNodeWithChildren(n.depth, n.prefix, nil, n.left, n.right)
+
 	var err error
 
 	// Depending on the path to follow, it will delete the key from the correct
@@ -531,34 +861,34 @@ func (n *InteriorNode) Delete(key *big.Int, b kv.Bucket) (TreeNode, error) {
 	// node so that the type can be compared. Errors are not wrapper to prevent
 	// very long error message.
 	if key.Bit(int(n.depth)) == 0 {
-		n.left, err = n.left.Delete(key, b)
+		next.left, err = n.left.Delete(key, b)
 		if err != nil {
 			return nil, err
 		}
 
-		n.right, err = n.load(n.right, key, 1, b)
+		next.right, err = n.load(n.right, key, 1, b)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		n.right, err = n.right.Delete(key, b)
+		next.right, err = n.right.Delete(key, b)
 		if err != nil {
 			return nil, err
 		}
 
-		n.left, err = n.load(n.left, key, 0, b)
+		next.left, err = n.load(n.left, key, 0, b)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if n.left.GetType() == emptyNodeType && n.right.GetType() == emptyNodeType {
+	if next.left.GetType() == emptyNodeType && next.right.GetType() == emptyNodeType {
 		// If an interior node points to two empty nodes, it is itself an empty
 		// one.
 		return NewEmptyNode(n.depth, n.prefix), nil
 	}
 
-	return n, nil
+	return next, nil
 }
 
 func (n *InteriorNode) load(node TreeNode, key *big.Int, bit uint, b kv.Bucket) (TreeNode, error) {
@@ -709,23 +1039,30 @@ func (n *LeafNode) GetType() byte {
 // Search implements binprefix.TreeNode. It returns the value if the key
 // matches.
 func (n *LeafNode) Search(key *big.Int, path *Path, b kv.Bucket) ([]byte, error) {
-	if path != nil {
-		path.value = n.value
-	}
-
 	if n.key.Cmp(key) == 0 {
+		if path != nil {
+			path.value = n.value
+		}
+
 		return n.value, nil
 	}
 
+	if path != nil {
+		// The leaf of another key is required to prove the absence of the
+		// key.
+		path.leafKey = n.GetKey()
+		path.leafValue = n.value
+	}
+
 	return nil, nil
 }
 
-// Insert implements binprefix.TreeNode. It replaces the leaf node by an
-// interior node that contains both the current pair and the new one to insert.
+// Insert implements binprefix.TreeNode. It replaces the leaf node by a new one
+// with the value if the key matches, or by an interior node that contains both
+// the current pair and the new one to insert.
 func (n *LeafNode) Insert(key *big.Int, value []byte, b kv.Bucket) (TreeNode, error) {
 	if n.key.Cmp(key) == 0 {
-		n.value = value
-		return n, nil
+		return NewLeafNode(n.depth, n.key, value), nil
 	}
 
 	prefix := new(big.Int)
@@ -733,12 +1070,12 @@ func (n *LeafNode) Insert(key *big.Int, value []byte, b kv.Bucket) (TreeNode, er
 		prefix.SetBit(prefix, i, key.Bit(i))
 	}
 
-	node := NewInteriorNode(n.depth, prefix)
+	var node TreeNode = NewInteriorNode(n.depth, prefix)
 
 	// As the node is freshly created, the operations are in-memory and thus it
 	// doesn't trigger any error.
-	node.Insert(n.key, n.value, b)
-	node.Insert(key, value, b)
+	node, _ = node.Insert(n.key, n.value, b)
+	node, _ = node.Insert(key, value, b)
 
 	return node, nil
 }
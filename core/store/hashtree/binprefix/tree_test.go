@@ -15,6 +15,14 @@ import (
 
 func init() {
 	nodeFormats.Register(fake.BadFormat, fake.NewBadFormat())
+	pathFormats.Register(fake.BadFormat, fake.NewBadFormat())
+	pathFormats.Register(fake.GoodFormat, fake.Format{Msg: fake.Message{}})
+	suffixFormats.Register(fake.BadFormat, fake.NewBadFormat())
+	suffixFormats.Register(fake.GoodFormat, fake.Format{Msg: fake.Message{}})
+	multiPathFormats.Register(fake.BadFormat, fake.NewBadFormat())
+	multiPathFormats.Register(fake.GoodFormat, fake.Format{Msg: fake.Message{}})
+	prefixFormats.Register(fake.BadFormat, fake.NewBadFormat())
+	prefixFormats.Register(fake.GoodFormat, fake.Format{Msg: fake.Message{}})
 }
 
 func TestTree_Len(t *testing.T) {
@@ -182,6 +190,37 @@ func TestTree_Persist(t *testing.T) {
 		fake.Err("visiting empty: failed to clean subtree"))
 }
 
+func TestTree_ForEach(t *testing.T) {
+	bucket := &fakeBucket{}
+
+	tree := NewTree(Nonce{})
+
+	for i := 1; i <= 10; i++ {
+		err := tree.Insert([]byte{byte(i)}, []byte{byte(i)}, bucket)
+		require.NoError(t, err)
+	}
+
+	// Leaves are loaded from the disk after the tree is persisted.
+	err := tree.Persist(bucket)
+	require.NoError(t, err)
+
+	pairs := map[byte]byte{}
+	err = tree.ForEach(func(key, value []byte) error {
+		pairs[key[0]] = value[0]
+		return nil
+	}, bucket)
+	require.NoError(t, err)
+	require.Len(t, pairs, 10)
+	require.Equal(t, byte(5), pairs[5])
+
+	err = tree.ForEach(nil, nil)
+	require.EqualError(t, err, "bucket is nil")
+
+	err = tree.ForEach(nil, &fakeBucket{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to load node: ")
+}
+
 func TestTree_Clone(t *testing.T) {
 	tree := NewTree(Nonce{})
 
@@ -198,6 +237,23 @@ func TestTree_Clone(t *testing.T) {
 	require.NoError(t, tree.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{}))
 	require.NoError(t, clone.CalculateRoot(crypto.NewSha256Factory(), &fakeBucket{}))
 	require.Equal(t, tree.root.GetHash(), clone.root.GetHash())
+
+	// The trees share the nodes once the root is calculated, but the changes
+	// of one are not visible in the other.
+	clone = tree.Clone()
+	require.Same(t, tree.root, clone.root)
+
+	size := tree.Len()
+
+	bucket := &fakeBucket{}
+	require.NoError(t, clone.Insert([]byte("A"), []byte("B"), bucket))
+	require.NoError(t, tree.Persist(bucket))
+
+	value, err := tree.Search([]byte("A"), nil, bucket)
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	require.Equal(t, size+1, clone.Len())
 }
 
 func TestEmptyNode_GetHash(t *testing.T) {
@@ -330,11 +386,16 @@ func TestInteriorNode_Insert(t *testing.T) {
 
 	next, err := node.Insert(big.NewInt(0), []byte("ping"), nil)
 	require.NoError(t, err)
-	require.Same(t, node, next)
+	require.NotSame(t, node, next)
+	require.IsType(t, (*LeafNode)(nil), next.(*InteriorNode).left)
+
+	// The node is copied so that the trees sharing it do not change.
+	require.IsType(t, (*EmptyNode)(nil), node.left)
 
-	next, err = node.Insert(big.NewInt(1), []byte("pong"), nil)
+	next, err = next.Insert(big.NewInt(1), []byte("pong"), nil)
 	require.NoError(t, err)
-	require.Same(t, node, next)
+	require.IsType(t, (*LeafNode)(nil), next.(*InteriorNode).left)
+	require.IsType(t, (*LeafNode)(nil), next.(*InteriorNode).right)
 }
 
 func TestInteriorNode_Delete(t *testing.T) {
@@ -344,9 +405,11 @@ func TestInteriorNode_Delete(t *testing.T) {
 
 	next, err := node.Delete(big.NewInt(0), nil)
 	require.NoError(t, err)
-	require.Same(t, node, next)
+	require.NotSame(t, node, next)
+	require.IsType(t, (*EmptyNode)(nil), next.(*InteriorNode).left)
+	require.IsType(t, (*LeafNode)(nil), node.left)
 
-	next, err = node.Delete(big.NewInt(1), nil)
+	next, err = next.Delete(big.NewInt(1), nil)
 	require.NoError(t, err)
 	require.IsType(t, (*EmptyNode)(nil), next)
 
@@ -459,15 +522,26 @@ func TestLeafNode_Search(t *testing.T) {
 	value, err = node.Search(makeKey([]byte("pong")), nil, nil)
 	require.NoError(t, err)
 	require.Nil(t, value)
+
+	path = newPath([]byte{}, []byte("pong"))
+	value, err = node.Search(makeKey([]byte("pong")), &path, nil)
+	require.NoError(t, err)
+	require.Nil(t, value)
+	require.Nil(t, path.value)
+	require.Equal(t, []byte("ping"), path.leafKey)
+	require.Equal(t, []byte("pong"), path.leafValue)
 }
 
 func TestLeafNode_Insert(t *testing.T) {
 	node := NewLeafNode(0, makeKey([]byte("ping")), []byte("pong"))
+	node.hash = []byte{1}
 
 	next, err := node.Insert(makeKey([]byte("ping")), []byte("abc"), nil)
 	require.NoError(t, err)
-	require.Same(t, node, next)
+	require.NotSame(t, node, next)
 	require.Equal(t, []byte("abc"), next.(*LeafNode).value)
+	require.Nil(t, next.(*LeafNode).hash)
+	require.Equal(t, []byte("pong"), node.value)
 
 	node = NewLeafNode(0, makeKey([]byte{0}), []byte{0xaa})
 	next, err = node.Insert(makeKey([]byte{1}), []byte{0xbb}, nil)
@@ -587,7 +661,7 @@ func (tx fakeTx) GetBucket(name []byte) kv.Bucket {
 }
 
 func (tx fakeTx) GetBucketOrCreate(name []byte) (kv.Bucket, error) {
-	return nil, nil
+	return tx.bucket, nil
 }
 
 type fakeDB struct {
@@ -7,11 +7,16 @@
 //
 package hashtree
 
-import "go.dedis.ch/dela/core/store"
+import (
+	"go.dedis.ch/dela/core/store"
+	"go.dedis.ch/dela/serde"
+)
 
 // Path is a path along the tree to a key and its value, or none if the key is
 // not set.
 type Path interface {
+	serde.Message
+
 	// GetKey returns the key of the path.
 	GetKey() []byte
 
@@ -23,6 +28,15 @@ type Path interface {
 	GetRoot() []byte
 }
 
+// PathFactory is the factory to deserialize paths.
+type PathFactory interface {
+	serde.Factory
+
+	// PathOf returns the path of the data if appropriate, otherwise it returns
+	// an error. The root of the path is calculated from the data.
+	PathOf(ctx serde.Context, data []byte) (Path, error)
+}
+
 // Tree is a specialization of a store. It uses the Merkle tree structure to
 // create a root hash that represents the state of the tree and can be used to
 // create proof of inclusion/proof of absence.
@@ -41,6 +55,138 @@ type Tree interface {
 	Stage(func(store.Snapshot) error) (StagingTree, error)
 }
 
+// HistoricalTree is a tree that retains its previous versions so that they can
+// be read after new modifications have been committed.
+type HistoricalTree interface {
+	Tree
+
+	// GetVersion returns a read-only tree of the version identified by its
+	// root, or an error if the version is not retained.
+	GetVersion(root []byte) (Tree, error)
+}
+
+// IterableTree is a tree that can enumerate its key/value pairs, for instance
+// to rebuild the same tree somewhere else.
+type IterableTree interface {
+	Tree
+
+	// ForEach calls the function with every key/value pair of the tree. It
+	// stops and returns the error if the function fails.
+	ForEach(fn func(key, value []byte) error) error
+}
+
+// MultiPath is a proof for several keys at once, which contains the paths to
+// every key without repeating the nodes they share.
+type MultiPath interface {
+	serde.Message
+
+	// GetKeys returns the keys of the proof.
+	GetKeys() [][]byte
+
+	// GetValues returns the values of the keys in the same order, or nil for
+	// the keys that are not set.
+	GetValues() [][]byte
+
+	// GetRoot returns the store root calculated from the paths. It should
+	// match the tree root for the proof to be valid.
+	GetRoot() []byte
+}
+
+// MultiPathFactory is the factory to deserialize multi-key proofs.
+type MultiPathFactory interface {
+	serde.Factory
+
+	// MultiPathOf returns the proof of the data if appropriate, otherwise it
+	// returns an error. The root of the proof is calculated from the data.
+	MultiPathOf(ctx serde.Context, data []byte) (MultiPath, error)
+}
+
+// MultiPathTree is a tree that can prove several keys with a single proof.
+type MultiPathTree interface {
+	Tree
+
+	// GetPaths returns a proof of inclusion or absence for every key.
+	GetPaths(keys ...[]byte) (MultiPath, error)
+}
+
+// SuffixProof is a proof that a list of key/value pairs is the complete set of
+// keys of the tree ending with the same bits.
+type SuffixProof interface {
+	serde.Message
+
+	// GetSuffix returns the suffix of the keys and its length in bits.
+	GetSuffix() ([]byte, uint16)
+
+	// ForEach calls the function with every key/value pair of the suffix in
+	// the order of the tree. It stops and returns the error if the function
+	// fails.
+	ForEach(fn func(key, value []byte) error) error
+
+	// GetRoot returns the store root calculated from the pairs. It should match
+	// the tree root for the list to be complete.
+	GetRoot() []byte
+}
+
+// SuffixProofFactory is the factory to deserialize suffix proofs.
+type SuffixProofFactory interface {
+	serde.Factory
+
+	// SuffixProofOf returns the suffix proof of the data if appropriate,
+	// otherwise it returns an error. The root of the proof is calculated from
+	// the data.
+	SuffixProofOf(ctx serde.Context, data []byte) (SuffixProof, error)
+}
+
+// PrefixProof is a proof that a list of key/value pairs is the complete set of
+// keys of the tree starting with the same bytes.
+type PrefixProof interface {
+	serde.Message
+
+	// GetPrefix returns the prefix of the keys.
+	GetPrefix() []byte
+
+	// ForEach calls the function with every key/value pair of the prefix in
+	// the lexicographic order of the keys. It stops and returns the error if
+	// the function fails.
+	ForEach(fn func(key, value []byte) error) error
+
+	// GetRoot returns the store root calculated from the pairs. It should match
+	// the tree root for the list to be complete.
+	GetRoot() []byte
+}
+
+// PrefixProofFactory is the factory to deserialize prefix proofs.
+type PrefixProofFactory interface {
+	serde.Factory
+
+	// PrefixProofOf returns the prefix proof of the data if appropriate,
+	// otherwise it returns an error. The root of the proof is calculated from
+	// the data.
+	PrefixProofOf(ctx serde.Context, data []byte) (PrefixProof, error)
+}
+
+// RangeTree is a tree that can enumerate its key/value pairs in order, and
+// prove that the keys starting with the same bytes are complete. As the tree
+// branches on the last bits of the keys first, it can also enumerate the keys
+// ending with the same bits, and prove that the enumeration is complete.
+type RangeTree interface {
+	Tree
+	store.RangeReadable
+
+	// ForSuffix calls the function with every key/value pair ending with the
+	// suffix in the order of the tree. It stops and returns the error if the
+	// function fails.
+	ForSuffix(suffix []byte, bits uint16, fn func(key, value []byte) error) error
+
+	// GetSuffix returns the key/value pairs ending with the suffix with a
+	// proof that no other pair of the tree ends with it.
+	GetSuffix(suffix []byte, bits uint16) (SuffixProof, error)
+
+	// GetPrefix returns the key/value pairs starting with the prefix with a
+	// proof that no other pair of the tree starts with it.
+	GetPrefix(prefix []byte) (PrefixProof, error)
+}
+
 // StagingTree is a tree that has been modified in-memory but is yet to be
 // committed to the disk.
 type StagingTree interface {
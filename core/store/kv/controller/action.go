@@ -0,0 +1,166 @@
+// This file contains the implementation of the actions to maintain the
+// database of a node.
+
+package controller
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"go.dedis.ch/dela/cli/node"
+	"go.dedis.ch/dela/core/store/kv"
+	"golang.org/x/xerrors"
+)
+
+// BackupAction is an action to write a copy of the database while the node is
+// running.
+//
+// - implements node.ActionTemplate
+type backupAction struct{}
+
+// Execute implements node.ActionTemplate. It writes the backup in a temporary
+// file that is renamed only when it is complete.
+func (backupAction) Execute(ctx node.Context) error {
+	var db kv.DB
+	err := ctx.Injector.Resolve(&db)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	bdb, ok := db.(kv.BackupDB)
+	if !ok {
+		return xerrors.Errorf("database '%T' does not support backups", db)
+	}
+
+	out := ctx.Flags.Path("out")
+	tmp := out + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return xerrors.Errorf("failed to create file: %v", err)
+	}
+
+	n, err := bdb.Backup(file)
+	file.Close()
+
+	if err != nil {
+		os.Remove(tmp)
+		return xerrors.Errorf("failed to backup: %v", err)
+	}
+
+	err = os.Rename(tmp, out)
+	if err != nil {
+		return xerrors.Errorf("failed to rename: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "backup of %d bytes written to %s\n", n, out)
+
+	return nil
+}
+
+// RestoreAction is an action to verify a backup and to restore it when the
+// node restarts, as the database cannot be replaced while it is used.
+//
+// - implements node.ActionTemplate
+type restoreAction struct{}
+
+// Execute implements node.ActionTemplate. It copies the backup next to the
+// database and verifies it with the injected verifier. The copy is kept only
+// if it is valid.
+func (restoreAction) Execute(ctx node.Context) error {
+	var verifier BackupVerifier
+	err := ctx.Injector.Resolve(&verifier)
+	if err != nil {
+		return xerrors.Errorf("injector: %v", err)
+	}
+
+	staged := filepath.Join(ctx.Flags.Path("config"), restoreFile)
+	tmp := staged + ".tmp"
+
+	err = copyFile(tmp, ctx.Flags.Path("in"))
+	if err != nil {
+		return xerrors.Errorf("failed to copy: %v", err)
+	}
+
+	desc, err := verifyFile(tmp, verifier)
+	if err != nil {
+		os.Remove(tmp)
+		return xerrors.Errorf("invalid backup: %v", err)
+	}
+
+	err = os.Rename(tmp, staged)
+	if err != nil {
+		return xerrors.Errorf("failed to rename: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "backup verified: %s\n", desc)
+	fmt.Fprintln(ctx.Out, "the database will be restored when the node restarts")
+
+	return nil
+}
+
+// CompactAction is an action to request the compaction of the database. The
+// database file is rewritten without its free pages when the node restarts, as
+// it cannot be replaced while it is used.
+//
+// - implements node.ActionTemplate
+type compactAction struct{}
+
+// Execute implements node.ActionTemplate. It requests the compaction and
+// prints the current size of the database.
+func (compactAction) Execute(ctx node.Context) error {
+	dir := ctx.Flags.Path("config")
+
+	stat, err := os.Stat(filepath.Join(dir, dbFile))
+	if err != nil {
+		return xerrors.Errorf("database file: %v", err)
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, compactFile), nil, 0644)
+	if err != nil {
+		return xerrors.Errorf("failed to request: %v", err)
+	}
+
+	fmt.Fprintf(ctx.Out, "the database of %d bytes will be compacted when "+
+		"the node restarts\n", stat.Size())
+
+	return nil
+}
+
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return xerrors.Errorf("failed to open: %v", err)
+	}
+
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return xerrors.Errorf("failed to create: %v", err)
+	}
+
+	_, err = io.Copy(out, in)
+	out.Close()
+
+	if err != nil {
+		os.Remove(dst)
+		return xerrors.Errorf("failed to write: %v", err)
+	}
+
+	return nil
+}
+
+func verifyFile(path string, verifier BackupVerifier) (string, error) {
+	db, err := kv.New(path)
+	if err != nil {
+		return "", xerrors.Errorf("failed to open: %v", err)
+	}
+
+	defer db.Close()
+
+	return verifier.VerifyBackup(db)
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/cli/node"
+	"go.dedis.ch/dela/core/store/kv"
+	"golang.org/x/xerrors"
+)
+
+func TestBackupAction_Execute(t *testing.T) {
+	dir, clean := makeDir(t)
+	defer clean()
+
+	db := makeDB(t, dir)
+	defer db.Close()
+
+	out := new(bytes.Buffer)
+	req := node.Context{
+		Out:      out,
+		Injector: node.NewInjector(),
+		Flags:    node.FlagSet{"out": filepath.Join(dir, "backup.db")},
+	}
+
+	req.Injector.Inject(db)
+
+	err := backupAction{}.Execute(req)
+	require.NoError(t, err)
+
+	stat, err := os.Stat(filepath.Join(dir, "backup.db"))
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("backup of %d bytes written to %s\n",
+		stat.Size(), filepath.Join(dir, "backup.db")), out.String())
+
+	req.Flags = node.FlagSet{"out": filepath.Join(dir, "unknown", "backup.db")}
+	err = backupAction{}.Execute(req)
+	require.Error(t, err)
+	require.Regexp(t, "^failed to create file: ", err.Error())
+
+	req.Injector = node.NewInjector()
+	req.Injector.Inject(kv.NewInMemory())
+
+	err = backupAction{}.Execute(req)
+	require.EqualError(t, err, "database '*kv.memoryDB' does not support backups")
+
+	req.Injector = node.NewInjector()
+	err = backupAction{}.Execute(req)
+	require.EqualError(t, err,
+		"injector: couldn't find dependency for 'kv.DB'")
+}
+
+func TestRestoreAction_Execute(t *testing.T) {
+	dir, clean := makeDir(t)
+	defer clean()
+
+	db := makeDB(t, dir)
+
+	file, err := os.Create(filepath.Join(dir, "backup.db"))
+	require.NoError(t, err)
+
+	_, err = db.(kv.BackupDB).Backup(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, db.Close())
+
+	out := new(bytes.Buffer)
+	req := node.Context{
+		Out:      out,
+		Injector: node.NewInjector(),
+		Flags: node.FlagSet{
+			"config": dir,
+			"in":     filepath.Join(dir, "backup.db"),
+		},
+	}
+
+	req.Injector.Inject(fakeVerifier{})
+
+	err = restoreAction{}.Execute(req)
+	require.NoError(t, err)
+	require.Equal(t, "backup verified: 1 bucket(s)\n"+
+		"the database will be restored when the node restarts\n", out.String())
+	require.FileExists(t, filepath.Join(dir, restoreFile))
+
+	req.Injector = node.NewInjector()
+	req.Injector.Inject(fakeVerifier{err: xerrors.New("oops")})
+
+	err = restoreAction{}.Execute(req)
+	require.EqualError(t, err, "invalid backup: oops")
+	require.NoFileExists(t, filepath.Join(dir, restoreFile+".tmp"))
+
+	req.Flags = node.FlagSet{"config": dir, "in": filepath.Join(dir, "unknown.db")}
+	err = restoreAction{}.Execute(req)
+	require.Error(t, err)
+	require.Regexp(t, "^failed to copy: failed to open: ", err.Error())
+
+	req.Injector = node.NewInjector()
+	err = restoreAction{}.Execute(req)
+	require.EqualError(t, err,
+		"injector: couldn't find dependency for 'controller.BackupVerifier'")
+}
+
+func TestCompactAction_Execute(t *testing.T) {
+	dir, clean := makeDir(t)
+	defer clean()
+
+	out := new(bytes.Buffer)
+	req := node.Context{
+		Out:   out,
+		Flags: node.FlagSet{"config": dir},
+	}
+
+	err := compactAction{}.Execute(req)
+	require.Error(t, err)
+	require.Regexp(t, "^database file: ", err.Error())
+
+	require.NoError(t, makeDB(t, dir).Close())
+
+	stat, err := os.Stat(filepath.Join(dir, dbFile))
+	require.NoError(t, err)
+
+	err = compactAction{}.Execute(req)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("the database of %d bytes will be compacted "+
+		"when the node restarts\n", stat.Size()), out.String())
+	require.FileExists(t, filepath.Join(dir, compactFile))
+}
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+func makeDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-kv-controller")
+	require.NoError(t, err)
+
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func makeDB(t *testing.T, dir string) kv.DB {
+	db, err := kv.New(filepath.Join(dir, dbFile))
+	require.NoError(t, err)
+
+	err = db.Update(func(txn kv.WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Set([]byte("ping"), []byte("pong"))
+	})
+	require.NoError(t, err)
+
+	return db
+}
+
+type fakeVerifier struct {
+	err error
+}
+
+func (v fakeVerifier) VerifyBackup(db kv.DB) (string, error) {
+	if v.err != nil {
+		return "", v.err
+	}
+
+	count := 0
+	err := db.View(func(txn kv.ReadableTx) error {
+		if txn.GetBucket([]byte("bucket")) != nil {
+			count++
+		}
+
+		return nil
+	})
+
+	return fmt.Sprintf("%d bucket(s)", count), err
+}
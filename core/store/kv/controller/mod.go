@@ -5,6 +5,7 @@
 package controller
 
 import (
+	"os"
 	"path/filepath"
 
 	"go.dedis.ch/dela/cli"
@@ -13,6 +14,27 @@ import (
 	"golang.org/x/xerrors"
 )
 
+const (
+	// dbFile is the name of the database file in the config folder.
+	dbFile = "dela.db"
+
+	// restoreFile is the name of a verified backup that replaces the database
+	// when the node starts.
+	restoreFile = dbFile + ".restore"
+
+	// compactFile is the name of the file that requests the compaction of the
+	// database when the node starts.
+	compactFile = dbFile + ".compact"
+)
+
+// BackupVerifier is the interface that a component storing its data in the
+// database can inject, so that a backup is checked before it is restored.
+type BackupVerifier interface {
+	// VerifyBackup returns a description of the backup if it is consistent
+	// with the node, otherwise an error.
+	VerifyBackup(db kv.DB) (string, error)
+}
+
 // MinimalController is a CLI controller to inject a key/value database.
 //
 // - implements node.Initializer
@@ -24,15 +46,72 @@ func NewController() node.Initializer {
 	return minimalController{}
 }
 
-// SetCommands implements node.Initializer. It does not register any command.
-func (m minimalController) SetCommands(builder node.Builder) {}
+// SetCommands implements node.Initializer. It registers the flag to select the
+// database engine, and the commands to maintain the database.
+func (m minimalController) SetCommands(builder node.Builder) {
+	builder.SetStartFlags(
+		cli.StringFlag{
+			Name: "db",
+			Usage: "database engine: bolt, or memory for an ephemeral node " +
+				"that loses its state when it stops",
+			Value: "bolt",
+		},
+	)
+
+	cmd := builder.SetCommand("db")
+	cmd.SetDescription("Database administration")
+
+	sub := cmd.SetSubCommand("backup")
+	sub.SetDescription("write a copy of the database while the node is running")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "out",
+			Usage:    "path of the backup file, relative to the node",
+			Required: true,
+		},
+	)
+	sub.SetAction(builder.MakeAction(backupAction{}))
+
+	sub = cmd.SetSubCommand("restore")
+	sub.SetDescription("verify a backup and restore it when the node restarts")
+	sub.SetFlags(
+		cli.StringFlag{
+			Name:     "in",
+			Usage:    "path of the backup file, relative to the node",
+			Required: true,
+		},
+	)
+	sub.SetAction(builder.MakeAction(restoreAction{}))
+
+	sub = cmd.SetSubCommand("compact")
+	sub.SetDescription("reclaim the free space of the database when the node " +
+		"restarts")
+	sub.SetAction(builder.MakeAction(compactAction{}))
+}
 
 // OnStart implements node.Initializer. It opens the database in a file using
-// the config path as the base.
+// the config path as the base, or in memory if requested. A restore or a
+// compaction that has been requested is applied beforehand.
 func (m minimalController) OnStart(flags cli.Flags, inj node.Injector) error {
-	db, err := kv.New(filepath.Join(flags.String("config"), "dela.db"))
-	if err != nil {
-		return xerrors.Errorf("db: %v", err)
+	var db kv.DB
+
+	switch flags.String("db") {
+	case "", "bolt":
+		dir := flags.String("config")
+
+		err := prepareFile(dir)
+		if err != nil {
+			return xerrors.Errorf("db: %v", err)
+		}
+
+		db, err = kv.New(filepath.Join(dir, dbFile))
+		if err != nil {
+			return xerrors.Errorf("db: %v", err)
+		}
+	case "memory":
+		db = kv.NewInMemory()
+	default:
+		return xerrors.Errorf("unknown database engine '%s'", flags.String("db"))
 	}
 
 	inj.Inject(db)
@@ -55,3 +134,56 @@ func (m minimalController) OnStop(inj node.Injector) error {
 
 	return nil
 }
+
+// prepareFile replaces the database file with the backup to restore if any,
+// and then compacts it if requested.
+func prepareFile(dir string) error {
+	path := filepath.Join(dir, dbFile)
+
+	_, err := os.Stat(filepath.Join(dir, restoreFile))
+	if err == nil {
+		err = os.Rename(filepath.Join(dir, restoreFile), path)
+		if err != nil {
+			return xerrors.Errorf("failed to restore: %v", err)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(dir, compactFile))
+	if err != nil {
+		return nil
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		err = compact(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Remove(filepath.Join(dir, compactFile))
+	if err != nil {
+		return xerrors.Errorf("failed to clean: %v", err)
+	}
+
+	return nil
+}
+
+func compact(path string) error {
+	tmp := path + ".tmp"
+
+	// A compaction interrupted before might have left a partial copy.
+	os.Remove(tmp)
+
+	err := kv.Compact(tmp, path)
+	if err != nil {
+		return xerrors.Errorf("failed to compact: %v", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		return xerrors.Errorf("failed to replace: %v", err)
+	}
+
+	return nil
+}
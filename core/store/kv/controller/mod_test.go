@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/dela/cli/node"
+	"go.dedis.ch/dela/core/store/kv"
+)
+
+func TestMinimal_SetCommands(t *testing.T) {
+	ctrl := NewController()
+
+	b := node.NewBuilder()
+	ctrl.SetCommands(b)
+}
+
+func TestMinimal_OnStart(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-kv-controller")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	ctrl := NewController()
+
+	inj := node.NewInjector()
+
+	err = ctrl.OnStart(node.FlagSet{"config": dir}, inj)
+	require.NoError(t, err)
+	require.NoError(t, ctrl.OnStop(inj))
+
+	inj = node.NewInjector()
+
+	err = ctrl.OnStart(node.FlagSet{"db": "memory"}, inj)
+	require.NoError(t, err)
+
+	var db kv.DB
+	require.NoError(t, inj.Resolve(&db))
+	require.Equal(t, kv.NewInMemory(), db)
+
+	err = ctrl.OnStart(node.FlagSet{"db": "unknown"}, node.NewInjector())
+	require.EqualError(t, err, "unknown database engine 'unknown'")
+
+	err = ctrl.OnStart(node.FlagSet{"config": "/unknown/path"}, node.NewInjector())
+	require.Error(t, err)
+	require.Regexp(t, "^db: failed to open db: ", err.Error())
+}
+
+func TestMinimal_OnStop(t *testing.T) {
+	ctrl := NewController()
+
+	err := ctrl.OnStop(node.NewInjector())
+	require.EqualError(t, err,
+		"injector: couldn't find dependency for 'kv.DB'")
+}
+
+func TestPrepareFile(t *testing.T) {
+	dir, clean := makeDir(t)
+	defer clean()
+
+	// Nothing to do when no database exists.
+	require.NoError(t, prepareFile(dir))
+
+	db := makeDB(t, dir)
+
+	file, err := os.Create(filepath.Join(dir, restoreFile))
+	require.NoError(t, err)
+
+	_, err = db.(kv.BackupDB).Backup(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	err = db.Update(func(txn kv.WritableTx) error {
+		return txn.GetBucket([]byte("bucket")).Delete([]byte("ping"))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	err = ioutil.WriteFile(filepath.Join(dir, compactFile), nil, 0644)
+	require.NoError(t, err)
+
+	err = prepareFile(dir)
+	require.NoError(t, err)
+	require.NoFileExists(t, filepath.Join(dir, restoreFile))
+	require.NoFileExists(t, filepath.Join(dir, compactFile))
+
+	db, err = kv.New(filepath.Join(dir, dbFile))
+	require.NoError(t, err)
+
+	err = db.View(func(txn kv.ReadableTx) error {
+		require.Equal(t, []byte("pong"), txn.GetBucket([]byte("bucket")).Get([]byte("ping")))
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The request is removed even if no database exists.
+	require.NoError(t, os.Remove(filepath.Join(dir, dbFile)))
+
+	err = ioutil.WriteFile(filepath.Join(dir, compactFile), nil, 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, prepareFile(dir))
+	require.NoFileExists(t, filepath.Join(dir, compactFile))
+
+	err = ioutil.WriteFile(filepath.Join(dir, dbFile), []byte("invalid"), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(filepath.Join(dir, compactFile), nil, 0644)
+	require.NoError(t, err)
+
+	err = prepareFile(dir)
+	require.Error(t, err)
+	require.Regexp(t, "^failed to compact: failed to open source: ", err.Error())
+}
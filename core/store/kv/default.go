@@ -9,14 +9,20 @@ package kv
 
 import (
 	"bytes"
+	"io"
 
 	"go.etcd.io/bbolt"
 	"golang.org/x/xerrors"
 )
 
+// compactTxSize is the number of bytes copied in a single transaction when
+// compacting a database.
+const compactTxSize = 64 * 1024 * 1024
+
 // BoltDB is an adapter of the KV database using bboltdb.
 //
 // - implements kv.DB
+// - implements kv.BackupDB
 type boltDB struct {
 	bolt *bbolt.DB
 }
@@ -57,6 +63,111 @@ func (db boltDB) Close() error {
 	return db.bolt.Close()
 }
 
+// Backup implements kv.BackupDB. It writes a copy of the database file from a
+// read-only transaction, so that the writable transactions can proceed in the
+// meantime.
+func (db boltDB) Backup(w io.Writer) (int64, error) {
+	var n int64
+
+	err := db.bolt.View(func(txn *bbolt.Tx) error {
+		var err error
+		n, err = txn.WriteTo(w)
+
+		return err
+	})
+
+	if err != nil {
+		return n, xerrors.Errorf("failed to write: %v", err)
+	}
+
+	return n, nil
+}
+
+// Compact copies the database file at the source path into a new database file
+// at the destination path. The copy leaves out the free pages of the source so
+// that the space they use is reclaimed. The source must not be opened.
+func Compact(dst, src string) error {
+	from, err := bbolt.Open(src, 0666, &bbolt.Options{ReadOnly: true})
+	if err != nil {
+		return xerrors.Errorf("failed to open source: %v", err)
+	}
+
+	defer from.Close()
+
+	to, err := bbolt.Open(dst, 0666, &bbolt.Options{})
+	if err != nil {
+		return xerrors.Errorf("failed to open destination: %v", err)
+	}
+
+	defer to.Close()
+
+	err = from.View(func(src *bbolt.Tx) error {
+		return src.ForEach(func(name []byte, bucket *bbolt.Bucket) error {
+			return compactBucket(to, name, bucket)
+		})
+	})
+
+	if err != nil {
+		return xerrors.Errorf("failed to copy: %v", err)
+	}
+
+	return nil
+}
+
+// compactBucket copies the bucket into the database with as many transactions
+// as necessary to stay under the maximum size.
+func compactBucket(db *bbolt.DB, name []byte, bucket *bbolt.Bucket) error {
+	txn, err := db.Begin(true)
+	if err != nil {
+		return xerrors.Errorf("failed to begin: %v", err)
+	}
+
+	defer func() {
+		// Rolling back a committed transaction is a no-op.
+		txn.Rollback()
+	}()
+
+	dst, err := txn.CreateBucketIfNotExists(name)
+	if err != nil {
+		return xerrors.Errorf("create bucket failed: %v", err)
+	}
+
+	size := 0
+
+	err = bucket.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return xerrors.Errorf("nested bucket %#x is not supported", k)
+		}
+
+		if size+len(k)+len(v) > compactTxSize {
+			err := txn.Commit()
+			if err != nil {
+				return xerrors.Errorf("failed to commit: %v", err)
+			}
+
+			txn, err = db.Begin(true)
+			if err != nil {
+				return xerrors.Errorf("failed to begin: %v", err)
+			}
+
+			dst = txn.Bucket(name)
+			size = 0
+		}
+
+		// Keys are inserted in order so that the pages can be filled.
+		dst.FillPercent = 1.0
+		size += len(k) + len(v)
+
+		return dst.Put(k, v)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return txn.Commit()
+}
+
 // BoltTx is the adapter of a bbolt transaction for the key/value database.
 //
 // - implements kv.ReadableTx
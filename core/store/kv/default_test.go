@@ -1,6 +1,7 @@
 package kv
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -63,6 +64,127 @@ func TestBoltDB_Close(t *testing.T) {
 	require.Error(t, db.(boltDB).bolt.Sync())
 }
 
+func TestBoltDB_Backup(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-core-kv")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	db, err := New(filepath.Join(dir, "test.db"))
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	err = db.Update(func(txn WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate([]byte("bucket"))
+		require.NoError(t, err)
+
+		return bucket.Set([]byte("ping"), []byte("pong"))
+	})
+	require.NoError(t, err)
+
+	buffer := new(bytes.Buffer)
+
+	n, err := db.(BackupDB).Backup(buffer)
+	require.NoError(t, err)
+	require.Equal(t, int64(buffer.Len()), n)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "backup.db"), buffer.Bytes(), 0644)
+	require.NoError(t, err)
+
+	backup, err := New(filepath.Join(dir, "backup.db"))
+	require.NoError(t, err)
+
+	defer backup.Close()
+
+	err = backup.View(func(txn ReadableTx) error {
+		require.Equal(t, []byte("pong"), txn.GetBucket([]byte("bucket")).Get([]byte("ping")))
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = db.(BackupDB).Backup(badWriter{})
+	require.EqualError(t, err, "failed to write: meta 0 copy: oops")
+}
+
+func TestCompact(t *testing.T) {
+	dir, err := ioutil.TempDir(os.TempDir(), "dela-core-kv")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "test.db")
+
+	db, err := New(src)
+	require.NoError(t, err)
+
+	err = db.Update(func(txn WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate([]byte("bucket"))
+		require.NoError(t, err)
+
+		for i := 0; i < 1000; i++ {
+			err = bucket.Set([]byte{byte(i >> 8), byte(i)}, make([]byte, 1024))
+			require.NoError(t, err)
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update(func(txn WritableTx) error {
+		bucket := txn.GetBucket([]byte("bucket"))
+
+		return bucket.ForEach(func(k, v []byte) error {
+			if k[1] != 0 {
+				return bucket.Delete(k)
+			}
+
+			return nil
+		})
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	dst := filepath.Join(dir, "compact.db")
+
+	err = Compact(dst, src)
+	require.NoError(t, err)
+
+	before, err := os.Stat(src)
+	require.NoError(t, err)
+
+	after, err := os.Stat(dst)
+	require.NoError(t, err)
+	require.Less(t, after.Size(), before.Size())
+
+	db, err = New(dst)
+	require.NoError(t, err)
+
+	defer db.Close()
+
+	err = db.View(func(txn ReadableTx) error {
+		count := 0
+		err := txn.GetBucket([]byte("bucket")).ForEach(func(k, v []byte) error {
+			require.Equal(t, byte(0), k[1])
+			count++
+			return nil
+		})
+
+		require.Equal(t, 4, count)
+
+		return err
+	})
+	require.NoError(t, err)
+
+	err = Compact(dst, filepath.Join(dir, "unknown.db"))
+	require.Error(t, err)
+	require.Regexp(t, "^failed to open source: ", err.Error())
+
+	err = Compact(dir, src)
+	require.Error(t, err)
+	require.Regexp(t, "^failed to open destination: ", err.Error())
+}
+
 func TestBoltTx_GetBucket(t *testing.T) {
 	dir, err := ioutil.TempDir(os.TempDir(), "dela-core-kv")
 	require.NoError(t, err)
@@ -186,3 +308,12 @@ func TestBoltBucket_Scan(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+// -----------------------------------------------------------------------------
+// Utility functions
+
+type badWriter struct{}
+
+func (badWriter) Write([]byte) (int, error) {
+	return 0, xerrors.New("oops")
+}
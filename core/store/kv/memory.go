@@ -0,0 +1,341 @@
+// This file contains the implementation of a key/value database in memory.
+
+package kv
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
+)
+
+// memState is a state of the in-memory database. A transaction that commits
+// while views are in progress creates a new state so that they are not
+// affected, otherwise it modifies the state in place.
+type memState map[string]map[string][]byte
+
+// memoryDB is an implementation of the key/value database that keeps the
+// buckets in memory. It provides the same guarantees as the bbolt database,
+// where a single writable transaction is executed at a time while the views
+// read a consistent state of the database.
+//
+// - implements kv.DB
+type memoryDB struct {
+	sync.Mutex
+
+	// writer is held by the writable transactions during their execution.
+	writer sync.Mutex
+	state  memState
+	// views is the number of views in progress, which might read any of the
+	// states that share buckets with the current one.
+	views  int
+	closed bool
+}
+
+// NewInMemory creates a new empty database that lives in memory.
+func NewInMemory() DB {
+	return &memoryDB{
+		state: memState{},
+	}
+}
+
+// View implements kv.DB. It executes the read-only transaction on the current
+// state of the database.
+func (db *memoryDB) View(fn func(ReadableTx) error) error {
+	db.Lock()
+
+	if db.closed {
+		db.Unlock()
+		return xerrors.New("database is closed")
+	}
+
+	state := db.state
+	db.views++
+
+	db.Unlock()
+
+	defer func() {
+		db.Lock()
+		db.views--
+		db.Unlock()
+	}()
+
+	return fn(newMemTx(state, false))
+}
+
+// Update implements kv.DB. It executes the writable transaction and applies the
+// changes only if it succeeds, before calling the commit callbacks. Otherwise
+// the changes are discarded.
+func (db *memoryDB) Update(fn func(WritableTx) error) error {
+	db.writer.Lock()
+	defer db.writer.Unlock()
+
+	state, err := db.getState()
+	if err != nil {
+		return err
+	}
+
+	tx := newMemTx(state, true)
+
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+
+	db.Lock()
+
+	if db.closed {
+		db.Unlock()
+		return xerrors.New("database is closed")
+	}
+
+	// The buckets are copied only if a view might still read them.
+	db.state = tx.commit(db.views == 0)
+
+	db.Unlock()
+
+	for _, cb := range tx.callbacks {
+		cb()
+	}
+
+	return nil
+}
+
+// Close implements kv.DB. It releases the state of the database. Any view or
+// update call will result in an error after this function is called.
+func (db *memoryDB) Close() error {
+	db.Lock()
+	defer db.Unlock()
+
+	db.closed = true
+	db.state = nil
+
+	return nil
+}
+
+func (db *memoryDB) getState() (memState, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	if db.closed {
+		return nil, xerrors.New("database is closed")
+	}
+
+	return db.state, nil
+}
+
+// memEntry is a modification of a key in a transaction.
+type memEntry struct {
+	value   []byte
+	deleted bool
+}
+
+// memTx is a transaction of the in-memory database. The modifications are kept
+// aside from the state until the transaction commits.
+//
+// - implements kv.ReadableTx
+// - implements kv.WritableTx
+type memTx struct {
+	state     memState
+	writable  bool
+	created   map[string]struct{}
+	writes    map[string]map[string]memEntry
+	callbacks []func()
+}
+
+func newMemTx(state memState, writable bool) *memTx {
+	return &memTx{
+		state:    state,
+		writable: writable,
+		created:  map[string]struct{}{},
+		writes:   map[string]map[string]memEntry{},
+	}
+}
+
+// GetBucket implements kv.ReadableTx. It returns the bucket with the given name
+// or nil if it does not exist.
+func (tx *memTx) GetBucket(name []byte) Bucket {
+	_, found := tx.state[string(name)]
+	if !found {
+		_, found = tx.created[string(name)]
+	}
+
+	if !found {
+		return nil
+	}
+
+	return memBucket{tx: tx, name: string(name)}
+}
+
+// GetBucketOrCreate implements kv.WritableTx. It creates the bucket if it does
+// not exist and then returns it.
+func (tx *memTx) GetBucketOrCreate(name []byte) (Bucket, error) {
+	if !tx.writable {
+		return nil, xerrors.New("transaction is read-only")
+	}
+
+	if len(name) == 0 {
+		return nil, xerrors.New("bucket name is empty")
+	}
+
+	_, found := tx.state[string(name)]
+	if !found {
+		tx.created[string(name)] = struct{}{}
+	}
+
+	return memBucket{tx: tx, name: string(name)}, nil
+}
+
+// OnCommit implements store.Transaction. It registers a callback that is called
+// after the transaction is successful.
+func (tx *memTx) OnCommit(fn func()) {
+	tx.callbacks = append(tx.callbacks, fn)
+}
+
+// commit returns the state with the modifications of the transaction. The
+// state is modified in place when it is not shared, otherwise a new state is
+// created where only the buckets that have been modified are copied.
+func (tx *memTx) commit(inPlace bool) memState {
+	if inPlace {
+		for name := range tx.created {
+			tx.state[name] = map[string][]byte{}
+		}
+
+		for name, writes := range tx.writes {
+			apply(tx.state[name], writes)
+		}
+
+		return tx.state
+	}
+
+	state := make(memState, len(tx.state)+len(tx.created))
+	for name, bucket := range tx.state {
+		state[name] = bucket
+	}
+
+	for name := range tx.created {
+		state[name] = map[string][]byte{}
+	}
+
+	for name, writes := range tx.writes {
+		bucket := make(map[string][]byte, len(state[name])+len(writes))
+		for key, value := range state[name] {
+			bucket[key] = value
+		}
+
+		apply(bucket, writes)
+
+		state[name] = bucket
+	}
+
+	return state
+}
+
+// apply applies the modifications of a transaction to the bucket.
+func apply(bucket map[string][]byte, writes map[string]memEntry) {
+	for key, entry := range writes {
+		if entry.deleted {
+			delete(bucket, key)
+		} else {
+			bucket[key] = entry.value
+		}
+	}
+}
+
+// memBucket is a bucket of a transaction of the in-memory database.
+//
+// - implements kv.Bucket
+type memBucket struct {
+	tx   *memTx
+	name string
+}
+
+// Get implements kv.Bucket. It returns the value associated to the key, or nil
+// if it does not exist.
+func (b memBucket) Get(key []byte) []byte {
+	entry, found := b.tx.writes[b.name][string(key)]
+	if found {
+		return entry.value
+	}
+
+	return b.tx.state[b.name][string(key)]
+}
+
+// Set implements kv.Bucket. It sets the provided key to the value.
+func (b memBucket) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return xerrors.New("key is empty")
+	}
+
+	return b.write(key, memEntry{value: append([]byte{}, value...)})
+}
+
+// Delete implements kv.Bucket. It deletes the key from the bucket.
+func (b memBucket) Delete(key []byte) error {
+	return b.write(key, memEntry{deleted: true})
+}
+
+// ForEach implements kv.Bucket. It iterates over the whole bucket in the order
+// of the keys. If the callback returns an error, the iteration is stopped and
+// the error returned to the caller.
+func (b memBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.Scan(nil, fn)
+}
+
+// Scan implements kv.Bucket. It iterates over the keys matching the prefix in a
+// sorted order. If the callback returns an error, the iteration is stopped and
+// the error returned to the caller.
+func (b memBucket) Scan(prefix []byte, fn func(k, v []byte) error) error {
+	// The keys are collected beforehand so that the callback can modify the
+	// bucket during the iteration.
+	keys := make([]string, 0, len(b.tx.state[b.name]))
+
+	for key := range b.tx.state[b.name] {
+		_, found := b.tx.writes[b.name][key]
+		if !found && bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	for key, entry := range b.tx.writes[b.name] {
+		if !entry.deleted && bytes.HasPrefix([]byte(key), prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := b.Get([]byte(key))
+		if value == nil {
+			// The key has been deleted by the callback.
+			continue
+		}
+
+		err := fn([]byte(key), value)
+		if err != nil {
+			// The caller is responsible for wrapping the errors inside the
+			// callback, as it returns the exact error to allow comparison.
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b memBucket) write(key []byte, entry memEntry) error {
+	if !b.tx.writable {
+		return xerrors.New("transaction is read-only")
+	}
+
+	writes := b.tx.writes[b.name]
+	if writes == nil {
+		writes = map[string]memEntry{}
+		b.tx.writes[b.name] = writes
+	}
+
+	writes[string(key)] = entry
+
+	return nil
+}
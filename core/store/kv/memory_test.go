@@ -0,0 +1,279 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
+)
+
+func TestMemoryDB_UpdateAndView(t *testing.T) {
+	db := NewInMemory()
+
+	committed := false
+	err := db.Update(func(txn WritableTx) error {
+		txn.OnCommit(func() { committed = true })
+
+		bucket, err := txn.GetBucketOrCreate([]byte("bucket"))
+		require.NoError(t, err)
+
+		return bucket.Set([]byte("ping"), []byte("pong"))
+	})
+	require.NoError(t, err)
+	require.True(t, committed)
+
+	err = db.View(func(txn ReadableTx) error {
+		bucket := txn.GetBucket([]byte("bucket"))
+		require.NotNil(t, bucket)
+
+		value := bucket.Get([]byte("ping"))
+		require.Equal(t, []byte("pong"), value)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A transaction that fails does not change the database.
+	err = db.Update(func(txn WritableTx) error {
+		txn.OnCommit(func() { t.Fatal("unexpected commit") })
+
+		bucket := txn.GetBucket([]byte("bucket"))
+		require.NoError(t, bucket.Delete([]byte("ping")))
+
+		_, err := txn.GetBucketOrCreate([]byte("other"))
+		require.NoError(t, err)
+
+		return xerrors.New("oops")
+	})
+	require.EqualError(t, err, "oops")
+
+	err = db.View(func(txn ReadableTx) error {
+		require.Nil(t, txn.GetBucket([]byte("other")))
+		require.Equal(t, []byte("pong"), txn.GetBucket([]byte("bucket")).Get([]byte("ping")))
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestMemoryDB_Isolation(t *testing.T) {
+	db := NewInMemory()
+
+	err := db.Update(func(txn WritableTx) error {
+		_, err := txn.GetBucketOrCreate([]byte("bucket"))
+		return err
+	})
+	require.NoError(t, err)
+
+	// A view started before a commit keeps reading the same state.
+	err = db.View(func(view ReadableTx) error {
+		err := db.Update(func(txn WritableTx) error {
+			return txn.GetBucket([]byte("bucket")).Set([]byte("A"), []byte("B"))
+		})
+		require.NoError(t, err)
+
+		require.Nil(t, view.GetBucket([]byte("bucket")).Get([]byte("A")))
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.View(func(view ReadableTx) error {
+		require.Equal(t, []byte("B"), view.GetBucket([]byte("bucket")).Get([]byte("A")))
+
+		return view.GetBucket([]byte("bucket")).Set([]byte("A"), nil)
+	})
+	require.EqualError(t, err, "transaction is read-only")
+
+	err = db.View(func(view ReadableTx) error {
+		_, err := view.(WritableTx).GetBucketOrCreate([]byte("bucket"))
+		return err
+	})
+	require.EqualError(t, err, "transaction is read-only")
+}
+
+func TestMemoryDB_CopyOnWrite(t *testing.T) {
+	db := NewInMemory().(*memoryDB)
+
+	err := db.Update(func(txn WritableTx) error {
+		bucket, err := txn.GetBucketOrCreate([]byte("bucket"))
+		require.NoError(t, err)
+
+		return bucket.Set([]byte("A"), []byte("1"))
+	})
+	require.NoError(t, err)
+
+	bucket := db.state["bucket"]
+
+	// The bucket is modified in place when no view is in progress.
+	err = db.Update(func(txn WritableTx) error {
+		return txn.GetBucket([]byte("bucket")).Set([]byte("B"), []byte("2"))
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("2"), bucket["B"])
+
+	// The bucket is copied when a view might still read it.
+	err = db.View(func(view ReadableTx) error {
+		require.Equal(t, 1, db.views)
+
+		err := db.Update(func(txn WritableTx) error {
+			return txn.GetBucket([]byte("bucket")).Delete([]byte("A"))
+		})
+		require.NoError(t, err)
+		require.Equal(t, []byte("1"), view.GetBucket([]byte("bucket")).Get([]byte("A")))
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, db.views)
+	require.Equal(t, []byte("1"), bucket["A"])
+	require.Nil(t, db.state["bucket"]["A"])
+}
+
+func TestMemoryDB_Close(t *testing.T) {
+	db := NewInMemory()
+
+	err := db.Close()
+	require.NoError(t, err)
+
+	err = db.View(func(ReadableTx) error { return nil })
+	require.EqualError(t, err, "database is closed")
+
+	err = db.Update(func(WritableTx) error { return nil })
+	require.EqualError(t, err, "database is closed")
+
+	db = NewInMemory()
+	err = db.Update(func(WritableTx) error {
+		return db.Close()
+	})
+	require.EqualError(t, err, "database is closed")
+}
+
+func TestMemoryTx_GetBucket(t *testing.T) {
+	db := NewInMemory()
+
+	err := db.Update(func(tx WritableTx) error {
+		require.Nil(t, tx.GetBucket([]byte("unknown")))
+
+		_, err := tx.GetBucketOrCreate([]byte("A"))
+		require.NoError(t, err)
+		require.NotNil(t, tx.GetBucket([]byte("A")))
+
+		_, err = tx.GetBucketOrCreate(nil)
+		require.EqualError(t, err, "bucket name is empty")
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestMemoryBucket_Get_Set_Delete(t *testing.T) {
+	db := NewInMemory()
+
+	err := db.Update(func(txn WritableTx) error {
+		b, err := txn.GetBucketOrCreate([]byte("bucket"))
+		require.NoError(t, err)
+
+		buffer := []byte("pong")
+		require.NoError(t, b.Set([]byte("ping"), buffer))
+
+		// The value is copied.
+		buffer[0] = 'P'
+
+		value := b.Get([]byte("ping"))
+		require.Equal(t, []byte("pong"), value)
+
+		value = b.Get([]byte("pong"))
+		require.Nil(t, value)
+
+		require.NoError(t, b.Delete([]byte("ping")))
+
+		value = b.Get([]byte("ping"))
+		require.Nil(t, value)
+
+		require.EqualError(t, b.Set(nil, nil), "key is empty")
+
+		return nil
+	})
+
+	require.NoError(t, err)
+}
+
+func TestMemoryBucket_ForEach(t *testing.T) {
+	db := NewInMemory()
+
+	err := db.Update(func(txn WritableTx) error {
+		b, err := txn.GetBucketOrCreate([]byte("test"))
+		require.NoError(t, err)
+
+		require.NoError(t, b.Set([]byte{2}, []byte{2}))
+		require.NoError(t, b.Set([]byte{0}, []byte{0}))
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update(func(txn WritableTx) error {
+		b := txn.GetBucket([]byte("test"))
+
+		require.NoError(t, b.Set([]byte{1}, []byte{1}))
+
+		var i byte = 0
+		return b.ForEach(func(k, v []byte) error {
+			require.Equal(t, []byte{i}, k)
+			require.Equal(t, []byte{i}, v)
+			i++
+			return nil
+		})
+	})
+	require.NoError(t, err)
+}
+
+func TestMemoryBucket_Scan(t *testing.T) {
+	db := NewInMemory()
+
+	err := db.Update(func(txn WritableTx) error {
+		b, err := txn.GetBucketOrCreate([]byte("bucket"))
+		require.NoError(t, err)
+
+		require.NoError(t, b.Set([]byte{7}, []byte{7}))
+		require.NoError(t, b.Set([]byte{0}, []byte{0}))
+		require.NoError(t, b.Set([]byte{1, 2}, []byte{1}))
+		require.NoError(t, b.Set([]byte{1, 1}, []byte{1}))
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update(func(txn WritableTx) error {
+		b := txn.GetBucket([]byte("bucket"))
+
+		require.NoError(t, b.Delete([]byte{1, 2}))
+		require.NoError(t, b.Set([]byte{1, 0}, []byte{1}))
+
+		var keys [][]byte
+		err := b.Scan([]byte{1}, func(k, v []byte) error {
+			keys = append(keys, k)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{{1, 0}, {1, 1}}, keys)
+
+		// The keys deleted by the callback are skipped.
+		keys = nil
+		err = b.Scan(nil, func(k, v []byte) error {
+			keys = append(keys, k)
+			return b.Delete([]byte{7})
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{{0}, {1, 0}, {1, 1}}, keys)
+
+		err = b.Scan([]byte{}, func(k, v []byte) error {
+			return xerrors.New("callback error")
+		})
+		require.EqualError(t, err, "callback error")
+
+		return nil
+	})
+	require.NoError(t, err)
+}
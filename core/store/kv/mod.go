@@ -1,13 +1,18 @@
 // Package kv defines the abstraction for a key/value database.
 //
 // The package also implements a default database implementation that is using
-// bbolt as the engine (https://github.com/etcd-io/bbolt).
+// bbolt as the engine (https://github.com/etcd-io/bbolt), and a database that
+// lives in memory for tests and ephemeral nodes.
 //
 // Documentation Last Review: 08.10.2020
 //
 package kv
 
-import "go.dedis.ch/dela/core/store"
+import (
+	"io"
+
+	"go.dedis.ch/dela/core/store"
+)
 
 // Bucket is a general interface to operate on a database bucket.
 type Bucket interface {
@@ -62,3 +67,13 @@ type DB interface {
 	// Close closes the database and free the resources.
 	Close() error
 }
+
+// BackupDB is a database that can write a consistent copy of itself while it
+// is used.
+type BackupDB interface {
+	DB
+
+	// Backup writes a copy of the database, as seen by a read-only
+	// transaction, and returns the number of bytes written.
+	Backup(w io.Writer) (int64, error)
+}
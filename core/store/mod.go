@@ -9,6 +9,16 @@ type Readable interface {
 	Get(key []byte) ([]byte, error)
 }
 
+// RangeReadable is the interface for a readable store that can enumerate its
+// key/value pairs in order.
+type RangeReadable interface {
+	// ForRange calls the function with every key/value pair whose key is
+	// between start, included, and end, excluded, in the lexicographic order of
+	// the keys. A nil end means there is no upper bound. It stops and returns
+	// the error if the function fails.
+	ForRange(start, end []byte, fn func(key, value []byte) error) error
+}
+
 // Writable is the interface for a writable store.
 type Writable interface {
 	Set(key []byte, value []byte) error
@@ -6,6 +6,8 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/rs/zerolog"
+	"go.dedis.ch/dela"
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/core/txn"
 	"go.dedis.ch/dela/core/validation"
@@ -101,6 +103,7 @@ type item struct {
 type simpleGatherer struct {
 	sync.Mutex
 
+	logger     zerolog.Logger
 	limit      int
 	queue      []item
 	validators []Filter
@@ -114,8 +117,9 @@ type simpleGatherer struct {
 // NewSimpleGatherer creates a new gatherer.
 func NewSimpleGatherer() Gatherer {
 	return &simpleGatherer{
-		limit: DefaultIdentitySize,
-		txs:   make(map[string]transactions),
+		logger: dela.Logger,
+		limit:  DefaultIdentitySize,
+		txs:    make(map[string]transactions),
 	}
 }
 
@@ -160,7 +164,7 @@ func (g *simpleGatherer) Add(tx txn.Transaction) error {
 
 	g.txs[key] = g.txs[key].Add(tx)
 
-	g.notify(g.calculateLength())
+	g.notify()
 
 	g.Unlock()
 
@@ -191,8 +195,8 @@ func (g *simpleGatherer) Wait(ctx context.Context, cfg Config) []txn.Transaction
 
 	g.Lock()
 
-	if g.calculateLength() >= cfg.Min {
-		txs := g.makeArray()
+	txs := g.makeArray(cfg)
+	if len(txs) >= cfg.Min {
 		g.Unlock()
 
 		return txs
@@ -230,16 +234,17 @@ func (g *simpleGatherer) Close() {
 	g.Unlock()
 }
 
-// Notify triggers the elements of the queue that are waiting for at least the
-// length in parameter and remove them from the queue.
-func (g *simpleGatherer) notify(length int) {
+// Notify triggers the elements of the queue that can be served with enough
+// transactions within their limits and remove them from the queue.
+func (g *simpleGatherer) notify() {
 	// Iterating by descending order to allow the deletion of the element inside
 	// the loop.
 	for i := len(g.queue) - 1; i >= 0; i-- {
 		item := g.queue[i]
 
-		if item.cfg.Min <= length {
-			item.ch <- g.makeArray()
+		txs := g.makeArray(item.cfg)
+		if item.cfg.Min <= len(txs) {
+			item.ch <- txs
 			g.queue = append(g.queue[:i], g.queue[i+1:]...)
 		}
 	}
@@ -254,10 +259,46 @@ func (g *simpleGatherer) calculateLength() int {
 	return num
 }
 
-func (g *simpleGatherer) makeArray() []txn.Transaction {
+// makeArray returns the transactions that fit in the limits of the
+// configuration. The transactions of an identity are taken in the order of the
+// nonces, and the first one that does not fit stops the identity so that no gap
+// is left in the sequence. A transaction that cannot fit on its own is evicted
+// so that the identity can submit another one with the same nonce.
+func (g *simpleGatherer) makeArray(cfg Config) []txn.Transaction {
 	txs := make([]txn.Transaction, 0, g.calculateLength())
-	for _, list := range g.txs {
-		txs = append(txs, list...)
+	size := 0
+
+	for key, list := range g.txs {
+		for _, tx := range list {
+			if cfg.Max > 0 && len(txs) >= cfg.Max {
+				return txs
+			}
+
+			if cfg.MaxSize > 0 {
+				data, err := tx.Serialize(cfg.Context)
+				if err != nil || len(data) > cfg.MaxSize {
+					g.logger.Warn().
+						Err(err).
+						Int("size", len(data)).
+						Int("limit", cfg.MaxSize).
+						Hex("id", tx.GetID()).
+						Msg("transaction evicted as it never fits in a block")
+
+					// The list is not used anymore after the eviction.
+					g.txs[key] = list.Remove(tx)
+
+					break
+				}
+
+				if size+len(data) > cfg.MaxSize {
+					break
+				}
+
+				size += len(data)
+			}
+
+			txs = append(txs, tx)
+		}
 	}
 
 	return txs
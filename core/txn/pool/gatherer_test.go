@@ -1,15 +1,18 @@
 package pool
 
 import (
+	"bytes"
 	"context"
 	"sync"
 	"testing"
 
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/core/txn"
 	"go.dedis.ch/dela/core/validation"
 	"go.dedis.ch/dela/internal/testing/fake"
+	"go.dedis.ch/dela/serde"
 )
 
 func TestSimpleGatherer_Len(t *testing.T) {
@@ -100,6 +103,63 @@ func TestSimpleGatherer_Wait(t *testing.T) {
 	require.Nil(t, txs)
 }
 
+func TestSimpleGatherer_Limits_Wait(t *testing.T) {
+	gatherer := NewSimpleGatherer().(*simpleGatherer)
+	gatherer.txs["Alice"] = transactions{newTx(0, "Alice"), newTx(1, "Alice"), newTx(2, "Alice")}
+
+	ctx := context.Background()
+
+	txs := gatherer.Wait(ctx, Config{Min: 1, Max: 2})
+	require.Len(t, txs, 2)
+
+	// Each fake transaction is serialized in a single byte.
+	txs = gatherer.Wait(ctx, Config{Min: 1, MaxSize: 1})
+	require.Len(t, txs, 1)
+	require.Equal(t, uint64(0), txs[0].GetNonce())
+
+	// The sequence of an identity stops at the first transaction that does
+	// not fit.
+	gatherer.txs["Alice"] = transactions{newTx(0, "Alice"), newTx(1, "Alice"), newTx(2, "Alice")}
+
+	txs = gatherer.Wait(ctx, Config{Min: 1, MaxSize: 2})
+	require.Len(t, txs, 2)
+
+	// A transaction that never fits is evicted so that the identity can
+	// replace it.
+	buffer := new(bytes.Buffer)
+	gatherer.logger = zerolog.New(buffer)
+	gatherer.txs["Alice"] = transactions{newTx(0, "Alice"), fakeTx{id: 1, size: 5}, newTx(2, "Alice")}
+
+	txs = gatherer.Wait(ctx, Config{Min: 1, MaxSize: 4})
+	require.Len(t, txs, 1)
+	require.Len(t, gatherer.txs["Alice"], 2)
+	require.Contains(t, buffer.String(), "transaction evicted as it never fits in a block")
+
+	require.NoError(t, gatherer.Add(newTx(1, "Alice")))
+
+	txs = gatherer.Wait(ctx, Config{Min: 1, MaxSize: 4})
+	require.Len(t, txs, 3)
+
+	gatherer.txs["Alice"] = transactions{fakeTx{id: 0, err: fake.GetError()}}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+
+	txs = gatherer.Wait(ctx, Config{Min: 1, MaxSize: 4})
+	require.Nil(t, txs)
+	require.Len(t, gatherer.queue, 1)
+
+	// A waiting gathering is notified only when enough transactions fit.
+	gatherer.queue[0].ch = make(chan []txn.Transaction, 1)
+	gatherer.txs["Alice"] = transactions{}
+
+	require.NoError(t, gatherer.Add(fakeTx{id: 0, size: 5, identity: fakeIdentity{text: "Bob"}}))
+	require.Len(t, gatherer.queue, 1)
+
+	require.NoError(t, gatherer.Add(newTx(0, "Alice")))
+	require.Empty(t, gatherer.queue)
+}
+
 func TestSimpleGatherer_Close(t *testing.T) {
 	gatherer := NewSimpleGatherer().(*simpleGatherer)
 
@@ -139,6 +199,8 @@ type fakeTx struct {
 
 	id       uint64
 	identity access.Identity
+	size     int
+	err      error
 }
 
 func newTx(nonce uint64, identity string) fakeTx {
@@ -160,6 +222,14 @@ func (tx fakeTx) GetIdentity() access.Identity {
 	return tx.identity
 }
 
+func (tx fakeTx) Serialize(serde.Context) ([]byte, error) {
+	if tx.size == 0 {
+		return []byte{byte(tx.id)}, tx.err
+	}
+
+	return make([]byte, tx.size), tx.err
+}
+
 type fakeIdentity struct {
 	access.Identity
 	text string
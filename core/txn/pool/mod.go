@@ -9,6 +9,7 @@ import (
 	"go.dedis.ch/dela/core/txn"
 	"go.dedis.ch/dela/core/validation"
 	"go.dedis.ch/dela/mino"
+	"go.dedis.ch/dela/serde"
 )
 
 // Config is the set of parameters that allows one to change the behavior of the
@@ -18,6 +19,19 @@ type Config struct {
 	// before returning.
 	Min int
 
+	// Max is the maximum number of transactions returned, or zero for no
+	// limit.
+	Max int
+
+	// MaxSize is the maximum sum of the size of the transactions returned, in
+	// bytes, or zero for no limit. The size of a transaction is the length of
+	// its serialization in the context.
+	MaxSize int
+
+	// Context is the serialization context used to measure the size of the
+	// transactions when a maximum size is set.
+	Context serde.Context
+
 	// Callback is a function called when the pool doesn't have enough
 	// transactions at the moment of calling and must therefore wait for new
 	// transactions to come. It allows one to take action to stop the gathering
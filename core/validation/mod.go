@@ -6,6 +6,8 @@
 package validation
 
 import (
+	"time"
+
 	"go.dedis.ch/dela/core/access"
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/txn"
@@ -49,6 +51,23 @@ type Leeway struct {
 	MaxSequenceDifference int
 }
 
+// Config is the context of the validation of a batch of transactions.
+type Config struct {
+	// Timestamp is the time of the block that contains the batch.
+	Timestamp time.Time
+}
+
+// Option is the type of option to set the context of a validation.
+type Option func(*Config)
+
+// WithTimestamp is an option to set the time of the block that contains the
+// batch, which is passed to the execution of the transactions.
+func WithTimestamp(ts time.Time) Option {
+	return func(cfg *Config) {
+		cfg.Timestamp = ts
+	}
+}
+
 // Service is the validation service that will process a batch of transactions
 // into a result that can be used as a payload of a block.
 type Service interface {
@@ -64,6 +83,6 @@ type Service interface {
 	Accept(store.Readable, txn.Transaction, Leeway) error
 
 	// Validate takes a snapshot and a list of transactions and returns a
-	// result.
-	Validate(store.Snapshot, []txn.Transaction) (Result, error)
+	// result. The options set the context of the execution.
+	Validate(store.Snapshot, []txn.Transaction, ...Option) (Result, error)
 }
@@ -89,11 +89,20 @@ func (s Service) Accept(store store.Readable, tx txn.Transaction, leeway validat
 
 // Validate implements validation.Service. It processes the list of transactions
 // while updating the snapshot then returns a bundle of the transaction results.
-func (s Service) Validate(store store.Snapshot, txs []txn.Transaction) (validation.Result, error) {
+func (s Service) Validate(store store.Snapshot, txs []txn.Transaction,
+	opts ...validation.Option) (validation.Result, error) {
+
+	cfg := validation.Config{}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	results := make([]TransactionResult, len(txs))
 
 	step := execution.Step{
-		Previous: make([]txn.Transaction, 0, len(txs)),
+		Previous:  make([]txn.Transaction, 0, len(txs)),
+		Timestamp: cfg.Timestamp,
 	}
 
 	for i, tx := range txs {
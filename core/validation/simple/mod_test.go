@@ -2,6 +2,7 @@ package simple
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.dedis.ch/dela/core/execution"
@@ -90,6 +91,15 @@ func TestService_Validate(t *testing.T) {
 
 	status, _ := res.GetTransactionResults()[0].GetStatus()
 	require.False(t, status)
+
+	ts := time.Unix(0, 42)
+
+	exec = &fakeExec{}
+	srvc = NewService(exec, nil)
+
+	_, err = srvc.Validate(fakeSnapshot{}, []txn.Transaction{newTx()}, validation.WithTimestamp(ts))
+	require.NoError(t, err)
+	require.Equal(t, ts, exec.timestamp)
 }
 
 func TestService_NilIdentity_Validate(t *testing.T) {
@@ -131,9 +141,10 @@ func TestService_FailExecuteTx_Validate(t *testing.T) {
 // Utility functions
 
 type fakeExec struct {
-	err   error
-	count int
-	check bool
+	err       error
+	count     int
+	check     bool
+	timestamp time.Time
 }
 
 func (e *fakeExec) Execute(store store.Snapshot, step execution.Step) (execution.Result, error) {
@@ -141,6 +152,7 @@ func (e *fakeExec) Execute(store store.Snapshot, step execution.Step) (execution
 		return execution.Result{}, xerrors.New("missing previous txs")
 	}
 
+	e.timestamp = step.Timestamp
 	e.count++
 	return execution.Result{Accepted: true}, e.err
 }
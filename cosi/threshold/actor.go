@@ -115,7 +115,7 @@ func (a thresholdActor) merge(signature *types.Signature, m serde.Message,
 		return xerrors.Errorf("couldn't verify: %v", err)
 	}
 
-	err = signature.Merge(a.signer, index, resp.Signature)
+	err = signature.Merge(a.getSigner(), index, resp.Signature)
 	if err != nil {
 		return xerrors.Errorf("couldn't merge signature: %v", err)
 	}
@@ -64,7 +64,7 @@ func (h thresholdHandler) processRequest(sender mino.Sender, msg serde.Message,
 		return xerrors.Errorf("couldn't hash message: %v", err)
 	}
 
-	signature, err := h.signer.Sign(buffer)
+	signature, err := h.getSigner().Sign(buffer)
 	if err != nil {
 		return xerrors.Errorf("couldn't sign: %v", err)
 	}
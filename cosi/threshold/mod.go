@@ -9,6 +9,7 @@
 package threshold
 
 import (
+	"sync"
 	"sync/atomic"
 
 	"github.com/rs/zerolog"
@@ -49,7 +50,10 @@ func ByzantineThreshold(n int) int {
 type Threshold struct {
 	logger zerolog.Logger
 	mino   mino.Mino
-	signer crypto.AggregateSigner
+	// The signer can be replaced at runtime when the key of the participant is
+	// rotated, hence the lock.
+	signerLock sync.RWMutex
+	signer     crypto.AggregateSigner
 	// Stores the cosi.Threshold function. It will always contain a valid
 	// function by construction.
 	thresholdFn atomic.Value
@@ -72,25 +76,37 @@ func NewThreshold(m mino.Mino, signer crypto.AggregateSigner) *Threshold {
 // GetSigner implements cosi.CollectiveSigning. It returns the signer of the
 // instance.
 func (c *Threshold) GetSigner() crypto.Signer {
-	return c.signer
+	return c.getSigner()
+}
+
+// SetSigner replaces the signer of the instance. It is expected to be of the
+// same kind as the previous one so that the factories stay valid.
+func (c *Threshold) SetSigner(signer crypto.AggregateSigner) {
+	if signer == nil {
+		return
+	}
+
+	c.signerLock.Lock()
+	c.signer = signer
+	c.signerLock.Unlock()
 }
 
 // GetPublicKeyFactory implements cosi.CollectiveSigning. It returns the public
 // key factory.
 func (c *Threshold) GetPublicKeyFactory() crypto.PublicKeyFactory {
-	return c.signer.GetPublicKeyFactory()
+	return c.getSigner().GetPublicKeyFactory()
 }
 
 // GetSignatureFactory implements cosi.CollectiveSigning. It returns the
 // signature factory.
 func (c *Threshold) GetSignatureFactory() crypto.SignatureFactory {
-	return types.NewSignatureFactory(c.signer.GetSignatureFactory())
+	return types.NewSignatureFactory(c.getSigner().GetSignatureFactory())
 }
 
 // GetVerifierFactory implements cosi.CollectiveSigning. It returns the verifier
 // factory.
 func (c *Threshold) GetVerifierFactory() crypto.VerifierFactory {
-	return types.NewThresholdVerifierFactory(c.signer.GetVerifierFactory())
+	return types.NewThresholdVerifierFactory(c.getSigner().GetVerifierFactory())
 }
 
 // SetThreshold implements cosi.CollectiveSigning. It sets a new threshold
@@ -106,7 +122,7 @@ func (c *Threshold) SetThreshold(fn cosi.Threshold) {
 // Listen implements cosi.CollectiveSigning. It creates the rpc endpoint and
 // returns the actor that can trigger a collective signature.
 func (c *Threshold) Listen(r cosi.Reactor) (cosi.Actor, error) {
-	factory := cosi.NewMessageFactory(r, c.signer.GetSignatureFactory())
+	factory := cosi.NewMessageFactory(r, c.getSigner().GetSignatureFactory())
 
 	actor := thresholdActor{
 		Threshold: c,
@@ -117,3 +133,10 @@ func (c *Threshold) Listen(r cosi.Reactor) (cosi.Actor, error) {
 
 	return actor, nil
 }
+
+func (c *Threshold) getSigner() crypto.AggregateSigner {
+	c.signerLock.RLock()
+	defer c.signerLock.RUnlock()
+
+	return c.signer
+}
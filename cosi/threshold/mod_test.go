@@ -65,6 +65,18 @@ func TestThreshold_GetSigner(t *testing.T) {
 	require.NotNil(t, c.GetSigner())
 }
 
+func TestThreshold_SetSigner(t *testing.T) {
+	c := NewThreshold(fake.Mino{}, bls.NewSigner())
+
+	signer := bls.NewSigner()
+
+	c.SetSigner(nil)
+	require.NotEqual(t, signer, c.GetSigner())
+
+	c.SetSigner(signer)
+	require.Equal(t, signer, c.GetSigner())
+}
+
 func TestThreshold_GetPublicKeyFactory(t *testing.T) {
 	c := &Threshold{signer: fake.NewAggregateSigner()}
 	require.NotNil(t, c.GetPublicKeyFactory())
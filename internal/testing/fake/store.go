@@ -1,6 +1,9 @@
 package fake
 
 import (
+	"bytes"
+	"sort"
+
 	"go.dedis.ch/dela/core/store"
 	"go.dedis.ch/dela/core/store/kv"
 )
@@ -8,6 +11,7 @@ import (
 // InMemorySnapshot is a fake implementation of a store snapshot.
 //
 // - implements store.Snapshot
+// - implements store.RangeReadable
 type InMemorySnapshot struct {
 	store.Snapshot
 
@@ -53,6 +57,39 @@ func (snap *InMemorySnapshot) Delete(key []byte) error {
 	return snap.ErrDelete
 }
 
+// ForRange implements store.RangeReadable.
+func (snap *InMemorySnapshot) ForRange(start, end []byte,
+	fn func(key, value []byte) error) error {
+
+	if snap.ErrRead != nil {
+		return snap.ErrRead
+	}
+
+	keys := make([]string, 0, len(snap.values))
+	for key := range snap.values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if bytes.Compare([]byte(key), start) < 0 {
+			continue
+		}
+
+		if end != nil && bytes.Compare([]byte(key), end) >= 0 {
+			break
+		}
+
+		err := fn([]byte(key), snap.values[key])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // InMemoryDB is a fake implementation of a key/value storage.
 //
 // - implements kv.DB
@@ -6,8 +6,8 @@
 // to simplify the writing of tests, therefore it also provides some additionnal
 // functionalities like filters.
 //
-// A filter is called for any message incoming and it will determine if the
-// instance should drop the message.
+// A filter is called for any message incoming, either from a call or from a
+// stream, and it will determine if the instance should drop the message.
 //
 // Documentation Last Review: 06.10.2020
 //
@@ -127,6 +127,36 @@ func (c RPC) Call(ctx context.Context,
 	return out, nil
 }
 
+// filter returns true if the filters of the recipient accept the message of a
+// stream.
+func (c RPC) filter(to mino.Address, env Envelope) bool {
+	peer, err := c.manager.get(to)
+	if err != nil {
+		return true
+	}
+
+	peer.Lock()
+	rpc, ok := peer.rpcs[c.path]
+	peer.Unlock()
+
+	if !ok || len(rpc.filters) == 0 {
+		return true
+	}
+
+	msg, err := c.factory.Deserialize(c.context, env.message)
+	if err != nil {
+		// The recipient will report the error.
+		return true
+	}
+
+	req := mino.Request{
+		Address: env.from,
+		Message: msg,
+	}
+
+	return rpc.runFilters(req)
+}
+
 func (c RPC) runFilters(req mino.Request) bool {
 	for _, filter := range c.filters {
 		if !filter(req) {
@@ -206,6 +236,11 @@ func (c RPC) Stream(ctx context.Context, memship mino.Players) (mino.Sender, min
 				return
 			case env := <-in:
 				for _, to := range env.to {
+					if !c.filter(to, env) {
+						// Message is dropped by one of the filter.
+						continue
+					}
+
 					if to.(address).orchestrator {
 						orchRecv.out <- env
 					} else {
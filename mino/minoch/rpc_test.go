@@ -157,6 +157,31 @@ func TestRPC_Stream(t *testing.T) {
 	require.Equal(t, err, context.Canceled)
 }
 
+func TestRPC_Filter_Stream(t *testing.T) {
+	manager := NewManager()
+
+	mA := MustCreate(manager, "A")
+	rpcA := mino.MustCreateRPC(mA, "test", fakeStreamHandler{}, fake.MessageFactory{})
+
+	mB := MustCreate(manager, "B")
+	mino.MustCreateRPC(mB, "test", fakeStreamHandler{}, fake.MessageFactory{})
+	mB.AddFilter(func(req mino.Request) bool { return false })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sender, receiver, err := rpcA.Stream(ctx, mino.NewAddresses(mB.GetAddress()))
+	require.NoError(t, err)
+
+	sender.Send(fake.Message{}, mB.GetAddress())
+
+	timeout, cancelTimeout := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelTimeout()
+
+	_, _, err = receiver.Recv(timeout)
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
 func TestRPC_Failures_Stream(t *testing.T) {
 	manager := NewManager()
 